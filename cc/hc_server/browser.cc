@@ -3,6 +3,7 @@
 #include <base/bind.h>
 #include <base/callback.h>
 #include <base/command_line.h>
+#include <base/files/file_path.h>
 #include <base/logging.h>
 #include <base/strings/string_number_conversions.h>
 #include <net/base/ip_address.h>
@@ -19,6 +20,7 @@ namespace {
 const char kPort[] = "port";
 const char kAddr[] = "addr";
 const char kProxy[] = "proxy";
+const char kUserDataDir[] = "user-data-dir";
 
 const int kDefaultPort = 9222;
 const char kDefaultAddr[] = "127.0.0.1";
@@ -53,6 +55,14 @@ int Browser::Run(int argc, const char** argv, std::function<void()> readyCb) {
         LOG(FATAL) << "Invalid devtools server address: " << addr;
     }
 
+    // Profile directory. Headless Chromium ignores the switch, and keeps everything in memory
+    // unless incognito mode is off.
+    std::string user_data_dir;
+    if (command_line.HasSwitch(kUserDataDir)) {
+        user_data_dir = command_line.GetSwitchValueASCII(kUserDataDir);
+        switche_map.erase(kUserDataDir);
+    }
+
     // Has proxy server?
     std::string proxy_server;
     if (command_line.HasSwitch(kProxy)) {
@@ -60,16 +70,24 @@ int Browser::Run(int argc, const char** argv, std::function<void()> readyCb) {
         switche_map.erase(kProxy);
     }
 
-    argc = switche_map.size();
+    // The builder takes argv[0] as the program, like main does.
+    argc = switche_map.size() + 1;
     argv = new const char*[argc];
     auto* args = new std::string[argc];
-    int i = 0;
+    args[0] = command_line.GetProgram().value();
+    argv[0] = args[0].c_str();
+    int i = 1;
     for (const auto& pair : switche_map) {
         args[i] = "--" + pair.first + "=" + pair.second;
         argv[i] = args[i].c_str();
         i++;
     }
     headless::HeadlessBrowser::Options::Builder builder(argc, argv);
+    if (!user_data_dir.empty()) {
+        LOG(INFO) << "Using profile " << user_data_dir;
+        builder.SetUserDataDir(base::FilePath(user_data_dir));
+        builder.SetIncognitoMode(false);
+    }
     builder.EnableDevToolsServer(net::IPEndPoint(parsed_addr, base::checked_cast<uint16_t>(port)));
     LOG(INFO) << "Opening devtools port on " << addr << ":" << port << " ...";
     if (!proxy_server.empty()) {
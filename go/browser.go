@@ -2,12 +2,16 @@ package headless_chromium
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/yijinliu/algo-lib/go/src/logging"
@@ -21,57 +25,145 @@ type Version struct {
 }
 
 type Browser struct {
+	// Guards output, process and profile, which Close clears. Close holds it till done, so
+	// that concurrent calls, e.g. of a cluster's health check and Shutdown, wait for the first.
+	closeMu  sync.Mutex
 	output   *os.File
 	process  *os.Process
 	addrPort string
 	version  Version
+
+	pageConnMu  sync.Mutex
+	pageConnMap map[string]*Conn
+
+	labels targetLabels
+
+	profile *profile
+
+	dialRetryWindow  time.Duration
+	closeGracePeriod time.Duration
+
+	valueMu  sync.Mutex
+	valueMap map[interface{}]interface{}
+}
+
+type LaunchOptions struct {
+	Port   int
+	Addr   string
+	Proxy  string
+	Binary string
+	// Directory of profiles. Defaults to DefaultProfileRoot().
+	ProfileRoot string
+	// If set, the profile of this name is used, and kept after the browser is closed, so that
+	// cache and cookies persist across runs. Otherwise a temporary profile is used and removed
+	// on Close. Either way a profile can only be used by one browser at a time.
+	ReuseProfile string
+	// How long to wait for the browser to answer once started. Defaults to
+	// DefaultStartupTimeout.
+	StartupTimeout time.Duration
+	// How long NewBrowserConn and NewPageConn retry failing to connect, e.g. with connection
+	// refused while the browser warms up. Defaults to DefaultDialRetryWindow.
+	DialRetryWindow time.Duration
+	// How long Close waits for the browser to exit once interrupted, before killing it.
+	// Defaults to DefaultCloseGracePeriod.
+	CloseGracePeriod time.Duration
 }
 
+const DefaultCloseGracePeriod = 5 * time.Second
+
 // Starts a headless Chromium instance and binds to it.
 func NewBrowser(port int, addr, proxy, binary string) (*Browser, error) {
+	return Launch(LaunchOptions{Port: port, Addr: addr, Proxy: proxy, Binary: binary})
+}
+
+// Starts a headless Chromium instance with a locked profile and binds to it. Temporary profiles
+// left behind by crashed processes are cleaned up first. Returns once the browser answers, so
+// that connections can be made right away.
+func Launch(opts LaunchOptions) (*Browser, error) {
+	if opts.ProfileRoot == "" {
+		opts.ProfileRoot = DefaultProfileRoot()
+	}
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = DefaultStartupTimeout
+	}
+	if opts.CloseGracePeriod <= 0 {
+		opts.CloseGracePeriod = DefaultCloseGracePeriod
+	}
+	if _, err := CleanStaleProfiles(opts.ProfileRoot); err != nil {
+		logging.Vlog(-1, err)
+	}
+	profile, err := openProfile(opts.ProfileRoot, opts.ReuseProfile)
+	if err != nil {
+		return nil, err
+	}
+	workDir := profile.dir
 	args := []string{
-		"--port=" + strconv.Itoa(port),
-		"--addr=" + addr,
+		opts.Binary,
+		"--port=" + strconv.Itoa(opts.Port),
+		"--addr=" + opts.Addr,
+		// hc_server keeps the profile there, rather than in memory.
+		"--user-data-dir=" + filepath.Join(workDir, "data"),
 	}
-	if proxy != "" {
-		args = append(args, "--proxy="+proxy)
+	if opts.Proxy != "" {
+		args = append(args, "--proxy="+opts.Proxy)
 	}
 	var pa os.ProcAttr
-	workDir := filepath.Join(os.TempDir(), fmt.Sprintf("hc-%x", time.Now().UnixNano()))
-	if err := os.MkdirAll(workDir, 0700); err != nil {
-		return nil, fmt.Errorf("Cannot create working dir: %v", err)
-	}
 	outputPath := filepath.Join(workDir, "output")
-	output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_RDWR, 0600)
+	output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
 	if err != nil {
+		profile.release()
 		return nil, fmt.Errorf("Cannot create output file: %v", err)
 	}
 	pa.Dir = workDir
 	pa.Files = []*os.File{nil, output, output}
-	logging.Vlogf(2, "Starting %s %v (work dir: %s) ...", binary, args, workDir)
-	process, err := os.StartProcess(binary, args, &pa)
+	// In its own process group, so that its children can be found by ResourceUsage and killed.
+	pa.Sys = newSysProcAttr()
+	logging.Vlogf(2, "Starting %s %v (work dir: %s) ...", opts.Binary, args[1:], workDir)
+	process, err := os.StartProcess(opts.Binary, args, &pa)
 	if err != nil {
 		output.Close()
+		profile.release()
 		return nil, err
 	}
+	if err := profile.setBrowserPid(process.Pid); err != nil {
+		logging.Vlog(-1, err)
+	}
 	browser := &Browser{
-		output:   output,
-		process:  process,
-		addrPort: fmt.Sprintf("%s:%d", addr, port),
-	}
-	for i := 0; i < 3; i++ {
-		time.Sleep(time.Second)
-		if err = browser.checkVersion(); err == nil {
-			break
-		}
+		output:           output,
+		process:          process,
+		addrPort:         fmt.Sprintf("%s:%d", opts.Addr, opts.Port),
+		profile:          profile,
+		dialRetryWindow:  opts.DialRetryWindow,
+		closeGracePeriod: opts.CloseGracePeriod,
 	}
-	if err != nil {
+	if err := browser.waitReady(opts.StartupTimeout); err != nil {
 		browser.Close()
 		return nil, err
 	}
 	return browser, nil
 }
 
+const DefaultBinary = "/usr/local/headless_chromium/bin/hc_server"
+
+var ErrBinaryNotFound = errors.New("hc_server binary not found")
+
+// Looks for the hc_server binary: $HC_SERVER_BINARY, DefaultBinary, and then $PATH.
+func FindBinary() (string, error) {
+	if binary := os.Getenv("HC_SERVER_BINARY"); binary != "" {
+		if _, err := os.Stat(binary); err != nil {
+			return "", err
+		}
+		return binary, nil
+	}
+	if _, err := os.Stat(DefaultBinary); err == nil {
+		return DefaultBinary, nil
+	}
+	if binary, err := exec.LookPath("hc_server"); err == nil {
+		return binary, nil
+	}
+	return "", ErrBinaryNotFound
+}
+
 // Binds to an existing Chromium instance.
 func NewRemoteBrowser(addrPort string) (*Browser, error) {
 	browser := &Browser{addrPort: addrPort}
@@ -81,33 +173,165 @@ func NewRemoteBrowser(addrPort string) (*Browser, error) {
 	return browser, nil
 }
 
+// Interrupts the browser, and kills it along with its children unless it exits within
+// LaunchOptions.CloseGracePeriod. Its output is closed and its profile released however that
+// goes. Does nothing for remote browsers, or if already closed. Safe for concurrent use.
 func (b *Browser) Close() error {
-	if b.process != nil {
-		if err := b.process.Signal(os.Interrupt); err != nil {
-			return err
+	b.closeMu.Lock()
+	defer b.closeMu.Unlock()
+	defer func() {
+		if b.output != nil {
+			b.output.Close()
+			b.output = nil
 		}
-		if ps, err := b.process.Wait(); err != nil {
-			return err
-		} else {
+		if b.profile != nil {
+			if err := b.profile.release(); err != nil {
+				logging.Vlog(-1, err)
+			}
+			b.profile = nil
+		}
+	}()
+	process := b.process
+	if process == nil {
+		return nil
+	}
+	b.process = nil
+	// Children of the browser may outlive it.
+	defer killProcessGroup(process.Pid)
+
+	exited := make(chan error, 1)
+	go func() {
+		ps, err := process.Wait()
+		if err == nil {
 			logging.Vlogf(1, "Headless Chromium exited: %s", ps.String())
 		}
+		exited <- err
+	}()
+	if err := process.Signal(os.Interrupt); err != nil && err != os.ErrProcessDone {
+		logging.Vlog(-1, err)
+	} else {
+		select {
+		case err := <-exited:
+			return err
+		case <-time.After(b.closeGracePeriod):
+		}
+		logging.Vlogf(-1, "Headless Chromium %d didn't exit within %v, killing it", process.Pid,
+			b.closeGracePeriod)
+	}
+	killProcessGroup(process.Pid)
+	if err := process.Kill(); err != nil && err != os.ErrProcessDone {
+		return err
 	}
-	if b.output != nil {
-		b.output.Close()
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(b.closeGracePeriod):
+		return fmt.Errorf("Headless Chromium %d didn't exit after being killed", process.Pid)
 	}
-	return nil
 }
 
-// Creates a connection to the browser, which accepts browser related commands.
+// Creates a connection to the browser, which accepts browser related commands. Transient
+// failures, e.g. connection refused while the browser warms up, are retried with backoff for
+// LaunchOptions.DialRetryWindow.
 func (b *Browser) NewBrowserConn() (*Conn, error) {
-	return newConn("ws://" + b.addrPort + "/devtools/browser")
+	return b.dial("ws://"+b.addrPort+"/devtools/browser", TargetBrowser)
 }
 
-// Creates a connection to the browser, which accepts tab related commands.
+var ErrNotAPage = errors.New("target isn't a page")
+
+// Creates a connection to the browser, which accepts tab related commands. Transient failures
+// are retried like by NewBrowserConn. Returns ErrNotAPage
+// if the target is listed with a kind other than KindPage, e.g. a service worker.
+// Works around https://bugs.chromium.org/p/chromium/issues/detail?id=704503, where new targets
+// can't be connected to till /json/list is fetched.
 func (b *Browser) NewPageConn(targetId string) (*Conn, error) {
-	return newConn("ws://" + b.addrPort + "/devtools/page/" + targetId)
+	var tabs []Tab
+	if err := retryTransient("Listing tabs of "+b.addrPort, b.retryWindow(),
+		func() (err error) {
+			tabs, err = b.ListTabs()
+			return err
+		}); err != nil {
+		return nil, err
+	}
+	for _, tab := range tabs {
+		if tab.ID == targetId && tab.Kind() != KindPage {
+			logging.Vlogf(2, "Not attaching to %s, which is a %s", targetId, tab.Type)
+			return nil, ErrNotAPage
+		}
+	}
+	url := "ws://" + b.addrPort + "/devtools/page/" + targetId
+	conn, err := b.dial(url, TargetPage)
+	if err != nil && b.needsListTabsWorkaround() {
+		backoff := 50 * time.Millisecond
+		for i := 0; i < 3 && err != nil; i++ {
+			logging.Vlogf(2, "Failed to connect to %s, refreshing tabs: %v", targetId, err)
+			if _, err := b.ListTabs(); err != nil {
+				return nil, err
+			}
+			if conn, err = newConn(url, TargetPage); err != nil {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.pageConnMu.Lock()
+	if b.pageConnMap == nil {
+		b.pageConnMap = make(map[string]*Conn)
+	}
+	b.pageConnMap[targetId] = conn
+	b.pageConnMu.Unlock()
+	go func() {
+		<-conn.Closed()
+		b.pageConnMu.Lock()
+		defer b.pageConnMu.Unlock()
+		if b.pageConnMap[targetId] == conn {
+			delete(b.pageConnMap, targetId)
+		}
+	}()
+	return conn, nil
+}
+
+// Returns the version the browser reported when bound to.
+func (b *Browser) Version() Version {
+	return b.version
+}
+
+// Like Conn.Value, for state kept per browser, e.g. what it supports.
+func (b *Browser) Value(key interface{}, create func() interface{}) interface{} {
+	b.valueMu.Lock()
+	defer b.valueMu.Unlock()
+	value, ok := b.valueMap[key]
+	if !ok && create != nil {
+		if b.valueMap == nil {
+			b.valueMap = make(map[interface{}]interface{})
+		}
+		value = create()
+		b.valueMap[key] = value
+	}
+	return value
+}
+
+// Returns the open page connection to targetId created by NewPageConn, or nil.
+func (b *Browser) PageConn(targetId string) *Conn {
+	b.pageConnMu.Lock()
+	defer b.pageConnMu.Unlock()
+	return b.pageConnMap[targetId]
 }
 
+type TabKind string
+
+const (
+	KindPage           TabKind = "page"
+	KindBackgroundPage TabKind = "background_page"
+	KindServiceWorker  TabKind = "service_worker"
+	KindBrowser        TabKind = "browser"
+	// Everything else, e.g. devtools front-ends, shared workers and iframes.
+	KindOther TabKind = "other"
+)
+
 type Tab struct {
 	Description          string `json:"description"`
 	DevtoolsFrontendUrl  string `json:"devtoolsFrontendUrl"`
@@ -116,11 +340,85 @@ type Tab struct {
 	Type                 string `json:"type"`
 	Url                  string `json:"url"`
 	WebSocketDebuggerUrl string `json:"webSocketDebuggerUrl"`
+	// The target a devtools front-end inspects, or the parent of e.g. an iframe target if the
+	// browser reports it. Empty otherwise.
+	ParentId string `json:"parentId"`
+}
+
+// Returns the kind of the target, KindOther for types not known.
+func (t *Tab) Kind() TabKind {
+	switch kind := TabKind(t.Type); kind {
+	case KindPage, KindBackgroundPage, KindServiceWorker, KindBrowser:
+		return kind
+	}
+	return KindOther
+}
+
+type listTabsOptions struct {
+	kinds []TabKind
+}
+
+type ListTabsOption func(opts *listTabsOptions)
+
+// Lists only targets of the given kinds.
+func WithKinds(kinds ...TabKind) ListTabsOption {
+	return func(opts *listTabsOptions) {
+		opts.kinds = append(opts.kinds, kinds...)
+	}
+}
+
+// Matches the inspected target of devtools front-end urls, e.g.
+// "chrome-devtools://devtools/bundled/inspector.html?ws=localhost:9222/devtools/page/<id>".
+var devtoolsTargetRe = regexp.MustCompile(
+	`^chrome-devtools://.*[?&]wss?=[^&]*/devtools/page/([^&/]+)`)
+
+// Lists all targets, including devtools front-ends, service workers and background pages,
+// unless filtered by opts.
+func (b *Browser) ListTabs(opts ...ListTabsOption) ([]Tab, error) {
+	var o listTabsOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var tabs []Tab
+	if err := b.httpGetJson("/json/list", &tabs); err != nil {
+		return nil, err
+	}
+	filtered := tabs[:0]
+	for _, tab := range tabs {
+		if tab.ParentId == "" {
+			if m := devtoolsTargetRe.FindStringSubmatch(tab.Url); m != nil {
+				tab.ParentId = m[1]
+			}
+		}
+		if len(o.kinds) > 0 && !containsKind(o.kinds, tab.Kind()) {
+			continue
+		}
+		filtered = append(filtered, tab)
+	}
+	return filtered, nil
 }
 
-func (b *Browser) ListTabs() (tabs []Tab, err error) {
-	err = b.httpGetJson("/json/list", &tabs)
-	return
+func containsKind(kinds []TabKind, kind TabKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// The first major version of Chromium with crbug 704503 fixed.
+const listTabsFixedVersion = 60
+
+var browserVersionRe = regexp.MustCompile(`/(\d+)\.`)
+
+func (b *Browser) needsListTabsWorkaround() bool {
+	m := browserVersionRe.FindStringSubmatch(b.version.Browser)
+	if m == nil {
+		return true
+	}
+	major, _ := strconv.Atoi(m[1])
+	return major < listTabsFixedVersion
 }
 
 func (b *Browser) checkVersion() error {
@@ -138,6 +436,9 @@ func (b *Browser) httpGetJson(path string, msg interface{}) error {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{url: uri, status: resp.StatusCode}
+	}
 	if content, err := ioutil.ReadAll(resp.Body); err != nil {
 		return err
 	} else if err := json.Unmarshal(content, msg); err != nil {
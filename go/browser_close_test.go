@@ -0,0 +1,151 @@
+//go:build linux
+
+package headless_chromium_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+)
+
+// Set in the environment of the test binary run as a fake hc_server, to "exit" or "ignore"
+// interrupts.
+const fakeBrowserEnv = "HC_TEST_FAKE_BROWSER"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeBrowserEnv); mode != "" {
+		runFakeBrowser(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Serves /json/version on the port and address hc.Launch passes, till interrupted, or forever.
+func runFakeBrowser(mode string) {
+	flags := flag.NewFlagSet("hc_server", flag.ExitOnError)
+	port := flags.Int("port", 0, "")
+	addr := flags.String("addr", "", "")
+	flags.String("user-data-dir", "", "")
+	flags.Parse(os.Args[1:])
+	interrupts := make(chan os.Signal, 1)
+	if mode == "ignore" {
+		signal.Ignore(os.Interrupt)
+	} else {
+		signal.Notify(interrupts, os.Interrupt)
+	}
+	l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", *addr, *port))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(hctest.FakeVersion)
+	}))
+	<-interrupts
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func launchFakeBrowser(t *testing.T, mode string, root string,
+	grace time.Duration) *hc.Browser {
+	t.Helper()
+	os.Setenv(fakeBrowserEnv, mode)
+	defer os.Unsetenv(fakeBrowserEnv)
+	browser, err := hc.Launch(hc.LaunchOptions{Port: freePort(t), Addr: "127.0.0.1",
+		Binary: os.Args[0], ProfileRoot: root, StartupTimeout: 10 * time.Second,
+		CloseGracePeriod: grace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return browser
+}
+
+func expectProfilesReleased(t *testing.T, root string) {
+	t.Helper()
+	if entries, err := ioutil.ReadDir(root); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Errorf("Left %d profiles", len(entries))
+	}
+}
+
+func TestCloseInterrupts(t *testing.T) {
+	root := t.TempDir()
+	// Long enough for binaries built with -race, which sleep a second on exit.
+	browser := launchFakeBrowser(t, "exit", root, 10*time.Second)
+	start := time.Now()
+	if err := browser.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Closed after %v", elapsed)
+	}
+	expectProfilesReleased(t, root)
+	if err := browser.Close(); err != nil {
+		t.Errorf("Closing again: %v", err)
+	}
+}
+
+// A browser which doesn't exit when interrupted is killed after the grace period, and its profile
+// released all the same.
+func TestCloseKillsAfterGracePeriod(t *testing.T) {
+	root := t.TempDir()
+	browser := launchFakeBrowser(t, "ignore", root, 500*time.Millisecond)
+	start := time.Now()
+	if err := browser.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Closed after %v", elapsed)
+	}
+	expectProfilesReleased(t, root)
+	if _, err := browser.ListTabs(); err == nil {
+		t.Error("Browser still answers")
+	}
+}
+
+// Concurrent closes, e.g. by a cluster's health check and its Shutdown, close the browser once,
+// and all return once it's closed.
+func TestCloseConcurrently(t *testing.T) {
+	root := t.TempDir()
+	browser := launchFakeBrowser(t, "exit", root, 10*time.Second)
+	const closers = 4
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*closers)
+	for i := 0; i < closers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- browser.Close()
+			if _, err := browser.ResourceUsage(); err != hc.ErrNoProcess {
+				errs <- fmt.Errorf("Got %v from ResourceUsage after closing", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	expectProfilesReleased(t, root)
+}
@@ -0,0 +1,257 @@
+// Package bytebudget caps the memory helpers spend on buffered payloads, e.g. WebSocket frames
+// or EventSource messages recorded by hcutil monitors, which pathological pages can make huge.
+// Helpers register accounts with a shared Budget and store payloads as Blobs. Once the budget is
+// nearly used up, payloads are spilled to temporary files if enabled, and read back by
+// Blob.Bytes transparently. Otherwise Store fails with ErrOverBudget, and helpers drop them.
+package bytebudget
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ErrOverBudget = errors.New("over byte budget")
+
+// Approximate memory overhead of a Blob kept in memory or spilled, besides its data: the Blob,
+// the slice header, and the pointer to it in a helper's buffer.
+const blobOverhead = 96
+
+// Returns the approximate memory n bytes of payload take, including the overhead of the slice
+// or string holding them and allocation rounding.
+func Cost(n int) int64 {
+	return int64((n+7)&^7) + blobOverhead
+}
+
+type Options struct {
+	// Max bytes of memory all accounts may use together. Required.
+	Cap int64
+	// Spill payloads to temporary files once usage gets above SpillThreshold of Cap, instead of
+	// failing with ErrOverBudget.
+	Spill bool
+	// Directory of the spill files. Defaults to os.TempDir().
+	SpillDir string
+	// Fraction of Cap. Defaults to 0.9.
+	SpillThreshold float64
+	// Size of each spill file. A file is deleted once all its blobs are released. Defaults to
+	// 64MB.
+	SpillFileSize int64
+}
+
+// Memory shared by the accounts registered with it. Safe for concurrent use.
+type Budget struct {
+	opts Options
+
+	mu       sync.Mutex
+	accounts map[*Account]bool
+	// Sum of max(reserved, used) over accounts.
+	committed int64
+	spilled   int64
+	spill     *spillStore
+}
+
+func New(opts Options) (*Budget, error) {
+	if opts.Cap <= 0 {
+		return nil, fmt.Errorf("Invalid cap %d", opts.Cap)
+	}
+	if opts.SpillThreshold <= 0 || opts.SpillThreshold > 1 {
+		opts.SpillThreshold = 0.9
+	}
+	if opts.SpillFileSize <= 0 {
+		opts.SpillFileSize = 64 << 20
+	}
+	if opts.SpillDir == "" {
+		opts.SpillDir = os.TempDir()
+	}
+	return &Budget{opts: opts, accounts: make(map[*Account]bool)}, nil
+}
+
+// Memory used by the accounts, and bytes of payloads in spill files.
+type Usage struct {
+	Memory   int64
+	Reserved int64
+	Spilled  int64
+}
+
+func (b *Budget) Usage() Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	usage := Usage{Spilled: b.spilled}
+	for a := range b.accounts {
+		usage.Memory += a.used
+		usage.Reserved += a.reserved
+	}
+	return usage
+}
+
+// Registers a helper's account, e.g. "websocket", with reserved bytes only it may use.
+// Reservations are taken from Cap whether used or not. Close the account when done.
+func (b *Budget) Register(name string, reserved int64) (*Account, error) {
+	if reserved < 0 {
+		reserved = 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.committed+reserved > b.opts.Cap {
+		return nil, fmt.Errorf("Can't reserve %d bytes for %s: %w", reserved, name, ErrOverBudget)
+	}
+	a := &Account{budget: b, name: name, reserved: reserved}
+	b.accounts[a] = true
+	b.committed += reserved
+	return a, nil
+}
+
+// Removes the spill files. Blobs which are still spilled can't be read afterwards.
+func (b *Budget) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.spill == nil {
+		return nil
+	}
+	err := b.spill.close()
+	b.spill = nil
+	return err
+}
+
+// A helper's share of a Budget.
+type Account struct {
+	budget   *Budget
+	name     string
+	reserved int64
+
+	// Guarded by budget.mu.
+	used   int64
+	closed bool
+}
+
+func (a *Account) Name() string {
+	return a.name
+}
+
+// Must be called with budget.mu held. Returns the committed bytes of the budget if the account
+// used delta more.
+func (a *Account) committedWith(delta int64) int64 {
+	b := a.budget
+	before, after := a.used, a.used+delta
+	if before < a.reserved {
+		before = a.reserved
+	}
+	if after < a.reserved {
+		after = a.reserved
+	}
+	return b.committed - before + after
+}
+
+// Must be called with budget.mu held.
+func (a *Account) charge(delta int64) {
+	a.budget.committed = a.committedWith(delta)
+	a.used += delta
+}
+
+// Stores a copy of data, in memory if the budget allows, otherwise in a spill file if enabled.
+// Returns ErrOverBudget if neither is possible.
+func (a *Account) Store(data []byte) (*Blob, error) {
+	b := a.budget
+	cost := Cost(len(data))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if a.closed {
+		return nil, fmt.Errorf("Account %s is closed", a.name)
+	}
+	committed := a.committedWith(cost)
+	inReservation := a.used+cost <= a.reserved
+	if inReservation || (committed <= b.opts.Cap &&
+		(!b.opts.Spill || float64(committed) <= b.opts.SpillThreshold*float64(b.opts.Cap))) {
+		a.charge(cost)
+		return &Blob{account: a, data: append([]byte(nil), data...), cost: cost}, nil
+	}
+	if !b.opts.Spill || a.committedWith(blobOverhead) > b.opts.Cap {
+		return nil, ErrOverBudget
+	}
+	if b.spill == nil {
+		b.spill = &spillStore{dir: b.opts.SpillDir, fileSize: b.opts.SpillFileSize}
+	}
+	file, offset, err := b.spill.write(data)
+	if err != nil {
+		return nil, err
+	}
+	a.charge(blobOverhead)
+	b.spilled += int64(len(data))
+	return &Blob{account: a, file: file, offset: offset, length: len(data), cost: blobOverhead},
+		nil
+}
+
+// Releases all blobs of the account, which must not be used afterwards, and its reservation.
+func (a *Account) Close() {
+	b := a.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if a.closed {
+		return
+	}
+	a.closed = true
+	b.committed = a.committedWith(-a.used) - a.reserved
+	a.used = 0
+	delete(b.accounts, a)
+}
+
+// A payload stored by Account.Store.
+type Blob struct {
+	account *Account
+	cost    int64
+
+	mu     sync.Mutex
+	data   []byte
+	file   *spillFile
+	offset int64
+	length int
+}
+
+func (blob *Blob) Len() int {
+	blob.mu.Lock()
+	defer blob.mu.Unlock()
+	if blob.file != nil {
+		return blob.length
+	}
+	return len(blob.data)
+}
+
+// Returns the payload, read back from its spill file if spilled. Callers must not modify it.
+func (blob *Blob) Bytes() ([]byte, error) {
+	blob.mu.Lock()
+	defer blob.mu.Unlock()
+	if blob.file == nil {
+		if blob.cost == 0 {
+			return nil, errors.New("Blob is released")
+		}
+		return blob.data, nil
+	}
+	data := make([]byte, blob.length)
+	if _, err := blob.file.f.ReadAt(data, blob.offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Returns the blob's memory to the budget. The blob must not be used afterwards.
+func (blob *Blob) Release() {
+	blob.mu.Lock()
+	defer blob.mu.Unlock()
+	if blob.cost == 0 {
+		return
+	}
+	b := blob.account.budget
+	b.mu.Lock()
+	if !blob.account.closed {
+		blob.account.charge(-blob.cost)
+	}
+	if blob.file != nil {
+		b.spilled -= int64(blob.length)
+		if b.spill != nil {
+			b.spill.release(blob.file)
+		}
+	}
+	b.mu.Unlock()
+	blob.cost, blob.data, blob.file = 0, nil, nil
+}
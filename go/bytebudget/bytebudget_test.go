@@ -0,0 +1,67 @@
+package bytebudget_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/bytebudget"
+)
+
+// Storing 1GB with a 16MB cap keeps memory under the cap, and every payload can still be read
+// back from the spill files.
+func TestSpillStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Writes 1GB of spill files")
+	}
+	const (
+		capBytes    = 16 << 20
+		total       = 1 << 30
+		payloadSize = 64 << 10
+	)
+	budget, err := bytebudget.New(bytebudget.Options{Cap: capBytes, Spill: true,
+		SpillDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer budget.Close()
+	account, err := budget.Register("stress", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer account.Close()
+
+	payload := make([]byte, payloadSize)
+	var blobs []*bytebudget.Blob
+	for i := 0; i < total/payloadSize; i++ {
+		for j := range payload {
+			payload[j] = byte(i)
+		}
+		blob, err := account.Store(payload)
+		if err != nil {
+			t.Fatalf("Storing payload %d: %v", i, err)
+		}
+		blobs = append(blobs, blob)
+		if usage := budget.Usage(); usage.Memory > capBytes {
+			t.Fatalf("Used %d bytes after payload %d", usage.Memory, i)
+		}
+	}
+	if usage := budget.Usage(); usage.Spilled < total-capBytes {
+		t.Errorf("Spilled only %d bytes", usage.Spilled)
+	}
+	for i, blob := range blobs {
+		for j := range payload {
+			payload[j] = byte(i)
+		}
+		data, err := blob.Bytes()
+		if err != nil {
+			t.Fatalf("Reading payload %d: %v", i, err)
+		}
+		if !bytes.Equal(data, payload) {
+			t.Fatalf("Payload %d is corrupted", i)
+		}
+		blob.Release()
+	}
+	if usage := budget.Usage(); usage.Memory != 0 || usage.Spilled != 0 {
+		t.Errorf("Got %+v after releasing all", usage)
+	}
+}
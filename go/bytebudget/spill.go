@@ -0,0 +1,82 @@
+package bytebudget
+
+import (
+	"os"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// Append-only temporary files holding spilled payloads. Blobs keep their file and offset, so
+// they are the index. Guarded by Budget.mu.
+type spillStore struct {
+	dir      string
+	fileSize int64
+	current  *spillFile
+	files    map[*spillFile]bool
+}
+
+type spillFile struct {
+	f    *os.File
+	size int64
+	// Blobs not released yet.
+	live int
+}
+
+func (s *spillStore) write(data []byte) (*spillFile, int64, error) {
+	if s.current == nil || s.current.size+int64(len(data)) > s.fileSize {
+		if s.current != nil && s.current.live == 0 {
+			s.remove(s.current)
+		}
+		f, err := os.CreateTemp(s.dir, "hc-spill-*")
+		if err != nil {
+			return nil, 0, err
+		}
+		if s.files == nil {
+			s.files = make(map[*spillFile]bool)
+		}
+		s.current = &spillFile{f: f}
+		s.files[s.current] = true
+	}
+	file := s.current
+	offset := file.size
+	if _, err := file.f.WriteAt(data, offset); err != nil {
+		return nil, 0, err
+	}
+	file.size += int64(len(data))
+	file.live++
+	return file, offset, nil
+}
+
+func (s *spillStore) release(file *spillFile) {
+	file.live--
+	if file.live == 0 && file != s.current && s.files[file] {
+		s.remove(file)
+	}
+}
+
+func (s *spillStore) remove(file *spillFile) {
+	delete(s.files, file)
+	if file == s.current {
+		s.current = nil
+	}
+	if err := file.f.Close(); err != nil {
+		logging.Vlog(1, err)
+	}
+	if err := os.Remove(file.f.Name()); err != nil {
+		logging.Vlog(1, err)
+	}
+}
+
+func (s *spillStore) close() error {
+	var firstErr error
+	for file := range s.files {
+		if err := file.f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := os.Remove(file.f.Name()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.files, s.current = nil, nil
+	return firstErr
+}
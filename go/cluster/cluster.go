@@ -0,0 +1,391 @@
+// Package cluster runs several headless Chromium instances, e.g. one per CPU, and spreads work
+// among them.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/metrics"
+)
+
+var ErrShutdown = errors.New("cluster is shut down")
+
+type Options struct {
+	// Number of browsers.
+	Size int
+	// Browser i listens on BasePort+i.
+	BasePort int
+	// Defaults to "127.0.0.1".
+	Addr   string
+	Proxy  string
+	Binary string
+	// How often to check whether browsers are alive. Defaults to 5 seconds.
+	HealthInterval time.Duration
+	// Backoff of relaunching a dead browser, doubled on every failure. Defaults to 1 second and
+	// 1 minute.
+	MinBackoff, MaxBackoff time.Duration
+	// If set, labels of targets are kept in this file suffixed by the port, and orphans older
+	// than CleanupOrphansOlderThan are cleaned up after launching. See Browser.CleanupOrphans.
+	TargetLabelFile         string
+	CleanupOrphansOlderThan time.Duration
+	// If set, browsers exceeding them are recycled: they aren't acquired any more, and are
+	// relaunched once released or after RecycleGracePeriod. Checked every HealthInterval. See
+	// Browser.ResourceUsage.
+	Limits hc.ResourceLimits
+	// Defaults to 1 minute.
+	RecycleGracePeriod time.Duration
+	// How long closing a browser waits for it to exit before killing it. Defaults to
+	// hc.DefaultCloseGracePeriod.
+	CloseGracePeriod time.Duration
+	// Receives the hc_cluster_* metrics, see package metrics. nil means none.
+	Metrics metrics.Metrics
+}
+
+type BrowserStats struct {
+	Port    int
+	Healthy bool
+	// Number of acquisitions not released yet.
+	Active int
+	// Number of acquisitions ever.
+	Total    int
+	Restarts int
+	// Number of restarts because of Limits.
+	Recycles int
+	// As of the last health check, or nil if not known.
+	Usage *hc.ResourceUsage
+}
+
+type instance struct {
+	port     int
+	browser  *hc.Browser
+	healthy  bool
+	active   int
+	total    int
+	restarts int
+	recycles int
+	usage    *hc.ResourceUsage
+}
+
+type Cluster struct {
+	opts Options
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	instances []*instance
+	shutdown  bool
+	// Number of Acquire calls waiting for a healthy browser.
+	waiting int
+}
+
+// Launches the browsers. Fails if any of them can't be launched.
+func NewCluster(opts Options) (*Cluster, error) {
+	if opts.Addr == "" {
+		opts.Addr = "127.0.0.1"
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = 5 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	if opts.RecycleGracePeriod <= 0 {
+		opts.RecycleGracePeriod = time.Minute
+	}
+	opts.Metrics = metrics.OrDiscard(opts.Metrics)
+	c := &Cluster{opts: opts, done: make(chan struct{})}
+	c.cond = sync.NewCond(&c.mu)
+	for i := 0; i < opts.Size; i++ {
+		inst := &instance{port: opts.BasePort + i}
+		browser, err := c.launch(inst.port)
+		if err != nil {
+			c.Shutdown()
+			return nil, err
+		}
+		inst.browser, inst.healthy = browser, true
+		c.instances = append(c.instances, inst)
+	}
+	c.mu.Lock()
+	c.recordGauges()
+	c.mu.Unlock()
+	for _, inst := range c.instances {
+		c.wg.Add(1)
+		go c.watch(inst)
+	}
+	return c, nil
+}
+
+func (c *Cluster) launch(port int) (*hc.Browser, error) {
+	browser, err := hc.Launch(hc.LaunchOptions{Port: port, Addr: c.opts.Addr,
+		Proxy: c.opts.Proxy, Binary: c.opts.Binary, CloseGracePeriod: c.opts.CloseGracePeriod})
+	if err != nil || c.opts.TargetLabelFile == "" {
+		return browser, err
+	}
+	labelFile := fmt.Sprintf("%s.%d", c.opts.TargetLabelFile, port)
+	if err := browser.SetTargetLabelFile(labelFile); err != nil {
+		logging.Vlog(-1, err)
+	} else if _, err := browser.CleanupOrphans(c.opts.CleanupOrphansOlderThan); err != nil {
+		logging.Vlog(-1, err)
+	}
+	return browser, nil
+}
+
+// Returns the least loaded healthy browser, waiting for one if none is healthy. Call release
+// once done with it.
+func (c *Cluster) Acquire(ctx context.Context) (browser *hc.Browser, release func(), err error) {
+	start := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		c.opts.Metrics.Histogram(metrics.ClusterAcquireWaitSeconds,
+			metrics.Labels{"result": result}, time.Since(start).Seconds())
+	}()
+	// Wake up waiters when ctx is done.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.cond.Broadcast()
+			c.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiting := false
+	defer func() {
+		if waiting {
+			c.waiting--
+			c.recordGauges()
+		}
+	}()
+	for {
+		if c.shutdown {
+			return nil, nil, ErrShutdown
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		var best *instance
+		for _, inst := range c.instances {
+			if inst.healthy && (best == nil || inst.active < best.active) {
+				best = inst
+			}
+		}
+		if best != nil {
+			best.active++
+			best.total++
+			c.recordGauges()
+			var once sync.Once
+			return best.browser, func() {
+				once.Do(func() {
+					c.mu.Lock()
+					defer c.mu.Unlock()
+					best.active--
+					c.recordGauges()
+				})
+			}, nil
+		}
+		if !waiting {
+			waiting = true
+			c.waiting++
+			c.recordGauges()
+		}
+		c.cond.Wait()
+	}
+}
+
+func (c *Cluster) Stats() []BrowserStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var stats []BrowserStats
+	for _, inst := range c.instances {
+		stats = append(stats, BrowserStats{
+			Port:     inst.port,
+			Healthy:  inst.healthy,
+			Active:   inst.active,
+			Total:    inst.total,
+			Restarts: inst.restarts,
+			Recycles: inst.recycles,
+			Usage:    inst.usage,
+		})
+	}
+	return stats
+}
+
+// Kills all browsers. Pending and later Acquire calls fail with ErrShutdown.
+func (c *Cluster) Shutdown() {
+	c.mu.Lock()
+	if c.shutdown {
+		c.mu.Unlock()
+		return
+	}
+	c.shutdown = true
+	close(c.done)
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	c.wg.Wait()
+	for _, inst := range c.instances {
+		if inst.browser != nil {
+			if err := inst.browser.Close(); err != nil {
+				logging.Vlog(-1, err)
+			}
+		}
+	}
+}
+
+// Checks the browser periodically, and relaunches it once it stops responding.
+func (c *Cluster) watch(inst *instance) {
+	defer c.wg.Done()
+	backoff := c.opts.MinBackoff
+	for {
+		select {
+		case <-time.After(c.opts.HealthInterval):
+		case <-c.done:
+			return
+		}
+		c.mu.Lock()
+		browser := inst.browser
+		c.mu.Unlock()
+		reason := "dead"
+		if browser != nil {
+			if _, err := browser.ListTabs(); err != nil {
+				logging.Vlogf(-1, "Browser on port %d is dead: %v", inst.port, err)
+				c.mu.Lock()
+				inst.healthy = false
+				inst.browser = nil
+				c.recordGauges()
+				c.mu.Unlock()
+			} else if !c.checkUsage(inst, browser) {
+				continue
+			} else {
+				reason = "recycled"
+				if !c.drain(inst) {
+					return
+				}
+			}
+			if err := browser.Close(); err != nil {
+				logging.Vlog(1, err)
+			}
+		}
+
+		for {
+			newBrowser, err := c.launch(inst.port)
+			if err == nil {
+				c.mu.Lock()
+				inst.browser, inst.healthy = newBrowser, true
+				inst.restarts++
+				c.cond.Broadcast()
+				c.recordGauges()
+				c.mu.Unlock()
+				c.opts.Metrics.Counter(metrics.ClusterBrowserRestartsTotal,
+					metrics.Labels{"reason": reason}, 1)
+				backoff = c.opts.MinBackoff
+				break
+			}
+			logging.Vlogf(-1, "Failed to relaunch browser on port %d, retrying in %v: %v",
+				inst.port, backoff, err)
+			c.opts.Metrics.Counter(metrics.ClusterBrowserLaunchFailuresTotal, nil, 1)
+			select {
+			case <-time.After(backoff):
+			case <-c.done:
+				return
+			}
+			if backoff *= 2; backoff > c.opts.MaxBackoff {
+				backoff = c.opts.MaxBackoff
+			}
+		}
+	}
+}
+
+// Records the resource usage of the browser, and returns whether it should be recycled.
+func (c *Cluster) checkUsage(inst *instance, browser *hc.Browser) bool {
+	usage, err := browser.ResourceUsage()
+	if err != nil {
+		logging.Vlog(2, err)
+		return false
+	}
+	exceeded := c.opts.Limits.Exceeded(usage)
+	c.mu.Lock()
+	inst.usage = usage
+	if exceeded {
+		inst.healthy = false
+		inst.recycles++
+		c.recordGauges()
+	}
+	c.mu.Unlock()
+	labels := metrics.Labels{"port": strconv.Itoa(inst.port)}
+	c.opts.Metrics.Gauge(metrics.ClusterBrowserRSSBytes, labels, float64(usage.RSS))
+	c.opts.Metrics.Gauge(metrics.ClusterBrowserCPUSeconds, labels, usage.CPUTime.Seconds())
+	c.opts.Metrics.Gauge(metrics.ClusterBrowserOpenFDs, labels, float64(usage.OpenFDs))
+	c.opts.Metrics.Gauge(metrics.ClusterBrowserProcesses, labels, float64(usage.Processes))
+	if !exceeded {
+		return false
+	}
+	logging.Vlogf(-1, "Recycling browser on port %d: %s", inst.port, usage)
+	if c.opts.Limits.OnExceeded != nil {
+		c.opts.Limits.OnExceeded(usage)
+	}
+	return true
+}
+
+// Waits till the browser is released or the grace period is over, and then takes it out.
+// Returns false if the cluster is shut down meanwhile.
+func (c *Cluster) drain(inst *instance) bool {
+	deadline := time.After(c.opts.RecycleGracePeriod)
+	for {
+		c.mu.Lock()
+		active := inst.active
+		if active == 0 {
+			inst.browser = nil
+		}
+		c.mu.Unlock()
+		if active == 0 {
+			return true
+		}
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			logging.Vlogf(-1, "Browser on port %d is still in use, recycling anyway", inst.port)
+			c.mu.Lock()
+			inst.browser = nil
+			c.recordGauges()
+			c.mu.Unlock()
+			return true
+		case <-c.done:
+			return false
+		}
+	}
+}
+
+// Records the gauges of the pool. Must be called with mu held.
+func (c *Cluster) recordGauges() {
+	var healthy, acquired int
+	for _, inst := range c.instances {
+		if inst.healthy {
+			healthy++
+		}
+		acquired += inst.active
+	}
+	c.opts.Metrics.Gauge(metrics.ClusterBrowsers, nil, float64(len(c.instances)))
+	c.opts.Metrics.Gauge(metrics.ClusterBrowsersHealthy, nil, float64(healthy))
+	c.opts.Metrics.Gauge(metrics.ClusterAcquired, nil, float64(acquired))
+	c.opts.Metrics.Gauge(metrics.ClusterAcquireWaiting, nil, float64(c.waiting))
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/metrics"
+)
+
+// A cluster of one instance which has a browser that's never launched.
+func newDrainCluster(gracePeriod time.Duration, active int) (*Cluster, *instance) {
+	c := &Cluster{
+		opts: Options{RecycleGracePeriod: gracePeriod, Metrics: metrics.OrDiscard(nil)},
+		done: make(chan struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	inst := &instance{port: 9222, browser: &hc.Browser{}, active: active}
+	c.instances = []*instance{inst}
+	return c, inst
+}
+
+func (c *Cluster) instanceBrowser(inst *instance) *hc.Browser {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return inst.browser
+}
+
+func TestDrain(t *testing.T) {
+	// Idle browsers are taken out right away.
+	c, inst := newDrainCluster(time.Hour, 0)
+	if !c.drain(inst) || c.instanceBrowser(inst) != nil {
+		t.Error("Idle browser not taken out")
+	}
+
+	// Busy ones once released.
+	c, inst = newDrainCluster(time.Hour, 1)
+	start := time.Now()
+	time.AfterFunc(200*time.Millisecond, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		inst.active--
+	})
+	if !c.drain(inst) || c.instanceBrowser(inst) != nil {
+		t.Error("Released browser not taken out")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Taken out after %v, before being released", elapsed)
+	}
+
+	// Or once the grace period is over.
+	c, inst = newDrainCluster(300*time.Millisecond, 1)
+	start = time.Now()
+	if !c.drain(inst) || c.instanceBrowser(inst) != nil {
+		t.Error("Busy browser not taken out")
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Taken out after %v, within the grace period", elapsed)
+	}
+
+	// Shutting down leaves the browser to Shutdown.
+	c, inst = newDrainCluster(time.Hour, 1)
+	time.AfterFunc(100*time.Millisecond, func() { close(c.done) })
+	if c.drain(inst) || c.instanceBrowser(inst) == nil {
+		t.Error("Browser taken out while shutting down")
+	}
+}
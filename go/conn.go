@@ -1,39 +1,294 @@
 package headless_chromium
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/yijinliu/algo-lib/go/src/logging"
 )
 
+var ErrConnClosed = errors.New("connection closed")
+var ErrCloseTimeout = errors.New("timed out waiting for callbacks")
+
+// Returned by commands not answered within their timeout. See Conn.SetDefaultTimeout.
+var ErrCommandTimeout = errors.New("command timed out")
+
+const defaultCloseTimeout = 5 * time.Second
+
+// The target of the connection went away, e.g. the tab was closed or crashed. Conn.Err returns
+// it once the connection is closed because of that, and wait helpers fail with it.
+type TargetClosedError struct {
+	// From Inspector.detached, e.g. "target_closed", or set by whoever noticed it.
+	Reason string
+}
+
+func (e *TargetClosedError) Error() string {
+	return "Target closed: " + e.Reason
+}
+
+// An event which couldn't be unmarshaled, e.g. because the browser speaks a newer protocol.
+type EventError struct {
+	Name   string
+	Params []byte
+	Err    error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("Bad event %s %s: %v", e.Name, string(e.Params), e.Err)
+}
+
+type ConnStats struct {
+	// Number of events which couldn't be unmarshaled, by event name.
+	EventErrors map[string]int
+	// Number of messages dropped for being over the limits. See SetMaxMessageSizes.
+	OversizedSends, OversizedRecvs int
+	// Number of errors not logged as similar ones were logged already, by method. Empty method
+	// is for errors not about a specific method. See SetErrorLogThrottle.
+	SuppressedErrors map[string]int
+	// Number of remote objects created by helpers, and released so far. See
+	// BeginHelperObjects.
+	HelperObjectsCreated, HelperObjectsReleased int
+	// Number of evaluations delayed by the throttle, and the total time they waited. See
+	// SetEvaluateThrottle.
+	ThrottledEvaluations int
+	EvaluationQueueTime  time.Duration
+	// Number of event sinks by event name. See SinkReport.
+	EventSinks map[string]int
+	// Number of commands which failed with ErrCommandTimeout, and of their responses which came
+	// later and were discarded.
+	CommandTimeouts, LateResponses int
+}
+
+// Default limits of message sizes. Screenshots and MHTML snapshots of big pages may need more.
+const (
+	DefaultMaxSendSize = 10 << 20
+	DefaultMaxRecvSize = 100 << 20
+)
+
+// A message over the limit. Only the command it belongs to fails, the connection stays open.
+type MessageSizeError struct {
+	// The command or the event, if known.
+	Method   string
+	Size     int
+	Limit    int
+	Incoming bool
+}
+
+func (e *MessageSizeError) Error() string {
+	direction := "Outgoing"
+	if e.Incoming {
+		direction = "Incoming"
+	}
+	return fmt.Sprintf("%s message of %s is %d bytes, over the limit %d. "+
+		"Use Conn.SetMaxMessageSizes to raise it.", direction, e.Method, e.Size, e.Limit)
+}
+
+// A command result or an event which doesn't match the protocol, found in strict decoding mode.
+// See SetStrictDecoding.
+type SchemaError struct {
+	Method string
+	Event  bool
+	// The unknown or missing field.
+	Field string
+	Err   error
+}
+
+func (e *SchemaError) Error() string {
+	what := "Result of"
+	if e.Event {
+		what = "Event"
+	}
+	return fmt.Sprintf("%s %s doesn't match the protocol at field '%s': %v",
+		what, e.Method, e.Field, e.Err)
+}
+
+// Checks a command result or event against the protocol. Registered by the protocol package.
+type SchemaChecker func(method string, event bool, data []byte) *SchemaError
+
+var schemaChecker SchemaChecker
+var strictDecoding int32
+
+// Don't call this. The protocol package registers its checker.
+func SetSchemaChecker(checker SchemaChecker) {
+	schemaChecker = checker
+}
+
+// In strict decoding mode, command results and events with fields unknown to the protocol or
+// without required fields are reported as SchemaError to the error handler of the connection.
+// Commands and events are still delivered as usual. Useful in tests to catch protocol drift.
+// This sets the default for all connections. See also Conn.SetStrictDecoding.
+func SetStrictDecoding(strict bool) {
+	var v int32
+	if strict {
+		v = 1
+	}
+	atomic.StoreInt32(&strictDecoding, v)
+}
+
+// Priority of a command in the send queue.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	// Low priority commands (e.g. screencast acks) are only sent when there is no normal command
+	// waiting, or after maxNormalStreak normal commands in a row so that they never starve.
+	PriorityLow
+)
+
+const maxNormalStreak = 8
+
 type Command interface {
 	Name() string
 	Params() interface{}
 	Done(result []byte, err error)
 }
 
+// Sends commands to a target, e.g. *Conn or *Session. Functions of the protocol package take
+// it, so that commands can go over other transports, or to fakes in tests.
+type CommandRunner interface {
+	// See Conn.SendCommand.
+	SendCommand(cmd Command) error
+}
+
+// Delivers events of a target to sinks, e.g. *Conn or *Session. The On functions of the protocol
+// package take it, like CommandRunner.
+type EventSource interface {
+	// See Conn.AddEventSink. Adding a sink twice adds it once.
+	AddEventSink(name string, sink EventSink)
+	RemoveEventSink(name string, sink EventSink)
+	// See Conn.ReportEventError.
+	ReportEventError(name string, params []byte, err error)
+}
+
+// Each OnEvent call runs in its own goroutine, never on the goroutine reading from the browser.
+// So it may block, e.g. run synchronous commands, without stalling the connection. The flip side
+// is that calls may be concurrent, and events are not necessarily seen in the order they came.
+// Neither are events and responses, unless SetOrderedResponses is on.
 type EventSink interface {
 	OnEvent(name string, params []byte)
 }
 
+// About an event, as it came from the browser.
+type EventMeta struct {
+	// Starts from 1 for each connection, in the order events arrived, which OnEvent calls may not
+	// follow.
+	Seq uint64
+	// When the event was read from the websocket.
+	Received time.Time
+	// Size of the whole message in bytes.
+	Size int
+}
+
+// An EventSink which also wants EventMeta. OnEventMeta is called instead of OnEvent.
+type MetaEventSink interface {
+	EventSink
+	OnEventMeta(meta EventMeta, name string, params []byte)
+}
+
+// Conn is a devtools protocol connection to the browser or one of its tabs.
+// All methods are safe for concurrent use. Commands still pending when the connection is closed
+// finish with ErrConnClosed. Event sinks are called from their own goroutines, so it's fine to
+// add / remove sinks or run commands from inside them. Don't close the connection from inside
+// them though, as Close waits for all callbacks to return.
 type Conn struct {
 	conn *websocket.Conn
+	kind TargetKinds
+
+	closeOnce sync.Once
+	closeErr  error
+	closed    chan struct{}
+
+	// Number of Done / OnEvent callbacks running. cbIdle is closed when it drops to 0. No callback
+	// is started once cbClosed is set by shutdown.
+	cbMu     sync.Mutex
+	cbCount  int
+	cbIdle   chan struct{}
+	cbClosed bool
 
 	cmdMu         sync.Mutex
 	pendingCmdMap map[int]Command // key is id.
-	nextCmdId     int
+	// When pending commands were sent, for DumpState.
+	pendingSentMap map[int]time.Time
+	// Timers of pending commands with a timeout.
+	pendingTimerMap map[int]*time.Timer
+	defaultTimeout  time.Duration
+	// Commands which timed out, whose responses are discarded.
+	timedOutIds     map[int]bool
+	commandTimeouts int
+	lateResponses   int
+	nextCmdId       int
+
+	writeMu     sync.Mutex
+	writeCond   *sync.Cond
+	writeQueues [2][]*CommandJson // Indexed by Priority.
 
 	evtMu      sync.Mutex
-	evtSinkMap map[string][]EventSink
+	evtSinkMap map[string][]EventSink // Slices are never modified in place.
+	// Where the sinks of evtSinkMap were added, at the same indices. See SetTrackSinkSites.
+	evtSinkSiteMap map[string][]string
+	sinkLimit      int
+	// The number of sinks last warned about, by event name. See SetSinkLimit.
+	sinkWarnedMap  map[string]int
+	trackSinkSites bool
+	// See AddEventHook. Slices are never modified in place either.
+	evtHookMap map[string][]*eventHook
+	// Last occurrences of sticky events since the main frame navigated.
+	stickyMap   map[string]*stickyEvent
+	mainFrameId string
+	lastEvtSeq  uint64
+	// Number of OnEvent calls not returned yet, by event sequence number. See Flush.
+	evtInFlight map[uint64]int
+	// Signaled when an entry is removed from evtInFlight.
+	evtCond *sync.Cond
+	// See SetOrderedResponses.
+	orderedResponses bool
+	// Last event sequence number when each pending command was sent, in ordered mode.
+	sentSeqMap map[int]uint64
+
+	valueMu  sync.Mutex
+	valueMap map[interface{}]interface{}
+
+	errMu          sync.Mutex
+	errHandler     func(err error)
+	strictEvents   bool
+	strictDecoding *bool // Overrides the package default if not nil.
+	closeCause     error
+	eventErrorsMap map[string]int
+	oversizedSends int
+	oversizedRecvs int
+	errLog         *errorLogger
+
+	sizeMu      sync.Mutex
+	maxSendSize int
+	maxRecvSize int
+
+	domainMu          sync.Mutex
+	autoEnableDomains bool
+	enabledDomainMap  map[string]bool // Value is whether it was enabled automatically.
+	domainRefMap      map[string]*domainRef
+	// Serializes AcquireDomain and ReleaseDomain, which send commands without domainMu held.
+	domainRefMu sync.Mutex
+
+	helperObjMu sync.Mutex
+	helperObjs  helperObjects
+
+	throttleMu   sync.Mutex
+	evalThrottle evalThrottle
 }
 
-func newConn(url string) (*Conn, error) {
+func newConn(url string, kind TargetKinds) (*Conn, error) {
 	logging.Vlogf(2, "Connecting to %s ...", url)
 	dialer := &websocket.Dialer{
 		EnableCompression: false,
@@ -41,21 +296,466 @@ func newConn(url string) (*Conn, error) {
 	header := http.Header{
 		"Origin": []string{"http://localhost/"},
 	}
-	ws, _, err := dialer.Dial(url, header)
+	ws, resp, err := dialer.Dial(url, header)
 	if err != nil {
+		if resp != nil {
+			return nil, &httpStatusError{url: url, status: resp.StatusCode, err: err}
+		}
 		return nil, err
 	}
 	conn := &Conn{
-		conn:          ws,
-		pendingCmdMap: make(map[int]Command),
-		evtSinkMap:    make(map[string][]EventSink),
+		conn:             ws,
+		kind:             kind,
+		closed:           make(chan struct{}),
+		pendingCmdMap:    make(map[int]Command),
+		pendingSentMap:   make(map[int]time.Time),
+		pendingTimerMap:  make(map[int]*time.Timer),
+		timedOutIds:      make(map[int]bool),
+		evtSinkMap:       make(map[string][]EventSink),
+		evtSinkSiteMap:   make(map[string][]string),
+		sinkWarnedMap:    make(map[string]int),
+		evtHookMap:       make(map[string][]*eventHook),
+		stickyMap:        make(map[string]*stickyEvent),
+		evtInFlight:      make(map[uint64]int),
+		sentSeqMap:       make(map[int]uint64),
+		enabledDomainMap: make(map[string]bool),
+		valueMap:         make(map[interface{}]interface{}),
+		eventErrorsMap:   make(map[string]int),
+		errLog:           newErrorLogger(),
+		maxSendSize:      DefaultMaxSendSize,
+		maxRecvSize:      DefaultMaxRecvSize,
 	}
+	conn.writeCond = sync.NewCond(&conn.writeMu)
+	conn.evtCond = sync.NewCond(&conn.evtMu)
 	go conn.readLoop()
+	go conn.writeLoop()
 	return conn, nil
 }
 
+// Closes the connection. It's safe to call this multiple times.
 func (c *Conn) Close() error {
-	return c.conn.Close()
+	return c.CloseWithTimeout(defaultCloseTimeout)
+}
+
+// Closes the connection: new commands are rejected, pending ones fail with ErrConnClosed.
+// Then waits up to timeout for running callbacks to return, so that none runs after this.
+func (c *Conn) CloseWithTimeout(timeout time.Duration) error {
+	c.shutdown()
+	select {
+	case <-c.callbacksIdle():
+	case <-time.After(timeout):
+		return ErrCloseTimeout
+	}
+	return c.closeErr
+}
+
+func (c *Conn) shutdown() {
+	c.closeOnce.Do(func() {
+		c.cmdMu.Lock()
+		close(c.closed)
+		pendingCmdMap := c.pendingCmdMap
+		c.pendingCmdMap = make(map[int]Command)
+		c.pendingSentMap = make(map[int]time.Time)
+		for _, timer := range c.pendingTimerMap {
+			timer.Stop()
+		}
+		c.pendingTimerMap = make(map[int]*time.Timer)
+		c.evtMu.Lock()
+		c.sentSeqMap = make(map[int]uint64)
+		c.evtMu.Unlock()
+		c.cmdMu.Unlock()
+
+		c.writeMu.Lock()
+		c.writeCond.Broadcast()
+		c.writeMu.Unlock()
+
+		c.closeErr = c.conn.Close()
+		c.cbMu.Lock()
+		c.cbClosed = true
+		for _, cmd := range pendingCmdMap {
+			cmd := cmd
+			c.startCallbackLocked(func() { cmd.Done(nil, ErrConnClosed) })
+		}
+		c.cbMu.Unlock()
+	})
+}
+
+// Waits till there is no pending command and no running callback, without closing the
+// connection.
+func (c *Conn) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		c.cmdMu.Lock()
+		idle := len(c.pendingCmdMap) == 0
+		c.cmdMu.Unlock()
+		if idle {
+			select {
+			case <-c.callbacksIdle():
+				// A callback might have sent a new command before returning.
+				c.cmdMu.Lock()
+				idle = len(c.pendingCmdMap) == 0
+				c.cmdMu.Unlock()
+				if idle {
+					return nil
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Runs cb in a new goroutine, keeping track of it. Once the connection is shutting down, cb is
+// dropped, so that no callback starts after Close has waited for the running ones. Done of
+// pending commands is called by shutdown itself. Returns whether cb was started.
+func (c *Conn) runCallback(cb func()) bool {
+	c.cbMu.Lock()
+	defer c.cbMu.Unlock()
+	if c.cbClosed {
+		return false
+	}
+	c.startCallbackLocked(cb)
+	return true
+}
+
+func (c *Conn) startCallbackLocked(cb func()) {
+	if c.cbCount == 0 {
+		c.cbIdle = make(chan struct{})
+	}
+	c.cbCount++
+	go func() {
+		defer func() {
+			c.cbMu.Lock()
+			c.cbCount--
+			if c.cbCount == 0 {
+				close(c.cbIdle)
+			}
+			c.cbMu.Unlock()
+		}()
+		cb()
+	}()
+}
+
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
+
+// Returns a channel that's closed once no callback is running.
+func (c *Conn) callbacksIdle() <-chan struct{} {
+	c.cbMu.Lock()
+	defer c.cbMu.Unlock()
+	if c.cbCount == 0 {
+		return closedChan
+	}
+	return c.cbIdle
+}
+
+// Sets the function called with errors which can't be returned to anyone, e.g. EventError.
+// By default they are logged.
+func (c *Conn) SetErrorHandler(handler func(err error)) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	c.errHandler = handler
+}
+
+// In strict mode, an event which can't be unmarshaled closes the connection, and Err returns
+// the EventError. Useful in tests to catch protocol drift.
+func (c *Conn) SetStrictEvents(strict bool) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	c.strictEvents = strict
+}
+
+// In ordered mode, Done of a command, and so Run of a generated command, is called only after
+// every OnEvent call for events which were received after the command was sent and before its
+// response has returned. E.g. once protocol.RequestChildNodes returns, sinks have seen the
+// DOM.setChildNodes events it caused, whichever goroutine got to run first. Events received
+// before the command was sent aren't waited for, so sinks may run commands themselves. A sink
+// must not wait for the caller of such a command though, e.g. on a mutex held while running it,
+// or both wait forever. Commands of Sessions aren't ordered. Off by default; it affects commands
+// sent after it's changed.
+func (c *Conn) SetOrderedResponses(ordered bool) {
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	c.orderedResponses = ordered
+}
+
+// Overrides the package default set by SetStrictDecoding for this connection.
+func (c *Conn) SetStrictDecoding(strict bool) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	c.strictDecoding = &strict
+}
+
+func (c *Conn) StrictDecoding() bool {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.strictDecoding != nil {
+		return *c.strictDecoding
+	}
+	return atomic.LoadInt32(&strictDecoding) != 0
+}
+
+func (c *Conn) checkSchema(method string, event bool, data []byte) {
+	if schemaChecker == nil || !c.StrictDecoding() {
+		return
+	}
+	c.runCallback(func() {
+		if err := schemaChecker(method, event, data); err != nil {
+			c.reportError(err)
+		}
+	})
+}
+
+// Don't call this. Functions from protocol package call it when failed to unmarshal events.
+func (c *Conn) ReportEventError(name string, params []byte, err error) {
+	evtErr := &EventError{Name: name, Params: params, Err: err}
+	c.errMu.Lock()
+	c.eventErrorsMap[name]++
+	strict := c.strictEvents
+	if strict && c.closeCause == nil {
+		c.closeCause = evtErr
+	}
+	c.errMu.Unlock()
+
+	c.reportError(evtErr)
+	if strict {
+		// Don't wait for callbacks, as we are in one.
+		c.shutdown()
+	}
+}
+
+// Closes the connection because its target went away, so that Err returns TargetClosedError
+// with reason. The connection does this itself on Inspector.detached. Call it when the target is
+// known to be gone otherwise, e.g. from Target.targetDestroyed on a browser connection.
+func (c *Conn) TargetClosed(reason string) {
+	c.errMu.Lock()
+	if c.closeCause == nil && !c.isClosed() {
+		c.closeCause = &TargetClosedError{Reason: reason}
+	}
+	c.errMu.Unlock()
+	c.shutdown()
+}
+
+// Passes err, which can't be returned to anyone, e.g. a malformed payload a helper recovered
+// from, to the error handler set by SetErrorHandler, or logs it.
+func (c *Conn) ReportError(err error) {
+	c.reportError(err)
+}
+
+// Passes err to the error handler, or logs it. See SetErrorLogThrottle.
+func (c *Conn) reportError(err error) {
+	c.errMu.Lock()
+	handler := c.errHandler
+	c.errMu.Unlock()
+	if handler != nil {
+		handler(err)
+	} else {
+		c.logError(errorMethod(err), err)
+	}
+}
+
+func (c *Conn) Stats() ConnStats {
+	// Not under errMu, which SendCommand may take with helperObjMu held.
+	c.helperObjMu.Lock()
+	created, released := c.helperObjs.created, c.helperObjs.released
+	c.helperObjMu.Unlock()
+	c.throttleMu.Lock()
+	throttled, queueTime := c.evalThrottle.throttled, c.evalThrottle.queueTime
+	c.throttleMu.Unlock()
+	sinks := c.sinkCounts()
+	c.cmdMu.Lock()
+	timeouts, late := c.commandTimeouts, c.lateResponses
+	c.cmdMu.Unlock()
+
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	stats := ConnStats{
+		EventErrors:           make(map[string]int, len(c.eventErrorsMap)),
+		OversizedSends:        c.oversizedSends,
+		OversizedRecvs:        c.oversizedRecvs,
+		SuppressedErrors:      c.errLog.suppressed(),
+		HelperObjectsCreated:  created,
+		HelperObjectsReleased: released,
+		ThrottledEvaluations:  throttled,
+		EvaluationQueueTime:   queueTime,
+		EventSinks:            sinks,
+		CommandTimeouts:       timeouts,
+		LateResponses:         late,
+	}
+	for name, count := range c.eventErrorsMap {
+		stats.EventErrors[name] = count
+	}
+	return stats
+}
+
+// Returns nil if the connection is open, otherwise why it was closed.
+func (c *Conn) Err() error {
+	if !c.isClosed() {
+		return nil
+	}
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.closeCause != nil {
+		return c.closeCause
+	}
+	return ErrConnClosed
+}
+
+// Sets the max sizes in bytes of messages sent and received. 0 means unlimited. Commands whose
+// params or responses are bigger fail with MessageSizeError.
+func (c *Conn) SetMaxMessageSizes(send, recv int) {
+	c.sizeMu.Lock()
+	defer c.sizeMu.Unlock()
+	c.maxSendSize, c.maxRecvSize = send, recv
+}
+
+func (c *Conn) MaxMessageSizes() (send, recv int) {
+	c.sizeMu.Lock()
+	defer c.sizeMu.Unlock()
+	return c.maxSendSize, c.maxRecvSize
+}
+
+// Domains which have to be enabled before their commands work or their events fire, and the
+// domains they depend on.
+var enableableDomains = map[string][]string{
+	"Animation": nil, "ApplicationCache": nil, "CSS": {"DOM"}, "Console": nil, "DOM": nil,
+	"DOMStorage": nil, "Database": nil, "Debugger": nil, "HeapProfiler": nil, "IndexedDB": nil,
+	"Inspector": nil, "LayerTree": nil, "Log": nil, "Network": nil, "Page": nil, "Profiler": nil,
+	"Runtime": nil, "Security": nil, "ServiceWorker": nil,
+}
+
+// If set, the domain of a command or an event is enabled with default params before the first
+// command is sent or the first event sink is added, unless it has been enabled already.
+func (c *Conn) SetAutoEnableDomains(auto bool) {
+	c.domainMu.Lock()
+	defer c.domainMu.Unlock()
+	c.autoEnableDomains = auto
+}
+
+// Returns the domains enabled by "<Domain>.enable" commands, either explicitly or
+// automatically, mapped to whether it was automatic.
+func (c *Conn) EnabledDomains() map[string]bool {
+	c.domainMu.Lock()
+	defer c.domainMu.Unlock()
+	domains := make(map[string]bool, len(c.enabledDomainMap))
+	for domain, auto := range c.enabledDomainMap {
+		domains[domain] = auto
+	}
+	return domains
+}
+
+// Keeps track of enabled domains, and enables the domain of method if necessary.
+func (c *Conn) trackDomain(method string) {
+	dot := strings.IndexByte(method, '.')
+	if dot < 0 {
+		return
+	}
+	domain := method[:dot]
+	if _, ok := enableableDomains[domain]; !ok {
+		return
+	}
+	c.domainMu.Lock()
+	switch method[dot+1:] {
+	case "enable":
+		c.enabledDomainMap[domain] = false
+		c.pinDomainLocked(domain)
+		c.domainMu.Unlock()
+		return
+	case "disable":
+		delete(c.enabledDomainMap, domain)
+		c.domainMu.Unlock()
+		return
+	}
+	_, enabled := c.enabledDomainMap[domain]
+	auto := c.autoEnableDomains
+	c.domainMu.Unlock()
+	if !enabled && auto {
+		c.autoEnable(domain)
+	}
+}
+
+func (c *Conn) autoEnable(domain string) {
+	for _, dep := range enableableDomains[domain] {
+		c.domainMu.Lock()
+		_, enabled := c.enabledDomainMap[dep]
+		c.domainMu.Unlock()
+		if !enabled {
+			c.autoEnable(dep)
+		}
+	}
+	c.domainMu.Lock()
+	if _, enabled := c.enabledDomainMap[domain]; enabled {
+		// Enabled by someone else meanwhile.
+		c.domainMu.Unlock()
+		return
+	}
+	c.enabledDomainMap[domain] = true
+	c.domainMu.Unlock()
+	logging.Vlogf(2, "Enabling %s automatically.", domain)
+	// Commands are written in order, so this goes before the one triggering it.
+	c.SendCommandWithPriority(&enableCommand{c, domain}, PriorityNormal)
+}
+
+type enableCommand struct {
+	conn   *Conn
+	domain string
+}
+
+func (cmd *enableCommand) Name() string {
+	return cmd.domain + ".enable"
+}
+
+func (cmd *enableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *enableCommand) Done(result []byte, err error) {
+	if err != nil {
+		cmd.conn.logError(cmd.Name(), fmt.Errorf("Failed to enable %s: %v", cmd.domain, err))
+	}
+}
+
+// Returns a channel that's closed when the connection is closed.
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+func (c *Conn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the value associated with key. If there is none, create is called to make one, unless
+// it's nil. Helper packages use this to keep per connection state.
+func (c *Conn) Value(key interface{}, create func() interface{}) interface{} {
+	c.valueMu.Lock()
+	defer c.valueMu.Unlock()
+	value, ok := c.valueMap[key]
+	if !ok && create != nil {
+		value = create()
+		c.valueMap[key] = value
+	}
+	return value
+}
+
+// Associates value with key, replacing the existing one if any.
+func (c *Conn) SetValue(key, value interface{}) {
+	c.valueMu.Lock()
+	defer c.valueMu.Unlock()
+	c.valueMap[key] = value
 }
 
 type CommandJson struct {
@@ -64,36 +764,228 @@ type CommandJson struct {
 	Params interface{} `json:"params"`
 }
 
-func (c *Conn) SendCommand(cmd Command) {
+// Sends cmd. Its Done is called exactly once: with the response, or with the error if the
+// command fails. If it fails without being sent, e.g. its params can't be marshaled, the
+// connection is closed, or the kind of target doesn't support it (see WrongTargetKindError),
+// Done is called before returning, and the error is returned too. If the response doesn't come
+// within the default timeout, Done gets ErrCommandTimeout, see SetDefaultTimeout.
+func (c *Conn) SendCommand(cmd Command) error {
+	return c.SendCommandWithPriority(cmd, PriorityNormal)
+}
+
+func (c *Conn) SendCommandWithPriority(cmd Command, prio Priority) error {
+	c.cmdMu.Lock()
+	timeout := c.defaultTimeout
+	c.cmdMu.Unlock()
+	return c.sendCommand(cmd, prio, timeout)
+}
+
+func (c *Conn) sendCommand(cmd Command, prio Priority, timeout time.Duration) error {
+	if err := CheckTargetKind(c.kind, cmd.Name()); err != nil {
+		cmd.Done(nil, err)
+		return err
+	}
+	if evaluationMethods[cmd.Name()] {
+		if err := c.throttleAllEvaluations(); err != nil {
+			cmd.Done(nil, err)
+			return err
+		}
+	}
+	switch cmd.(type) {
+	case *enableCommand, *domainCommand:
+		// They keep track themselves.
+	default:
+		c.trackDomain(cmd.Name())
+	}
+	// Marshal here instead of in writeLoop, so oversized params fail only this command.
+	// PrecomputedParams and KeyedParams seen before aren't marshaled again.
+	var params json.RawMessage
+	if p := cmd.Params(); p != nil {
+		var err error
+		if params, err = sharedParamsCache.marshal(cmd.Name(), p); err != nil {
+			cmd.Done(nil, err)
+			return err
+		}
+		if limit, _ := c.MaxMessageSizes(); limit > 0 && len(params) > limit {
+			c.errMu.Lock()
+			c.oversizedSends++
+			c.errMu.Unlock()
+			err := &MessageSizeError{Method: cmd.Name(), Size: len(params), Limit: limit}
+			cmd.Done(nil, err)
+			return err
+		}
+	}
+
 	c.cmdMu.Lock()
+	if c.isClosed() {
+		c.cmdMu.Unlock()
+		cmd.Done(nil, ErrConnClosed)
+		return ErrConnClosed
+	}
 	defer c.cmdMu.Unlock()
 
 	c.nextCmdId++
 	cj := &CommandJson{
 		Id:     c.nextCmdId,
 		Method: cmd.Name(),
-		Params: cmd.Params(),
 	}
-	logging.Vlogf(3, "SendCommand %#v", cj)
-	if err := c.conn.WriteJSON(cj); err != nil {
-		cmd.Done(nil, err)
-		return
+	if params != nil {
+		cj.Params = params
 	}
+	logging.Vlogf(3, "SendCommand %#v", cj)
 	c.pendingCmdMap[c.nextCmdId] = cmd
-	// TODO: Implement timeout.
+	c.pendingSentMap[c.nextCmdId] = time.Now()
+	c.evtMu.Lock()
+	if c.orderedResponses {
+		c.sentSeqMap[c.nextCmdId] = c.lastEvtSeq
+	}
+	c.evtMu.Unlock()
+	if timeout > 0 {
+		id := c.nextCmdId
+		c.pendingTimerMap[id] = time.AfterFunc(timeout, func() { c.timeOutCommand(id, timeout) })
+	}
+
+	c.writeMu.Lock()
+	c.writeQueues[prio] = append(c.writeQueues[prio], cj)
+	c.writeCond.Signal()
+	c.writeMu.Unlock()
+	return nil
+}
+
+// Returns the next command to write, or nil if the connection is closed.
+func (c *Conn) nextToWrite(normalStreak *int) *CommandJson {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	for len(c.writeQueues[PriorityNormal]) == 0 && len(c.writeQueues[PriorityLow]) == 0 {
+		if c.isClosed() {
+			return nil
+		}
+		c.writeCond.Wait()
+	}
+	prio := PriorityNormal
+	if len(c.writeQueues[PriorityNormal]) == 0 ||
+		(len(c.writeQueues[PriorityLow]) > 0 && *normalStreak >= maxNormalStreak) {
+		prio = PriorityLow
+		*normalStreak = 0
+	} else {
+		*normalStreak++
+	}
+	cj := c.writeQueues[prio][0]
+	c.writeQueues[prio][0] = nil
+	c.writeQueues[prio] = c.writeQueues[prio][1:]
+	return cj
+}
+
+func (c *Conn) writeLoop() {
+	normalStreak := 0
+	for {
+		cj := c.nextToWrite(&normalStreak)
+		if cj == nil {
+			return
+		}
+		data, err := cj.encode()
+		if err == nil {
+			err = c.conn.WriteMessage(websocket.TextMessage, data)
+		}
+		if err != nil {
+			c.handleResp(cj.Id, err, nil)
+		}
+	}
 }
 
 // Don't call this. Use functions from protocol package.
 func (c *Conn) AddEventSink(name string, sink EventSink) {
+	c.trackDomain(name)
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	c.addEventSinkLocked(name, sink)
+}
+
+// One-shot page lifecycle events, which are remembered for sinks added after they fired.
+// Page.frameStoppedLoading is only remembered for the main frame.
+var stickyEventNames = map[string]bool{
+	"Page.loadEventFired":       true,
+	"Page.domContentEventFired": true,
+	"Page.frameStoppedLoading":  true,
+}
+
+// Like AddEventSink, but if the event is sticky and already fired for the current document of
+// the main frame, sink is called with it right away. This avoids missing the event when the
+// page loads before the sink is added.
+// Don't call this. Use functions from protocol package.
+func (c *Conn) AddStickyEventSink(name string, sink EventSink) {
+	c.trackDomain(name)
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	c.addEventSinkLocked(name, sink)
+	if evt, ok := c.stickyMap[name]; ok {
+		c.runCallback(func() { deliverEvent(sink, evt.meta, name, evt.params) })
+	}
+}
+
+// Forgets the sticky events of the current document, e.g. after its load was stopped, so that
+// sinks added afterwards only get them if they fire again.
+func (c *Conn) ForgetStickyEvents() {
 	c.evtMu.Lock()
 	defer c.evtMu.Unlock()
+	c.stickyMap = make(map[string]*stickyEvent)
+}
+
+type stickyEvent struct {
+	meta   EventMeta
+	params []byte
+}
+
+// Remembers sticky events, and forgets them once the main frame starts loading another
+// document. Called with evtMu held.
+func (c *Conn) updateStickyLocked(meta EventMeta, name string, params []byte) {
+	var evt struct {
+		FrameId string `json:"frameId"`
+		Frame   struct {
+			Id       string `json:"id"`
+			ParentId string `json:"parentId"`
+		} `json:"frame"`
+	}
+	switch name {
+	case "Page.frameNavigated", "Page.frameStartedLoading", "Page.frameStoppedLoading":
+		if err := json.Unmarshal(params, &evt); err != nil {
+			c.logError(name, err)
+			return
+		}
+	}
+	switch name {
+	case "Page.frameNavigated":
+		if evt.Frame.ParentId == "" {
+			c.mainFrameId = evt.Frame.Id
+			c.stickyMap = make(map[string]*stickyEvent)
+		}
+	case "Page.frameStartedLoading":
+		if evt.FrameId == c.mainFrameId {
+			c.stickyMap = make(map[string]*stickyEvent)
+		}
+	case "Page.frameStoppedLoading":
+		if c.mainFrameId == "" || evt.FrameId == c.mainFrameId {
+			c.stickyMap[name] = &stickyEvent{meta, params}
+		}
+	default:
+		if stickyEventNames[name] {
+			c.stickyMap[name] = &stickyEvent{meta, params}
+		}
+	}
+}
+
+func (c *Conn) addEventSinkLocked(name string, sink EventSink) {
 	sinks := c.evtSinkMap[name]
 	for _, s := range sinks {
 		if s == sink {
 			return
 		}
 	}
-	c.evtSinkMap[name] = append(sinks, sink)
+	newSinks := make([]EventSink, len(sinks), len(sinks)+1)
+	copy(newSinks, sinks)
+	c.evtSinkMap[name] = append(newSinks, sink)
+	site := c.checkSinkLocked(name, len(sinks)+1)
+	c.evtSinkSiteMap[name] = append(c.evtSinkSiteMap[name], site)
 }
 
 // Don't call this. Use functions from protocol package.
@@ -103,14 +995,54 @@ func (c *Conn) RemoveEventSink(name string, sink EventSink) {
 	sinks := c.evtSinkMap[name]
 	for i, s := range sinks {
 		if s == sink {
-			l := len(sinks)
-			sinks[i] = sinks[l-1]
-			c.evtSinkMap[name] = sinks[:l-1]
+			if len(sinks) == 1 {
+				delete(c.evtSinkMap, name)
+				delete(c.evtSinkSiteMap, name)
+				return
+			}
+			newSinks := make([]EventSink, 0, len(sinks)-1)
+			newSinks = append(newSinks, sinks[:i]...)
+			c.evtSinkMap[name] = append(newSinks, sinks[i+1:]...)
+			sites := c.evtSinkSiteMap[name]
+			c.evtSinkSiteMap[name] = append(sites[:i:i], sites[i+1:]...)
 			return
 		}
 	}
 }
 
+type eventHook struct {
+	fn func(params []byte)
+}
+
+// Calls hook with the params of each event name on the goroutine reading from the browser,
+// before any EventSink, in the order events arrived. It stalls the connection meanwhile, so it
+// must be quick and never wait for anything, e.g. only send an ack without waiting for it, and
+// leave the rest to sinks. Call remove once done.
+func (c *Conn) AddEventHook(name string, hook func(params []byte)) (remove func()) {
+	h := &eventHook{hook}
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	hooks := c.evtHookMap[name]
+	c.evtHookMap[name] = append(append(make([]*eventHook, 0, len(hooks)+1), hooks...), h)
+	return func() {
+		c.evtMu.Lock()
+		defer c.evtMu.Unlock()
+		hooks := c.evtHookMap[name]
+		for i, hh := range hooks {
+			if hh == h {
+				newHooks := make([]*eventHook, 0, len(hooks)-1)
+				newHooks = append(append(newHooks, hooks[:i]...), hooks[i+1:]...)
+				if len(newHooks) == 0 {
+					delete(c.evtHookMap, name)
+				} else {
+					c.evtHookMap[name] = newHooks
+				}
+				return
+			}
+		}
+	}
+}
+
 type simpleEventSink struct {
 	cb func(name string, params []byte)
 }
@@ -123,39 +1055,250 @@ func FuncToEventSink(cb func(name string, params []byte)) EventSink {
 	return &simpleEventSink{cb}
 }
 
-func (c *Conn) handleResp(id int, errStr string, result []byte) {
-	logging.Vlogf(3, "handleResp %d %s %s", id, string(result), errStr)
-	c.cmdMu.Lock()
-	defer c.cmdMu.Unlock()
+type simpleMetaEventSink struct {
+	cb func(meta EventMeta, name string, params []byte)
+}
+
+func (s *simpleMetaEventSink) OnEvent(name string, params []byte) {
+	s.cb(EventMeta{}, name, params)
+}
+
+func (s *simpleMetaEventSink) OnEventMeta(meta EventMeta, name string, params []byte) {
+	s.cb(meta, name, params)
+}
+
+func FuncToMetaEventSink(cb func(meta EventMeta, name string, params []byte)) EventSink {
+	return &simpleMetaEventSink{cb}
+}
 
-	if cmd, ok := c.pendingCmdMap[id]; !ok {
-		logging.Vlogf(0, "Unknown command %d: result=%s err=%s", id, string(result), errStr)
+func deliverEvent(sink EventSink, meta EventMeta, name string, params []byte) {
+	if s, ok := sink.(MetaEventSink); ok {
+		s.OnEventMeta(meta, name, params)
 	} else {
-		delete(c.pendingCmdMap, id)
-		var err error
-		if errStr != "" {
-			err = errors.New(errStr)
+		sink.OnEvent(name, params)
+	}
+}
+
+func (c *Conn) handleResp(id int, err error, result []byte) {
+	logging.Vlogf(3, "handleResp %d %s %v", id, string(result), err)
+	var method string
+	if !c.finishCommand(id, result, func(cmd Command) error {
+		method = cmd.Name()
+		return err
+	}) {
+		if c.timedOut(id) {
+			logging.Vlogf(1, "Discarding late response of command %d", id)
+		} else {
+			logging.Vlogf(0, "Unknown command %d: result=%s err=%v", id, string(result), err)
 		}
-		go cmd.Done(result, err)
+	} else if err == nil {
+		c.checkSchema(method, false, result)
 	}
 }
 
-func (c *Conn) handleEvent(name string, params []byte) {
-	logging.Vlogf(3, "handleEvent %s %s", name, string(params))
-	if name == "Inspector.targetCrashed" {
-		logging.Fatal("Chrome has crashed!")
+// Calls Done of pending command id with result and the error returned by getErr, which is
+// called with cmdMu held. Returns false if there is no such command.
+func (c *Conn) finishCommand(id int, result []byte, getErr func(cmd Command) error) bool {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	cmd, ok := c.pendingCmdMap[id]
+	if !ok {
+		return false
 	}
+	delete(c.pendingCmdMap, id)
+	delete(c.pendingSentMap, id)
+	if timer := c.pendingTimerMap[id]; timer != nil {
+		timer.Stop()
+		delete(c.pendingTimerMap, id)
+	}
+	err := getErr(cmd)
+	// Called from readLoop, so events received before the response have lower sequence numbers.
+	c.evtMu.Lock()
+	sentSeq, ordered := c.sentSeqMap[id]
+	delete(c.sentSeqMap, id)
+	lastSeq := c.lastEvtSeq
+	c.evtMu.Unlock()
+	c.runCallback(func() {
+		if ordered {
+			c.waitEventsDelivered(sentSeq, lastSeq)
+		}
+		cmd.Done(result, err)
+	})
+	return true
+}
+
+// Waits till every OnEvent call for events with sequence numbers in (after, upTo] has returned.
+func (c *Conn) waitEventsDelivered(after, upTo uint64) {
 	c.evtMu.Lock()
 	defer c.evtMu.Unlock()
+	for {
+		pending := false
+		for seq := range c.evtInFlight {
+			if seq > after && seq <= upTo {
+				pending = true
+				break
+			}
+		}
+		if !pending {
+			return
+		}
+		c.evtCond.Wait()
+	}
+}
+
+// Only called from readLoop, so sequence numbers follow the arrival order.
+func (c *Conn) handleEvent(received time.Time, size int, name string, params []byte) {
+	logging.Vlogf(3, "handleEvent %s %s", name, string(params))
+	c.evtMu.Lock()
+	c.lastEvtSeq++
+	meta := EventMeta{Seq: c.lastEvtSeq, Received: received, Size: size}
+	c.updateStickyLocked(meta, name, params)
 	sinks := c.evtSinkMap[name]
+	if len(sinks) > 0 {
+		c.evtInFlight[meta.Seq] = len(sinks)
+	}
+	hooks := c.evtHookMap[name]
+	c.evtMu.Unlock()
+	for _, hook := range hooks {
+		hook.fn(params)
+	}
+	c.checkSchema(name, true, params)
 	for _, sink := range sinks {
-		go sink.OnEvent(name, params)
+		sink := sink
+		if !c.runCallback(func() {
+			defer c.eventDelivered(meta.Seq)
+			deliverEvent(sink, meta, name, params)
+		}) {
+			c.eventDelivered(meta.Seq)
+		}
+	}
+	switch name {
+	case "Inspector.detached":
+		var evt struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal(params, &evt); err != nil {
+			c.logError(name, err)
+		}
+		c.TargetClosed(evt.Reason)
+	case "Inspector.targetCrashed":
+		logging.Vlog(-1, "Target crashed!")
+		c.TargetClosed("target crashed")
+	}
+}
+
+func (c *Conn) eventDelivered(seq uint64) {
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	if c.evtInFlight[seq]--; c.evtInFlight[seq] <= 0 {
+		delete(c.evtInFlight, seq)
+		c.evtCond.Broadcast()
+	}
+}
+
+// Waits till the browser has answered a round trip command, and every OnEvent call for events
+// received before the answer has returned. So callbacks have seen everything the browser sent
+// before Flush was called. Events received after the answer, calls made by AddStickyEventSink
+// for events fired before the sink was added, and Done callbacks of commands aren't waited for.
+// The command is Schema.getDomains, whose result doesn't matter, so it works on any connection.
+func (c *Conn) Flush(ctx context.Context) error {
+	done := make(chan error, 1)
+	if err := c.SendCommand(&flushCommand{done}); err != nil {
+		return c.closedErr(err)
+	}
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err == ErrConnClosed {
+		return c.closedErr(err)
+	}
+	// The response was read after every event before it, which got lower sequence numbers.
+	c.evtMu.Lock()
+	last := c.lastEvtSeq
+	c.evtMu.Unlock()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		c.evtMu.Lock()
+		pending := false
+		for seq := range c.evtInFlight {
+			if seq <= last {
+				pending = true
+				break
+			}
+		}
+		c.evtMu.Unlock()
+		if !pending {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Returns Err if err is ErrConnClosed and the connection was closed for a reason.
+func (c *Conn) closedErr(err error) error {
+	if err == ErrConnClosed {
+		if cause := c.Err(); cause != nil {
+			return cause
+		}
 	}
+	return err
+}
+
+type flushCommand struct {
+	done chan error
+}
+
+func (cmd *flushCommand) Name() string {
+	return "Schema.getDomains"
+}
+
+func (cmd *flushCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *flushCommand) Done(result []byte, err error) {
+	cmd.done <- err
 }
 
 type ErrorJson struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// Details, e.g. which params are invalid.
+	Data string `json:"data"`
+}
+
+func (e *ErrorJson) String() string {
+	if e.Message != "" && e.Data != "" {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Data)
+	}
+	return e.Message
+}
+
+// JSON-RPC error code of commands the browser doesn't know.
+const CodeMethodNotFound = -32601
+
+// The error the browser answered a command with.
+type CommandError struct {
+	ErrorJson
+}
+
+func (e *CommandError) Error() string {
+	return e.String()
+}
+
+// Reports whether err is the browser answering that it doesn't know the command, e.g. one newer
+// than its protocol.
+func IsMethodNotFound(err error) bool {
+	var cmdErr *CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == CodeMethodNotFound
 }
 
 type MessageJson struct {
@@ -167,18 +1310,86 @@ type MessageJson struct {
 }
 
 func (c *Conn) readLoop() {
+	// Fail all pending commands once the browser goes away.
+	defer c.shutdown()
 	for {
-		mj := &MessageJson{}
-		if err := c.conn.ReadJSON(mj); err != nil {
-			if err == io.EOF || websocket.IsCloseError(err, 1006) ||
-				strings.Contains(err.Error(), "use of closed network connection") {
-				break
+		data, err := c.readMessage()
+		received := time.Now()
+		if err != nil {
+			if err != io.EOF && !websocket.IsCloseError(err, 1006) && !c.isClosed() &&
+				!strings.Contains(err.Error(), "use of closed network connection") {
+				// The underlying connection is broken.
+				logging.Vlog(-1, err)
 			}
-			logging.Vlog(-1, err)
-		} else if mj.Id > 0 {
-			c.handleResp(mj.Id, mj.Error.Message, []byte(mj.Result))
-		} else {
-			c.handleEvent(mj.Method, []byte(mj.Params))
+			break
+		}
+		if data != nil {
+			c.handleMessage(data, received)
+		}
+	}
+}
+
+// Handles a message from the browser, which may be anything, e.g. a truncated frame.
+func (c *Conn) handleMessage(data []byte, received time.Time) {
+	mj := &MessageJson{}
+	if err := json.Unmarshal(data, mj); err != nil {
+		c.logError("", err)
+	} else if mj.Id > 0 {
+		var err error
+		if mj.Error.Code != 0 || mj.Error.Message != "" {
+			err = &CommandError{ErrorJson: mj.Error}
+		}
+		c.handleResp(mj.Id, err, []byte(mj.Result))
+	} else if mj.Method != "" {
+		c.handleEvent(received, len(data), mj.Method, []byte(mj.Params))
+	} else {
+		c.logError("", fmt.Errorf("Message without id or method: %.100s", data))
+	}
+}
+
+var idPrefixRe = regexp.MustCompile(`^\s*\{\s*"id"\s*:\s*(\d+)`)
+var methodPrefixRe = regexp.MustCompile(`^\s*\{\s*"method"\s*:\s*"([^"]*)"`)
+
+// Reads the next message. Returns nil data if it's too big, in which case it is skipped without
+// being kept in memory.
+func (c *Conn) readMessage() ([]byte, error) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	_, limit := c.MaxMessageSizes()
+	if limit <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil || len(data) <= limit {
+		return data, err
+	}
+	rest, err := io.Copy(ioutil.Discard, r)
+	if err != nil {
+		return nil, err
+	}
+	c.handleOversized(data, len(data)+int(rest), limit)
+	return nil, nil
+}
+
+// Fails the command the oversized message responds to, if it can be told from the beginning of
+// the message. Chromium always puts "id" or "method" first.
+func (c *Conn) handleOversized(prefix []byte, size, limit int) {
+	c.errMu.Lock()
+	c.oversizedRecvs++
+	c.errMu.Unlock()
+	if m := idPrefixRe.FindSubmatch(prefix); m != nil {
+		id, _ := strconv.Atoi(string(m[1]))
+		if c.finishCommand(id, nil, func(cmd Command) error {
+			return &MessageSizeError{Method: cmd.Name(), Size: size, Limit: limit, Incoming: true}
+		}) {
+			return
 		}
 	}
+	err := &MessageSizeError{Size: size, Limit: limit, Incoming: true}
+	if m := methodPrefixRe.FindSubmatch(prefix); m != nil {
+		err.Method = string(m[1])
+	}
+	c.reportError(err)
 }
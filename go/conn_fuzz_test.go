@@ -0,0 +1,49 @@
+package headless_chromium_test
+
+import (
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Feeds arbitrary messages to a connection as if the browser sent them. Nothing may panic, and
+// the connection must still close promptly. Seeds are in testdata/fuzz/FuzzHandleMessage.
+func FuzzHandleMessage(f *testing.F) {
+	f.Add([]byte(`{"id":1,"result":{}}`))
+	f.Add([]byte(`{"method":"Page.loadEventFired","params":{"timestamp":1}}`))
+	server := hctest.NewFakeServer(f)
+	server.Handle("Test.hang", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, hctest.ErrNoReply
+	})
+	browser := server.Browser()
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		conn, err := browser.NewPageConn(hctest.FakePageId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.SetErrorHandler(func(error) {})
+		// Pending with id 1, so that responses have something to match.
+		cmd := newTestCommand("Test.hang", nil)
+		if err := conn.SendCommand(cmd); err != nil {
+			t.Fatal(err)
+		}
+		protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {})
+		protocol.OnFrameNavigated(conn, func(*protocol.FrameNavigatedEvent) {})
+		conn.AddEventSink("Inspector.detached", hc.FuncToEventSink(func(string, []byte) {}))
+
+		conn.HandleMessage(data)
+		if err := conn.CloseWithTimeout(5 * time.Second); err != nil {
+			t.Fatalf("Closing after %q: %v", data, err)
+		}
+		select {
+		case <-cmd.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Command pending after %q", data)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package headless_chromium
+
+import (
+	"sync"
+	"testing"
+)
+
+// Returns the priorities in which queued commands are written.
+func writeOrder(normal, low int) []Priority {
+	c := &Conn{closed: make(chan struct{})}
+	c.writeCond = sync.NewCond(&c.writeMu)
+	for i := 0; i < normal; i++ {
+		c.writeQueues[PriorityNormal] = append(c.writeQueues[PriorityNormal],
+			&CommandJson{Method: "normal"})
+	}
+	for i := 0; i < low; i++ {
+		c.writeQueues[PriorityLow] = append(c.writeQueues[PriorityLow], &CommandJson{Method: "low"})
+	}
+	var order []Priority
+	normalStreak := 0
+	for i := 0; i < normal+low; i++ {
+		prio := PriorityNormal
+		if c.nextToWrite(&normalStreak).Method == "low" {
+			prio = PriorityLow
+		}
+		order = append(order, prio)
+	}
+	return order
+}
+
+func TestWritePriority(t *testing.T) {
+	// Low priority commands yield to normal ones, but one gets through after every
+	// maxNormalStreak normal ones.
+	order := writeOrder(3*maxNormalStreak, 100)
+	for i, prio := range order[:3*(maxNormalStreak+1)] {
+		want := PriorityNormal
+		if i%(maxNormalStreak+1) == maxNormalStreak {
+			want = PriorityLow
+		}
+		if prio != want {
+			t.Fatalf("Command %d written with priority %d, not %d: %v", i, prio, want, order)
+		}
+	}
+	for i, prio := range order[3*(maxNormalStreak+1):] {
+		if prio != PriorityLow {
+			t.Fatalf("Normal command left at %d: %v", i, order)
+		}
+	}
+
+	// Without normal commands, low ones flow.
+	for _, prio := range writeOrder(0, 10) {
+		if prio != PriorityLow {
+			t.Fatal("Normal command written without any queued")
+		}
+	}
+}
@@ -0,0 +1,609 @@
+package headless_chromium_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// A command of any method, whose Done can be waited for.
+type testCommand struct {
+	method string
+	params interface{}
+	done   chan struct{}
+	calls  int32
+	result []byte
+	err    error
+}
+
+func newTestCommand(method string, params interface{}) *testCommand {
+	return &testCommand{method: method, params: params, done: make(chan struct{})}
+}
+
+func (cmd *testCommand) Name() string {
+	return cmd.method
+}
+
+func (cmd *testCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *testCommand) Done(result []byte, err error) {
+	if atomic.AddInt32(&cmd.calls, 1) > 1 {
+		// Can't fail the test from here, the caller checks calls.
+		return
+	}
+	cmd.result, cmd.err = result, err
+	close(cmd.done)
+}
+
+// Sends cmd and waits for it, failing the test if that takes longer than timeout.
+func (cmd *testCommand) run(t testing.TB, runner hc.CommandRunner, timeout time.Duration) error {
+	runner.SendCommand(cmd)
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-time.After(timeout):
+		t.Errorf("%s didn't finish in %v", cmd.method, timeout)
+		return nil
+	}
+}
+
+func echo(cmd *hctest.FakeCommand) (interface{}, error) {
+	return cmd.Params, nil
+}
+
+type echoParams struct {
+	N int `json:"n"`
+}
+
+func TestConcurrentUse(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.echo", echo)
+	conn, fake := server.NewPageConn()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	// The browser keeps sending events.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := fake.Emit("Test.event", echoParams{i}); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Sinks adding and removing sinks, and running commands, from inside callbacks.
+	var events int32
+	inner := hc.FuncToEventSink(func(string, []byte) {})
+	outer := hc.FuncToEventSink(func(name string, params []byte) {
+		atomic.AddInt32(&events, 1)
+		conn.AddEventSink("Test.event", inner)
+		conn.RemoveEventSink("Test.event", inner)
+		newTestCommand("Test.echo", echoParams{-1}).run(t, conn, 10*time.Second)
+	})
+	conn.AddEventSink("Test.event", outer)
+
+	for g := 0; g < 8; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				n := g*1000 + i
+				cmd := newTestCommand("Test.echo", echoParams{n})
+				if err := cmd.run(t, conn, 10*time.Second); err != nil {
+					t.Error(err)
+					return
+				}
+				var result echoParams
+				if err := json.Unmarshal(cmd.result, &result); err != nil || result.N != n {
+					t.Errorf("Got %s for %d: %v", cmd.result, n, err)
+				}
+			}
+		}()
+	}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				sink := hc.FuncToEventSink(func(string, []byte) {})
+				conn.AddEventSink("Test.event", sink)
+				conn.Stats()
+				conn.SinkReport()
+				conn.SetValue(sink, i)
+				conn.Value(sink, nil)
+				conn.RemoveEventSink("Test.event", sink)
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if atomic.LoadInt32(&events) == 0 {
+		t.Error("No event seen")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Done is called exactly once on every failure path, and synchronous commands never hang.
+func TestSendErrors(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.twice", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		cmd.Conn.Reply(cmd.Id, echoParams{1})
+		cmd.Conn.Reply(cmd.Id, echoParams{2})
+		return nil, hctest.ErrNoReply
+	})
+	conn, _ := server.NewPageConn()
+
+	// Params which can't be marshaled.
+	cmd := newTestCommand("Test.echo", map[string]interface{}{"c": make(chan int)})
+	if err := conn.SendCommand(cmd); err == nil {
+		t.Error("Sent params with a channel")
+	}
+	if calls := atomic.LoadInt32(&cmd.calls); calls != 1 || cmd.err == nil {
+		t.Errorf("Done called %d times, with %v", calls, cmd.err)
+	}
+
+	// A misbehaving server answering twice.
+	cmd = newTestCommand("Test.twice", nil)
+	if err := cmd.run(t, conn, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(conn)
+	if calls := atomic.LoadInt32(&cmd.calls); calls != 1 {
+		t.Errorf("Done called %d times for a double response", calls)
+	}
+	if string(cmd.result) != `{"n":1}` {
+		t.Errorf("Got %s, not the first response", cmd.result)
+	}
+
+	// A closed connection.
+	conn.Close()
+	cmd = newTestCommand("Test.echo", nil)
+	if err := conn.SendCommand(cmd); err != hc.ErrConnClosed {
+		t.Errorf("SendCommand on a closed conn returned %v", err)
+	}
+	if calls := atomic.LoadInt32(&cmd.calls); calls != 1 || cmd.err != hc.ErrConnClosed {
+		t.Errorf("Done called %d times, with %v", calls, cmd.err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != hc.ErrConnClosed {
+			t.Errorf("GetDocument on a closed conn returned %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("GetDocument on a closed conn hung")
+	}
+}
+
+// Errors the browser answers keep their code, whatever the wording of their message.
+func TestCommandErrors(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.unknown", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, &hctest.FakeError{Code: hctest.CodeMethodNotFound, Message: "Method not found"}
+	})
+	server.Handle("Test.invalid", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, &hctest.FakeError{Code: hctest.CodeInvalidParams,
+			Message: "Invalid parameters", Data: "n: integer value expected"}
+	})
+	conn, _ := server.NewPageConn()
+
+	err := newTestCommand("Test.unknown", nil).run(t, conn, 10*time.Second)
+	if !hc.IsMethodNotFound(err) {
+		t.Errorf("Got %v, want method not found", err)
+	}
+	err = newTestCommand("Test.invalid", nil).run(t, conn, 10*time.Second)
+	cmdErr, ok := err.(*hc.CommandError)
+	if !ok || cmdErr.Code != hctest.CodeInvalidParams || hc.IsMethodNotFound(err) ||
+		err.Error() != "Invalid parameters (n: integer value expected)" {
+		t.Errorf("Got %#v", err)
+	}
+	if hc.IsMethodNotFound(fmt.Errorf("'Test.unknown' wasn't found")) {
+		t.Error("Matched an error by its message")
+	}
+
+	// Generated wrappers return no result with errors.
+	server.Handle("DOM.getDocument", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, errors.New("Document not ready")
+	})
+	if result, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn); result != nil ||
+		err == nil {
+		t.Errorf("Got %v, %v", result, err)
+	}
+}
+
+func TestOrderedResponses(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	// Like DOM.requestChildNodes, sends the events it causes, then answers.
+	server.Handle("Test.eventsFirst", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		for i := 0; i < 3; i++ {
+			cmd.Conn.Emit("Test.event", echoParams{i})
+		}
+		return struct{}{}, nil
+	})
+	// Answers, then sends events.
+	server.Handle("Test.eventsLater", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		cmd.Conn.Reply(cmd.Id, struct{}{})
+		cmd.Conn.Emit("Test.event", echoParams{100})
+		return nil, hctest.ErrNoReply
+	})
+	conn, _ := server.NewPageConn()
+	conn.SetOrderedResponses(true)
+	var delivered int32
+	conn.AddEventSink("Test.event", hc.FuncToEventSink(func(name string, params []byte) {
+		var p echoParams
+		json.Unmarshal(params, &p)
+		delay := 20 * time.Millisecond
+		if p.N == 100 {
+			delay = 200 * time.Millisecond
+		}
+		time.Sleep(delay)
+		atomic.AddInt32(&delivered, 1)
+	}))
+
+	for i := 0; i < 5; i++ {
+		atomic.StoreInt32(&delivered, 0)
+		if err := newTestCommand("Test.eventsFirst", nil).run(t, conn, 10*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(&delivered); n != 3 {
+			t.Fatalf("Command returned with %d of 3 events delivered", n)
+		}
+	}
+
+	// Events after the response aren't waited for.
+	atomic.StoreInt32(&delivered, 0)
+	if err := newTestCommand("Test.eventsLater", nil).run(t, conn, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&delivered); n != 0 {
+		t.Errorf("Command waited for %d events sent after its response", n)
+	}
+}
+
+// Returns how many of a flood of big commands of prio, like screencast acks on a busy conn, the
+// browser gets before a normal command sent after them.
+func acksBefore(t *testing.T, prio hc.Priority) int {
+	server := hctest.NewFakeServer(t)
+	// The browser is busy till the flood and the normal command are all sent, so that they
+	// queue up once the socket buffers are full.
+	busy := make(chan struct{})
+	var acks int32
+	server.Handle("Test.ack", func(*hctest.FakeCommand) (interface{}, error) {
+		<-busy
+		atomic.AddInt32(&acks, 1)
+		return struct{}{}, nil
+	})
+	var before int32 = -1
+	server.Handle("Test.interactive", func(*hctest.FakeCommand) (interface{}, error) {
+		atomic.StoreInt32(&before, atomic.LoadInt32(&acks))
+		return struct{}{}, nil
+	})
+	conn, _ := server.NewPageConn()
+	payload := strings.Repeat("x", 256<<10)
+	cmds := make([]*testCommand, 80)
+	for i := range cmds {
+		cmds[i] = newTestCommand("Test.ack", map[string]string{"data": payload})
+		conn.SendCommandWithPriority(cmds[i], prio)
+	}
+	interactive := newTestCommand("Test.interactive", nil)
+	conn.SendCommand(interactive)
+	close(busy)
+	if err := interactive.run(t, conn, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range cmds {
+		<-cmd.done
+	}
+	return int(atomic.LoadInt32(&before))
+}
+
+func TestPriority(t *testing.T) {
+	if n := acksBefore(t, hc.PriorityNormal); n != 80 {
+		t.Errorf("A normal command went ahead of %d normal ones sent before it", 80-n)
+	}
+	// Only those already written to the socket, and maxNormalStreak more, go first.
+	if n := acksBefore(t, hc.PriorityLow); n > 40 {
+		t.Errorf("%d low priority commands went ahead of a normal one", n)
+	}
+}
+
+// Sinks run off the read goroutine, so they may run synchronous commands.
+func TestBlockingCommandInCallback(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("DOM.getDocument", hctest.FakeResult(map[string]interface{}{
+		"root": map[string]interface{}{"nodeId": 1, "nodeName": "#document"},
+	}))
+	conn, fake := server.NewPageConn()
+	type outcome struct {
+		result *protocol.GetDocumentResult
+		err    error
+	}
+	outcomes := make(chan outcome, 1)
+	protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {
+		result, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+		outcomes <- outcome{result, err}
+	})
+	fake.Emit("Page.loadEventFired", map[string]interface{}{"timestamp": 1})
+	select {
+	case o := <-outcomes:
+		if o.err != nil {
+			t.Fatal(o.err)
+		}
+		if o.result.Root.NodeId != 1 {
+			t.Errorf("Got root %+v", o.result.Root)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("GetDocument from a callback hung")
+	}
+}
+
+func TestCloseFailsPendingCommands(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.hang", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, hctest.ErrNoReply
+	})
+	conn, _ := server.NewPageConn()
+
+	const n = 16
+	cmds := make([]*testCommand, n)
+	for i := range cmds {
+		cmds[i] = newTestCommand("Test.hang", nil)
+		if err := conn.SendCommand(cmds[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := roundTrip(conn); err != nil {
+		t.Fatal(err)
+	}
+	// Close from several goroutines at once, while commands are pending.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := conn.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for i, cmd := range cmds {
+		select {
+		case <-cmd.done:
+		default:
+			t.Fatalf("Command %d still pending after Close", i)
+		}
+		if cmd.err != hc.ErrConnClosed {
+			t.Errorf("Command %d failed with %v, not ErrConnClosed", i, cmd.err)
+		}
+		if calls := atomic.LoadInt32(&cmd.calls); calls != 1 {
+			t.Errorf("Done of command %d called %d times", i, calls)
+		}
+	}
+
+	// Commands after Close fail right away.
+	cmd := newTestCommand("Test.hang", nil)
+	if err := conn.SendCommand(cmd); err != hc.ErrConnClosed {
+		t.Errorf("SendCommand after Close returned %v", err)
+	}
+	if cmd.err != hc.ErrConnClosed {
+		t.Errorf("Command after Close failed with %v", cmd.err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Closing again returned %v", err)
+	}
+}
+
+func TestNoCallbackAfterClose(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.slow", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		go func() {
+			time.Sleep(time.Millisecond)
+			cmd.Conn.Reply(cmd.Id, struct{}{})
+		}()
+		return nil, hctest.ErrNoReply
+	})
+	for i := 0; i < 10; i++ {
+		conn, fake := server.NewPageConn()
+		stop := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if fake.Emit("Test.event", echoParams{}) != nil {
+					return
+				}
+			}
+		}()
+		var closed int32
+		check := func(what string) {
+			if atomic.LoadInt32(&closed) != 0 {
+				t.Errorf("%s called after Close returned", what)
+			}
+		}
+		conn.AddEventSink("Test.event", hc.FuncToEventSink(func(string, []byte) {
+			check("OnEvent")
+			time.Sleep(time.Millisecond)
+		}))
+		for j := 0; j < 20; j++ {
+			conn.SendCommand(&callbackCommand{"Test.slow", func(error) { check("Done") }})
+		}
+		time.Sleep(5 * time.Millisecond)
+		if err := conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+		atomic.StoreInt32(&closed, 1)
+		close(stop)
+		// Whatever arrives after Close must not reach callbacks either.
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// An event read while Close runs must not reach sinks once Close has returned.
+func TestNoEventAfterClose(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	hookEntered := make(chan struct{})
+	closeReturned := make(chan struct{})
+	hookDone := make(chan struct{})
+	// Holds up the event on the read goroutine till Close has returned.
+	conn.AddEventHook("Test.event", func([]byte) {
+		close(hookEntered)
+		<-closeReturned
+		close(hookDone)
+	})
+	called := make(chan struct{}, 1)
+	conn.AddEventSink("Test.event", hc.FuncToEventSink(func(string, []byte) {
+		called <- struct{}{}
+	}))
+	fake.Emit("Test.event", echoParams{})
+	<-hookEntered
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(closeReturned)
+	<-hookDone
+	select {
+	case <-called:
+		t.Error("Sink called after Close returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCloseReleasesGoroutines(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.hang", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, hctest.ErrNoReply
+	})
+	baseline := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		conn, fake := server.NewPageConn()
+		conn.AddEventSink("Test.event", hc.FuncToEventSink(func(string, []byte) {}))
+		fake.Emit("Test.event", echoParams{})
+		for j := 0; j < 10; j++ {
+			conn.SendCommand(newTestCommand("Test.hang", nil))
+		}
+		if err := conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= baseline {
+			break
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Fatalf("%d goroutines, %d before:\n%s", n, baseline, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCloseWithTimeout(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{}, 1)
+	conn.AddEventSink("Test.event", hc.FuncToEventSink(func(string, []byte) {
+		entered <- struct{}{}
+		<-release
+	}))
+	fake.Emit("Test.event", echoParams{})
+	<-entered
+	if err := conn.CloseWithTimeout(10 * time.Millisecond); err != hc.ErrCloseTimeout {
+		t.Errorf("CloseWithTimeout returned %v, not ErrCloseTimeout", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.slow", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		time.AfterFunc(20*time.Millisecond, func() { cmd.Conn.Reply(cmd.Id, struct{}{}) })
+		return nil, hctest.ErrNoReply
+	})
+	conn, _ := server.NewPageConn()
+	var done int32
+	for i := 0; i < 5; i++ {
+		conn.SendCommand(&callbackCommand{"Test.slow", func(error) {
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&done, 1)
+		}})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := conn.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&done); n != 5 {
+		t.Errorf("Drain returned with %d of 5 callbacks done", n)
+	}
+	if conn.Err() != nil {
+		t.Errorf("Drain closed the connection: %v", conn.Err())
+	}
+}
+
+// A command calling cb when done.
+type callbackCommand struct {
+	method string
+	cb     func(err error)
+}
+
+func (cmd *callbackCommand) Name() string {
+	return cmd.method
+}
+
+func (cmd *callbackCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *callbackCommand) Done(result []byte, err error) {
+	cmd.cb(err)
+}
+
+// Waits till the fake server has answered every command sent so far, as it answers in order.
+func roundTrip(conn *hc.Conn) error {
+	cmd := newTestCommand("Test.flush", nil)
+	conn.SendCommand(cmd)
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("Flush timed out")
+	}
+}
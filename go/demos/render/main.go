@@ -3,118 +3,31 @@
 package main
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"context"
 	"flag"
-	"image"
-	"image/gif"
-	"image/jpeg"
-	"image/png"
-	"os"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/yijinliu/algo-lib/go/src/logging"
 
 	hc "github.com/yijinliu/headless-chromium/go"
-	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+	"github.com/yijinliu/headless-chromium/go/profiles"
+	"github.com/yijinliu/headless-chromium/go/render"
 )
 
 var hcPortFlag = flag.Int("port", 9222, "")
-var hcBinaryFlag = flag.String("hc-binary", "/usr/local/headless_chromium/bin/hc_server", "")
+var hcBinaryFlag = flag.String("hc-binary", hc.DefaultBinary, "")
 var urlFlag = flag.String("url", "https://en.wikipedia.org/wiki/May_Day", "")
 var outputFlag = flag.String("output", "mayday.jpeg", "")
 var widthFlag = flag.Int("width", 1920, "")
 var heightFlag = flag.Int("height", 1080, "")
-
-func captureScreenshot(conn *hc.Conn, output string) {
-	// Get size of the page.
-	var width, height int
-	if result, err := protocol.Evaluate(
-		&protocol.EvaluateParams{
-			Expression:    "document.scrollingElement.scrollWidth",
-			ReturnByValue: true}, conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	} else if result.ExceptionDetails != nil {
-		logging.Vlogf(-1, "%#v", result.ExceptionDetails)
-		return
-	} else if err := json.Unmarshal([]byte(result.Result.Value), &width); err != nil {
-		logging.Vlog(-1, err)
-		return
-	}
-	if result, err := protocol.Evaluate(
-		&protocol.EvaluateParams{
-			Expression:    "document.scrollingElement.scrollHeight",
-			ReturnByValue: true}, conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	} else if result.ExceptionDetails != nil {
-		logging.Vlogf(-1, "%#v", result.ExceptionDetails)
-		return
-	} else if err := json.Unmarshal([]byte(result.Result.Value), &height); err != nil {
-		logging.Vlog(-1, err)
-		return
-	}
-
-	// Set device size.
-	if err := protocol.EmulationSetDeviceMetricsOverride(
-		&protocol.EmulationSetDeviceMetricsOverrideParams{Width: width, Height: height}, conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	}
-
-	// Force viewport.
-	if err := protocol.ForceViewport(
-		&protocol.ForceViewportParams{X: 0, Y: 0, Scale: 1}, conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	}
-
-	// Set visible size.
-	if err := protocol.SetVisibleSize(
-		&protocol.SetVisibleSizeParams{Width: width, Height: height}, conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	}
-
-	// Capture screenshot.
-	if result, err := protocol.CaptureScreenshot(conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	} else {
-		reader := base64.NewDecoder(base64.StdEncoding, strings.NewReader(result.Data))
-		img, _, err := image.Decode(reader)
-		if err != nil {
-			logging.Vlog(-1, err)
-			return
-		}
-		var ofmt string
-		if ext := filepath.Ext(output); ext != "" {
-			ofmt = ext[1:]
-		}
-
-		file, err := os.Create(output)
-		if err != nil {
-			logging.Vlog(-1, err)
-			return
-		}
-		defer file.Close()
-		switch ofmt {
-		case "gif":
-			err = gif.Encode(file, img, nil)
-		case "png":
-			err = png.Encode(file, img)
-		default:
-			err = jpeg.Encode(file, img, nil)
-		}
-		if err != nil {
-			logging.Vlog(-1, err)
-			return
-		}
-	}
-}
+var qualityFlag = flag.Int("quality", 0, "JPEG quality. 0 means the default.")
+var timeoutFlag = flag.Duration("timeout", 30*time.Second, "")
+var profileFlag = flag.String("profile", "",
+	"Profile to launch and render with, as path#name, see package profiles. Flags given "+
+		"explicitly override it.")
 
 func main() {
 	flag.Parse()
@@ -123,69 +36,76 @@ func main() {
 	if hcBin == "" || url == "" || output == "" {
 		logging.Fatal("--hc-binary, --url and --output are required!")
 	}
-
-	// Create browser.
-	browser, err := hc.NewBrowser(*hcPortFlag, "127.0.0.1", "", hcBin)
-	if err != nil {
-		logging.Vlog(-1, err)
-		return
+	format := render.FormatJpeg
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".png":
+		format = render.FormatPng
+	case ".gif":
+		format = render.FormatGif
+	case ".pdf":
+		format = render.FormatPdf
 	}
-	defer browser.Close()
 
-	// Connect to it.
-	conn, err := browser.NewBrowserConn()
-	if err != nil {
-		logging.Vlog(-1, err)
-		return
+	profile := &profiles.Profile{}
+	if *profileFlag != "" {
+		var err error
+		if profile, err = profiles.LoadProfile(*profileFlag); err != nil {
+			logging.Fatal(err)
+		}
 	}
-	defer conn.Close()
-
-	// Create browser context.
-	var contextId protocol.BrowserContextID
-	if result, err := protocol.CreateBrowserContext(conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	} else {
-		contextId = result.BrowserContextId
+	// Flags override the profile if given, or if it doesn't set them.
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	launchOpts := profile.LaunchOptions()
+	if set["port"] || launchOpts.Port == 0 {
+		launchOpts.Port = *hcPortFlag
 	}
-
-	// Open page.
-	var targetId string
-	if result, err := protocol.CreateTarget(
-		&protocol.CreateTargetParams{url, *widthFlag, *heightFlag, contextId}, conn); err != nil {
-		logging.Vlog(-1, err)
-		return
-	} else {
-		targetId = string(result.TargetId)
+	if set["hc-binary"] || launchOpts.Binary == "" {
+		launchOpts.Binary = hcBin
+	}
+	if launchOpts.Addr == "" {
+		launchOpts.Addr = "127.0.0.1"
+	}
+	req := render.RenderRequest{URL: url, Format: format}
+	if set["width"] {
+		req.Width = *widthFlag
+	}
+	if set["height"] {
+		req.Height = *heightFlag
+	}
+	if set["quality"] {
+		req.Quality = *qualityFlag
+	}
+	if set["timeout"] {
+		req.Timeout = *timeoutFlag
+	}
+	profile.Apply(&req)
+	if req.Width == 0 {
+		req.Width = *widthFlag
+	}
+	if req.Height == 0 {
+		req.Height = *heightFlag
+	}
+	if req.Timeout == 0 {
+		req.Timeout = *timeoutFlag
 	}
 
-	// Due to a bug of Chromium (https://bugs.chromium.org/p/chromium/issues/detail?id=704503),
-	// have to do this before we could connect to the page.
-	if tabs, err := browser.ListTabs(); err != nil {
+	// Create browser.
+	browser, err := hc.Launch(launchOpts)
+	if err != nil {
 		logging.Vlog(-1, err)
 		return
-	} else {
-		logging.Vlog(1, tabs)
 	}
+	defer browser.Close()
 
-	// Connect to the page.
-	pageConn, err := browser.NewPageConn(targetId)
+	result, err := render.Render(context.Background(), browser, req)
 	if err != nil {
 		logging.Vlog(-1, err)
 		return
 	}
-	defer pageConn.Close()
-
-	// Wait till the page is loaded.
-	if err := protocol.PageEnable(pageConn); err != nil {
+	logging.Vlogf(1, "Rendered %s (status %d) in %v + %v.", result.FinalURL, result.Status,
+		result.LoadTime, result.CaptureTime)
+	if err := ioutil.WriteFile(output, result.Image, 0644); err != nil {
 		logging.Vlog(-1, err)
-		return
 	}
-	var wg sync.WaitGroup
-	wg.Add(1)
-	protocol.OnLoadEventFired(pageConn, func(*protocol.LoadEventFiredEvent) {
-		captureScreenshot(pageConn, output)
-		wg.Done()
-	})
-	wg.Wait()
 }
@@ -0,0 +1,393 @@
+// An HTTP server rendering web pages, e.g.
+//   curl 'http://localhost:8080/render?url=https://example.com&format=png' > example.png
+//
+// GET /render takes url, width, height, format (jpeg, png, gif, or pdf if the browser supports
+// it), quality, wait (a JavaScript condition to wait for after load), timeout (e.g. 10s) and
+// nocache=1 to render again even if cached. POST /purge?url=... drops cached renders of a URL, or
+// all without url. GET /healthz checks the browser. GET /capabilities lists the formats the
+// browser supports, see render.Capabilities. GET /debug/vars serves the hc_render_* metrics as
+// expvar "hc", see package metrics.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	"github.com/yijinliu/headless-chromium/go/metrics"
+	"github.com/yijinliu/headless-chromium/go/profiles"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+	"github.com/yijinliu/headless-chromium/go/render"
+)
+
+var addrFlag = flag.String("addr", ":8080", "Address to serve HTTP on.")
+var hcPortFlag = flag.Int("port", 9222, "")
+var hcBinaryFlag = flag.String("hc-binary", hc.DefaultBinary, "")
+var maxConcurrentFlag = flag.Int("max-concurrent", 4, "Max number of renders at the same time.")
+var maxQueuedFlag = flag.Int("max-queued", 16,
+	"Max number of requests waiting for a slot. More get 503.")
+var maxTimeoutFlag = flag.Duration("max-timeout", time.Minute, "Upper bound of timeout parameter.")
+var cacheMaxAgeFlag = flag.Duration("cache-max-age", 5*time.Minute,
+	"max-age of Cache-Control header of rendered images.")
+var renderCacheBytesFlag = flag.Int64("render-cache-bytes", 0,
+	"Max size of rendered images to cache. 0 disables the cache.")
+var renderCacheTTLFlag = flag.Duration("render-cache-ttl", 5*time.Minute,
+	"How long rendered images are cached.")
+var renderCacheErrorTTLFlag = flag.Duration("render-cache-error-ttl", 10*time.Second,
+	"How long failed renders are cached.")
+var allowHostsFlag = flag.String("allow-hosts", "",
+	"Comma separated hosts allowed to render, with subdomains. Empty means all.")
+var denyHostsFlag = flag.String("deny-hosts", "localhost,127.0.0.1",
+	"Comma separated hosts not allowed to render, with subdomains.")
+var profileFlag = flag.String("profile", "",
+	"Profile to launch the browser with, and of render defaults, as path#name, see package "+
+		"profiles. Request parameters override it.")
+
+type server struct {
+	browser    *hc.Browser
+	healthConn *hc.Conn
+	allowHosts []string
+	denyHosts  []string
+	maxTimeout time.Duration
+	slots      chan struct{}
+	maxQueued  int32
+	queued     int32
+	cache      *render.Cache
+	metrics    metrics.Metrics
+	renderFunc render.RenderFunc
+	// Defaults of requests.
+	profile *profiles.Profile
+}
+
+type tooBusyError struct{}
+
+func (tooBusyError) Error() string   { return "Too busy" }
+func (tooBusyError) Temporary() bool { return true }
+
+func splitHosts(hosts string) []string {
+	var result []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(strings.ToLower(host)); host != "" {
+			result = append(result, host)
+		}
+	}
+	return result
+}
+
+func matchHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if host == pattern || strings.HasSuffix(host, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *server) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Unsupported scheme '%s'", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchHost(host, s.denyHosts) {
+		return fmt.Errorf("Host '%s' is denied", host)
+	}
+	if len(s.allowHosts) > 0 && !matchHost(host, s.allowHosts) {
+		return fmt.Errorf("Host '%s' is not allowed", host)
+	}
+	return nil
+}
+
+func intParam(query url.Values, name string, def int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func (s *server) parseRequest(r *http.Request) (*render.RenderRequest, error) {
+	query := r.URL.Query()
+	req := &render.RenderRequest{
+		URL:    query.Get("url"),
+		Format: render.Format(query.Get("format")),
+	}
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	var err error
+	if req.Width, err = intParam(query, "width", 0); err != nil {
+		return nil, err
+	}
+	if req.Height, err = intParam(query, "height", 0); err != nil {
+		return nil, err
+	}
+	if req.Quality, err = intParam(query, "quality", 0); err != nil {
+		return nil, err
+	}
+	if req.Format != "" {
+		caps, err := render.Capabilities(s.browser)
+		if err != nil {
+			return nil, err
+		}
+		if err := caps.Check(req.Format); err != nil {
+			return nil, err
+		} else if !caps.Supports(req.Format) {
+			return nil, fmt.Errorf("Unknown format '%s'", req.Format)
+		}
+	}
+	req.Wait.Condition = query.Get("wait")
+	if timeout := query.Get("timeout"); timeout != "" {
+		if req.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, err
+		}
+		if req.Timeout <= 0 {
+			req.Timeout = s.maxTimeout
+		}
+	}
+	s.profile.Apply(req)
+	if req.Width == 0 {
+		req.Width = 1280
+	}
+	if req.Height == 0 {
+		req.Height = 800
+	}
+	if req.Timeout == 0 || req.Timeout > s.maxTimeout {
+		req.Timeout = s.maxTimeout
+	}
+	return req, nil
+}
+
+// Waits for a render slot. Returns false if too many requests are waiting already.
+func (s *server) acquire(ctx context.Context) bool {
+	select {
+	case s.slots <- struct{}{}:
+		s.metrics.Gauge(metrics.RenderSlotsActive, nil, float64(len(s.slots)))
+		return true
+	default:
+	}
+	queued := atomic.AddInt32(&s.queued, 1)
+	defer func() {
+		s.metrics.Gauge(metrics.RenderQueueDepth, nil, float64(atomic.AddInt32(&s.queued, -1)))
+	}()
+	if queued > s.maxQueued {
+		return false
+	}
+	s.metrics.Gauge(metrics.RenderQueueDepth, nil, float64(queued))
+	select {
+	case s.slots <- struct{}{}:
+		s.metrics.Gauge(metrics.RenderSlotsActive, nil, float64(len(s.slots)))
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *server) release() {
+	<-s.slots
+	s.metrics.Gauge(metrics.RenderSlotsActive, nil, float64(len(s.slots)))
+}
+
+func (s *server) handleRender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := s.parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), req.Timeout)
+	defer cancel()
+
+	var result render.RenderResult
+	if s.cache == nil {
+		result, err = s.renderFunc(ctx, *req)
+	} else if r.URL.Query().Get("nocache") == "1" {
+		result, err = s.cache.Refresh(ctx, *req)
+	} else {
+		result, err = s.cache.Render(ctx, *req)
+	}
+	if err != nil {
+		logging.Vlogf(1, "Failed to render %s: %v", req.URL, err)
+		status := http.StatusBadGateway
+		if _, ok := err.(tooBusyError); ok {
+			status = http.StatusServiceUnavailable
+		} else if err == context.DeadlineExceeded {
+			status = http.StatusGatewayTimeout
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if s.cache != nil {
+		if result.Cached {
+			w.Header().Set("X-Cache", "HIT")
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+	}
+	w.Header().Set("Content-Type", result.Format.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(result.Image)))
+	w.Header().Set("Cache-Control",
+		fmt.Sprintf("public, max-age=%d", int(cacheMaxAgeFlag.Seconds())))
+	w.Header().Set("X-Final-Url", result.FinalURL)
+	w.Header().Set("X-Status", strconv.Itoa(result.Status))
+	if _, err := w.Write(result.Image); err != nil {
+		logging.Vlog(1, err)
+	}
+}
+
+// Renders in a slot.
+func (s *server) render(ctx context.Context, req render.RenderRequest) (
+	render.RenderResult, error) {
+	if !s.acquire(ctx) {
+		return render.RenderResult{}, tooBusyError{}
+	}
+	defer s.release()
+	return render.Render(ctx, s.browser, req)
+}
+
+func (s *server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.cache == nil {
+		http.Error(w, "Cache is disabled", http.StatusNotFound)
+		return
+	}
+	rawURL := r.URL.Query().Get("url")
+	if rawURL == "" {
+		s.cache.PurgeAll()
+	} else {
+		s.cache.Purge(rawURL)
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	caps, err := render.Capabilities(s.browser)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(caps); err != nil {
+		logging.Vlog(1, err)
+	}
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan error, 1)
+	go func() {
+		var result int
+		if err := hcutil.Evaluate(s.healthConn, "1 + 1", &result); err != nil {
+			ch <- err
+		} else if result != 2 {
+			ch <- fmt.Errorf("1 + 1 = %d", result)
+		} else {
+			ch <- nil
+		}
+	}()
+	select {
+	case err := <-ch:
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	case <-time.After(5 * time.Second):
+		http.Error(w, "Browser didn't respond", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// Opens a blank page to run health checks in.
+func openHealthPage(browser *hc.Browser) (*hc.Conn, error) {
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{Url: "about:blank"}, conn)
+	if err != nil {
+		return nil, err
+	}
+	return browser.NewPageConn(string(target.TargetId))
+}
+
+func main() {
+	flag.Parse()
+
+	profile := &profiles.Profile{}
+	if *profileFlag != "" {
+		var err error
+		if profile, err = profiles.LoadProfile(*profileFlag); err != nil {
+			logging.Fatal(err)
+		}
+	}
+	launchOpts := profile.LaunchOptions()
+	if launchOpts.Port == 0 {
+		launchOpts.Port = *hcPortFlag
+	}
+	if launchOpts.Addr == "" {
+		launchOpts.Addr = "127.0.0.1"
+	}
+	if launchOpts.Binary == "" {
+		launchOpts.Binary = *hcBinaryFlag
+	}
+	browser, err := hc.Launch(launchOpts)
+	if err != nil {
+		logging.Fatal(err)
+	}
+	defer browser.Close()
+	healthConn, err := openHealthPage(browser)
+	if err != nil {
+		logging.Fatal(err)
+	}
+	defer healthConn.Close()
+
+	s := &server{
+		browser:    browser,
+		healthConn: healthConn,
+		allowHosts: splitHosts(*allowHostsFlag),
+		denyHosts:  splitHosts(*denyHostsFlag),
+		maxTimeout: *maxTimeoutFlag,
+		slots:      make(chan struct{}, *maxConcurrentFlag),
+		maxQueued:  int32(*maxQueuedFlag),
+		metrics:    metrics.NewExpvar("hc"),
+		profile:    profile,
+	}
+	// Renders served from the cache aren't counted.
+	s.renderFunc = render.Instrument(s.metrics, s.render)
+	if *renderCacheBytesFlag > 0 {
+		s.cache = render.NewCache(render.CacheOptions{
+			TTL:      *renderCacheTTLFlag,
+			ErrorTTL: *renderCacheErrorTTLFlag,
+			MaxBytes: *renderCacheBytesFlag,
+			Metrics:  s.metrics,
+		}, s.renderFunc)
+	}
+	http.HandleFunc("/render", s.handleRender)
+	http.HandleFunc("/purge", s.handlePurge)
+	http.HandleFunc("/healthz", s.handleHealthz)
+	http.HandleFunc("/capabilities", s.handleCapabilities)
+	logging.Vlogf(0, "Serving on %s ...", *addrFlag)
+	logging.Vlog(-1, http.ListenAndServe(*addrFlag, nil))
+}
@@ -0,0 +1,252 @@
+package headless_chromium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var ErrDomainParamsConflict = errors.New("conflicting domain enable params")
+
+// AcquireDomain was called with params which can't be merged with those the domain is enabled
+// with. errors.Is(err, ErrDomainParamsConflict) is true for it.
+type DomainParamsConflictError struct {
+	Domain    string
+	Field     string
+	Current   interface{}
+	Requested interface{}
+}
+
+func (e *DomainParamsConflictError) Error() string {
+	return fmt.Sprintf("%s is enabled with %s %v, not %v", e.Domain, e.Field, e.Current,
+		e.Requested)
+}
+
+func (e *DomainParamsConflictError) Is(target error) bool {
+	return target == ErrDomainParamsConflict
+}
+
+// Users of a domain enabled by AcquireDomain. Guarded by domainMu.
+type domainRef struct {
+	count int
+	// Merged params of the acquirers, sent with the last enable command.
+	params map[string]interface{}
+	// The domain stays enabled when count drops to 0, as it was enabled before the first
+	// AcquireDomain, or by an enable command sent meanwhile.
+	pinned bool
+}
+
+// Enables domain, e.g. "Network", for a helper, unless other helpers have it enabled already.
+// ReleaseDomain disables it once all of them released it, so that one finishing doesn't stop
+// events the others rely on. Domains it depends on, e.g. DOM for CSS, are acquired too. params
+// are those of the enable command, e.g. *protocol.NetworkEnableParams, and may be nil.
+//
+// Params of all acquirers are merged: numbers, e.g. buffer sizes, take the largest value
+// requested, sending the enable command again if that grew, and any other differing value is a
+// DomainParamsConflictError. Merged params don't shrink as acquirers release the domain.
+//
+// Domains enabled by plain enable commands, e.g. protocol.NetworkEnable, or automatically, see
+// SetAutoEnableDomains, are never disabled by ReleaseDomain.
+func (c *Conn) AcquireDomain(domain string, params interface{}) error {
+	requested, err := domainParamsMap(params)
+	if err != nil {
+		return err
+	}
+	c.domainRefMu.Lock()
+	defer c.domainRefMu.Unlock()
+	return c.acquireDomainLocked(domain, requested)
+}
+
+// Must be called with domainRefMu held.
+func (c *Conn) acquireDomainLocked(domain string, requested map[string]interface{}) error {
+	deps, ok := enableableDomains[domain]
+	if !ok {
+		return fmt.Errorf("Domain %s can't be enabled", domain)
+	}
+	for i, dep := range deps {
+		if err := c.acquireDomainLocked(dep, nil); err != nil {
+			for _, acquired := range deps[:i] {
+				c.releaseDomainLocked(acquired)
+			}
+			return err
+		}
+	}
+	releaseDeps := func() {
+		for _, dep := range deps {
+			c.releaseDomainLocked(dep)
+		}
+	}
+
+	c.domainMu.Lock()
+	ref := c.domainRefMap[domain]
+	if ref == nil {
+		_, enabled := c.enabledDomainMap[domain]
+		ref = &domainRef{pinned: enabled}
+	}
+	merged, changed, err := mergeDomainParams(domain, ref.params, requested)
+	c.domainMu.Unlock()
+	if err != nil {
+		releaseDeps()
+		return err
+	}
+	if changed || (ref.count == 0 && !ref.pinned) {
+		if err := c.sendDomainCommand(domain+".enable", merged); err != nil {
+			releaseDeps()
+			return err
+		}
+	}
+	c.domainMu.Lock()
+	defer c.domainMu.Unlock()
+	ref.count++
+	ref.params = merged
+	if c.domainRefMap == nil {
+		c.domainRefMap = make(map[string]*domainRef)
+	}
+	c.domainRefMap[domain] = ref
+	if _, enabled := c.enabledDomainMap[domain]; !enabled {
+		c.enabledDomainMap[domain] = false
+	}
+	return nil
+}
+
+// Releases domain acquired by AcquireDomain, and the domains it depends on. The last release
+// disables it.
+func (c *Conn) ReleaseDomain(domain string) error {
+	c.domainRefMu.Lock()
+	defer c.domainRefMu.Unlock()
+	return c.releaseDomainLocked(domain)
+}
+
+// Must be called with domainRefMu held.
+func (c *Conn) releaseDomainLocked(domain string) error {
+	c.domainMu.Lock()
+	ref := c.domainRefMap[domain]
+	if ref == nil {
+		c.domainMu.Unlock()
+		return fmt.Errorf("Domain %s isn't acquired", domain)
+	}
+	ref.count--
+	disable := ref.count == 0 && !ref.pinned
+	if ref.count == 0 {
+		delete(c.domainRefMap, domain)
+	}
+	c.domainMu.Unlock()
+
+	var err error
+	if disable {
+		if err = c.sendDomainCommand(domain+".disable", nil); err == nil {
+			c.domainMu.Lock()
+			delete(c.enabledDomainMap, domain)
+			c.domainMu.Unlock()
+		}
+	}
+	for _, dep := range enableableDomains[domain] {
+		if depErr := c.releaseDomainLocked(dep); depErr != nil && err == nil {
+			err = depErr
+		}
+	}
+	return err
+}
+
+// Returns the number of AcquireDomain calls not released yet for domain.
+func (c *Conn) DomainRefs(domain string) int {
+	c.domainMu.Lock()
+	defer c.domainMu.Unlock()
+	if ref := c.domainRefMap[domain]; ref != nil {
+		return ref.count
+	}
+	return 0
+}
+
+// Must be called with domainMu held, for enable commands not sent by AcquireDomain.
+func (c *Conn) pinDomainLocked(domain string) {
+	if ref := c.domainRefMap[domain]; ref != nil {
+		ref.pinned = true
+	}
+}
+
+func domainParamsMap(params interface{}) (map[string]interface{}, error) {
+	if params == nil || (reflect.ValueOf(params).Kind() == reflect.Ptr &&
+		reflect.ValueOf(params).IsNil()) {
+		return nil, nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("Enable params must be an object: %v", err)
+	}
+	return m, nil
+}
+
+// Returns current with requested merged in, and whether it changed.
+func mergeDomainParams(domain string, current, requested map[string]interface{}) (
+	map[string]interface{}, bool, error) {
+	merged := make(map[string]interface{}, len(current)+len(requested))
+	for k, v := range current {
+		merged[k] = v
+	}
+	changed := false
+	for k, v := range requested {
+		cur, ok := merged[k]
+		if !ok {
+			merged[k] = v
+			changed = true
+			continue
+		} else if reflect.DeepEqual(cur, v) {
+			continue
+		}
+		curNum, curIsNum := cur.(float64)
+		num, isNum := v.(float64)
+		if !curIsNum || !isNum {
+			return nil, false, &DomainParamsConflictError{
+				Domain: domain, Field: k, Current: cur, Requested: v}
+		}
+		if num > curNum {
+			merged[k] = num
+			changed = true
+		}
+	}
+	if len(merged) == 0 {
+		merged = nil
+	}
+	return merged, changed, nil
+}
+
+// Sends an enable or disable command of AcquireDomain or ReleaseDomain, and waits for it.
+func (c *Conn) sendDomainCommand(name string, params map[string]interface{}) error {
+	cmd := &domainCommand{name: name, done: make(chan error, 1)}
+	if params != nil {
+		cmd.params = params
+	}
+	if err := c.SendCommand(cmd); err != nil {
+		return err
+	}
+	select {
+	case err := <-cmd.done:
+		return err
+	case <-c.closed:
+		return ErrConnClosed
+	}
+}
+
+type domainCommand struct {
+	name   string
+	params interface{}
+	done   chan error
+}
+
+func (cmd *domainCommand) Name() string {
+	return cmd.name
+}
+
+func (cmd *domainCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *domainCommand) Done(result []byte, err error) {
+	cmd.done <- err
+}
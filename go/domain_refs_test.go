@@ -0,0 +1,111 @@
+package headless_chromium_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+)
+
+func TestAcquireDomain(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	count := func(method string) int {
+		roundTrip(conn)
+		return len(server.CommandsOf(method))
+	}
+
+	// Only the first acquirer enables it, and only the last release disables it.
+	for i := 0; i < 2; i++ {
+		if err := conn.AcquireDomain("Network", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := count("Network.enable"); n != 1 {
+		t.Errorf("Network.enable sent %d times", n)
+	}
+	if err := conn.ReleaseDomain("Network"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("Network.disable"); n != 0 {
+		t.Error("Network disabled while still acquired")
+	}
+	if err := conn.ReleaseDomain("Network"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("Network.disable"); n != 1 {
+		t.Errorf("Network.disable sent %d times", n)
+	}
+	if err := conn.ReleaseDomain("Network"); err == nil {
+		t.Error("Released a domain not acquired")
+	}
+
+	// Dependencies are acquired too.
+	if err := conn.AcquireDomain("CSS", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := conn.DomainRefs("DOM"); n != 1 {
+		t.Errorf("DOM acquired %d times for CSS", n)
+	}
+	conn.ReleaseDomain("CSS")
+	if n := conn.DomainRefs("DOM"); n != 0 {
+		t.Errorf("DOM still acquired %d times", n)
+	}
+}
+
+func TestAcquireDomainParams(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	if err := conn.AcquireDomain("Network", map[string]interface{}{
+		"maxTotalBufferSize": 100}); err != nil {
+		t.Fatal(err)
+	}
+	// Bigger buffers are merged in, enabling again.
+	if err := conn.AcquireDomain("Network", map[string]interface{}{
+		"maxTotalBufferSize": 200}); err != nil {
+		t.Fatal(err)
+	}
+	// Smaller ones are covered already.
+	if err := conn.AcquireDomain("Network", map[string]interface{}{
+		"maxTotalBufferSize": 50}); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(conn)
+	enables := server.CommandsOf("Network.enable")
+	if len(enables) != 2 || string(enables[1].Params) != `{"maxTotalBufferSize":200}` {
+		t.Errorf("Got %d enables, the last with %s", len(enables),
+			enables[len(enables)-1].Params)
+	}
+
+	err := conn.AcquireDomain("Network", map[string]interface{}{"maxTotalBufferSize": "x"})
+	var conflict *hc.DomainParamsConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, hc.ErrDomainParamsConflict) ||
+		conflict.Field != "maxTotalBufferSize" {
+		t.Errorf("Got %v, not a conflict", err)
+	}
+	if n := conn.DomainRefs("Network"); n != 3 {
+		t.Errorf("%d refs, not 3", n)
+	}
+}
+
+// A domain enabled by a plain enable command stays enabled.
+func TestAcquirePinnedDomain(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	if err := newTestCommand("Page.enable", nil).run(t, conn, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AcquireDomain("Page", nil); err != nil {
+		t.Fatal(err)
+	}
+	conn.ReleaseDomain("Page")
+	roundTrip(conn)
+	if n := len(server.CommandsOf("Page.enable")); n != 1 {
+		t.Errorf("Page.enable sent %d times", n)
+	}
+	if n := len(server.CommandsOf("Page.disable")); n != 0 {
+		t.Error("Disabled a domain enabled explicitly")
+	}
+}
@@ -0,0 +1,168 @@
+package headless_chromium
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// A snapshot of what a connection and the helpers using it know, written by DumpState, e.g. to
+// attach to a bug report. Params, results and event payloads are left out.
+type StateDump struct {
+	Time    time.Time `json:"time"`
+	Targets string    `json:"targets"`
+	Closed  bool      `json:"closed"`
+	// Why the connection was closed, if it was.
+	Err             string           `json:"err,omitempty"`
+	PendingCommands []PendingCommand `json:"pendingCommands"`
+	// Number of commands waiting to be written, by priority.
+	QueuedCommands [2]int `json:"queuedCommands"`
+	// Enabled domains. The value is whether they were enabled automatically.
+	EnabledDomains map[string]bool `json:"enabledDomains"`
+	// Number of AcquireDomain calls not released yet, by domain.
+	DomainRefs map[string]int `json:"domainRefs"`
+	// Number of event sinks by event name.
+	EventSinks map[string]int `json:"eventSinks"`
+	// Names of sticky events received since the main frame navigated.
+	StickyEvents []string `json:"stickyEvents"`
+	MainFrameId  string   `json:"mainFrameId"`
+	LastEventSeq uint64   `json:"lastEventSeq"`
+	// Number of events whose sinks haven't returned yet.
+	EventsInFlight int       `json:"eventsInFlight"`
+	Stats          ConnStats `json:"stats"`
+	// State of helpers by the name they were added with, see AddStateDumper.
+	Helpers map[string]json.RawMessage `json:"helpers"`
+}
+
+type PendingCommand struct {
+	Id     int    `json:"id"`
+	Method string `json:"method"`
+	// Time since it was sent.
+	Age time.Duration `json:"age"`
+}
+
+// State dumpers of helpers, see AddStateDumper.
+type stateDumpers struct {
+	mu      sync.Mutex
+	dumpers map[string]func() interface{}
+}
+
+type stateDumpersKey struct{}
+
+func (c *Conn) stateDumpers() *stateDumpers {
+	return c.Value(stateDumpersKey{}, func() interface{} {
+		return &stateDumpers{dumpers: make(map[string]func() interface{})}
+	}).(*stateDumpers)
+}
+
+// Adds a helper's part of DumpState, the JSON of what dump returns, under name. dump is called
+// without any lock of the connection held, and must only take the helper's own locks. Call
+// remove when the helper is done.
+func (c *Conn) AddStateDumper(name string, dump func() interface{}) (remove func()) {
+	d := c.stateDumpers()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dumpers[name] = dump
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.dumpers, name)
+	}
+}
+
+// Writes a StateDump of the connection to w in JSON. It's safe to call at any time, e.g. from a
+// signal handler goroutine while commands and events are flowing: each lock of the connection
+// is taken on its own, never nested, so the parts are consistent by themselves, though not
+// necessarily with each other.
+func (c *Conn) DumpState(w io.Writer) error {
+	dump := c.State()
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Returns what DumpState writes.
+func (c *Conn) State() *StateDump {
+	now := time.Now()
+	dump := &StateDump{
+		Time:           now,
+		Targets:        c.kind.String(),
+		Closed:         c.isClosed(),
+		EnabledDomains: make(map[string]bool),
+		DomainRefs:     make(map[string]int),
+		EventSinks:     make(map[string]int),
+		Helpers:        make(map[string]json.RawMessage),
+	}
+	if err := c.Err(); err != nil {
+		dump.Err = err.Error()
+	}
+
+	c.cmdMu.Lock()
+	for id, cmd := range c.pendingCmdMap {
+		dump.PendingCommands = append(dump.PendingCommands, PendingCommand{
+			Id: id, Method: cmd.Name(), Age: now.Sub(c.pendingSentMap[id])})
+	}
+	c.cmdMu.Unlock()
+	sort.Slice(dump.PendingCommands, func(i, j int) bool {
+		return dump.PendingCommands[i].Id < dump.PendingCommands[j].Id
+	})
+
+	c.writeMu.Lock()
+	for prio, queue := range c.writeQueues {
+		dump.QueuedCommands[prio] = len(queue)
+	}
+	c.writeMu.Unlock()
+
+	c.evtMu.Lock()
+	for name, sinks := range c.evtSinkMap {
+		if len(sinks) > 0 {
+			dump.EventSinks[name] = len(sinks)
+		}
+	}
+	for name := range c.stickyMap {
+		dump.StickyEvents = append(dump.StickyEvents, name)
+	}
+	dump.MainFrameId = c.mainFrameId
+	dump.LastEventSeq = c.lastEvtSeq
+	dump.EventsInFlight = len(c.evtInFlight)
+	c.evtMu.Unlock()
+	sort.Strings(dump.StickyEvents)
+
+	c.domainMu.Lock()
+	for domain, auto := range c.enabledDomainMap {
+		dump.EnabledDomains[domain] = auto
+	}
+	for domain, ref := range c.domainRefMap {
+		if ref.count > 0 {
+			dump.DomainRefs[domain] = ref.count
+		}
+	}
+	c.domainMu.Unlock()
+
+	dump.Stats = c.Stats()
+
+	d := c.stateDumpers()
+	d.mu.Lock()
+	dumpers := make(map[string]func() interface{}, len(d.dumpers))
+	for name, dumper := range d.dumpers {
+		dumpers[name] = dumper
+	}
+	d.mu.Unlock()
+	for name, dumper := range dumpers {
+		data, err := json.Marshal(dumper())
+		if err != nil {
+			logging.Vlog(1, err)
+			data, _ = json.Marshal(fmt.Sprintf("Failed to marshal: %v", err))
+		}
+		dump.Helpers[name] = data
+	}
+	return dump
+}
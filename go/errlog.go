@@ -0,0 +1,115 @@
+package headless_chromium
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// Limits logging of errors which keep happening, e.g. every event of some kind failing to
+// unmarshal. Errors are similar if they are about the same method and have the same text.
+type ErrorLogThrottle struct {
+	// How many similar errors are logged in full within Window. 0 means no limit.
+	Burst int
+	// The rest are only counted, and summarized once the window is over.
+	Window time.Duration
+}
+
+var DefaultErrorLogThrottle = ErrorLogThrottle{Burst: 5, Window: time.Minute}
+
+type errLogKey struct {
+	method, err string
+}
+
+type errLogWindow struct {
+	start      time.Time
+	logged     int
+	suppressed int
+}
+
+type errorLogger struct {
+	mu            sync.Mutex
+	throttle      ErrorLogThrottle
+	windowMap     map[errLogKey]*errLogWindow
+	suppressedMap map[string]int // Key is method. Totals since the connection was opened.
+}
+
+func newErrorLogger() *errorLogger {
+	return &errorLogger{
+		throttle:      DefaultErrorLogThrottle,
+		windowMap:     make(map[errLogKey]*errLogWindow),
+		suppressedMap: make(map[string]int),
+	}
+}
+
+// Sets how errors of the connection are logged when there is no error handler, see
+// SetErrorHandler, and for those which are only logged, e.g. malformed messages. An error
+// handler gets every error regardless.
+func (c *Conn) SetErrorLogThrottle(throttle ErrorLogThrottle) {
+	c.errLog.mu.Lock()
+	defer c.errLog.mu.Unlock()
+	c.errLog.throttle = throttle
+}
+
+// Logs err of method, which may be empty if unknown, unless similar errors have been logged
+// Burst times in the current window.
+func (c *Conn) logError(method string, err error) {
+	l := c.errLog
+	key := errLogKey{method, err.Error()}
+	now := time.Now()
+	l.mu.Lock()
+	throttle := l.throttle
+	w := l.windowMap[key]
+	if w == nil || now.Sub(w.start) >= throttle.Window {
+		w = &errLogWindow{start: now}
+		l.windowMap[key] = w
+	}
+	if throttle.Burst > 0 && w.logged >= throttle.Burst {
+		w.suppressed++
+		l.suppressedMap[method]++
+		if w.suppressed == 1 {
+			// Summarize when the window is over, even if no similar error comes after it.
+			time.AfterFunc(throttle.Window-now.Sub(w.start), func() { l.summarize(key, w) })
+		}
+		l.mu.Unlock()
+		return
+	}
+	w.logged++
+	l.mu.Unlock()
+	logging.Vlog(-1, err)
+}
+
+func (l *errorLogger) summarize(key errLogKey, w *errLogWindow) {
+	l.mu.Lock()
+	suppressed := w.suppressed
+	if l.windowMap[key] == w {
+		delete(l.windowMap, key)
+	}
+	l.mu.Unlock()
+	logging.Vlogf(-1, "Suppressed %d similar errors in the last %v: %s", suppressed,
+		time.Since(w.start).Round(time.Second), key.err)
+}
+
+func (l *errorLogger) suppressed() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	suppressed := make(map[string]int, len(l.suppressedMap))
+	for method, count := range l.suppressedMap {
+		suppressed[method] = count
+	}
+	return suppressed
+}
+
+// Returns the method an error of the connection is about, if known.
+func errorMethod(err error) string {
+	switch err := err.(type) {
+	case *EventError:
+		return err.Name
+	case *SchemaError:
+		return err.Method
+	case *MessageSizeError:
+		return err.Method
+	}
+	return ""
+}
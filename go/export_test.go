@@ -0,0 +1,8 @@
+package headless_chromium
+
+import "time"
+
+// For tests of the headless_chromium_test package.
+func (c *Conn) HandleMessage(data []byte) {
+	c.handleMessage(data, time.Now())
+}
@@ -0,0 +1,54 @@
+// Package hcdebug reads the state dumps written by hc.Conn.DumpState, e.g. for tools inspecting
+// those attached to bug reports.
+package hcdebug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// A state dump with the sections of hcutil helpers decoded.
+type Dump struct {
+	hc.StateDump
+	// Nil if no Correlator was used.
+	Correlator *hcutil.CorrelatorState
+	// By the name they were added with.
+	IsolatedWorlds  map[string]*hcutil.IsolatedWorldState
+	ResponseWaiters map[string]*hcutil.ResponseWaiterState
+}
+
+// Parses what hc.Conn.DumpState wrote. Sections of unknown helpers are left in Helpers only.
+func Load(r io.Reader) (*Dump, error) {
+	d := &Dump{
+		IsolatedWorlds:  make(map[string]*hcutil.IsolatedWorldState),
+		ResponseWaiters: make(map[string]*hcutil.ResponseWaiterState),
+	}
+	if err := json.NewDecoder(r).Decode(&d.StateDump); err != nil {
+		return nil, fmt.Errorf("Invalid state dump: %v", err)
+	}
+	for name, data := range d.Helpers {
+		var err error
+		switch {
+		case name == hcutil.StateCorrelator:
+			d.Correlator = &hcutil.CorrelatorState{}
+			err = json.Unmarshal(data, d.Correlator)
+		case strings.HasPrefix(name, hcutil.StateIsolatedWorld):
+			state := &hcutil.IsolatedWorldState{}
+			err = json.Unmarshal(data, state)
+			d.IsolatedWorlds[name] = state
+		case strings.HasPrefix(name, hcutil.StateResponseWaiter):
+			state := &hcutil.ResponseWaiterState{}
+			err = json.Unmarshal(data, state)
+			d.ResponseWaiters[name] = state
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid state of %s: %v", name, err)
+		}
+	}
+	return d, nil
+}
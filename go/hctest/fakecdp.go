@@ -0,0 +1,453 @@
+package hctest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+// The target listed by a FakeServer unless others are added.
+const FakePageId = "fake-page"
+
+// The version a FakeServer reports, new enough to not need workarounds of old browsers.
+var FakeVersion = hc.Version{
+	Browser:         "HeadlessChrome/61.0.3163.100",
+	ProtocolVersion: "1.2",
+	UserAgent:       "Mozilla/5.0 HeadlessChrome/61.0.3163.100",
+	WebKitVersion:   "537.36",
+}
+
+// Devtools error codes.
+const (
+	CodeServerError    = -32000
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+)
+
+// A command received by a FakeServer.
+type FakeCommand struct {
+	Id     int
+	Method string
+	Params json.RawMessage
+	// The connection it came from, to answer later or to send events.
+	Conn *FakeConn
+}
+
+// An error answered by a FakeHandler, as the browser would.
+type FakeError struct {
+	Code    int
+	Message string
+	// Details, e.g. which params are invalid. Sent only if set.
+	Data string
+}
+
+func (e *FakeError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// The error the browser answers commands it doesn't know with.
+func MethodNotFound(method string) *FakeError {
+	return &FakeError{Code: CodeMethodNotFound, Message: fmt.Sprintf("'%s' wasn't found", method)}
+}
+
+// Returned by a FakeHandler which answers the command itself later, with FakeConn.Reply.
+var ErrNoReply = errors.New("no reply")
+
+// Answers cmd with result, which is marshaled, or err. A *FakeError is sent as is, other errors
+// as server errors. Handlers run on the goroutine reading from the connection, so a handler
+// which blocks holds up later commands of the connection, like a busy browser would.
+type FakeHandler func(cmd *FakeCommand) (result interface{}, err error)
+
+// Answers with result.
+func FakeResult(result interface{}) FakeHandler {
+	return func(*FakeCommand) (interface{}, error) {
+		return result, nil
+	}
+}
+
+// A fake devtools endpoint, for tests of the protocol handling without a browser. It serves
+// /json/version and /json/list, and accepts connections to /devtools/browser and
+// /devtools/page/<id> of listed targets. Schema.getDomains, which Conn.Flush sends, is answered
+// with no domains. Other commands without a handler are answered with an empty result.
+type FakeServer struct {
+	t      testing.TB
+	server *httptest.Server
+	addr   string
+
+	mu       sync.Mutex
+	handlers map[string]FakeHandler
+	targets  []hc.Tab
+	commands []*FakeCommand
+	conns    []*FakeConn
+	// Closed and replaced when a connection is made.
+	connAdded chan struct{}
+	// How many more WebSocket handshakes to answer with 502.
+	rejectDials int
+}
+
+// Starts a FakeServer listing a page FakePageId, which is closed when the test finishes.
+func NewFakeServer(t testing.TB) *FakeServer {
+	s := newFakeServer(t)
+	s.server.Start()
+	s.addr = s.server.Listener.Addr().String()
+	return s
+}
+
+// Like NewFakeServer, but only starts listening after delay, like a browser still starting.
+// Addr is known right away.
+func NewDelayedFakeServer(t testing.TB, delay time.Duration) *FakeServer {
+	port, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newFakeServer(t)
+	s.addr = fmt.Sprintf("127.0.0.1:%d", port)
+	started := make(chan struct{})
+	timer := time.AfterFunc(delay, func() {
+		defer close(started)
+		l, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.server.Listener = l
+		s.server.Start()
+	})
+	t.Cleanup(func() {
+		if timer.Stop() {
+			close(started)
+		}
+		<-started
+	})
+	return s
+}
+
+func newFakeServer(t testing.TB) *FakeServer {
+	s := &FakeServer{
+		t:         t,
+		handlers:  make(map[string]FakeHandler),
+		connAdded: make(chan struct{}),
+	}
+	s.AddTarget(FakePageId, string(hc.KindPage), "about:blank")
+	s.Handle("Schema.getDomains", FakeResult(map[string][]struct{}{"domains": {}}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/version", func(w http.ResponseWriter, r *http.Request) {
+		s.writeJson(w, FakeVersion)
+	})
+	mux.HandleFunc("/json/list", func(w http.ResponseWriter, r *http.Request) {
+		s.writeJson(w, s.Targets())
+	})
+	mux.HandleFunc("/devtools/", s.serveWebSocket)
+	s.server = httptest.NewUnstartedServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Host and port of the server.
+func (s *FakeServer) Addr() string {
+	return s.addr
+}
+
+// Closes every connection and the server. Called when the test finishes.
+func (s *FakeServer) Close() {
+	s.mu.Lock()
+	conns := s.conns
+	s.mu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	if s.server.Listener != nil {
+		s.server.Close()
+	}
+}
+
+// Sets the handler of method, replacing the existing one if any.
+func (s *FakeServer) Handle(method string, handler FakeHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+// Answers the next n WebSocket handshakes with 502 Bad Gateway, like a proxy in front of a
+// browser still starting.
+func (s *FakeServer) RejectDials(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectDials = n
+}
+
+// Lists a target of typ, e.g. "page" or "service_worker", which can be connected to.
+func (s *FakeServer) AddTarget(id, typ, url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = append(s.targets, hc.Tab{
+		ID:   id,
+		Type: typ,
+		Url:  url,
+		// Filled in by Targets, as the address may not be known yet.
+		WebSocketDebuggerUrl: "/devtools/page/" + id,
+	})
+}
+
+func (s *FakeServer) Targets() []hc.Tab {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	targets := make([]hc.Tab, len(s.targets))
+	for i, target := range s.targets {
+		target.WebSocketDebuggerUrl = "ws://" + s.addr + target.WebSocketDebuggerUrl
+		targets[i] = target
+	}
+	return targets
+}
+
+// Returns the commands received so far, of all connections, in the order they came.
+func (s *FakeServer) Commands() []*FakeCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*FakeCommand(nil), s.commands...)
+}
+
+// Returns the commands of method received so far.
+func (s *FakeServer) CommandsOf(method string) []*FakeCommand {
+	var cmds []*FakeCommand
+	for _, cmd := range s.Commands() {
+		if cmd.Method == method {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// Binds to the server. Fails the test on errors.
+func (s *FakeServer) Browser() *hc.Browser {
+	s.t.Helper()
+	browser, err := hc.NewRemoteBrowser(s.addr)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return browser
+}
+
+// Connects to the page FakePageId, and returns both ends of the connection. The connection is
+// closed when the test finishes. Fails the test on errors.
+func (s *FakeServer) NewPageConn() (*hc.Conn, *FakeConn) {
+	s.t.Helper()
+	n := s.connCount()
+	conn, err := s.Browser().NewPageConn(FakePageId)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { conn.Close() })
+	return conn, s.Conn(n)
+}
+
+// Like NewPageConn, for the browser target.
+func (s *FakeServer) NewBrowserConn() (*hc.Conn, *FakeConn) {
+	s.t.Helper()
+	n := s.connCount()
+	conn, err := s.Browser().NewBrowserConn()
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { conn.Close() })
+	return conn, s.Conn(n)
+}
+
+func (s *FakeServer) connCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Returns the server end of the i-th connection made to the server, counting from 0, waiting
+// for it if necessary.
+func (s *FakeServer) Conn(i int) *FakeConn {
+	s.t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		s.mu.Lock()
+		if i < len(s.conns) {
+			defer s.mu.Unlock()
+			return s.conns[i]
+		}
+		added := s.connAdded
+		s.mu.Unlock()
+		select {
+		case <-added:
+		case <-timeout:
+			s.t.Fatalf("No connection %d to the fake server", i)
+			return nil
+		}
+	}
+}
+
+func (s *FakeServer) writeJson(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+var fakeUpgrader = &websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+func (s *FakeServer) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/devtools/")
+	if path != "browser" {
+		found := false
+		for _, target := range s.Targets() {
+			if path == "page/"+target.ID {
+				found = true
+			}
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	s.mu.Lock()
+	reject := s.rejectDials > 0
+	if reject {
+		s.rejectDials--
+	}
+	s.mu.Unlock()
+	if reject {
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		return
+	}
+	ws, err := fakeUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &FakeConn{server: s, ws: ws, Path: r.URL.Path, closed: make(chan struct{})}
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	close(s.connAdded)
+	s.connAdded = make(chan struct{})
+	s.mu.Unlock()
+	conn.serve()
+}
+
+// The server end of a connection to a FakeServer. All methods are safe for concurrent use.
+type FakeConn struct {
+	server *FakeServer
+	ws     *websocket.Conn
+	// E.g. "/devtools/page/fake-page".
+	Path string
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *FakeConn) serve() {
+	defer c.Close()
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		cmd := &FakeCommand{Conn: c}
+		var msg struct {
+			Id     int             `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.server.t.Errorf("Bad command %s: %v", data, err)
+			return
+		}
+		cmd.Id, cmd.Method, cmd.Params = msg.Id, msg.Method, msg.Params
+		s := c.server
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		handler := s.handlers[cmd.Method]
+		s.mu.Unlock()
+		if handler == nil {
+			handler = FakeResult(struct{}{})
+		}
+		result, err := handler(cmd)
+		if err == ErrNoReply {
+			continue
+		}
+		if err != nil {
+			err = c.ReplyError(cmd.Id, err)
+		} else {
+			err = c.Reply(cmd.Id, result)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Answers command id with result, which is marshaled.
+func (c *FakeConn) Reply(id int, result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return c.WriteJson(map[string]interface{}{"id": id, "result": json.RawMessage(data)})
+}
+
+// Answers command id with err. A *FakeError is sent as is, other errors as server errors.
+func (c *FakeConn) ReplyError(id int, err error) error {
+	fe, ok := err.(*FakeError)
+	if !ok {
+		fe = &FakeError{Code: CodeServerError, Message: err.Error()}
+	}
+	errJson := map[string]interface{}{"code": fe.Code, "message": fe.Message}
+	if fe.Data != "" {
+		errJson["data"] = fe.Data
+	}
+	return c.WriteJson(map[string]interface{}{"id": id, "error": errJson})
+}
+
+// Sends event method with params, which are marshaled.
+func (c *FakeConn) Emit(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.WriteJson(map[string]interface{}{"method": method, "params": json.RawMessage(data)})
+}
+
+func (c *FakeConn) WriteJson(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.WriteRaw(data)
+}
+
+// Sends data as is, e.g. a malformed message.
+func (c *FakeConn) WriteRaw(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+// Drops the connection, like a crashed browser.
+func (c *FakeConn) Close() {
+	c.closeOnce.Do(func() {
+		c.ws.Close()
+		close(c.closed)
+	})
+}
+
+// Returns a channel that's closed once the connection is closed by either end.
+func (c *FakeConn) Closed() <-chan struct{} {
+	return c.closed
+}
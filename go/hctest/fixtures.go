@@ -0,0 +1,351 @@
+package hctest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Paths served by the fixture server.
+const (
+	// A static page with a title, a heading, a link and a button.
+	FixtureStatic = "/static"
+	// Redirects 3 times and ends at FixtureStatic.
+	FixtureRedirect = "/redirect/3"
+	// Serves a page whose image takes FixtureSlowDelay to load, which delays its load event.
+	FixtureSlow = "/slow"
+	// A page embedding FixtureStatic in an iframe.
+	FixtureIframe = "/iframe"
+	// Opens an alert when loaded.
+	FixtureDialog = "/dialog"
+	// A form posting to FixtureEcho.
+	FixtureForm = "/form"
+	// Images only loaded when scrolled into view.
+	FixtureLazyImages = "/lazy"
+	// Echoes the request method, URL and body as text.
+	FixtureEcho = "/echo"
+	// Sets the cookie "fixture=1".
+	FixtureCookie = "/cookie"
+	// Sets the cookie "stored=1" expiring in a day, which is kept in the profile.
+	FixtureStoredCookie = "/stored-cookie"
+	// A WebSocket endpoint echoing every message back.
+	FixtureWebSocket = "/ws"
+	// Connects to FixtureWebSocket, sends "hello" and shows the reply in #reply.
+	FixtureWebSocketPage = "/ws-page"
+	// Logs the visibility state and focus seen by its first script, and every visibilitychange
+	// event, as lines of #log.
+	FixtureVisibility = "/visibility"
+	// Loads quickly, but requests an image every 200ms afterwards, so the network is never idle.
+	FixtureBusyNetwork = "/busy"
+	// Moves a box on every animation frame, so the page never stops changing, e.g. for
+	// screencasts.
+	FixtureAnimation = "/animation"
+	// Runs "while(true){}" once hang() is evaluated, so that the page stops responding.
+	FixtureBusyLoop = "/busy-loop"
+	// Text in paragraphs, a link, hidden elements, an image with alt text, an open shadow root
+	// under #host, and FixtureStatic in an iframe, e.g. for text extraction.
+	FixtureText = "/text"
+	// Allocates and fills FixtureMemoryHogSize bytes when loaded, and keeps them.
+	FixtureMemoryHog = "/memory-hog"
+	// Buttons #top and #bottom, 4000px apart. Ids of clicked elements are pushed to
+	// window.clicked.
+	FixtureScrollClick = "/scroll-click"
+	// White, or black when the page prefers a dark color scheme, by its style sheet or by
+	// matchMedia, for browsers where only the latter can be emulated.
+	FixtureColorScheme = "/color-scheme"
+	// A black canvas as large as the window, redrawn only by resize listeners.
+	FixtureCanvas = "/canvas"
+	// Streams Server-Sent Events of FixtureEventStreamMessageSize bytes, as many MB of them as
+	// the mb query parameter says, then an "end" message. Message i repeats letter i mod 26.
+	FixtureEventStream = "/event-stream"
+	// Reads FixtureEventStream with its own query, and sets window.done once the end is
+	// received.
+	FixtureEventStreamPage = "/event-stream-page"
+	// A white page taller and wider than the viewport, so it has scrollbars.
+	FixtureOverflow = "/overflow"
+)
+
+const FixtureSlowDelay = time.Second
+
+const FixtureMemoryHogSize = 256 << 20
+
+const FixtureEventStreamMessageSize = 64 << 10
+
+const staticPage = `<!DOCTYPE html>
+<html><head><title>Static</title></head>
+<body><h1>Hello</h1><a id="link" href="/echo">Echo</a><button id="button">Button</button></body>
+</html>`
+
+const iframePage = `<!DOCTYPE html>
+<html><head><title>Iframe</title></head>
+<body><iframe id="frame" src="/static"></iframe></body></html>`
+
+const dialogPage = `<!DOCTYPE html>
+<html><head><title>Dialog</title></head>
+<body><script>window.onload = function() { alert("fixture"); };</script></body></html>`
+
+const formPage = `<!DOCTYPE html>
+<html><head><title>Form</title></head>
+<body><form id="form" method="POST" action="/echo">
+<input id="name" name="name" type="text"><input id="submit" type="submit">
+</form></body></html>`
+
+const slowPage = `<!DOCTYPE html>
+<html><head><title>Slow</title></head>
+<body><img id="slow" src="/slow.png"></body></html>`
+
+const webSocketPage = `<!DOCTYPE html>
+<html><head><title>WebSocket</title></head>
+<body><div id="reply"></div><script>
+var ws = new WebSocket("ws://" + location.host + "/ws");
+ws.onopen = function() { ws.send("hello"); };
+ws.onmessage = function(e) { document.getElementById("reply").textContent = e.data; };
+</script></body></html>`
+
+const visibilityPage = `<!DOCTYPE html>
+<html><head><title>Visibility</title><script>
+var entries = ["initial " + document.visibilityState + " " + document.hasFocus()];
+document.addEventListener("visibilitychange", function() {
+	entries.push("visibilitychange " + document.visibilityState + " " + document.hasFocus());
+	var log = document.getElementById("log");
+	if (log) log.textContent = entries.join("\n");
+});
+</script></head>
+<body><pre id="log"></pre><script>
+document.getElementById("log").textContent = entries.join("\n");
+</script></body></html>`
+
+const busyNetworkPage = `<!DOCTYPE html>
+<html><head><title>Busy</title></head>
+<body><script>
+setInterval(function() { new Image().src = "/pixel.gif?" + Date.now(); }, 200);
+</script></body></html>`
+
+const animationPage = `<!DOCTYPE html>
+<html><head><title>Animation</title></head>
+<body style="margin: 0"><div id="box" style="position: absolute; width: 50px; height: 50px;
+	background: red"></div><script>
+var box = document.getElementById("box");
+function step(t) {
+	box.style.left = (t / 5 % 500) + "px";
+	requestAnimationFrame(step);
+}
+requestAnimationFrame(step);
+</script></body></html>`
+
+const busyLoopPage = `<!DOCTYPE html>
+<html><head><title>Busy loop</title></head>
+<body><script>
+// Returns first, so that the evaluation calling it is answered.
+function hang() { setTimeout(function() { while (true) {} }, 0); }
+</script></body></html>`
+
+const textPage = `<!DOCTYPE html>
+<html><head><title>Text</title><style>.hidden { display: none; }</style></head>
+<body><h1>Title</h1>
+<p>First <a href="/echo">link</a> and <span>inline</span> text.</p>
+<div class="hidden">Hidden by class</div>
+<div style="visibility: hidden">Invisible</div>
+<div>Block<br>Next line <img src="/pixel.gif" alt="Pixel"></div>
+<div id="host"><span>Not rendered</span></div>
+<iframe src="/static"></iframe>
+<script>
+document.getElementById("host").attachShadow({mode: "open"}).innerHTML = "<p>Shadow text</p>";
+</script></body></html>`
+
+var memoryHogPage = fmt.Sprintf(`<!DOCTYPE html>
+<html><head><title>Memory hog</title></head>
+<body><script>
+// Filled, so that the pages are resident.
+var hog = [];
+for (var i = 0; i < %d; i++) { var a = new Uint8Array(1 << 20); a.fill(1); hog.push(a); }
+</script></body></html>`, FixtureMemoryHogSize>>20)
+
+const scrollClickPage = `<!DOCTYPE html>
+<html><head><title>Scroll click</title></head>
+<body style="margin: 0"><button id="top">Top</button><div style="height: 4000px"></div>
+<button id="bottom">Bottom</button><script>
+window.clicked = [];
+document.addEventListener("click", function(e) { window.clicked.push(e.target.id); });
+</script></body></html>`
+
+const colorSchemePage = `<!DOCTYPE html>
+<html><head><title>Color scheme</title><style>
+body { margin: 0; background: white; }
+@media (prefers-color-scheme: dark) { body { background: black; } }
+html.dark body { background: black; }
+</style><script>
+if (matchMedia("(prefers-color-scheme: dark)").matches) {
+	document.documentElement.className = "dark";
+}
+</script></head><body></body></html>`
+
+const canvasPage = `<!DOCTYPE html>
+<html><head><title>Canvas</title></head>
+<body style="margin: 0; overflow: hidden; background: white">
+<canvas id="canvas" style="display: block"></canvas><script>
+function draw() {
+	var canvas = document.getElementById("canvas");
+	canvas.width = window.innerWidth;
+	canvas.height = window.innerHeight;
+	var ctx = canvas.getContext("2d");
+	ctx.fillStyle = "black";
+	ctx.fillRect(0, 0, canvas.width, canvas.height);
+}
+draw();
+window.addEventListener("resize", draw);
+</script></body></html>`
+
+const eventStreamPage = `<!DOCTYPE html>
+<html><head><title>Event stream</title></head>
+<body><script>
+var source = new EventSource("/event-stream" + location.search);
+source.onmessage = function(e) {
+	if (e.data == "end") {
+		source.close();
+		window.done = true;
+	}
+};
+</script></body></html>`
+
+const overflowPage = `<!DOCTYPE html>
+<html><head><title>Overflow</title></head>
+<body style="margin: 0; background: white"><div style="width: 5000px; height: 5000px"></div>
+</body></html>`
+
+// A 1x1 transparent GIF.
+var pixel = []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00!\xf9\x04\x01" +
+	"\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+
+// Returns a handler serving the fixture pages, e.g. to mount it in another server.
+func FixtureHandler() http.Handler {
+	mux := http.NewServeMux()
+	html := func(path, page string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, page)
+		})
+	}
+	html(FixtureStatic, staticPage)
+	html(FixtureIframe, iframePage)
+	html(FixtureDialog, dialogPage)
+	html(FixtureForm, formPage)
+	html(FixtureSlow, slowPage)
+	html(FixtureWebSocketPage, webSocketPage)
+	html(FixtureVisibility, visibilityPage)
+	html(FixtureBusyNetwork, busyNetworkPage)
+	html(FixtureAnimation, animationPage)
+	html(FixtureBusyLoop, busyLoopPage)
+	html(FixtureText, textPage)
+	html(FixtureMemoryHog, memoryHogPage)
+	html(FixtureScrollClick, scrollClickPage)
+	html(FixtureColorScheme, colorSchemePage)
+	html(FixtureCanvas, canvasPage)
+	html(FixtureEventStreamPage, eventStreamPage)
+	html(FixtureOverflow, overflowPage)
+
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(r.URL.Path[len("/redirect/"):])
+		if err != nil {
+			http.NotFound(w, r)
+		} else if n <= 1 {
+			http.Redirect(w, r, FixtureStatic, http.StatusFound)
+		} else {
+			http.Redirect(w, r, fmt.Sprintf("/redirect/%d", n-1), http.StatusFound)
+		}
+	})
+	mux.HandleFunc("/slow.png", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(FixtureSlowDelay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write(pixel)
+	})
+	mux.HandleFunc("/pixel.gif", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write(pixel)
+	})
+	mux.HandleFunc(FixtureLazyImages, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!DOCTYPE html><html><head><title>Lazy</title></head><body>`)
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `<div style="height: 1000px"><img class="lazy" data-src="/pixel.gif?%d">`+
+				`</div>`, i)
+		}
+		fmt.Fprint(w, `<script>
+var observer = new IntersectionObserver(function(entries) {
+	entries.forEach(function(e) {
+		if (e.isIntersecting) { e.target.src = e.target.dataset.src; observer.unobserve(e.target); }
+	});
+});
+document.querySelectorAll("img.lazy").forEach(function(img) { observer.observe(img); });
+</script></body></html>`)
+	})
+	mux.HandleFunc(FixtureEventStream, func(w http.ResponseWriter, r *http.Request) {
+		mb, err := strconv.Atoi(r.URL.Query().Get("mb"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		data := make([]byte, FixtureEventStreamMessageSize)
+		for i := 0; i < mb<<20/FixtureEventStreamMessageSize; i++ {
+			for j := range data {
+				data[j] = byte('a' + i%26)
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		fmt.Fprint(w, "data: end\n\n")
+	})
+	mux.HandleFunc(FixtureEcho, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "%s %s\n%s", r.Method, r.URL, r.PostForm.Encode())
+	})
+	mux.HandleFunc(FixtureCookie, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "fixture", Value: "1", Path: "/"})
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, staticPage)
+	})
+	mux.HandleFunc(FixtureStoredCookie, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "stored", Value: "1", Path: "/",
+			Expires: time.Now().Add(24 * time.Hour)})
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, staticPage)
+	})
+	upgrader := &websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	mux.HandleFunc(FixtureWebSocket, func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			typ, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := ws.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	})
+	return mux
+}
+
+// Starts a server of the fixture pages, which is closed when the test finishes. Open them with
+// server.URL + FixtureStatic etc.
+func NewFixtureServer(t testing.TB) *httptest.Server {
+	server := httptest.NewServer(FixtureHandler())
+	t.Cleanup(server.Close)
+	return server
+}
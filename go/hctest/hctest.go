@@ -0,0 +1,239 @@
+// Package hctest provides browser fixtures for tests. Everything created is cleaned up
+// automatically when the test finishes, even if it fails or panics.
+package hctest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type launchConfig struct {
+	binary         string
+	proxy          string
+	startupTimeout time.Duration
+	profileRoot    string
+	reuseProfile   string
+}
+
+type LaunchOption func(*launchConfig)
+
+func newLaunchConfig(t testing.TB, opts []LaunchOption) *launchConfig {
+	config := &launchConfig{startupTimeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.binary == "" {
+		binary, err := hc.FindBinary()
+		if err != nil {
+			t.Skip(err)
+		}
+		config.binary = binary
+	}
+	return config
+}
+
+func (c *launchConfig) launchOptions(port int) hc.LaunchOptions {
+	return hc.LaunchOptions{Port: port, Addr: "127.0.0.1", Proxy: c.proxy, Binary: c.binary,
+		StartupTimeout: c.startupTimeout, ProfileRoot: c.profileRoot,
+		ReuseProfile: c.reuseProfile}
+}
+
+// Uses binary instead of the one found by hc.FindBinary.
+func WithBinary(binary string) LaunchOption {
+	return func(c *launchConfig) {
+		c.binary = binary
+	}
+}
+
+func WithProxy(proxy string) LaunchOption {
+	return func(c *launchConfig) {
+		c.proxy = proxy
+	}
+}
+
+// Fails the test if the browser isn't up within timeout. The default is 10 seconds.
+func WithStartupTimeout(timeout time.Duration) LaunchOption {
+	return func(c *launchConfig) {
+		c.startupTimeout = timeout
+	}
+}
+
+// Keeps profiles under root, and uses the one named reuse if set, like LaunchOptions.ProfileRoot
+// and LaunchOptions.ReuseProfile.
+func WithProfile(root, reuse string) LaunchOption {
+	return func(c *launchConfig) {
+		c.profileRoot = root
+		c.reuseProfile = reuse
+	}
+}
+
+// Starts a browser for the test, which is killed when the test finishes. Skips the test if no
+// hc_server binary can be found.
+func NewBrowser(t testing.TB, opts ...LaunchOption) *hc.Browser {
+	t.Helper()
+	config := newLaunchConfig(t, opts)
+	port, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	browser, err := hc.Launch(config.launchOptions(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := browser.Close(); err != nil {
+			t.Log(err)
+		}
+	})
+	return browser
+}
+
+var shared struct {
+	mu      sync.Mutex
+	browser *hc.Browser
+	refs    int
+}
+
+// Returns a browser shared by all tests (e.g. parallel ones) using it at the same time. Use
+// NewPage to get isolated pages. The browser is killed once the last of these tests finishes.
+// Options only matter when the browser is started.
+func SharedBrowser(t testing.TB, opts ...LaunchOption) *hc.Browser {
+	t.Helper()
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.browser == nil {
+		// The browser must outlive this test, so clean it up ourselves.
+		config := newLaunchConfig(t, opts)
+		port, err := freePort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		browser, err := hc.Launch(config.launchOptions(port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shared.browser = browser
+	}
+	shared.refs++
+	t.Cleanup(func() {
+		shared.mu.Lock()
+		defer shared.mu.Unlock()
+		if shared.refs--; shared.refs == 0 {
+			if err := shared.browser.Close(); err != nil {
+				t.Log(err)
+			}
+			shared.browser = nil
+		}
+	})
+	return shared.browser
+}
+
+// Opens url in a new page in its own browser context, and returns a connection to it. The page
+// and its context are disposed when the test finishes. Connections decode strictly, and fail the
+// test on results or events not matching the protocol. See hc.SetStrictDecoding.
+func NewPage(t testing.TB, browser *hc.Browser, url string) *hc.Conn {
+	t.Helper()
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeStrictly(t, conn)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	context, err := protocol.CreateBrowserContext(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := protocol.DisposeBrowserContext(&protocol.DisposeBrowserContextParams{
+			BrowserContextId: context.BrowserContextId}, conn); err != nil {
+			t.Log(err)
+		}
+	})
+
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{
+		Url: url, BrowserContextId: context.BrowserContextId}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := protocol.CloseTarget(&protocol.CloseTargetParams{
+			TargetId: target.TargetId}, conn); err != nil {
+			t.Log(err)
+		}
+	})
+
+	pageConn, err := browser.NewPageConn(string(target.TargetId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeStrictly(t, pageConn)
+	t.Cleanup(func() {
+		pageConn.Close()
+	})
+	return pageConn
+}
+
+const flushTimeout = 10 * time.Second
+
+// Waits till event callbacks of conn have seen every event the browser sent so far, and fails
+// the test if that takes too long. Use it rather than sleeping before checking what the
+// callbacks recorded. See hc.Conn.Flush.
+func Flush(t testing.TB, conn *hc.Conn) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := conn.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Fails the test if conn has more event sinks when the test finishes than now, e.g. because a
+// helper doesn't remove its sinks. Sites of the sinks are tracked meanwhile, and reported with
+// the failure. See hc.Conn.SinkReport.
+func ExpectNoSinkLeaks(t testing.TB, conn *hc.Conn) {
+	t.Helper()
+	conn.SetTrackSinkSites(true)
+	baseline := conn.Stats().EventSinks
+	t.Cleanup(func() {
+		for _, entry := range conn.SinkReport() {
+			if entry.Count <= baseline[entry.Event] {
+				continue
+			}
+			t.Errorf("%d sinks of %s, %d at first. Added by:", entry.Count, entry.Event,
+				baseline[entry.Event])
+			for _, site := range entry.Sites {
+				t.Errorf("\t%d by %s", site.Count, site.Site)
+			}
+		}
+	})
+}
+
+func decodeStrictly(t testing.TB, conn *hc.Conn) {
+	conn.SetStrictDecoding(true)
+	conn.SetErrorHandler(func(err error) {
+		if _, ok := err.(*hc.SchemaError); ok {
+			t.Error(err)
+		} else {
+			t.Log(err)
+		}
+	})
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,139 @@
+package hctest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Records errors instead of failing the test, and runs cleanups on demand.
+type recordingTB struct {
+	testing.TB
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Error(args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recordingTB) errs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.errors...)
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+// Answers Target commands like the browser, with the pages created listed by server.
+func handleTargets(server *hctest.FakeServer) {
+	server.Handle("Target.createBrowserContext", hctest.FakeResult(map[string]string{
+		"browserContextId": "context1"}))
+	server.Handle("Target.createTarget", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		var params protocol.CreateTargetParams
+		json.Unmarshal(cmd.Params, &params)
+		server.AddTarget("page1", "page", params.Url)
+		return map[string]string{"targetId": "page1"}, nil
+	})
+	server.Handle("Target.closeTarget", hctest.FakeResult(map[string]bool{"success": true}))
+	server.Handle("Target.disposeBrowserContext", hctest.FakeResult(struct{}{}))
+}
+
+// Methods of the Target commands received, along with their target or context.
+func targetCommands(server *hctest.FakeServer) []string {
+	var cmds []string
+	for _, cmd := range server.Commands() {
+		if !strings.HasPrefix(cmd.Method, "Target.") {
+			continue
+		}
+		var params struct {
+			Url              string
+			BrowserContextId string
+			TargetId         string
+		}
+		json.Unmarshal(cmd.Params, &params)
+		fields := []string{cmd.Method}
+		for _, field := range []string{params.Url, params.BrowserContextId, params.TargetId} {
+			if field != "" {
+				fields = append(fields, field)
+			}
+		}
+		cmds = append(cmds, strings.Join(fields, " "))
+	}
+	return cmds
+}
+
+// NewPage opens the page in its own context, and closes both when the test finishes, page first.
+func TestNewPage(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	handleTargets(server)
+	tb := &recordingTB{TB: t}
+	conn := hctest.NewPage(tb, server.Browser(), "http://example.com/")
+	if err := protocol.PageEnable(conn); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := server.CommandsOf("Page.enable"); len(cmds) != 1 {
+		t.Errorf("Got %d Page.enable", len(cmds))
+	}
+	created := []string{
+		"Target.createBrowserContext",
+		"Target.createTarget http://example.com/ context1",
+	}
+	if got := targetCommands(server); strings.Join(got, "\n") != strings.Join(created, "\n") {
+		t.Errorf("Got %q before finishing", got)
+	}
+
+	tb.finish()
+	want := append(created, "Target.closeTarget page1",
+		"Target.disposeBrowserContext context1")
+	if got := targetCommands(server); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	if err := protocol.PageEnable(conn); err == nil {
+		t.Error("Page connection still open")
+	}
+	if errs := tb.errs(); len(errs) != 0 {
+		t.Errorf("Got errors %v", errs)
+	}
+}
+
+// Pages decode strictly, so events not matching the protocol fail the test.
+func TestNewPageDecodesStrictly(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	handleTargets(server)
+	tb := &recordingTB{TB: t}
+	defer tb.finish()
+	conn := hctest.NewPage(tb, server.Browser(), "about:blank")
+	fired := make(chan struct{}, 2)
+	defer protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {
+		fired <- struct{}{}
+	})()
+	fake := server.Conn(1)
+	fake.Emit("Page.loadEventFired", map[string]float64{"timestamp": 1})
+	fake.Emit("Page.loadEventFired", map[string]string{"timestamp": "soon"})
+	hctest.Flush(t, conn)
+	if len(fired) != 1 {
+		t.Errorf("%d events delivered", len(fired))
+	}
+	if errs := tb.errs(); len(errs) != 1 {
+		t.Errorf("Got errors %v, want the bad event reported", errs)
+	}
+}
@@ -0,0 +1,31 @@
+package hcutil
+
+import (
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Returns the attributes of the element by name. A malformed attribute array is reported to the
+// error handler of conn as *protocol.MalformedAttributesError, see hc.Conn.SetErrorHandler, and
+// the attributes recovered from it are returned.
+func NodeAttributes(conn *hc.Conn, nodeId protocol.NodeId) (map[string]string, error) {
+	result, err := protocol.GetAttributes(&protocol.GetAttributesParams{NodeId: nodeId}, conn)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := result.AttributesMap()
+	if malformed, ok := err.(*protocol.MalformedAttributesError); ok {
+		malformed.NodeId = nodeId
+		conn.ReportError(malformed)
+	}
+	return attrs, nil
+}
+
+// Like NodeAttributes, for a node returned by DOM.getDocument etc.
+func nodeAttributes(conn *hc.Conn, node *protocol.Node) map[string]string {
+	attrs, err := node.AttributesMap()
+	if err != nil {
+		conn.ReportError(err)
+	}
+	return attrs
+}
@@ -0,0 +1,38 @@
+package hcutil_test
+
+import (
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// An odd-length attribute array is reported with the node id instead of misaligning names and
+// values.
+func TestNodeAttributesMalformed(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("DOM.getAttributes", hctest.FakeResult(map[string]interface{}{
+		"attributes": []string{"id", "main", "class"},
+	}))
+	conn, _ := server.NewPageConn()
+	errs := make(chan error, 1)
+	conn.SetErrorHandler(func(err error) { errs <- err })
+
+	attrs, err := hcutil.NodeAttributes(conn, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 1 || attrs["id"] != "main" {
+		t.Errorf("Got %v", attrs)
+	}
+	select {
+	case err := <-errs:
+		malformed, ok := err.(*protocol.MalformedAttributesError)
+		if !ok || malformed.NodeId != 42 || len(malformed.Attributes) != 3 {
+			t.Errorf("Got %v", err)
+		}
+	default:
+		t.Error("Malformed attributes not reported")
+	}
+}
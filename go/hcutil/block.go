@@ -0,0 +1,209 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// File extensions of resource types, for browsers without the Fetch domain, which can only block
+// by URL. Other types can't be blocked by them.
+var resourceTypeExtensions = map[protocol.ResourceType][]string{
+	protocol.ResourceTypeImage: {
+		"png", "jpg", "jpeg", "gif", "webp", "svg", "ico", "bmp", "avif"},
+	protocol.ResourceTypeFont:       {"woff", "woff2", "ttf", "otf", "eot"},
+	protocol.ResourceTypeMedia:      {"mp4", "webm", "ogg", "ogv", "mp3", "wav", "m4a", "mov", "flac"},
+	protocol.ResourceTypeStylesheet: {"css"},
+	protocol.ResourceTypeScript:     {"js"},
+}
+
+type blockerKey struct{}
+
+type blocker struct {
+	mu       sync.Mutex
+	fetch    bool
+	types    map[protocol.ResourceType]bool
+	patterns []string
+	counts   map[protocol.ResourceType]int
+	cancels  []func()
+	// Whether Network domain is acquired.
+	acquired bool
+}
+
+func getBlocker(conn *hc.Conn) *blocker {
+	return conn.Value(blockerKey{}, func() interface{} {
+		return &blocker{
+			types:  make(map[protocol.ResourceType]bool),
+			counts: make(map[protocol.ResourceType]int),
+		}
+	}).(*blocker)
+}
+
+// Blocks requests of types, e.g. images and fonts when only text is needed, on top of types
+// blocked before. With the Fetch domain, requests are matched by their exact type. Otherwise
+// Network.addBlockedURL is used with URL patterns of file extensions, which misses resources
+// without them, and only image, font, media, stylesheet and script types can be blocked.
+// Network domain is enabled till UnblockAll, unless other helpers need it, see
+// hc.Conn.AcquireDomain, to count blocked requests.
+func BlockResourceTypes(conn *hc.Conn, types ...protocol.ResourceType) error {
+	b := getBlocker(conn)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.cancels) == 0 {
+		fetch, err := supportsFetch(conn)
+		if err != nil {
+			return err
+		}
+		b.fetch = fetch
+		if err := conn.AcquireDomain("Network", nil); err != nil {
+			return err
+		}
+		b.acquired = true
+		if fetch {
+			b.cancels = append(b.cancels,
+				listen(conn, "Fetch.requestPaused", b.onRequestPaused(conn)))
+		} else {
+			b.cancels = append(b.cancels,
+				listen(conn, "Network.loadingFailed", b.onLoadingFailed(conn)))
+		}
+	}
+
+	var added []protocol.ResourceType
+	for _, tp := range types {
+		if b.types[tp] {
+			continue
+		}
+		if !b.fetch && resourceTypeExtensions[tp] == nil {
+			return fmt.Errorf("Cannot block %s without the Fetch domain", tp)
+		}
+		added = append(added, tp)
+	}
+	for _, tp := range added {
+		b.types[tp] = true
+	}
+	if b.fetch {
+		// Only requests of blocked types are paused.
+		var patterns []map[string]string
+		for tp := range b.types {
+			patterns = append(patterns,
+				map[string]string{"urlPattern": "*", "resourceType": string(tp)})
+		}
+		return sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+			return &rawCommand{name: "Fetch.enable", params: map[string]interface{}{
+				"patterns": patterns}, cb: cb}
+		})
+	}
+	for _, tp := range added {
+		for _, ext := range resourceTypeExtensions[tp] {
+			for _, pattern := range []string{"*." + ext, "*." + ext + "?*"} {
+				if err := protocol.AddBlockedURL(
+					&protocol.AddBlockedURLParams{Url: pattern}, conn); err != nil {
+					return err
+				}
+				b.patterns = append(b.patterns, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *blocker) onRequestPaused(conn *hc.Conn) func(params []byte) {
+	return func(params []byte) {
+		var evt struct {
+			RequestId    string                `json:"requestId"`
+			ResourceType protocol.ResourceType `json:"resourceType"`
+		}
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("Fetch.requestPaused", params, err)
+			return
+		}
+		b.mu.Lock()
+		blocked := b.types[evt.ResourceType]
+		if blocked {
+			b.counts[evt.ResourceType]++
+		}
+		b.mu.Unlock()
+		cmd := &rawCommand{
+			name:   "Fetch.continueRequest",
+			params: map[string]string{"requestId": evt.RequestId},
+			cb: func(err error) {
+				if err != nil {
+					logging.Vlog(2, err)
+				}
+			},
+		}
+		if blocked {
+			cmd.name = "Fetch.failRequest"
+			cmd.params = map[string]string{
+				"requestId": evt.RequestId, "errorReason": "BlockedByClient"}
+		}
+		conn.SendCommand(cmd)
+	}
+}
+
+func (b *blocker) onLoadingFailed(conn *hc.Conn) func(params []byte) {
+	return func(params []byte) {
+		var evt protocol.LoadingFailedEvent
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("Network.loadingFailed", params, err)
+			return
+		}
+		if evt.BlockedReason == protocol.BlockedReasonInspector {
+			b.mu.Lock()
+			b.counts[evt.Type]++
+			b.mu.Unlock()
+		}
+	}
+}
+
+// Returns the number of requests blocked by BlockResourceTypes per type. Without the Fetch
+// domain, the types are as reported by Chromium, so they may differ from the blocked ones.
+func BlockedCounts(conn *hc.Conn) map[protocol.ResourceType]int {
+	b := getBlocker(conn)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	counts := make(map[protocol.ResourceType]int, len(b.counts))
+	for tp, n := range b.counts {
+		counts[tp] = n
+	}
+	return counts
+}
+
+// Stops blocking what BlockResourceTypes blocked. Counts are kept.
+func UnblockAll(conn *hc.Conn) error {
+	b := getBlocker(conn)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, cancel := range b.cancels {
+		cancel()
+	}
+	b.cancels = nil
+	b.types = make(map[protocol.ResourceType]bool)
+	if b.acquired {
+		b.acquired = false
+		defer func() {
+			if err := conn.ReleaseDomain("Network"); err != nil {
+				logging.Vlog(1, err)
+			}
+		}()
+	}
+	if b.fetch {
+		return sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+			return &rawCommand{name: "Fetch.disable", cb: cb}
+		})
+	}
+	patterns := b.patterns
+	b.patterns = nil
+	for _, pattern := range patterns {
+		if err := protocol.RemoveBlockedURL(
+			&protocol.RemoveBlockedURLParams{Url: pattern}, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
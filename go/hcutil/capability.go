@@ -0,0 +1,86 @@
+package hcutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/jpeg"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+const capabilityTimeout = 5 * time.Second
+
+// Reports whether the browser supports the Fetch domain, which BlockResourceTypes uses when it
+// can. Protocol v1.2 has no bindings for it.
+func supportsFetch(conn *hc.Conn) (bool, error) {
+	// Fetch.disable is harmless, unlike Fetch.enable which pauses all requests without patterns.
+	return supportsMethod(conn, "Fetch.disable")
+}
+
+// Sends method, which must take no params and have no effect worth worrying about, to see
+// whether the browser knows it.
+func supportsMethod(conn *hc.Conn, method string) (bool, error) {
+	err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+		return &rawCommand{name: method, cb: cb}
+	})
+	if err == nil {
+		return true, nil
+	} else if hc.IsMethodNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// What Page.captureScreenshot and Page.printToPDF of a browser support beyond protocol v1.2.
+type CaptureCapabilities struct {
+	// captureScreenshot honors format "jpeg". In protocol v1.2 it always returns PNG.
+	JpegCapture bool
+	// captureScreenshot honors clip. CaptureScreenshot emulates it otherwise.
+	Clip bool
+	// Page.printToPDF exists, see PrintToPDF.
+	PrintToPDF bool
+}
+
+// Probes what the browser of the page of conn can capture: a 1x1 JPEG screenshot is requested,
+// which older browsers return as a PNG of the whole view, and the page is printed. So better
+// probe a blank page.
+func ProbeCaptureCapabilities(conn *hc.Conn) (*CaptureCapabilities, error) {
+	var result struct {
+		Data string `json:"data"`
+	}
+	err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+		return &rawCommand{
+			name: "Page.captureScreenshot",
+			params: map[string]interface{}{
+				"format":  "jpeg",
+				"quality": 10,
+				"clip": map[string]float64{
+					"x": 0, "y": 0, "width": 1, "height": 1, "scale": 1},
+			},
+			result: &result,
+			cb:     cb,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		return nil, err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	caps := &CaptureCapabilities{
+		JpegCapture: format == "jpeg",
+		// A few pixels with HiDPI.
+		Clip: config.Width <= 4 && config.Height <= 4,
+	}
+	if caps.PrintToPDF, err = supportsMethod(conn, "Page.printToPDF"); err != nil {
+		return nil, err
+	}
+	return caps, nil
+}
@@ -0,0 +1,75 @@
+package hcutil_test
+
+import (
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Blocking uses the Fetch domain if the browser has it, and URL patterns otherwise. Unknown
+// methods are told by the error code, not its message, which differs across versions.
+func TestBlockResourceTypesBackend(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		err   error
+		fetch bool
+		fails bool
+	}{
+		{name: "supported", fetch: true},
+		{name: "old message", err: hctest.MethodNotFound("Fetch.disable")},
+		{name: "new message", err: &hctest.FakeError{Code: hctest.CodeMethodNotFound,
+			Message: "Method not found"}},
+		{name: "other error", err: &hctest.FakeError{Code: hctest.CodeServerError,
+			Message: "'Fetch.disable' wasn't found"}, fails: true},
+	} {
+		server := hctest.NewFakeServer(t)
+		server.Handle("Fetch.disable", func(*hctest.FakeCommand) (interface{}, error) {
+			if c.err != nil {
+				return nil, c.err
+			}
+			return struct{}{}, nil
+		})
+		conn, _ := server.NewPageConn()
+		err := hcutil.BlockResourceTypes(conn, protocol.ResourceTypeImage)
+		if (err != nil) != c.fails {
+			t.Errorf("%s: got %v", c.name, err)
+			continue
+		}
+		enabled := len(server.CommandsOf("Fetch.enable")) > 0
+		patterns := len(server.CommandsOf("Network.addBlockedURL")) > 0
+		if !c.fails && (enabled != c.fetch || patterns == c.fetch) {
+			t.Errorf("%s: Fetch.enable sent %t, URL patterns added %t", c.name, enabled, patterns)
+		}
+	}
+}
+
+// Network domain stays enabled after UnblockAll while another helper holds it.
+func TestUnblockAllReleasesNetwork(t *testing.T) {
+	for _, shared := range []bool{false, true} {
+		server := hctest.NewFakeServer(t)
+		conn, _ := server.NewPageConn()
+		if shared {
+			if err := conn.AcquireDomain("Network", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := hcutil.BlockResourceTypes(conn, protocol.ResourceTypeImage); err != nil {
+			t.Fatal(err)
+		}
+		if err := hcutil.UnblockAll(conn); err != nil {
+			t.Fatal(err)
+		}
+		wantDisables := 1
+		if shared {
+			wantDisables = 0
+		}
+		enables := len(server.CommandsOf("Network.enable"))
+		disables := len(server.CommandsOf("Network.disable"))
+		if enables != 1 || disables != wantDisables {
+			t.Errorf("Shared %t: got %d Network.enable, %d Network.disable", shared, enables,
+				disables)
+		}
+	}
+}
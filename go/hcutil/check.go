@@ -0,0 +1,211 @@
+package hcutil
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type SelectorAssertion struct {
+	Selector string
+	// How many elements must match at least. 0 means 1.
+	MinCount int
+}
+
+// What CheckPage asserts about a page. Empty fields aren't checked.
+type PageAssertions struct {
+	MustContainText    []string
+	MustNotContainText []string
+	MustMatchSelector  []SelectorAssertion
+	// Time NavigateAndWait may take.
+	MaxLoadTime time.Duration
+	// Minimum size of the main document body, e.g. to catch empty error pages served with 200.
+	MinBodyBytes int
+	// Timeout of loading the page. Defaults to 30 seconds, or MaxLoadTime if it's longer.
+	LoadTimeout time.Duration
+	// Capture the URL and a screenshot of the page if an assertion fails.
+	CaptureFailure bool
+}
+
+type AssertionResult struct {
+	// E.g. `contains "Welcome"`.
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Why it failed, or what was found, e.g. "2 matches".
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+type CheckResult struct {
+	URL      string `json:"url"`
+	FinalURL string `json:"finalUrl,omitempty"`
+	// HTTP status of the main document. 0 if unknown.
+	Status int `json:"status,omitempty"`
+	// Whether the page loaded and every assertion passed.
+	Passed   bool          `json:"passed"`
+	LoadTime time.Duration `json:"loadTime"`
+	// Why the page didn't load. The assertions aren't checked then.
+	LoadError  string            `json:"loadError,omitempty"`
+	Assertions []AssertionResult `json:"assertions"`
+	Duration   time.Duration     `json:"duration"`
+	// See PageAssertions.CaptureFailure.
+	FailureURL        string `json:"failureUrl,omitempty"`
+	FailureScreenshot []byte `json:"failureScreenshot,omitempty"`
+}
+
+const (
+	defaultCheckLoadTimeout = 30 * time.Second
+	checkSelectorBudget     = 5 * time.Second
+)
+
+// Loads url with NavigateAndWait and checks the page against assertions, e.g. for uptime
+// monitoring. A page which fails to load or to satisfy an assertion is reported in the result,
+// not as an error. Errors are returned for invalid assertions, and if conn is closed.
+func CheckPage(conn *hc.Conn, url string, assertions PageAssertions) (*CheckResult, error) {
+	for _, a := range assertions.MustMatchSelector {
+		if a.Selector == "" {
+			return nil, errors.New("Empty selector in MustMatchSelector")
+		}
+	}
+	result := &CheckResult{URL: url, Assertions: []AssertionResult{}}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	timeout := assertions.LoadTimeout
+	if timeout <= 0 {
+		timeout = defaultCheckLoadTimeout
+		if assertions.MaxLoadTime > timeout {
+			timeout = assertions.MaxLoadTime
+		}
+	}
+	err := NavigateAndWaitWithOptions(conn, url,
+		&NavigateOptions{Timeout: timeout, AbortOnTimeout: true})
+	result.LoadTime = time.Since(start)
+	if err != nil {
+		if cause := conn.Err(); cause != nil {
+			return nil, cause
+		}
+		result.LoadError = err.Error()
+		result.capture(conn, &assertions)
+		return result, nil
+	}
+	var requestId protocol.RequestId
+	if c, _ := conn.Value(correlatorKey{}, nil).(*Correlator); c != nil {
+		frameId, _ := conn.Value(navigatedFrameKey{}, nil).(protocol.FrameId)
+		if req := c.MainDocumentRequest(frameId); req != nil {
+			requestId = req.RequestId
+			result.FinalURL = req.Response.Url
+			result.Status = int(req.Response.Status)
+		}
+	}
+
+	if assertions.MaxLoadTime > 0 {
+		result.add(fmt.Sprintf("load time <= %v", assertions.MaxLoadTime),
+			func() (bool, string) {
+				return result.LoadTime <= assertions.MaxLoadTime, result.LoadTime.String()
+			})
+	}
+	if assertions.MinBodyBytes > 0 {
+		result.add(fmt.Sprintf("body >= %d bytes", assertions.MinBodyBytes),
+			func() (bool, string) {
+				size, err := responseBodySize(conn, requestId)
+				if err != nil {
+					return false, err.Error()
+				}
+				return size >= assertions.MinBodyBytes, fmt.Sprintf("%d bytes", size)
+			})
+	}
+	if len(assertions.MustContainText) > 0 || len(assertions.MustNotContainText) > 0 {
+		text, textErr := ExtractText(conn, nil)
+		for _, s := range assertions.MustContainText {
+			result.add(fmt.Sprintf("contains %q", s), func() (bool, string) {
+				if textErr != nil {
+					return false, textErr.Error()
+				}
+				return strings.Contains(text, s), ""
+			})
+		}
+		for _, s := range assertions.MustNotContainText {
+			result.add(fmt.Sprintf("doesn't contain %q", s), func() (bool, string) {
+				if textErr != nil {
+					return false, textErr.Error()
+				}
+				return !strings.Contains(text, s), ""
+			})
+		}
+	}
+	for _, a := range assertions.MustMatchSelector {
+		minCount := a.MinCount
+		if minCount <= 0 {
+			minCount = 1
+		}
+		result.add(fmt.Sprintf("matches %q >= %d times", a.Selector, minCount),
+			func() (bool, string) {
+				count, err := countMatches(conn, a.Selector, minCount)
+				if err != nil {
+					return false, err.Error()
+				}
+				if count >= minCount {
+					return true, fmt.Sprintf("at least %d matches", count)
+				}
+				return false, fmt.Sprintf("%d matches", count)
+			})
+	}
+	if cause := conn.Err(); cause != nil {
+		return nil, cause
+	}
+
+	result.Passed = true
+	for _, a := range result.Assertions {
+		result.Passed = result.Passed && a.Passed
+	}
+	if !result.Passed {
+		result.capture(conn, &assertions)
+	}
+	return result, nil
+}
+
+func (r *CheckResult) add(name string, check func() (passed bool, detail string)) {
+	start := time.Now()
+	passed, detail := check()
+	r.Assertions = append(r.Assertions, AssertionResult{
+		Name: name, Passed: passed, Detail: detail, Duration: time.Since(start)})
+}
+
+func (r *CheckResult) capture(conn *hc.Conn, assertions *PageAssertions) {
+	if assertions.CaptureFailure {
+		r.FailureURL, r.FailureScreenshot = captureFailure(conn)
+	}
+}
+
+func responseBodySize(conn *hc.Conn, requestId protocol.RequestId) (int, error) {
+	if requestId == "" {
+		return 0, ErrNoMainDocument
+	}
+	body, err := protocol.GetResponseBody(
+		&protocol.GetResponseBodyParams{RequestId: requestId}, conn)
+	if err != nil {
+		return 0, err
+	}
+	if body.Base64Encoded {
+		data, err := base64.StdEncoding.DecodeString(body.Body)
+		return len(data), err
+	}
+	return len(body.Body), nil
+}
+
+// Counts elements of the document matching selector, stopping at maxCount.
+func countMatches(conn *hc.Conn, selector string, maxCount int) (int, error) {
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+	if err != nil {
+		return 0, err
+	}
+	nodeIds, _, err := QuerySelectorAllWithBudget(conn, doc.Root.NodeId, selector,
+		checkSelectorBudget, maxCount)
+	return len(nodeIds), err
+}
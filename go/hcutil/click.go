@@ -0,0 +1,199 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// What happened after ClickAndWait clicked.
+type ClickOutcomeKind int
+
+const (
+	// Nothing happened within ClickWaitOptions.QuietTimeout.
+	ClickNothing ClickOutcomeKind = iota
+	// The main frame navigated to a new document, which has loaded.
+	ClickNavigated
+	// The URL changed without loading a new document, e.g. history.pushState or a hash change.
+	ClickSameDocument
+	// ClickWaitOptions.Condition became truthy.
+	ClickCondition
+	// A new page was opened, e.g. by target="_blank".
+	ClickNewTarget
+)
+
+func (k ClickOutcomeKind) String() string {
+	switch k {
+	case ClickNothing:
+		return "nothing"
+	case ClickNavigated:
+		return "navigated"
+	case ClickSameDocument:
+		return "sameDocument"
+	case ClickCondition:
+		return "condition"
+	case ClickNewTarget:
+		return "newTarget"
+	}
+	return "unknown"
+}
+
+type ClickOutcome struct {
+	Kind ClickOutcomeKind
+	// The new page for ClickNewTarget.
+	TargetId protocol.TargetID
+}
+
+type ClickWaitOptions struct {
+	EvalOptions
+	// How long to wait for the outcome. Defaults to 30 seconds.
+	Timeout time.Duration
+	// How long to wait for anything to happen. Defaults to 1 second. It doesn't apply once a
+	// navigation has started.
+	QuietTimeout time.Duration
+	// A JavaScript expression, e.g. `document.querySelector(".result")`.
+	Condition string
+	// A browser connection, to detect new pages. They aren't detected without it.
+	BrowserConn *hc.Conn
+}
+
+// Clicks the first element matching selector, and waits for whichever comes first: a navigation
+// of the main frame, a same document navigation, opts.Condition, a new page, or nothing within
+// opts.QuietTimeout. Page domain is enabled meanwhile, see hc.Conn.AcquireDomain, and target
+// discovery as a side effect if opts.BrowserConn is set.
+func ClickAndWait(conn *hc.Conn, selector string, opts ClickWaitOptions) (ClickOutcome, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.QuietTimeout <= 0 {
+		opts.QuietTimeout = time.Second
+	}
+	if err := conn.AcquireDomain("Page", nil); err != nil {
+		return ClickOutcome{}, err
+	}
+	defer func() {
+		if err := conn.ReleaseDomain("Page"); err != nil {
+			logging.Vlog(1, err)
+		}
+	}()
+	tree, err := protocol.GetResourceTree(conn)
+	if err != nil {
+		return ClickOutcome{}, err
+	}
+	mainFrameId := protocol.FrameId(tree.FrameTree.Frame.Id)
+	var oldURL string
+	if err := evaluate(conn, "location.href", &oldURL, &opts.EvalOptions); err != nil {
+		return ClickOutcome{}, err
+	}
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+	if err != nil {
+		return ClickOutcome{}, err
+	}
+	node, err := protocol.QuerySelector(&protocol.QuerySelectorParams{
+		NodeId: doc.Root.NodeId, Selector: selector}, conn)
+	if err != nil {
+		return ClickOutcome{}, err
+	} else if node.NodeId == 0 {
+		return ClickOutcome{}, fmt.Errorf("No element matches %s", selector)
+	}
+
+	// Listen before clicking, or a fast navigation may be missed.
+	navStarted := make(chan struct{}, 1)
+	loaded := make(chan struct{}, 1)
+	newTarget := make(chan protocol.TargetID, 1)
+	notify := func(ch chan struct{}) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	cancels := []func(){
+		listen(conn, "Page.frameStartedLoading", func(params []byte) {
+			var evt protocol.FrameStartedLoadingEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Page.frameStartedLoading", params, err)
+			} else if evt.FrameId == mainFrameId {
+				notify(navStarted)
+			}
+		}),
+		listen(conn, "Page.loadEventFired", func([]byte) { notify(loaded) }),
+	}
+	if opts.BrowserConn != nil {
+		cancels = append(cancels, listen(opts.BrowserConn, "Target.targetCreated",
+			func(params []byte) {
+				var evt protocol.TargetCreatedEvent
+				if err := json.Unmarshal(params, &evt); err != nil {
+					opts.BrowserConn.ReportEventError("Target.targetCreated", params, err)
+				} else if evt.TargetInfo != nil && evt.TargetInfo.Type == "page" {
+					select {
+					case newTarget <- evt.TargetInfo.TargetId:
+					default:
+					}
+				}
+			}))
+		if err := protocol.SetDiscoverTargets(
+			&protocol.SetDiscoverTargetsParams{Discover: true}, opts.BrowserConn); err != nil {
+			return ClickOutcome{}, err
+		}
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	if err := Click(conn, node.NodeId); err != nil {
+		return ClickOutcome{}, err
+	}
+
+	deadline := time.After(opts.Timeout)
+	quiet := time.After(opts.QuietTimeout)
+	ticker := time.NewTicker(conditionPollInterval)
+	defer ticker.Stop()
+	navigating := false
+	for {
+		select {
+		case <-navStarted:
+			navigating = true
+			quiet = nil
+		case <-loaded:
+			if navigating {
+				return ClickOutcome{Kind: ClickNavigated}, nil
+			}
+		case targetId := <-newTarget:
+			return ClickOutcome{Kind: ClickNewTarget, TargetId: targetId}, nil
+		case <-quiet:
+			return ClickOutcome{Kind: ClickNothing}, nil
+		case <-deadline:
+			return ClickOutcome{}, ErrTimeout
+		case <-conn.Closed():
+			return ClickOutcome{}, conn.Err()
+		case <-ticker.C:
+			if navigating {
+				// The old document is going away, so don't bother with it.
+				continue
+			}
+			var url string
+			if err := evaluate(conn, "location.href", &url, &opts.EvalOptions); err != nil {
+				// Most likely the document is being replaced.
+				logging.Vlog(2, err)
+			} else if url != oldURL {
+				return ClickOutcome{Kind: ClickSameDocument}, nil
+			}
+			if opts.Condition != "" {
+				var ok bool
+				if err := evaluate(conn, "!!("+opts.Condition+")", &ok,
+					&opts.EvalOptions); err != nil {
+					logging.Vlog(2, err)
+				} else if ok {
+					return ClickOutcome{Kind: ClickCondition}, nil
+				}
+			}
+		}
+	}
+}
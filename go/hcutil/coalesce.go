@@ -0,0 +1,45 @@
+package hcutil
+
+import (
+	"sync"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+// Concurrent calls of a helper doing the same thing on a connection, e.g. WaitForCondition
+// polling the same predicate, share one evaluation instead of each sending its own.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type coalescerKey struct{}
+
+// Calls fn, unless a call with key is running on conn already, and returns its result then.
+func coalesce(conn *hc.Conn, key string, fn func() (interface{}, error)) (interface{}, error) {
+	c := conn.Value(coalescerKey{}, func() interface{} {
+		return &coalescer{calls: make(map[string]*coalescedCall)}
+	}).(*coalescer)
+	c.mu.Lock()
+	if call := c.calls[key]; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.value, call.err = fn()
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
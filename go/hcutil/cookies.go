@@ -0,0 +1,199 @@
+package hcutil
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Manages cookies of one browser context.
+//
+// Protocol v1.2 has no Storage.getCookies/setCookies taking a browserContextId, and Network
+// cookie commands sent to the browser connection hit the default context. But sent to a page
+// connection, they only see the cookies of the page's context. So a CookieJar keeps a blank page
+// in the context and routes everything through it. This is the only way to not accidentally
+// share cookies between contexts.
+type CookieJar struct {
+	conn      *hc.Conn
+	pageConn  *hc.Conn
+	targetId  protocol.TargetID
+	ContextId protocol.BrowserContextID
+}
+
+// Opens a cookie jar of the browser context. conn is a browser connection.
+func NewCookieJar(browser *hc.Browser, conn *hc.Conn, contextId protocol.BrowserContextID) (
+	*CookieJar, error) {
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{
+		Url: "about:blank", BrowserContextId: contextId}, conn)
+	if err != nil {
+		return nil, err
+	}
+	jar := &CookieJar{conn: conn, targetId: target.TargetId, ContextId: contextId}
+	if jar.pageConn, err = browser.NewPageConn(string(jar.targetId)); err != nil {
+		jar.Close()
+		return nil, err
+	}
+	return jar, nil
+}
+
+// Closes the page behind the jar. Cookies stay in the context.
+func (j *CookieJar) Close() {
+	if j.pageConn != nil {
+		j.pageConn.Close()
+	}
+	if _, err := protocol.CloseTarget(
+		&protocol.CloseTargetParams{TargetId: j.targetId}, j.conn); err != nil {
+		logging.Vlog(-1, err)
+	}
+}
+
+// Returns all cookies of the context.
+func (j *CookieJar) Cookies() ([]*protocol.Cookie, error) {
+	result, err := protocol.GetAllCookies(j.pageConn)
+	if err != nil {
+		return nil, err
+	}
+	return result.Cookies, nil
+}
+
+func (j *CookieJar) SetCookie(params *protocol.SetCookieParams) error {
+	result, err := protocol.SetCookie(params, j.pageConn)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("Failed to set cookie '%s' for %s", params.Name, params.Url)
+	}
+	return nil
+}
+
+func (j *CookieJar) DeleteCookie(name, url string) error {
+	return protocol.NetworkDeleteCookie(
+		&protocol.NetworkDeleteCookieParams{CookieName: name, Url: url}, j.pageConn)
+}
+
+// Deletes all cookies of the context.
+func (j *CookieJar) Clear() error {
+	return protocol.ClearBrowserCookies(j.pageConn)
+}
+
+// Deletes cookies of the context matching matcher. Returns how many were deleted.
+func (j *CookieJar) DeleteCookies(matcher CookieMatcher) (int, error) {
+	return DeleteCookies(j.pageConn, matcher)
+}
+
+type CookieMatcher func(cookie *protocol.Cookie) bool
+
+func FilterCookies(cookies []*protocol.Cookie, matcher CookieMatcher) []*protocol.Cookie {
+	var matched []*protocol.Cookie
+	for _, cookie := range cookies {
+		if matcher(cookie) {
+			matched = append(matched, cookie)
+		}
+	}
+	return matched
+}
+
+// Matches cookies matching all matchers.
+func MatchAll(matchers ...CookieMatcher) CookieMatcher {
+	return func(cookie *protocol.Cookie) bool {
+		for _, matcher := range matchers {
+			if !matcher(cookie) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Matches cookies which would be sent with a request to u, per RFC 6265 section 5.4. Cookies
+// whose domains start with a dot are domain cookies, otherwise they are host-only.
+func MatchURL(u *url.URL) CookieMatcher {
+	host := strings.ToLower(u.Hostname())
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	return func(cookie *protocol.Cookie) bool {
+		return (secure || !cookie.Secure) && domainMatch(host, cookie.Domain) &&
+			pathMatch(u.Path, cookie.Path)
+	}
+}
+
+// Matches cookies by name with a glob pattern like "*session*". See path.Match for the syntax.
+func MatchName(pattern string) CookieMatcher {
+	return func(cookie *protocol.Cookie) bool {
+		matched, _ := path.Match(pattern, cookie.Name)
+		return matched
+	}
+}
+
+// Matches cookies expired at now, or unexpired ones if expired is false. Session cookies never
+// expire.
+func MatchExpired(now time.Time, expired bool) CookieMatcher {
+	return func(cookie *protocol.Cookie) bool {
+		isExpired := !cookie.Session && cookie.Expires > 0 &&
+			cookie.Expires <= float64(now.UnixNano())/1e9
+		return isExpired == expired
+	}
+}
+
+// RFC 6265 section 5.1.3. IP addresses only match themselves.
+func domainMatch(host, cookieDomain string) bool {
+	domain := strings.ToLower(cookieDomain)
+	if !strings.HasPrefix(domain, ".") {
+		return host == domain
+	}
+	domain = domain[1:]
+	return host == domain ||
+		(strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil)
+}
+
+// RFC 6265 section 5.1.4.
+func pathMatch(requestPath, cookiePath string) bool {
+	if !strings.HasPrefix(requestPath, "/") {
+		requestPath = "/"
+	}
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return len(requestPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") ||
+		requestPath[len(cookiePath)] == '/'
+}
+
+// Deletes cookies matching matcher, which conn can see. Returns how many were deleted.
+func DeleteCookies(conn *hc.Conn, matcher CookieMatcher) (int, error) {
+	result, err := protocol.GetAllCookies(conn)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, cookie := range FilterCookies(result.Cookies, matcher) {
+		if err := protocol.NetworkDeleteCookie(&protocol.NetworkDeleteCookieParams{
+			CookieName: cookie.Name, Url: cookieURL(cookie)}, conn); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Returns a URL the cookie applies to.
+func cookieURL(cookie *protocol.Cookie) string {
+	u := url.URL{Scheme: "http", Host: strings.TrimPrefix(cookie.Domain, "."), Path: cookie.Path}
+	if cookie.Secure {
+		u.Scheme = "https"
+	}
+	if ip := net.ParseIP(u.Host); ip != nil && ip.To4() == nil {
+		u.Host = "[" + u.Host + "]"
+	}
+	return u.String()
+}
@@ -0,0 +1,139 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+func TestMatchURL(t *testing.T) {
+	for _, c := range []struct {
+		domain, path string
+		secure       bool
+		url          string
+		want         bool
+	}{
+		// Host-only cookies, whose domains have no leading dot, match their hosts exactly.
+		{"example.com", "/", false, "http://example.com/", true},
+		{"example.com", "/", false, "http://www.example.com/", false},
+		{"www.example.com", "/", false, "http://example.com/", false},
+		{"Example.COM", "/", false, "http://example.com/", true},
+		{"example.com", "/", false, "http://EXAMPLE.com/", true},
+		{"example.com", "/", false, "http://example.com:8080/", true},
+
+		// Domain cookies match the domain and its subdomains.
+		{".example.com", "/", false, "http://example.com/", true},
+		{".example.com", "/", false, "http://www.example.com/", true},
+		{".example.com", "/", false, "http://a.b.example.com/", true},
+		{".example.com", "/", false, "http://badexample.com/", false},
+		{".example.com", "/", false, "http://example.com.evil.test/", false},
+		{".www.example.com", "/", false, "http://example.com/", false},
+
+		// IP addresses never domain-match, only match themselves.
+		{"127.0.0.1", "/", false, "http://127.0.0.1/", true},
+		{".0.0.1", "/", false, "http://127.0.0.1/", false},
+		{".127.0.0.1", "/", false, "http://127.0.0.1/", true},
+		{"127.0.0.1", "/", false, "http://127.0.0.2/", false},
+		{"::1", "/", false, "http://[::1]:8080/", true},
+
+		// Path-match of section 5.1.4.
+		{"example.com", "/docs", false, "http://example.com/docs", true},
+		{"example.com", "/docs", false, "http://example.com/docs/a", true},
+		{"example.com", "/docs/", false, "http://example.com/docs/a", true},
+		{"example.com", "/docs", false, "http://example.com/docsearch", false},
+		{"example.com", "/docs/", false, "http://example.com/docs", false},
+		{"example.com", "/docs", false, "http://example.com/", false},
+		{"example.com", "/", false, "http://example.com", true},
+		{"example.com", "", false, "http://example.com/any", true},
+
+		// Secure cookies only go over secure schemes.
+		{"example.com", "/", true, "http://example.com/", false},
+		{"example.com", "/", true, "https://example.com/", true},
+		{"example.com", "/", true, "wss://example.com/", true},
+		{"example.com", "/", false, "https://example.com/", true},
+	} {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookie := &protocol.Cookie{Name: "c", Domain: c.domain, Path: c.path, Secure: c.secure}
+		if got := hcutil.MatchURL(u)(cookie); got != c.want {
+			t.Errorf("Cookie of %s%s, secure %v, matches %s: %v, want %v", c.domain, c.path,
+				c.secure, c.url, got, c.want)
+		}
+	}
+}
+
+func TestMatchNameAndExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cookies := []*protocol.Cookie{
+		{Name: "sessionid", Expires: 2000},
+		{Name: "my_session", Expires: 500},
+		{Name: "theme", Session: true},
+		{Name: "prefs", Expires: 999},
+	}
+	names := func(cookies []*protocol.Cookie) []string {
+		var names []string
+		for _, cookie := range cookies {
+			names = append(names, cookie.Name)
+		}
+		return names
+	}
+	for _, c := range []struct {
+		matcher hcutil.CookieMatcher
+		want    []string
+	}{
+		{hcutil.MatchName("*session*"), []string{"sessionid", "my_session"}},
+		{hcutil.MatchName("session"), nil},
+		{hcutil.MatchExpired(now, true), []string{"my_session", "prefs"}},
+		{hcutil.MatchExpired(now, false), []string{"sessionid", "theme"}},
+		{hcutil.MatchAll(hcutil.MatchName("*session*"), hcutil.MatchExpired(now, true)),
+			[]string{"my_session"}},
+	} {
+		if got := names(hcutil.FilterCookies(cookies, c.matcher)); !reflect.DeepEqual(got,
+			c.want) {
+			t.Errorf("Got %v, want %v", got, c.want)
+		}
+	}
+}
+
+// Each cookie is deleted with a URL it applies to.
+func TestDeleteCookies(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Network.getAllCookies", hctest.FakeResult(map[string]interface{}{
+		"cookies": []map[string]interface{}{
+			{"name": "sid", "domain": ".example.com", "path": "/app", "secure": true},
+			{"name": "sid", "domain": "127.0.0.1", "path": "/"},
+			{"name": "sid", "domain": "::1", "path": "/"},
+			{"name": "theme", "domain": "example.com", "path": "/"},
+		}}))
+	conn, _ := server.NewPageConn()
+	deleted, err := hcutil.DeleteCookies(conn, hcutil.MatchName("sid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("Deleted %d cookies", deleted)
+	}
+	var urls []string
+	for _, cmd := range server.CommandsOf("Network.deleteCookie") {
+		var params struct {
+			CookieName, Url string
+		}
+		json.Unmarshal(cmd.Params, &params)
+		if params.CookieName != "sid" {
+			t.Errorf("Deleted %s", params.CookieName)
+		}
+		urls = append(urls, params.Url)
+	}
+	want := []string{"https://example.com/app", "http://127.0.0.1/", "http://[::1]/"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("Got %v, want %v", urls, want)
+	}
+}
@@ -0,0 +1,301 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// A network request, correlated with the frame and loader it was made for.
+type RequestInfo struct {
+	RequestId protocol.RequestId
+	FrameId   protocol.FrameId
+	LoaderId  protocol.LoaderId
+	Type      protocol.ResourceType
+	// The URL first requested, before redirects.
+	URL string
+	// Whether it's the document request of its loader, as opposed to a subresource.
+	Document bool
+	// Of the first Network.requestWillBeSent.
+	Timestamp protocol.NetworkTimestamp
+	// Redirect responses in the order they happened.
+	Redirects []*protocol.Response
+	// nil till the response is received.
+	Response *protocol.Response
+}
+
+type requestRecord struct {
+	info RequestInfo
+	// Timestamps of redirect responses, for sorting them as events may arrive out of order.
+	redirectTimes []protocol.NetworkTimestamp
+}
+
+// Correlates requests with frames and loaders. After redirects and navigations, ids which look
+// alike point to different things, e.g. requests of a frame made by its previous loader. See
+// Correlate.
+type Correlator struct {
+	mu sync.Mutex
+	// Current loader of each frame, from Page.frameNavigated.
+	loaderMap map[protocol.FrameId]protocol.LoaderId
+	// Loaders replaced by navigations or aborted, whose events are ignored even if they arrive
+	// late.
+	retiredMap map[protocol.FrameId][]protocol.LoaderId
+	requestMap map[protocol.RequestId]*requestRecord
+}
+
+// How many retired loaders of a frame are remembered.
+const maxRetiredLoaders = 8
+
+type correlatorKey struct{}
+
+type correlatorStartKey struct{}
+
+// Returns the correlator of conn, starting it if necessary. Page and Network domains are
+// acquired for the life of conn, see hc.Conn.AcquireDomain. Requests are forgotten once their
+// loader is replaced, i.e. when their frame navigates again, or their frame is detached.
+func Correlate(conn *hc.Conn) (*Correlator, error) {
+	if err := conn.CheckKind("Page.frameNavigated", "Network.requestWillBeSent"); err != nil {
+		return nil, err
+	}
+	if c, _ := conn.Value(correlatorKey{}, nil).(*Correlator); c != nil {
+		return c, nil
+	}
+	// Concurrent calls start one correlator, which is only kept once its domains are enabled.
+	mu := conn.Value(correlatorStartKey{}, func() interface{} {
+		return &sync.Mutex{}
+	}).(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+	if c, _ := conn.Value(correlatorKey{}, nil).(*Correlator); c != nil {
+		return c, nil
+	}
+	c := &Correlator{
+		loaderMap:  make(map[protocol.FrameId]protocol.LoaderId),
+		retiredMap: make(map[protocol.FrameId][]protocol.LoaderId),
+		requestMap: make(map[protocol.RequestId]*requestRecord),
+	}
+	cancels := []func(){
+		conn.AddStateDumper(StateCorrelator, c.state),
+		listen(conn, "Page.frameNavigated", func(params []byte) {
+			evt := &protocol.FrameNavigatedEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Page.frameNavigated", params, err)
+				return
+			}
+			if evt.Frame != nil {
+				c.onFrameNavigated(protocol.FrameId(evt.Frame.Id), evt.Frame.LoaderId)
+			}
+		}),
+		listen(conn, "Page.frameDetached", func(params []byte) {
+			evt := &protocol.FrameDetachedEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Page.frameDetached", params, err)
+				return
+			}
+			c.onFrameDetached(evt.FrameId)
+		}),
+		listen(conn, "Network.requestWillBeSent", func(params []byte) {
+			evt := &protocol.RequestWillBeSentEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Network.requestWillBeSent", params, err)
+				return
+			}
+			c.onRequestWillBeSent(evt)
+		}),
+		listen(conn, "Network.responseReceived", func(params []byte) {
+			evt := &protocol.ResponseReceivedEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Network.responseReceived", params, err)
+				return
+			}
+			c.onResponseReceived(evt)
+		}),
+	}
+	stop := func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+	if err := conn.AcquireDomain("Page", nil); err != nil {
+		stop()
+		return nil, err
+	}
+	if err := conn.AcquireDomain("Network", nil); err != nil {
+		if err := conn.ReleaseDomain("Page"); err != nil {
+			logging.Vlog(1, err)
+		}
+		stop()
+		return nil, err
+	}
+	conn.SetValue(correlatorKey{}, c)
+	return c, nil
+}
+
+func (c *Correlator) onFrameNavigated(frameId protocol.FrameId, loaderId protocol.LoaderId) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.retired(frameId, loaderId) {
+		// Late event of an aborted navigation.
+		return
+	}
+	old, found := c.loaderMap[frameId]
+	c.loaderMap[frameId] = loaderId
+	if found && old != loaderId {
+		c.retire(frameId, old)
+	}
+}
+
+// Drops requests of loaderId, and those arriving later. Must be called with mu held.
+func (c *Correlator) retire(frameId protocol.FrameId, loaderId protocol.LoaderId) {
+	retired := append(c.retiredMap[frameId], loaderId)
+	if len(retired) > maxRetiredLoaders {
+		retired = retired[len(retired)-maxRetiredLoaders:]
+	}
+	c.retiredMap[frameId] = retired
+	for id, record := range c.requestMap {
+		if record.info.FrameId == frameId && record.info.LoaderId == loaderId {
+			delete(c.requestMap, id)
+		}
+	}
+}
+
+// Retires the loaders of navigations in progress, i.e. of requests made by loaders other than
+// the current ones of their frames.
+func (c *Correlator) abortPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, record := range c.requestMap {
+		frameId, loaderId := record.info.FrameId, record.info.LoaderId
+		if current, found := c.loaderMap[frameId]; !found || current != loaderId {
+			c.retire(frameId, loaderId)
+		}
+	}
+}
+
+func (c *Correlator) onFrameDetached(frameId protocol.FrameId) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.loaderMap, frameId)
+	delete(c.retiredMap, frameId)
+	for id, record := range c.requestMap {
+		if record.info.FrameId == frameId {
+			delete(c.requestMap, id)
+		}
+	}
+}
+
+// Must be called with mu held.
+func (c *Correlator) retired(frameId protocol.FrameId, loaderId protocol.LoaderId) bool {
+	for _, id := range c.retiredMap[frameId] {
+		if id == loaderId {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Correlator) onRequestWillBeSent(evt *protocol.RequestWillBeSentEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.retired(evt.FrameId, evt.LoaderId) {
+		return
+	}
+	record := c.requestMap[evt.RequestId]
+	if record == nil {
+		record = &requestRecord{info: RequestInfo{
+			RequestId: evt.RequestId,
+			FrameId:   evt.FrameId,
+			LoaderId:  evt.LoaderId,
+			Type:      evt.Type,
+			Document:  evt.Type == protocol.ResourceTypeDocument,
+			Timestamp: evt.Timestamp,
+		}}
+		c.requestMap[evt.RequestId] = record
+	}
+	if evt.RedirectResponse == nil {
+		// The first request, though it may come after its redirects.
+		record.info.Timestamp = evt.Timestamp
+		if evt.Request != nil {
+			record.info.URL = evt.Request.Url
+		}
+		return
+	}
+	i := sort.Search(len(record.redirectTimes), func(i int) bool {
+		return record.redirectTimes[i] > evt.Timestamp
+	})
+	record.redirectTimes = append(record.redirectTimes, 0)
+	copy(record.redirectTimes[i+1:], record.redirectTimes[i:])
+	record.redirectTimes[i] = evt.Timestamp
+	record.info.Redirects = append(record.info.Redirects, nil)
+	copy(record.info.Redirects[i+1:], record.info.Redirects[i:])
+	record.info.Redirects[i] = evt.RedirectResponse
+}
+
+func (c *Correlator) onResponseReceived(evt *protocol.ResponseReceivedEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if record := c.requestMap[evt.RequestId]; record != nil {
+		record.info.Response = evt.Response
+	}
+}
+
+func (r *requestRecord) copy() *RequestInfo {
+	info := r.info
+	info.Redirects = append([]*protocol.Response(nil), r.info.Redirects...)
+	return &info
+}
+
+// Returns the requests of frameId made since it last navigated, including those of a navigation
+// in progress, sorted by time.
+func (c *Correlator) RequestsForFrame(frameId protocol.FrameId) []*RequestInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var requests []*RequestInfo
+	for _, record := range c.requestMap {
+		if record.info.FrameId == frameId {
+			requests = append(requests, record.copy())
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Timestamp < requests[j].Timestamp
+	})
+	return requests
+}
+
+// Returns the document request of the current loader of frameId, i.e. of the document it
+// shows, or nil. Before the frame has navigated, the latest document request with a response
+// is returned instead.
+func (c *Correlator) MainDocumentRequest(frameId protocol.FrameId) *RequestInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	loaderId, loaderKnown := c.loaderMap[frameId]
+	var latest *requestRecord
+	for _, record := range c.requestMap {
+		info := &record.info
+		if info.FrameId != frameId || !info.Document || info.Response == nil ||
+			(loaderKnown && info.LoaderId != loaderId) {
+			continue
+		}
+		if latest == nil || info.Timestamp > latest.info.Timestamp {
+			latest = record
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return latest.copy()
+}
+
+// Whether loaderId is the loader of the document frameId shows. False if it's unknown, e.g.
+// before the frame has navigated.
+func (c *Correlator) IsCurrentLoader(frameId protocol.FrameId, loaderId protocol.LoaderId) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current, found := c.loaderMap[frameId]
+	return found && current == loaderId
+}
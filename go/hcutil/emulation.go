@@ -0,0 +1,348 @@
+package hcutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Emulation overrides set by this package on a page. Helpers like SetDeviceMetrics, SetUserAgent
+// and EmulateMediaFeature record what they set here, so that they change only their part, and
+// so that SaveEmulationState and RestoreEmulationState can undo them. Overrides set by calling
+// the protocol directly aren't tracked.
+type emulation struct {
+	mu    sync.Mutex
+	state emulationState
+	// Size of the view before this package first changed it, as protocol v1.2 can't clear the
+	// visible size.
+	initialSize *viewSize
+	// Injected matchMedia override of the media features.
+	mediaScriptId protocol.ScriptIdentifier
+}
+
+// Values are never modified once stored, so that copies of the struct are snapshots.
+type emulationState struct {
+	// nil if not overridden.
+	metrics     *protocol.EmulationSetDeviceMetricsOverrideParams
+	visibleSize *viewSize
+	viewport    *protocol.ForceViewportParams
+	geolocation *protocol.EmulationSetGeolocationOverrideParams
+	// "" if not overridden.
+	userAgent string
+	touch     bool
+	media     map[string]string
+}
+
+type viewSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type emulationKey struct{}
+
+func getEmulation(conn *hc.Conn) *emulation {
+	return conn.Value(emulationKey{}, func() interface{} {
+		return &emulation{}
+	}).(*emulation)
+}
+
+// Must be called with mu held before the size of the view changes.
+func (e *emulation) recordInitialSize(conn *hc.Conn) {
+	if e.initialSize != nil || e.state.metrics != nil || e.state.visibleSize != nil {
+		return
+	}
+	var size viewSize
+	if err := Evaluate(conn, "({width: window.innerWidth, height: window.innerHeight})",
+		&size); err != nil {
+		logging.Vlogf(1, "Failed to get the size of the view: %v", err)
+		return
+	}
+	e.initialSize = &size
+}
+
+// The set* methods must be called with mu held. nil clears or resets the override.
+
+func (e *emulation) setMetrics(conn *hc.Conn,
+	params *protocol.EmulationSetDeviceMetricsOverrideParams) error {
+	if params == nil {
+		if err := protocol.EmulationClearDeviceMetricsOverride(conn); err != nil {
+			return err
+		}
+		e.state.metrics = nil
+		return nil
+	}
+	e.recordInitialSize(conn)
+	copied := *params
+	if copied.ScreenOrientation != nil {
+		orientation := *copied.ScreenOrientation
+		copied.ScreenOrientation = &orientation
+	}
+	if err := protocol.EmulationSetDeviceMetricsOverride(&copied, conn); err != nil {
+		return err
+	}
+	e.state.metrics = &copied
+	return nil
+}
+
+func (e *emulation) setVisibleSize(conn *hc.Conn, size *viewSize) error {
+	e.recordInitialSize(conn)
+	target := size
+	if target == nil {
+		if target = e.initialSize; target == nil {
+			return errors.New("Unknown initial size of the view")
+		}
+	}
+	if err := protocol.SetVisibleSize(&protocol.SetVisibleSizeParams{
+		Width: target.Width, Height: target.Height}, conn); err != nil {
+		return err
+	}
+	if size != nil {
+		copied := *size
+		size = &copied
+	}
+	e.state.visibleSize = size
+	return nil
+}
+
+func (e *emulation) setViewport(conn *hc.Conn, params *protocol.ForceViewportParams) error {
+	if params == nil {
+		if err := protocol.ResetViewport(conn); err != nil {
+			return err
+		}
+		e.state.viewport = nil
+		return nil
+	}
+	copied := *params
+	if err := protocol.ForceViewport(&copied, conn); err != nil {
+		return err
+	}
+	e.state.viewport = &copied
+	return nil
+}
+
+func (e *emulation) setUserAgent(conn *hc.Conn, userAgent string) error {
+	// An empty user agent clears the override.
+	if err := protocol.SetUserAgentOverride(
+		&protocol.SetUserAgentOverrideParams{UserAgent: userAgent}, conn); err != nil {
+		return err
+	}
+	e.state.userAgent = userAgent
+	return nil
+}
+
+func (e *emulation) setTouch(conn *hc.Conn, enabled bool) error {
+	if err := protocol.EmulationSetTouchEmulationEnabled(
+		&protocol.EmulationSetTouchEmulationEnabledParams{Enabled: enabled}, conn); err != nil {
+		return err
+	}
+	e.state.touch = enabled
+	return nil
+}
+
+func (e *emulation) setGeolocation(conn *hc.Conn,
+	params *protocol.EmulationSetGeolocationOverrideParams) error {
+	if params == nil {
+		if err := protocol.EmulationClearGeolocationOverride(conn); err != nil {
+			return err
+		}
+		e.state.geolocation = nil
+		return nil
+	}
+	copied := *params
+	if err := protocol.EmulationSetGeolocationOverride(&copied, conn); err != nil {
+		return err
+	}
+	e.state.geolocation = &copied
+	return nil
+}
+
+// Browsers newer than protocol v1.2 take the features of Emulation.setEmulatedMedia, which
+// affects everything. Older ones ignore them, so matchMedia() is overridden as well.
+func (e *emulation) setMedia(conn *hc.Conn, media map[string]string) error {
+	if len(media) == 0 {
+		media = nil
+	}
+	features := []mediaFeature{}
+	for name, value := range media {
+		features = append(features, mediaFeature{name, value})
+	}
+	if err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+		return &rawCommand{
+			name:   "Emulation.setEmulatedMedia",
+			params: map[string]interface{}{"media": "", "features": features},
+			cb:     cb,
+		}
+	}); err != nil {
+		return err
+	}
+
+	if e.mediaScriptId != "" {
+		if err := RemoveInjected(conn, e.mediaScriptId); err != nil {
+			return err
+		}
+		e.mediaScriptId = ""
+	}
+	e.state.media = media
+	if len(media) == 0 {
+		return nil
+	}
+	data, err := jsbuilder.JSValue(media)
+	if err != nil {
+		return err
+	}
+	// Replaces emulated features in queries with ones always true or always false.
+	e.mediaScriptId, err = InjectOnNewDocument(conn, fmt.Sprintf(`(function() {
+	var features = %s;
+	var matchMedia = window.matchMedia;
+	window.matchMedia = function(query) {
+		query = String(query).replace(/\(\s*([a-z-]+)\s*:\s*([a-z-]+)\s*\)/g,
+			function(feature, name, value) {
+				if (!(name in features)) return feature;
+				return features[name] === value ? "(min-width: 0px)" : "(max-width: -1px)";
+			});
+		return matchMedia.call(window, query);
+	};
+})();`, data))
+	return err
+}
+
+// Overrides the user agent, for the page and its requests. Empty userAgent clears the override.
+func SetUserAgent(conn *hc.Conn, userAgent string) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setUserAgent(conn, userAgent)
+}
+
+// Turns emulation of touch events from mouse events on or off.
+func EmulateTouch(conn *hc.Conn, enabled bool) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setTouch(conn, enabled)
+}
+
+// Overrides the position reported by navigator.geolocation, with accuracy in meters.
+func SetGeolocation(conn *hc.Conn, latitude, longitude, accuracy float64) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setGeolocation(conn, &protocol.EmulationSetGeolocationOverrideParams{
+		Latitude: latitude, Longitude: longitude, Accuracy: accuracy})
+}
+
+func ClearGeolocation(conn *hc.Conn) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setGeolocation(conn, nil)
+}
+
+// Emulation overrides of a page at some point. See SaveEmulationState.
+type EmulationState struct {
+	conn  *hc.Conn
+	state emulationState
+}
+
+// Returns the emulation overrides set by this package on the page, i.e. device metrics, visible
+// size, viewport, user agent, touch, geolocation and media features, to be restored with
+// RestoreEmulationState.
+func SaveEmulationState(conn *hc.Conn) *EmulationState {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &EmulationState{conn: conn, state: e.state}
+}
+
+// Sets the overrides saved by SaveEmulationState again. Only the overrides which changed since
+// are sent, and those which weren't set then are cleared. Pages get a resize event if the size
+// of the view changed, like with SetViewportSize.
+func RestoreEmulationState(conn *hc.Conn, saved *EmulationState) error {
+	if saved.conn != conn {
+		return errors.New("Emulation state saved on another connection")
+	}
+	e := getEmulation(conn)
+	e.mu.Lock()
+	resized, err := e.restore(conn, &saved.state)
+	e.mu.Unlock()
+	if err != nil || !resized {
+		return err
+	}
+	return settleResize(conn)
+}
+
+// Must be called with mu held. Returns whether the size of the view may have changed.
+func (e *emulation) restore(conn *hc.Conn, saved *emulationState) (resized bool, err error) {
+	current := e.state
+	if !reflect.DeepEqual(current.metrics, saved.metrics) {
+		if err := e.setMetrics(conn, saved.metrics); err != nil {
+			return false, err
+		}
+		resized = true
+	}
+	if !reflect.DeepEqual(current.visibleSize, saved.visibleSize) {
+		if err := e.setVisibleSize(conn, saved.visibleSize); err != nil {
+			return resized, err
+		}
+		resized = true
+	}
+	if !reflect.DeepEqual(current.viewport, saved.viewport) {
+		if err := e.setViewport(conn, saved.viewport); err != nil {
+			return resized, err
+		}
+	}
+	if current.userAgent != saved.userAgent {
+		if err := e.setUserAgent(conn, saved.userAgent); err != nil {
+			return resized, err
+		}
+	}
+	if current.touch != saved.touch {
+		if err := e.setTouch(conn, saved.touch); err != nil {
+			return resized, err
+		}
+	}
+	if !reflect.DeepEqual(current.geolocation, saved.geolocation) {
+		if err := e.setGeolocation(conn, saved.geolocation); err != nil {
+			return resized, err
+		}
+	}
+	if !reflect.DeepEqual(current.media, saved.media) {
+		if err := e.setMedia(conn, saved.media); err != nil {
+			return resized, err
+		}
+	}
+	return resized, nil
+}
+
+// Runs fn, then restores the emulation overrides set by this package to what they were before,
+// whether fn fails or not. Returns the error of fn if any, otherwise that of restoring.
+func WithEmulation(conn *hc.Conn, fn func() error) error {
+	saved := SaveEmulationState(conn)
+	err := fn()
+	if restoreErr := RestoreEmulationState(conn, saved); restoreErr != nil {
+		if err == nil {
+			return restoreErr
+		}
+		logging.Vlogf(-1, "Failed to restore emulation: %v", restoreErr)
+	}
+	return err
+}
+
+// Runs fn with device metrics overridden by params, keeping the other overrides. See
+// WithEmulation.
+func WithDeviceMetrics(conn *hc.Conn, params *protocol.EmulationSetDeviceMetricsOverrideParams,
+	fn func() error) error {
+	return WithEmulation(conn, func() error {
+		if err := SetDeviceMetrics(conn, params); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
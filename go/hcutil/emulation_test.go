@@ -0,0 +1,165 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// What a page sees of the emulation overrides, kept up to date by the fake browser.
+type fakePage struct {
+	mu        sync.Mutex
+	width     int
+	height    int
+	userAgent string
+	media     map[string]string
+	scripts   map[string]bool
+}
+
+const defaultUserAgent = "HeadlessChrome"
+
+func newFakePage(server *hctest.FakeServer) *fakePage {
+	p := &fakePage{width: 800, height: 600, userAgent: defaultUserAgent,
+		media: map[string]string{}, scripts: map[string]bool{}}
+	handle := func(method string, fn func(params json.RawMessage) interface{}) {
+		server.Handle(method, func(cmd *hctest.FakeCommand) (interface{}, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return fn(cmd.Params), nil
+		})
+	}
+	handle("Emulation.setDeviceMetricsOverride", func(params json.RawMessage) interface{} {
+		var metrics struct{ Width, Height int }
+		json.Unmarshal(params, &metrics)
+		p.width, p.height = metrics.Width, metrics.Height
+		return struct{}{}
+	})
+	handle("Emulation.clearDeviceMetricsOverride", func(json.RawMessage) interface{} {
+		p.width, p.height = 800, 600
+		return struct{}{}
+	})
+	handle("Network.setUserAgentOverride", func(params json.RawMessage) interface{} {
+		var ua struct{ UserAgent string }
+		json.Unmarshal(params, &ua)
+		if p.userAgent = ua.UserAgent; ua.UserAgent == "" {
+			p.userAgent = defaultUserAgent
+		}
+		return struct{}{}
+	})
+	handle("Emulation.setEmulatedMedia", func(params json.RawMessage) interface{} {
+		var media struct {
+			Features []struct{ Name, Value string }
+		}
+		json.Unmarshal(params, &media)
+		p.media = map[string]string{}
+		for _, f := range media.Features {
+			p.media[f.Name] = f.Value
+		}
+		return struct{}{}
+	})
+	handle("Page.addScriptToEvaluateOnLoad", func(json.RawMessage) interface{} {
+		id := string(rune('a' + len(p.scripts)))
+		p.scripts[id] = true
+		return map[string]string{"identifier": id}
+	})
+	handle("Page.removeScriptToEvaluateOnLoad", func(params json.RawMessage) interface{} {
+		var script struct{ Identifier string }
+		json.Unmarshal(params, &script)
+		delete(p.scripts, script.Identifier)
+		return struct{}{}
+	})
+	handle("Runtime.evaluate", func(params json.RawMessage) interface{} {
+		var eval struct{ Expression string }
+		json.Unmarshal(params, &eval)
+		var value interface{} = true
+		if strings.Contains(eval.Expression, "innerWidth") {
+			value = map[string]int{"width": p.width, "height": p.height}
+		}
+		data, _ := json.Marshal(value)
+		return map[string]interface{}{
+			"result": map[string]interface{}{"type": "object", "value": json.RawMessage(data)}}
+	})
+	return p
+}
+
+func (p *fakePage) observed() (int, int, string, map[string]string, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	media := make(map[string]string, len(p.media))
+	for k, v := range p.media {
+		media[k] = v
+	}
+	return p.width, p.height, p.userAgent, media, len(p.scripts)
+}
+
+func TestRestoreEmulationState(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	page := newFakePage(server)
+	conn, _ := server.NewPageConn()
+
+	saved := hcutil.SaveEmulationState(conn)
+	if err := hcutil.SetDeviceMetrics(conn, &protocol.EmulationSetDeviceMetricsOverrideParams{
+		Width: 375, Height: 667, DeviceScaleFactor: 2, Mobile: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := hcutil.SetUserAgent(conn, "Mobile"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hcutil.EmulateMediaFeature(conn, "prefers-color-scheme", "dark"); err != nil {
+		t.Fatal(err)
+	}
+	width, height, ua, media, scripts := page.observed()
+	if width != 375 || height != 667 || ua != "Mobile" ||
+		media["prefers-color-scheme"] != "dark" || scripts != 1 {
+		t.Fatalf("Got %dx%d, %q, %v, %d scripts", width, height, ua, media, scripts)
+	}
+
+	if err := hcutil.RestoreEmulationState(conn, saved); err != nil {
+		t.Fatal(err)
+	}
+	width, height, ua, media, scripts = page.observed()
+	if width != 800 || height != 600 || ua != defaultUserAgent || len(media) != 0 ||
+		scripts != 0 {
+		t.Errorf("Got %dx%d, %q, %v, %d scripts after restoring", width, height, ua, media,
+			scripts)
+	}
+	if !reflect.DeepEqual(hcutil.SaveEmulationState(conn), saved) {
+		t.Error("Saved state differs after restoring")
+	}
+
+	// Restoring again sends nothing.
+	n := len(server.Commands())
+	if err := hcutil.RestoreEmulationState(conn, saved); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := server.Commands()[n:]; len(cmds) != 0 {
+		t.Errorf("Sent %s restoring an unchanged state", cmds[0].Method)
+	}
+}
+
+func TestWithDeviceMetrics(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	page := newFakePage(server)
+	conn, _ := server.NewPageConn()
+	if err := hcutil.SetUserAgent(conn, "Kept"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hcutil.WithDeviceMetrics(conn, &protocol.EmulationSetDeviceMetricsOverrideParams{
+		Width: 1024, Height: 768, DeviceScaleFactor: 1}, func() error {
+		if width, _, ua, _, _ := page.observed(); width != 1024 || ua != "Kept" {
+			t.Errorf("Got width %d and %q inside", width, ua)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if width, _, ua, _, _ := page.observed(); width != 800 || ua != "Kept" {
+		t.Errorf("Got width %d and %q after", width, ua)
+	}
+}
@@ -0,0 +1,244 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Collects uncaught exceptions and console errors of a page matching some patterns, e.g. to gate
+// a release on "no TypeError during this flow". Created by ErrorWatch.
+type Watch struct {
+	conn     *hc.Conn
+	patterns []*regexp.Regexp
+	// Milliseconds since epoch, like protocol.RuntimeTimestamp.
+	start   float64
+	cancels []func()
+	// Runtime domain is acquired till Stop.
+	acquired bool
+
+	mu      sync.Mutex
+	matches ErrorMatches
+	stopped bool
+}
+
+// An exception or console error matching a pattern of a Watch.
+type ErrorMatch struct {
+	Pattern string
+	// "exception", or the type of the console call, "error" or "assert".
+	Source string
+	Text   string
+	// Where it was thrown or logged, if known. Line and Column are 1-based.
+	URL          string
+	Line, Column int
+	// One "at function (url:line:column)" line per call frame.
+	Stack string
+	Time  time.Time
+}
+
+func (m *ErrorMatch) String() string {
+	s := fmt.Sprintf("%s matching /%s/: %s", m.Source, m.Pattern, m.Text)
+	if m.URL != "" {
+		s += fmt.Sprintf(" at %s:%d:%d", m.URL, m.Line, m.Column)
+	}
+	if m.Stack != "" {
+		s += "\n" + m.Stack
+	}
+	return s
+}
+
+// Returned by Watch.Assert, with all matches.
+type ErrorMatches []*ErrorMatch
+
+func (e ErrorMatches) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%d errors matched", len(e)))
+	for _, m := range e {
+		lines = append(lines, m.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Starts collecting uncaught exceptions and console.error and console.assert calls of the page
+// whose text matches any of patterns, which are regular expressions, e.g.
+// "TypeError|ReferenceError". Entries logged before the watch started aren't collected, nor are
+// those of other pages or workers of the browser, which are never sent to conn. Runtime domain
+// is enabled till Stop, unless other helpers need it, see hc.Conn.AcquireDomain.
+func ErrorWatch(conn *hc.Conn, patterns []string) (*Watch, error) {
+	if err := conn.CheckKind("Runtime.consoleAPICalled", "Runtime.exceptionThrown"); err != nil {
+		return nil, err
+	}
+	w := &Watch{conn: conn, start: float64(time.Now().UnixNano()) / 1e6}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern '%s': %v", pattern, err)
+		}
+		w.patterns = append(w.patterns, re)
+	}
+	w.cancels = append(w.cancels,
+		listen(conn, "Runtime.exceptionThrown", func(params []byte) {
+			evt := &protocol.ExceptionThrownEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Runtime.exceptionThrown", params, err)
+				return
+			}
+			if details := evt.ExceptionDetails; details != nil {
+				w.check(float64(evt.Timestamp), "exception", exceptionText(details), details.Url,
+					details.LineNumber, details.ColumnNumber, details.StackTrace)
+			}
+		}),
+		listen(conn, "Runtime.consoleAPICalled", func(params []byte) {
+			evt := &protocol.ConsoleAPICalledEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Runtime.consoleAPICalled", params, err)
+				return
+			}
+			if evt.Type != "error" && evt.Type != "assert" {
+				return
+			}
+			var url string
+			var line, column int
+			if evt.StackTrace != nil && len(evt.StackTrace.CallFrames) > 0 {
+				frame := evt.StackTrace.CallFrames[0]
+				url, line, column = frame.Url, frame.LineNumber, frame.ColumnNumber
+			}
+			w.check(float64(evt.Timestamp), evt.Type, consoleText(evt.Args), url, line, column,
+				evt.StackTrace)
+		}))
+	if err := conn.AcquireDomain("Runtime", nil); err != nil {
+		w.Stop()
+		return nil, err
+	}
+	w.acquired = true
+	return w, nil
+}
+
+// Records the entry if it matches. line and column are 0-based.
+func (w *Watch) check(timestamp float64, source, text, url string, line, column int,
+	stack *protocol.StackTrace) {
+	// Runtime.enable reports entries logged before.
+	if timestamp != 0 && timestamp < w.start {
+		return
+	}
+	for _, re := range w.patterns {
+		if !re.MatchString(text) {
+			continue
+		}
+		match := &ErrorMatch{
+			Pattern: re.String(),
+			Source:  source,
+			Text:    text,
+			URL:     url,
+			Stack:   formatStack(stack),
+			Time:    time.Now(),
+		}
+		if url != "" {
+			match.Line, match.Column = line+1, column+1
+		}
+		w.mu.Lock()
+		if !w.stopped {
+			w.matches = append(w.matches, match)
+		}
+		w.mu.Unlock()
+		return
+	}
+}
+
+func exceptionText(details *protocol.ExceptionDetails) string {
+	if details.Exception != nil && details.Exception.Description != "" {
+		return details.Text + " " + details.Exception.Description
+	}
+	return details.Text
+}
+
+// Joins the arguments of a console call like the console shows them.
+func consoleText(args []*protocol.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		var s string
+		if arg.Type == "string" && json.Unmarshal(arg.Value, &s) == nil {
+			parts = append(parts, s)
+		} else if arg.Description != "" {
+			parts = append(parts, arg.Description)
+		} else if arg.UnserializableValue != "" {
+			parts = append(parts, string(arg.UnserializableValue))
+		} else {
+			parts = append(parts, string(arg.Value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatStack(stack *protocol.StackTrace) string {
+	var lines []string
+	for ; stack != nil; stack = stack.Parent {
+		for _, frame := range stack.CallFrames {
+			name := frame.FunctionName
+			if name == "" {
+				name = "<anonymous>"
+			}
+			lines = append(lines, fmt.Sprintf("    at %s (%s:%d:%d)", name, frame.Url,
+				frame.LineNumber+1, frame.ColumnNumber+1))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the entries collected so far.
+func (w *Watch) Matches() ErrorMatches {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append(ErrorMatches(nil), w.matches...)
+}
+
+// Returns ErrorMatches listing the entries collected so far, or nil if there are none.
+func (w *Watch) Assert() error {
+	if matches := w.Matches(); len(matches) > 0 {
+		return matches
+	}
+	return nil
+}
+
+// Number of entries collected so far, for matchesSince.
+func (w *Watch) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.matches)
+}
+
+func (w *Watch) matchesSince(n int) ErrorMatches {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if n >= len(w.matches) {
+		return nil
+	}
+	return append(ErrorMatches(nil), w.matches[n:]...)
+}
+
+// Stops collecting. Entries collected before are kept.
+func (w *Watch) Stop() {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.stopped = true
+	w.mu.Unlock()
+	for _, cancel := range w.cancels {
+		cancel()
+	}
+	if w.acquired {
+		if err := w.conn.ReleaseDomain("Runtime"); err != nil {
+			logging.Vlog(1, err)
+		}
+	}
+}
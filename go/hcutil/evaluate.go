@@ -0,0 +1,41 @@
+// Package hcutil contains higher level helpers built on top of the generated protocol package.
+package hcutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Evaluates expression in the page and unmarshals its value into result, which may be nil.
+func Evaluate(conn *hc.Conn, expression string, result interface{}) error {
+	return EvaluateWithParams(conn, &protocol.EvaluateParams{Expression: expression}, result)
+}
+
+// Like Evaluate, but allows to customize the evaluation. ReturnByValue is always set. Like all
+// evaluations of helpers, it waits for the throttle of conn, see hc.Conn.SetEvaluateThrottle.
+func EvaluateWithParams(conn *hc.Conn, params *protocol.EvaluateParams, result interface{}) error {
+	params.ReturnByValue = true
+	if err := conn.ThrottleEvaluation(); err != nil {
+		return err
+	}
+	if res, err := protocol.Evaluate(params, conn); err != nil {
+		return err
+	} else if res.ExceptionDetails != nil {
+		return exceptionError(res.ExceptionDetails)
+	} else if result == nil || res.Result == nil || len(res.Result.Value) == 0 {
+		return nil
+	} else {
+		return json.Unmarshal([]byte(res.Result.Value), result)
+	}
+}
+
+func exceptionError(details *protocol.ExceptionDetails) error {
+	if details.Exception != nil && details.Exception.Description != "" {
+		return fmt.Errorf("%s: %s", details.Text, details.Exception.Description)
+	}
+	return fmt.Errorf("%s (line %d, column %d)", details.Text, details.LineNumber,
+		details.ColumnNumber)
+}
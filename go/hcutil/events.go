@@ -0,0 +1,17 @@
+package hcutil
+
+import (
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+// Calls cb with the raw params of every event named name. Call the returned function to stop.
+// Note that, like all event sinks, cb may be called concurrently and out of order.
+func listen(conn *hc.Conn, name string, cb func(params []byte)) (cancel func()) {
+	sink := hc.FuncToEventSink(func(_ string, params []byte) {
+		cb(params)
+	})
+	conn.AddEventSink(name, sink)
+	return func() {
+		conn.RemoveEventSink(name, sink)
+	}
+}
@@ -0,0 +1,90 @@
+package hcutil
+
+import (
+	"fmt"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+type EvalOptions struct {
+	// If set, scripts run in this isolated world of the main frame instead of the page's own.
+	World *IsolatedWorld
+	// If set, scripts run in the selected frame, in World or an isolated world shared by
+	// helpers of this package, since the frame's own execution context isn't tracked.
+	Frame *FrameSelector
+}
+
+type frameWorldKey struct{}
+
+func evaluate(conn *hc.Conn, expression string, result interface{}, opts *EvalOptions) error {
+	if opts != nil && opts.Frame != nil {
+		frame, err := FindFrame(conn, opts.Frame)
+		if err != nil {
+			return err
+		}
+		world := opts.World
+		if world == nil {
+			world = conn.Value(frameWorldKey{}, func() interface{} {
+				return NewIsolatedWorld(conn, "__hc_frames")
+			}).(*IsolatedWorld)
+		}
+		return world.Evaluate(frame.Id, expression, result)
+	}
+	if opts != nil && opts.World != nil {
+		return opts.World.Evaluate("", expression, result)
+	}
+	return Evaluate(conn, expression, result)
+}
+
+type Link struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Returns all links of the page, with absolute URLs. Falls back to DOM commands if JavaScript is
+// disabled by DisableJavaScript.
+func ExtractLinks(conn *hc.Conn, opts *EvalOptions) (links []Link, err error) {
+	if JavaScriptDisabled(conn) {
+		return extractLinksWithDOM(conn)
+	}
+	err = evaluate(conn, `Array.prototype.map.call(document.querySelectorAll("a[href]"),
+	function(a) { return {href: a.href, text: a.innerText}; })`, &links, opts)
+	return
+}
+
+const conditionPollInterval = 100 * time.Millisecond
+
+// Polls expression till it's truthy. Concurrent waits for the same expression share each
+// evaluation. Returns ErrJSDisabled if JavaScript is disabled by DisableJavaScript, as the
+// condition would never change.
+func WaitForCondition(conn *hc.Conn, expression string, timeout time.Duration,
+	opts *EvalOptions) error {
+	if err := conn.CheckKind("Runtime.evaluate"); err != nil {
+		return err
+	}
+	if JavaScriptDisabled(conn) {
+		return ErrJSDisabled
+	}
+	key := "condition:" + expression
+	if opts != nil {
+		key = fmt.Sprintf("condition:%p:%p:%s", opts.World, opts.Frame, expression)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := coalesce(conn, key, func() (interface{}, error) {
+			var ok bool
+			err := evaluate(conn, "!!("+expression+")", &ok, opts)
+			return ok, err
+		})
+		if err != nil {
+			return connErr(conn, err)
+		} else if ok.(bool) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(conditionPollInterval)
+	}
+}
@@ -0,0 +1,335 @@
+package hcutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// A point of a page load to capture with CaptureFilmstrip.
+type Milestone string
+
+const (
+	MilestoneDOMContentLoaded Milestone = "DOMContentLoaded"
+	// Needs a browser newer than protocol v1.2, with Page.lifecycleEvent.
+	MilestoneFirstPaint Milestone = "firstPaint"
+	MilestoneLoad       Milestone = "load"
+	// No requests for networkIdleTime after DOMContentLoaded, like Chrome's networkIdle.
+	MilestoneNetworkIdle Milestone = "networkIdle"
+)
+
+const networkIdleTime = 500 * time.Millisecond
+
+// A screenshot taken at a milestone.
+type FrameCapture struct {
+	Milestone Milestone
+	// When the milestone was reached, relative to the start of the navigation.
+	Offset time.Duration
+	// PNG data. nil if skipped or the screenshot failed.
+	Data []byte
+	// The milestone wasn't reached within the timeout, or the browser can't report it.
+	Skipped bool
+	// Why it was skipped or not captured.
+	Err error
+}
+
+type reachedMilestone struct {
+	milestone Milestone
+	offset    time.Duration
+}
+
+// Navigates the page to url and captures a screenshot with opts, which may be nil, as each of
+// milestones is reached, like the filmstrip of DevTools. Frames are returned in the order the
+// milestones were reached, followed by the skipped ones. The page keeps loading while a
+// screenshot is taken, so it may show a bit more than the milestone. If a screenshot fails, e.g.
+// because the renderer is busy, the latest screencast frame is used instead. Page domain is
+// enabled meanwhile, see hc.Conn.AcquireDomain.
+func CaptureFilmstrip(conn *hc.Conn, url string, milestones []Milestone, opts *ScreenshotOptions,
+	timeout time.Duration) ([]FrameCapture, error) {
+	if err := conn.CheckKind("Page.navigate", "Page.captureScreenshot"); err != nil {
+		return nil, err
+	}
+	// Only the capture loop changes pending. requested is read by event sinks.
+	pending := make(map[Milestone]bool)
+	requested := make(map[Milestone]bool)
+	for _, m := range milestones {
+		switch m {
+		case MilestoneDOMContentLoaded, MilestoneFirstPaint, MilestoneLoad, MilestoneNetworkIdle:
+			pending[m], requested[m] = true, true
+		default:
+			return nil, fmt.Errorf("Unknown milestone '%s'", m)
+		}
+	}
+	if err := conn.AcquireDomain("Page", nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.ReleaseDomain("Page"); err != nil {
+			logging.Vlog(1, err)
+		}
+	}()
+
+	start := time.Now()
+	ch := make(chan reachedMilestone, len(requested))
+	var mu sync.Mutex
+	fired := make(map[Milestone]bool)
+	fire := func(m Milestone) {
+		mu.Lock()
+		defer mu.Unlock()
+		if requested[m] && !fired[m] {
+			fired[m] = true
+			ch <- reachedMilestone{m, time.Since(start)}
+		}
+	}
+	var frames []FrameCapture
+	skip := func(m Milestone, err error) {
+		delete(pending, m)
+		frames = append(frames, FrameCapture{Milestone: m, Skipped: true, Err: err})
+	}
+
+	idle := newNetworkIdleTracker()
+	var cancels []func()
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	cancels = append(cancels,
+		listen(conn, "Page.domContentEventFired", func([]byte) {
+			idle.parsed()
+			fire(MilestoneDOMContentLoaded)
+		}),
+		listen(conn, "Page.loadEventFired", func([]byte) {
+			fire(MilestoneLoad)
+		}))
+	if pending[MilestoneFirstPaint] {
+		cancel, err := listenFirstPaint(conn, func() { fire(MilestoneFirstPaint) })
+		if err != nil {
+			skip(MilestoneFirstPaint, err)
+		} else {
+			cancels = append(cancels, cancel)
+		}
+	}
+	if pending[MilestoneNetworkIdle] {
+		cancels = append(cancels, idle.listen(conn))
+		if err := conn.AcquireDomain("Network", nil); err != nil {
+			return nil, err
+		}
+		cancels = append(cancels, func() {
+			if err := conn.ReleaseDomain("Network"); err != nil {
+				logging.Vlog(1, err)
+			}
+		})
+		done := make(chan struct{})
+		cancels = append(cancels, func() { close(done) })
+		go idle.wait(done, func() { fire(MilestoneNetworkIdle) })
+	}
+	latestFrame, stopScreencast := startFallbackScreencast(conn)
+	defer stopScreencast()
+
+	if _, err := protocol.Navigate(&protocol.NavigateParams{Url: url}, conn); err != nil {
+		return nil, connErr(conn, err)
+	}
+	deadline := time.After(timeout)
+capture:
+	for len(pending) > 0 {
+		select {
+		case reached := <-ch:
+			delete(pending, reached.milestone)
+			frame := FrameCapture{Milestone: reached.milestone, Offset: reached.offset}
+			frame.Data, frame.Err = CaptureScreenshot(conn, opts)
+			if frame.Err != nil {
+				if data := latestFrame(); data != nil {
+					logging.Vlogf(1, "Using a screencast frame for %s: %v", reached.milestone,
+						frame.Err)
+					frame.Data, frame.Err = data, nil
+				}
+			}
+			frames = append(frames, frame)
+		case <-deadline:
+			break capture
+		case <-conn.Closed():
+			return nil, conn.Err()
+		}
+	}
+	// In the requested order.
+	for _, m := range milestones {
+		if pending[m] {
+			skip(m, ErrTimeout)
+		}
+	}
+	return frames, nil
+}
+
+// Calls cb on the first paint of the next document of the main frame.
+func listenFirstPaint(conn *hc.Conn, cb func()) (cancel func(), err error) {
+	tree, err := protocol.GetResourceTree(conn)
+	if err != nil {
+		return nil, err
+	}
+	mainFrameId := tree.FrameTree.Frame.Id
+	var mu sync.Mutex
+	armed := false
+	cancel = listen(conn, "Page.lifecycleEvent", func(params []byte) {
+		var evt struct {
+			FrameId string `json:"frameId"`
+			Name    string `json:"name"`
+		}
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("Page.lifecycleEvent", params, err)
+			return
+		}
+		mu.Lock()
+		ok := armed && evt.FrameId == mainFrameId && evt.Name == "firstPaint"
+		mu.Unlock()
+		if ok {
+			cb()
+		}
+	})
+	if err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+		return &rawCommand{
+			name:   "Page.setLifecycleEventsEnabled",
+			params: map[string]interface{}{"enabled": true},
+			cb:     cb,
+		}
+	}); err != nil {
+		cancel()
+		return nil, err
+	}
+	// Enabling them reports the events of the current document, which must be ignored.
+	ctx, cancelFlush := context.WithTimeout(context.Background(), capabilityTimeout)
+	defer cancelFlush()
+	if err := conn.Flush(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	mu.Lock()
+	armed = true
+	mu.Unlock()
+	return cancel, nil
+}
+
+// Counts requests in flight, to tell when the network went idle after the document was parsed.
+type networkIdleTracker struct {
+	mu         sync.Mutex
+	isParsed   bool
+	inFlight   map[string]bool
+	lastChange time.Time
+}
+
+func newNetworkIdleTracker() *networkIdleTracker {
+	return &networkIdleTracker{inFlight: make(map[string]bool), lastChange: time.Now()}
+}
+
+func (t *networkIdleTracker) parsed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.isParsed = true
+}
+
+func (t *networkIdleTracker) update(requestId string, inFlight bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if inFlight {
+		t.inFlight[requestId] = true
+	} else {
+		delete(t.inFlight, requestId)
+	}
+	t.lastChange = time.Now()
+}
+
+func (t *networkIdleTracker) listen(conn *hc.Conn) (cancel func()) {
+	var cancels []func()
+	for name, inFlight := range map[string]bool{
+		"Network.requestWillBeSent": true,
+		"Network.loadingFinished":   false,
+		"Network.loadingFailed":     false,
+	} {
+		name, inFlight := name, inFlight
+		cancels = append(cancels, listen(conn, name, func(params []byte) {
+			var evt struct {
+				RequestId string `json:"requestId"`
+			}
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError(name, params, err)
+				return
+			}
+			t.update(evt.RequestId, inFlight)
+		}))
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+// Calls cb once the network is idle, unless done is closed first.
+func (t *networkIdleTracker) wait(done <-chan struct{}, cb func()) {
+	ticker := time.NewTicker(networkIdleTime / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.mu.Lock()
+			idle := t.isParsed && len(t.inFlight) == 0 && time.Since(t.lastChange) >= networkIdleTime
+			t.mu.Unlock()
+			if idle {
+				cb()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Starts a PNG screencast keeping the latest frame, returned by latest. Returns a latest always
+// returning nil if the screencast can't be started.
+func startFallbackScreencast(conn *hc.Conn) (latest func() []byte, stop func()) {
+	var mu sync.Mutex
+	var data []byte
+	cancelAck := ackScreencastFrames(conn, nil)
+	cancelListen := listen(conn, "Page.screencastFrame", func(params []byte) {
+		evt := &protocol.ScreencastFrameEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError("Page.screencastFrame", params, err)
+			return
+		}
+		frame, err := base64.StdEncoding.DecodeString(evt.Data)
+		if err != nil {
+			logging.Vlog(2, err)
+			return
+		}
+		mu.Lock()
+		data = frame
+		mu.Unlock()
+	})
+	latest = func() []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return data
+	}
+	if err := protocol.StartScreencast(
+		&protocol.StartScreencastParams{Format: "png"}, conn); err != nil {
+		logging.Vlogf(1, "Failed to start screencast: %v", err)
+		cancelAck()
+		cancelListen()
+		return latest, func() {}
+	}
+	return latest, func() {
+		if err := protocol.StopScreencast(conn); err != nil {
+			logging.Vlog(1, err)
+		}
+		cancelAck()
+		cancelListen()
+	}
+}
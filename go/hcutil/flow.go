@@ -0,0 +1,217 @@
+package hcutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+// A linear sequence of steps on a page, which stops at the first failing step, e.g.
+//
+//	flow := hcutil.NewFlow(conn)
+//	flow.Step("open", func(ctx context.Context) error { ... })
+//	flow.Step("login", func(ctx context.Context) error { ... })
+//	report, err := flow.Run(ctx)
+type Flow struct {
+	conn  *hc.Conn
+	steps []*flowStep
+	// Timeout of steps which don't have their own. 0 means no timeout other than the flow's.
+	StepTimeout time.Duration
+}
+
+type flowStep struct {
+	name     string
+	fn       func(ctx context.Context) error
+	timeout  time.Duration
+	attempts int
+	backoff  time.Duration
+	// Fails the step if any of them collects entries while it runs.
+	watches []*Watch
+}
+
+type StepStatus string
+
+const (
+	StepOk      StepStatus = "ok"
+	StepFailed  StepStatus = "failed"
+	StepSkipped StepStatus = "skipped"
+)
+
+type StepReport struct {
+	Name     string
+	Status   StepStatus
+	Duration time.Duration
+	Attempts int
+	Err      error
+}
+
+type FlowReport struct {
+	Steps    []StepReport
+	Duration time.Duration
+	// Captured when a step fails, to help finding out why. Empty if they can't be captured.
+	FailureURL        string
+	FailureScreenshot []byte
+}
+
+func NewFlow(conn *hc.Conn) *Flow {
+	return &Flow{conn: conn}
+}
+
+func (f *Flow) Step(name string, fn func(ctx context.Context) error) *Flow {
+	return f.StepWithTimeout(name, 0, fn)
+}
+
+// Like Step, but fails the step if it takes longer than timeout.
+func (f *Flow) StepWithTimeout(name string, timeout time.Duration,
+	fn func(ctx context.Context) error) *Flow {
+	f.steps = append(f.steps, &flowStep{name: name, fn: fn, timeout: timeout, attempts: 1})
+	return f
+}
+
+// Like Step, but runs fn up to attempts times till it succeeds, waiting backoff, doubled each
+// time, in between. Each attempt has its own timeout.
+func (f *Flow) RetryStep(name string, attempts int, backoff time.Duration,
+	fn func(ctx context.Context) error) *Flow {
+	if attempts < 1 {
+		attempts = 1
+	}
+	f.steps = append(f.steps, &flowStep{name: name, fn: fn, attempts: attempts, backoff: backoff})
+	return f
+}
+
+// Makes the step added last fail if watch collects any entry while it runs, e.g.
+//
+//	watch, err := hcutil.ErrorWatch(conn, []string{"TypeError|ReferenceError"})
+//	...
+//	defer watch.Stop()
+//	flow.Step("checkout", checkout).FailOnErrors(watch)
+//
+// The step's error is then ErrorMatches with the new entries.
+func (f *Flow) FailOnErrors(watch *Watch) *Flow {
+	if len(f.steps) > 0 {
+		step := f.steps[len(f.steps)-1]
+		step.watches = append(step.watches, watch)
+	}
+	return f
+}
+
+// Runs the steps in order. Returns the error of the first failing step, if any. Steps after it
+// are reported as skipped.
+func (f *Flow) Run(ctx context.Context) (*FlowReport, error) {
+	report := &FlowReport{}
+	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
+	var firstErr error
+	for _, step := range f.steps {
+		if firstErr != nil {
+			report.Steps = append(report.Steps, StepReport{Name: step.name, Status: StepSkipped})
+			continue
+		}
+		stepReport := f.runStep(ctx, step)
+		report.Steps = append(report.Steps, stepReport)
+		if stepReport.Err != nil {
+			firstErr = fmt.Errorf("Step '%s' failed: %v", step.name, stepReport.Err)
+			f.captureFailure(report)
+		}
+	}
+	return report, firstErr
+}
+
+func (f *Flow) runStep(ctx context.Context, step *flowStep) StepReport {
+	report := StepReport{Name: step.name}
+	start := time.Now()
+	timeout := step.timeout
+	if timeout == 0 {
+		timeout = f.StepTimeout
+	}
+	backoff := step.backoff
+attempts:
+	for report.Attempts < step.attempts {
+		if report.Attempts > 0 {
+			logging.Vlogf(1, "Retrying step '%s' after %v: %v", step.name, backoff, report.Err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				report.Err = ctx.Err()
+				break attempts
+			}
+			backoff *= 2
+		}
+		report.Attempts++
+		marks := make([]int, len(step.watches))
+		for i, watch := range step.watches {
+			marks[i] = watch.count()
+		}
+		report.Err = runWithTimeout(ctx, timeout, step.fn)
+		if report.Err == nil {
+			var matches ErrorMatches
+			for i, watch := range step.watches {
+				matches = append(matches, watch.matchesSince(marks[i])...)
+			}
+			if len(matches) > 0 {
+				report.Err = matches
+			}
+		}
+		if report.Err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	report.Duration = time.Since(start)
+	if report.Err != nil {
+		report.Status = StepFailed
+	} else {
+		report.Status = StepOk
+	}
+	return report
+}
+
+func runWithTimeout(ctx context.Context, timeout time.Duration,
+	fn func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+const captureFailureTimeout = 5 * time.Second
+
+func (f *Flow) captureFailure(report *FlowReport) {
+	report.FailureURL, report.FailureScreenshot = captureFailure(f.conn)
+}
+
+// Returns the URL and a screenshot of the page, or what of them could be captured. Gives up
+// after captureFailureTimeout, as the page may be hung.
+func captureFailure(conn *hc.Conn) (url string, screenshot []byte) {
+	type failure struct {
+		url        string
+		screenshot []byte
+	}
+	ch := make(chan failure, 1)
+	go func() {
+		var result failure
+		if err := Evaluate(conn, "location.href", &result.url); err != nil {
+			logging.Vlog(1, err)
+		}
+		var err error
+		if result.screenshot, err = CaptureScreenshot(conn, nil); err != nil {
+			logging.Vlog(1, err)
+		}
+		ch <- result
+	}()
+	select {
+	case result := <-ch:
+		return result.url, result.screenshot
+	case <-time.After(captureFailureTimeout):
+		logging.Vlog(1, "Timed out capturing the failure.")
+		return "", nil
+	}
+}
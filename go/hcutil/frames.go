@@ -0,0 +1,98 @@
+package hcutil
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrNoBeginFrameControl = errors.New(
+	"BeginFrame control is not enabled. The target must be created with it, and the browser " +
+		"started with --enable-begin-frame-control.")
+
+// Drives compositor frames of a page with HeadlessExperimental.beginFrame, so screenshots are
+// taken at exactly known frames.
+type FrameDriver struct {
+	conn *hc.Conn
+}
+
+type FrameOptions struct {
+	// Capture a screenshot of the frame.
+	Screenshot *protocol.ScreenshotParams
+	// Milliseconds between frames reported to the compositor. 0 means 60 frames per second.
+	Interval float64
+}
+
+type FrameResult struct {
+	// Whether the frame had damage, i.e. the content changed.
+	HasDamage bool
+	// Decoded screenshot, if requested.
+	Screenshot []byte
+}
+
+// Issues a frame to make sure the page is under BeginFrame control. Returns
+// ErrNoBeginFrameControl if not.
+func NewFrameDriver(conn *hc.Conn) (*FrameDriver, error) {
+	d := &FrameDriver{conn: conn}
+	if _, err := d.BeginFrame(nil); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *FrameDriver) BeginFrame(opts *FrameOptions) (*FrameResult, error) {
+	if opts == nil {
+		opts = &FrameOptions{}
+	}
+	result, err := protocol.BeginFrame(&protocol.BeginFrameParams{
+		Interval: opts.Interval, Screenshot: opts.Screenshot}, d.conn)
+	if err != nil {
+		if hc.IsMethodNotFound(err) || strings.Contains(err.Error(), "BeginFrameControl") {
+			return nil, ErrNoBeginFrameControl
+		}
+		return nil, err
+	}
+	frame := &FrameResult{HasDamage: result.HasDamage}
+	if opts.Screenshot != nil {
+		if result.ScreenshotData == "" {
+			return nil, fmt.Errorf("No screenshot captured")
+		}
+		if frame.Screenshot, err = base64.StdEncoding.DecodeString(result.ScreenshotData); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+// Issues at least n frames, and more till one has no damage, i.e. the content is stable. Then
+// captures a PNG screenshot. Gives up after 10n frames.
+func CaptureAfterNFrames(conn *hc.Conn, n int) ([]byte, error) {
+	d, err := NewFrameDriver(conn)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 1
+	}
+	for i := 1; ; i++ {
+		frame, err := d.BeginFrame(nil)
+		if err != nil {
+			return nil, err
+		}
+		if i >= n && !frame.HasDamage {
+			break
+		}
+		if i >= 10*n {
+			return nil, fmt.Errorf("Content is not stable after %d frames", i)
+		}
+	}
+	frame, err := d.BeginFrame(&FrameOptions{Screenshot: &protocol.ScreenshotParams{Format: "png"}})
+	if err != nil {
+		return nil, err
+	}
+	return frame.Screenshot, nil
+}
@@ -0,0 +1,170 @@
+package hcutil
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+)
+
+// A form field of a frozen page. It's found again by id, then by name and its index among fields
+// of that name, and then by its index among all fields.
+type FormField struct {
+	Index     int    `json:"index"`
+	Tag       string `json:"tag"`
+	Type      string `json:"type"`
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	NameIndex int    `json:"nameIndex"`
+	// Set depending on the kind of field.
+	Value    string   `json:"value,omitempty"`
+	Checked  bool     `json:"checked,omitempty"`
+	Selected []string `json:"selected,omitempty"`
+}
+
+// What Thaw needs to bring a page back. It's JSON serializable, so it can be parked anywhere.
+// Note that it contains whatever the user typed.
+type TabState struct {
+	URL            string            `json:"url"`
+	ScrollX        float64           `json:"scrollX"`
+	ScrollY        float64           `json:"scrollY"`
+	Fields         []FormField       `json:"fields"`
+	SessionStorage map[string]string `json:"sessionStorage"`
+}
+
+type FreezeOptions struct {
+	// Also capture values of password fields.
+	IncludePasswords bool
+}
+
+const freezeFunction = `(function(includePasswords) {
+	var skipped = {file: 1, submit: 1, button: 1, reset: 1, image: 1};
+	var all = document.querySelectorAll("input, select, textarea");
+	var nameCounts = {};
+	var fields = [];
+	for (var i = 0; i < all.length; i++) {
+		var el = all[i];
+		var type = (el.type || "").toLowerCase();
+		var nameIndex = 0;
+		if (el.name) {
+			nameIndex = nameCounts[el.name] || 0;
+			nameCounts[el.name] = nameIndex + 1;
+		}
+		if (skipped[type] || (type === "password" && !includePasswords)) continue;
+		var field = {index: i, tag: el.tagName, type: type, id: el.id, name: el.name,
+			nameIndex: nameIndex};
+		if (type === "checkbox" || type === "radio") {
+			field.checked = el.checked;
+		} else if (el.tagName === "SELECT") {
+			field.selected = Array.prototype.filter.call(el.options, function(o) {
+				return o.selected;
+			}).map(function(o) { return o.value; });
+		} else {
+			field.value = el.value;
+		}
+		fields.push(field);
+	}
+	var storage = {};
+	try {
+		for (var i = 0; i < sessionStorage.length; i++) {
+			var key = sessionStorage.key(i);
+			storage[key] = sessionStorage.getItem(key);
+		}
+	} catch (e) {}
+	return {url: location.href, scrollX: window.scrollX, scrollY: window.scrollY,
+		fields: fields, sessionStorage: storage};
+})`
+
+const thawFunction = `(function(state) {
+	var all = document.querySelectorAll("input, select, textarea");
+	var warnings = [];
+	state.fields.forEach(function(field) {
+		var desc = field.id ? "#" + field.id :
+			field.name ? field.name + "[" + field.nameIndex + "]" : "field " + field.index;
+		var el = field.id ? document.getElementById(field.id) : null;
+		if (!el && field.name) el = document.getElementsByName(field.name)[field.nameIndex];
+		if (!el) el = all[field.index];
+		if (!el) {
+			warnings.push("No field matches " + desc);
+			return;
+		}
+		var type = (el.type || "").toLowerCase();
+		if (el.tagName !== field.tag || type !== field.type) {
+			warnings.push(desc + " is " + el.tagName + " " + type + " now, not " + field.tag +
+				" " + field.type);
+			return;
+		}
+		if (type === "checkbox" || type === "radio") {
+			el.checked = !!field.checked;
+		} else if (el.tagName === "SELECT") {
+			var selected = field.selected || [];
+			Array.prototype.forEach.call(el.options, function(o) {
+				o.selected = selected.indexOf(o.value) >= 0;
+			});
+		} else {
+			el.value = field.value || "";
+		}
+		el.dispatchEvent(new Event("input", {bubbles: true}));
+		el.dispatchEvent(new Event("change", {bubbles: true}));
+	});
+	window.scrollTo(state.scrollX, state.scrollY);
+	return warnings;
+})`
+
+// Captures the URL, scroll position, form field values and sessionStorage of the main frame, so
+// that the page can be parked, e.g. navigated to about:blank, and brought back by Thaw.
+func Freeze(conn *hc.Conn, opts *FreezeOptions) (*TabState, error) {
+	if opts == nil {
+		opts = &FreezeOptions{}
+	}
+	state := &TabState{}
+	if err := Evaluate(conn, fmt.Sprintf("%s(%v)", freezeFunction, opts.IncludePasswords),
+		state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Navigates to state.URL with its sessionStorage restored before any of the page's scripts run,
+// then restores form field values and the scroll position once loaded. Fields which can't be
+// found, or whose kind changed, are skipped and reported as warnings.
+func Thaw(conn *hc.Conn, state *TabState, timeout time.Duration) (warnings []string, err error) {
+	u, err := url.Parse(state.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(state.SessionStorage) > 0 {
+		script, err := jsbuilder.JSTemplate(`(function(origin, storage) {
+	if (window !== window.top || location.origin !== origin) return;
+	try {
+		for (var key in storage) sessionStorage.setItem(key, storage[key]);
+	} catch (e) {}
+})({{.Origin}}, {{.Storage}})`, map[string]interface{}{
+			"Origin": u.Scheme + "://" + u.Host, "Storage": state.SessionStorage})
+		if err != nil {
+			return nil, err
+		}
+		id, err := InjectOnNewDocument(conn, script)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := RemoveInjected(conn, id); err != nil {
+				logging.Vlog(-1, err)
+			}
+		}()
+	}
+	if err := NavigateAndWait(conn, state.URL, timeout); err != nil {
+		return nil, err
+	}
+	data, err := jsbuilder.JSValue(state)
+	if err != nil {
+		return nil, err
+	}
+	err = Evaluate(conn, fmt.Sprintf("%s(%s)", thawFunction, data), &warnings)
+	return
+}
@@ -0,0 +1,242 @@
+package hcutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrFrameNotFound = errors.New("frame not found")
+
+// The frame is cross-origin, so its coordinates can't be composed with the page's, or its
+// document isn't in this process.
+type CrossOriginFrameError struct {
+	FrameId        protocol.FrameId
+	URL            string
+	SecurityOrigin string
+}
+
+func (e *CrossOriginFrameError) Error() string {
+	return fmt.Sprintf("Frame %s (%s) is cross-origin", e.FrameId, e.URL)
+}
+
+// Selects a frame other than the main frame. All set fields must match.
+type FrameSelector struct {
+	// The name attribute of the frame.
+	Name       string
+	URLPattern *regexp.Regexp
+	// Index among matching frames in tree order.
+	Index int
+}
+
+type FrameInfo struct {
+	Id             protocol.FrameId
+	ParentId       protocol.FrameId
+	Name           string
+	URL            string
+	SecurityOrigin string
+	// Its origin differs from the main frame's.
+	CrossOrigin bool
+}
+
+// Returns the frames of the page in tree order, the main frame first.
+func Frames(conn *hc.Conn) ([]*FrameInfo, error) {
+	result, err := protocol.GetResourceTree(conn)
+	if err != nil {
+		return nil, err
+	}
+	mainOrigin := result.FrameTree.Frame.SecurityOrigin
+	var frames []*FrameInfo
+	var walk func(tree *protocol.FrameResourceTree)
+	walk = func(tree *protocol.FrameResourceTree) {
+		frame := tree.Frame
+		frames = append(frames, &FrameInfo{
+			Id:             protocol.FrameId(frame.Id),
+			ParentId:       protocol.FrameId(frame.ParentId),
+			Name:           frame.Name,
+			URL:            frame.Url,
+			SecurityOrigin: frame.SecurityOrigin,
+			CrossOrigin:    frame.SecurityOrigin != mainOrigin,
+		})
+		for _, child := range tree.ChildFrames {
+			walk(child)
+		}
+	}
+	walk(result.FrameTree)
+	return frames, nil
+}
+
+// Returns the frame selected by sel, or the main frame if sel is nil.
+func FindFrame(conn *hc.Conn, sel *FrameSelector) (*FrameInfo, error) {
+	frames, err := Frames(conn)
+	if err != nil {
+		return nil, err
+	}
+	if sel == nil {
+		return frames[0], nil
+	}
+	index := 0
+	for _, frame := range frames[1:] {
+		if (sel.Name != "" && frame.Name != sel.Name) ||
+			(sel.URLPattern != nil && !sel.URLPattern.MatchString(frame.URL)) {
+			continue
+		}
+		if index == sel.Index {
+			return frame, nil
+		}
+		index++
+	}
+	return nil, ErrFrameNotFound
+}
+
+// Fetches the whole document, piercing iframes, and returns the document of frame along with the
+// frame owner elements leading to it, outermost first.
+func frameDocument(conn *hc.Conn, frame *FrameInfo) (*protocol.Node, []*protocol.Node, error) {
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{Depth: -1, Pierce: true}, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if frame.ParentId == "" {
+		return doc.Root, nil, nil
+	}
+	var owners []*protocol.Node
+	var find func(node *protocol.Node) *protocol.Node
+	find = func(node *protocol.Node) *protocol.Node {
+		if node.FrameId == frame.Id && node.NodeId != doc.Root.NodeId {
+			owners = append(owners, node)
+			return node
+		}
+		if node.ContentDocument != nil {
+			owners = append(owners, node)
+			if found := find(node.ContentDocument); found != nil {
+				return found
+			}
+			owners = owners[:len(owners)-1]
+		}
+		for _, children := range [][]*protocol.Node{node.ShadowRoots, node.Children} {
+			for _, child := range children {
+				if found := find(child); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	owner := find(doc.Root)
+	if owner == nil {
+		return nil, nil, ErrFrameNotFound
+	} else if owner.ContentDocument == nil {
+		// Out of process.
+		return nil, nil, &CrossOriginFrameError{frame.Id, frame.URL, frame.SecurityOrigin}
+	}
+	return owner.ContentDocument, owners, nil
+}
+
+// Returns the first element matching selector in the document of the frame selected by frame,
+// or the main frame if it's nil. The node id is valid till the next DOM.getDocument.
+func QuerySelectorInFrame(conn *hc.Conn, frame *FrameSelector, selector string) (
+	protocol.NodeId, error) {
+	info, err := FindFrame(conn, frame)
+	if err != nil {
+		return 0, err
+	}
+	doc, _, err := frameDocument(conn, info)
+	if err != nil {
+		return 0, err
+	}
+	result, err := protocol.QuerySelector(&protocol.QuerySelectorParams{
+		NodeId: doc.NodeId, Selector: selector}, conn)
+	if err != nil {
+		return 0, err
+	} else if result.NodeId == 0 {
+		return 0, fmt.Errorf("No element matches %s", selector)
+	}
+	return result.NodeId, nil
+}
+
+// Captures the first element matching selector in the frame selected by frame, or the main frame
+// if it's nil. Box models of elements in same-origin frames are already relative to the main
+// frame's viewport, so the element is only clipped to the content boxes of the frame owners, as
+// anything outside them isn't visible. Fails with CrossOriginFrameError for elements in or under
+// cross-origin frames, whose coordinates aren't comparable. opts.Clip is ignored but its Scale.
+func CaptureNodeScreenshot(conn *hc.Conn, frame *FrameSelector, selector string,
+	opts *ScreenshotOptions) ([]byte, error) {
+	frames, err := Frames(conn)
+	if err != nil {
+		return nil, err
+	}
+	info, err := FindFrame(conn, frame)
+	if err != nil {
+		return nil, err
+	}
+	doc, owners, err := frameDocument(conn, info)
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		for _, f := range frames {
+			if f.Id == owner.FrameId && f.CrossOrigin {
+				return nil, &CrossOriginFrameError{f.Id, f.URL, f.SecurityOrigin}
+			}
+		}
+	}
+	node, err := protocol.QuerySelector(&protocol.QuerySelectorParams{
+		NodeId: doc.NodeId, Selector: selector}, conn)
+	if err != nil {
+		return nil, err
+	} else if node.NodeId == 0 {
+		return nil, fmt.Errorf("No element matches %s", selector)
+	}
+
+	box, err := protocol.GetBoxModel(&protocol.GetBoxModelParams{NodeId: node.NodeId}, conn)
+	if err != nil {
+		return nil, err
+	}
+	left, top, right, bottom := quadBounds(box.Model.Border)
+	for _, owner := range owners {
+		ownerBox, err := protocol.GetBoxModel(&protocol.GetBoxModelParams{NodeId: owner.NodeId},
+			conn)
+		if err != nil {
+			return nil, err
+		}
+		l, t, r, b := quadBounds(ownerBox.Model.Content)
+		left, top = math.Max(left, l), math.Max(top, t)
+		right, bottom = math.Min(right, r), math.Min(bottom, b)
+	}
+	if right <= left || bottom <= top {
+		return nil, fmt.Errorf("%s isn't visible in its frame", selector)
+	}
+
+	// Box models are relative to the viewport, but clips to the document.
+	var scroll struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	}
+	if err := Evaluate(conn, "({x: window.scrollX, y: window.scrollY})", &scroll); err != nil {
+		return nil, err
+	}
+	clipped := ScreenshotOptions{}
+	if opts != nil {
+		clipped = *opts
+	}
+	clip := &Clip{X: left + scroll.X, Y: top + scroll.Y, Width: right - left, Height: bottom - top}
+	if clipped.Clip != nil {
+		clip.Scale = clipped.Clip.Scale
+	}
+	clipped.Clip = clip
+	return CaptureScreenshot(conn, &clipped)
+}
+
+func quadBounds(quad protocol.Quad) (left, top, right, bottom float64) {
+	left, top = math.Inf(1), math.Inf(1)
+	right, bottom = math.Inf(-1), math.Inf(-1)
+	for i := 0; i+1 < len(quad); i += 2 {
+		left, right = math.Min(left, quad[i]), math.Max(right, quad[i])
+		top, bottom = math.Min(top, quad[i+1]), math.Max(bottom, quad[i+1])
+	}
+	return
+}
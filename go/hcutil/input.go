@@ -0,0 +1,129 @@
+package hcutil
+
+import (
+	"fmt"
+	"math"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Converts page coordinates, e.g. from GetBoxModel, into viewport coordinates, which mouse events
+// take. Both are in CSS pixels, but the latter are affected by scrolling and scaling.
+func PageToViewport(conn *hc.Conn, x, y float64) (float64, float64, error) {
+	metrics, err := protocol.GetLayoutMetrics(conn)
+	if err != nil {
+		return 0, 0, err
+	}
+	vv := metrics.VisualViewport
+	if vv == nil {
+		return 0, 0, fmt.Errorf("No visual viewport")
+	}
+	scale := vv.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return (x - vv.PageX) * scale, (y - vv.PageY) * scale, nil
+}
+
+// Returns the center of the node's border box in viewport coordinates.
+func NodeCenterInViewport(conn *hc.Conn, nodeId protocol.NodeId) (float64, float64, error) {
+	result, err := protocol.GetBoxModel(&protocol.GetBoxModelParams{NodeId: nodeId}, conn)
+	if err != nil {
+		return 0, 0, err
+	}
+	quad := result.Model.Border
+	if len(quad) < 8 {
+		return 0, 0, fmt.Errorf("Bad border quad of node %d: %v", nodeId, quad)
+	}
+	var x, y float64
+	for i := 0; i < 8; i += 2 {
+		x += quad[i]
+		y += quad[i+1]
+	}
+	return PageToViewport(conn, x/4, y/4)
+}
+
+// Scrolls the node into the view if it isn't already.
+func ScrollIntoView(conn *hc.Conn, nodeId protocol.NodeId) error {
+	group, end := conn.BeginHelperObjects()
+	created := 0
+	defer func() { end(created) }()
+	resolved, err := protocol.ResolveNode(
+		&protocol.ResolveNodeParams{NodeId: nodeId, ObjectGroup: group}, conn)
+	if err != nil {
+		return err
+	}
+	created++
+	objectId := resolved.Object.ObjectId
+	if err := conn.ThrottleEvaluation(); err != nil {
+		return err
+	}
+	result, err := protocol.CallFunctionOn(&protocol.CallFunctionOnParams{
+		ObjectId:            objectId,
+		FunctionDeclaration: "function() { this.scrollIntoViewIfNeeded(true); }",
+	}, conn)
+	if err != nil {
+		return err
+	} else if result.ExceptionDetails != nil {
+		return exceptionError(result.ExceptionDetails)
+	}
+	return nil
+}
+
+// Scrolls the node into the view and clicks its center with the left button.
+func Click(conn *hc.Conn, nodeId protocol.NodeId) error {
+	if err := ScrollIntoView(conn, nodeId); err != nil {
+		return err
+	}
+	x, y, err := NodeCenterInViewport(conn, nodeId)
+	if err != nil {
+		return err
+	}
+	return ClickAt(conn, x, y)
+}
+
+// Clicks the first element matching selector like a user would: the mouse is moved towards the
+// element and onto it before pressing and releasing, so hover handlers run. Input events are
+// trusted, so the page gets a user gesture, which e.g. window.open and autoplay need, and
+// navigations it triggers carry it. Unlike the params of NavigateRequest, this holds with
+// protocol v1.2 too.
+func ClickLikeUser(conn *hc.Conn, selector string) error {
+	nodeId, err := querySelectorFromDocument(conn, selector)
+	if err != nil {
+		return err
+	}
+	if err := ScrollIntoView(conn, nodeId); err != nil {
+		return err
+	}
+	x, y, err := NodeCenterInViewport(conn, nodeId)
+	if err != nil {
+		return err
+	}
+	for _, p := range [][2]float64{{x / 2, y / 2}, {x, y}} {
+		if err := protocol.DispatchMouseEvent(&protocol.DispatchMouseEventParams{
+			Type: "mouseMoved",
+			X:    int(math.Round(p[0])),
+			Y:    int(math.Round(p[1])),
+		}, conn); err != nil {
+			return err
+		}
+	}
+	return ClickAt(conn, x, y)
+}
+
+// Clicks at the viewport coordinates with the left button.
+func ClickAt(conn *hc.Conn, x, y float64) error {
+	for _, typ := range []string{"mousePressed", "mouseReleased"} {
+		if err := protocol.DispatchMouseEvent(&protocol.DispatchMouseEventParams{
+			Type:       typ,
+			X:          int(math.Round(x)),
+			Y:          int(math.Round(y)),
+			Button:     "left",
+			ClickCount: 1,
+		}, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package hcutil_test
+
+import (
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// Page coordinates are offset by the scroll position and scaled by the visual viewport.
+func TestPageToViewport(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Page.getLayoutMetrics", hctest.FakeResult(map[string]interface{}{
+		"layoutViewport": map[string]int{"pageX": 0, "pageY": 3000, "clientWidth": 200,
+			"clientHeight": 300},
+		"visualViewport": map[string]float64{"offsetX": 0, "offsetY": 0, "pageX": 10,
+			"pageY": 3000, "clientWidth": 200, "clientHeight": 300, "scale": 2},
+		"contentSize": map[string]float64{"x": 0, "y": 0, "width": 400, "height": 4100},
+	}))
+	server.Handle("DOM.getBoxModel", hctest.FakeResult(map[string]interface{}{
+		"model": map[string]interface{}{
+			"content": []float64{20, 3050, 60, 3050, 60, 3070, 20, 3070},
+			"padding": []float64{20, 3050, 60, 3050, 60, 3070, 20, 3070},
+			"border":  []float64{20, 3050, 60, 3050, 60, 3070, 20, 3070},
+			"margin":  []float64{20, 3050, 60, 3050, 60, 3070, 20, 3070},
+			"width":   40, "height": 20,
+		},
+	}))
+	conn, _ := server.NewPageConn()
+	if x, y, err := hcutil.PageToViewport(conn, 30, 3100); err != nil || x != 40 || y != 200 {
+		t.Errorf("Got %v, %v, %v", x, y, err)
+	}
+	if x, y, err := hcutil.NodeCenterInViewport(conn, 5); err != nil || x != 60 || y != 120 {
+		t.Errorf("Got %v, %v, %v", x, y, err)
+	}
+}
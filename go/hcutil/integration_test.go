@@ -0,0 +1,1019 @@
+//go:build integration
+
+package hcutil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/bytebudget"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// End-to-end tests against a real browser and the fixture server. Run them with
+// "go test -tags integration"; they're skipped when no hc_server binary is found.
+
+const navigateTimeout = 30 * time.Second
+
+// Opens a blank page of the shared browser and navigates it to the fixture at path.
+func openFixture(t *testing.T, path string) (*hc.Conn, string) {
+	t.Helper()
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	url := fixtures.URL + path
+	if err := hcutil.NavigateAndWait(conn, url, navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+	return conn, url
+}
+
+func evaluateString(t *testing.T, conn *hc.Conn, expression string) string {
+	t.Helper()
+	var s string
+	if err := hcutil.Evaluate(conn, expression, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestIntegrationNavigateAndScreenshot(t *testing.T) {
+	conn, url := openFixture(t, hctest.FixtureRedirect)
+	response, err := hcutil.MainDocumentResponse(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != 200 || !strings.HasSuffix(response.Url, hctest.FixtureStatic) ||
+		len(response.Redirects) != 3 || response.Redirects[0].Url != url {
+		t.Errorf("Got %+v", response)
+	}
+
+	data, err := hcutil.CaptureScreenshot(conn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() == 0 || b.Dy() == 0 {
+		t.Errorf("Got bounds %v", b)
+	}
+	path := filepath.Join(t.TempDir(), "static.jpg")
+	if err := hcutil.CaptureScreenshotToFile(conn, path, &hcutil.ScreenshotFileOptions{
+		Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntegrationQuery(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureStatic)
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeIds, truncated, err := hcutil.QuerySelectorAllWithBudget(conn, doc.Root.NodeId,
+		"a, button", time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeIds) != 2 || truncated {
+		t.Fatalf("Got %v, truncated %t", nodeIds, truncated)
+	}
+	html, err := protocol.GetOuterHTML(&protocol.GetOuterHTMLParams{NodeId: nodeIds[0]}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.OuterHTML, `id="link"`) {
+		t.Errorf("First match is %s", html.OuterHTML)
+	}
+
+	nodeIds, truncated, err = hcutil.QuerySelectorAllWithBudget(conn, doc.Root.NodeId,
+		"a, button", time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeIds) != 1 || !truncated {
+		t.Errorf("Got %v, truncated %t with maxResults 1", nodeIds, truncated)
+	}
+}
+
+func TestIntegrationEvaluate(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureStatic)
+	if title := evaluateString(t, conn, "document.title"); title != "Static" {
+		t.Errorf("Got title %q", title)
+	}
+	var sum struct{ A, B int }
+	if err := hcutil.Evaluate(conn, "({a: 1, b: 1 + 1})", &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum.A != 1 || sum.B != 2 {
+		t.Errorf("Got %+v", sum)
+	}
+	if err := hcutil.Evaluate(conn, "throw new Error('fixture')", nil); err == nil ||
+		!strings.Contains(err.Error(), "fixture") {
+		t.Errorf("Got %v, want the exception", err)
+	}
+}
+
+func TestIntegrationCookies(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureCookie)
+	cookies, err := protocol.GetAllCookies(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matcher := hcutil.MatchName("fixture")
+	if found := hcutil.FilterCookies(cookies.Cookies, matcher); len(found) != 1 ||
+		found[0].Value != "1" {
+		t.Fatalf("Got %+v", cookies.Cookies)
+	}
+	if n, err := hcutil.DeleteCookies(conn, matcher); err != nil || n != 1 {
+		t.Fatalf("Deleted %d cookies, %v", n, err)
+	}
+	if cookie := evaluateString(t, conn, "document.cookie"); cookie != "" {
+		t.Errorf("Page still sees %q", cookie)
+	}
+}
+
+// The page doesn't finish loading till its alert is handled, from the sink.
+func TestIntegrationDialog(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	dialogs := make(chan *protocol.JavascriptDialogOpeningEvent, 1)
+	remove := protocol.OnJavascriptDialogOpening(conn, func(
+		evt *protocol.JavascriptDialogOpeningEvent) {
+		dialogs <- evt
+		if err := protocol.HandleJavaScriptDialog(
+			&protocol.HandleJavaScriptDialogParams{Accept: true}, conn); err != nil {
+			t.Error(err)
+		}
+	})
+	defer remove()
+	if err := hcutil.NavigateAndWait(conn, fixtures.URL+hctest.FixtureDialog,
+		navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case evt := <-dialogs:
+		if evt.Type != "alert" || evt.Message != "fixture" {
+			t.Errorf("Got %+v", evt)
+		}
+	default:
+		t.Error("Loaded without a dialog")
+	}
+}
+
+func TestIntegrationInput(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureForm)
+	if err := hcutil.ClickLikeUser(conn, "#name"); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range "hc test" {
+		if err := protocol.DispatchKeyEvent(&protocol.DispatchKeyEventParams{
+			Type: "char", Text: string(r)}, conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if value := evaluateString(t, conn,
+		"document.getElementById('name').value"); value != "hc test" {
+		t.Fatalf("Typed %q", value)
+	}
+
+	outcome, err := hcutil.ClickAndWait(conn, "#submit", hcutil.ClickWaitOptions{
+		Timeout: navigateTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Kind != hcutil.ClickNavigated {
+		t.Fatalf("Got %v", outcome.Kind)
+	}
+	if body := evaluateString(t, conn, "document.body.textContent"); !strings.Contains(body,
+		"POST") || !strings.Contains(body, "name=hc+test") {
+		t.Errorf("Echoed %q", body)
+	}
+}
+
+// The world sees the DOM of the page, but not its globals, and is recreated on navigation.
+func TestIntegrationIsolatedWorld(t *testing.T) {
+	conn, url := openFixture(t, hctest.FixtureStatic)
+	world := hcutil.NewIsolatedWorld(conn, "hc-integration")
+	defer world.Close()
+	frameId, err := hcutil.MainFrameId(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func() {
+		t.Helper()
+		if err := hcutil.Evaluate(conn, "window.pageGlobal = 1", nil); err != nil {
+			t.Fatal(err)
+		}
+		var seen struct {
+			Title  string
+			Global string
+		}
+		if err := world.Evaluate(frameId, `({title: document.querySelector("title").textContent,
+			global: typeof pageGlobal})`, &seen); err != nil {
+			t.Fatal(err)
+		}
+		if seen.Title != "Static" || seen.Global != "undefined" {
+			t.Errorf("World saw %+v", seen)
+		}
+	}
+	check()
+	if err := hcutil.NavigateAndWait(conn, url, navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
+
+// Whether every pixel of the right and bottom edges is white.
+func edgesWhite(img image.Image) bool {
+	b := img.Bounds()
+	white := color.RGBAModel.Convert(color.White)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if color.RGBAModel.Convert(img.At(b.Max.X-1, y)) != white {
+			return false
+		}
+	}
+	for x := b.Min.X; x < b.Max.X; x++ {
+		if color.RGBAModel.Convert(img.At(x, b.Max.Y-1)) != white {
+			return false
+		}
+	}
+	return true
+}
+
+const screenshotsDir = "testdata/screenshots"
+
+// Fraction of pixels which may differ from a golden image, by more than goldenChannelTolerance
+// in a channel, e.g. due to anti-aliasing.
+const (
+	goldenPixelTolerance   = 0.01
+	goldenChannelTolerance = 16
+)
+
+// Returns the fraction of pixels of a and b which differ by more than goldenChannelTolerance,
+// or an error if their sizes differ.
+func imageDiff(a, b image.Image) (float64, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Dx() != bb.Dx() || ab.Dy() != bb.Dy() {
+		return 0, fmt.Errorf("Got a %dx%d image, want %dx%d", ab.Dx(), ab.Dy(), bb.Dx(), bb.Dy())
+	}
+	diff := func(x, y uint32) bool {
+		return int(x>>8)-int(y>>8) > goldenChannelTolerance ||
+			int(y>>8)-int(x>>8) > goldenChannelTolerance
+	}
+	differing := 0
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			r1, g1, b1, a1 := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			if diff(r1, r2) || diff(g1, g2) || diff(b1, b2) || diff(a1, a2) {
+				differing++
+			}
+		}
+	}
+	return float64(differing) / float64(ab.Dx()*ab.Dy()), nil
+}
+
+// Compares img with the golden PNG name under testdata/screenshots, or rewrites it with -update.
+func checkImageGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+	path := filepath.Join(screenshotsDir, name)
+	if *updateFlag {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := imageDiff(img, want); err != nil {
+		t.Errorf("%s: %v", name, err)
+	} else if d > goldenPixelTolerance {
+		t.Errorf("%s: %.1f%% of pixels differ from the golden image. Run with -update if "+
+			"that's expected", name, d*100)
+	}
+}
+
+func decodePNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// With scrollbars hidden, a white overflowing page is captured all white, like its golden
+// image, at the size of the view. The scrollbars come back afterwards.
+func TestIntegrationHideScrollbars(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureOverflow)
+	if err := hcutil.SetDeviceMetrics(conn, &protocol.EmulationSetDeviceMetricsOverrideParams{
+		Width: 400, Height: 300, DeviceScaleFactor: 1}); err != nil {
+		t.Fatal(err)
+	}
+	capture := func(hide bool) image.Image {
+		t.Helper()
+		data, err := hcutil.CaptureScreenshot(conn, &hcutil.ScreenshotOptions{
+			HideScrollbars: hide})
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := decodePNG(t, data)
+		if b := img.Bounds(); b.Dx() != 400 || b.Dy() != 300 {
+			t.Errorf("Hide %t: got %dx%d, want 400x300", hide, b.Dx(), b.Dy())
+		}
+		return img
+	}
+	before := capture(false)
+	hidden := capture(true)
+	if !edgesWhite(hidden) {
+		t.Error("Scrollbars captured")
+	}
+	checkImageGolden(t, "hide_scrollbars.png", hidden)
+	if after := capture(false); edgesWhite(after) != edgesWhite(before) {
+		t.Error("Scrollbars not restored")
+	} else if d, _ := imageDiff(after, before); d > 0 {
+		t.Errorf("%.1f%% of pixels differ after the scrollbars are restored", d*100)
+	}
+}
+
+// Captures a screenshot clipped by the browser, with Page.captureScreenshot's clip parameter,
+// which protocol v1.2 lacks.
+type nativeClipCommand struct {
+	clip *hcutil.Clip
+	data []byte
+	err  error
+	done chan struct{}
+}
+
+func (c *nativeClipCommand) Name() string {
+	return "Page.captureScreenshot"
+}
+
+func (c *nativeClipCommand) Params() interface{} {
+	scale := c.clip.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return map[string]interface{}{"format": "png", "clip": map[string]float64{
+		"x": c.clip.X, "y": c.clip.Y, "width": c.clip.Width, "height": c.clip.Height,
+		"scale": scale}}
+}
+
+func (c *nativeClipCommand) Done(result []byte, err error) {
+	defer close(c.done)
+	if c.err = err; err != nil {
+		return
+	}
+	var r struct {
+		Data []byte `json:"data"`
+	}
+	c.err = json.Unmarshal(result, &r)
+	c.data = r.Data
+}
+
+// Clipped screenshots have the size of the clip times its scale, and match the browser's own
+// clipping, if it has that.
+func TestIntegrationClipRoundTrip(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureStatic)
+	for _, clip := range []*hcutil.Clip{
+		{X: 0, Y: 0, Width: 200, Height: 60},
+		{X: 8, Y: 20, Width: 100.5, Height: 40.5, Scale: 2},
+	} {
+		data, err := hcutil.CaptureScreenshot(conn, &hcutil.ScreenshotOptions{Clip: clip})
+		if err != nil {
+			t.Fatal(err)
+		}
+		emulated := decodePNG(t, data)
+		scale := clip.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		width := int(math.Round(clip.Width * scale))
+		height := int(math.Round(clip.Height * scale))
+		if b := emulated.Bounds(); b.Dx() != width || b.Dy() != height {
+			t.Errorf("%+v: got %dx%d, want %dx%d", *clip, b.Dx(), b.Dy(), width, height)
+			continue
+		}
+
+		cmd := &nativeClipCommand{clip: clip, done: make(chan struct{})}
+		if err := conn.SendCommand(cmd); err != nil {
+			t.Fatal(err)
+		}
+		<-cmd.done
+		if cmd.err != nil {
+			t.Fatal(cmd.err)
+		}
+		native := decodePNG(t, cmd.data)
+		if b := native.Bounds(); b.Dx() != width || b.Dy() != height {
+			t.Logf("The browser doesn't clip screenshots, got %dx%d", b.Dx(), b.Dy())
+			continue
+		}
+		if d, err := imageDiff(emulated, native); err != nil || d > goldenPixelTolerance {
+			t.Errorf("%+v: %.1f%% of pixels differ from the browser's clip, %v", *clip,
+				d*100, err)
+		}
+	}
+}
+
+// A page stuck in a busy loop is found hung and replaced by a responsive one.
+func TestIntegrationWatchdog(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	browser := hctest.NewBrowser(t)
+	browserConn, err := browser.NewBrowserConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer browserConn.Close()
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{Url: "about:blank"},
+		browserConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := browser.NewPageConn(string(target.TargetId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hcutil.NavigateAndWait(conn, fixtures.URL+hctest.FixtureBusyLoop,
+		navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := make(chan hcutil.WatchdogStep, 1)
+	w := hcutil.StartWatchdog(browser, browserConn, target.TargetId, conn, hcutil.WatchdogOptions{
+		Interval: 200 * time.Millisecond,
+		Timeout:  time.Second,
+		OnStep: func(step hcutil.WatchdogStep, ok bool, err error) {
+			t.Logf("Step %v: recovered=%v err=%v", step, ok, err)
+			if ok {
+				recovered <- step
+			}
+		},
+	})
+	defer w.Stop()
+	if err := hcutil.Evaluate(conn, "hang()", nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case step := <-recovered:
+		// Protocol v1.2 can't interrupt scripts, so only a new target helps.
+		if step != hcutil.StepRecreateTarget {
+			t.Errorf("Recovered by %v", step)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Not recovered")
+	}
+	_, fresh := w.Target()
+	defer fresh.Close()
+	if s := evaluateString(t, fresh, "document.readyState"); s != "complete" {
+		t.Errorf("Got %q", s)
+	}
+}
+
+// Renderer handles stay flat over many helper operations, as sweeps release the objects helpers
+// leave behind. Each operation holds a detached node and a script library object.
+func TestIntegrationHelperObjectSoak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Slow")
+	}
+	conn, _ := openFixture(t, hctest.FixtureStatic)
+	const threshold = 100
+	conn.SetHelperObjectSweep(threshold, 0)
+	lib := hcutil.NewScriptLibrary(nil)
+	if err := lib.Register("echo", "function(x) { return x; }"); err != nil {
+		t.Fatal(err)
+	}
+	operate := func(i int) {
+		group, end := conn.BeginHelperObjects()
+		defer end(1)
+		if _, err := protocol.Evaluate(&protocol.EvaluateParams{
+			Expression:  "({node: document.createElement('div')})",
+			ObjectGroup: group}, conn); err != nil {
+			t.Fatal(err)
+		}
+		var echoed int
+		if err := lib.Call(conn, "echo", []interface{}{i}, &echoed); err != nil || echoed != i {
+			t.Fatalf("Got %d, %v", echoed, err)
+		}
+	}
+	nodes := func() int {
+		if err := protocol.CollectGarbage(conn); err != nil {
+			t.Fatal(err)
+		}
+		counters, err := protocol.GetDOMCounters(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counters.Nodes
+	}
+
+	for i := 0; i < 1000; i++ {
+		operate(i)
+	}
+	baseline := nodes()
+	for i := 0; i < 10000; i++ {
+		operate(i)
+	}
+	// Up to a threshold of objects may be alive between sweeps.
+	if n := nodes(); n > baseline+threshold {
+		t.Errorf("Nodes grew from %d to %d", baseline, n)
+	}
+	if stats := conn.Stats(); stats.HelperObjectsCreated-stats.HelperObjectsReleased >
+		threshold {
+		t.Errorf("Got %d objects created, %d released", stats.HelperObjectsCreated,
+			stats.HelperObjectsReleased)
+	}
+}
+
+// Hidden elements are dropped, while open shadow roots and same-origin iframes are walked.
+func TestIntegrationExtractText(t *testing.T) {
+	conn, url := openFixture(t, hctest.FixtureText)
+	base := strings.TrimSuffix(url, hctest.FixtureText)
+	for _, c := range []struct {
+		golden string
+		opts   *hcutil.TextOptions
+	}{
+		{"js.txt", nil},
+		{"js_hrefs.txt", &hcutil.TextOptions{IncludeHrefs: true}},
+		{"js_selector.txt", &hcutil.TextOptions{Selector: "#host"}},
+	} {
+		text, err := hcutil.ExtractText(conn, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The port of the fixture server changes.
+		checkTextGolden(t, c.golden, strings.ReplaceAll(text, base, "http://fixtures"))
+	}
+}
+
+// DOMContentLoaded of the slow fixture comes before its image loads, while the load event waits
+// for it. The fast fixture loads well within the delay.
+func TestIntegrationReadinessLevels(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	navigate := func(path string, level hcutil.ReadinessLevel) *hcutil.NavigationResult {
+		t.Helper()
+		result, err := hcutil.NavigateAndWaitForReadiness(conn, fixtures.URL+path,
+			&hcutil.NavigateOptions{Timeout: navigateTimeout, Readiness: level})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := navigate(hctest.FixtureSlow, hcutil.ReadyDOMContentLoaded)
+	if parsed := result.Times[hcutil.ReadyDOMContentLoaded]; parsed >= hctest.FixtureSlowDelay {
+		t.Errorf("DOMContentLoaded of the slow fixture took %v", parsed)
+	}
+	result = navigate(hctest.FixtureSlow, hcutil.ReadyLoad)
+	parsed, loaded := result.Times[hcutil.ReadyDOMContentLoaded], result.Times[hcutil.ReadyLoad]
+	if loaded < hctest.FixtureSlowDelay || loaded-parsed < hctest.FixtureSlowDelay/2 {
+		t.Errorf("Slow fixture parsed at %v, loaded at %v", parsed, loaded)
+	}
+	result = navigate(hctest.FixtureStatic, hcutil.ReadyLoad)
+	if loaded := result.Times[hcutil.ReadyLoad]; loaded >= hctest.FixtureSlowDelay {
+		t.Errorf("Fast fixture loaded at %v", loaded)
+	}
+}
+
+// The busy fixture never gets the network idle, so waiting for it falls back to the load event,
+// or times out if strict.
+func TestIntegrationNetworkIdleFallback(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	for _, strict := range []bool{false, true} {
+		result, err := hcutil.NavigateAndWaitForReadiness(conn,
+			fixtures.URL+hctest.FixtureBusyNetwork, &hcutil.NavigateOptions{
+				Timeout: 3 * time.Second, Readiness: hcutil.ReadyNetworkIdle, Strict: strict})
+		if _, idle := result.Times[hcutil.ReadyNetworkIdle]; idle {
+			t.Errorf("Strict %t: network idle at %v", strict, result.Times)
+		}
+		if _, loaded := result.Times[hcutil.ReadyLoad]; !loaded {
+			t.Errorf("Strict %t: not loaded, %v", strict, result.Times)
+		}
+		if strict {
+			if err != hcutil.ErrTimeout {
+				t.Errorf("Got %v", err)
+			}
+		} else if err != nil || !result.FellBack || result.Reached != hcutil.ReadyLoad {
+			t.Errorf("Got %+v, %v", result, err)
+		}
+	}
+}
+
+// Opens url in a new page of the browser context, which is closed when the test finishes.
+func openInContext(t *testing.T, browser *hc.Browser, conn *hc.Conn,
+	contextId protocol.BrowserContextID, url string) *hc.Conn {
+	t.Helper()
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{
+		Url: "about:blank", BrowserContextId: contextId}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		protocol.CloseTarget(&protocol.CloseTargetParams{TargetId: target.TargetId}, conn)
+	})
+	page, err := browser.NewPageConn(string(target.TargetId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { page.Close() })
+	if err := hcutil.NavigateAndWait(page, url, navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+// The same cookie set to different values in two contexts is seen by each context's pages with
+// its own value only.
+func TestIntegrationCookieJarIsolation(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	browser := hctest.SharedBrowser(t)
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	values := []string{"a", "b"}
+	jars := make([]*hcutil.CookieJar, len(values))
+	for i, value := range values {
+		context, err := protocol.CreateBrowserContext(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer protocol.DisposeBrowserContext(&protocol.DisposeBrowserContextParams{
+			BrowserContextId: context.BrowserContextId}, conn)
+		if jars[i], err = hcutil.NewCookieJar(browser, conn, context.BrowserContextId); err != nil {
+			t.Fatal(err)
+		}
+		defer jars[i].Close()
+		if err := jars[i].SetCookie(&protocol.SetCookieParams{Url: fixtures.URL, Name: "shared",
+			Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, value := range values {
+		page := openInContext(t, browser, conn, jars[i].ContextId,
+			fixtures.URL+hctest.FixtureStatic)
+		if cookie := evaluateString(t, page, "document.cookie"); cookie != "shared="+value {
+			t.Errorf("Context of %s sees %q", value, cookie)
+		}
+		cookies, err := jars[i].Cookies()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found := hcutil.FilterCookies(cookies, hcutil.MatchName("shared")); len(found) != 1 ||
+			found[0].Value != value {
+			t.Errorf("Jar of %s has %+v", value, found)
+		}
+	}
+}
+
+// Clicks land on the element scrolled to, with and without a scaled mobile viewport.
+func TestIntegrationScrollAndClick(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureScrollClick)
+	for _, metrics := range []*protocol.EmulationSetDeviceMetricsOverrideParams{
+		nil,
+		{Width: 400, Height: 600, DeviceScaleFactor: 2, Mobile: true},
+	} {
+		if metrics != nil {
+			if err := hcutil.SetDeviceMetrics(conn, metrics); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := hcutil.Evaluate(conn, "window.clicked = []", nil); err != nil {
+			t.Fatal(err)
+		}
+		for _, selector := range []string{"#bottom", "#top"} {
+			if err := hcutil.ClickLikeUser(conn, selector); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if clicked := evaluateString(t, conn, "window.clicked.join()"); clicked != "bottom,top" {
+			t.Errorf("Metrics %+v: clicked %q", metrics, clicked)
+		}
+	}
+}
+
+// The background of the color scheme fixture follows the emulated scheme.
+func TestIntegrationColorScheme(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	for _, c := range []struct {
+		emulate func(conn *hc.Conn) error
+		want    color.Color
+	}{
+		{hcutil.EmulateDarkMode, color.Black},
+		{hcutil.EmulateLightMode, color.White},
+	} {
+		if err := c.emulate(conn); err != nil {
+			t.Fatal(err)
+		}
+		// Emulation applies to documents loaded afterwards.
+		if err := hcutil.NavigateAndWait(conn, fixtures.URL+hctest.FixtureColorScheme,
+			navigateTimeout); err != nil {
+			t.Fatal(err)
+		}
+		data, err := hcutil.CaptureScreenshot(conn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := img.Bounds()
+		center := img.At((b.Min.X+b.Max.X)/2, (b.Min.Y+b.Max.Y)/2)
+		if color.RGBAModel.Convert(center) != color.RGBAModel.Convert(c.want) {
+			t.Errorf("Got background %v, want %v", center, c.want)
+		}
+	}
+}
+
+// Blocking images skips the slow image of FixtureSlow, so the page loads without waiting for it,
+// and no image response is received.
+func TestIntegrationBlockImages(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	browser := hctest.SharedBrowser(t)
+	load := func(block bool) (time.Duration, []protocol.ResourceType) {
+		conn := hctest.NewPage(t, browser, "about:blank")
+		if block {
+			if err := hcutil.BlockResourceTypes(conn, protocol.ResourceTypeImage); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := protocol.NetworkEnable(&protocol.NetworkEnableParams{}, conn); err != nil {
+			t.Fatal(err)
+		}
+		var mu sync.Mutex
+		var types []protocol.ResourceType
+		conn.AddEventSink("Network.responseReceived", hc.FuncToEventSink(
+			func(_ string, params []byte) {
+				var evt protocol.ResponseReceivedEvent
+				if err := json.Unmarshal(params, &evt); err == nil {
+					mu.Lock()
+					types = append(types, evt.Type)
+					mu.Unlock()
+				}
+			}))
+		start := time.Now()
+		if err := hcutil.NavigateAndWait(conn, fixtures.URL+hctest.FixtureSlow,
+			navigateTimeout); err != nil {
+			t.Fatal(err)
+		}
+		elapsed := time.Since(start)
+		hctest.Flush(t, conn)
+		if block {
+			if n := hcutil.BlockedCounts(conn)[protocol.ResourceTypeImage]; n != 1 {
+				t.Errorf("Got %d blocked images, want 1", n)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return elapsed, types
+	}
+
+	if elapsed, _ := load(false); elapsed < hctest.FixtureSlowDelay {
+		t.Fatalf("Loaded in %v without blocking, faster than the image", elapsed)
+	}
+	elapsed, types := load(true)
+	if elapsed >= hctest.FixtureSlowDelay {
+		t.Errorf("Loaded in %v with images blocked", elapsed)
+	}
+	for _, tp := range types {
+		if tp == protocol.ResourceTypeImage {
+			t.Errorf("Got an image response with images blocked, all responses %v", types)
+			break
+		}
+	}
+}
+
+// Screenshots of the same page at DPR 1 and 2 have the pixel sizes their options ask for.
+func TestIntegrationScreenshotDimensions(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureStatic)
+	if err := hcutil.SetDeviceMetrics(conn, &protocol.EmulationSetDeviceMetricsOverrideParams{
+		Width: 400, Height: 300, DeviceScaleFactor: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		opts          hcutil.ScreenshotOptions
+		width, height int
+	}{
+		{opts: hcutil.ScreenshotOptions{DeviceScaleFactor: 1}, width: 400, height: 300},
+		{opts: hcutil.ScreenshotOptions{DeviceScaleFactor: 2}, width: 800, height: 600},
+		// Full pages are as wide as asked for, unless HiDPI output is kept.
+		{opts: hcutil.ScreenshotOptions{FullPage: true, Width: 300, DeviceScaleFactor: 1},
+			width: 300},
+		{opts: hcutil.ScreenshotOptions{FullPage: true, Width: 300, DeviceScaleFactor: 2},
+			width: 300},
+		{opts: hcutil.ScreenshotOptions{FullPage: true, Width: 300, DeviceScaleFactor: 2,
+			KeepHiDPI: true}, width: 600},
+	} {
+		data, err := hcutil.CaptureScreenshot(conn, &c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Width != c.width || (c.height > 0 && config.Height != c.height) {
+			t.Errorf("%+v: got %dx%d, want %dx%d", c.opts, config.Width, config.Height,
+				c.width, c.height)
+		}
+	}
+}
+
+// A navigation aborted on timeout doesn't leak into the next one: the next page loads, and its
+// main document response is its own.
+func TestIntegrationAbortOnTimeout(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	opts := &hcutil.NavigateOptions{Timeout: hctest.FixtureSlowDelay / 4, AbortOnTimeout: true}
+	if err := hcutil.NavigateAndWaitWithOptions(conn, fixtures.URL+hctest.FixtureSlow,
+		opts); err != hcutil.ErrTimeout {
+		t.Fatalf("Got %v", err)
+	}
+	opts.Timeout = navigateTimeout
+	url := fixtures.URL + hctest.FixtureStatic
+	if err := hcutil.NavigateAndWaitWithOptions(conn, url, opts); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := hcutil.MainDocumentResponse(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Url != url || resp.Status != 200 {
+		t.Errorf("Got %d from %s", int(resp.Status), resp.Url)
+	}
+	if title := evaluateString(t, conn, "document.title"); title != "Static" {
+		t.Errorf("Got title %q", title)
+	}
+}
+
+// The canvas of FixtureCanvas is redrawn by its resize listener before SetViewportSize returns,
+// so it fills screenshots of both sizes.
+func TestIntegrationCanvasResize(t *testing.T) {
+	conn, _ := openFixture(t, hctest.FixtureCanvas)
+	for _, width := range []int{400, 600} {
+		if err := hcutil.SetViewportSize(conn, width, 300); err != nil {
+			t.Fatal(err)
+		}
+		data, err := hcutil.CaptureScreenshot(conn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := img.Bounds()
+		black := color.RGBAModel.Convert(color.Black)
+		if b.Dx() != width {
+			t.Errorf("Got a %d pixel wide screenshot, want %d", b.Dx(), width)
+		} else if right := img.At(b.Max.X-1, b.Max.Y/2); color.RGBAModel.Convert(right) != black {
+			t.Errorf("Width %d: the canvas ends before the right edge", width)
+		}
+	}
+}
+
+// 1GB of EventSource messages kept by a StreamMonitor stay under its budget's cap, and are read
+// back from the spill files intact.
+func TestIntegrationStreamBudgetStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Streams 1GB")
+	}
+	const (
+		capBytes = 64 << 20
+		mb       = 1 << 10
+	)
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	budget, err := bytebudget.New(bytebudget.Options{Cap: capBytes, Spill: true,
+		SpillDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer budget.Close()
+	var mu sync.Mutex
+	var maxMemory int64
+	monitor, err := hcutil.NewStreamMonitor(conn, hcutil.StreamMonitorOptions{
+		URLPattern: regexp.MustCompile(regexp.QuoteMeta(hctest.FixtureEventStream)),
+		MaxChunks:  1 << 20,
+		Budget:     budget,
+		OnChunk: func(protocol.RequestId, *hcutil.StreamChunk) {
+			memory := budget.Usage().Memory
+			mu.Lock()
+			if memory > maxMemory {
+				maxMemory = memory
+			}
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Release()
+	url := fmt.Sprintf("%s%s?mb=%d", fixtures.URL, hctest.FixtureEventStreamPage, mb)
+	if err := hcutil.NavigateAndWait(conn, url, navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if err := hcutil.WaitForCondition(conn, "window.done", 10*time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	hctest.Flush(t, conn)
+	monitor.Stop()
+	mu.Lock()
+	if maxMemory > capBytes {
+		t.Errorf("Used up to %d bytes, over the cap of %d", maxMemory, capBytes)
+	}
+	mu.Unlock()
+	if usage := budget.Usage(); usage.Spilled < mb<<20-capBytes {
+		t.Errorf("Spilled only %d bytes", usage.Spilled)
+	}
+
+	streams := monitor.Streams()
+	if len(streams) != 1 {
+		t.Fatalf("Got %d streams", len(streams))
+	}
+	i := 0
+	for _, chunk := range streams[0].Chunks {
+		if !chunk.Message || chunk.Data == "end" {
+			continue
+		}
+		if chunk.DataDropped {
+			t.Fatalf("Message %d dropped", i)
+		}
+		want := strings.Repeat(string(rune('a'+i%26)), hctest.FixtureEventStreamMessageSize)
+		if chunk.Data != want {
+			t.Fatalf("Message %d is corrupted", i)
+		}
+		i++
+	}
+	if i != mb<<20/hctest.FixtureEventStreamMessageSize {
+		t.Errorf("Got %d messages", i)
+	}
+}
+
+// Visibility set before navigating is what the page's first script sees, and changing it later
+// fires visibilitychange.
+func TestIntegrationVisibility(t *testing.T) {
+	t.Parallel()
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), "about:blank")
+	if err := hcutil.SetVisibilityState(conn, hcutil.VisibilityHidden); err != nil {
+		t.Fatal(err)
+	}
+	if err := hcutil.NavigateAndWait(conn, fixtures.URL+hctest.FixtureVisibility,
+		navigateTimeout); err != nil {
+		t.Fatal(err)
+	}
+	const getLog = `document.getElementById("log").textContent`
+	if log := evaluateString(t, conn, getLog); log != "initial hidden false" {
+		t.Errorf("Got %q after loading hidden", log)
+	}
+	if err := hcutil.SetVisibilityState(conn, hcutil.VisibilityVisible); err != nil {
+		t.Fatal(err)
+	}
+	want := "initial hidden false\nvisibilitychange visible true"
+	if log := evaluateString(t, conn, getLog); log != want {
+		t.Errorf("Got %q after showing, want %q", log, want)
+	}
+}
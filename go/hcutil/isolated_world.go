@@ -0,0 +1,127 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// A named isolated world. Scripts running in it share the DOM with the page, but not its
+// JavaScript globals, so they can neither clobber nor be detected by the page's own code.
+// Execution contexts are created lazily per frame and re-created after the frame navigates.
+type IsolatedWorld struct {
+	conn        *hc.Conn
+	name        string
+	cancel      func()
+	removeState func()
+
+	mu         sync.Mutex
+	contextMap map[protocol.FrameId]protocol.ExecutionContextId
+}
+
+func NewIsolatedWorld(conn *hc.Conn, name string) *IsolatedWorld {
+	w := &IsolatedWorld{
+		conn:       conn,
+		name:       name,
+		contextMap: make(map[protocol.FrameId]protocol.ExecutionContextId),
+	}
+	w.cancel = listen(conn, "Page.frameNavigated", w.onFrameNavigated)
+	w.removeState = conn.AddStateDumper(StateIsolatedWorld+stateId(name, w), w.state)
+	return w
+}
+
+// Stops tracking navigations. The execution contexts go away with their documents.
+func (w *IsolatedWorld) Close() {
+	w.cancel()
+	w.removeState()
+}
+
+func (w *IsolatedWorld) onFrameNavigated(params []byte) {
+	evt := &protocol.FrameNavigatedEvent{}
+	if err := json.Unmarshal(params, evt); err != nil {
+		logging.Vlog(-1, err)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.contextMap, protocol.FrameId(evt.Frame.Id))
+}
+
+// Returns the execution context of the world in frame, creating it if necessary.
+// Empty frameId means the main frame.
+func (w *IsolatedWorld) ContextId(frameId protocol.FrameId) (protocol.ExecutionContextId, error) {
+	if frameId == "" {
+		var err error
+		if frameId, err = MainFrameId(w.conn); err != nil {
+			return 0, err
+		}
+	}
+	w.mu.Lock()
+	contextId, ok := w.contextMap[frameId]
+	w.mu.Unlock()
+	if ok {
+		return contextId, nil
+	}
+	result, err := protocol.CreateIsolatedWorld(&protocol.CreateIsolatedWorldParams{
+		FrameId:   frameId,
+		WorldName: w.name,
+	}, w.conn)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.contextMap[frameId] = result.ExecutionContextId
+	return result.ExecutionContextId, nil
+}
+
+// Evaluates expression in the world of frame and unmarshals its value into result.
+func (w *IsolatedWorld) Evaluate(frameId protocol.FrameId, expression string,
+	result interface{}) error {
+	contextId, err := w.ContextId(frameId)
+	if err != nil {
+		return err
+	}
+	return EvaluateWithParams(w.conn, &protocol.EvaluateParams{
+		Expression: expression,
+		ContextId:  contextId,
+	}, result)
+}
+
+// Calls functionDeclaration with args, which are passed as JSON, in the world of frame and
+// unmarshals its return value into result.
+func (w *IsolatedWorld) CallFunction(frameId protocol.FrameId, functionDeclaration string,
+	args []interface{}, result interface{}) error {
+	expression, err := callExpression(functionDeclaration, args)
+	if err != nil {
+		return err
+	}
+	return w.Evaluate(frameId, expression, result)
+}
+
+func callExpression(functionDeclaration string, args []interface{}) (string, error) {
+	jsArgs := make([]string, len(args))
+	for i, arg := range args {
+		value, err := jsbuilder.JSValue(arg)
+		if err != nil {
+			return "", err
+		}
+		jsArgs[i] = value
+	}
+	return "(" + functionDeclaration + ")(" + strings.Join(jsArgs, ", ") + ")", nil
+}
+
+// Returns id of the main frame of the page.
+func MainFrameId(conn *hc.Conn) (protocol.FrameId, error) {
+	result, err := protocol.GetResourceTree(conn)
+	if err != nil {
+		return "", err
+	}
+	return protocol.FrameId(result.FrameTree.Frame.Id), nil
+}
@@ -0,0 +1,104 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// Worlds are created once per document, and evaluations run in the world's context.
+func TestIsolatedWorldRecreatedAfterNavigation(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Page.getResourceTree", hctest.FakeResult(map[string]interface{}{
+		"frameTree": map[string]interface{}{"frame": map[string]interface{}{
+			"id": "main", "loaderId": "l1", "url": "http://a.test/", "securityOrigin": "",
+			"mimeType": "text/html"}}}))
+	contexts := 0
+	server.Handle("Page.createIsolatedWorld", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		contexts++
+		return map[string]int{"executionContextId": contexts}, nil
+	})
+	server.Handle("Runtime.evaluate", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		var params struct {
+			ContextId int
+		}
+		json.Unmarshal(cmd.Params, &params)
+		return map[string]interface{}{
+			"result": map[string]interface{}{"type": "number", "value": params.ContextId}}, nil
+	})
+	conn, fake := server.NewPageConn()
+	hctest.ExpectNoSinkLeaks(t, conn)
+	world := hcutil.NewIsolatedWorld(conn, "hc-test")
+	defer world.Close()
+
+	evaluate := func(want int) {
+		t.Helper()
+		var contextId int
+		if err := world.Evaluate("", "1", &contextId); err != nil {
+			t.Fatal(err)
+		}
+		if contextId != want {
+			t.Errorf("Evaluated in context %d, not %d", contextId, want)
+		}
+	}
+	evaluate(1)
+	evaluate(1)
+	creates := server.CommandsOf("Page.createIsolatedWorld")
+	if len(creates) != 1 {
+		t.Fatalf("Created %d worlds", len(creates))
+	}
+	var params struct{ FrameId, WorldName string }
+	json.Unmarshal(creates[0].Params, &params)
+	if params.FrameId != "main" || params.WorldName != "hc-test" {
+		t.Errorf("Created %+v", params)
+	}
+
+	fake.Emit("Page.frameNavigated", map[string]interface{}{
+		"frame": map[string]interface{}{"id": "main", "loaderId": "l2",
+			"url": "http://b.test/", "securityOrigin": "", "mimeType": "text/html"}})
+	hctest.Flush(t, conn)
+	evaluate(2)
+	if n := len(server.CommandsOf("Page.createIsolatedWorld")); n != 2 {
+		t.Errorf("Created %d worlds after navigating", n)
+	}
+
+	// Navigations of other frames keep the world.
+	fake.Emit("Page.frameNavigated", map[string]interface{}{
+		"frame": map[string]interface{}{"id": "child", "parentId": "main", "loaderId": "l3",
+			"url": "http://c.test/", "securityOrigin": "", "mimeType": "text/html"}})
+	hctest.Flush(t, conn)
+	evaluate(2)
+}
+
+func TestIsolatedWorldCallFunction(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Page.createIsolatedWorld", hctest.FakeResult(
+		map[string]int{"executionContextId": 7}))
+	server.Handle("Runtime.evaluate", hctest.FakeResult(map[string]interface{}{
+		"result": map[string]interface{}{"type": "string", "value": "ok"}}))
+	conn, _ := server.NewPageConn()
+	world := hcutil.NewIsolatedWorld(conn, "hc-test")
+	defer world.Close()
+
+	var result string
+	if err := world.CallFunction("frame", "function(a, b) { return a + b; }",
+		[]interface{}{"x\"y", 2}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result != "ok" {
+		t.Errorf("Got %q", result)
+	}
+	var params struct {
+		Expression string
+		ContextId  int
+	}
+	json.Unmarshal(server.CommandsOf("Runtime.evaluate")[0].Params, &params)
+	if expression := params.Expression; params.ContextId != 7 ||
+		!strings.HasPrefix(expression, "(function(a, b) { return a + b; })(") ||
+		!strings.Contains(expression, `"x\"y"`) {
+		t.Errorf("Evaluated %s", expression)
+	}
+}
@@ -0,0 +1,84 @@
+package hcutil
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrJSDisabled = errors.New("JavaScript is disabled")
+
+type jsDisabledKey struct{}
+
+// Stops the page from running its own scripts, e.g. to take archival snapshots. Applies to
+// documents loaded afterwards, so call it before navigating.
+func DisableJavaScript(conn *hc.Conn) error {
+	return setScriptExecutionDisabled(conn, true)
+}
+
+func EnableJavaScript(conn *hc.Conn) error {
+	return setScriptExecutionDisabled(conn, false)
+}
+
+func setScriptExecutionDisabled(conn *hc.Conn, disabled bool) error {
+	if err := protocol.SetScriptExecutionDisabled(
+		&protocol.SetScriptExecutionDisabledParams{Value: disabled}, conn); err != nil {
+		return err
+	}
+	conn.SetValue(jsDisabledKey{}, disabled)
+	return nil
+}
+
+// Whether JavaScript was disabled by DisableJavaScript.
+func JavaScriptDisabled(conn *hc.Conn) bool {
+	disabled, _ := conn.Value(jsDisabledKey{}, func() interface{} { return false }).(bool)
+	return disabled
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+var spacesRe = regexp.MustCompile(`\s+`)
+
+// ExtractLinks without JavaScript. Text is the text content of the links, not what's rendered.
+func extractLinksWithDOM(conn *hc.Conn) ([]Link, error) {
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := doc.Root.BaseURL
+	if baseURL == "" {
+		baseURL = doc.Root.DocumentURL
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	result, err := protocol.QuerySelectorAll(&protocol.QuerySelectorAllParams{
+		NodeId: doc.Root.NodeId, Selector: "a[href]"}, conn)
+	if err != nil {
+		return nil, err
+	}
+	var links []Link
+	for _, nodeId := range result.NodeIds {
+		attrs, err := NodeAttributes(conn, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		link := Link{Href: attrs["href"]}
+		if href, err := base.Parse(strings.TrimSpace(link.Href)); err == nil {
+			link.Href = href.String()
+		}
+		outer, err := protocol.GetOuterHTML(&protocol.GetOuterHTMLParams{NodeId: nodeId}, conn)
+		if err != nil {
+			return nil, err
+		}
+		text := html.UnescapeString(tagRe.ReplaceAllString(outer.OuterHTML, " "))
+		link.Text = strings.TrimSpace(spacesRe.ReplaceAllString(text, " "))
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -0,0 +1,82 @@
+package hcutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Simplified BCP 47: language, then optional script / region / variant subtags.
+var languageTagRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z]{4})?(-([a-zA-Z]{2}|[0-9]{3}))?(-([a-zA-Z0-9]{5,8}|[0-9][a-zA-Z0-9]{3}))*$`)
+
+// Makes the page prefer primary, then fallbacks, e.g. SetLanguage(conn, "fr-CA", "fr", "en").
+// Both the Accept-Language header and navigator.language(s) are overridden, so they agree.
+// Call it before navigating. Note that it replaces extra HTTP headers set before.
+func SetLanguage(conn *hc.Conn, primary string, fallbacks ...string) error {
+	languages := append([]string{primary}, fallbacks...)
+	if err := checkLanguages(languages); err != nil {
+		return err
+	}
+	if err := protocol.SetExtraHTTPHeaders(&protocol.SetExtraHTTPHeadersParams{
+		Headers: protocol.Headers{"Accept-Language": acceptLanguage(languages)}}, conn); err != nil {
+		return err
+	}
+	return injectLanguages(conn, languages)
+}
+
+func checkLanguages(languages []string) error {
+	for _, lang := range languages {
+		if !languageTagRegexp.MatchString(lang) {
+			return fmt.Errorf("Invalid language tag '%s'", lang)
+		}
+	}
+	return nil
+}
+
+// Returns the Accept-Language header, e.g. "fr-CA,fr;q=0.9,en;q=0.8".
+func acceptLanguage(languages []string) string {
+	parts := make([]string, len(languages))
+	for i, lang := range languages {
+		if i == 0 {
+			parts[i] = lang
+		} else {
+			q := 10 - i
+			if q < 1 {
+				q = 1
+			}
+			parts[i] = fmt.Sprintf("%s;q=0.%d", lang, q)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+type languageScriptKey struct{}
+
+// Overrides navigator.language(s) of new documents, replacing the override injected before.
+func injectLanguages(conn *hc.Conn, languages []string) error {
+	data, err := jsbuilder.JSValue(languages)
+	if err != nil {
+		return err
+	}
+	if id, ok := conn.Value(languageScriptKey{}, func() interface{} {
+		return protocol.ScriptIdentifier("")
+	}).(protocol.ScriptIdentifier); ok && id != "" {
+		if err := RemoveInjected(conn, id); err != nil {
+			return err
+		}
+	}
+	id, err := InjectOnNewDocument(conn, fmt.Sprintf(`(function() {
+	var languages = %s;
+	Object.defineProperty(navigator, "language", {get: function() { return languages[0]; }});
+	Object.defineProperty(navigator, "languages", {get: function() { return languages.slice(); }});
+})();`, data))
+	if err != nil {
+		return err
+	}
+	conn.SetValue(languageScriptKey{}, id)
+	return nil
+}
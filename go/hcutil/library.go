@@ -0,0 +1,291 @@
+package hcutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Returned by ScriptLibrary.Call if the library isn't in the page even after injecting it into
+// the current document, e.g. because the page's Content-Security-Policy blocked it, or the page
+// replaced the global. Use ScriptLibrary.CallExpression with Evaluate instead.
+var ErrLibraryBlocked = errors.New("script library couldn't be injected into the page")
+
+// Functions injected into every document of a page, e.g. extraction helpers, which are then
+// called by name with ScriptLibrary.Call instead of sending their source with every evaluation.
+// Functions live in a hidden global named after the namespace, or in an isolated world.
+type ScriptLibrary struct {
+	namespace string
+	world     *IsolatedWorld
+
+	mu      sync.Mutex
+	funcMap map[string]libraryFunc
+	version string
+	script  string
+}
+
+type libraryFunc struct {
+	source string
+	hash   string
+}
+
+type ScriptLibraryOptions struct {
+	// Name of the global holding the functions. Defaults to "__hcLib".
+	Namespace string
+	// If set, functions are injected lazily into this isolated world of each frame instead of
+	// into the page's own world, where neither the page nor its CSP can interfere with them.
+	World *IsolatedWorld
+}
+
+const defaultLibraryNamespace = "__hcLib"
+
+// Creates an empty library. opts may be nil.
+func NewScriptLibrary(opts *ScriptLibraryOptions) *ScriptLibrary {
+	lib := &ScriptLibrary{
+		namespace: defaultLibraryNamespace,
+		funcMap:   make(map[string]libraryFunc),
+	}
+	if opts != nil {
+		if opts.Namespace != "" {
+			lib.namespace = opts.Namespace
+		}
+		lib.world = opts.World
+	}
+	return lib
+}
+
+// Adds function source under name, e.g. Register("tableToJSON", "function(selector) {...}"),
+// replacing a function registered before. Pages get it the next time Call runs on them.
+func (lib *ScriptLibrary) Register(name, source string) error {
+	if name == "" {
+		return errors.New("Empty function name")
+	} else if strings.TrimSpace(source) == "" {
+		return fmt.Errorf("Empty source of function %s", name)
+	}
+	sum := sha256.Sum256([]byte(source))
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	lib.funcMap[name] = libraryFunc{source: source, hash: hex.EncodeToString(sum[:8])}
+	lib.script = ""
+	return nil
+}
+
+// Returns the script defining the functions, and its version, which changes with any of them.
+func (lib *ScriptLibrary) build() (script, version string) {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	if lib.script != "" {
+		return lib.script, lib.version
+	}
+	names := make([]string, 0, len(lib.funcMap))
+	for name := range lib.funcMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	// Functions whose hash didn't change keep their current definition, so re-injecting the
+	// library neither re-evaluates their sources nor replaces what callers may have captured.
+	b.WriteString(`(function(lib) {
+	function define(name, hash, get) {
+		if (lib.hashes[name] !== hash) {
+			lib.fns[name] = get();
+			lib.hashes[name] = hash;
+		}
+	}
+`)
+	versionHash := sha256.New()
+	for _, name := range names {
+		f := lib.funcMap[name]
+		fmt.Fprintf(&b, "\tdefine(%s, %s, function() { return (%s\n); });\n",
+			jsbuilder.JSString(name), jsbuilder.JSString(f.hash), f.source)
+		fmt.Fprintf(versionHash, "%s\x00%s\x00", name, f.hash)
+	}
+	ns := jsbuilder.JSString(lib.namespace)
+	fmt.Fprintf(&b, `})(window[%s] || Object.defineProperty(window, %s, {
+	value: {fns: Object.create(null), hashes: Object.create(null)}
+})[%s]);`, ns, ns, ns)
+	lib.script = b.String()
+	lib.version = hex.EncodeToString(versionHash.Sum(nil)[:8])
+	return lib.script, lib.version
+}
+
+// What the library injected into a page.
+type libraryState struct {
+	mu       sync.Mutex
+	scriptId protocol.ScriptIdentifier
+	version  string
+}
+
+type libraryKey struct {
+	lib *ScriptLibrary
+}
+
+func (lib *ScriptLibrary) state(conn *hc.Conn) *libraryState {
+	return conn.Value(libraryKey{lib}, func() interface{} {
+		return &libraryState{}
+	}).(*libraryState)
+}
+
+// Injects the library into every new document of the page. Call does this as needed, but
+// calling Install before navigating makes the functions available to documents from the start.
+// Does nothing if the same version is injected already. Libraries with an isolated world are
+// injected lazily by Call only.
+func (lib *ScriptLibrary) Install(conn *hc.Conn) error {
+	if lib.world != nil {
+		return nil
+	}
+	script, version := lib.build()
+	state := lib.state(conn)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.version == version {
+		return nil
+	}
+	if state.scriptId != "" {
+		if err := RemoveInjected(conn, state.scriptId); err != nil {
+			return err
+		}
+		state.scriptId, state.version = "", ""
+	}
+	id, err := InjectOnNewDocument(conn, script)
+	if err != nil {
+		return err
+	}
+	state.scriptId, state.version = id, version
+	return nil
+}
+
+// Stops injecting the library into new documents. Documents which have it keep it.
+func (lib *ScriptLibrary) Uninstall(conn *hc.Conn) error {
+	state := lib.state(conn)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.scriptId == "" {
+		return nil
+	}
+	if err := RemoveInjected(conn, state.scriptId); err != nil {
+		return err
+	}
+	state.scriptId, state.version = "", ""
+	return nil
+}
+
+const libraryCallFunction = `function(name, args) { return this.fns[name].apply(null, args); }`
+
+// Calls the function registered as name in the main frame with args, which are passed as JSON,
+// and unmarshals its return value into result, which may be nil. The library is installed first
+// if necessary, and injected into the current document if it doesn't have the function yet,
+// e.g. because it was loaded before. Returns ErrLibraryBlocked if that fails.
+func (lib *ScriptLibrary) Call(conn *hc.Conn, name string, args []interface{},
+	result interface{}) error {
+	lib.mu.Lock()
+	f, found := lib.funcMap[name]
+	lib.mu.Unlock()
+	if !found {
+		return fmt.Errorf("Function %s isn't registered", name)
+	}
+	if err := lib.Install(conn); err != nil {
+		return err
+	}
+	var contextId protocol.ExecutionContextId
+	if lib.world != nil {
+		var err error
+		if contextId, err = lib.world.ContextId(""); err != nil {
+			return err
+		}
+	}
+	group, end := conn.BeginHelperObjects()
+	created := 0
+	defer func() { end(created) }()
+	objectId, err := lib.lookup(conn, contextId, name, f.hash, group)
+	if err != nil {
+		return err
+	}
+	if objectId == "" {
+		script, _ := lib.build()
+		if err := EvaluateWithParams(conn, &protocol.EvaluateParams{
+			Expression: script, ContextId: contextId}, nil); err != nil {
+			return err
+		}
+		if objectId, err = lib.lookup(conn, contextId, name, f.hash, group); err != nil {
+			return err
+		} else if objectId == "" {
+			return ErrLibraryBlocked
+		}
+	}
+	created++
+
+	if args == nil {
+		args = []interface{}{}
+	}
+	argsData, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	nameData, _ := json.Marshal(name)
+	if err := conn.ThrottleEvaluation(); err != nil {
+		return err
+	}
+	res, err := protocol.CallFunctionOn(&protocol.CallFunctionOnParams{
+		ObjectId:            objectId,
+		FunctionDeclaration: libraryCallFunction,
+		Arguments: []*protocol.CallArgument{
+			{Value: json.RawMessage(nameData)}, {Value: json.RawMessage(argsData)}},
+		ReturnByValue: true,
+	}, conn)
+	if err != nil {
+		return err
+	} else if res.ExceptionDetails != nil {
+		return exceptionError(res.ExceptionDetails)
+	} else if result == nil || res.Result == nil || len(res.Result.Value) == 0 {
+		return nil
+	}
+	return json.Unmarshal([]byte(res.Result.Value), result)
+}
+
+// Returns the library object of the context, in object group, if it has name at hash, otherwise
+// "".
+func (lib *ScriptLibrary) lookup(conn *hc.Conn, contextId protocol.ExecutionContextId,
+	name, hash, group string) (protocol.RemoteObjectId, error) {
+	expression, err := jsbuilder.JSTemplate(`(function(lib) {
+	return lib && lib.hashes && lib.hashes[{{.Name}}] === {{.Hash}} ? lib : undefined;
+})(window[{{.Namespace}}])`, map[string]interface{}{
+		"Name": name, "Hash": hash, "Namespace": lib.namespace})
+	if err != nil {
+		return "", err
+	}
+	if err := conn.ThrottleEvaluation(); err != nil {
+		return "", err
+	}
+	res, err := protocol.Evaluate(&protocol.EvaluateParams{
+		Expression: expression, ContextId: contextId, ObjectGroup: group}, conn)
+	if err != nil {
+		return "", err
+	} else if res.ExceptionDetails != nil {
+		return "", exceptionError(res.ExceptionDetails)
+	} else if res.Result == nil {
+		return "", nil
+	}
+	return res.Result.ObjectId, nil
+}
+
+// Returns an expression calling the function registered as name with args, for Evaluate, e.g.
+// on pages where Call returns ErrLibraryBlocked.
+func (lib *ScriptLibrary) CallExpression(name string, args []interface{}) (string, error) {
+	lib.mu.Lock()
+	f, found := lib.funcMap[name]
+	lib.mu.Unlock()
+	if !found {
+		return "", fmt.Errorf("Function %s isn't registered", name)
+	}
+	return callExpression(f.source, args)
+}
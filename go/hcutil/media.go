@@ -0,0 +1,46 @@
+package hcutil
+
+import (
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+type mediaFeature struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Makes the page prefer dark color scheme. See EmulateMediaFeature.
+func EmulateDarkMode(conn *hc.Conn) error {
+	return EmulateMediaFeature(conn, "prefers-color-scheme", "dark")
+}
+
+func EmulateLightMode(conn *hc.Conn) error {
+	return EmulateMediaFeature(conn, "prefers-color-scheme", "light")
+}
+
+func EmulateReducedMotion(conn *hc.Conn) error {
+	return EmulateMediaFeature(conn, "prefers-reduced-motion", "reduce")
+}
+
+// Emulates a CSS media feature, e.g. "prefers-color-scheme", with value, e.g. "dark". Empty
+// value stops emulating it. Call it before navigating. It resets the media type set by
+// protocol.SetEmulatedMedia.
+//
+// Browsers newer than protocol v1.2 take the features of Emulation.setEmulatedMedia, which
+// affects everything. Older ones ignore them, so matchMedia() is overridden as well. But then
+// @media rules of style sheets are not affected, only scripts checking the features are.
+func EmulateMediaFeature(conn *hc.Conn, name, value string) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	media := make(map[string]string)
+	for k, v := range e.state.media {
+		media[k] = v
+	}
+	if value == "" {
+		delete(media, name)
+	} else {
+		media[name] = value
+	}
+	return e.setMedia(conn, media)
+}
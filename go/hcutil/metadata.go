@@ -0,0 +1,281 @@
+package hcutil
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrNoFavicon = errors.New("no favicon")
+
+// Metadata of a page, e.g. for link previews. URLs are absolute.
+type PageMetadata struct {
+	URL string `json:"url"`
+	// The title element, or og:title or twitter:title if it's missing.
+	Title string `json:"title"`
+	// The description meta tag, or og:description or twitter:description if it's missing.
+	Description  string `json:"description"`
+	CanonicalURL string `json:"canonicalURL"`
+	// The og:image or twitter:image.
+	Image    string `json:"image"`
+	SiteName string `json:"siteName"`
+	Language string `json:"language"`
+	// Open Graph properties without "og:", e.g. "type", and Twitter card fields without
+	// "twitter:", e.g. "card". Only the first of repeated ones is kept.
+	OpenGraph map[string]string `json:"openGraph"`
+	Twitter   map[string]string `json:"twitter"`
+	Icons     []*Icon           `json:"icons"`
+}
+
+// An icon link of a page.
+type Icon struct {
+	URL string `json:"url"`
+	// E.g. "icon" or "apple-touch-icon".
+	Rel string `json:"rel"`
+	// E.g. "32x32 64x64" or "any".
+	Sizes string `json:"sizes"`
+	// The type attribute, e.g. "image/png". Often empty.
+	Type string `json:"type"`
+}
+
+const extractMetadataScript = `(function() {
+	function abs(href) {
+		if (!href) return "";
+		try { return new URL(href, document.baseURI).href; } catch (e) { return ""; }
+	}
+	function meta(attr, prefix) {
+		var result = {};
+		document.querySelectorAll("meta[" + attr + "]").forEach(function(m) {
+			var key = m.getAttribute(attr).trim().toLowerCase();
+			if (key.indexOf(prefix) !== 0 || !m.content) return;
+			key = key.substring(prefix.length);
+			if (!(key in result)) result[key] = m.content.trim();
+		});
+		return result;
+	}
+	var named = meta("name", "");
+	var og = meta("property", "og:");
+	// Some sites use name instead of property.
+	var ogNamed = meta("name", "og:");
+	for (var k in ogNamed) if (!(k in og)) og[k] = ogNamed[k];
+	var twitter = meta("name", "twitter:");
+	var twitterProperty = meta("property", "twitter:");
+	for (var k in twitterProperty) if (!(k in twitter)) twitter[k] = twitterProperty[k];
+	["image", "url", "image:url", "image:secure_url"].forEach(function(k) {
+		if (og[k]) og[k] = abs(og[k]);
+	});
+	if (twitter.image) twitter.image = abs(twitter.image);
+	var canonical = document.querySelector("link[rel~=canonical][href]");
+	var icons = [];
+	document.querySelectorAll("link[rel][href]").forEach(function(link) {
+		var rel = link.getAttribute("rel").toLowerCase();
+		if (!/(^|\s)(icon|apple-touch-icon|apple-touch-icon-precomposed)(\s|$)/.test(rel)) {
+			return;
+		}
+		icons.push({url: abs(link.getAttribute("href")), rel: rel,
+			sizes: link.getAttribute("sizes") || "", type: link.getAttribute("type") || ""});
+	});
+	return {
+		url: location.href,
+		title: document.title.trim() || og.title || twitter.title || "",
+		description: named.description || og.description || twitter.description || "",
+		canonicalURL: canonical ? abs(canonical.getAttribute("href")) : (og.url || ""),
+		image: og.image || og["image:url"] || og["image:secure_url"] || twitter.image || "",
+		siteName: og.site_name || named["application-name"] || "",
+		language: document.documentElement.lang || og.locale || "",
+		openGraph: og,
+		twitter: twitter,
+		icons: icons
+	};
+})()`
+
+// Returns the metadata of the page in the main frame, from a single evaluation.
+func ExtractMetadata(conn *hc.Conn) (*PageMetadata, error) {
+	metadata := &PageMetadata{}
+	if err := Evaluate(conn, extractMetadataScript, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// Returns the data and MIME type of the best icon of the page: the largest one, PNG preferred
+// among icons of the same size. Icons loaded by the page are taken from the cache, others are
+// fetched by the page, and data: URLs are decoded. Falls back to the next icon if one can't be
+// loaded, and to /favicon.ico of the page's origin without icon links. Returns ErrNoFavicon if
+// all fail.
+func FetchFavicon(conn *hc.Conn) ([]byte, string, error) {
+	metadata, err := ExtractMetadata(conn)
+	if err != nil {
+		return nil, "", err
+	}
+	var urls []string
+	for _, icon := range sortIcons(metadata.Icons) {
+		urls = append(urls, icon.URL)
+	}
+	if len(urls) == 0 {
+		if base, err := url.Parse(metadata.URL); err == nil &&
+			(base.Scheme == "http" || base.Scheme == "https") {
+			urls = append(urls, (&url.URL{Scheme: base.Scheme, Host: base.Host,
+				Path: "/favicon.ico"}).String())
+		}
+	}
+	for _, u := range urls {
+		data, mimeType, err := loadIcon(conn, u)
+		if err != nil {
+			logging.Vlogf(1, "Failed to load icon %s: %v", u, err)
+			continue
+		}
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		return data, mimeType, nil
+	}
+	return nil, "", ErrNoFavicon
+}
+
+// Best first.
+func sortIcons(icons []*Icon) []*Icon {
+	sorted := append([]*Icon(nil), icons...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		si, sj := iconSize(sorted[i]), iconSize(sorted[j])
+		if si != sj {
+			return si > sj
+		}
+		return isPNGIcon(sorted[i]) && !isPNGIcon(sorted[j])
+	})
+	return sorted
+}
+
+// The largest width in sizes, 0 if unknown, e.g. "any".
+func iconSize(icon *Icon) int {
+	size := 0
+	for _, s := range strings.Fields(strings.ToLower(icon.Sizes)) {
+		if i := strings.IndexByte(s, 'x'); i > 0 {
+			if width, err := strconv.Atoi(s[:i]); err == nil && width > size {
+				size = width
+			}
+		}
+	}
+	return size
+}
+
+func isPNGIcon(icon *Icon) bool {
+	return icon.Type == "image/png" || strings.HasSuffix(strings.ToLower(icon.URL), ".png") ||
+		strings.HasPrefix(icon.URL, "data:image/png")
+}
+
+func loadIcon(conn *hc.Conn, rawurl string) ([]byte, string, error) {
+	if strings.HasPrefix(rawurl, "data:") {
+		return decodeDataURL(rawurl)
+	}
+	data, mimeType, err := cachedResource(conn, rawurl)
+	if err == nil {
+		return data, mimeType, nil
+	}
+	logging.Vlogf(2, "%s isn't cached: %v", rawurl, err)
+	return fetchInPage(conn, rawurl)
+}
+
+// Decodes "data:[<mime type>][;base64],<data>".
+func decodeDataURL(rawurl string) ([]byte, string, error) {
+	comma := strings.IndexByte(rawurl, ',')
+	if comma < 0 {
+		return nil, "", errors.New("Invalid data URL")
+	}
+	header, payload := rawurl[len("data:"):comma], rawurl[comma+1:]
+	isBase64 := strings.HasSuffix(header, ";base64")
+	mimeType := strings.TrimSuffix(header, ";base64")
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			// Some pages omit padding.
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		}
+		return data, mimeType, err
+	}
+	data, err := url.PathUnescape(payload)
+	return []byte(data), mimeType, err
+}
+
+// Returns a resource of the main frame loaded by the page.
+func cachedResource(conn *hc.Conn, rawurl string) ([]byte, string, error) {
+	tree, err := protocol.GetResourceTree(conn)
+	if err != nil {
+		return nil, "", err
+	}
+	var resource *protocol.FrameResource
+	for _, r := range tree.FrameTree.Resources {
+		if r.Url == rawurl && !r.Failed && !r.Canceled {
+			resource = r
+			break
+		}
+	}
+	if resource == nil {
+		return nil, "", errors.New("Not a resource of the page")
+	}
+	result, err := protocol.GetResourceContent(&protocol.GetResourceContentParams{
+		FrameId: protocol.FrameId(tree.FrameTree.Frame.Id), Url: rawurl}, conn)
+	if err != nil {
+		return nil, "", err
+	}
+	if !result.Base64Encoded {
+		return []byte(result.Content), resource.MimeType, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Content)
+	return data, resource.MimeType, err
+}
+
+// Fetches rawurl from the page, with its cookies, subject to CORS for other origins.
+func fetchInPage(conn *hc.Conn, rawurl string) ([]byte, string, error) {
+	expression, err := jsbuilder.JSTemplate(`fetch({{.URL}}, {credentials: "include"})
+	.then(function(resp) {
+		if (!resp.ok) return {status: resp.status};
+		return resp.arrayBuffer().then(function(buffer) {
+			var bytes = new Uint8Array(buffer), chunks = [];
+			for (var i = 0; i < bytes.length; i += 0x8000) {
+				chunks.push(String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000)));
+			}
+			return {status: resp.status, type: resp.headers.get("Content-Type") || "",
+				data: btoa(chunks.join(""))};
+		});
+	})`, map[string]interface{}{"URL": rawurl})
+	if err != nil {
+		return nil, "", err
+	}
+	var result struct {
+		Status int    `json:"status"`
+		Type   string `json:"type"`
+		Data   string `json:"data"`
+	}
+	if err := EvaluateWithParams(conn, &protocol.EvaluateParams{
+		Expression: expression, AwaitPromise: true}, &result); err != nil {
+		return nil, "", err
+	}
+	if result.Status < 200 || result.Status >= 300 {
+		return nil, "", fmt.Errorf("Status %d", result.Status)
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		return nil, "", err
+	}
+	mimeType := result.Type
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	return data, mimeType, nil
+}
@@ -0,0 +1,275 @@
+package hcutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrTimeout = errors.New("timeout")
+
+// How far a page must be loaded for a navigation to be done.
+type ReadinessLevel int
+
+const (
+	// The load event fired. The default.
+	ReadyLoad ReadinessLevel = iota
+	// The document was parsed, though images, stylesheets etc may still be loading.
+	ReadyDOMContentLoaded
+	// No requests for 500ms after DOMContentLoaded, like MilestoneNetworkIdle.
+	ReadyNetworkIdle
+	// NavigateOptions.Ready returned true. It's polled from DOMContentLoaded on.
+	ReadyCustom
+)
+
+func (l ReadinessLevel) String() string {
+	switch l {
+	case ReadyLoad:
+		return "load"
+	case ReadyDOMContentLoaded:
+		return "DOMContentLoaded"
+	case ReadyNetworkIdle:
+		return "networkIdle"
+	case ReadyCustom:
+		return "custom"
+	}
+	return fmt.Sprintf("ReadinessLevel(%d)", int(l))
+}
+
+type NavigateOptions struct {
+	Timeout time.Duration
+	// Stop loading the page with AbortNavigation on timeout, so that the next navigation
+	// doesn't see its events.
+	AbortOnTimeout bool
+	// What to wait for. Defaults to ReadyLoad.
+	Readiness ReadinessLevel
+	// Predicate of ReadyCustom, e.g. whether an element was rendered.
+	Ready func(conn *hc.Conn) (bool, error)
+	// Fail with ErrTimeout if ReadyNetworkIdle or ReadyCustom isn't reached within Timeout.
+	// Otherwise the navigation succeeds once Timeout passed if the load event fired, with
+	// NavigationResult.FellBack set. Lower levels are always strict.
+	Strict bool
+	// Page.navigate params of newer protocols. The navigation fails with ErrUnsupported if the
+	// browser lacks them, see Navigate.
+	Referrer       string
+	TransitionType TransitionType
+}
+
+// How a navigation got done.
+type NavigationResult struct {
+	// NavigateOptions.Readiness, or ReadyLoad if it fell back. Meaningless along with an error,
+	// see Times instead.
+	Reached ReadinessLevel
+	// NavigateOptions.Readiness wasn't reached in time, so the navigation fell back to the load
+	// event. See NavigateOptions.Strict.
+	FellBack bool
+	// When levels were reached, relative to the start of the navigation. ReadyDOMContentLoaded
+	// and ReadyLoad are tracked always, others only when waited for.
+	Times map[ReadinessLevel]time.Duration
+}
+
+// Navigates the page to url and waits till its load event fires. Page and Network domains are
+// enabled as a side effect. Use MainDocumentResponse afterwards to check the response.
+func NavigateAndWait(conn *hc.Conn, url string, timeout time.Duration) error {
+	return NavigateAndWaitWithOptions(conn, url, &NavigateOptions{Timeout: timeout})
+}
+
+func NavigateAndWaitWithOptions(conn *hc.Conn, url string, opts *NavigateOptions) error {
+	_, err := NavigateAndWaitForReadiness(conn, url, opts)
+	return err
+}
+
+// Like NavigateAndWait, but waits till opts.Readiness, and reports when each level was reached.
+// The result is also returned along with ErrTimeout, telling how far the page got.
+func NavigateAndWaitForReadiness(conn *hc.Conn, url string, opts *NavigateOptions) (
+	*NavigationResult, error) {
+	if err := conn.CheckKind("Page.navigate", "Page.loadEventFired"); err != nil {
+		return nil, err
+	}
+	if opts.Readiness == ReadyCustom && opts.Ready == nil {
+		return nil, errors.New("ReadyCustom needs NavigateOptions.Ready")
+	}
+	if _, err := Correlate(conn); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result := &NavigationResult{Times: make(map[ReadinessLevel]time.Duration)}
+	var mu sync.Mutex
+	reachedCh := make(chan ReadinessLevel, 4)
+	reach := func(level ReadinessLevel) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := result.Times[level]; !ok {
+			result.Times[level] = time.Since(start)
+			reachedCh <- level
+		}
+	}
+	// Returns a copy, as sinks may still update it.
+	snapshot := func(reached ReadinessLevel, fellBack bool) *NavigationResult {
+		mu.Lock()
+		defer mu.Unlock()
+		r := &NavigationResult{Reached: reached, FellBack: fellBack,
+			Times: make(map[ReadinessLevel]time.Duration, len(result.Times))}
+		for level, t := range result.Times {
+			r.Times[level] = t
+		}
+		return r
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	idle := newNetworkIdleTracker()
+	cancels := []func(){
+		listen(conn, "Page.domContentEventFired", func([]byte) {
+			idle.parsed()
+			reach(ReadyDOMContentLoaded)
+		}),
+		listen(conn, "Page.loadEventFired", func([]byte) {
+			reach(ReadyLoad)
+		}),
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	if opts.Readiness == ReadyNetworkIdle {
+		cancels = append(cancels, idle.listen(conn))
+		if err := conn.AcquireDomain("Network", nil); err != nil {
+			return nil, err
+		}
+		cancels = append(cancels, func() {
+			if err := conn.ReleaseDomain("Network"); err != nil {
+				logging.Vlog(1, err)
+			}
+		})
+		go idle.wait(done, func() { reach(ReadyNetworkIdle) })
+	}
+
+	frameId, err := Navigate(conn, &NavigateRequest{
+		URL: url, Referrer: opts.Referrer, TransitionType: opts.TransitionType})
+	if err != nil {
+		return nil, err
+	}
+	conn.SetValue(navigatedFrameKey{}, frameId)
+	deadline := time.After(opts.Timeout)
+	readyErr := make(chan error, 1)
+	polling := false
+	for {
+		select {
+		case level := <-reachedCh:
+			if level == opts.Readiness {
+				return snapshot(level, false), nil
+			}
+			if opts.Readiness == ReadyCustom && !polling {
+				polling = true
+				go pollReady(conn, opts.Ready, done, readyErr, func() { reach(ReadyCustom) })
+			}
+		case err := <-readyErr:
+			return snapshot(opts.Readiness, false), connErr(conn, err)
+		case <-deadline:
+			mu.Lock()
+			_, loaded := result.Times[ReadyLoad]
+			mu.Unlock()
+			if loaded && !opts.Strict && opts.Readiness != ReadyDOMContentLoaded {
+				return snapshot(ReadyLoad, true), nil
+			}
+			if opts.AbortOnTimeout {
+				if err := AbortNavigation(conn); err != nil {
+					return nil, err
+				}
+			}
+			return snapshot(opts.Readiness, false), ErrTimeout
+		case <-conn.Closed():
+			return nil, conn.Err()
+		}
+	}
+}
+
+// Calls ready every conditionPollInterval till it returns true, then calls cb, unless done is
+// closed first. Errors are sent to errCh.
+func pollReady(conn *hc.Conn, ready func(conn *hc.Conn) (bool, error), done <-chan struct{},
+	errCh chan<- error, cb func()) {
+	for {
+		ok, err := ready(conn)
+		if err != nil {
+			errCh <- err
+			return
+		} else if ok {
+			cb()
+			return
+		}
+		select {
+		case <-time.After(conditionPollInterval):
+		case <-done:
+			return
+		}
+	}
+}
+
+// How long AbortNavigation waits for events of the aborted load to be delivered.
+const abortFlushTimeout = 10 * time.Second
+
+// Stops loading the page, and makes sure events of the aborted load don't reach waits started
+// afterwards: events received so far are delivered first, sticky events are forgotten, and the
+// correlator, if started, drops requests and late events of the aborted loaders.
+func AbortNavigation(conn *hc.Conn) error {
+	if err := protocol.StopLoading(conn); err != nil {
+		return connErr(conn, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), abortFlushTimeout)
+	defer cancel()
+	if err := conn.Flush(ctx); err != nil {
+		return err
+	}
+	conn.ForgetStickyEvents()
+	if c, _ := conn.Value(correlatorKey{}, nil).(*Correlator); c != nil {
+		c.abortPending()
+	}
+	return nil
+}
+
+// Returns why conn was closed instead of hc.ErrConnClosed, e.g. *hc.TargetClosedError, so that
+// callers can tell a closed tab from a closed connection.
+func connErr(conn *hc.Conn, err error) error {
+	if err == hc.ErrConnClosed {
+		if cause := conn.Err(); cause != nil {
+			return cause
+		}
+	}
+	return err
+}
+
+// Closes pageConn with hc.TargetClosedError once browserConn reports its target destroyed, so
+// that helpers waiting on the page fail right away. Pages closed by the browser itself are
+// noticed by pageConn anyway, from Inspector.detached. Target discovery is enabled on
+// browserConn as a side effect. Call stop once done with the page.
+func WatchTarget(browserConn *hc.Conn, targetId protocol.TargetID, pageConn *hc.Conn) (
+	stop func(), err error) {
+	if err := browserConn.CheckKind("Target.targetDestroyed"); err != nil {
+		return nil, err
+	}
+	stop = listen(browserConn, "Target.targetDestroyed", func(params []byte) {
+		var evt protocol.TargetDestroyedEvent
+		if err := json.Unmarshal(params, &evt); err != nil {
+			browserConn.ReportEventError("Target.targetDestroyed", params, err)
+		} else if evt.TargetId == targetId {
+			pageConn.TargetClosed("target destroyed")
+		}
+	})
+	if err := protocol.SetDiscoverTargets(
+		&protocol.SetDiscoverTargetsParams{Discover: true}, browserConn); err != nil {
+		stop()
+		return nil, err
+	}
+	return stop, nil
+}
@@ -0,0 +1,138 @@
+package hcutil
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Returned when a param needs a newer protocol than the browser speaks, instead of dropping it.
+var ErrUnsupported = errors.New("unsupported by the browser")
+
+// How the browser is told a navigation started, e.g. for the history and the Sec-Fetch headers.
+// Protocol v1.2 lacks it, see Navigate.
+type TransitionType string
+
+const (
+	TransitionLink           TransitionType = "link"
+	TransitionTyped          TransitionType = "typed"
+	TransitionAutoBookmark   TransitionType = "auto_bookmark"
+	TransitionAutoSubframe   TransitionType = "auto_subframe"
+	TransitionManualSubframe TransitionType = "manual_subframe"
+	TransitionGenerated      TransitionType = "generated"
+	TransitionAutoToplevel   TransitionType = "auto_toplevel"
+	TransitionFormSubmit     TransitionType = "form_submit"
+	TransitionReload         TransitionType = "reload"
+	TransitionKeyword        TransitionType = "keyword"
+	TransitionOther          TransitionType = "other"
+)
+
+// Page.navigate params of newer protocols. protocol.NavigateParams, generated from v1.2, has only
+// the URL.
+type NavigateRequest struct {
+	URL string `json:"url"`
+	// Sent as the Referer header, and exposed as document.referrer.
+	Referrer       string         `json:"referrer,omitempty"`
+	TransitionType TransitionType `json:"transitionType,omitempty"`
+}
+
+// Navigates the page like protocol.Navigate, with the params of req the browser supports. v1.2
+// browsers ignore params they don't know, so the protocol version of the browser is checked
+// first, and ErrUnsupported returned if it lacks the params. Doesn't wait for anything, see
+// NavigateAndWaitForReadiness.
+func Navigate(conn *hc.Conn, req *NavigateRequest) (protocol.FrameId, error) {
+	if req.Referrer == "" && req.TransitionType == "" {
+		result, err := protocol.Navigate(&protocol.NavigateParams{Url: req.URL}, conn)
+		if err != nil {
+			return "", connErr(conn, err)
+		}
+		return result.FrameId, nil
+	}
+	if ok, err := supportsNavigateParams(conn); err != nil {
+		return "", err
+	} else if !ok {
+		return "", ErrUnsupported
+	}
+	var result protocol.NavigateResult
+	err := sendWithTimeout(conn, navigateTimeout, func(cb func(error)) hc.Command {
+		return &rawCommand{name: "Page.navigate", params: req, result: &result, cb: cb}
+	})
+	if err != nil {
+		return "", connErr(conn, err)
+	}
+	return result.FrameId, nil
+}
+
+// Like Navigate, with the Referer header and document.referrer of the page set to referrer.
+func NavigateWithReferrer(conn *hc.Conn, url, referrer string) (protocol.FrameId, error) {
+	return Navigate(conn, &NavigateRequest{URL: url, Referrer: referrer})
+}
+
+// Page.navigate answers once the response starts, which a slow server may take long for.
+const navigateTimeout = time.Minute
+
+type navigateParamsKey struct{}
+
+// Version of the Page domain of the browser, by Schema.getDomains, once known.
+type navigateParams struct {
+	mu      sync.Mutex
+	version []int
+}
+
+// Version of the Page domain of protocols whose Page.navigate has all params of NavigateRequest.
+var navigateParamsVersion = []int{1, 3}
+
+// Reports whether Page.navigate of the browser takes the params of NavigateRequest, by the
+// version of its Page domain, which is asked once per connection. Older browsers may know some params as experimental, but
+// can't be told apart from those which don't without relying on the wording of errors, so they
+// are treated as not supporting any.
+func supportsNavigateParams(conn *hc.Conn) (bool, error) {
+	p := conn.Value(navigateParamsKey{}, func() interface{} {
+		return &navigateParams{}
+	}).(*navigateParams)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.version == nil {
+		var result protocol.GetDomainsResult
+		err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+			return &rawCommand{name: "Schema.getDomains", result: &result, cb: cb}
+		})
+		if err == hc.ErrConnClosed || err == ErrTimeout {
+			return false, connErr(conn, err)
+		} else if err != nil {
+			return false, err
+		}
+		p.version = []int{}
+		for _, domain := range result.Domains {
+			if domain.Name == "Page" {
+				p.version = parseVersion(domain.Version)
+			}
+		}
+	}
+	for i, want := range navigateParamsVersion {
+		if i >= len(p.version) || p.version[i] < want {
+			return false, nil
+		} else if p.version[i] > want {
+			break
+		}
+	}
+	return true, nil
+}
+
+// Parses a version like "1.3" into its numbers. Parsing stops at the first part which isn't one.
+func parseVersion(version string) []int {
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
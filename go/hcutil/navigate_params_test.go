@@ -0,0 +1,79 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// Params newer than v1.2 are only sent to browsers whose Page domain is new enough, which is
+// asked once per connection.
+func TestNavigateParams(t *testing.T) {
+	for _, c := range []struct {
+		version   string
+		supported bool
+	}{
+		{version: "1.2"},
+		{version: "1.3", supported: true},
+		{version: "1.10", supported: true},
+		{version: "2.0", supported: true},
+		{version: ""},
+	} {
+		server := hctest.NewFakeServer(t)
+		domains := []map[string]string{{"name": "Runtime", "version": "9.9"}}
+		if c.version != "" {
+			domains = append(domains, map[string]string{"name": "Page", "version": c.version})
+		}
+		server.Handle("Schema.getDomains", hctest.FakeResult(map[string]interface{}{
+			"domains": domains}))
+		server.Handle("Page.navigate", hctest.FakeResult(map[string]string{"frameId": "f1"}))
+		conn, _ := server.NewPageConn()
+
+		for i := 0; i < 2; i++ {
+			frameId, err := hcutil.Navigate(conn, &hcutil.NavigateRequest{
+				URL: "http://example.com/", Referrer: "http://referrer.com/",
+				TransitionType: hcutil.TransitionLink})
+			if !c.supported {
+				if err != hcutil.ErrUnsupported {
+					t.Errorf("%s: got %v", c.version, err)
+				}
+				continue
+			}
+			if err != nil || frameId != "f1" {
+				t.Errorf("%s: got %s, %v", c.version, frameId, err)
+			}
+		}
+		if n := len(server.CommandsOf("Schema.getDomains")); n != 1 {
+			t.Errorf("%s: asked for domains %d times", c.version, n)
+		}
+		navigates := server.CommandsOf("Page.navigate")
+		if !c.supported {
+			if len(navigates) != 0 {
+				t.Errorf("%s: navigated without the params", c.version)
+			}
+			continue
+		}
+		var params map[string]string
+		json.Unmarshal(navigates[0].Params, &params)
+		if len(navigates) != 2 || params["referrer"] != "http://referrer.com/" ||
+			params["transitionType"] != "link" {
+			t.Errorf("%s: sent %d, first %v", c.version, len(navigates), params)
+		}
+	}
+}
+
+// Without new params, nothing is asked.
+func TestNavigatePlain(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Page.navigate", hctest.FakeResult(map[string]string{"frameId": "f1"}))
+	conn, _ := server.NewPageConn()
+	if frameId, err := hcutil.Navigate(conn, &hcutil.NavigateRequest{
+		URL: "http://example.com/"}); err != nil || frameId != "f1" {
+		t.Fatalf("Got %s, %v", frameId, err)
+	}
+	if n := len(server.CommandsOf("Schema.getDomains")); n != 0 {
+		t.Errorf("Asked for domains %d times", n)
+	}
+}
@@ -0,0 +1,119 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// Answers Page.navigate right after DOMContentLoaded, and fires the load event loadDelay later.
+// If busy, a request is left in flight, so the network never gets idle.
+func handleLoad(server *hctest.FakeServer, loadDelay time.Duration, busy bool) {
+	server.Handle("Page.navigate", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		if busy {
+			cmd.Conn.Emit("Network.requestWillBeSent", map[string]interface{}{
+				"requestId": "r1", "frameId": "f1", "loaderId": "l1",
+				"documentURL": "http://a.test/", "timestamp": 1, "type": "Image",
+				"request": map[string]interface{}{"url": "http://a.test/busy", "method": "GET"},
+			})
+		}
+		cmd.Conn.Emit("Page.domContentEventFired", map[string]float64{"timestamp": 2})
+		time.AfterFunc(loadDelay, func() {
+			cmd.Conn.Emit("Page.loadEventFired", map[string]float64{"timestamp": 3})
+		})
+		return map[string]string{"frameId": "f1"}, nil
+	})
+}
+
+// Each level is done as soon as it's reached, without waiting for later ones.
+func TestNavigateReadinessLevels(t *testing.T) {
+	const loadDelay = 300 * time.Millisecond
+	for _, level := range []hcutil.ReadinessLevel{hcutil.ReadyDOMContentLoaded, hcutil.ReadyLoad,
+		hcutil.ReadyNetworkIdle} {
+		server := hctest.NewFakeServer(t)
+		handleLoad(server, loadDelay, false)
+		conn, _ := server.NewPageConn()
+		result, err := hcutil.NavigateAndWaitForReadiness(conn, "http://a.test/",
+			&hcutil.NavigateOptions{Timeout: 10 * time.Second, Readiness: level})
+		if err != nil {
+			t.Fatalf("%v: %v", level, err)
+		}
+		_, loaded := result.Times[hcutil.ReadyLoad]
+		if result.Reached != level || result.FellBack ||
+			loaded != (level != hcutil.ReadyDOMContentLoaded) {
+			t.Errorf("%v: got %+v", level, result)
+		}
+		if level == hcutil.ReadyDOMContentLoaded && result.Times[level] >= loadDelay {
+			t.Errorf("DOMContentLoaded took %v", result.Times[level])
+		}
+	}
+}
+
+// A network which never gets idle falls back to the load event once the timeout passes, unless
+// strict.
+func TestNavigateNetworkIdleFallback(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		server := hctest.NewFakeServer(t)
+		handleLoad(server, 0, true)
+		conn, _ := server.NewPageConn()
+		result, err := hcutil.NavigateAndWaitForReadiness(conn, "http://a.test/",
+			&hcutil.NavigateOptions{Timeout: time.Second, Readiness: hcutil.ReadyNetworkIdle,
+				Strict: strict})
+		if _, idle := result.Times[hcutil.ReadyNetworkIdle]; idle {
+			t.Errorf("Strict %t: network idle at %v", strict, result.Times)
+		}
+		if strict {
+			if err != hcutil.ErrTimeout {
+				t.Errorf("Got %v", err)
+			}
+		} else if err != nil || !result.FellBack || result.Reached != hcutil.ReadyLoad {
+			t.Errorf("Got %+v, %v", result, err)
+		}
+	}
+}
+
+// After a navigation times out and is aborted, late events of its load don't finish the next
+// navigation early.
+func TestNavigateAbortOnTimeout(t *testing.T) {
+	const loadDelay = 300 * time.Millisecond
+	server := hctest.NewFakeServer(t)
+	server.Handle("Page.navigate", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		var params struct {
+			Url string `json:"url"`
+		}
+		if err := json.Unmarshal(cmd.Params, &params); err != nil {
+			return nil, err
+		}
+		// The first page never loads.
+		if params.Url == "http://b.test/" {
+			time.AfterFunc(loadDelay, func() {
+				cmd.Conn.Emit("Page.loadEventFired", map[string]float64{"timestamp": 3})
+			})
+		}
+		return map[string]string{"frameId": "f1"}, nil
+	})
+	server.Handle("Page.stopLoading", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		cmd.Conn.Emit("Page.loadEventFired", map[string]float64{"timestamp": 2})
+		return struct{}{}, nil
+	})
+	conn, _ := server.NewPageConn()
+	opts := &hcutil.NavigateOptions{Timeout: 200 * time.Millisecond, AbortOnTimeout: true}
+	if _, err := hcutil.NavigateAndWaitForReadiness(conn, "http://a.test/",
+		opts); err != hcutil.ErrTimeout {
+		t.Fatalf("Got %v", err)
+	}
+	if len(server.CommandsOf("Page.stopLoading")) != 1 {
+		t.Fatal("Navigation not aborted")
+	}
+	opts.Timeout = 10 * time.Second
+	result, err := hcutil.NavigateAndWaitForReadiness(conn, "http://b.test/", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Times[hcutil.ReadyLoad] < loadDelay {
+		t.Errorf("Loaded after %v, before the page did", result.Times[hcutil.ReadyLoad])
+	}
+}
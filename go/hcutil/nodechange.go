@@ -0,0 +1,405 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrNodeRemoved = errors.New("watched node was removed")
+
+type NodeChangeKind string
+
+const (
+	NodeAttributeModified     NodeChangeKind = "attributeModified"
+	NodeAttributeRemoved      NodeChangeKind = "attributeRemoved"
+	NodeCharacterDataModified NodeChangeKind = "characterDataModified"
+	NodeChildInserted         NodeChangeKind = "childInserted"
+	NodeChildRemoved          NodeChangeKind = "childRemoved"
+	// The watched node was removed, and another one matching the selector took its place.
+	NodeReplaced NodeChangeKind = "replaced"
+)
+
+// A mutation of the watched node or its subtree.
+type NodeChange struct {
+	Kind NodeChangeKind `json:"kind"`
+	// The changed node, i.e. the inserted or removed child, or the new node for NodeReplaced.
+	// Only set when watching with protocol events.
+	NodeId protocol.NodeId `json:"-"`
+	// Node name of the changed node, e.g. "SPAN" or "#text".
+	NodeName string `json:"nodeName"`
+	// The attribute name, for attribute changes.
+	Name string `json:"name"`
+	// The new attribute value, the new character data, or the value of an inserted text node.
+	Value string `json:"value"`
+}
+
+// Waits till predicate returns true for a change of the node or its subtree, driven by
+// DOM.attributeModified, characterDataModified, childNodeInserted and childNodeRemoved events
+// rather than polling. The subtree is pushed with DOM.requestChildNodes first, as events only
+// fire for nodes known to the client. Returns ErrNodeRemoved if the node is removed, and
+// ErrTimeout if nothing matches within timeout.
+func WaitForNodeChange(conn *hc.Conn, nodeId protocol.NodeId,
+	predicate func(change NodeChange) bool, timeout time.Duration) error {
+	return waitForNodeChangeWithDOM(conn, nodeId, "", predicate, timeout)
+}
+
+// Like WaitForNodeChange, but watches the first element matching selector, and follows the
+// element when it's replaced, e.g. by innerHTML of its parent, reporting NodeReplaced. A
+// MutationObserver in the page is used, falling back to protocol events if JavaScript is
+// disabled by DisableJavaScript.
+func WaitForSelectorChange(conn *hc.Conn, selector string,
+	predicate func(change NodeChange) bool, timeout time.Duration) error {
+	if JavaScriptDisabled(conn) {
+		nodeId, err := querySelectorFromDocument(conn, selector)
+		if err != nil {
+			return err
+		}
+		return waitForNodeChangeWithDOM(conn, nodeId, selector, predicate, timeout)
+	}
+	return waitForNodeChangeWithObserver(conn, selector, predicate, timeout)
+}
+
+func querySelectorFromDocument(conn *hc.Conn, selector string) (protocol.NodeId, error) {
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn)
+	if err != nil {
+		return 0, err
+	}
+	node, err := protocol.QuerySelector(&protocol.QuerySelectorParams{
+		NodeId: doc.Root.NodeId, Selector: selector}, conn)
+	if err != nil {
+		return 0, err
+	} else if node.NodeId == 0 {
+		return 0, fmt.Errorf("No element matches %s", selector)
+	}
+	return node.NodeId, nil
+}
+
+// Keeps track of the node ids in the watched subtree, and queues its changes. Events may come
+// concurrently and out of order, so a change of a freshly inserted node can be missed.
+type nodeWatcher struct {
+	mu      sync.Mutex
+	rootId  protocol.NodeId
+	subtree map[protocol.NodeId]bool
+	changes []NodeChange
+	removed bool
+	notify  chan struct{}
+}
+
+func (w *nodeWatcher) reset(rootId protocol.NodeId) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rootId = rootId
+	w.subtree = map[protocol.NodeId]bool{rootId: true}
+	w.removed = false
+}
+
+// Called with mu held.
+func (w *nodeWatcher) addLocked(nodes []*protocol.Node) {
+	for _, node := range nodes {
+		w.subtree[node.NodeId] = true
+		w.addLocked(node.Children)
+		w.addLocked(node.ShadowRoots)
+	}
+}
+
+func (w *nodeWatcher) push(nodeId protocol.NodeId, change NodeChange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.subtree[nodeId] {
+		return
+	}
+	w.changes = append(w.changes, change)
+	w.signalLocked()
+}
+
+func (w *nodeWatcher) signalLocked() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *nodeWatcher) take() (changes []NodeChange, removed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changes, w.changes = w.changes, nil
+	return changes, w.removed
+}
+
+func (w *nodeWatcher) listen(conn *hc.Conn) (cancel func()) {
+	cancels := []func(){
+		listen(conn, "DOM.setChildNodes", func(params []byte) {
+			var evt protocol.SetChildNodesEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("DOM.setChildNodes", params, err)
+				return
+			}
+			w.mu.Lock()
+			if w.subtree[evt.ParentId] {
+				w.addLocked(evt.Nodes)
+			}
+			w.mu.Unlock()
+		}),
+		listen(conn, "DOM.attributeModified", func(params []byte) {
+			var evt protocol.AttributeModifiedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("DOM.attributeModified", params, err)
+				return
+			}
+			w.push(evt.NodeId, NodeChange{Kind: NodeAttributeModified, NodeId: evt.NodeId,
+				Name: evt.Name, Value: evt.Value})
+		}),
+		listen(conn, "DOM.attributeRemoved", func(params []byte) {
+			var evt protocol.AttributeRemovedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("DOM.attributeRemoved", params, err)
+				return
+			}
+			w.push(evt.NodeId, NodeChange{Kind: NodeAttributeRemoved, NodeId: evt.NodeId,
+				Name: evt.Name})
+		}),
+		listen(conn, "DOM.characterDataModified", func(params []byte) {
+			var evt protocol.CharacterDataModifiedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("DOM.characterDataModified", params, err)
+				return
+			}
+			w.push(evt.NodeId, NodeChange{Kind: NodeCharacterDataModified, NodeId: evt.NodeId,
+				NodeName: "#text", Value: evt.CharacterData})
+		}),
+		listen(conn, "DOM.childNodeInserted", func(params []byte) {
+			var evt protocol.ChildNodeInsertedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("DOM.childNodeInserted", params, err)
+				return
+			} else if evt.Node == nil {
+				return
+			}
+			w.push(evt.ParentNodeId, NodeChange{Kind: NodeChildInserted, NodeId: evt.Node.NodeId,
+				NodeName: evt.Node.NodeName, Value: evt.Node.NodeValue})
+			w.mu.Lock()
+			if w.subtree[evt.ParentNodeId] {
+				w.addLocked([]*protocol.Node{evt.Node})
+			}
+			w.mu.Unlock()
+		}),
+		listen(conn, "DOM.childNodeRemoved", func(params []byte) {
+			var evt protocol.ChildNodeRemovedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("DOM.childNodeRemoved", params, err)
+				return
+			}
+			w.mu.Lock()
+			if evt.NodeId == w.rootId {
+				w.removed = true
+				w.signalLocked()
+			}
+			w.mu.Unlock()
+			w.push(evt.ParentNodeId, NodeChange{Kind: NodeChildRemoved, NodeId: evt.NodeId})
+		}),
+		// All node ids are invalidated, so the node is as good as removed.
+		listen(conn, "DOM.documentUpdated", func([]byte) {
+			w.mu.Lock()
+			w.removed = true
+			w.signalLocked()
+			w.mu.Unlock()
+		}),
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+type domHoldKey struct{}
+
+type domHold struct {
+	mu       sync.Mutex
+	acquired bool
+}
+
+// Acquires DOM domain once for the life of conn, see hc.Conn.AcquireDomain. It's never released,
+// as disabling it would invalidate the node ids callers hold.
+func holdDOM(conn *hc.Conn) error {
+	h := conn.Value(domHoldKey{}, func() interface{} { return &domHold{} }).(*domHold)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.acquired {
+		return nil
+	}
+	if err := conn.AcquireDomain("DOM", nil); err != nil {
+		return err
+	}
+	h.acquired = true
+	return nil
+}
+
+// Only removal of the node itself is noticed, not that of one of its ancestors.
+func waitForNodeChangeWithDOM(conn *hc.Conn, nodeId protocol.NodeId, selector string,
+	predicate func(change NodeChange) bool, timeout time.Duration) error {
+	if err := holdDOM(conn); err != nil {
+		return err
+	}
+	w := &nodeWatcher{notify: make(chan struct{}, 1)}
+	w.reset(nodeId)
+	cancel := w.listen(conn)
+	defer cancel()
+	if err := protocol.RequestChildNodes(
+		&protocol.RequestChildNodesParams{NodeId: nodeId, Depth: -1}, conn); err != nil {
+		return err
+	}
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-w.notify:
+		case <-deadline:
+			return ErrTimeout
+		case <-conn.Closed():
+			return conn.Err()
+		}
+		changes, removed := w.take()
+		for _, change := range changes {
+			if predicate(change) {
+				return nil
+			}
+		}
+		if !removed {
+			continue
+		}
+		if selector == "" {
+			return ErrNodeRemoved
+		}
+		// Replacements usually happen in one go, e.g. by innerHTML, so the new node is there.
+		newId, err := querySelectorFromDocument(conn, selector)
+		if err != nil {
+			logging.Vlog(2, err)
+			return ErrNodeRemoved
+		}
+		w.reset(newId)
+		if err := protocol.RequestChildNodes(
+			&protocol.RequestChildNodesParams{NodeId: newId, Depth: -1}, conn); err != nil {
+			return err
+		}
+		if predicate(NodeChange{Kind: NodeReplaced, NodeId: newId}) {
+			return nil
+		}
+	}
+}
+
+// Installs a watch on window[id], whose next(ms) resolves with the changes queued so far, or
+// waits up to ms for some. A second observer on the document notices the element being
+// detached, and re-resolves the selector.
+const watchNodeFunction = `(function(selector, id) {
+	var watch = {queue: [], waiter: null, el: null};
+	function push(change) {
+		watch.queue.push(change);
+		if (watch.waiter) watch.waiter();
+	}
+	function changed(kind, node, name, value) {
+		push({kind: kind, nodeName: node.nodeName, name: name || "", value: value || ""});
+	}
+	var observer = new MutationObserver(function(records) {
+		records.forEach(function(r) {
+			if (r.type === "attributes") {
+				var value = r.target.getAttribute(r.attributeName);
+				changed(value === null ? "attributeRemoved" : "attributeModified", r.target,
+					r.attributeName, value);
+			} else if (r.type === "characterData") {
+				changed("characterDataModified", r.target, "", r.target.data);
+			} else {
+				Array.prototype.forEach.call(r.addedNodes, function(n) {
+					changed("childInserted", n, "", n.nodeType === Node.TEXT_NODE ? n.data : "");
+				});
+				Array.prototype.forEach.call(r.removedNodes, function(n) {
+					changed("childRemoved", n);
+				});
+			}
+		});
+	});
+	function observe(el) {
+		watch.el = el;
+		observer.observe(el, {attributes: true, characterData: true, childList: true, subtree: true});
+	}
+	var docObserver = new MutationObserver(function() {
+		if (watch.el.isConnected) return;
+		var el = document.querySelector(selector);
+		if (!el) return;
+		observer.disconnect();
+		observe(el);
+		changed("replaced", el);
+	});
+	watch.next = function(ms) {
+		return new Promise(function(resolve) {
+			function flush() {
+				var queue = watch.queue;
+				watch.queue = [];
+				watch.waiter = null;
+				resolve(queue);
+			}
+			if (watch.queue.length) return flush();
+			var timer = setTimeout(flush, ms);
+			watch.waiter = function() {
+				clearTimeout(timer);
+				flush();
+			};
+		});
+	};
+	watch.stop = function() {
+		observer.disconnect();
+		docObserver.disconnect();
+		delete window[id];
+	};
+	var el = document.querySelector(selector);
+	if (!el) throw new Error("No element matches " + selector);
+	observe(el);
+	docObserver.observe(document, {childList: true, subtree: true});
+	window[id] = watch;
+})`
+
+var lastWatchId int32
+
+func waitForNodeChangeWithObserver(conn *hc.Conn, selector string,
+	predicate func(change NodeChange) bool, timeout time.Duration) error {
+	id := jsbuilder.JSString(fmt.Sprintf("__hc_watch_%d", atomic.AddInt32(&lastWatchId, 1)))
+	if err := Evaluate(conn, fmt.Sprintf("%s(%s, %s)", watchNodeFunction,
+		jsbuilder.JSString(selector), id), nil); err != nil {
+		return err
+	}
+	defer func() {
+		if err := Evaluate(conn, fmt.Sprintf("window[%s] && window[%s].stop()", id, id),
+			nil); err != nil {
+			logging.Vlog(2, err)
+		}
+	}()
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return ErrTimeout
+		}
+		var changes []NodeChange
+		if err := EvaluateWithParams(conn, &protocol.EvaluateParams{
+			Expression:   fmt.Sprintf("window[%s].next(%d)", id, remaining/time.Millisecond),
+			AwaitPromise: true,
+		}, &changes); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if predicate(change) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package hcutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type ScreenOrientationType string
+
+const (
+	PortraitPrimary    ScreenOrientationType = "portraitPrimary"
+	PortraitSecondary  ScreenOrientationType = "portraitSecondary"
+	LandscapePrimary   ScreenOrientationType = "landscapePrimary"
+	LandscapeSecondary ScreenOrientationType = "landscapeSecondary"
+)
+
+func (t ScreenOrientationType) portrait() bool {
+	return t == PortraitPrimary || t == PortraitSecondary
+}
+
+// Overrides device metrics like protocol.EmulationSetDeviceMetricsOverride, but remembers them,
+// so that SetOrientation, Rotate and screenshots with a device scale factor change only what
+// they need to and keep the rest. Returns once the page has laid out for them, see
+// SetViewportSize.
+func SetDeviceMetrics(conn *hc.Conn,
+	params *protocol.EmulationSetDeviceMetricsOverrideParams) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	err := e.setMetrics(conn, params)
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return settleResize(conn)
+}
+
+// Resizes the view to width x height, keeping the other device metrics. The page gets a resize
+// event, as not all browsers fire one for emulated sizes, and this returns after the next frame
+// is rendered, so that e.g. charts redrawn by resize listeners show in screenshots taken
+// afterwards.
+func SetViewportSize(conn *hc.Conn, width, height int) error {
+	if err := resizeView(conn, width, height, 0); err != nil {
+		return err
+	}
+	if err := setVisibleSize(conn, width, height); err != nil {
+		return err
+	}
+	return settleResize(conn)
+}
+
+func setVisibleSize(conn *hc.Conn, width, height int) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setVisibleSize(conn, &viewSize{Width: width, Height: height})
+}
+
+func ClearDeviceMetrics(conn *hc.Conn) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setMetrics(conn, nil)
+}
+
+// Changes the current device metrics override with update. Without one, it starts from the
+// size of the view.
+func updateDeviceMetrics(conn *hc.Conn,
+	update func(params *protocol.EmulationSetDeviceMetricsOverrideParams)) error {
+	e := getEmulation(conn)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var params protocol.EmulationSetDeviceMetricsOverrideParams
+	if e.state.metrics != nil {
+		params = *e.state.metrics
+		if params.ScreenOrientation != nil {
+			orientation := *params.ScreenOrientation
+			params.ScreenOrientation = &orientation
+		}
+	} else {
+		var size viewSize
+		if err := Evaluate(conn, "({width: window.innerWidth, height: window.innerHeight})",
+			&size); err != nil {
+			return err
+		}
+		if e.initialSize == nil && e.state.visibleSize == nil {
+			e.initialSize = &size
+		}
+		params.Width = size.Width
+		params.Height = size.Height
+	}
+	update(&params)
+	return e.setMetrics(conn, &params)
+}
+
+// Emulates the screen in orientation, with angle in degrees, keeping the other device metrics.
+// The page gets orientationchange and resize events, dispatched by this function if the browser
+// doesn't. The size of the view isn't changed, see Rotate for that.
+func SetOrientation(conn *hc.Conn, orientation ScreenOrientationType, angle int) error {
+	return changeOrientation(conn, func(params *protocol.EmulationSetDeviceMetricsOverrideParams) {
+		params.ScreenOrientation = &protocol.ScreenOrientation{
+			Type: string(orientation), Angle: angle}
+	})
+}
+
+// Turns the device by 90 degrees: swaps the width and height of the view and screen, and goes
+// from portrait to landscape or the other way around. A device without orientation emulated is
+// assumed to be in its natural orientation, i.e. at angle 0, portrait unless it's wider than
+// high. The angle goes back to 0 when rotated twice, so devices which are naturally landscape,
+// e.g. tablets, should have landscape orientation at angle 0 set with SetOrientation first.
+func Rotate(conn *hc.Conn) error {
+	return changeOrientation(conn, func(params *protocol.EmulationSetDeviceMetricsOverrideParams) {
+		current := &protocol.ScreenOrientation{Type: string(PortraitPrimary)}
+		if params.ScreenOrientation != nil {
+			current = params.ScreenOrientation
+		} else if params.Width > params.Height {
+			current.Type = string(LandscapePrimary)
+		}
+		rotated := &protocol.ScreenOrientation{Type: string(PortraitPrimary)}
+		if ScreenOrientationType(current.Type).portrait() {
+			rotated.Type = string(LandscapePrimary)
+		}
+		if current.Angle%180 == 0 {
+			rotated.Angle = 90
+		}
+		params.ScreenOrientation = rotated
+		params.Width, params.Height = params.Height, params.Width
+		params.ScreenWidth, params.ScreenHeight = params.ScreenHeight, params.ScreenWidth
+	})
+}
+
+const orientationListenerVar = "__hcOrientationChange"
+
+func changeOrientation(conn *hc.Conn,
+	update func(params *protocol.EmulationSetDeviceMetricsOverrideParams)) error {
+	if JavaScriptDisabled(conn) {
+		// The page can't observe anything.
+		return updateDeviceMetrics(conn, update)
+	}
+	if err := Evaluate(conn, `(function() {
+	var state = {orientationchange: false, resize: false,
+		width: window.innerWidth, height: window.innerHeight};
+	window.`+orientationListenerVar+` = state;
+	["orientationchange", "resize"].forEach(function(type) {
+		window.addEventListener(type, function listener() {
+			state[type] = true;
+			window.removeEventListener(type, listener);
+		});
+	});
+})()`, nil); err != nil {
+		return err
+	}
+	if err := updateDeviceMetrics(conn, update); err != nil {
+		return err
+	}
+	// Older browsers don't fire orientationchange for emulated orientations. Give the browser
+	// some time to fire the events, then fire the missing ones.
+	return EvaluateWithParams(conn, &protocol.EvaluateParams{
+		Expression: `new Promise(function(resolve) {
+	setTimeout(function() {
+		var state = window.` + orientationListenerVar + `;
+		delete window.` + orientationListenerVar + `;
+		if (!state) return resolve();
+		if (!state.orientationchange) window.dispatchEvent(new Event("orientationchange"));
+		if (!state.resize && (state.width !== window.innerWidth ||
+				state.height !== window.innerHeight)) {
+			window.dispatchEvent(new Event("resize"));
+		}
+		resolve();
+	}, 100);
+})`,
+		AwaitPromise: true,
+	}, nil)
+}
+
+// How long settleResize waits for requestAnimationFrame, which doesn't fire e.g. when frames
+// are only rendered on HeadlessExperimental.beginFrame.
+const frameTimeout = 200 * time.Millisecond
+
+// Fires resize on the page, and waits till a frame is rendered.
+func settleResize(conn *hc.Conn) error {
+	if !JavaScriptDisabled(conn) {
+		var rendered bool
+		if err := EvaluateWithParams(conn, &protocol.EvaluateParams{
+			Expression: fmt.Sprintf(`new Promise(function(resolve) {
+	window.dispatchEvent(new Event("resize"));
+	requestAnimationFrame(function() { resolve(true); });
+	setTimeout(function() { resolve(false); }, %d);
+})`, frameTimeout/time.Millisecond),
+			AwaitPromise: true,
+		}, &rendered); err != nil {
+			return err
+		}
+		if rendered {
+			return nil
+		}
+	}
+	// Only works if the browser is started with --enable-begin-frame-control.
+	if _, err := protocol.BeginFrame(&protocol.BeginFrameParams{}, conn); err != nil {
+		logging.Vlogf(2, "Failed to begin a frame: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package hcutil
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+var ErrNoPrintToPDF = errors.New("the browser doesn't support Page.printToPDF")
+
+type PDFOptions struct {
+	Landscape       bool
+	PrintBackground bool
+	// Scale of the rendering of the page. 0 means 1.
+	Scale float64
+	// Paper size in inches. 0 means letter, 8.5 x 11.
+	PaperWidth, PaperHeight float64
+}
+
+type printToPDFParams struct {
+	Landscape       bool    `json:"landscape,omitempty"`
+	PrintBackground bool    `json:"printBackground,omitempty"`
+	Scale           float64 `json:"scale,omitempty"`
+	PaperWidth      float64 `json:"paperWidth,omitempty"`
+	PaperHeight     float64 `json:"paperHeight,omitempty"`
+}
+
+// Printing large pages takes a while.
+const printTimeout = time.Minute
+
+// Prints the page into a PDF document with Page.printToPDF, which protocol v1.2 doesn't have,
+// but newer browsers do. Returns ErrNoPrintToPDF with older ones. opts may be nil.
+func PrintToPDF(conn *hc.Conn, opts *PDFOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &PDFOptions{}
+	}
+	var result struct {
+		Data string `json:"data"`
+	}
+	err := sendWithTimeout(conn, printTimeout, func(cb func(error)) hc.Command {
+		return &rawCommand{
+			name: "Page.printToPDF",
+			params: &printToPDFParams{
+				Landscape:       opts.Landscape,
+				PrintBackground: opts.PrintBackground,
+				Scale:           opts.Scale,
+				PaperWidth:      opts.PaperWidth,
+				PaperHeight:     opts.PaperHeight,
+			},
+			result: &result,
+			cb:     cb,
+		}
+	})
+	if err != nil {
+		if hc.IsMethodNotFound(err) {
+			return nil, ErrNoPrintToPDF
+		}
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Data)
+}
@@ -0,0 +1,139 @@
+package hcutil
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Returns the elements under nodeId matching selector, in document order. The query stops once
+// maxResults elements are found, or budget is used up, in which case what's found so far is
+// returned with truncated set. 0 means no limit. The query runs in the page, where elements are
+// walked and matched one by one. Should a single match take longer than the budget, it's
+// terminated with Runtime.terminateExecution where supported, and nothing is returned. Falls back
+// to DOM.querySelectorAll if JavaScript is disabled by DisableJavaScript, which can't be stopped
+// early, so it fails with ErrTimeout if it takes longer than budget.
+func QuerySelectorAllWithBudget(conn *hc.Conn, nodeId protocol.NodeId, selector string,
+	budget time.Duration, maxResults int) (nodeIds []protocol.NodeId, truncated bool, err error) {
+	if budget <= 0 {
+		budget = time.Hour
+	}
+	if JavaScriptDisabled(conn) {
+		return querySelectorAllWithDOM(conn, nodeId, selector, budget, maxResults)
+	}
+	// Results of calls and properties of objects are in the group of the objects.
+	group, end := conn.BeginHelperObjects()
+	created := 0
+	defer func() { end(created) }()
+	node, err := protocol.ResolveNode(
+		&protocol.ResolveNodeParams{NodeId: nodeId, ObjectGroup: group}, conn)
+	if err != nil {
+		return nil, false, err
+	}
+	created++
+	args := []*protocol.CallArgument{
+		{Value: json.RawMessage(jsbuilder.JSString(selector))},
+		{Value: json.RawMessage(strconv.Itoa(maxResults))},
+		{Value: json.RawMessage(strconv.FormatInt(int64(budget/time.Millisecond), 10))},
+	}
+	if err := conn.ThrottleEvaluation(); err != nil {
+		return nil, false, err
+	}
+	call := protocol.NewCallFunctionOnCommand(&protocol.CallFunctionOnParams{
+		ObjectId: node.Object.ObjectId,
+		FunctionDeclaration: `function(selector, maxResults, budget) {
+	var deadline = performance.now() + budget;
+	var walker = document.createTreeWalker(this, NodeFilter.SHOW_ELEMENT);
+	var found = [];
+	var truncated = false;
+	for (var n = 0, el = walker.nextNode(); el; el = walker.nextNode(), n++) {
+		if (el.matches(selector)) {
+			if (maxResults > 0 && found.length >= maxResults) {
+				truncated = true;
+				break;
+			}
+			found.push(el);
+		}
+		if (n % 256 === 255 && performance.now() > deadline) {
+			truncated = true;
+			break;
+		}
+	}
+	found.truncated = truncated;
+	return found;
+}`,
+		Arguments: args,
+	}).Send(conn)
+	// Leave the page some time to notice the budget is used up by itself.
+	ctx, cancel := context.WithTimeout(context.Background(), budget+budget/2+time.Second)
+	defer cancel()
+	result, err := call.Wait(ctx)
+	if err == context.DeadlineExceeded {
+		if err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+			return &rawCommand{name: "Runtime.terminateExecution", cb: cb}
+		}); err != nil {
+			return nil, false, err
+		}
+		// The call fails once terminated.
+		ctx, cancel := context.WithTimeout(context.Background(), capabilityTimeout)
+		defer cancel()
+		call.Wait(ctx)
+		return nil, true, nil
+	} else if err != nil {
+		return nil, false, err
+	} else if result.ExceptionDetails != nil {
+		return nil, false, exceptionError(result.ExceptionDetails)
+	}
+	created++
+
+	props, err := protocol.GetProperties(&protocol.GetPropertiesParams{
+		ObjectId: result.Result.ObjectId, OwnProperties: true}, conn)
+	if err != nil {
+		return nil, false, err
+	}
+	elements := make(map[int]protocol.RemoteObjectId)
+	for _, prop := range props.Result {
+		if prop.Value == nil {
+			continue
+		}
+		if prop.Value.ObjectId != "" {
+			created++
+		}
+		if prop.Name == "truncated" {
+			truncated = string(prop.Value.Value) == "true"
+		} else if i, err := strconv.Atoi(prop.Name); err == nil {
+			elements[i] = prop.Value.ObjectId
+		}
+	}
+	for i := 0; i < len(elements); i++ {
+		node, err := protocol.RequestNode(
+			&protocol.RequestNodeParams{ObjectId: elements[i]}, conn)
+		if err != nil {
+			return nil, false, err
+		}
+		nodeIds = append(nodeIds, node.NodeId)
+	}
+	return nodeIds, truncated, nil
+}
+
+func querySelectorAllWithDOM(conn *hc.Conn, nodeId protocol.NodeId, selector string,
+	budget time.Duration, maxResults int) ([]protocol.NodeId, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+	result, err := protocol.NewQuerySelectorAllCommand(&protocol.QuerySelectorAllParams{
+		NodeId: nodeId, Selector: selector}).Send(conn).Wait(ctx)
+	if err == context.DeadlineExceeded {
+		return nil, true, ErrTimeout
+	} else if err != nil {
+		return nil, false, err
+	}
+	if maxResults > 0 && len(result.NodeIds) > maxResults {
+		return result.NodeIds[:maxResults], true, nil
+	}
+	return result.NodeIds, false, nil
+}
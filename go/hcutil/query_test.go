@@ -0,0 +1,110 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Answers a query like the page would, finding elements with node ids 10 and 11.
+func handleQuery(server *hctest.FakeServer) {
+	server.Handle("DOM.resolveNode", hctest.FakeResult(map[string]interface{}{
+		"object": map[string]string{"type": "object", "objectId": "root"}}))
+	server.Handle("Runtime.callFunctionOn", hctest.FakeResult(map[string]interface{}{
+		"result": map[string]string{"type": "object", "subtype": "array", "objectId": "found"}}))
+	server.Handle("Runtime.getProperties", hctest.FakeResult(map[string]interface{}{
+		"result": []map[string]interface{}{
+			{"name": "0", "value": map[string]string{"type": "object", "objectId": "el10"}},
+			{"name": "1", "value": map[string]string{"type": "object", "objectId": "el11"}},
+			{"name": "truncated", "value": map[string]interface{}{"type": "boolean",
+				"value": false}},
+		}}))
+	server.Handle("DOM.requestNode", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		var params protocol.RequestNodeParams
+		json.Unmarshal(cmd.Params, &params)
+		nodeId := 10
+		if params.ObjectId == "el11" {
+			nodeId = 11
+		}
+		return map[string]int{"nodeId": nodeId}, nil
+	})
+}
+
+func releasedGroups(server *hctest.FakeServer) []string {
+	var groups []string
+	for _, cmd := range server.CommandsOf("Runtime.releaseObjectGroup") {
+		var params protocol.ReleaseObjectGroupParams
+		json.Unmarshal(cmd.Params, &params)
+		groups = append(groups, params.ObjectGroup)
+	}
+	return groups
+}
+
+// Queries use the helper object group of the connection, rather than a fixed one, so queries
+// running at the same time don't release each other's objects.
+func TestQueryObjectGroup(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	handleQuery(server)
+	conn, _ := server.NewPageConn()
+	conn.SetHelperObjectGroup("jobs")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nodeIds, truncated, err := hcutil.QuerySelectorAllWithBudget(conn, 1, "a",
+				time.Second, 0)
+			if err != nil {
+				t.Error(err)
+			} else if len(nodeIds) != 2 || nodeIds[0] != 10 || nodeIds[1] != 11 || truncated {
+				t.Errorf("Got %v, truncated %t", nodeIds, truncated)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, cmd := range server.CommandsOf("DOM.resolveNode") {
+		var params protocol.ResolveNodeParams
+		json.Unmarshal(cmd.Params, &params)
+		if params.ObjectGroup != "jobs" {
+			t.Errorf("Resolved in group %q", params.ObjectGroup)
+		}
+	}
+	// Setting the group released the default one.
+	if groups := releasedGroups(server); len(groups) != 1 ||
+		groups[0] != hc.DefaultHelperObjectGroup {
+		t.Errorf("Released %v", groups)
+	}
+	// The node, the array and its 2 elements, by each query.
+	if stats := conn.Stats(); stats.HelperObjectsCreated != 16 {
+		t.Errorf("Counted %d objects", stats.HelperObjectsCreated)
+	}
+	if err := conn.ReleaseAllHelperObjects(); err != nil {
+		t.Fatal(err)
+	}
+	if groups := releasedGroups(server); len(groups) != 2 || groups[1] != "jobs" {
+		t.Errorf("Released %v", groups)
+	}
+}
+
+func TestQueryDefaultObjectGroup(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	handleQuery(server)
+	conn, _ := server.NewPageConn()
+	if _, _, err := hcutil.QuerySelectorAllWithBudget(conn, 1, "a", time.Second,
+		0); err != nil {
+		t.Fatal(err)
+	}
+	var params protocol.ResolveNodeParams
+	json.Unmarshal(server.CommandsOf("DOM.resolveNode")[0].Params, &params)
+	if params.ObjectGroup != hc.DefaultHelperObjectGroup {
+		t.Errorf("Resolved in group %q", params.ObjectGroup)
+	}
+}
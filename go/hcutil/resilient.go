@@ -0,0 +1,226 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// The main document failed to load, e.g. "net::ERR_NAME_NOT_RESOLVED".
+type LoadingFailedError struct {
+	URL       string
+	ErrorText string
+}
+
+func (e *LoadingFailedError) Error() string {
+	return fmt.Sprintf("Failed to load %s: %s", e.URL, e.ErrorText)
+}
+
+// Network errors which another URL variant may not run into.
+var retryableErrorPrefixes = []string{
+	"net::ERR_NAME_NOT_RESOLVED",
+	"net::ERR_NAME_RESOLUTION_FAILED",
+	"net::ERR_CONNECTION_",
+	"net::ERR_ADDRESS_UNREACHABLE",
+	"net::ERR_SSL_",
+	"net::ERR_CERT_",
+	"net::ERR_EMPTY_RESPONSE",
+}
+
+func retryable(err error) bool {
+	if err == ErrTimeout {
+		return true
+	}
+	if failed, ok := err.(*LoadingFailedError); ok {
+		for _, prefix := range retryableErrorPrefixes {
+			if strings.HasPrefix(failed.ErrorText, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type ResilientOptions struct {
+	// Timeout of each attempt. Defaults to 30 seconds.
+	AttemptTimeout time.Duration
+	// Timeout of all attempts. Defaults to 2 minutes.
+	TotalTimeout time.Duration
+	// Also try http for https URLs and vice versa.
+	SwitchScheme bool
+	// Also try adding or removing "www.".
+	SwitchWWW bool
+}
+
+type NavigationAttempt struct {
+	URL      string
+	Duration time.Duration
+	// nil if the main document loaded, even with an HTTP error status.
+	Err error
+}
+
+type NavigationReport struct {
+	Attempts []NavigationAttempt
+	// The URL of the attempt that worked, and the response of its main document.
+	URL      string
+	Response *Response
+}
+
+// Navigates to rawurl like NavigateAndWait. If it fails because of DNS, connection or SSL
+// errors, or times out, other variants of rawurl are tried as configured by opts. HTTP errors
+// like 404 are valid responses, so they aren't retried. The page is reset to about:blank between
+// attempts. Returns the report, and the error of the last attempt if none worked.
+func NavigateResilient(conn *hc.Conn, rawurl string, opts ResilientOptions) (
+	*NavigationReport, error) {
+	if opts.AttemptTimeout <= 0 {
+		opts.AttemptTimeout = 30 * time.Second
+	}
+	if opts.TotalTimeout <= 0 {
+		opts.TotalTimeout = 2 * time.Minute
+	}
+	variants, err := urlVariants(rawurl, opts.SwitchScheme, opts.SwitchWWW)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.AcquireDomain("Page", nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.ReleaseDomain("Page"); err != nil {
+			logging.Vlog(1, err)
+		}
+	}()
+	tree, err := protocol.GetResourceTree(conn)
+	if err != nil {
+		return nil, err
+	}
+	mainFrameId := protocol.FrameId(tree.FrameTree.Frame.Id)
+
+	report := &NavigationReport{}
+	deadline := time.Now().Add(opts.TotalTimeout)
+	for i, variant := range variants {
+		timeout := opts.AttemptTimeout
+		if left := deadline.Sub(time.Now()); left <= 0 {
+			break
+		} else if left < timeout {
+			timeout = left
+		}
+		if i > 0 {
+			if _, err := protocol.Navigate(
+				&protocol.NavigateParams{Url: "about:blank"}, conn); err != nil {
+				return report, err
+			}
+		}
+		start := time.Now()
+		err = navigateOnce(conn, mainFrameId, variant, timeout)
+		report.Attempts = append(report.Attempts,
+			NavigationAttempt{URL: variant, Duration: time.Since(start), Err: err})
+		if err == nil {
+			report.URL = variant
+			report.Response, err = MainDocumentResponse(conn)
+			return report, err
+		} else if !retryable(err) {
+			return report, err
+		}
+	}
+	if err == nil {
+		err = ErrTimeout
+	}
+	return report, err
+}
+
+// Like NavigateAndWait, but fails with LoadingFailedError if the main document fails to load.
+func navigateOnce(conn *hc.Conn, mainFrameId protocol.FrameId, rawurl string,
+	timeout time.Duration) error {
+	var mu sync.Mutex
+	docRequests := make(map[protocol.RequestId]bool)
+	failed := make(chan string, 1)
+	cancelSent := listen(conn, "Network.requestWillBeSent", func(params []byte) {
+		var evt protocol.RequestWillBeSentEvent
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("Network.requestWillBeSent", params, err)
+		} else if evt.Type == protocol.ResourceTypeDocument && evt.FrameId == mainFrameId {
+			mu.Lock()
+			docRequests[evt.RequestId] = true
+			mu.Unlock()
+		}
+	})
+	defer cancelSent()
+	cancelFailed := listen(conn, "Network.loadingFailed", func(params []byte) {
+		var evt protocol.LoadingFailedEvent
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("Network.loadingFailed", params, err)
+			return
+		}
+		if evt.Type != protocol.ResourceTypeDocument || evt.Canceled {
+			return
+		}
+		// Events may come out of order, but loadingFailed follows requestWillBeSent closely
+		// enough for this in practice.
+		mu.Lock()
+		isMain := docRequests[evt.RequestId]
+		mu.Unlock()
+		if isMain {
+			select {
+			case failed <- evt.ErrorText:
+			default:
+			}
+		}
+	})
+	defer cancelFailed()
+
+	// The error page loads if the main document fails.
+	err := NavigateAndWait(conn, rawurl, timeout)
+	select {
+	case errorText := <-failed:
+		return &LoadingFailedError{URL: rawurl, ErrorText: errorText}
+	default:
+		return err
+	}
+}
+
+// Returns rawurl followed by its variants.
+func urlVariants(rawurl string, switchScheme, switchWWW bool) ([]string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return []string{rawurl}, nil
+	}
+	schemes := []string{u.Scheme}
+	if switchScheme {
+		if u.Scheme == "https" {
+			schemes = append(schemes, "http")
+		} else {
+			schemes = append(schemes, "https")
+		}
+	}
+	hosts := []string{u.Host}
+	if switchWWW && net.ParseIP(u.Hostname()) == nil {
+		if strings.HasPrefix(u.Host, "www.") {
+			hosts = append(hosts, strings.TrimPrefix(u.Host, "www."))
+		} else {
+			hosts = append(hosts, "www."+u.Host)
+		}
+	}
+	var variants []string
+	for _, host := range hosts {
+		for _, scheme := range schemes {
+			variant := *u
+			variant.Scheme, variant.Host = scheme, host
+			variants = append(variants, variant.String())
+		}
+	}
+	variants[0] = rawurl
+	return variants, nil
+}
@@ -0,0 +1,45 @@
+package hcutil
+
+import (
+	"errors"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrNoMainDocument = errors.New("no main document response")
+
+// Response of a document, along with the redirects that led to it.
+type Response struct {
+	*protocol.Response
+	// Redirect responses in the order they happened, e.g. the 301 for a 301 -> 200 chain.
+	Redirects []*protocol.Response
+}
+
+// Returns the value of header name, which is matched case-insensitively.
+func (r *Response) Header(name string) (string, bool) {
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Returns the response of the document shown in the frame the last NavigateAndWait call on conn
+// navigated, which is the one it loaded unless the page navigated again.
+func MainDocumentResponse(conn *hc.Conn) (*Response, error) {
+	frameId, _ := conn.Value(navigatedFrameKey{}, nil).(protocol.FrameId)
+	c, _ := conn.Value(correlatorKey{}, nil).(*Correlator)
+	if frameId == "" || c == nil {
+		return nil, ErrNoMainDocument
+	}
+	req := c.MainDocumentRequest(frameId)
+	if req == nil {
+		return nil, ErrNoMainDocument
+	}
+	return &Response{Response: req.Response, Redirects: req.Redirects}, nil
+}
+
+type navigatedFrameKey struct{}
@@ -0,0 +1,151 @@
+package hcutil_test
+
+import (
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+type fakeResponse struct {
+	url     string
+	status  int
+	headers map[string]string
+}
+
+func (r *fakeResponse) json() map[string]interface{} {
+	return map[string]interface{}{"url": r.url, "status": r.status, "headers": r.headers,
+		"mimeType": "text/html"}
+}
+
+// Navigates conn to the first URL of chain, with the fake browser answering it with the
+// redirects then the final response of chain.
+func navigateThrough(t *testing.T, server *hctest.FakeServer, conn *hc.Conn,
+	fake *hctest.FakeConn, chain []*fakeResponse) {
+	navigating := make(chan *hctest.FakeCommand, 1)
+	server.Handle("Page.navigate", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		navigating <- cmd
+		return nil, hctest.ErrNoReply
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- hcutil.NavigateAndWait(conn, chain[0].url, 10*time.Second)
+	}()
+	cmd := <-navigating
+
+	request := func(url string, redirect *fakeResponse, timestamp float64) {
+		params := map[string]interface{}{
+			"requestId": "r1", "frameId": "f1", "loaderId": "l1", "documentURL": url,
+			"request":   map[string]interface{}{"url": url, "method": "GET"},
+			"timestamp": timestamp, "type": "Document",
+		}
+		if redirect != nil {
+			params["redirectResponse"] = redirect.json()
+		}
+		fake.Emit("Network.requestWillBeSent", params)
+	}
+	request(chain[0].url, nil, 1)
+	for i, redirect := range chain[:len(chain)-1] {
+		request(chain[i+1].url, redirect, float64(i+2))
+	}
+	// Sinks may run concurrently, and a response is dropped if its request isn't known yet.
+	hctest.Flush(t, conn)
+	last := chain[len(chain)-1]
+	fake.Emit("Network.responseReceived", map[string]interface{}{
+		"requestId": "r1", "frameId": "f1", "loaderId": "l1", "timestamp": len(chain) + 1,
+		"type": "Document", "response": last.json(),
+	})
+	fake.Emit("Page.frameNavigated", map[string]interface{}{
+		"frame": map[string]interface{}{"id": "f1", "loaderId": "l1", "url": last.url,
+			"securityOrigin": "", "mimeType": "text/html"},
+	})
+	hctest.Flush(t, conn)
+	fake.Reply(cmd.Id, map[string]string{"frameId": "f1"})
+	fake.Emit("Page.loadEventFired", map[string]interface{}{"timestamp": len(chain) + 2})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMainDocumentResponseRedirected(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	if _, err := hcutil.MainDocumentResponse(conn); err != hcutil.ErrNoMainDocument {
+		t.Errorf("Got %v before navigating", err)
+	}
+
+	navigateThrough(t, server, conn, fake, []*fakeResponse{
+		{url: "http://a.test/", status: 301, headers: map[string]string{
+			"Location": "http://b.test/"}},
+		{url: "http://b.test/", status: 200, headers: map[string]string{
+			"Content-Type": "text/html"}},
+	})
+	resp, err := hcutil.MainDocumentResponse(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != 200 || resp.Url != "http://b.test/" {
+		t.Errorf("Got %d from %s", int(resp.Status), resp.Url)
+	}
+	if len(resp.Redirects) != 1 || resp.Redirects[0].Status != 301 {
+		t.Fatalf("Got redirects %+v", resp.Redirects)
+	}
+	if value, ok := resp.Header("content-type"); !ok || value != "text/html" {
+		t.Errorf("Got content-type %q, %v", value, ok)
+	}
+	if _, ok := resp.Header("location"); ok {
+		t.Error("Got the location header of the redirect")
+	}
+}
+
+func TestMainDocumentResponseNotFound(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	navigateThrough(t, server, conn, fake, []*fakeResponse{
+		{url: "http://a.test/missing", status: 404, headers: map[string]string{
+			"X-Reason": "gone"}},
+	})
+	resp, err := hcutil.MainDocumentResponse(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != 404 || len(resp.Redirects) != 0 {
+		t.Errorf("Got %d after %d redirects", int(resp.Status), len(resp.Redirects))
+	}
+	if value, ok := resp.Header("x-REASON"); !ok || value != "gone" {
+		t.Errorf("Got x-reason %q, %v", value, ok)
+	}
+}
+
+// A correlator whose domains couldn't be enabled isn't kept, and the next call starts again.
+func TestCorrelateEnableFailure(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Network.enable", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, &hctest.FakeError{Code: hctest.CodeServerError, Message: "Failed"}
+	})
+	conn, _ := server.NewPageConn()
+	baseline := conn.Stats().EventSinks["Network.requestWillBeSent"]
+	if _, err := hcutil.Correlate(conn); err == nil {
+		t.Fatal("Started with Network.enable failing")
+	}
+	if n := conn.Stats().EventSinks["Network.requestWillBeSent"]; n != baseline {
+		t.Errorf("Got %d sinks after failing, want %d", n, baseline)
+	}
+	if _, err := hcutil.MainDocumentResponse(conn); err != hcutil.ErrNoMainDocument {
+		t.Errorf("Got %v after failing", err)
+	}
+
+	server.Handle("Network.enable", hctest.FakeResult(struct{}{}))
+	c, err := hcutil.Correlate(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := hcutil.Correlate(conn); err != nil || again != c {
+		t.Errorf("Got another correlator %p, %v", again, err)
+	}
+	if n := len(server.CommandsOf("Network.enable")); n != 2 {
+		t.Errorf("Got %d Network.enable, want 2", n)
+	}
+}
@@ -0,0 +1,279 @@
+package hcutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Which responses ExpectResponses waits for.
+type ResponseMatch struct {
+	// A glob like "*/api/items?*", where * matches any characters, including '/', and ? a single
+	// one. With a "re:" prefix, a regular expression instead, e.g. `re:/api/items/\d+`.
+	URL string
+	// Request method, e.g. "POST", matched case-insensitively. Empty matches any.
+	Method string
+	// Response status. 0 matches any.
+	Status int
+}
+
+// A response captured by ResponseWaiter, with its whole body.
+type CapturedResponse struct {
+	RequestId  protocol.RequestId
+	URL        string
+	Method     string
+	Status     int
+	StatusText string
+	Headers    protocol.Headers
+	MimeType   string
+	Body       []byte
+}
+
+// Returns the value of header name, which is matched case-insensitively.
+func (r *CapturedResponse) Header(name string) (string, bool) {
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Waits for responses matching a ResponseMatch. See ExpectResponses.
+type ResponseWaiter struct {
+	conn     *hc.Conn
+	match    ResponseMatch
+	url      *regexp.Regexp
+	n        int
+	cancels  []func()
+	acquired bool
+	stopOnce sync.Once
+
+	mu      sync.Mutex
+	pending map[protocol.RequestId]*pendingResponse
+	// Responses whose body is loaded, in the order they finished loading.
+	finished chan *pendingResponse
+}
+
+type pendingResponse struct {
+	method   string
+	response *protocol.Response
+	// Requests whose response doesn't match are kept till they finish, to ignore their events.
+	rejected bool
+	loaded   bool
+	queued   bool
+	id       protocol.RequestId
+}
+
+// Starts waiting for the first n responses matching match. The Network listeners are registered
+// once it returns, so the action triggering the requests, e.g. a click, can be started then
+// without missing them. Call Wait afterwards, or Stop to give up. Network domain is enabled till
+// then, unless other helpers need it, see hc.Conn.AcquireDomain.
+func ExpectResponses(conn *hc.Conn, match ResponseMatch, n int) (*ResponseWaiter, error) {
+	if n <= 0 {
+		n = 1
+	}
+	re, err := compileURLPattern(match.URL)
+	if err != nil {
+		return nil, err
+	}
+	w := &ResponseWaiter{
+		conn: conn, match: match, url: re, n: n,
+		pending:  make(map[protocol.RequestId]*pendingResponse),
+		finished: make(chan *pendingResponse, n),
+	}
+	w.cancels = []func(){
+		conn.AddStateDumper(StateResponseWaiter+stateId(match.URL, w), w.state),
+		listen(conn, "Network.requestWillBeSent", func(params []byte) {
+			var evt protocol.RequestWillBeSentEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.requestWillBeSent", params, err)
+				return
+			}
+			if evt.Request != nil {
+				w.update(evt.RequestId, func(p *pendingResponse) { p.method = evt.Request.Method })
+			}
+		}),
+		listen(conn, "Network.responseReceived", func(params []byte) {
+			var evt protocol.ResponseReceivedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.responseReceived", params, err)
+				return
+			}
+			if evt.Response == nil {
+				return
+			}
+			w.update(evt.RequestId, func(p *pendingResponse) {
+				if !w.url.MatchString(evt.Response.Url) ||
+					(match.Status != 0 && int(evt.Response.Status) != match.Status) {
+					p.rejected = true
+					return
+				}
+				p.response = evt.Response
+			})
+		}),
+		listen(conn, "Network.loadingFinished", func(params []byte) {
+			var evt protocol.LoadingFinishedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.loadingFinished", params, err)
+				return
+			}
+			w.update(evt.RequestId, func(p *pendingResponse) { p.loaded = true })
+		}),
+		listen(conn, "Network.loadingFailed", func(params []byte) {
+			var evt protocol.LoadingFailedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.loadingFailed", params, err)
+				return
+			}
+			w.mu.Lock()
+			delete(w.pending, evt.RequestId)
+			w.mu.Unlock()
+		}),
+	}
+	if err := conn.AcquireDomain("Network", nil); err != nil {
+		w.Stop()
+		return nil, err
+	}
+	w.acquired = true
+	return w, nil
+}
+
+// Applies f to the state of request id, and queues the response once it's known to match and its
+// body is loaded. Events of a request may be handled in any order, see listen.
+func (w *ResponseWaiter) update(id protocol.RequestId, f func(p *pendingResponse)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p := w.pending[id]
+	if p == nil {
+		p = &pendingResponse{id: id}
+		w.pending[id] = p
+	}
+	f(p)
+	if p.loaded && (p.rejected || p.response == nil) {
+		// Finished without a response, e.g. served from memory cache, or doesn't match.
+		delete(w.pending, id)
+		return
+	}
+	if !p.loaded || p.queued || (w.match.Method != "" && p.method == "") {
+		return
+	}
+	delete(w.pending, id)
+	if w.match.Method != "" && !strings.EqualFold(p.method, w.match.Method) {
+		return
+	}
+	p.queued = true
+	select {
+	case w.finished <- p:
+	default:
+		// n responses matched already.
+	}
+}
+
+// Waits till n responses matched, or timeout passes, and returns them with their bodies in the
+// order they finished loading. Returns those captured so far along with ErrTimeout on timeout.
+// Listeners are removed before it returns.
+func (w *ResponseWaiter) Wait(timeout time.Duration) ([]*CapturedResponse, error) {
+	defer w.Stop()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	captured := make([]*CapturedResponse, 0, w.n)
+	for len(captured) < w.n {
+		select {
+		case p := <-w.finished:
+			r, err := w.capture(p)
+			if err != nil {
+				return captured, err
+			}
+			captured = append(captured, r)
+		case <-timer.C:
+			return captured, ErrTimeout
+		case <-w.conn.Closed():
+			return captured, w.conn.Err()
+		}
+	}
+	return captured, nil
+}
+
+// Stops waiting. Wait calls it, so it's only needed when giving up without waiting.
+func (w *ResponseWaiter) Stop() {
+	w.stopOnce.Do(func() {
+		for _, cancel := range w.cancels {
+			cancel()
+		}
+		if w.acquired {
+			if err := w.conn.ReleaseDomain("Network"); err != nil {
+				logging.Vlog(1, err)
+			}
+		}
+	})
+}
+
+func (w *ResponseWaiter) capture(p *pendingResponse) (*CapturedResponse, error) {
+	body, err := protocol.GetResponseBody(
+		&protocol.GetResponseBodyParams{RequestId: p.id}, w.conn)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get body of %s: %v", p.response.Url, err)
+	}
+	data := []byte(body.Body)
+	if body.Base64Encoded {
+		if data, err = base64.StdEncoding.DecodeString(body.Body); err != nil {
+			return nil, err
+		}
+	}
+	return &CapturedResponse{
+		RequestId:  p.id,
+		URL:        p.response.Url,
+		Method:     p.method,
+		Status:     int(p.response.Status),
+		StatusText: p.response.StatusText,
+		Headers:    p.response.Headers,
+		MimeType:   p.response.MimeType,
+		Body:       data,
+	}, nil
+}
+
+// Waits for a response whose URL matches urlPattern, see ResponseMatch.URL. As it only starts
+// listening when called, requests triggered before may be missed, e.g. by an action of the
+// calling goroutine. Use ExpectResponses to start listening before the action.
+func WaitForResponse(conn *hc.Conn, urlPattern string, timeout time.Duration) (
+	*CapturedResponse, error) {
+	responses, err := WaitForResponses(conn, urlPattern, timeout, 1)
+	if err != nil {
+		return nil, err
+	}
+	return responses[0], nil
+}
+
+// Like WaitForResponse, but waits for the first n responses matching. Returns those captured so
+// far along with ErrTimeout on timeout.
+func WaitForResponses(conn *hc.Conn, urlPattern string, timeout time.Duration, n int) (
+	[]*CapturedResponse, error) {
+	w, err := ExpectResponses(conn, ResponseMatch{URL: urlPattern}, n)
+	if err != nil {
+		return nil, err
+	}
+	return w.Wait(timeout)
+}
+
+func compileURLPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "re:") {
+		re, err := regexp.Compile(pattern[3:])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern '%s': %v", pattern, err)
+		}
+		return re, nil
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(expr)
+	return regexp.Compile("^" + expr + "$")
+}
@@ -0,0 +1,321 @@
+package hcutil
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Starts screencast and calls cb for each frame. Frames are acked as they arrive, see
+// ackScreencastFrames. Use NewScreencaster to detect stalls or stop it.
+func StartScreencast(conn *hc.Conn, params *protocol.StartScreencastParams,
+	cb func(evt *protocol.ScreencastFrameEvent)) error {
+	_, err := NewScreencaster(conn, &ScreencastOptions{Params: params}, cb)
+	return err
+}
+
+// Returned by Screencaster.Err, and passed to ScreencastOptions.OnStall, once no frame came for
+// ScreencastOptions.StallTimeout.
+type ErrScreencastStalled struct {
+	// Time since the last frame, or the start if none came.
+	Since time.Duration
+	// Frames received since the start.
+	Frames int
+}
+
+func (e *ErrScreencastStalled) Error() string {
+	return fmt.Sprintf("No screencast frame for %v, after %d frames", e.Since, e.Frames)
+}
+
+type ScreencastOptions struct {
+	// nil means the defaults.
+	Params *protocol.StartScreencastParams
+	// The screencast is stalled once no frame came for this long. 0 means no watchdog. The
+	// browser only sends frames when the page changes, so it's only meaningful for pages which
+	// keep changing, e.g. animations.
+	StallTimeout time.Duration
+	// Called on the watchdog goroutine once the screencast stalled, e.g. to call Restart. nil
+	// only logs it.
+	OnStall func(err *ErrScreencastStalled)
+}
+
+// A running screencast. See NewScreencaster.
+type Screencaster struct {
+	conn    *hc.Conn
+	opts    ScreencastOptions
+	cancels []func()
+	done    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	frames  int
+	last    time.Time
+	stalled *ErrScreencastStalled
+}
+
+// Starts screencast and calls cb for each frame, on goroutines of its own, which may run
+// concurrently, see hc.EventSink. Frames are acked as they arrive, in order, before cb is called,
+// so that a slow cb or a busy connection doesn't make the browser stop sending frames. With
+// opts.StallTimeout, a watchdog reports when frames stop coming anyway.
+func NewScreencaster(conn *hc.Conn, opts *ScreencastOptions,
+	cb func(evt *protocol.ScreencastFrameEvent)) (*Screencaster, error) {
+	s := &Screencaster{conn: conn, opts: *opts, done: make(chan struct{}), last: time.Now()}
+	if s.opts.Params == nil {
+		s.opts.Params = &protocol.StartScreencastParams{}
+	}
+	s.cancels = []func(){
+		ackScreencastFrames(conn, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.frames++
+			s.last = time.Now()
+		}),
+		listen(conn, "Page.screencastFrame", func(params []byte) {
+			evt := &protocol.ScreencastFrameEvent{}
+			if err := json.Unmarshal(params, evt); err != nil {
+				conn.ReportEventError("Page.screencastFrame", params, err)
+				return
+			}
+			cb(evt)
+		}),
+	}
+	if err := protocol.StartScreencast(s.opts.Params, conn); err != nil {
+		for _, cancel := range s.cancels {
+			cancel()
+		}
+		return nil, err
+	}
+	if s.opts.StallTimeout > 0 {
+		go s.watch()
+	}
+	return s, nil
+}
+
+// Acks Page.screencastFrame as soon as it's read, see hc.Conn.AddEventHook, with normal priority.
+// The browser stops sending frames when acks lag behind, and acks are sent in the order frames
+// came. received is called for each frame, and must be quick too.
+func ackScreencastFrames(conn *hc.Conn, received func()) (cancel func()) {
+	return conn.AddEventHook("Page.screencastFrame", func(params []byte) {
+		var evt struct {
+			SessionId int `json:"sessionId"`
+		}
+		if err := json.Unmarshal(params, &evt); err != nil {
+			// Reported by the sinks.
+			return
+		}
+		conn.SendCommand(protocol.NewAsyncScreencastFrameAckCommand(
+			&protocol.ScreencastFrameAckParams{SessionId: evt.SessionId},
+			func(err error) {
+				if err != nil {
+					logging.Vlog(2, err)
+				}
+			}))
+		if received != nil {
+			received()
+		}
+	})
+}
+
+func (s *Screencaster) watch() {
+	ticker := time.NewTicker(s.opts.StallTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		case <-s.conn.Closed():
+			return
+		}
+		s.mu.Lock()
+		var stalled *ErrScreencastStalled
+		if since := time.Since(s.last); s.stalled == nil && since >= s.opts.StallTimeout {
+			s.stalled = &ErrScreencastStalled{Since: since, Frames: s.frames}
+			stalled = s.stalled
+		}
+		s.mu.Unlock()
+		if stalled == nil {
+			continue
+		}
+		logging.Vlog(1, stalled)
+		if s.opts.OnStall != nil {
+			s.opts.OnStall(stalled)
+		}
+	}
+}
+
+// Returns *ErrScreencastStalled once the screencast stalled, till it's restarted. nil otherwise.
+func (s *Screencaster) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stalled == nil {
+		return nil
+	}
+	return s.stalled
+}
+
+// Returns the number of frames received so far.
+func (s *Screencaster) Frames() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frames
+}
+
+// Stops and starts the screencast again with the same params, e.g. after it stalled.
+func (s *Screencaster) Restart() error {
+	if err := protocol.StopScreencast(s.conn); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.stalled = nil
+	s.last = time.Now()
+	s.mu.Unlock()
+	return protocol.StartScreencast(s.opts.Params, s.conn)
+}
+
+// Stops the screencast. cb may still be called for frames received before.
+func (s *Screencaster) Stop() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = protocol.StopScreencast(s.conn)
+		for _, cancel := range s.cancels {
+			cancel()
+		}
+	})
+	return err
+}
+
+type CroppedScreencastOptions struct {
+	// Params of the screencast. Only the format and size matter, and nil means the defaults.
+	Params *protocol.StartScreencastParams
+	// The area of the viewport to deliver, in CSS pixels, e.g. of a fixed position widget. Scale
+	// is ignored. nil means whole frames.
+	Crop *Clip
+	// Drop frames whose cropped area is identical to the one of the last frame delivered.
+	DropUnchanged bool
+}
+
+// A decoded frame of StartCroppedScreencast.
+type CroppedFrame struct {
+	// The cropped area of the frame.
+	Image    image.Image
+	Metadata *protocol.ScreencastFrameMetadata
+	// Size of the whole frame in pixels.
+	FrameWidth, FrameHeight int
+	// Size of Image in pixels. Smaller than the crop area converted to pixels if it's partly out
+	// of the frame.
+	Width, Height int
+	// Frames dropped so far by DropUnchanged.
+	Dropped int
+}
+
+// Like StartScreencast, but decodes frames and calls cb with the cropped area of each. The crop
+// area is converted to pixels with the metadata of each frame, so it stays right whatever the
+// device scale factor, page zoom or screencast size.
+func StartCroppedScreencast(conn *hc.Conn, opts *CroppedScreencastOptions,
+	cb func(frame *CroppedFrame)) error {
+	params := opts.Params
+	if params == nil {
+		params = &protocol.StartScreencastParams{}
+	}
+	var mu sync.Mutex
+	var lastHash []byte
+	dropped := 0
+	return StartScreencast(conn, params, func(evt *protocol.ScreencastFrameEvent) {
+		data, err := base64.StdEncoding.DecodeString(evt.Data)
+		if err != nil {
+			logging.Vlog(2, err)
+			return
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			logging.Vlog(2, err)
+			return
+		}
+		bounds := img.Bounds()
+		frame := &CroppedFrame{Image: img, Metadata: evt.Metadata,
+			FrameWidth: bounds.Dx(), FrameHeight: bounds.Dy()}
+		if opts.Crop != nil {
+			frame.Image = cropFrame(img, opts.Crop, evt.Metadata)
+		}
+		frame.Width, frame.Height = frame.Image.Bounds().Dx(), frame.Image.Bounds().Dy()
+
+		// Frames may be delivered concurrently, see hc.EventSink.
+		mu.Lock()
+		defer mu.Unlock()
+		if opts.DropUnchanged {
+			hash := hashPixels(frame.Image)
+			if bytes.Equal(hash, lastHash) {
+				dropped++
+				return
+			}
+			lastHash = hash
+		}
+		frame.Dropped = dropped
+		cb(frame)
+	})
+}
+
+// Returns the area of crop in the frame. The frame shows DeviceWidth x DeviceHeight DIPs, below
+// OffsetTop, with CSS pixels scaled by PageScaleFactor.
+func cropFrame(img image.Image, crop *Clip, metadata *protocol.ScreencastFrameMetadata) image.Image {
+	bounds := img.Bounds()
+	scaleX, scaleY := 1.0, 1.0
+	pageScale := 1.0
+	var offsetTop float64
+	if metadata != nil {
+		if metadata.DeviceWidth > 0 && metadata.DeviceHeight > 0 {
+			scaleX = float64(bounds.Dx()) / metadata.DeviceWidth
+			scaleY = float64(bounds.Dy()) / metadata.DeviceHeight
+		}
+		if metadata.PageScaleFactor > 0 {
+			pageScale = metadata.PageScaleFactor
+		}
+		offsetTop = metadata.OffsetTop
+	}
+	rect := image.Rect(
+		int(math.Floor(crop.X*pageScale*scaleX)),
+		int(math.Floor((crop.Y*pageScale+offsetTop)*scaleY)),
+		int(math.Ceil((crop.X+crop.Width)*pageScale*scaleX)),
+		int(math.Ceil(((crop.Y+crop.Height)*pageScale+offsetTop)*scaleY)),
+	).Add(bounds.Min).Intersect(bounds)
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	cropped := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, rect.Min, draw.Src)
+	return cropped
+}
+
+func hashPixels(img image.Image) []byte {
+	h := sha1.New()
+	bounds := img.Bounds()
+	row := make([]byte, 0, bounds.Dx()*8)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			row = append(row, byte(r>>8), byte(r), byte(g>>8), byte(g), byte(b>>8), byte(b),
+				byte(a>>8), byte(a))
+		}
+		h.Write(row)
+	}
+	return h.Sum(nil)
+}
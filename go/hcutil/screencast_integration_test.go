@@ -0,0 +1,75 @@
+//go:build integration
+
+package hcutil_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Screencasts an animation for a minute, 10 seconds with -short. Frames must keep coming at a
+// steady pace, with no stall, even with every other frame skipped and a slow callback.
+func TestScreencastSoak(t *testing.T) {
+	duration := time.Minute
+	if testing.Short() {
+		duration = 10 * time.Second
+	}
+	fixtures := hctest.NewFixtureServer(t)
+	conn := hctest.NewPage(t, hctest.SharedBrowser(t), fixtures.URL+hctest.FixtureAnimation)
+
+	var mu sync.Mutex
+	var times []time.Time
+	var stalls []*hcutil.ErrScreencastStalled
+	s, err := hcutil.NewScreencaster(conn, &hcutil.ScreencastOptions{
+		Params: &protocol.StartScreencastParams{Format: "jpeg", MaxWidth: 400, MaxHeight: 300,
+			EveryNthFrame: 2},
+		StallTimeout: 2 * time.Second,
+		OnStall: func(err *hcutil.ErrScreencastStalled) {
+			mu.Lock()
+			defer mu.Unlock()
+			stalls = append(stalls, err)
+		},
+	}, func(*protocol.ScreencastFrameEvent) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		// Slower than the frames come, which must not hold up acks.
+		time.Sleep(50 * time.Millisecond)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(duration)
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stalls) > 0 {
+		t.Fatalf("Stalled %d times, first %v", len(stalls), stalls[0])
+	}
+	if min := int(duration.Seconds()) * 5; len(times) < min {
+		t.Fatalf("Got %d frames in %v, want at least %d", len(times), duration, min)
+	}
+	// Callbacks may run concurrently, so sort the times they were called.
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	intervals := make([]time.Duration, len(times)-1)
+	for i := range intervals {
+		intervals[i] = times[i+1].Sub(times[i])
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	median := intervals[len(intervals)/2]
+	p99 := intervals[len(intervals)*99/100]
+	t.Logf("%d frames, median interval %v, p99 %v, max %v", len(times), median, p99,
+		intervals[len(intervals)-1])
+	if p99 > 10*median || p99 > 500*time.Millisecond {
+		t.Errorf("Unsteady frame interval: median %v, p99 %v", median, p99)
+	}
+}
@@ -0,0 +1,218 @@
+package hcutil_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Waits till the fake server got n commands of method, and returns them.
+func waitForCommands(t *testing.T, server *hctest.FakeServer, method string,
+	n int) []*hctest.FakeCommand {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		cmds := server.CommandsOf(method)
+		if len(cmds) >= n {
+			return cmds
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Got %d %s, want %d", len(cmds), method, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func emitFrame(fake *hctest.FakeConn, sessionId int) {
+	fake.Emit("Page.screencastFrame", map[string]interface{}{
+		"data": "", "sessionId": sessionId,
+		"metadata": map[string]interface{}{"offsetTop": 0, "pageScaleFactor": 1,
+			"deviceWidth": 800, "deviceHeight": 600, "scrollOffsetX": 0, "scrollOffsetY": 0},
+	})
+}
+
+// Frames are acked in the order they came, without waiting for the callback.
+func TestScreencastAcksInOrder(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	hctest.ExpectNoSinkLeaks(t, conn)
+	release := make(chan struct{})
+	delivered := make(chan int, 100)
+	s, err := hcutil.NewScreencaster(conn, &hcutil.ScreencastOptions{},
+		func(evt *protocol.ScreencastFrameEvent) {
+			<-release
+			delivered <- evt.SessionId
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	const frames = 50
+	for i := 1; i <= frames; i++ {
+		emitFrame(fake, i)
+	}
+	acks := waitForCommands(t, server, "Page.screencastFrameAck", frames)
+	for i, ack := range acks {
+		var params struct{ SessionId int }
+		json.Unmarshal(ack.Params, &params)
+		if params.SessionId != i+1 {
+			t.Fatalf("Ack %d is of frame %d", i, params.SessionId)
+		}
+	}
+	if len(delivered) != 0 {
+		t.Error("Frames delivered before the callback was released")
+	}
+	if n := s.Frames(); n != frames {
+		t.Errorf("Counted %d frames", n)
+	}
+	close(release)
+	for i := 0; i < frames; i++ {
+		select {
+		case <-delivered:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%d frames delivered", i)
+		}
+	}
+}
+
+func TestScreencastStall(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	hctest.ExpectNoSinkLeaks(t, conn)
+	stalls := make(chan *hcutil.ErrScreencastStalled, 10)
+	s, err := hcutil.NewScreencaster(conn, &hcutil.ScreencastOptions{
+		StallTimeout: 200 * time.Millisecond,
+		OnStall:      func(err *hcutil.ErrScreencastStalled) { stalls <- err },
+	}, func(*protocol.ScreencastFrameEvent) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	// Frames keep the watchdog quiet.
+	for i := 1; i <= 10; i++ {
+		emitFrame(fake, i)
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Stalled while frames came: %v", err)
+	}
+	var stalled *hcutil.ErrScreencastStalled
+	select {
+	case stalled = <-stalls:
+	case <-time.After(10 * time.Second):
+		t.Fatal("No stall reported")
+	}
+	if stalled.Frames != 10 || stalled.Since < 200*time.Millisecond {
+		t.Errorf("Got %+v", stalled)
+	}
+	if err, ok := s.Err().(*hcutil.ErrScreencastStalled); !ok || err != stalled {
+		t.Errorf("Err returned %v", s.Err())
+	}
+	// Reported once per stall.
+	time.Sleep(300 * time.Millisecond)
+	if len(stalls) != 0 {
+		t.Error("Stall reported again")
+	}
+
+	if err := s.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Still stalled after restarting: %v", err)
+	}
+	if n := len(server.CommandsOf("Page.startScreencast")); n != 2 {
+		t.Errorf("Started %d times", n)
+	}
+	if n := len(server.CommandsOf("Page.stopScreencast")); n != 1 {
+		t.Errorf("Stopped %d times", n)
+	}
+	select {
+	case <-stalls:
+	case <-time.After(10 * time.Second):
+		t.Fatal("No stall reported after restarting")
+	}
+}
+
+// Emits an 800x600 PNG frame of a 400x300 DIP view at device scale factor 2, with a 10 DIP top
+// offset, filled with outside but for rect filled with inside.
+func emitScaledFrame(t *testing.T, fake *hctest.FakeConn, sessionId int, rect image.Rectangle,
+	inside, outside color.Color) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	draw.Draw(img, img.Bounds(), image.NewUniform(outside), image.Point{}, draw.Src)
+	draw.Draw(img, rect, image.NewUniform(inside), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	fake.Emit("Page.screencastFrame", map[string]interface{}{
+		"data": base64.StdEncoding.EncodeToString(buf.Bytes()), "sessionId": sessionId,
+		"metadata": map[string]interface{}{"offsetTop": 10, "pageScaleFactor": 1,
+			"deviceWidth": 400, "deviceHeight": 300, "scrollOffsetX": 0, "scrollOffsetY": 0},
+	})
+}
+
+// At device scale factor 2, the crop area in CSS pixels covers twice as many pixels, below the
+// top offset, and frames whose crop area didn't change are dropped.
+func TestCroppedScreencastScaled(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	var frames []*hcutil.CroppedFrame
+	if err := hcutil.StartCroppedScreencast(conn, &hcutil.CroppedScreencastOptions{
+		Crop: &hcutil.Clip{X: 100, Y: 50, Width: 50, Height: 30}, DropUnchanged: true,
+	}, func(frame *hcutil.CroppedFrame) {
+		frames = append(frames, frame)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	// The crop area is at (200, 120)-(300, 180) in pixels.
+	area := image.Rect(200, 120, 300, 180)
+	for i, f := range []struct{ inside, outside color.Color }{
+		{red, color.White},
+		{red, color.White},
+		// Only outside of the crop area.
+		{red, blue},
+		{blue, blue},
+	} {
+		emitScaledFrame(t, fake, i+1, area, f.inside, f.outside)
+		// Frames are delivered one by one.
+		hctest.Flush(t, conn)
+	}
+
+	if len(frames) != 2 {
+		t.Fatalf("Got %d frames, want 2", len(frames))
+	}
+	for i, want := range []color.Color{red, blue} {
+		frame := frames[i]
+		if frame.FrameWidth != 800 || frame.FrameHeight != 600 || frame.Width != 100 ||
+			frame.Height != 60 {
+			t.Errorf("Frame %d: got %dx%d of %dx%d", i, frame.Width, frame.Height,
+				frame.FrameWidth, frame.FrameHeight)
+			continue
+		}
+		b := frame.Image.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if color.RGBAModel.Convert(frame.Image.At(x, y)) != want {
+					t.Fatalf("Frame %d: got %v at (%d, %d)", i, frame.Image.At(x, y), x, y)
+				}
+			}
+		}
+	}
+	if frames[1].Dropped != 2 {
+		t.Errorf("Dropped %d frames, want 2", frames[1].Dropped)
+	}
+}
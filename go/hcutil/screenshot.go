@@ -0,0 +1,285 @@
+package hcutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type ScreenshotOptions struct {
+	// Resize the view to the whole document before capturing.
+	FullPage bool
+	// Inject "::-webkit-scrollbar{display:none}" while capturing, so no scrollbar strip shows up.
+	// Protocol v1.2 has neither fromSurface nor captureBeyondViewport, so this is the only way.
+	HideScrollbars bool
+	// Only capture this area of the page. FullPage is ignored if set.
+	Clip *Clip
+	// Device pixel ratio to emulate while capturing, e.g. 2 for HiDPI screens. 0 means the
+	// browser's default.
+	DeviceScaleFactor float64
+	// Width of the view in CSS pixels for FullPage. 0 means the width of the document.
+	Width int
+	// FullPage screenshots are scaled down to one image pixel per CSS pixel, so that Width is
+	// also the width of the image regardless of DeviceScaleFactor. Set this to keep all pixels
+	// rendered instead.
+	KeepHiDPI bool
+}
+
+// A rectangle of the page, in CSS pixels relative to the document.
+type Clip struct {
+	X, Y          float64
+	Width, Height float64
+	// Scale to render the area with. 0 means 1.
+	Scale float64
+}
+
+const hideScrollbarsStyleId = "__hc_hide_scrollbars"
+
+// Captures a screenshot of the page. Returns the PNG data.
+func CaptureScreenshot(conn *hc.Conn, opts *ScreenshotOptions) ([]byte, error) {
+	if err := conn.CheckKind("Page.captureScreenshot"); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &ScreenshotOptions{}
+	}
+	if opts.HideScrollbars {
+		if err := Evaluate(conn, `(function() {
+	var style = document.createElement("style");
+	style.id = "`+hideScrollbarsStyleId+`";
+	style.textContent = "::-webkit-scrollbar{display:none}";
+	document.head.appendChild(style);
+})()`, nil); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := Evaluate(conn, `(function() {
+	var style = document.getElementById("`+hideScrollbarsStyleId+`");
+	if (style) style.remove();
+})()`, nil); err != nil {
+				logging.Vlog(-1, err)
+			}
+		}()
+	}
+	if opts.Clip == nil && !opts.FullPage && opts.DeviceScaleFactor == 0 {
+		return captureScreenshot(conn)
+	}
+	// The view is changed for capturing only, so that emulation set before, e.g. a device's
+	// metrics, stays in effect afterwards.
+	var data []byte
+	err := WithEmulation(conn, func() (err error) {
+		data, err = captureEmulated(conn, opts)
+		return err
+	})
+	return data, err
+}
+
+func captureEmulated(conn *hc.Conn, opts *ScreenshotOptions) ([]byte, error) {
+	if opts.Clip != nil {
+		return captureClip(conn, opts.Clip, opts.DeviceScaleFactor)
+	}
+	if !opts.FullPage {
+		if err := setDeviceScaleFactor(conn, opts.DeviceScaleFactor); err != nil {
+			return nil, err
+		}
+		return captureScreenshot(conn)
+	}
+	width, err := resizeToDocument(conn, opts.Width, opts.DeviceScaleFactor)
+	if err != nil {
+		return nil, err
+	}
+	data, err := captureScreenshot(conn)
+	if err != nil || opts.KeepHiDPI {
+		return data, err
+	}
+	return scaleToWidth(data, width)
+}
+
+// Keeps the size of the view, so only the pixel density changes.
+func setDeviceScaleFactor(conn *hc.Conn, factor float64) error {
+	return updateDeviceMetrics(conn, func(params *protocol.EmulationSetDeviceMetricsOverrideParams) {
+		params.DeviceScaleFactor = factor
+	})
+}
+
+// Scales the PNG image down to width, keeping the aspect ratio. Returns data as is if it isn't
+// wider.
+func scaleToWidth(data []byte, width int) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return data, nil
+	}
+	height := int(math.Round(float64(bounds.Dy()) * float64(width) / float64(bounds.Dx())))
+	if height < 1 {
+		height = 1
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleDown(img, width, height)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Box filter: each pixel is the average of the source pixels it covers.
+func scaleDown(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	xRatio := float64(bounds.Dx()) / float64(width)
+	yRatio := float64(bounds.Dy()) / float64(height)
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + int(float64(y)*yRatio)
+		y1 := bounds.Min.Y + int(math.Ceil(float64(y+1)*yRatio))
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + int(float64(x)*xRatio)
+			x1 := bounds.Min.X + int(math.Ceil(float64(x+1)*xRatio))
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1 && sy < bounds.Max.Y; sy++ {
+				for sx := x0; sx < x1 && sx < bounds.Max.X; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			scaled.SetRGBA(x, y, color.RGBA{
+				uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+	return scaled
+}
+
+func captureScreenshot(conn *hc.Conn) ([]byte, error) {
+	result, err := protocol.CaptureScreenshot(conn)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Data)
+}
+
+// Protocol v1.2 can't clip screenshots. So make the view as small as possible while containing
+// the clip, then crop the captured image to exactly the clip.
+func captureClip(conn *hc.Conn, clip *Clip, deviceScaleFactor float64) ([]byte, error) {
+	scale := clip.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	// The clip is moved to the top-left corner of the view.
+	width := int(math.Ceil(clip.Width * scale))
+	height := int(math.Ceil(clip.Height * scale))
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("Empty clip %v", *clip)
+	}
+	// CaptureScreenshot restores all of it afterwards.
+	e := getEmulation(conn)
+	e.mu.Lock()
+	err := e.setMetrics(conn, &protocol.EmulationSetDeviceMetricsOverrideParams{
+		Width: width, Height: height, DeviceScaleFactor: deviceScaleFactor})
+	if err == nil {
+		err = e.setViewport(conn, &protocol.ForceViewportParams{X: clip.X, Y: clip.Y, Scale: scale})
+	}
+	if err == nil {
+		err = e.setVisibleSize(conn, &viewSize{Width: width, Height: height})
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	data, err := captureScreenshot(conn)
+	if err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// The image may be bigger than the view due to device pixel ratio.
+	bounds := img.Bounds()
+	ratio := float64(bounds.Dx()) / float64(width)
+	cropWidth := int(math.Round(clip.Width * scale * ratio))
+	cropHeight := int(math.Round(clip.Height * scale * ratio))
+	if cropWidth > bounds.Dx() {
+		cropWidth = bounds.Dx()
+	}
+	if cropHeight > bounds.Dy() {
+		cropHeight = bounds.Dy()
+	}
+	if cropWidth == bounds.Dx() && cropHeight == bounds.Dy() {
+		return data, nil
+	}
+	cropped := image.NewRGBA(image.Rect(0, 0, cropWidth, cropHeight))
+	draw.Draw(cropped, cropped.Bounds(), img, bounds.Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, cropped); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sets device size and visible size to the size of the whole document, or to width and the
+// height of the document laid out in it. Returns the width.
+func resizeToDocument(conn *hc.Conn, width int, deviceScaleFactor float64) (int, error) {
+	if width > 0 {
+		// Lay the document out in width first, as that's what its height depends on.
+		var height int
+		if err := Evaluate(conn, "window.innerHeight", &height); err != nil {
+			return 0, err
+		}
+		if err := resizeView(conn, width, height, deviceScaleFactor); err != nil {
+			return 0, err
+		}
+	}
+	var size struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}
+	if err := Evaluate(conn, `({
+	width: document.scrollingElement.scrollWidth,
+	height: document.scrollingElement.scrollHeight
+})`, &size); err != nil {
+		return 0, err
+	}
+	if width > 0 {
+		size.Width = width
+	}
+	if err := resizeView(conn, size.Width, size.Height, deviceScaleFactor); err != nil {
+		return 0, err
+	}
+	e := getEmulation(conn)
+	e.mu.Lock()
+	err := e.setViewport(conn, &protocol.ForceViewportParams{X: 0, Y: 0, Scale: 1})
+	if err == nil {
+		err = e.setVisibleSize(conn, &viewSize{Width: size.Width, Height: size.Height})
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return size.Width, settleResize(conn)
+}
+
+// Keeps the other device metrics, e.g. the orientation, and the device scale factor if it's 0.
+func resizeView(conn *hc.Conn, width, height int, deviceScaleFactor float64) error {
+	return updateDeviceMetrics(conn, func(params *protocol.EmulationSetDeviceMetricsOverrideParams) {
+		params.Width = width
+		params.Height = height
+		if deviceScaleFactor != 0 {
+			params.DeviceScaleFactor = deviceScaleFactor
+		}
+	})
+}
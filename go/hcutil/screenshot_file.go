@@ -0,0 +1,154 @@
+package hcutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type ScreenshotFileOptions struct {
+	ScreenshotOptions
+	// "png", "jpeg" or "gif". Empty means the one of the extension of the file, and PNG for
+	// CaptureScreenshotTo.
+	Format string
+	// JPEG quality in [1, 100]. 0 means the default. Other formats take no quality.
+	Quality int
+}
+
+var imageFormats = map[string]string{
+	".png": "png", ".jpg": "jpeg", ".jpeg": "jpeg", ".gif": "gif",
+}
+
+// Captures a screenshot like CaptureScreenshot, and writes it to path, encoded in opts.Format,
+// or the format of the extension of path. Fails without capturing if the format isn't
+// supported, rather than picking one. The image is written to a temporary file next to path,
+// which is renamed to path once complete, so path is never left truncated, and an existing file
+// is kept if capturing fails. opts may be nil.
+func CaptureScreenshotToFile(conn *hc.Conn, path string, opts *ScreenshotFileOptions) error {
+	o := ScreenshotFileOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Format == "" {
+		ext := strings.ToLower(filepath.Ext(path))
+		if o.Format = imageFormats[ext]; o.Format == "" {
+			return fmt.Errorf("Unsupported image extension '%s' of %s, use .png, .jpg or .gif",
+				ext, path)
+		}
+	}
+	data, err := CaptureScreenshotBytes(conn, &o)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Like CaptureScreenshotToFile, but returns the encoded image.
+func CaptureScreenshotBytes(conn *hc.Conn, opts *ScreenshotFileOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := CaptureScreenshotTo(conn, &buf, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Like CaptureScreenshotToFile, but writes the image to w. Plain screenshots are decoded from
+// base64 as they're written or re-encoded, so the decoded PNG isn't held in memory.
+func CaptureScreenshotTo(conn *hc.Conn, w io.Writer, opts *ScreenshotFileOptions) error {
+	if opts == nil {
+		opts = &ScreenshotFileOptions{}
+	}
+	format := opts.Format
+	if format == "" {
+		format = "png"
+	}
+	if err := checkImageFormat(format); err != nil {
+		return err
+	}
+	if opts.Quality < 0 || opts.Quality > 100 {
+		return fmt.Errorf("Quality %d out of [0, 100]", opts.Quality)
+	}
+	if opts.Quality != 0 && format != "jpeg" {
+		return fmt.Errorf("Quality %d given for %s, only JPEG takes one", opts.Quality, format)
+	}
+	r, err := captureScreenshotReader(conn, &opts.ScreenshotOptions)
+	if err != nil {
+		return err
+	}
+	if format == "png" {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	img, err := png.Decode(r)
+	if err != nil {
+		return err
+	}
+	return encodeImage(w, img, format, opts.Quality)
+}
+
+func checkImageFormat(format string) error {
+	switch format {
+	case "png", "jpeg", "gif":
+		return nil
+	}
+	return fmt.Errorf("Unsupported image format '%s', use png, jpeg or gif", format)
+}
+
+func encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "jpeg":
+		var opts *jpeg.Options
+		if quality > 0 {
+			opts = &jpeg.Options{Quality: quality}
+		}
+		return jpeg.Encode(w, img, opts)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	}
+	return png.Encode(w, img)
+}
+
+// Returns the PNG data of a screenshot. Those without options are decoded from base64 while
+// read. Others are processed by CaptureScreenshot, which needs them decoded.
+func captureScreenshotReader(conn *hc.Conn, opts *ScreenshotOptions) (io.Reader, error) {
+	if opts.Clip != nil || opts.FullPage || opts.DeviceScaleFactor != 0 || opts.HideScrollbars {
+		data, err := CaptureScreenshot(conn, opts)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+	if err := conn.CheckKind("Page.captureScreenshot"); err != nil {
+		return nil, err
+	}
+	result, err := protocol.CaptureScreenshot(conn)
+	if err != nil {
+		return nil, err
+	}
+	return base64.NewDecoder(base64.StdEncoding, strings.NewReader(result.Data)), nil
+}
@@ -0,0 +1,152 @@
+package hcutil_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+func readScreenshotFixture(t *testing.T) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "screenshot.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Names of the files in dir.
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestCaptureScreenshotToFile(t *testing.T) {
+	fixture := readScreenshotFixture(t)
+	want, err := png.Decode(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := hctest.NewFakeServer(t)
+	handleScreenshot(server, fixture)
+	conn, _ := server.NewPageConn()
+	dir := t.TempDir()
+
+	for _, c := range []struct {
+		name   string
+		opts   *hcutil.ScreenshotFileOptions
+		decode func(*os.File) (image.Image, error)
+	}{
+		{name: "shot.png", decode: func(f *os.File) (image.Image, error) { return png.Decode(f) }},
+		{name: "shot.JPG", opts: &hcutil.ScreenshotFileOptions{Quality: 90},
+			decode: func(f *os.File) (image.Image, error) { return jpeg.Decode(f) }},
+		{name: "shot.gif", decode: func(f *os.File) (image.Image, error) { return gif.Decode(f) }},
+		{name: "shot.img", opts: &hcutil.ScreenshotFileOptions{Format: "jpeg"},
+			decode: func(f *os.File) (image.Image, error) { return jpeg.Decode(f) }},
+	} {
+		path := filepath.Join(dir, c.name)
+		if err := hcutil.CaptureScreenshotToFile(conn, path, c.opts); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := c.decode(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		} else if img.Bounds() != want.Bounds() {
+			t.Errorf("%s: got bounds %v, want %v", c.name, img.Bounds(), want.Bounds())
+		}
+	}
+	// PNG is written as captured.
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "shot.png")); !bytes.Equal(data, fixture) {
+		t.Error("PNG differs from the screenshot")
+	}
+	if files := dirFiles(t, dir); len(files) != 4 {
+		t.Errorf("Left %v", files)
+	}
+}
+
+// Bad options fail before capturing, and failures leave an existing file as it was, with no
+// temporary file around.
+func TestCaptureScreenshotToFileErrors(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shot.png")
+	old := []byte("old screenshot")
+	if err := ioutil.WriteFile(path, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		path string
+		opts *hcutil.ScreenshotFileOptions
+	}{
+		{name: "unknown extension", path: filepath.Join(dir, "shot.bmp")},
+		{name: "unknown format", path: path,
+			opts: &hcutil.ScreenshotFileOptions{Format: "webp"}},
+		{name: "quality over 100", path: filepath.Join(dir, "shot.jpg"),
+			opts: &hcutil.ScreenshotFileOptions{Quality: 101}},
+		{name: "negative quality", path: filepath.Join(dir, "shot.jpg"),
+			opts: &hcutil.ScreenshotFileOptions{Quality: -1}},
+		{name: "quality of PNG", path: path, opts: &hcutil.ScreenshotFileOptions{Quality: 80}},
+		{name: "quality of GIF", path: filepath.Join(dir, "shot.gif"),
+			opts: &hcutil.ScreenshotFileOptions{Quality: 80}},
+	} {
+		if err := hcutil.CaptureScreenshotToFile(conn, c.path, c.opts); err == nil {
+			t.Errorf("%s: no error", c.name)
+		}
+	}
+	if n := len(server.CommandsOf("Page.captureScreenshot")); n != 0 {
+		t.Errorf("Captured %d times with bad options", n)
+	}
+
+	// The browser fails to capture.
+	server.Handle("Page.captureScreenshot", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, errors.New("Unable to capture screenshot")
+	})
+	if err := hcutil.CaptureScreenshotToFile(conn, path, nil); err == nil {
+		t.Error("No error when capturing fails")
+	}
+	// The screenshot isn't a PNG, so can't be re-encoded.
+	handleScreenshot(server, []byte("not a png"))
+	if err := hcutil.CaptureScreenshotToFile(conn, filepath.Join(dir, "new.jpg"),
+		nil); err == nil {
+		t.Error("No error when decoding fails")
+	}
+	if data, _ := ioutil.ReadFile(path); !bytes.Equal(data, old) {
+		t.Errorf("Overwritten with %q", data)
+	}
+	if files := dirFiles(t, dir); len(files) != 1 || files[0] != "shot.png" {
+		t.Errorf("Left %v", files)
+	}
+
+	// A missing directory fails after capturing.
+	handleScreenshot(server, readScreenshotFixture(t))
+	if err := hcutil.CaptureScreenshotToFile(conn, filepath.Join(dir, "missing", "shot.png"),
+		nil); err == nil {
+		t.Error("Wrote to a missing directory")
+	}
+}
@@ -0,0 +1,96 @@
+package hcutil_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// A PNG of width x height filled with c.
+func testPng(t testing.TB, width, height int, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func handleScreenshot(server *hctest.FakeServer, data []byte) {
+	server.Handle("Page.captureScreenshot", hctest.FakeResult(map[string]string{
+		"data": base64.StdEncoding.EncodeToString(data)}))
+}
+
+// Methods of the commands sent, with "+style" and "-style" for the scripts adding and removing
+// the style hiding scrollbars.
+func screenshotSteps(server *hctest.FakeServer) []string {
+	var steps []string
+	for _, cmd := range server.Commands() {
+		switch cmd.Method {
+		case "Runtime.evaluate":
+			var params struct{ Expression string }
+			json.Unmarshal(cmd.Params, &params)
+			if strings.Contains(params.Expression, "::-webkit-scrollbar{display:none}") {
+				steps = append(steps, "+style")
+			} else if strings.Contains(params.Expression, ".remove()") {
+				steps = append(steps, "-style")
+			}
+		case "Page.captureScreenshot":
+			steps = append(steps, "capture")
+		}
+	}
+	return steps
+}
+
+func TestHideScrollbars(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		server := hctest.NewFakeServer(t)
+		want := testPng(t, 4, 3, color.White)
+		handleScreenshot(server, want)
+		conn, _ := server.NewPageConn()
+		data, err := hcutil.CaptureScreenshot(conn, &hcutil.ScreenshotOptions{HideScrollbars: hide})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Error("Got another image")
+		}
+		wantSteps := "capture"
+		if hide {
+			wantSteps = "+style capture -style"
+		}
+		if steps := strings.Join(screenshotSteps(server), " "); steps != wantSteps {
+			t.Errorf("HideScrollbars %v: got %s, want %s", hide, steps, wantSteps)
+		}
+	}
+}
+
+// The style is removed even if capturing fails.
+func TestHideScrollbarsCaptureFails(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Page.captureScreenshot", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, errors.New("Unable to capture screenshot")
+	})
+	conn, _ := server.NewPageConn()
+	if _, err := hcutil.CaptureScreenshot(conn, &hcutil.ScreenshotOptions{
+		HideScrollbars: true}); err == nil {
+		t.Fatal("Capture succeeded")
+	}
+	if steps := strings.Join(screenshotSteps(server), " "); steps != "+style capture -style" {
+		t.Errorf("Got %s", steps)
+	}
+}
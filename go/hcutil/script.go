@@ -0,0 +1,58 @@
+package hcutil
+
+import (
+	"encoding/json"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Runs script in every new document of the page, before any of the page's own scripts. Large
+// scripts, e.g. libraries injected into every page, are marshaled only once for all
+// connections, see hc.KeyedParams.
+func InjectOnNewDocument(conn *hc.Conn, script string) (protocol.ScriptIdentifier, error) {
+	cmd := &injectCommand{params: injectParams{ScriptSource: script}, done: make(chan error, 1)}
+	if err := conn.SendCommand(cmd); err != nil {
+		return "", err
+	}
+	if err := <-cmd.done; err != nil {
+		return "", err
+	}
+	return cmd.result.Identifier, nil
+}
+
+// Stops injecting the script added by InjectOnNewDocument.
+func RemoveInjected(conn *hc.Conn, id protocol.ScriptIdentifier) error {
+	return protocol.RemoveScriptToEvaluateOnLoad(
+		&protocol.RemoveScriptToEvaluateOnLoadParams{Identifier: id}, conn)
+}
+
+// Like protocol.AddScriptToEvaluateOnLoadParams, keyed by the script itself.
+type injectParams struct {
+	ScriptSource string `json:"scriptSource"`
+}
+
+func (p injectParams) ParamsKey() string {
+	return p.ScriptSource
+}
+
+type injectCommand struct {
+	params injectParams
+	result protocol.AddScriptToEvaluateOnLoadResult
+	done   chan error
+}
+
+func (cmd *injectCommand) Name() string {
+	return "Page.addScriptToEvaluateOnLoad"
+}
+
+func (cmd *injectCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *injectCommand) Done(result []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(result, &cmd.result)
+	}
+	cmd.done <- err
+}
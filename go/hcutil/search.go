@@ -0,0 +1,125 @@
+package hcutil
+
+import (
+	"context"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+const (
+	defaultSearchChunkSize = 500
+	// Chunks SearchStream requests ahead of the one fn is called for.
+	searchStreamLookahead = 2
+)
+
+type SearchOptions struct {
+	// Number of results fetched by each DOM.getSearchResults. Defaults to 500.
+	ChunkSize                 int
+	IncludeUserAgentShadowDOM bool
+}
+
+// Returns the nodes matching query, which is plain text, a CSS selector or an XPath, see
+// protocol.PerformSearch. opts may be nil. Results are fetched in chunks, all requested at once,
+// so that no response gets oversized, yet there is a single round trip. The search is always
+// discarded afterwards.
+func Search(conn *hc.Conn, query string, opts *SearchOptions) ([]protocol.NodeId, error) {
+	s, err := startSearch(conn, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer s.discard()
+	cmds := make([]*protocol.AsyncGetSearchResultsCommand, 0, s.chunks())
+	for i := 0; i < s.chunks(); i++ {
+		cmds = append(cmds, s.requestChunk(i))
+	}
+	nodeIds := make([]protocol.NodeId, 0, s.count)
+	for _, cmd := range cmds {
+		result, err := cmd.Wait(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		nodeIds = append(nodeIds, result.NodeIds...)
+	}
+	return nodeIds, nil
+}
+
+// Calls fn with the nodes matching query in order, see Search, until it returns false. Chunks
+// are fetched a few ahead of fn, so stopping early, e.g. after the first match, skips fetching
+// most of the remaining results.
+func SearchStream(conn *hc.Conn, query string, fn func(nodeId protocol.NodeId) bool) error {
+	s, err := startSearch(conn, query, nil)
+	if err != nil {
+		return err
+	}
+	defer s.discard()
+	var pending []*protocol.AsyncGetSearchResultsCommand
+	next := 0
+	for i := 0; i < s.chunks(); i++ {
+		for ; next < s.chunks() && next <= i+searchStreamLookahead; next++ {
+			pending = append(pending, s.requestChunk(next))
+		}
+		result, err := pending[0].Wait(context.Background())
+		pending = pending[1:]
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range result.NodeIds {
+			if !fn(nodeId) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+type search struct {
+	conn      *hc.Conn
+	id        string
+	count     int
+	chunkSize int
+}
+
+func startSearch(conn *hc.Conn, query string, opts *SearchOptions) (*search, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultSearchChunkSize
+	}
+	// Results can only be reported once the document was requested.
+	if _, err := protocol.GetDocument(&protocol.GetDocumentParams{}, conn); err != nil {
+		return nil, err
+	}
+	result, err := protocol.PerformSearch(&protocol.PerformSearchParams{
+		Query: query, IncludeUserAgentShadowDOM: opts.IncludeUserAgentShadowDOM}, conn)
+	if err != nil {
+		return nil, err
+	}
+	return &search{conn: conn, id: result.SearchId, count: result.ResultCount,
+		chunkSize: chunkSize}, nil
+}
+
+func (s *search) chunks() int {
+	return (s.count + s.chunkSize - 1) / s.chunkSize
+}
+
+func (s *search) requestChunk(i int) *protocol.AsyncGetSearchResultsCommand {
+	to := (i + 1) * s.chunkSize
+	if to > s.count {
+		to = s.count
+	}
+	return protocol.NewGetSearchResultsCommand(&protocol.GetSearchResultsParams{
+		SearchId: s.id, FromIndex: i * s.chunkSize, ToIndex: to}).Send(s.conn)
+}
+
+// Chunks requested before are still answered, as commands run in order, and just ignored.
+func (s *search) discard() {
+	if err := protocol.DiscardSearchResults(
+		&protocol.DiscardSearchResultsParams{SearchId: s.id}, s.conn); err != nil {
+		logging.Vlog(2, err)
+	}
+}
@@ -0,0 +1,179 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+var ErrSecurityNotTracked = errors.New("security isn't tracked, call TrackSecurity first")
+
+// Security of the page and the certificate of its main document.
+type SecuritySummary struct {
+	// From Security.securityStateChanged.
+	State                 protocol.SecurityState
+	SchemeIsCryptographic bool
+	Explanations          []*protocol.SecurityStateExplanation
+	InsecureContent       *protocol.InsecureContentStatus
+
+	// From the main document response. Certificate fields are empty if it wasn't over TLS.
+	URL         string
+	Protocol    string
+	KeyExchange string
+	Cipher      string
+	SubjectName string
+	SanList     []string
+	Issuer      string
+	ValidFrom   time.Time
+	ValidTo     time.Time
+	Expired     bool
+	// The certificate expires within SecurityOptions.ExpiryThreshold.
+	ExpiresSoon bool
+
+	// Resources loaded as mixed content or otherwise insecurely since the main frame navigated.
+	InsecureURLs []string
+}
+
+// Whether the page is secure, without insecure resources or certificate issues.
+func (s *SecuritySummary) Secure() bool {
+	return s.State == protocol.SecurityStateSecure && len(s.InsecureURLs) == 0 && !s.Expired
+}
+
+type SecurityOptions struct {
+	// Defaults to 30 days.
+	ExpiryThreshold time.Duration
+}
+
+type securityTrackerKey struct{}
+
+type securityTracker struct {
+	mu           sync.Mutex
+	state        *protocol.SecurityStateChangedEvent
+	insecureURLs map[string]bool
+}
+
+// Starts tracking the security state and insecure resources of the page. Call it before
+// navigating, e.g. with NavigateAndWait, and PageSecurity afterwards. Security and Network domains
+// are acquired for the life of conn, see hc.Conn.AcquireDomain. Insecure resources are
+// forgotten when the main frame navigates, which is only known if Page domain is enabled too.
+func TrackSecurity(conn *hc.Conn) error {
+	t := &securityTracker{insecureURLs: make(map[string]bool)}
+	if conn.Value(securityTrackerKey{}, func() interface{} { return t }) != t {
+		// Already tracking.
+		return nil
+	}
+	listen(conn, "Security.securityStateChanged", func(params []byte) {
+		evt := &protocol.SecurityStateChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError("Security.securityStateChanged", params, err)
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.state = evt
+	})
+	listen(conn, "Page.frameNavigated", func(params []byte) {
+		evt := &protocol.FrameNavigatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError("Page.frameNavigated", params, err)
+			return
+		}
+		if evt.Frame != nil && evt.Frame.ParentId == "" {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.insecureURLs = make(map[string]bool)
+		}
+	})
+	listen(conn, "Network.requestWillBeSent", func(params []byte) {
+		evt := &protocol.RequestWillBeSentEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError("Network.requestWillBeSent", params, err)
+			return
+		}
+		if evt.Request == nil {
+			return
+		}
+		switch evt.Request.MixedContentType {
+		case "", "none":
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.insecureURLs[evt.Request.Url] = true
+	})
+	listen(conn, "Network.responseReceived", func(params []byte) {
+		evt := &protocol.ResponseReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError("Network.responseReceived", params, err)
+			return
+		}
+		if evt.Response == nil || evt.Response.SecurityState != protocol.SecurityStateInsecure ||
+			evt.Type == protocol.ResourceTypeDocument {
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.insecureURLs[evt.Response.Url] = true
+	})
+	if err := conn.AcquireDomain("Network", nil); err != nil {
+		return err
+	}
+	return conn.AcquireDomain("Security", nil)
+}
+
+// Summarizes security of the page loaded by the last NavigateAndWait call. TrackSecurity must be
+// called before navigating.
+func PageSecurity(conn *hc.Conn, opts *SecurityOptions) (*SecuritySummary, error) {
+	threshold := 30 * 24 * time.Hour
+	if opts != nil && opts.ExpiryThreshold > 0 {
+		threshold = opts.ExpiryThreshold
+	}
+	t, _ := conn.Value(securityTrackerKey{}, nil).(*securityTracker)
+	if t == nil {
+		return nil, ErrSecurityNotTracked
+	}
+	resp, err := MainDocumentResponse(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &SecuritySummary{URL: resp.Url, State: protocol.SecurityStateUnknown}
+	t.mu.Lock()
+	if t.state != nil {
+		summary.State = t.state.SecurityState
+		summary.SchemeIsCryptographic = t.state.SchemeIsCryptographic
+		summary.Explanations = t.state.Explanations
+		summary.InsecureContent = t.state.InsecureContentStatus
+	}
+	for url := range t.insecureURLs {
+		summary.InsecureURLs = append(summary.InsecureURLs, url)
+	}
+	t.mu.Unlock()
+	sort.Strings(summary.InsecureURLs)
+
+	if details := resp.SecurityDetails; details != nil {
+		summary.Protocol = details.Protocol
+		summary.KeyExchange = details.KeyExchange
+		summary.Cipher = details.Cipher
+		summary.SubjectName = details.SubjectName
+		summary.SanList = details.SanList
+		summary.Issuer = details.Issuer
+		summary.ValidFrom = timestampToTime(details.ValidFrom)
+		summary.ValidTo = timestampToTime(details.ValidTo)
+		now := time.Now()
+		summary.Expired = now.After(summary.ValidTo) || now.Before(summary.ValidFrom)
+		summary.ExpiresSoon = !summary.Expired && summary.ValidTo.Sub(now) < threshold
+	}
+	return summary, nil
+}
+
+// Converts seconds since epoch.
+func timestampToTime(ts protocol.NetworkTimestamp) time.Time {
+	sec := int64(ts)
+	return time.Unix(sec, int64((float64(ts)-float64(sec))*1e9))
+}
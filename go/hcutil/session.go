@@ -0,0 +1,81 @@
+package hcutil
+
+import (
+	"fmt"
+	"regexp"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Settings of a logical session, to be applied to every page the session uses. Protocol v1.2
+// has no per browser context knobs for them, so they have to be applied to each page
+// connection, including reused ones.
+type SessionConfig struct {
+	UserAgent    string
+	ExtraHeaders map[string]string
+	// Preferred languages, e.g. {"fr-CA", "fr", "en"}. See SetLanguage.
+	Languages []string
+	Cookies   []*protocol.SetCookieParams
+}
+
+// RFC 7230 token.
+var headerNameRegexp = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+func (s *SessionConfig) Validate() error {
+	for name, value := range s.ExtraHeaders {
+		if !headerNameRegexp.MatchString(name) {
+			return fmt.Errorf("Invalid header name '%s'", name)
+		}
+		for _, c := range value {
+			if c == '\r' || c == '\n' {
+				return fmt.Errorf("Invalid value of header '%s'", name)
+			}
+		}
+	}
+	if err := checkLanguages(s.Languages); err != nil {
+		return err
+	}
+	for _, cookie := range s.Cookies {
+		if cookie.Name == "" || cookie.Url == "" {
+			return fmt.Errorf("Cookies need names and URLs: %v", *cookie)
+		}
+	}
+	return nil
+}
+
+// Applies the config to the page, replacing extra headers and user agent set before. Call it
+// before navigating.
+func (s *SessionConfig) Apply(conn *hc.Conn) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if err := SetUserAgent(conn, s.UserAgent); err != nil {
+		return err
+	}
+	headers := protocol.Headers{}
+	for name, value := range s.ExtraHeaders {
+		headers[name] = value
+	}
+	if len(s.Languages) > 0 {
+		headers["Accept-Language"] = acceptLanguage(s.Languages)
+	}
+	if err := protocol.SetExtraHTTPHeaders(
+		&protocol.SetExtraHTTPHeadersParams{Headers: headers}, conn); err != nil {
+		return err
+	}
+	if len(s.Languages) > 0 {
+		if err := injectLanguages(conn, s.Languages); err != nil {
+			return err
+		}
+	}
+	for _, cookie := range s.Cookies {
+		result, err := protocol.SetCookie(cookie, conn)
+		if err != nil {
+			return err
+		} else if !result.Success {
+			return fmt.Errorf("Failed to set cookie '%s' for %s", cookie.Name, cookie.Url)
+		}
+	}
+	return nil
+}
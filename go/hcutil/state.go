@@ -0,0 +1,128 @@
+package hcutil
+
+import (
+	"fmt"
+	"sort"
+
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Names helpers of this package add their state with, see hc.Conn.AddStateDumper. Isolated
+// worlds and response waiters are suffixed with their name and an id, e.g.
+// "isolatedWorld:__hc_frames".
+const (
+	StateCorrelator     = "correlator"
+	StateIsolatedWorld  = "isolatedWorld:"
+	StateResponseWaiter = "responseWaiter:"
+)
+
+// What a Correlator knows, in hc.StateDump.Helpers.
+type CorrelatorState struct {
+	// Current loader of each frame.
+	Loaders map[protocol.FrameId]protocol.LoaderId `json:"loaders"`
+	// Loaders whose events are ignored, by frame.
+	Retired  map[protocol.FrameId][]protocol.LoaderId `json:"retired"`
+	Requests []*RequestState                          `json:"requests"`
+}
+
+// A request without its headers, sorted by time.
+type RequestState struct {
+	RequestId protocol.RequestId        `json:"requestId"`
+	FrameId   protocol.FrameId          `json:"frameId"`
+	LoaderId  protocol.LoaderId         `json:"loaderId"`
+	Type      protocol.ResourceType     `json:"type"`
+	URL       string                    `json:"url"`
+	Document  bool                      `json:"document"`
+	Timestamp protocol.NetworkTimestamp `json:"timestamp"`
+	Redirects int                       `json:"redirects"`
+	// 0 till the response is received.
+	Status   int    `json:"status"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+func (c *Correlator) state() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := &CorrelatorState{
+		Loaders: make(map[protocol.FrameId]protocol.LoaderId, len(c.loaderMap)),
+		Retired: make(map[protocol.FrameId][]protocol.LoaderId, len(c.retiredMap)),
+	}
+	for frameId, loaderId := range c.loaderMap {
+		state.Loaders[frameId] = loaderId
+	}
+	for frameId, loaderIds := range c.retiredMap {
+		state.Retired[frameId] = append([]protocol.LoaderId(nil), loaderIds...)
+	}
+	for _, record := range c.requestMap {
+		info := &record.info
+		request := &RequestState{
+			RequestId: info.RequestId,
+			FrameId:   info.FrameId,
+			LoaderId:  info.LoaderId,
+			Type:      info.Type,
+			URL:       info.URL,
+			Document:  info.Document,
+			Timestamp: info.Timestamp,
+			Redirects: len(info.Redirects),
+		}
+		if info.Response != nil {
+			request.Status = int(info.Response.Status)
+			request.MimeType = info.Response.MimeType
+		}
+		state.Requests = append(state.Requests, request)
+	}
+	sort.Slice(state.Requests, func(i, j int) bool {
+		return state.Requests[i].Timestamp < state.Requests[j].Timestamp
+	})
+	return state
+}
+
+// Execution contexts of an IsolatedWorld, in hc.StateDump.Helpers.
+type IsolatedWorldState struct {
+	Name     string                                           `json:"name"`
+	Contexts map[protocol.FrameId]protocol.ExecutionContextId `json:"contexts"`
+}
+
+func (w *IsolatedWorld) state() interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	state := &IsolatedWorldState{
+		Name:     w.name,
+		Contexts: make(map[protocol.FrameId]protocol.ExecutionContextId, len(w.contextMap)),
+	}
+	for frameId, contextId := range w.contextMap {
+		state.Contexts[frameId] = contextId
+	}
+	return state
+}
+
+// What a ResponseWaiter waits for, in hc.StateDump.Helpers.
+type ResponseWaiterState struct {
+	URL    string `json:"url"`
+	Method string `json:"method,omitempty"`
+	Status int    `json:"status,omitempty"`
+	N      int    `json:"n"`
+	// Requests seen but not done yet.
+	Pending []protocol.RequestId `json:"pending"`
+	// Responses matched, whose bodies are to be fetched.
+	Matched int `json:"matched"`
+}
+
+func (w *ResponseWaiter) state() interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	state := &ResponseWaiterState{
+		URL: w.match.URL, Method: w.match.Method, Status: w.match.Status, N: w.n,
+		Matched: len(w.finished),
+	}
+	for id := range w.pending {
+		state.Pending = append(state.Pending, id)
+	}
+	sort.Slice(state.Pending, func(i, j int) bool { return state.Pending[i] < state.Pending[j] })
+	return state
+}
+
+// Suffix of state names of helpers which may exist several times on a connection.
+func stateId(name string, helper interface{}) string {
+	return fmt.Sprintf("%s@%p", name, helper)
+}
@@ -0,0 +1,331 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/bytebudget"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// A chunk of data, from Network.dataReceived, or an EventSource message, from
+// Network.eventSourceMessageReceived.
+type StreamChunk struct {
+	Timestamp protocol.NetworkTimestamp
+	// Set for data chunks.
+	DataLength        int
+	EncodedDataLength int
+	// Set for EventSource messages.
+	Message   bool
+	EventName string
+	EventId   string
+	Data      string
+	// Data was dropped to stay within StreamMonitorOptions.Budget.
+	DataDropped bool
+
+	// Holds Data while the chunk is kept by a monitor with a budget.
+	blob *bytebudget.Blob
+}
+
+// A request of the page and the data it has received so far.
+type Stream struct {
+	RequestId protocol.RequestId
+	URL       string
+	// Timestamps of Network.requestWillBeSent and Network.responseReceived. 0 if not yet.
+	RequestTime  protocol.NetworkTimestamp
+	ResponseTime protocol.NetworkTimestamp
+	// Timestamp of the first chunk. 0 if not yet.
+	FirstChunkTime protocol.NetworkTimestamp
+	// The most recent chunks, in order.
+	Chunks []StreamChunk
+	// Number of chunks dropped to keep Chunks bounded.
+	Dropped int
+	// Sums over all chunks, including the dropped ones.
+	DataLength        int
+	EncodedDataLength int
+	Finished          bool
+	// Set if loading failed.
+	ErrorText string
+}
+
+// Returns the time from sending the request to receiving the first chunk, in seconds. Returns -1
+// if either is unknown.
+func (s *Stream) TimeToFirstByte() float64 {
+	if s.RequestTime == 0 || s.FirstChunkTime == 0 {
+		return -1
+	}
+	return float64(s.FirstChunkTime - s.RequestTime)
+}
+
+// Returns the timestamp of the most recent activity on the stream.
+func (s *Stream) LastActivity() protocol.NetworkTimestamp {
+	last := s.RequestTime
+	if s.ResponseTime > last {
+		last = s.ResponseTime
+	}
+	if n := len(s.Chunks); n > 0 && s.Chunks[n-1].Timestamp > last {
+		last = s.Chunks[n-1].Timestamp
+	}
+	return last
+}
+
+type StreamMonitorOptions struct {
+	// Only monitor requests whose URLs match.
+	URLPattern *regexp.Regexp
+	// Max chunks kept per request. Defaults to 1000.
+	MaxChunks int
+	// Called with every chunk. It may be called concurrently.
+	OnChunk func(requestId protocol.RequestId, chunk *StreamChunk)
+	// If set, data of kept EventSource messages is accounted to it, and spilled or dropped when
+	// it runs out. Call StreamMonitor.Release when done with them.
+	Budget *bytebudget.Budget
+	// Bytes of Budget reserved for the monitor.
+	BudgetReservation int64
+}
+
+// Records incremental data of the page's requests sent after it starts, e.g. Server-Sent Events
+// or fetch streaming, whose bodies GetResponseBody can't return until they end.
+type StreamMonitor struct {
+	opts    StreamMonitorOptions
+	conn    *hc.Conn
+	cancels []func()
+	// Network domain is acquired till Stop.
+	acquired bool
+	stopOnce sync.Once
+	// nil without a budget.
+	account *bytebudget.Account
+
+	mu      sync.Mutex
+	streams map[protocol.RequestId]*Stream
+}
+
+// Starts monitoring. Network domain is enabled till Stop, unless other helpers need it, see
+// hc.Conn.AcquireDomain.
+func NewStreamMonitor(conn *hc.Conn, opts StreamMonitorOptions) (*StreamMonitor, error) {
+	if opts.MaxChunks <= 0 {
+		opts.MaxChunks = 1000
+	}
+	m := &StreamMonitor{conn: conn, opts: opts, streams: make(map[protocol.RequestId]*Stream)}
+	if opts.Budget != nil {
+		var err error
+		if m.account, err = opts.Budget.Register("stream", opts.BudgetReservation); err != nil {
+			return nil, err
+		}
+	}
+	m.cancels = []func(){
+		listen(conn, "Network.requestWillBeSent", func(params []byte) {
+			var evt protocol.RequestWillBeSentEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.requestWillBeSent", params, err)
+				return
+			}
+			if evt.Request == nil ||
+				(opts.URLPattern != nil && !opts.URLPattern.MatchString(evt.Request.Url)) {
+				return
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if s := m.streams[evt.RequestId]; s != nil {
+				// Redirected.
+				s.URL = evt.Request.Url
+				return
+			}
+			m.streams[evt.RequestId] = &Stream{
+				RequestId: evt.RequestId, URL: evt.Request.Url, RequestTime: evt.Timestamp}
+		}),
+		listen(conn, "Network.responseReceived", func(params []byte) {
+			var evt protocol.ResponseReceivedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.responseReceived", params, err)
+				return
+			}
+			m.update(evt.RequestId, func(s *Stream) { s.ResponseTime = evt.Timestamp })
+		}),
+		listen(conn, "Network.dataReceived", func(params []byte) {
+			var evt protocol.DataReceivedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.dataReceived", params, err)
+				return
+			}
+			m.addChunk(evt.RequestId, StreamChunk{
+				Timestamp:         evt.Timestamp,
+				DataLength:        evt.DataLength,
+				EncodedDataLength: evt.EncodedDataLength,
+			})
+		}),
+		listen(conn, "Network.eventSourceMessageReceived", func(params []byte) {
+			var evt protocol.EventSourceMessageReceivedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.eventSourceMessageReceived", params, err)
+				return
+			}
+			m.addChunk(evt.RequestId, StreamChunk{
+				Timestamp: evt.Timestamp,
+				Message:   true,
+				EventName: evt.EventName,
+				EventId:   evt.EventId,
+				Data:      evt.Data,
+			})
+		}),
+		listen(conn, "Network.loadingFinished", func(params []byte) {
+			var evt protocol.LoadingFinishedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.loadingFinished", params, err)
+				return
+			}
+			m.update(evt.RequestId, func(s *Stream) { s.Finished = true })
+		}),
+		listen(conn, "Network.loadingFailed", func(params []byte) {
+			var evt protocol.LoadingFailedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.loadingFailed", params, err)
+				return
+			}
+			m.update(evt.RequestId, func(s *Stream) {
+				s.Finished = true
+				s.ErrorText = evt.ErrorText
+			})
+		}),
+	}
+	if err := conn.AcquireDomain("Network", nil); err != nil {
+		m.Stop()
+		m.Release()
+		return nil, err
+	}
+	m.acquired = true
+	return m, nil
+}
+
+func (m *StreamMonitor) update(requestId protocol.RequestId, f func(s *Stream)) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.streams[requestId]
+	if s == nil {
+		return false
+	}
+	f(s)
+	return true
+}
+
+func (m *StreamMonitor) addChunk(requestId protocol.RequestId, chunk StreamChunk) {
+	if !m.update(requestId, func(s *Stream) {
+		s.DataLength += chunk.DataLength
+		s.EncodedDataLength += chunk.EncodedDataLength
+		if s.FirstChunkTime == 0 || chunk.Timestamp < s.FirstChunkTime {
+			s.FirstChunkTime = chunk.Timestamp
+		}
+		kept := chunk
+		if m.account != nil && chunk.Message {
+			kept.Data = ""
+			var err error
+			if kept.blob, err = m.account.Store([]byte(chunk.Data)); err != nil {
+				logging.Vlogf(2, "Dropped a message of %s: %v", s.URL, err)
+				kept.DataDropped = true
+			}
+		}
+		// Events may come out of order.
+		i := sort.Search(len(s.Chunks), func(i int) bool {
+			return s.Chunks[i].Timestamp > chunk.Timestamp
+		})
+		s.Chunks = append(s.Chunks, StreamChunk{})
+		copy(s.Chunks[i+1:], s.Chunks[i:])
+		s.Chunks[i] = kept
+		if over := len(s.Chunks) - m.opts.MaxChunks; over > 0 {
+			for _, dropped := range s.Chunks[:over] {
+				if dropped.blob != nil {
+					dropped.blob.Release()
+				}
+			}
+			s.Chunks = append([]StreamChunk(nil), s.Chunks[over:]...)
+			s.Dropped += over
+		}
+	}) {
+		return
+	}
+	if m.opts.OnChunk != nil {
+		m.opts.OnChunk(requestId, &chunk)
+	}
+}
+
+// Returns a snapshot of the request's stream, or nil if it isn't monitored.
+func (m *StreamMonitor) Stream(requestId protocol.RequestId) *Stream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.streams[requestId]; s != nil {
+		return s.snapshot()
+	}
+	return nil
+}
+
+// Returns snapshots of all monitored requests.
+func (m *StreamMonitor) Streams() []*Stream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	streams := make([]*Stream, 0, len(m.streams))
+	for _, s := range m.streams {
+		streams = append(streams, s.snapshot())
+	}
+	return streams
+}
+
+// Returns the unfinished requests without activity since the timestamp, e.g. stalled streams.
+// Timestamps are those of the browser; see LastActivity of the most active stream for "now".
+func (m *StreamMonitor) Stalled(since protocol.NetworkTimestamp) []*Stream {
+	var stalled []*Stream
+	for _, s := range m.Streams() {
+		if !s.Finished && s.LastActivity() < since {
+			stalled = append(stalled, s)
+		}
+	}
+	return stalled
+}
+
+// Data held by a budget is loaded, from spill files if spilled.
+func (s *Stream) snapshot() *Stream {
+	copied := *s
+	copied.Chunks = append([]StreamChunk(nil), s.Chunks...)
+	for i := range copied.Chunks {
+		chunk := &copied.Chunks[i]
+		if chunk.blob == nil {
+			continue
+		}
+		if data, err := chunk.blob.Bytes(); err != nil {
+			logging.Vlogf(1, "Failed to load a message of %s: %v", s.URL, err)
+			chunk.DataDropped = true
+		} else {
+			chunk.Data = string(data)
+		}
+		chunk.blob = nil
+	}
+	return &copied
+}
+
+// Stops recording. Data recorded so far is kept.
+func (m *StreamMonitor) Stop() {
+	m.stopOnce.Do(func() {
+		for _, cancel := range m.cancels {
+			cancel()
+		}
+		if m.acquired {
+			if err := m.conn.ReleaseDomain("Network"); err != nil {
+				logging.Vlog(1, err)
+			}
+		}
+	})
+}
+
+// Forgets the recorded streams, and returns their memory to StreamMonitorOptions.Budget. Call
+// Stop first.
+func (m *StreamMonitor) Release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streams = make(map[protocol.RequestId]*Stream)
+	if m.account != nil {
+		m.account.Close()
+	}
+}
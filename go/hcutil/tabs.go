@@ -0,0 +1,212 @@
+package hcutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// A page with its connection, for scenarios spanning several pages, e.g. following a link which
+// opens in a new tab, scraping it, and going back. See ExpectNewTab.
+type Tab struct {
+	Browser *hc.Browser
+	// Used to watch and close targets. It may be shared by tabs, and isn't closed by Close.
+	BrowserConn *hc.Conn
+	TargetId    protocol.TargetID
+	Conn        *hc.Conn
+	// The tab which opened this one, activated again when this one is closed. Nil for tabs
+	// created by AttachTab.
+	Opener *Tab
+
+	stopWatch func()
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Connects to page targetId. The connection is closed with hc.TargetClosedError once the page
+// is closed, see WatchTarget.
+func AttachTab(browser *hc.Browser, browserConn *hc.Conn, targetId protocol.TargetID) (
+	*Tab, error) {
+	conn, err := browser.NewPageConn(string(targetId))
+	if err != nil {
+		return nil, err
+	}
+	stop, err := WatchTarget(browserConn, targetId, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Tab{
+		Browser: browser, BrowserConn: browserConn, TargetId: targetId, Conn: conn,
+		stopWatch: stop,
+	}, nil
+}
+
+// Returned by ExpectNewTab when no new page showed up, e.g. because the browser blocked the
+// popup, or the trigger opened something else.
+type NewTabTimeoutError struct {
+	Timeout time.Duration
+	// Targets created meanwhile, whatever their type.
+	Targets []protocol.TargetInfo
+}
+
+func (e *NewTabTimeoutError) Error() string {
+	if len(e.Targets) == 0 {
+		return fmt.Sprintf("No new page within %v, and no other targets either. Was the popup "+
+			"blocked?", e.Timeout)
+	}
+	seen := make([]string, len(e.Targets))
+	for i, target := range e.Targets {
+		seen[i] = fmt.Sprintf("%s %s", target.Type, target.Url)
+	}
+	return fmt.Sprintf("No new page within %v. Targets seen: %s", e.Timeout,
+		strings.Join(seen, ", "))
+}
+
+// Target.targetCreated of newer protocols, which tell which target opened the new one.
+type targetCreatedEvent struct {
+	TargetInfo *struct {
+		protocol.TargetInfo
+		OpenerId protocol.TargetID `json:"openerId"`
+	} `json:"targetInfo"`
+}
+
+// How long ExpectNewTab waits for events of existing targets, which the browser reports when
+// discovery is enabled.
+const discoverFlushTimeout = 5 * time.Second
+
+// Runs trigger, e.g. a click on a target="_blank" link or a window.open, and returns the page it
+// opened once loaded, i.e. its document is complete and no longer about:blank. Pages opened by
+// other tabs are ignored, if the browser reports openers. Fails with NewTabTimeoutError if no
+// page shows up within timeout, and ErrTimeout if it doesn't load in time, in which case it's
+// closed. Target discovery is enabled on BrowserConn as a side effect.
+func (t *Tab) ExpectNewTab(trigger func() error, timeout time.Duration) (*Tab, error) {
+	deadline := time.Now().Add(timeout)
+	var mu sync.Mutex
+	armed := false
+	var seen []protocol.TargetInfo
+	newPage := make(chan protocol.TargetID, 1)
+	cancel := listen(t.BrowserConn, "Target.targetCreated", func(params []byte) {
+		var evt targetCreatedEvent
+		if err := json.Unmarshal(params, &evt); err != nil {
+			t.BrowserConn.ReportEventError("Target.targetCreated", params, err)
+			return
+		}
+		info := evt.TargetInfo
+		mu.Lock()
+		defer mu.Unlock()
+		if info == nil || !armed {
+			return
+		}
+		seen = append(seen, info.TargetInfo)
+		opened := info.OpenerId == "" || info.OpenerId == t.TargetId
+		if info.Type == string(hc.KindPage) && opened {
+			select {
+			case newPage <- info.TargetId:
+			default:
+			}
+		}
+	})
+	defer cancel()
+	if err := protocol.SetDiscoverTargets(
+		&protocol.SetDiscoverTargetsParams{Discover: true}, t.BrowserConn); err != nil {
+		return nil, err
+	}
+	ctx, cancelFlush := context.WithTimeout(context.Background(), discoverFlushTimeout)
+	defer cancelFlush()
+	if err := t.BrowserConn.Flush(ctx); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	armed = true
+	mu.Unlock()
+
+	if err := trigger(); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	var targetId protocol.TargetID
+	select {
+	case targetId = <-newPage:
+	case <-timer.C:
+		mu.Lock()
+		defer mu.Unlock()
+		return nil, &NewTabTimeoutError{Timeout: timeout, Targets: seen}
+	case <-t.BrowserConn.Closed():
+		return nil, t.BrowserConn.Err()
+	}
+
+	tab, err := AttachTab(t.Browser, t.BrowserConn, targetId)
+	if err != nil {
+		return nil, err
+	}
+	tab.Opener = t
+	if err := waitTabLoaded(tab.Conn, deadline); err != nil {
+		if err := tab.Close(); err != nil {
+			logging.Vlog(1, err)
+		}
+		return nil, err
+	}
+	return tab, nil
+}
+
+// Polls the document of a page attached to after it started loading, whose load events may have
+// been missed.
+func waitTabLoaded(conn *hc.Conn, deadline time.Time) error {
+	for {
+		var loaded bool
+		err := evaluate(conn,
+			`document.readyState == "complete" && location.href != "about:blank"`, &loaded, nil)
+		if err != nil {
+			// The document may be being replaced, e.g. after about:blank.
+			logging.Vlog(2, err)
+		} else if loaded {
+			return nil
+		}
+		if time.Now().Add(conditionPollInterval).After(deadline) {
+			return ErrTimeout
+		}
+		select {
+		case <-time.After(conditionPollInterval):
+		case <-conn.Closed():
+			return conn.Err()
+		}
+	}
+}
+
+// Closes the page and its connection, and activates the opener, if any, so that it's in the
+// foreground again, e.g. for requestAnimationFrame. The opener stays usable, as it has its own
+// connection. It's safe to call this multiple times.
+func (t *Tab) Close() error {
+	t.closeOnce.Do(func() {
+		t.stopWatch()
+		t.Conn.Close()
+		result, err := protocol.CloseTarget(
+			&protocol.CloseTargetParams{TargetId: t.TargetId}, t.BrowserConn)
+		if err != nil {
+			t.closeErr = err
+			return
+		} else if !result.Success {
+			logging.Vlogf(1, "Target %s was closed already", t.TargetId)
+		}
+		if t.Opener == nil {
+			return
+		}
+		select {
+		case <-t.Opener.Conn.Closed():
+		default:
+			t.closeErr = protocol.ActivateTarget(
+				&protocol.ActivateTargetParams{TargetId: t.Opener.TargetId}, t.BrowserConn)
+		}
+	})
+	return t.closeErr
+}
@@ -0,0 +1,190 @@
+package hcutil
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type TextOptions struct {
+	EvalOptions
+	// Append the URL of links to their text, like "text (https://...)".
+	IncludeHrefs bool
+	// Only extract the text of the first element matching this CSS selector.
+	Selector string
+}
+
+// Walks the rendered tree, including open shadow roots and same-origin iframes. Blocks are
+// separated by newlines, paragraphs and headings by empty lines.
+const extractTextFunction = `(function(selector, includeHrefs) {
+	var skipped = {SCRIPT: 1, STYLE: 1, NOSCRIPT: 1, TEMPLATE: 1, HEAD: 1};
+	var paragraphs = {P: 1, H1: 1, H2: 1, H3: 1, H4: 1, H5: 1, H6: 1, BLOCKQUOTE: 1, PRE: 1};
+	var out = [];
+	function walkChildren(node) {
+		for (var child = node.firstChild; child; child = child.nextSibling) walk(child);
+	}
+	function walk(node) {
+		if (node.nodeType === Node.TEXT_NODE) {
+			out.push(node.nodeValue);
+			return;
+		}
+		if (node.nodeType !== Node.ELEMENT_NODE || skipped[node.tagName]) return;
+		var style = node.ownerDocument.defaultView.getComputedStyle(node);
+		if (style.display === "none" || style.visibility === "hidden") return;
+		var sep = paragraphs[node.tagName] ? "\n\n" : style.display.indexOf("inline") === 0 ? "" : "\n";
+		out.push(sep);
+		if (node.tagName === "BR") {
+			out.push("\n");
+		} else if (node.tagName === "IMG") {
+			if (node.alt) out.push(" " + node.alt + " ");
+		} else if (node.tagName === "IFRAME") {
+			var doc = null;
+			try { doc = node.contentDocument; } catch (e) {}
+			if (doc && doc.body) {
+				walk(doc.body);
+			} else {
+				out.push("[iframe " + node.src + "]");
+			}
+		} else if (node.tagName === "SLOT") {
+			var assigned = node.assignedNodes();
+			if (assigned.length) assigned.forEach(walk); else walkChildren(node);
+		} else {
+			walkChildren(node.shadowRoot || node);
+		}
+		if (includeHrefs && node.tagName === "A" && node.href) out.push(" (" + node.href + ")");
+		out.push(sep);
+	}
+	var root = selector ? document.querySelector(selector) : document.body;
+	if (!root) throw new Error("No element matches " + selector);
+	walk(root);
+	return out.join("");
+})`
+
+var blankRe = regexp.MustCompile(`[ \t\r\f\v\xa0]+`)
+var newlinesRe = regexp.MustCompile(`\n{3,}`)
+
+// Tidies up whitespace: collapses spaces, trims lines, and keeps at most one empty line.
+func normalizeText(text string) string {
+	lines := strings.Split(blankRe.ReplaceAllString(text, " "), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(newlinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// Returns the readable text of the page, e.g. for NLP: scripts, styles and hidden elements are
+// dropped, and alt text of images is included. Falls back to DOM commands if JavaScript is
+// disabled by DisableJavaScript, in which case visibility can't be told.
+func ExtractText(conn *hc.Conn, opts *TextOptions) (string, error) {
+	if opts == nil {
+		opts = &TextOptions{}
+	}
+	if JavaScriptDisabled(conn) {
+		return extractTextWithDOM(conn, opts)
+	}
+	var text string
+	if err := evaluate(conn, fmt.Sprintf("%s(%s, %v)", extractTextFunction,
+		jsbuilder.JSString(opts.Selector), opts.IncludeHrefs), &text,
+		&opts.EvalOptions); err != nil {
+		return "", err
+	}
+	return normalizeText(text), nil
+}
+
+var blockTags = map[string]bool{
+	"ADDRESS": true, "ARTICLE": true, "ASIDE": true, "BLOCKQUOTE": true, "DD": true, "DIV": true,
+	"DL": true, "DT": true, "FIGCAPTION": true, "FIGURE": true, "FOOTER": true, "FORM": true,
+	"H1": true, "H2": true, "H3": true, "H4": true, "H5": true, "H6": true, "HEADER": true,
+	"HR": true, "LI": true, "MAIN": true, "NAV": true, "OL": true, "P": true, "PRE": true,
+	"SECTION": true, "TABLE": true, "TR": true, "UL": true, "BR": true,
+}
+
+var skippedTags = map[string]bool{
+	"SCRIPT": true, "STYLE": true, "NOSCRIPT": true, "TEMPLATE": true, "HEAD": true,
+}
+
+func extractTextWithDOM(conn *hc.Conn, opts *TextOptions) (string, error) {
+	doc, err := protocol.GetDocument(&protocol.GetDocumentParams{Depth: -1, Pierce: true}, conn)
+	if err != nil {
+		return "", err
+	}
+	root := doc.Root
+	if opts.Selector != "" {
+		result, err := protocol.QuerySelector(&protocol.QuerySelectorParams{
+			NodeId: doc.Root.NodeId, Selector: opts.Selector}, conn)
+		if err != nil {
+			return "", err
+		}
+		if root = findNode(doc.Root, result.NodeId); root == nil {
+			return "", fmt.Errorf("No element matches %s", opts.Selector)
+		}
+	}
+	var buf bytes.Buffer
+	var walk func(node *protocol.Node)
+	walk = func(node *protocol.Node) {
+		switch node.NodeType {
+		case 3: // Text.
+			buf.WriteString(node.NodeValue)
+			return
+		case 1, 9, 11: // Element, document and document fragment, e.g. shadow root.
+		default:
+			return
+		}
+		if skippedTags[node.NodeName] {
+			return
+		}
+		if blockTags[node.NodeName] {
+			buf.WriteString("\n")
+		}
+		if node.NodeName == "IMG" {
+			if alt := nodeAttributes(conn, node)["alt"]; alt != "" {
+				buf.WriteString(" " + alt + " ")
+			}
+		}
+		if node.ContentDocument != nil {
+			walk(node.ContentDocument)
+		}
+		// Children of shadow hosts are only rendered where slotted, which the DOM doesn't
+		// tell, so only the shadow roots are walked, like the script does.
+		for _, shadowRoot := range node.ShadowRoots {
+			walk(shadowRoot)
+		}
+		if len(node.ShadowRoots) == 0 {
+			for _, child := range node.Children {
+				walk(child)
+			}
+		}
+		if opts.IncludeHrefs && node.NodeName == "A" {
+			if href := nodeAttributes(conn, node)["href"]; href != "" {
+				buf.WriteString(" (" + href + ")")
+			}
+		}
+		if blockTags[node.NodeName] {
+			buf.WriteString("\n")
+		}
+	}
+	walk(root)
+	return normalizeText(buf.String()), nil
+}
+
+func findNode(node *protocol.Node, nodeId protocol.NodeId) *protocol.Node {
+	if node.NodeId == nodeId {
+		return node
+	}
+	for _, children := range [][]*protocol.Node{node.Children, node.ShadowRoots} {
+		for _, child := range children {
+			if found := findNode(child, nodeId); found != nil {
+				return found
+			}
+		}
+	}
+	if node.ContentDocument != nil {
+		return findNode(node.ContentDocument, nodeId)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package hcutil_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+var updateFlag = flag.Bool("update", false, "Rewrite the golden files under testdata.")
+
+const textDir = "testdata/text"
+
+// The document of hctest.FixtureText as DOM.getDocument returns it with pierce, and the node id
+// of its #host.
+const (
+	textDocumentFile = "document.json"
+	textHostNodeId   = 42
+)
+
+// Compares text with the golden file name under testdata/text, or rewrites it with -update.
+func checkTextGolden(t *testing.T, name, text string) {
+	t.Helper()
+	path := filepath.Join(textDir, name)
+	if *updateFlag {
+		if err := ioutil.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text+"\n" != string(want) {
+		t.Errorf("%s differs from the golden file. Run with -update if that's expected, "+
+			"got:\n%s", name, text)
+	}
+}
+
+// Without JavaScript, text is extracted from the DOM, including shadow roots and iframes, but
+// hidden elements can't be told.
+func TestExtractTextWithDOM(t *testing.T) {
+	document, err := ioutil.ReadFile(filepath.Join(textDir, textDocumentFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := hctest.NewFakeServer(t)
+	server.Handle("DOM.getDocument", hctest.FakeResult(json.RawMessage(document)))
+	server.Handle("DOM.querySelector", hctest.FakeResult(map[string]int{
+		"nodeId": textHostNodeId}))
+	conn, _ := server.NewPageConn()
+	if err := hcutil.DisableJavaScript(conn); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		golden string
+		opts   *hcutil.TextOptions
+	}{
+		{"dom.txt", nil},
+		{"dom_hrefs.txt", &hcutil.TextOptions{IncludeHrefs: true}},
+		{"dom_selector.txt", &hcutil.TextOptions{Selector: "#host"}},
+	} {
+		text, err := hcutil.ExtractText(conn, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkTextGolden(t, c.golden, text)
+	}
+}
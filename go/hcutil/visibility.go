@@ -0,0 +1,149 @@
+package hcutil
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// States of SetVisibilityState.
+const (
+	// Visible and focused.
+	VisibilityVisible = "visible"
+	// Hidden and not focused, like a background tab.
+	VisibilityHidden = "hidden"
+	// Hidden, with timers and tasks of the page stopped, like a discarded background tab. Needs
+	// a browser newer than protocol v1.2, with Page.setWebLifecycleState.
+	VisibilityFrozen = "frozen"
+)
+
+type visibility struct {
+	mu       sync.Mutex
+	scriptId protocol.ScriptIdentifier
+}
+
+type visibilityKey struct{}
+
+func getVisibility(conn *hc.Conn) *visibility {
+	return conn.Value(visibilityKey{}, func() interface{} {
+		return &visibility{}
+	}).(*visibility)
+}
+
+// Overrides document.visibilityState, document.hidden and document.hasFocus(), which the
+// page's scripts may check before playing videos, rotating carousels or sending analytics.
+const visibilityScript = `(function(state, dispatch) {
+	var hidden = state !== "visible";
+	var changed = document.visibilityState !== state;
+	Object.defineProperty(Document.prototype, "visibilityState", {
+		configurable: true, get: function() { return state; }});
+	Object.defineProperty(Document.prototype, "hidden", {
+		configurable: true, get: function() { return hidden; }});
+	Document.prototype.hasFocus = function() { return !hidden; };
+	if (dispatch && changed) {
+		document.dispatchEvent(new Event("visibilitychange"));
+		window.dispatchEvent(new Event(hidden ? "blur" : "focus"));
+	}
+})(%s, %v)`
+
+// Makes the page visible or hidden to its scripts, see VisibilityVisible etc. Headless pages
+// may report themselves hidden, and pages pausing work when hidden then behave differently
+// than in real browsers.
+//
+// Focus is emulated by Emulation.setFocusEmulationEnabled, and VisibilityFrozen freezes the
+// page by Page.setWebLifecycleState, in browsers which have them. Either way, a script injected
+// into every new document overrides visibilityState, hidden and hasFocus() before the page's
+// scripts run, and the current document gets them too, with a visibilitychange event if the
+// state changed. Empty state stops injecting the script and emulating focus, which takes effect
+// on the next navigation.
+func SetVisibilityState(conn *hc.Conn, state string) error {
+	switch state {
+	case "", VisibilityVisible, VisibilityHidden, VisibilityFrozen:
+	default:
+		return fmt.Errorf("Unknown visibility state '%s'", state)
+	}
+	v := getVisibility(conn)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	lifecycleState := "active"
+	if state == VisibilityFrozen {
+		lifecycleState = "frozen"
+	}
+	if err := sendOptional(conn, "Page.setWebLifecycleState",
+		map[string]interface{}{"state": lifecycleState}); err != nil {
+		if state == VisibilityFrozen || !hc.IsMethodNotFound(err) {
+			return err
+		}
+	}
+	if err := sendOptional(conn, "Emulation.setFocusEmulationEnabled",
+		map[string]interface{}{"enabled": state == VisibilityVisible}); err != nil &&
+		!hc.IsMethodNotFound(err) {
+		return err
+	}
+
+	if v.scriptId != "" {
+		if err := RemoveInjected(conn, v.scriptId); err != nil {
+			return err
+		}
+		v.scriptId = ""
+	}
+	// Pages without JavaScript can't tell.
+	if state == "" || JavaScriptDisabled(conn) {
+		return nil
+	}
+	documentState := state
+	if state == VisibilityFrozen {
+		documentState = VisibilityHidden
+	}
+	value, err := jsbuilder.JSValue(documentState)
+	if err != nil {
+		return err
+	}
+	v.scriptId, err = InjectOnNewDocument(conn, fmt.Sprintf(visibilityScript, value, false))
+	if err != nil {
+		return err
+	}
+	if state == VisibilityFrozen {
+		// The frozen page wouldn't run it.
+		return nil
+	}
+	return Evaluate(conn, fmt.Sprintf(visibilityScript, value, true), nil)
+}
+
+// Overrides the user idle state reported to the page's IdleDetector. isScreenUnlocked false
+// means the screen is locked. Needs a browser newer than protocol v1.2, with
+// Emulation.setIdleOverride, and there is no fallback, as IdleDetector needs a permission the
+// page rarely has anyway.
+func SetUserIdle(conn *hc.Conn, isIdle, isScreenUnlocked bool) error {
+	return sendOptional(conn, "Emulation.setIdleOverride", map[string]interface{}{
+		"isUserActive":     !isIdle,
+		"isScreenUnlocked": isScreenUnlocked,
+	})
+}
+
+// Stops overriding the user idle state set by SetUserIdle.
+func ClearUserIdle(conn *hc.Conn) error {
+	return sendOptional(conn, "Emulation.clearIdleOverride", nil)
+}
+
+// Sends a command protocol v1.2 doesn't have. Browsers without it fail with
+// hc.CodeMethodNotFound.
+func sendOptional(conn *hc.Conn, method string, params map[string]interface{}) error {
+	err := sendWithTimeout(conn, capabilityTimeout, func(cb func(error)) hc.Command {
+		cmd := &rawCommand{name: method, cb: cb}
+		if params != nil {
+			cmd.params = params
+		}
+		return cmd
+	})
+	if err != nil {
+		logging.Vlogf(2, "%s failed: %v", method, err)
+	}
+	return err
+}
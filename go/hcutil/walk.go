@@ -0,0 +1,84 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+const childNodesTimeout = 10 * time.Second
+
+// Walks the DOM tree depth first, without fetching it as a whole. visit is called with every
+// node; return false to skip its subtree, e.g. <svg> or hidden containers. Nodes are fetched
+// maxDepthPerRequest levels at a time, and dropped once visited, so memory stays bounded even on
+// pages with lots of nodes. Children of the nodes passed to visit are not populated reliably.
+func WalkDocument(conn *hc.Conn, maxDepthPerRequest int, visit func(node *protocol.Node) bool) error {
+	if maxDepthPerRequest <= 0 {
+		maxDepthPerRequest = 1
+	}
+	var mu sync.Mutex
+	waiting := make(map[protocol.NodeId]chan []*protocol.Node)
+	cancel := listen(conn, "DOM.setChildNodes", func(params []byte) {
+		var evt protocol.SetChildNodesEvent
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("DOM.setChildNodes", params, err)
+			return
+		}
+		mu.Lock()
+		ch := waiting[evt.ParentId]
+		delete(waiting, evt.ParentId)
+		mu.Unlock()
+		if ch != nil {
+			ch <- evt.Nodes
+		}
+	})
+	defer cancel()
+
+	result, err := protocol.GetDocument(&protocol.GetDocumentParams{Depth: maxDepthPerRequest}, conn)
+	if err != nil {
+		return err
+	}
+	stack := []*protocol.Node{result.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		children := node.Children
+		node.Children = nil
+		if !visit(node) {
+			continue
+		}
+		if children == nil && node.ChildNodeCount > 0 {
+			// At the frontier of what we have fetched.
+			ch := make(chan []*protocol.Node, 1)
+			mu.Lock()
+			waiting[node.NodeId] = ch
+			mu.Unlock()
+			if err := protocol.RequestChildNodes(&protocol.RequestChildNodesParams{
+				NodeId: node.NodeId, Depth: maxDepthPerRequest}, conn); err != nil {
+				return err
+			}
+			select {
+			case children = <-ch:
+			case <-time.After(childNodesTimeout):
+				mu.Lock()
+				delete(waiting, node.NodeId)
+				mu.Unlock()
+				// The node may have been removed from the document meanwhile.
+				logging.Vlogf(1, "No children of node %d arrived.", node.NodeId)
+				continue
+			case <-conn.Closed():
+				return conn.Err()
+			}
+		}
+		// Push in reverse order, so children are visited in document order.
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package hcutil
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// What the watchdog does to recover a hung page, in the order given by WatchdogOptions.Steps.
+type WatchdogStep int
+
+const (
+	// Runtime.terminateExecution. Protocol v1.2 doesn't have it, but newer browsers do.
+	StepTerminateExecution WatchdogStep = iota
+	StepStopLoading
+	// Closes the target and creates a new blank one in its place. See WatchdogOptions.OnRecreated.
+	StepRecreateTarget
+)
+
+func (s WatchdogStep) String() string {
+	switch s {
+	case StepTerminateExecution:
+		return "terminateExecution"
+	case StepStopLoading:
+		return "stopLoading"
+	case StepRecreateTarget:
+		return "recreateTarget"
+	}
+	return "unknown"
+}
+
+type WatchdogOptions struct {
+	// How often to probe the page. Defaults to 5 seconds.
+	Interval time.Duration
+	// How long a probe or an escalation step may take. Defaults to 2 seconds.
+	Timeout time.Duration
+	// Number of failed probes in a row before escalating. Defaults to 3.
+	MaxFailures int
+	// Defaults to all steps.
+	Steps []WatchdogStep
+	// The browser context to recreate the target in.
+	BrowserContextId protocol.BrowserContextID
+	// Called when the page is considered hung.
+	OnHung func()
+	// Called after each escalation step, with whether the page responds again afterwards.
+	OnStep func(step WatchdogStep, recovered bool, err error)
+	// Called with the connection to the new target. The old connection is closed.
+	OnRecreated func(targetId protocol.TargetID, conn *hc.Conn)
+}
+
+// Watches a page and recovers it when it stops responding, e.g. running "while(true){}". If the
+// target goes away instead, i.e. the connection fails with hc.TargetClosedError, it's recreated
+// right away when StepRecreateTarget is among the steps.
+type Watchdog struct {
+	browser     *hc.Browser
+	browserConn *hc.Conn
+	opts        WatchdogOptions
+	done        chan struct{}
+	stopOnce    sync.Once
+
+	mu       sync.Mutex
+	targetId protocol.TargetID
+	conn     *hc.Conn
+}
+
+// Starts watching the target, to which pageConn is connected. browserConn is used to recreate
+// the target.
+func StartWatchdog(browser *hc.Browser, browserConn *hc.Conn, targetId protocol.TargetID,
+	pageConn *hc.Conn, opts WatchdogOptions) *Watchdog {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = 3
+	}
+	if opts.Steps == nil {
+		opts.Steps = []WatchdogStep{StepTerminateExecution, StepStopLoading, StepRecreateTarget}
+	}
+	w := &Watchdog{
+		browser:     browser,
+		browserConn: browserConn,
+		opts:        opts,
+		done:        make(chan struct{}),
+		targetId:    targetId,
+		conn:        pageConn,
+	}
+	go w.run()
+	return w
+}
+
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+// Returns the current target and the connection to it, which change after StepRecreateTarget.
+func (w *Watchdog) Target() (protocol.TargetID, *hc.Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.targetId, w.conn
+}
+
+func (w *Watchdog) run() {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+		_, conn := w.Target()
+		if err := probe(conn, w.opts.Timeout); err == nil {
+			failures = 0
+			continue
+		} else if _, ok := conn.Err().(*hc.TargetClosedError); ok {
+			// The tab is gone, so replace it right away rather than escalating.
+			if w.hasStep(StepRecreateTarget) {
+				err := w.recreateTarget()
+				logging.Vlogf(1, "Recreated closed target: err=%v", err)
+				if w.opts.OnStep != nil {
+					w.opts.OnStep(StepRecreateTarget, err == nil, err)
+				}
+				if err == nil {
+					failures = 0
+					continue
+				}
+			}
+			return
+		} else if err == hc.ErrConnClosed {
+			return
+		} else {
+			logging.Vlogf(1, "Probe failed: %v", err)
+		}
+		if failures++; failures < w.opts.MaxFailures {
+			continue
+		}
+		failures = 0
+		if w.opts.OnHung != nil {
+			w.opts.OnHung()
+		}
+		w.escalate()
+	}
+}
+
+func (w *Watchdog) hasStep(step WatchdogStep) bool {
+	for _, s := range w.opts.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watchdog) escalate() {
+	for _, step := range w.opts.Steps {
+		_, conn := w.Target()
+		var err error
+		switch step {
+		case StepTerminateExecution:
+			err = sendWithTimeout(conn, w.opts.Timeout, func(cb func(error)) hc.Command {
+				return &rawCommand{name: "Runtime.terminateExecution", cb: cb}
+			})
+		case StepStopLoading:
+			err = sendWithTimeout(conn, w.opts.Timeout, func(cb func(error)) hc.Command {
+				return protocol.NewAsyncStopLoadingCommand(protocol.StopLoadingCB(cb))
+			})
+		case StepRecreateTarget:
+			err = w.recreateTarget()
+		}
+		recovered := false
+		if err == nil {
+			_, conn = w.Target()
+			recovered = probe(conn, w.opts.Timeout) == nil
+		}
+		logging.Vlogf(1, "Watchdog step %v: recovered=%v err=%v", step, recovered, err)
+		if w.opts.OnStep != nil {
+			w.opts.OnStep(step, recovered, err)
+		}
+		if recovered {
+			return
+		}
+	}
+}
+
+func (w *Watchdog) recreateTarget() error {
+	oldTargetId, oldConn := w.Target()
+	oldConn.Close()
+	if _, err := protocol.CloseTarget(
+		&protocol.CloseTargetParams{TargetId: oldTargetId}, w.browserConn); err != nil {
+		logging.Vlog(1, err)
+	}
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{
+		Url: "about:blank", BrowserContextId: w.opts.BrowserContextId}, w.browserConn)
+	if err != nil {
+		return err
+	}
+	conn, err := w.browser.NewPageConn(string(target.TargetId))
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.targetId, w.conn = target.TargetId, conn
+	w.mu.Unlock()
+	if w.opts.OnRecreated != nil {
+		w.opts.OnRecreated(target.TargetId, conn)
+	}
+	return nil
+}
+
+// Evaluates "1", which any responsive page answers quickly.
+func probe(conn *hc.Conn, timeout time.Duration) error {
+	return sendWithTimeout(conn, timeout, func(cb func(error)) hc.Command {
+		return protocol.NewAsyncEvaluateCommand(&protocol.EvaluateParams{Expression: "1"},
+			func(_ *protocol.EvaluateResult, err error) { cb(err) })
+	})
+}
+
+// Sends the command created by newCmd and waits up to timeout for it to finish. The connection
+// times the command out, so that it isn't left pending if the page never answers.
+func sendWithTimeout(conn *hc.Conn, timeout time.Duration,
+	newCmd func(cb func(error)) hc.Command) error {
+	ch := make(chan error, 1)
+	conn.WithTimeout(timeout).SendCommand(newCmd(func(err error) { ch <- err }))
+	if err := <-ch; err != hc.ErrCommandTimeout {
+		return err
+	}
+	return ErrTimeout
+}
+
+// A command without result, for methods or params the protocol package doesn't have.
+type rawCommand struct {
+	name   string
+	params interface{}
+	// If set, the result is unmarshaled into it.
+	result interface{}
+	cb     func(err error)
+}
+
+func (cmd *rawCommand) Name() string {
+	return cmd.name
+}
+
+func (cmd *rawCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *rawCommand) Done(result []byte, err error) {
+	if err == nil && cmd.result != nil {
+		err = json.Unmarshal(result, cmd.result)
+	}
+	cmd.cb(err)
+}
@@ -0,0 +1,146 @@
+package hcutil_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+type watchdogStep struct {
+	step      hcutil.WatchdogStep
+	recovered bool
+	failed    bool
+}
+
+// A page of a fake server which stops answering evaluations while hung, and the steps the
+// watchdog takes on it.
+type hungPage struct {
+	server *hctest.FakeServer
+	conn   *hc.Conn
+
+	mu    sync.Mutex
+	fake  *hctest.FakeConn
+	hung  bool
+	hungs int
+	steps []watchdogStep
+	done  chan struct{}
+}
+
+// Starts a watchdog of a hung page, which recovers when unhang returns true for a command.
+func startHungWatchdog(t *testing.T, unhang func(method string) bool) (*hungPage,
+	*hcutil.Watchdog) {
+	server := hctest.NewFakeServer(t)
+	server.AddTarget("fresh", "page", "about:blank")
+	p := &hungPage{server: server, hung: true, done: make(chan struct{})}
+	server.Handle("Runtime.evaluate", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.hung && cmd.Conn == p.fake {
+			return nil, hctest.ErrNoReply
+		}
+		return map[string]interface{}{"result": map[string]string{"type": "number"}}, nil
+	})
+	for _, method := range []string{"Runtime.terminateExecution", "Page.stopLoading"} {
+		method := method
+		server.Handle(method, func(*hctest.FakeCommand) (interface{}, error) {
+			if !unhang(method) {
+				if method == "Runtime.terminateExecution" {
+					return nil, hctest.MethodNotFound(method)
+				}
+				return struct{}{}, nil
+			}
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.hung = false
+			return struct{}{}, nil
+		})
+	}
+	server.Handle("Target.createTarget", hctest.FakeResult(map[string]string{"targetId": "fresh"}))
+	browserConn, _ := server.NewBrowserConn()
+	p.mu.Lock()
+	p.conn, p.fake = server.NewPageConn()
+	p.mu.Unlock()
+
+	w := hcutil.StartWatchdog(server.Browser(), browserConn, hctest.FakePageId, p.conn,
+		hcutil.WatchdogOptions{
+			Interval:    20 * time.Millisecond,
+			Timeout:     50 * time.Millisecond,
+			MaxFailures: 2,
+			OnHung: func() {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				p.hungs++
+			},
+			OnStep: func(step hcutil.WatchdogStep, recovered bool, err error) {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				p.steps = append(p.steps, watchdogStep{step, recovered, err != nil})
+				if recovered {
+					close(p.done)
+				}
+			},
+		})
+	t.Cleanup(w.Stop)
+	return p, w
+}
+
+func (p *hungPage) wait(t *testing.T) (int, []watchdogStep) {
+	t.Helper()
+	select {
+	case <-p.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Not recovered")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hungs, append([]watchdogStep(nil), p.steps...)
+}
+
+// Steps are taken in order till one recovers the page, and the probes of the hung page are timed
+// out by the connection rather than left pending.
+func TestWatchdogEscalation(t *testing.T) {
+	p, w := startHungWatchdog(t, func(string) bool { return false })
+	hungs, steps := p.wait(t)
+	want := []watchdogStep{
+		// Not in protocol v1.2.
+		{hcutil.StepTerminateExecution, false, true},
+		{hcutil.StepStopLoading, false, false},
+		{hcutil.StepRecreateTarget, true, false},
+	}
+	if hungs != 1 || len(steps) != len(want) {
+		t.Fatalf("Hung %d times, got steps %+v", hungs, steps)
+	}
+	for i := range want {
+		if steps[i] != want[i] {
+			t.Errorf("Got step %+v, want %+v", steps[i], want[i])
+		}
+	}
+
+	if closed := p.server.CommandsOf("Target.closeTarget"); len(closed) != 1 {
+		t.Errorf("Closed %d targets", len(closed))
+	}
+	if targetId, _ := w.Target(); targetId != "fresh" {
+		t.Errorf("Got target %s", targetId)
+	}
+	// The two failed probes, and the one after stopLoading. terminateExecution failed, so the
+	// page wasn't probed after it.
+	if stats := p.conn.Stats(); stats.CommandTimeouts != 3 {
+		t.Errorf("Got %d command timeouts", stats.CommandTimeouts)
+	}
+}
+
+func TestWatchdogRecoversEarly(t *testing.T) {
+	p, w := startHungWatchdog(t, func(method string) bool { return method == "Page.stopLoading" })
+	hungs, steps := p.wait(t)
+	if hungs != 1 || len(steps) != 2 || steps[1] != (watchdogStep{hcutil.StepStopLoading, true,
+		false}) {
+		t.Errorf("Hung %d times, got steps %+v", hungs, steps)
+	}
+	if targetId, _ := w.Target(); targetId != hctest.FakePageId {
+		t.Errorf("Recreated the target as %s", targetId)
+	}
+}
@@ -0,0 +1,269 @@
+package hcutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/bytebudget"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type WebSocketFrame struct {
+	Timestamp protocol.NetworkTimestamp
+	// Sent by the page, otherwise received.
+	Sent bool
+	// 1 for text, 2 for binary, 8 for close etc.
+	Opcode int
+	// Binary payloads are base64 decoded.
+	Payload []byte
+	// The payload was dropped to stay within WebSocketMonitorOptions.Budget.
+	PayloadDropped bool
+
+	// Holds Payload while the frame is kept by a monitor with a budget.
+	blob *bytebudget.Blob
+}
+
+// A WebSocket of the page.
+type WebSocket struct {
+	RequestId protocol.RequestId
+	URL       string
+	// The most recent frames, in order.
+	Frames []WebSocketFrame
+	// Number of frames dropped to keep Frames bounded.
+	Dropped int
+	Errors  []string
+	Closed  bool
+	// From the close frame, if any. 0 if unknown.
+	CloseCode int
+}
+
+type WebSocketMonitorOptions struct {
+	// Only monitor WebSockets whose URLs match.
+	URLPattern *regexp.Regexp
+	// Max frames kept per WebSocket. Defaults to 1000.
+	MaxFrames int
+	// Called with every frame kept. It may be called concurrently.
+	OnFrame func(requestId protocol.RequestId, frame *WebSocketFrame)
+	// If set, payloads of kept frames are accounted to it, and spilled or dropped when it runs
+	// out. Call WebSocketMonitor.Release when done with them.
+	Budget *bytebudget.Budget
+	// Bytes of Budget reserved for the monitor.
+	BudgetReservation int64
+}
+
+// Records frames of the page's own WebSockets created after it starts.
+type WebSocketMonitor struct {
+	opts    WebSocketMonitorOptions
+	conn    *hc.Conn
+	cancels []func()
+	// Network domain is acquired till Stop.
+	acquired bool
+	stopOnce sync.Once
+	// nil without a budget.
+	account *bytebudget.Account
+
+	mu      sync.Mutex
+	sockets map[protocol.RequestId]*WebSocket
+}
+
+// Starts monitoring. Network domain is enabled till Stop, unless other helpers need it, see
+// hc.Conn.AcquireDomain.
+func NewWebSocketMonitor(conn *hc.Conn, opts WebSocketMonitorOptions) (*WebSocketMonitor, error) {
+	if opts.MaxFrames <= 0 {
+		opts.MaxFrames = 1000
+	}
+	m := &WebSocketMonitor{conn: conn, opts: opts, sockets: make(map[protocol.RequestId]*WebSocket)}
+	if opts.Budget != nil {
+		var err error
+		if m.account, err = opts.Budget.Register("websocket", opts.BudgetReservation); err != nil {
+			return nil, err
+		}
+	}
+	m.cancels = []func(){
+		listen(conn, "Network.webSocketCreated", func(params []byte) {
+			var evt protocol.WebSocketCreatedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.webSocketCreated", params, err)
+				return
+			}
+			if opts.URLPattern != nil && !opts.URLPattern.MatchString(evt.Url) {
+				return
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.sockets[evt.RequestId] = &WebSocket{RequestId: evt.RequestId, URL: evt.Url}
+		}),
+		listen(conn, "Network.webSocketFrameSent", func(params []byte) {
+			m.onFrame(conn, "Network.webSocketFrameSent", params, true)
+		}),
+		listen(conn, "Network.webSocketFrameReceived", func(params []byte) {
+			m.onFrame(conn, "Network.webSocketFrameReceived", params, false)
+		}),
+		listen(conn, "Network.webSocketFrameError", func(params []byte) {
+			var evt protocol.WebSocketFrameErrorEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.webSocketFrameError", params, err)
+				return
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if ws := m.sockets[evt.RequestId]; ws != nil {
+				ws.Errors = append(ws.Errors, evt.ErrorMessage)
+			}
+		}),
+		listen(conn, "Network.webSocketClosed", func(params []byte) {
+			var evt protocol.WebSocketClosedEvent
+			if err := json.Unmarshal(params, &evt); err != nil {
+				conn.ReportEventError("Network.webSocketClosed", params, err)
+				return
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if ws := m.sockets[evt.RequestId]; ws != nil {
+				ws.Closed = true
+			}
+		}),
+	}
+	if err := conn.AcquireDomain("Network", nil); err != nil {
+		m.Stop()
+		m.Release()
+		return nil, err
+	}
+	m.acquired = true
+	return m, nil
+}
+
+func (m *WebSocketMonitor) onFrame(conn *hc.Conn, name string, params []byte, sent bool) {
+	var evt protocol.WebSocketFrameSentEvent // Same as WebSocketFrameReceivedEvent.
+	if err := json.Unmarshal(params, &evt); err != nil {
+		conn.ReportEventError(name, params, err)
+		return
+	}
+	if evt.Response == nil {
+		return
+	}
+	frame := WebSocketFrame{
+		Timestamp: evt.Timestamp,
+		Sent:      sent,
+		Opcode:    int(evt.Response.Opcode),
+		Payload:   []byte(evt.Response.PayloadData),
+	}
+	if frame.Opcode != 1 {
+		if data, err := base64.StdEncoding.DecodeString(evt.Response.PayloadData); err == nil {
+			frame.Payload = data
+		}
+	}
+
+	m.mu.Lock()
+	ws := m.sockets[evt.RequestId]
+	if ws == nil {
+		// Created before the monitor started, or filtered out.
+		m.mu.Unlock()
+		return
+	}
+	if frame.Opcode == 8 && len(frame.Payload) >= 2 {
+		ws.CloseCode = int(frame.Payload[0])<<8 | int(frame.Payload[1])
+	}
+	kept := frame
+	if m.account != nil {
+		kept.Payload = nil
+		var err error
+		if kept.blob, err = m.account.Store(frame.Payload); err != nil {
+			logging.Vlogf(2, "Dropped a payload of WebSocket %s: %v", ws.URL, err)
+			kept.PayloadDropped = true
+		}
+	}
+	// Events may come out of order.
+	i := sort.Search(len(ws.Frames), func(i int) bool {
+		return ws.Frames[i].Timestamp > frame.Timestamp
+	})
+	ws.Frames = append(ws.Frames, WebSocketFrame{})
+	copy(ws.Frames[i+1:], ws.Frames[i:])
+	ws.Frames[i] = kept
+	if over := len(ws.Frames) - m.opts.MaxFrames; over > 0 {
+		for _, dropped := range ws.Frames[:over] {
+			if dropped.blob != nil {
+				dropped.blob.Release()
+			}
+		}
+		ws.Frames = append([]WebSocketFrame(nil), ws.Frames[over:]...)
+		ws.Dropped += over
+	}
+	m.mu.Unlock()
+
+	if m.opts.OnFrame != nil {
+		m.opts.OnFrame(evt.RequestId, &frame)
+	}
+}
+
+// Returns a snapshot of the WebSocket, or nil if it isn't monitored.
+func (m *WebSocketMonitor) WebSocket(requestId protocol.RequestId) *WebSocket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ws := m.sockets[requestId]; ws != nil {
+		return ws.snapshot()
+	}
+	return nil
+}
+
+// Returns snapshots of all monitored WebSockets.
+func (m *WebSocketMonitor) WebSockets() []*WebSocket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sockets := make([]*WebSocket, 0, len(m.sockets))
+	for _, ws := range m.sockets {
+		sockets = append(sockets, ws.snapshot())
+	}
+	return sockets
+}
+
+// Payloads held by a budget are loaded, from spill files if spilled.
+func (ws *WebSocket) snapshot() *WebSocket {
+	copied := *ws
+	copied.Frames = append([]WebSocketFrame(nil), ws.Frames...)
+	for i := range copied.Frames {
+		frame := &copied.Frames[i]
+		if frame.blob == nil {
+			continue
+		}
+		var err error
+		if frame.Payload, err = frame.blob.Bytes(); err != nil {
+			logging.Vlogf(1, "Failed to load a payload of WebSocket %s: %v", ws.URL, err)
+			frame.PayloadDropped = true
+		}
+		frame.blob = nil
+	}
+	copied.Errors = append([]string(nil), ws.Errors...)
+	return &copied
+}
+
+// Stops recording. Frames recorded so far are kept.
+func (m *WebSocketMonitor) Stop() {
+	m.stopOnce.Do(func() {
+		for _, cancel := range m.cancels {
+			cancel()
+		}
+		if m.acquired {
+			if err := m.conn.ReleaseDomain("Network"); err != nil {
+				logging.Vlog(1, err)
+			}
+		}
+	})
+}
+
+// Forgets the recorded frames, and returns their memory to WebSocketMonitorOptions.Budget.
+// Call Stop first.
+func (m *WebSocketMonitor) Release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sockets = make(map[protocol.RequestId]*WebSocket)
+	if m.account != nil {
+		m.account.Close()
+	}
+}
@@ -0,0 +1,51 @@
+package hcutil_test
+
+import (
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// Stopping one helper using the Network domain doesn't stop events for another.
+func TestMonitorsShareNetworkDomain(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	wsMonitor, err := hcutil.NewWebSocketMonitor(conn, hcutil.WebSocketMonitorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamMonitor, err := hcutil.NewStreamMonitor(conn, hcutil.StreamMonitorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamMonitor.Stop()
+	streamMonitor.Release()
+
+	fake.Emit("Network.webSocketCreated", map[string]interface{}{
+		"requestId": "1", "url": "ws://example.com/"})
+	// Sinks may run concurrently, so frames could be seen before the WebSocket otherwise.
+	hctest.Flush(t, conn)
+	fake.Emit("Network.webSocketFrameReceived", map[string]interface{}{
+		"requestId": "1", "timestamp": 1,
+		"response": map[string]interface{}{"opcode": 1, "mask": false, "payloadData": "hi"},
+	})
+	hctest.Flush(t, conn)
+	if n := len(server.CommandsOf("Network.disable")); n != 0 {
+		t.Error("Network disabled while the WebSocket monitor runs")
+	}
+	sockets := wsMonitor.WebSockets()
+	if len(sockets) != 1 || len(sockets[0].Frames) != 1 ||
+		string(sockets[0].Frames[0].Payload) != "hi" {
+		t.Fatalf("Got %+v", sockets)
+	}
+
+	wsMonitor.Stop()
+	hctest.Flush(t, conn)
+	if n := len(server.CommandsOf("Network.enable")); n != 1 {
+		t.Errorf("Network.enable sent %d times", n)
+	}
+	if n := len(server.CommandsOf("Network.disable")); n != 1 {
+		t.Errorf("Network.disable sent %d times", n)
+	}
+}
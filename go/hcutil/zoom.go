@@ -0,0 +1,28 @@
+package hcutil
+
+import (
+	"fmt"
+	"strconv"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Zooms the page by factor, 1 meaning no zoom. Emulation.setPageScaleFactor is used, which
+// scales the page like pinch zoom. Browsers without it get CSS zoom of the document element
+// instead, which lays the page out again, and needs JavaScript.
+func SetPageZoom(conn *hc.Conn, factor float64) error {
+	if factor <= 0 {
+		return fmt.Errorf("Invalid zoom factor %v", factor)
+	}
+	err := protocol.SetPageScaleFactor(
+		&protocol.SetPageScaleFactorParams{PageScaleFactor: factor}, conn)
+	if err == nil || !hc.IsMethodNotFound(err) {
+		return err
+	}
+	if JavaScriptDisabled(conn) {
+		return ErrJSDisabled
+	}
+	return Evaluate(conn, "document.documentElement.style.zoom = "+
+		strconv.FormatFloat(factor, 'g', -1, 64), nil)
+}
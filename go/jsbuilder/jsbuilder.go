@@ -0,0 +1,104 @@
+// Package jsbuilder builds JavaScript expressions with Go values embedded safely, e.g. for
+// Runtime.evaluate. Use it instead of fmt.Sprintf whenever a value may come from users or
+// pages, so that quotes, backslashes, line separators or "</script>" can neither break nor
+// inject into the script.
+package jsbuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Trusted JavaScript code, which JSValue and JSTemplate insert as is.
+type Raw string
+
+// Returns a JavaScript string literal evaluating to s. Invalid UTF-8 is replaced by U+FFFD.
+// "<", ">", "&", U+2028 and U+2029 are escaped, so the literal is also safe inside <script>,
+// and in engines which don't allow line separators in string literals.
+func JSString(s string) string {
+	// Marshaling a string never fails.
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// Returns a JavaScript expression evaluating to v: strings are quoted, numbers are validated,
+// e.g. NaN is an error, and everything else is JSON encoded. json.RawMessage is validated and
+// inserted as is, escaped like JSString. Raw is inserted as is.
+func JSValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case Raw:
+		return string(v), nil
+	case string:
+		return JSString(v), nil
+	case json.RawMessage:
+		if !json.Valid(v) {
+			return "", fmt.Errorf("Invalid JSON %q", string(v))
+		}
+		var buf bytes.Buffer
+		json.HTMLEscape(&buf, v)
+		return buf.String(), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+const escaperName = "_jsbuilder_value"
+
+// Executes text as a text/template with data, each action escaped by JSValue, e.g.
+//
+//	JSTemplate("document.querySelector({{.Sel}}).value = {{.Val}}", data)
+//
+// Actions must not be inside quotes, as the values are quoted already. Missing map keys are
+// errors.
+func JSTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("js").Option("missingkey=error").Funcs(template.FuncMap{
+		escaperName: JSValue,
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tmpl.Templates() {
+		escapeList(t.Tree.Root)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Pipes the output of every action in list to JSValue, like html/template does.
+func escapeList(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.ActionNode:
+			// Declarations and assignments don't output anything.
+			if len(node.Pipe.Decl) == 0 {
+				node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{
+					NodeType: parse.NodeCommand,
+					Pos:      node.Pos,
+					Args:     []parse.Node{parse.NewIdentifier(escaperName).SetPos(node.Pos)},
+				})
+			}
+		case *parse.IfNode:
+			escapeList(node.List)
+			escapeList(node.ElseList)
+		case *parse.RangeNode:
+			escapeList(node.List)
+			escapeList(node.ElseList)
+		case *parse.WithNode:
+			escapeList(node.List)
+			escapeList(node.ElseList)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package jsbuilder_test
+
+import (
+	"encoding/json"
+	"math"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/yijinliu/headless-chromium/go/jsbuilder"
+)
+
+var hostileStrings = []string{
+	"",
+	`"`,
+	`'`,
+	"\\",
+	`\"`,
+	"\\\n",
+	"a\nb\rc\td",
+	"a\u2028b\u2029c",
+	"</script><script>alert(1)</script>",
+	"<!--",
+	"${alert(1)}",
+	"`",
+	"\x00\x1f\x7f",
+	"\U0001F600",
+	"\xff\xfe",
+	"\"); alert(1); (\"",
+}
+
+// Checks that the literal is safe to embed, and returns the value JSON decodes it to, which is
+// what JavaScript evaluates it to as well.
+func decodeLiteral(t *testing.T, literal string) string {
+	t.Helper()
+	for _, unsafe := range []string{"\n", "\r", "\u2028", "\u2029", "<", ">", "&"} {
+		if strings.Contains(literal, unsafe) {
+			t.Errorf("%q isn't escaped in %s", unsafe, literal)
+		}
+	}
+	var s string
+	if err := json.Unmarshal([]byte(literal), &s); err != nil {
+		t.Fatalf("%s: %v", literal, err)
+	}
+	return s
+}
+
+func FuzzJSString(f *testing.F) {
+	for _, s := range hostileStrings {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		// Each invalid byte becomes U+FFFD.
+		want := string([]rune(s))
+		if got := decodeLiteral(t, jsbuilder.JSString(s)); got != want {
+			t.Errorf("%q evaluates to %q", s, got)
+		}
+	})
+}
+
+type templateData struct {
+	S string
+	N float64
+	M map[string]string
+}
+
+func FuzzJSTemplate(f *testing.F) {
+	for i, s := range hostileStrings {
+		f.Add(s, float64(i)/3)
+	}
+	f.Add("x", math.MaxFloat64)
+	f.Add("x", math.Inf(1))
+	f.Add("x", math.NaN())
+	f.Fuzz(func(t *testing.T, s string, n float64) {
+		if !utf8.ValidString(s) {
+			return
+		}
+		expr, err := jsbuilder.JSTemplate("[{{.S}}, {{.N}}, {{.M}}, {{index .M .S}}]",
+			&templateData{S: s, N: n, M: map[string]string{s: s}})
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			if err == nil {
+				t.Errorf("%v gives %s", n, expr)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var values []json.RawMessage
+		if err := json.Unmarshal([]byte(expr), &values); err != nil || len(values) != 4 {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		var gotN float64
+		var gotM map[string]string
+		json.Unmarshal(values[1], &gotN)
+		json.Unmarshal(values[2], &gotM)
+		if got := decodeLiteral(t, string(values[0])); got != s {
+			t.Errorf("%q evaluates to %q", s, got)
+		}
+		if gotN != n {
+			t.Errorf("%v evaluates to %v", n, gotN)
+		}
+		if len(gotM) != 1 || gotM[s] != s {
+			t.Errorf("Map of %q evaluates to %v", s, gotM)
+		}
+		if got := decodeLiteral(t, string(values[3])); got != s {
+			t.Errorf("Index %q evaluates to %q", s, got)
+		}
+	})
+}
+
+func TestJSValue(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		want string
+	}{
+		{jsbuilder.Raw("window.x"), "window.x"},
+		{json.RawMessage(`{"a":"</script>"}`), `{"a":"\u003c/script\u003e"}`},
+		{[]int{1, 2}, "[1,2]"},
+		{nil, "null"},
+	} {
+		if got, err := jsbuilder.JSValue(c.v); err != nil || got != c.want {
+			t.Errorf("%v gives %s, %v", c.v, got, err)
+		}
+	}
+	for _, v := range []interface{}{json.RawMessage(`{"a":`), math.NaN(), func() {}} {
+		if got, err := jsbuilder.JSValue(v); err == nil {
+			t.Errorf("%v gives %s", v, got)
+		}
+	}
+	if got, err := jsbuilder.JSTemplate("f({{.missing}})", map[string]string{}); err == nil {
+		t.Errorf("Missing key gives %s", got)
+	}
+}
+
+// Evaluates the literals with a JavaScript engine, if one is installed, rather than trusting
+// JSON to be a subset of JavaScript.
+func TestJSStringEvaluates(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node isn't installed")
+	}
+	var literals []string
+	for _, s := range hostileStrings {
+		if utf8.ValidString(s) {
+			literals = append(literals, jsbuilder.JSString(s))
+		}
+	}
+	script := "process.stdout.write(JSON.stringify([" + strings.Join(literals, ", ") + "]))"
+	out, err := exec.Command(node, "-e", script).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	for _, s := range hostileStrings {
+		if !utf8.ValidString(s) {
+			continue
+		}
+		if got[i] != s {
+			t.Errorf("%q evaluates to %q", s, got[i])
+		}
+		i++
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type expvarMetrics struct {
+	vars *expvar.Map
+
+	mu        sync.Mutex
+	histogram map[string]*expvarHistogram
+}
+
+// Publishes metrics as the expvar map name, e.g. served as JSON on /debug/vars. Samples are keyed
+// by metric name and labels, e.g. `hc_render_total{status="ok"}`. Histograms are maps with
+// count, sum, and cumulative counts of DurationBuckets keyed like "le_0.5". Panics if name is
+// published already, like expvar.NewMap.
+func NewExpvar(name string) Metrics {
+	return &expvarMetrics{vars: expvar.NewMap(name), histogram: make(map[string]*expvarHistogram)}
+}
+
+func (m *expvarMetrics) Counter(name string, labels Labels, delta float64) {
+	m.vars.AddFloat(sampleKey(name, labels), delta)
+}
+
+func (m *expvarMetrics) Gauge(name string, labels Labels, value float64) {
+	key := sampleKey(name, labels)
+	if v, ok := m.vars.Get(key).(*expvar.Float); ok {
+		v.Set(value)
+		return
+	}
+	v := new(expvar.Float)
+	v.Set(value)
+	m.vars.Set(key, v)
+}
+
+func (m *expvarMetrics) Histogram(name string, labels Labels, value float64) {
+	key := sampleKey(name, labels)
+	m.mu.Lock()
+	h := m.histogram[key]
+	if h == nil {
+		h = &expvarHistogram{buckets: make([]uint64, len(DurationBuckets))}
+		m.histogram[key] = h
+		m.vars.Set(key, h)
+	}
+	m.mu.Unlock()
+	h.observe(value)
+}
+
+type expvarHistogram struct {
+	mu      sync.Mutex
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+func (h *expvarHistogram) observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += value
+	for i, bound := range DurationBuckets {
+		if value <= bound {
+			h.buckets[i]++
+		}
+	}
+}
+
+// Implements expvar.Var.
+func (h *expvarHistogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"count": %d, "sum": %g`, h.count, h.sum)
+	for i, bound := range DurationBuckets {
+		fmt.Fprintf(&b, `, "le_%g": %d`, bound, h.buckets[i])
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// Formats name and labels like Prometheus does, with labels sorted.
+func sampleKey(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%s=%q", k, labels[k])
+	}
+	b.WriteString("}")
+	return b.String()
+}
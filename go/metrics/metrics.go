@@ -0,0 +1,87 @@
+// Package metrics is a minimal facade for the metrics of the cluster and render packages, so that
+// they don't depend on a specific client library. NewExpvar adapts it to expvar. The prometheus
+// sub-package adapts it to prometheus/client_golang, and needs the "prometheus" build tag.
+//
+// Metric names, all prefixed by "hc_":
+//
+//	hc_cluster_acquire_wait_seconds     histogram  Time Cluster.Acquire waited. result: ok, error.
+//	hc_cluster_acquire_waiting          gauge      Acquire calls waiting for a healthy browser.
+//	hc_cluster_acquired                 gauge      Acquisitions not released yet.
+//	hc_cluster_browsers                 gauge      Browsers of the cluster.
+//	hc_cluster_browsers_healthy         gauge      Browsers which may be acquired.
+//	hc_cluster_browser_restarts_total   counter    Relaunches of browsers. reason: dead, recycled.
+//	hc_cluster_browser_launch_failures_total
+//	                                    counter    Failed relaunches, retried with backoff.
+//	hc_cluster_browser_rss_bytes        gauge      Resident set size of a browser. port.
+//	hc_cluster_browser_cpu_seconds      gauge      CPU time used by a browser. port.
+//	hc_cluster_browser_open_fds         gauge      Open file descriptors of a browser. port.
+//	hc_cluster_browser_processes        gauge      Processes of a browser. port.
+//	hc_render_total                     counter    Renders by status: ok, timeout, canceled,
+//	                                               busy, error.
+//	hc_render_duration_seconds          histogram  Time of a whole render. status.
+//	hc_render_load_seconds              histogram  Time of loading pages rendered successfully.
+//	hc_render_capture_seconds           histogram  Time of capturing and encoding them.
+//	hc_render_cache_total               counter    Cache lookups. result: hit, miss, bypass.
+//	hc_render_cache_bytes               gauge      Size of the cached images.
+//	hc_render_queue_depth               gauge      Requests waiting for a render slot (renderd).
+//	hc_render_slots_active              gauge      Renders in progress (renderd).
+//
+// Label names are in the descriptions above. Durations are in seconds.
+package metrics
+
+// Labels of a metric sample, e.g. {"status": "ok"}. A metric must always have the same label
+// names.
+type Labels map[string]string
+
+// Records metric samples. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Adds delta, which must not be negative, to the counter.
+	Counter(name string, labels Labels, delta float64)
+	// Sets the gauge to value.
+	Gauge(name string, labels Labels, value float64)
+	// Observes value, e.g. a duration in seconds.
+	Histogram(name string, labels Labels, value float64)
+}
+
+// Drops all samples. Used when no Metrics is configured.
+var Discard Metrics = discard{}
+
+type discard struct{}
+
+func (discard) Counter(name string, labels Labels, delta float64)   {}
+func (discard) Gauge(name string, labels Labels, value float64)     {}
+func (discard) Histogram(name string, labels Labels, value float64) {}
+
+// Returns m, or Discard if it's nil.
+func OrDiscard(m Metrics) Metrics {
+	if m == nil {
+		return Discard
+	}
+	return m
+}
+
+const (
+	ClusterAcquireWaitSeconds         = "hc_cluster_acquire_wait_seconds"
+	ClusterAcquireWaiting             = "hc_cluster_acquire_waiting"
+	ClusterAcquired                   = "hc_cluster_acquired"
+	ClusterBrowsers                   = "hc_cluster_browsers"
+	ClusterBrowsersHealthy            = "hc_cluster_browsers_healthy"
+	ClusterBrowserRestartsTotal       = "hc_cluster_browser_restarts_total"
+	ClusterBrowserLaunchFailuresTotal = "hc_cluster_browser_launch_failures_total"
+	ClusterBrowserRSSBytes            = "hc_cluster_browser_rss_bytes"
+	ClusterBrowserCPUSeconds          = "hc_cluster_browser_cpu_seconds"
+	ClusterBrowserOpenFDs             = "hc_cluster_browser_open_fds"
+	ClusterBrowserProcesses           = "hc_cluster_browser_processes"
+
+	RenderTotal           = "hc_render_total"
+	RenderDurationSeconds = "hc_render_duration_seconds"
+	RenderLoadSeconds     = "hc_render_load_seconds"
+	RenderCaptureSeconds  = "hc_render_capture_seconds"
+	RenderCacheTotal      = "hc_render_cache_total"
+	RenderCacheBytes      = "hc_render_cache_bytes"
+	RenderQueueDepth      = "hc_render_queue_depth"
+	RenderSlotsActive     = "hc_render_slots_active"
+)
+
+// Buckets of duration histograms, in seconds, for adapters which need them up front.
+var DurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
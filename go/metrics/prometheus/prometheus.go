@@ -0,0 +1,94 @@
+//go:build prometheus
+
+// Package prometheus adapts metrics.Metrics to prometheus/client_golang. It's only built with the
+// "prometheus" build tag, so that the rest of the library doesn't depend on client_golang.
+package prometheus
+
+import (
+	"sort"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yijinliu/headless-chromium/go/metrics"
+)
+
+type adapter struct {
+	registerer prom.Registerer
+
+	mu         sync.Mutex
+	counters   map[string]*prom.CounterVec
+	gauges     map[string]*prom.GaugeVec
+	histograms map[string]*prom.HistogramVec
+}
+
+// Returns Metrics registering collectors with registerer, e.g. prom.DefaultRegisterer, as
+// metrics are first recorded. Histograms use metrics.DurationBuckets.
+func New(registerer prom.Registerer) metrics.Metrics {
+	return &adapter{
+		registerer: registerer,
+		counters:   make(map[string]*prom.CounterVec),
+		gauges:     make(map[string]*prom.GaugeVec),
+		histograms: make(map[string]*prom.HistogramVec),
+	}
+}
+
+func (a *adapter) Counter(name string, labels metrics.Labels, delta float64) {
+	a.mu.Lock()
+	vec := a.counters[name]
+	if vec == nil {
+		vec = prom.NewCounterVec(prom.CounterOpts{Name: name, Help: help(name)},
+			labelNames(labels))
+		a.counters[name] = vec
+		a.register(vec)
+	}
+	a.mu.Unlock()
+	vec.With(prom.Labels(labels)).Add(delta)
+}
+
+func (a *adapter) Gauge(name string, labels metrics.Labels, value float64) {
+	a.mu.Lock()
+	vec := a.gauges[name]
+	if vec == nil {
+		vec = prom.NewGaugeVec(prom.GaugeOpts{Name: name, Help: help(name)},
+			labelNames(labels))
+		a.gauges[name] = vec
+		a.register(vec)
+	}
+	a.mu.Unlock()
+	vec.With(prom.Labels(labels)).Set(value)
+}
+
+func (a *adapter) Histogram(name string, labels metrics.Labels, value float64) {
+	a.mu.Lock()
+	vec := a.histograms[name]
+	if vec == nil {
+		vec = prom.NewHistogramVec(prom.HistogramOpts{
+			Name: name, Help: help(name), Buckets: metrics.DurationBuckets,
+		}, labelNames(labels))
+		a.histograms[name] = vec
+		a.register(vec)
+	}
+	a.mu.Unlock()
+	vec.With(prom.Labels(labels)).Observe(value)
+}
+
+// Must be called with mu held.
+func (a *adapter) register(c prom.Collector) {
+	// Metrics are recorded from deep inside the library, which has nowhere to return errors to.
+	// A duplicate registration, e.g. by two adapters on one registerer, is a programming error.
+	a.registerer.MustRegister(c)
+}
+
+func labelNames(labels metrics.Labels) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func help(name string) string {
+	return name + ", see package github.com/yijinliu/headless-chromium/go/metrics."
+}
@@ -0,0 +1,151 @@
+package headless_chromium
+
+import (
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// Object group of remote objects created by helpers, unless SetHelperObjectGroup changed it.
+const DefaultHelperObjectGroup = "__hc_helpers"
+
+// Helper objects are released once this many are alive, unless SetHelperObjectSweep changed it.
+const DefaultHelperObjectSweepThreshold = 1000
+
+// Remote objects created by helpers, e.g. nodes resolved to call functions on. Guarded by
+// Conn.helperObjMu.
+type helperObjects struct {
+	group string
+	// 0 means DefaultHelperObjectSweepThreshold, negative never.
+	threshold int
+	// Number of helpers between BeginHelperObjects and end. The group is never released
+	// automatically meanwhile.
+	inUse int
+	// Objects created since the group was last released.
+	live              int
+	created, released int
+	// Closed to stop the periodic sweep.
+	stopSweep chan struct{}
+}
+
+// Sets the object group of remote objects created by helpers, DefaultHelperObjectGroup by
+// default. Objects in the previous group are released.
+func (c *Conn) SetHelperObjectGroup(group string) {
+	c.helperObjMu.Lock()
+	defer c.helperObjMu.Unlock()
+	if group == "" {
+		group = DefaultHelperObjectGroup
+	}
+	if group == c.helperObjectGroupLocked() {
+		return
+	}
+	c.releaseHelperObjectsLocked(nil)
+	c.helperObjs.group = group
+}
+
+func (c *Conn) helperObjectGroupLocked() string {
+	if c.helperObjs.group == "" {
+		return DefaultHelperObjectGroup
+	}
+	return c.helperObjs.group
+}
+
+// Sets when the object group of helpers is released: once threshold objects are alive, or every
+// interval, whenever no helper is using them. threshold 0 means
+// DefaultHelperObjectSweepThreshold, and negative never. interval 0 means no periodic sweep.
+func (c *Conn) SetHelperObjectSweep(threshold int, interval time.Duration) {
+	c.helperObjMu.Lock()
+	defer c.helperObjMu.Unlock()
+	c.helperObjs.threshold = threshold
+	if c.helperObjs.stopSweep != nil {
+		close(c.helperObjs.stopSweep)
+		c.helperObjs.stopSweep = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	c.helperObjs.stopSweep = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.helperObjMu.Lock()
+				if c.helperObjs.inUse == 0 && c.helperObjs.live > 0 {
+					c.releaseHelperObjectsLocked(nil)
+				}
+				c.helperObjMu.Unlock()
+			case <-stop:
+				return
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+}
+
+// Called by helpers creating remote objects. Returns the object group to create them in, and end
+// to call with the number created once the helper doesn't need them any more. The group isn't
+// released automatically until then, so helpers needn't release the objects themselves.
+func (c *Conn) BeginHelperObjects() (group string, end func(created int)) {
+	c.helperObjMu.Lock()
+	defer c.helperObjMu.Unlock()
+	c.helperObjs.inUse++
+	ended := false
+	return c.helperObjectGroupLocked(), func(created int) {
+		c.helperObjMu.Lock()
+		defer c.helperObjMu.Unlock()
+		if ended {
+			return
+		}
+		ended = true
+		h := &c.helperObjs
+		h.inUse--
+		h.live += created
+		h.created += created
+		threshold := h.threshold
+		if threshold == 0 {
+			threshold = DefaultHelperObjectSweepThreshold
+		}
+		if h.inUse == 0 && threshold > 0 && h.live >= threshold {
+			c.releaseHelperObjectsLocked(nil)
+		}
+	}
+}
+
+// Releases all remote objects created by helpers now, even if helpers are still using them, e.g.
+// before reusing the page for another job.
+func (c *Conn) ReleaseAllHelperObjects() error {
+	done := make(chan error, 1)
+	c.helperObjMu.Lock()
+	c.releaseHelperObjectsLocked(done)
+	c.helperObjMu.Unlock()
+	select {
+	case err := <-done:
+		return err
+	case <-c.closed:
+		return ErrConnClosed
+	}
+}
+
+// Must be called with helperObjMu held, so that the release is sent before objects created by
+// helpers beginning afterwards. done, which may be nil, gets the result.
+func (c *Conn) releaseHelperObjectsLocked(done chan<- error) {
+	h := &c.helperObjs
+	h.released += h.live
+	h.live = 0
+	c.SendCommand(&rawCommand{
+		name:   "Runtime.releaseObjectGroup",
+		params: map[string]string{"objectGroup": c.helperObjectGroupLocked()},
+		done: func(_ []byte, err error) {
+			if err != nil {
+				logging.Vlog(2, err)
+			}
+			if done != nil {
+				done <- err
+			}
+		},
+	})
+}
@@ -0,0 +1,71 @@
+package headless_chromium_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+)
+
+func releasedObjectGroups(server *hctest.FakeServer) []string {
+	var groups []string
+	for _, cmd := range server.CommandsOf("Runtime.releaseObjectGroup") {
+		var params struct{ ObjectGroup string }
+		json.Unmarshal(cmd.Params, &params)
+		groups = append(groups, params.ObjectGroup)
+	}
+	return groups
+}
+
+// The group is released once the threshold is reached, but not while a helper still uses it.
+func TestHelperObjectSweepThreshold(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	conn.SetHelperObjectSweep(5, 0)
+
+	group, end := conn.BeginHelperObjects()
+	if group != hc.DefaultHelperObjectGroup {
+		t.Errorf("Got group %q", group)
+	}
+	end(3)
+	// Ending twice counts once.
+	end(3)
+	_, endFirst := conn.BeginHelperObjects()
+	_, endSecond := conn.BeginHelperObjects()
+	endFirst(3)
+	hctest.Flush(t, conn)
+	if groups := releasedObjectGroups(server); len(groups) != 0 {
+		t.Fatalf("Released %v while in use", groups)
+	}
+	endSecond(0)
+	hctest.Flush(t, conn)
+	if groups := releasedObjectGroups(server); len(groups) != 1 ||
+		groups[0] != hc.DefaultHelperObjectGroup {
+		t.Errorf("Released %v", groups)
+	}
+	if stats := conn.Stats(); stats.HelperObjectsCreated != 6 ||
+		stats.HelperObjectsReleased != 6 {
+		t.Errorf("Got %+v", stats)
+	}
+}
+
+func TestHelperObjectSweepInterval(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	// Only the periodic sweep releases them.
+	conn.SetHelperObjectSweep(-1, 10*time.Millisecond)
+	_, end := conn.BeginHelperObjects()
+	end(2000)
+	for deadline := time.Now().Add(5 * time.Second); len(releasedObjectGroups(server)) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Not swept")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	conn.SetHelperObjectSweep(-1, 0)
+	if stats := conn.Stats(); stats.HelperObjectsReleased != 2000 {
+		t.Errorf("Got %+v", stats)
+	}
+}
@@ -0,0 +1,204 @@
+package headless_chromium
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// What's known about a target created by some process using this package.
+type TargetLabel struct {
+	Label            string    `json:"label"`
+	BrowserContextId string    `json:"browserContextId,omitempty"`
+	Pid              int       `json:"pid"`
+	Created          time.Time `json:"created"`
+}
+
+type targetLabels struct {
+	mu     sync.Mutex
+	path   string
+	labels map[string]*TargetLabel // Key is target id.
+}
+
+// Keeps target labels in path, so that they survive crashes of this process and CleanupOrphans
+// of the next one can tell which targets were left behind. Existing labels in the file are loaded.
+func (b *Browser) SetTargetLabelFile(path string) error {
+	labels := make(map[string]*TargetLabel)
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &labels); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	b.labels.mu.Lock()
+	defer b.labels.mu.Unlock()
+	for id, label := range b.labels.labels {
+		labels[id] = label
+	}
+	b.labels.path = path
+	b.labels.labels = labels
+	return b.labels.saveLocked()
+}
+
+// Labels a target created by this process, and the browser context it's in if any, so that
+// CleanupOrphans can clean them up once this process is gone.
+func (b *Browser) LabelTarget(targetId, browserContextId, label string) error {
+	b.labels.mu.Lock()
+	defer b.labels.mu.Unlock()
+	if b.labels.labels == nil {
+		b.labels.labels = make(map[string]*TargetLabel)
+	}
+	b.labels.labels[targetId] = &TargetLabel{
+		Label:            label,
+		BrowserContextId: browserContextId,
+		Pid:              os.Getpid(),
+		Created:          time.Now(),
+	}
+	return b.labels.saveLocked()
+}
+
+// Returns the label of the target, or nil if it isn't labeled.
+func (b *Browser) TargetLabel(targetId string) *TargetLabel {
+	b.labels.mu.Lock()
+	defer b.labels.mu.Unlock()
+	if label := b.labels.labels[targetId]; label != nil {
+		copied := *label
+		return &copied
+	}
+	return nil
+}
+
+func (l *targetLabels) saveLocked() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(l.labels)
+	if err != nil {
+		return err
+	}
+	tmpPath := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, l.path)
+}
+
+// Closes pages left behind by crashed processes, along with their browser contexts, and returns
+// their target ids. A page is left behind if it's labeled by another process, or unlabeled and
+// blank, and was created or first seen by CleanupOrphans at least olderThan ago. Pages this
+// process is connected to, and pages any other client is attached to, are never touched.
+func (b *Browser) CleanupOrphans(olderThan time.Duration) ([]string, error) {
+	tabs, err := b.ListTabs()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var orphans []string
+	var contextIds []string
+	b.labels.mu.Lock()
+	if b.labels.labels == nil {
+		b.labels.labels = make(map[string]*TargetLabel)
+	}
+	live := make(map[string]bool, len(tabs))
+	for _, tab := range tabs {
+		live[tab.ID] = true
+		// Attached targets don't have webSocketDebuggerUrl.
+		if tab.Kind() != KindPage || b.PageConn(tab.ID) != nil || tab.WebSocketDebuggerUrl == "" {
+			continue
+		}
+		label := b.labels.labels[tab.ID]
+		if label == nil {
+			if tab.Url != "about:blank" {
+				continue
+			}
+			// Remember when it's first seen, as its age isn't known.
+			label = &TargetLabel{Created: now}
+			b.labels.labels[tab.ID] = label
+		}
+		if label.Pid == os.Getpid() || now.Sub(label.Created) < olderThan {
+			continue
+		}
+		orphans = append(orphans, tab.ID)
+		if label.BrowserContextId != "" {
+			contextIds = append(contextIds, label.BrowserContextId)
+		}
+	}
+	// Forget targets which are gone.
+	for id := range b.labels.labels {
+		if !live[id] {
+			delete(b.labels.labels, id)
+		}
+	}
+	if err := b.labels.saveLocked(); err != nil {
+		logging.Vlog(-1, err)
+	}
+	b.labels.mu.Unlock()
+
+	var closed []string
+	for _, id := range orphans {
+		if err := b.closeTarget(id); err != nil {
+			logging.Vlogf(-1, "Failed to close orphan %s: %v", id, err)
+			continue
+		}
+		closed = append(closed, id)
+	}
+	if len(contextIds) > 0 {
+		b.disposeContexts(contextIds)
+	}
+	logging.Vlogf(1, "Closed %d orphans", len(closed))
+	return closed, nil
+}
+
+func (b *Browser) closeTarget(targetId string) error {
+	resp, err := http.Get("http://" + b.addrPort + "/json/close/" + targetId)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	b.labels.mu.Lock()
+	defer b.labels.mu.Unlock()
+	delete(b.labels.labels, targetId)
+	return b.labels.saveLocked()
+}
+
+// Protocol v1.2 can't list browser contexts, so only labeled ones are disposed of.
+func (b *Browser) disposeContexts(contextIds []string) {
+	conn, err := b.NewBrowserConn()
+	if err != nil {
+		logging.Vlog(-1, err)
+		return
+	}
+	defer conn.Close()
+	var wg sync.WaitGroup
+	for _, id := range contextIds {
+		wg.Add(1)
+		conn.SendCommand(&disposeContextCommand{id, &wg})
+	}
+	wg.Wait()
+}
+
+type disposeContextCommand struct {
+	contextId string
+	wg        *sync.WaitGroup
+}
+
+func (cmd *disposeContextCommand) Name() string {
+	return "Target.disposeBrowserContext"
+}
+
+func (cmd *disposeContextCommand) Params() interface{} {
+	return map[string]string{"browserContextId": cmd.contextId}
+}
+
+func (cmd *disposeContextCommand) Done(result []byte, err error) {
+	if err != nil {
+		logging.Vlogf(-1, "Failed to dispose browser context %s: %v", cmd.contextId, err)
+	}
+	cmd.wg.Done()
+}
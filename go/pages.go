@@ -0,0 +1,94 @@
+package headless_chromium
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+type PageInfo struct {
+	TargetId string
+	// "page", "background_page", "service_worker", "other" etc.
+	Type  string
+	Title string
+	URL   string
+	// Whether we hold a page connection to it. See Conn.
+	Attached bool
+	// Not reported by protocol v1.2 browsers, in which case it's empty.
+	BrowserContextId string
+	// The page connection created by NewPageConn, or nil.
+	Conn *Conn
+	// See Tab.ParentId.
+	ParentId string
+}
+
+// Lists targets of the given types, or only "page"s if no type is given, without connecting to
+// them.
+func (b *Browser) Pages(types ...string) ([]PageInfo, error) {
+	tabs, err := b.ListTabs()
+	if err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		types = []string{"page"}
+	}
+	var pages []PageInfo
+	for _, tab := range tabs {
+		if !containsString(types, tab.Type) {
+			continue
+		}
+		conn := b.PageConn(tab.ID)
+		pages = append(pages, PageInfo{
+			TargetId: tab.ID,
+			Type:     tab.Type,
+			Title:    tab.Title,
+			URL:      tab.Url,
+			Attached: conn != nil,
+			Conn:     conn,
+			ParentId: tab.ParentId,
+		})
+	}
+	return pages, nil
+}
+
+// Calls Pages every interval, and cb with the result whenever it changes, starting with the
+// first one. Errors are logged. Call the returned function to stop watching.
+func (b *Browser) WatchPages(interval time.Duration, cb func(pages []PageInfo),
+	types ...string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var last []PageInfo
+		first := true
+		for {
+			if pages, err := b.Pages(types...); err != nil {
+				logging.Vlog(1, err)
+			} else if first || !reflect.DeepEqual(pages, last) {
+				first = false
+				last = pages
+				cb(pages)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
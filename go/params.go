@@ -0,0 +1,154 @@
+package headless_chromium
+
+import (
+	"container/list"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// Params marshaled once by PrecomputeParams, e.g. a large script injected into many pages, so
+// that sending them again costs no marshaling. Return it from Command.Params.
+type PrecomputedParams struct {
+	data json.RawMessage
+}
+
+func PrecomputeParams(params interface{}) (*PrecomputedParams, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return &PrecomputedParams{data: data}, nil
+}
+
+// Size of the marshaled params in bytes.
+func (p *PrecomputedParams) Len() int {
+	return len(p.data)
+}
+
+func (p *PrecomputedParams) MarshalJSON() ([]byte, error) {
+	return p.data, nil
+}
+
+// Params which are often sent again unchanged can implement it to have their marshaled form
+// cached, shared by all connections. ParamsKey identifies the content of the params, e.g. a hash
+// of it, or a large string field itself, which the cache keeps a reference to rather than a
+// copy. Empty key means not to cache them.
+type KeyedParams interface {
+	ParamsKey() string
+}
+
+const (
+	defaultParamsCacheBytes = 32 << 20
+	// Smaller params are marshaled quickly enough.
+	minCachedParamsSize = 16 << 10
+)
+
+var sharedParamsCache = newParamsCache(defaultParamsCacheBytes)
+
+// Sets the max bytes of marshaled KeyedParams cached, evicting least recently used ones beyond
+// it. 0 disables the cache.
+func SetParamsCacheSize(maxBytes int64) {
+	sharedParamsCache.resize(maxBytes)
+}
+
+type paramsCacheKey struct {
+	method, key string
+}
+
+type paramsCacheEntry struct {
+	key  paramsCacheKey
+	data json.RawMessage
+}
+
+type paramsCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	lru      *list.List // Most recently used first. Values are *paramsCacheEntry.
+	entryMap map[paramsCacheKey]*list.Element
+}
+
+func newParamsCache(maxBytes int64) *paramsCache {
+	return &paramsCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entryMap: make(map[paramsCacheKey]*list.Element),
+	}
+}
+
+// Returns the marshaled params of method, from the cache if they implement KeyedParams.
+func (c *paramsCache) marshal(method string, params interface{}) (json.RawMessage, error) {
+	switch p := params.(type) {
+	case *PrecomputedParams:
+		return p.data, nil
+	case KeyedParams:
+		if key := p.ParamsKey(); key != "" {
+			return c.marshalKeyed(paramsCacheKey{method, key}, params)
+		}
+	}
+	return json.Marshal(params)
+}
+
+func (c *paramsCache) marshalKeyed(key paramsCacheKey, params interface{}) (
+	json.RawMessage, error) {
+	c.mu.Lock()
+	if elem := c.entryMap[key]; elem != nil {
+		c.lru.MoveToFront(elem)
+		data := elem.Value.(*paramsCacheEntry).data
+		c.mu.Unlock()
+		return data, nil
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(params)
+	if err != nil || len(data) < minCachedParamsSize {
+		return data, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if int64(len(data)) > c.maxBytes || c.entryMap[key] != nil {
+		return data, nil
+	}
+	c.entryMap[key] = c.lru.PushFront(&paramsCacheEntry{key: key, data: data})
+	c.bytes += int64(len(data))
+	c.evictLocked()
+	return data, nil
+}
+
+func (c *paramsCache) resize(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxBytes = maxBytes
+	c.evictLocked()
+}
+
+// Must be called with mu held.
+func (c *paramsCache) evictLocked() {
+	for c.bytes > c.maxBytes {
+		entry := c.lru.Remove(c.lru.Back()).(*paramsCacheEntry)
+		delete(c.entryMap, entry.key)
+		c.bytes -= int64(len(entry.data))
+	}
+}
+
+// Encodes cj like json.Marshal, but copies already marshaled params as is, instead of validating
+// and compacting them again.
+func (cj *CommandJson) encode() ([]byte, error) {
+	params, ok := cj.Params.(json.RawMessage)
+	if !ok {
+		return json.Marshal(cj)
+	}
+	method, err := json.Marshal(cj.Method)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(params)+len(method)+32)
+	buf = append(buf, `{"id":`...)
+	buf = strconv.AppendInt(buf, int64(cj.Id), 10)
+	buf = append(buf, `,"method":`...)
+	buf = append(buf, method...)
+	buf = append(buf, `,"params":`...)
+	buf = append(buf, params...)
+	return append(buf, '}'), nil
+}
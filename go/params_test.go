@@ -0,0 +1,127 @@
+package headless_chromium
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testKeyedParams struct {
+	Key    string `json:"-"`
+	Source string `json:"source"`
+}
+
+func (p *testKeyedParams) ParamsKey() string {
+	return p.Key
+}
+
+// Params of key whose marshaled form is about size bytes.
+func newTestKeyedParams(key string, size int) *testKeyedParams {
+	return &testKeyedParams{Key: key, Source: strings.Repeat("x", size)}
+}
+
+func (c *paramsCache) cachedKeys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*paramsCacheEntry).key.key)
+	}
+	return keys
+}
+
+func marshalParams(t *testing.T, c *paramsCache, params interface{}) json.RawMessage {
+	t.Helper()
+	data, err := c.marshal("Test.run", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParamsCacheEviction(t *testing.T) {
+	size := minCachedParamsSize
+	a, b, c := newTestKeyedParams("a", size), newTestKeyedParams("b", size),
+		newTestKeyedParams("c", size)
+	entryBytes := int64(len(marshalParams(t, newParamsCache(0), a)))
+	cache := newParamsCache(2 * entryBytes)
+
+	marshalParams(t, cache, a)
+	marshalParams(t, cache, b)
+	// Makes b the least recently used.
+	first := marshalParams(t, cache, a)
+	if again := marshalParams(t, cache, a); &again[0] != &first[0] {
+		t.Error("Marshaled a again")
+	}
+	marshalParams(t, cache, c)
+	if keys := cache.cachedKeys(); strings.Join(keys, ",") != "c,a" {
+		t.Errorf("Got %v cached", keys)
+	}
+	if cache.bytes != 2*entryBytes {
+		t.Errorf("Got %d bytes, want %d", cache.bytes, 2*entryBytes)
+	}
+
+	// Small params, params without a key, and params larger than the cache aren't cached.
+	marshalParams(t, cache, newTestKeyedParams("small", 10))
+	marshalParams(t, cache, newTestKeyedParams("", size))
+	marshalParams(t, cache, newTestKeyedParams("large", 3*size))
+	if keys := cache.cachedKeys(); strings.Join(keys, ",") != "c,a" {
+		t.Errorf("Got %v cached", keys)
+	}
+
+	cache.resize(entryBytes)
+	if keys := cache.cachedKeys(); strings.Join(keys, ",") != "c" {
+		t.Errorf("Got %v cached after shrinking", keys)
+	}
+}
+
+func TestParamsCacheDisabled(t *testing.T) {
+	cache := newParamsCache(defaultParamsCacheBytes)
+	params := newTestKeyedParams("a", minCachedParamsSize)
+	marshalParams(t, cache, params)
+	cache.resize(0)
+	if keys := cache.cachedKeys(); len(keys) != 0 || cache.bytes != 0 {
+		t.Errorf("Got %v cached, %d bytes", keys, cache.bytes)
+	}
+
+	data := marshalParams(t, cache, params)
+	var decoded testKeyedParams
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Source != params.Source {
+		t.Errorf("Got %.40s..., %v", data, err)
+	}
+	if keys := cache.cachedKeys(); len(keys) != 0 {
+		t.Errorf("Got %v cached", keys)
+	}
+}
+
+func BenchmarkParamsCacheMarshal(b *testing.B) {
+	// About the size of a bundled library injected into every page.
+	const size = 200 << 10
+	for _, c := range []struct {
+		name     string
+		maxBytes int64
+		key      string
+	}{
+		{"Keyed", defaultParamsCacheBytes, "script"},
+		{"Unkeyed", defaultParamsCacheBytes, ""},
+		{"Disabled", 0, "script"},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			cache := newParamsCache(c.maxBytes)
+			params := newTestKeyedParams(c.key, size)
+			b.SetBytes(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				data, err := cache.marshal("Page.addScriptToEvaluateOnNewDocument", params)
+				if err != nil {
+					b.Fatal(err)
+				}
+				cj := &CommandJson{Id: i, Method: "Page.addScriptToEvaluateOnNewDocument",
+					Params: data}
+				if _, err := cj.encode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
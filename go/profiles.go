@@ -0,0 +1,164 @@
+package headless_chromium
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+const (
+	profileLockFile   = "hc.lock"
+	tempProfilePrefix = "tmp-"
+	// Temporary profiles without a lock file younger than this may be just being created.
+	unlockedProfileGrace = time.Minute
+)
+
+// Where profiles live unless LaunchOptions.ProfileRoot is set.
+func DefaultProfileRoot() string {
+	return filepath.Join(os.TempDir(), "hc-profiles")
+}
+
+// A profile directory locked by this process.
+type profile struct {
+	dir  string
+	temp bool
+}
+
+// Creates a temporary profile under root, or locks the profile named reuse if set.
+func openProfile(root, reuse string) (*profile, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, fmt.Errorf("Cannot create profile root: %v", err)
+	}
+	p := &profile{}
+	if reuse == "" {
+		p.temp = true
+		p.dir = filepath.Join(root, fmt.Sprintf("%s%x", tempProfilePrefix, time.Now().UnixNano()))
+	} else if strings.ContainsAny(reuse, `/\`) || reuse == "." || reuse == ".." ||
+		strings.HasPrefix(reuse, tempProfilePrefix) {
+		return nil, fmt.Errorf("Invalid profile name %q", reuse)
+	} else {
+		p.dir = filepath.Join(root, reuse)
+	}
+	if err := os.MkdirAll(p.dir, 0700); err != nil {
+		return nil, fmt.Errorf("Cannot create profile: %v", err)
+	}
+	if err := p.lock(); err != nil {
+		if p.temp {
+			os.RemoveAll(p.dir)
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// The lock file holds the pid of the process using the profile, followed by the pid of its
+// browser once launched, since the browser may outlive it. Checking and taking over a stale lock
+// happen under a lock of the directory, so that two processes can't both take it over.
+func (p *profile) lock() error {
+	unlock, err := lockDir(p.dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	lockPath := filepath.Join(p.dir, profileLockFile)
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return err
+		} else if !os.IsExist(err) {
+			return err
+		}
+		if pid, alive := lockOwnerAlive(lockPath); alive {
+			return fmt.Errorf("Profile %s is in use by process %d", p.dir, pid)
+		}
+		logging.Vlogf(1, "Taking over stale profile %s", p.dir)
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("Cannot take over stale profile %s", p.dir)
+}
+
+func (p *profile) setBrowserPid(pid int) error {
+	return ioutil.WriteFile(filepath.Join(p.dir, profileLockFile),
+		[]byte(fmt.Sprintf("%d\n%d\n", os.Getpid(), pid)), 0600)
+}
+
+// Unlocks the profile, and removes it if it's temporary.
+func (p *profile) release() error {
+	if p.temp {
+		return os.RemoveAll(p.dir)
+	}
+	return os.Remove(filepath.Join(p.dir, profileLockFile))
+}
+
+// Returns the first live pid in the lock file. A lock file which can't be read or parsed counts
+// as live, to be on the safe side, unless it's gone.
+func lockOwnerAlive(lockPath string) (int, bool) {
+	data, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		return 0, !os.IsNotExist(err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		// Being written.
+		return 0, true
+	}
+	for _, field := range fields {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			return 0, true
+		}
+		if processAlive(pid) {
+			return pid, true
+		}
+	}
+	return 0, false
+}
+
+// Removes temporary profiles under root whose owners are gone, e.g. after crashes, and returns
+// their paths. Empty root means DefaultProfileRoot. Reusable profiles are kept, as their stale
+// locks are taken over when they're used again.
+func CleanStaleProfiles(root string) ([]string, error) {
+	if root == "" {
+		root = DefaultProfileRoot()
+	}
+	infos, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, info := range infos {
+		if !info.IsDir() || !strings.HasPrefix(info.Name(), tempProfilePrefix) {
+			continue
+		}
+		dir := filepath.Join(root, info.Name())
+		lockPath := filepath.Join(dir, profileLockFile)
+		if _, err := os.Stat(lockPath); os.IsNotExist(err) {
+			if time.Since(info.ModTime()) < unlockedProfileGrace {
+				continue
+			}
+		} else if _, alive := lockOwnerAlive(lockPath); alive {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logging.Vlog(-1, err)
+			continue
+		}
+		removed = append(removed, dir)
+	}
+	if len(removed) > 0 {
+		logging.Vlogf(1, "Removed %d stale profiles under %s", len(removed), root)
+	}
+	return removed, nil
+}
@@ -0,0 +1,66 @@
+//go:build integration
+
+package headless_chromium_test
+
+import (
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Opens url in the default browser context of browser, which is the one kept in the profile,
+// and returns the cookies it has then.
+func storedCookies(t *testing.T, browser *hc.Browser, url string) []*protocol.Cookie {
+	t.Helper()
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	target, err := protocol.CreateTarget(&protocol.CreateTargetParams{Url: "about:blank"}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer protocol.CloseTarget(&protocol.CloseTargetParams{TargetId: target.TargetId}, conn)
+	page, err := browser.NewPageConn(string(target.TargetId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer page.Close()
+	if err := hcutil.NavigateAndWait(page, url, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	cookies, err := protocol.GetAllCookies(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hcutil.FilterCookies(cookies.Cookies, hcutil.MatchName("stored"))
+}
+
+// Browsers launched side by side don't see each other's cookies, while a reused profile keeps
+// them across launches.
+func TestIntegrationProfileIsolation(t *testing.T) {
+	fixtures := hctest.NewFixtureServer(t)
+	root := t.TempDir()
+	first := hctest.NewBrowser(t, hctest.WithProfile(root, "first"))
+	if found := storedCookies(t, first, fixtures.URL+hctest.FixtureStoredCookie); len(found) != 1 {
+		t.Fatalf("Got %+v", found)
+	}
+
+	second := hctest.NewBrowser(t, hctest.WithProfile(root, ""))
+	if found := storedCookies(t, second, fixtures.URL+hctest.FixtureStatic); len(found) != 0 {
+		t.Errorf("Second browser got %+v", found)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	again := hctest.NewBrowser(t, hctest.WithProfile(root, "first"))
+	if found := storedCookies(t, again, fixtures.URL+hctest.FixtureStatic); len(found) != 1 {
+		t.Errorf("Reused profile got %+v", found)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package headless_chromium
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Of many processes finding the same stale lock, only one takes the profile over.
+func TestProfileStaleLockTakeover(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "reused")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(dir, profileLockFile)
+	// The window between finding the lock stale and taking it over is small, so try many times.
+	for round := 0; round < 100; round++ {
+		// No such process, as pids don't go that high.
+		if err := ioutil.WriteFile(lockPath, []byte("2147483647\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		const n = 16
+		var wg sync.WaitGroup
+		profiles := make(chan *profile, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if p, err := openProfile(root, "reused"); err == nil {
+					profiles <- p
+				}
+			}()
+		}
+		wg.Wait()
+		close(profiles)
+		if len(profiles) != 1 {
+			t.Fatalf("Round %d: %d took the profile over", round, len(profiles))
+		}
+		if pid, alive := lockOwnerAlive(lockPath); !alive || pid != os.Getpid() {
+			t.Fatalf("Round %d: lock held by %d, %v", round, pid, alive)
+		}
+		if err := (<-profiles).release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := openProfile(root, "reused")
+	if err != nil {
+		t.Fatalf("Cannot lock the released profile: %v", err)
+	}
+	p.release()
+}
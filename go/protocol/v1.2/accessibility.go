@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -67,9 +67,9 @@ type AXValueSource struct {
 }
 
 type AXRelatedNode struct {
-	BackendDOMNodeId *BackendNodeId `json:"backendDOMNodeId"` // The BackendNodeId of the related DOM node.
-	Idref            string         `json:"idref,omitempty"`  // The IDRef value provided, if any.
-	Text             string         `json:"text,omitempty"`   // The text alternative of this node in the current context.
+	BackendDOMNodeId BackendNodeId `json:"backendDOMNodeId"` // The BackendNodeId of the related DOM node.
+	Idref            string        `json:"idref,omitempty"`  // The IDRef value provided, if any.
+	Text             string        `json:"text,omitempty"`   // The text alternative of this node in the current context.
 }
 
 type AXProperty struct {
@@ -137,21 +137,21 @@ const AXRelationshipAttributesOwns AXRelationshipAttributes = "owns"
 
 // A node in the accessibility tree.
 type AXNode struct {
-	NodeId           AXNodeId       `json:"nodeId"`                     // Unique identifier for this node.
-	Ignored          bool           `json:"ignored"`                    // Whether this node is ignored for accessibility
-	IgnoredReasons   []*AXProperty  `json:"ignoredReasons,omitempty"`   // Collection of reasons why this node is hidden.
-	Role             *AXValue       `json:"role,omitempty"`             // This Node's role, whether explicit or implicit.
-	Name             *AXValue       `json:"name,omitempty"`             // The accessible name for this Node.
-	Description      *AXValue       `json:"description,omitempty"`      // The accessible description for this Node.
-	Value            *AXValue       `json:"value,omitempty"`            // The value for this Node.
-	Properties       []*AXProperty  `json:"properties,omitempty"`       // All other properties
-	ChildIds         []AXNodeId     `json:"childIds,omitempty"`         // IDs for each of this node's child nodes.
-	BackendDOMNodeId *BackendNodeId `json:"backendDOMNodeId,omitempty"` // The backend ID for the associated DOM node, if any.
+	NodeId           AXNodeId      `json:"nodeId"`                     // Unique identifier for this node.
+	Ignored          bool          `json:"ignored"`                    // Whether this node is ignored for accessibility
+	IgnoredReasons   []*AXProperty `json:"ignoredReasons,omitempty"`   // Collection of reasons why this node is hidden.
+	Role             *AXValue      `json:"role,omitempty"`             // This Node's role, whether explicit or implicit.
+	Name             *AXValue      `json:"name,omitempty"`             // The accessible name for this Node.
+	Description      *AXValue      `json:"description,omitempty"`      // The accessible description for this Node.
+	Value            *AXValue      `json:"value,omitempty"`            // The value for this Node.
+	Properties       []*AXProperty `json:"properties,omitempty"`       // All other properties
+	ChildIds         []AXNodeId    `json:"childIds,omitempty"`         // IDs for each of this node's child nodes.
+	BackendDOMNodeId BackendNodeId `json:"backendDOMNodeId,omitempty"` // The backend ID for the associated DOM node, if any.
 }
 
 type GetPartialAXTreeParams struct {
-	NodeId         *NodeId `json:"nodeId"`                   // ID of node to get the partial accessibility tree for.
-	FetchRelatives bool    `json:"fetchRelatives,omitempty"` // Whether to fetch this nodes ancestors, siblings and children. Defaults to true.
+	NodeId         NodeId `json:"nodeId"`                   // ID of node to get the partial accessibility tree for.
+	FetchRelatives bool   `json:"fetchRelatives,omitempty"` // Whether to fetch this nodes ancestors, siblings and children. Defaults to true.
 }
 
 type GetPartialAXTreeResult struct {
@@ -181,17 +181,21 @@ func (cmd *GetPartialAXTreeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetPartialAXTreeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetPartialAXTreeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetPartialAXTree(params *GetPartialAXTreeParams, conn *hc.Conn) (result *GetPartialAXTreeResult, err error) {
+func GetPartialAXTree(params *GetPartialAXTreeParams, conn hc.CommandRunner) (result *GetPartialAXTreeResult, err error) {
 	cmd := NewGetPartialAXTreeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetPartialAXTreeCB func(result *GetPartialAXTreeResult, err error)
@@ -201,12 +205,17 @@ type GetPartialAXTreeCB func(result *GetPartialAXTreeResult, err error)
 type AsyncGetPartialAXTreeCommand struct {
 	params *GetPartialAXTreeParams
 	cb     GetPartialAXTreeCB
+	result *GetPartialAXTreeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetPartialAXTreeCommand(params *GetPartialAXTreeParams, cb GetPartialAXTreeCB) *AsyncGetPartialAXTreeCommand {
 	return &AsyncGetPartialAXTreeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -235,11 +244,29 @@ func (cmd *AsyncGetPartialAXTreeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetPartialAXTreeCommand) Wait(ctx context.Context) (*GetPartialAXTreeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetPartialAXTreeCommand) Send(conn hc.CommandRunner) *AsyncGetPartialAXTreeCommand {
+	async := NewAsyncGetPartialAXTreeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -32,7 +32,7 @@ type AnimationEffect struct {
 	Duration       float64        `json:"duration"`                // AnimationEffect's iteration duration.
 	Direction      string         `json:"direction"`               // AnimationEffect's playback direction.
 	Fill           string         `json:"fill"`                    // AnimationEffect's fill mode.
-	BackendNodeId  *BackendNodeId `json:"backendNodeId"`           // AnimationEffect's target node.
+	BackendNodeId  BackendNodeId  `json:"backendNodeId"`           // AnimationEffect's target node.
 	KeyframesRule  *KeyframesRule `json:"keyframesRule,omitempty"` // AnimationEffect's keyframes.
 	Easing         string         `json:"easing"`                  // AnimationEffect's timing function.
 }
@@ -50,7 +50,6 @@ type KeyframeStyle struct {
 }
 
 // Enables animation domain notifications.
-
 type AnimationEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -68,30 +67,34 @@ func (cmd *AnimationEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *AnimationEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *AnimationEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AnimationEnable(conn *hc.Conn) (err error) {
+func AnimationEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewAnimationEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type AnimationEnableCB func(err error)
 
 // Enables animation domain notifications.
-
 type AsyncAnimationEnableCommand struct {
-	cb AnimationEnableCB
+	cb   AnimationEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAnimationEnableCommand(cb AnimationEnableCB) *AsyncAnimationEnableCommand {
 	return &AsyncAnimationEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -109,11 +112,31 @@ func (cmd *AnimationEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncAnimationEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables animation domain notifications.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAnimationEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AnimationEnableCommand) Send(conn hc.CommandRunner) *AsyncAnimationEnableCommand {
+	async := NewAsyncAnimationEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Disables animation domain notifications.
 type AnimationDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -131,30 +154,34 @@ func (cmd *AnimationDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *AnimationDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *AnimationDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AnimationDisable(conn *hc.Conn) (err error) {
+func AnimationDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewAnimationDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type AnimationDisableCB func(err error)
 
 // Disables animation domain notifications.
-
 type AsyncAnimationDisableCommand struct {
-	cb AnimationDisableCB
+	cb   AnimationDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAnimationDisableCommand(cb AnimationDisableCB) *AsyncAnimationDisableCommand {
 	return &AsyncAnimationDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -172,7 +199,28 @@ func (cmd *AnimationDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncAnimationDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAnimationDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AnimationDisableCommand) Send(conn hc.CommandRunner) *AsyncAnimationDisableCommand {
+	async := NewAsyncAnimationDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetPlaybackRateResult struct {
@@ -180,7 +228,6 @@ type GetPlaybackRateResult struct {
 }
 
 // Gets the playback rate of the document timeline.
-
 type GetPlaybackRateCommand struct {
 	result GetPlaybackRateResult
 	wg     sync.WaitGroup
@@ -199,30 +246,38 @@ func (cmd *GetPlaybackRateCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetPlaybackRateCommand) Run(conn *hc.Conn) error {
+func (cmd *GetPlaybackRateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetPlaybackRate(conn *hc.Conn) (result *GetPlaybackRateResult, err error) {
+func GetPlaybackRate(conn hc.CommandRunner) (result *GetPlaybackRateResult, err error) {
 	cmd := NewGetPlaybackRateCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetPlaybackRateCB func(result *GetPlaybackRateResult, err error)
 
 // Gets the playback rate of the document timeline.
-
 type AsyncGetPlaybackRateCommand struct {
-	cb GetPlaybackRateCB
+	cb     GetPlaybackRateCB
+	result *GetPlaybackRateResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetPlaybackRateCommand(cb GetPlaybackRateCB) *AsyncGetPlaybackRateCommand {
 	return &AsyncGetPlaybackRateCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -251,21 +306,38 @@ func (cmd *AsyncGetPlaybackRateCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetPlaybackRateCommand) Wait(ctx context.Context) (*GetPlaybackRateResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetPlaybackRateCommand) Send(conn hc.CommandRunner) *AsyncGetPlaybackRateCommand {
+	async := NewAsyncGetPlaybackRateCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetPlaybackRateParams struct {
 	PlaybackRate float64 `json:"playbackRate"` // Playback rate for animations on page
 }
 
 // Sets the playback rate of the document timeline.
-
 type SetPlaybackRateCommand struct {
 	params *SetPlaybackRateParams
 	wg     sync.WaitGroup
@@ -286,32 +358,36 @@ func (cmd *SetPlaybackRateCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetPlaybackRateCommand) Run(conn *hc.Conn) error {
+func (cmd *SetPlaybackRateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetPlaybackRate(params *SetPlaybackRateParams, conn *hc.Conn) (err error) {
+func SetPlaybackRate(params *SetPlaybackRateParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetPlaybackRateCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetPlaybackRateCB func(err error)
 
 // Sets the playback rate of the document timeline.
-
 type AsyncSetPlaybackRateCommand struct {
 	params *SetPlaybackRateParams
 	cb     SetPlaybackRateCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetPlaybackRateCommand(params *SetPlaybackRateParams, cb SetPlaybackRateCB) *AsyncSetPlaybackRateCommand {
 	return &AsyncSetPlaybackRateCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -329,7 +405,28 @@ func (cmd *SetPlaybackRateCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetPlaybackRateCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetPlaybackRateCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetPlaybackRateCommand) Send(conn hc.CommandRunner) *AsyncSetPlaybackRateCommand {
+	async := NewAsyncSetPlaybackRateCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetCurrentTimeParams struct {
@@ -341,7 +438,6 @@ type GetCurrentTimeResult struct {
 }
 
 // Returns the current time of the an animation.
-
 type GetCurrentTimeCommand struct {
 	params *GetCurrentTimeParams
 	result GetCurrentTimeResult
@@ -363,32 +459,40 @@ func (cmd *GetCurrentTimeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetCurrentTimeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetCurrentTimeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetCurrentTime(params *GetCurrentTimeParams, conn *hc.Conn) (result *GetCurrentTimeResult, err error) {
+func GetCurrentTime(params *GetCurrentTimeParams, conn hc.CommandRunner) (result *GetCurrentTimeResult, err error) {
 	cmd := NewGetCurrentTimeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetCurrentTimeCB func(result *GetCurrentTimeResult, err error)
 
 // Returns the current time of the an animation.
-
 type AsyncGetCurrentTimeCommand struct {
 	params *GetCurrentTimeParams
 	cb     GetCurrentTimeCB
+	result *GetCurrentTimeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetCurrentTimeCommand(params *GetCurrentTimeParams, cb GetCurrentTimeCB) *AsyncGetCurrentTimeCommand {
 	return &AsyncGetCurrentTimeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -417,22 +521,39 @@ func (cmd *AsyncGetCurrentTimeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetCurrentTimeCommand) Wait(ctx context.Context) (*GetCurrentTimeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetCurrentTimeCommand) Send(conn hc.CommandRunner) *AsyncGetCurrentTimeCommand {
+	async := NewAsyncGetCurrentTimeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetPausedParams struct {
 	Animations []string `json:"animations"` // Animations to set the pause state of.
 	Paused     bool     `json:"paused"`     // Paused state to set to.
 }
 
 // Sets the paused state of a set of animations.
-
 type SetPausedCommand struct {
 	params *SetPausedParams
 	wg     sync.WaitGroup
@@ -453,32 +574,36 @@ func (cmd *SetPausedCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetPausedCommand) Run(conn *hc.Conn) error {
+func (cmd *SetPausedCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetPaused(params *SetPausedParams, conn *hc.Conn) (err error) {
+func SetPaused(params *SetPausedParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetPausedCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetPausedCB func(err error)
 
 // Sets the paused state of a set of animations.
-
 type AsyncSetPausedCommand struct {
 	params *SetPausedParams
 	cb     SetPausedCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetPausedCommand(params *SetPausedParams, cb SetPausedCB) *AsyncSetPausedCommand {
 	return &AsyncSetPausedCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -496,7 +621,28 @@ func (cmd *SetPausedCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetPausedCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetPausedCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetPausedCommand) Send(conn hc.CommandRunner) *AsyncSetPausedCommand {
+	async := NewAsyncSetPausedCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetTimingParams struct {
@@ -506,7 +652,6 @@ type SetTimingParams struct {
 }
 
 // Sets the timing of an animation node.
-
 type SetTimingCommand struct {
 	params *SetTimingParams
 	wg     sync.WaitGroup
@@ -527,32 +672,36 @@ func (cmd *SetTimingCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetTimingCommand) Run(conn *hc.Conn) error {
+func (cmd *SetTimingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetTiming(params *SetTimingParams, conn *hc.Conn) (err error) {
+func SetTiming(params *SetTimingParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetTimingCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetTimingCB func(err error)
 
 // Sets the timing of an animation node.
-
 type AsyncSetTimingCommand struct {
 	params *SetTimingParams
 	cb     SetTimingCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetTimingCommand(params *SetTimingParams, cb SetTimingCB) *AsyncSetTimingCommand {
 	return &AsyncSetTimingCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -570,7 +719,28 @@ func (cmd *SetTimingCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetTimingCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetTimingCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetTimingCommand) Send(conn hc.CommandRunner) *AsyncSetTimingCommand {
+	async := NewAsyncSetTimingCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SeekAnimationsParams struct {
@@ -579,7 +749,6 @@ type SeekAnimationsParams struct {
 }
 
 // Seek a set of animations to a particular time within each animation.
-
 type SeekAnimationsCommand struct {
 	params *SeekAnimationsParams
 	wg     sync.WaitGroup
@@ -600,32 +769,36 @@ func (cmd *SeekAnimationsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SeekAnimationsCommand) Run(conn *hc.Conn) error {
+func (cmd *SeekAnimationsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SeekAnimations(params *SeekAnimationsParams, conn *hc.Conn) (err error) {
+func SeekAnimations(params *SeekAnimationsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSeekAnimationsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SeekAnimationsCB func(err error)
 
 // Seek a set of animations to a particular time within each animation.
-
 type AsyncSeekAnimationsCommand struct {
 	params *SeekAnimationsParams
 	cb     SeekAnimationsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSeekAnimationsCommand(params *SeekAnimationsParams, cb SeekAnimationsCB) *AsyncSeekAnimationsCommand {
 	return &AsyncSeekAnimationsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -643,7 +816,28 @@ func (cmd *SeekAnimationsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSeekAnimationsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSeekAnimationsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SeekAnimationsCommand) Send(conn hc.CommandRunner) *AsyncSeekAnimationsCommand {
+	async := NewAsyncSeekAnimationsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ReleaseAnimationsParams struct {
@@ -651,7 +845,6 @@ type ReleaseAnimationsParams struct {
 }
 
 // Releases a set of animations to no longer be manipulated.
-
 type ReleaseAnimationsCommand struct {
 	params *ReleaseAnimationsParams
 	wg     sync.WaitGroup
@@ -672,32 +865,36 @@ func (cmd *ReleaseAnimationsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReleaseAnimationsCommand) Run(conn *hc.Conn) error {
+func (cmd *ReleaseAnimationsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ReleaseAnimations(params *ReleaseAnimationsParams, conn *hc.Conn) (err error) {
+func ReleaseAnimations(params *ReleaseAnimationsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewReleaseAnimationsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ReleaseAnimationsCB func(err error)
 
 // Releases a set of animations to no longer be manipulated.
-
 type AsyncReleaseAnimationsCommand struct {
 	params *ReleaseAnimationsParams
 	cb     ReleaseAnimationsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReleaseAnimationsCommand(params *ReleaseAnimationsParams, cb ReleaseAnimationsCB) *AsyncReleaseAnimationsCommand {
 	return &AsyncReleaseAnimationsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -715,7 +912,28 @@ func (cmd *ReleaseAnimationsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncReleaseAnimationsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReleaseAnimationsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReleaseAnimationsCommand) Send(conn hc.CommandRunner) *AsyncReleaseAnimationsCommand {
+	async := NewAsyncReleaseAnimationsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ResolveAnimationParams struct {
@@ -727,7 +945,6 @@ type ResolveAnimationResult struct {
 }
 
 // Gets the remote object of the Animation.
-
 type ResolveAnimationCommand struct {
 	params *ResolveAnimationParams
 	result ResolveAnimationResult
@@ -749,32 +966,40 @@ func (cmd *ResolveAnimationCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ResolveAnimationCommand) Run(conn *hc.Conn) error {
+func (cmd *ResolveAnimationCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ResolveAnimation(params *ResolveAnimationParams, conn *hc.Conn) (result *ResolveAnimationResult, err error) {
+func ResolveAnimation(params *ResolveAnimationParams, conn hc.CommandRunner) (result *ResolveAnimationResult, err error) {
 	cmd := NewResolveAnimationCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type ResolveAnimationCB func(result *ResolveAnimationResult, err error)
 
 // Gets the remote object of the Animation.
-
 type AsyncResolveAnimationCommand struct {
 	params *ResolveAnimationParams
 	cb     ResolveAnimationCB
+	result *ResolveAnimationResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncResolveAnimationCommand(params *ResolveAnimationParams, cb ResolveAnimationCB) *AsyncResolveAnimationCommand {
 	return &AsyncResolveAnimationCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -803,65 +1028,128 @@ func (cmd *AsyncResolveAnimationCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncResolveAnimationCommand) Wait(ctx context.Context) (*ResolveAnimationResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
-// Event for each animation that has been created.
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ResolveAnimationCommand) Send(conn hc.CommandRunner) *AsyncResolveAnimationCommand {
+	async := NewAsyncResolveAnimationCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Event for each animation that has been created.
 type AnimationCreatedEvent struct {
 	Id string `json:"id"` // Id of the animation that was created.
 }
 
-func OnAnimationCreated(conn *hc.Conn, cb func(evt *AnimationCreatedEvent)) {
+// Calls cb for each Animation.animationCreated event, till the returned func is called.
+func OnAnimationCreated(conn hc.EventSource, cb func(evt *AnimationCreatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AnimationCreatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Animation.animationCreated", sink)
+	return func() { conn.RemoveEventSink("Animation.animationCreated", sink) }
 }
 
-// Event for animation that has been started.
+// Like OnAnimationCreated, but cb also gets hc.EventMeta.
+func OnAnimationCreatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AnimationCreatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AnimationCreatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Animation.animationCreated", sink)
+	return func() { conn.RemoveEventSink("Animation.animationCreated", sink) }
+}
 
+// Event for animation that has been started.
 type AnimationStartedEvent struct {
 	Animation *Animation `json:"animation"` // Animation that was started.
 }
 
-func OnAnimationStarted(conn *hc.Conn, cb func(evt *AnimationStartedEvent)) {
+// Calls cb for each Animation.animationStarted event, till the returned func is called.
+func OnAnimationStarted(conn hc.EventSource, cb func(evt *AnimationStartedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AnimationStartedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Animation.animationStarted", sink)
+	return func() { conn.RemoveEventSink("Animation.animationStarted", sink) }
 }
 
-// Event for when an animation has been cancelled.
+// Like OnAnimationStarted, but cb also gets hc.EventMeta.
+func OnAnimationStartedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AnimationStartedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AnimationStartedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Animation.animationStarted", sink)
+	return func() { conn.RemoveEventSink("Animation.animationStarted", sink) }
+}
 
+// Event for when an animation has been cancelled.
 type AnimationCanceledEvent struct {
 	Id string `json:"id"` // Id of the animation that was cancelled.
 }
 
-func OnAnimationCanceled(conn *hc.Conn, cb func(evt *AnimationCanceledEvent)) {
+// Calls cb for each Animation.animationCanceled event, till the returned func is called.
+func OnAnimationCanceled(conn hc.EventSource, cb func(evt *AnimationCanceledEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AnimationCanceledEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Animation.animationCanceled", sink)
+	return func() { conn.RemoveEventSink("Animation.animationCanceled", sink) }
+}
+
+// Like OnAnimationCanceled, but cb also gets hc.EventMeta.
+func OnAnimationCanceledMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AnimationCanceledEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AnimationCanceledEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Animation.animationCanceled", sink)
+	return func() { conn.RemoveEventSink("Animation.animationCanceled", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -25,9 +25,9 @@ type ApplicationCache struct {
 
 // Frame identifier - manifest URL pair.
 type FrameWithManifest struct {
-	FrameId     *FrameId `json:"frameId"`     // Frame identifier.
-	ManifestURL string   `json:"manifestURL"` // Manifest URL.
-	Status      int      `json:"status"`      // Application cache status.
+	FrameId     FrameId `json:"frameId"`     // Frame identifier.
+	ManifestURL string  `json:"manifestURL"` // Manifest URL.
+	Status      int     `json:"status"`      // Application cache status.
 }
 
 type GetFramesWithManifestsResult struct {
@@ -35,7 +35,6 @@ type GetFramesWithManifestsResult struct {
 }
 
 // Returns array of frame identifiers with manifest urls for each frame containing a document associated with some application cache.
-
 type GetFramesWithManifestsCommand struct {
 	result GetFramesWithManifestsResult
 	wg     sync.WaitGroup
@@ -54,30 +53,38 @@ func (cmd *GetFramesWithManifestsCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetFramesWithManifestsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetFramesWithManifestsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetFramesWithManifests(conn *hc.Conn) (result *GetFramesWithManifestsResult, err error) {
+func GetFramesWithManifests(conn hc.CommandRunner) (result *GetFramesWithManifestsResult, err error) {
 	cmd := NewGetFramesWithManifestsCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetFramesWithManifestsCB func(result *GetFramesWithManifestsResult, err error)
 
 // Returns array of frame identifiers with manifest urls for each frame containing a document associated with some application cache.
-
 type AsyncGetFramesWithManifestsCommand struct {
-	cb GetFramesWithManifestsCB
+	cb     GetFramesWithManifestsCB
+	result *GetFramesWithManifestsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetFramesWithManifestsCommand(cb GetFramesWithManifestsCB) *AsyncGetFramesWithManifestsCommand {
 	return &AsyncGetFramesWithManifestsCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -106,17 +113,34 @@ func (cmd *AsyncGetFramesWithManifestsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Enables application cache domain notifications.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetFramesWithManifestsCommand) Wait(ctx context.Context) (*GetFramesWithManifestsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetFramesWithManifestsCommand) Send(conn hc.CommandRunner) *AsyncGetFramesWithManifestsCommand {
+	async := NewAsyncGetFramesWithManifestsCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Enables application cache domain notifications.
 type ApplicationCacheEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -134,30 +158,34 @@ func (cmd *ApplicationCacheEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ApplicationCacheEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *ApplicationCacheEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ApplicationCacheEnable(conn *hc.Conn) (err error) {
+func ApplicationCacheEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewApplicationCacheEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ApplicationCacheEnableCB func(err error)
 
 // Enables application cache domain notifications.
-
 type AsyncApplicationCacheEnableCommand struct {
-	cb ApplicationCacheEnableCB
+	cb   ApplicationCacheEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncApplicationCacheEnableCommand(cb ApplicationCacheEnableCB) *AsyncApplicationCacheEnableCommand {
 	return &AsyncApplicationCacheEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -175,11 +203,32 @@ func (cmd *ApplicationCacheEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncApplicationCacheEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncApplicationCacheEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ApplicationCacheEnableCommand) Send(conn hc.CommandRunner) *AsyncApplicationCacheEnableCommand {
+	async := NewAsyncApplicationCacheEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetManifestForFrameParams struct {
-	FrameId *FrameId `json:"frameId"` // Identifier of the frame containing document whose manifest is retrieved.
+	FrameId FrameId `json:"frameId"` // Identifier of the frame containing document whose manifest is retrieved.
 }
 
 type GetManifestForFrameResult struct {
@@ -187,7 +236,6 @@ type GetManifestForFrameResult struct {
 }
 
 // Returns manifest URL for document in the given frame.
-
 type GetManifestForFrameCommand struct {
 	params *GetManifestForFrameParams
 	result GetManifestForFrameResult
@@ -209,32 +257,40 @@ func (cmd *GetManifestForFrameCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetManifestForFrameCommand) Run(conn *hc.Conn) error {
+func (cmd *GetManifestForFrameCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetManifestForFrame(params *GetManifestForFrameParams, conn *hc.Conn) (result *GetManifestForFrameResult, err error) {
+func GetManifestForFrame(params *GetManifestForFrameParams, conn hc.CommandRunner) (result *GetManifestForFrameResult, err error) {
 	cmd := NewGetManifestForFrameCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetManifestForFrameCB func(result *GetManifestForFrameResult, err error)
 
 // Returns manifest URL for document in the given frame.
-
 type AsyncGetManifestForFrameCommand struct {
 	params *GetManifestForFrameParams
 	cb     GetManifestForFrameCB
+	result *GetManifestForFrameResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetManifestForFrameCommand(params *GetManifestForFrameParams, cb GetManifestForFrameCB) *AsyncGetManifestForFrameCommand {
 	return &AsyncGetManifestForFrameCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -263,17 +319,35 @@ func (cmd *AsyncGetManifestForFrameCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetManifestForFrameCommand) Wait(ctx context.Context) (*GetManifestForFrameResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetManifestForFrameCommand) Send(conn hc.CommandRunner) *AsyncGetManifestForFrameCommand {
+	async := NewAsyncGetManifestForFrameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetApplicationCacheForFrameParams struct {
-	FrameId *FrameId `json:"frameId"` // Identifier of the frame containing document whose application cache is retrieved.
+	FrameId FrameId `json:"frameId"` // Identifier of the frame containing document whose application cache is retrieved.
 }
 
 type GetApplicationCacheForFrameResult struct {
@@ -281,7 +355,6 @@ type GetApplicationCacheForFrameResult struct {
 }
 
 // Returns relevant application cache data for the document in given frame.
-
 type GetApplicationCacheForFrameCommand struct {
 	params *GetApplicationCacheForFrameParams
 	result GetApplicationCacheForFrameResult
@@ -303,32 +376,40 @@ func (cmd *GetApplicationCacheForFrameCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetApplicationCacheForFrameCommand) Run(conn *hc.Conn) error {
+func (cmd *GetApplicationCacheForFrameCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetApplicationCacheForFrame(params *GetApplicationCacheForFrameParams, conn *hc.Conn) (result *GetApplicationCacheForFrameResult, err error) {
+func GetApplicationCacheForFrame(params *GetApplicationCacheForFrameParams, conn hc.CommandRunner) (result *GetApplicationCacheForFrameResult, err error) {
 	cmd := NewGetApplicationCacheForFrameCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetApplicationCacheForFrameCB func(result *GetApplicationCacheForFrameResult, err error)
 
 // Returns relevant application cache data for the document in given frame.
-
 type AsyncGetApplicationCacheForFrameCommand struct {
 	params *GetApplicationCacheForFrameParams
 	cb     GetApplicationCacheForFrameCB
+	result *GetApplicationCacheForFrameResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetApplicationCacheForFrameCommand(params *GetApplicationCacheForFrameParams, cb GetApplicationCacheForFrameCB) *AsyncGetApplicationCacheForFrameCommand {
 	return &AsyncGetApplicationCacheForFrameCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -357,45 +438,95 @@ func (cmd *AsyncGetApplicationCacheForFrameCommand) Done(data []byte, err error)
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetApplicationCacheForFrameCommand) Wait(ctx context.Context) (*GetApplicationCacheForFrameResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetApplicationCacheForFrameCommand) Send(conn hc.CommandRunner) *AsyncGetApplicationCacheForFrameCommand {
+	async := NewAsyncGetApplicationCacheForFrameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ApplicationCacheStatusUpdatedEvent struct {
-	FrameId     *FrameId `json:"frameId"`     // Identifier of the frame containing document whose application cache updated status.
-	ManifestURL string   `json:"manifestURL"` // Manifest URL.
-	Status      int      `json:"status"`      // Updated application cache status.
+	FrameId     FrameId `json:"frameId"`     // Identifier of the frame containing document whose application cache updated status.
+	ManifestURL string  `json:"manifestURL"` // Manifest URL.
+	Status      int     `json:"status"`      // Updated application cache status.
 }
 
-func OnApplicationCacheStatusUpdated(conn *hc.Conn, cb func(evt *ApplicationCacheStatusUpdatedEvent)) {
+// Calls cb for each ApplicationCache.applicationCacheStatusUpdated event, till the returned func is called.
+func OnApplicationCacheStatusUpdated(conn hc.EventSource, cb func(evt *ApplicationCacheStatusUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ApplicationCacheStatusUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("ApplicationCache.applicationCacheStatusUpdated", sink)
+	return func() { conn.RemoveEventSink("ApplicationCache.applicationCacheStatusUpdated", sink) }
+}
+
+// Like OnApplicationCacheStatusUpdated, but cb also gets hc.EventMeta.
+func OnApplicationCacheStatusUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ApplicationCacheStatusUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ApplicationCacheStatusUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("ApplicationCache.applicationCacheStatusUpdated", sink)
+	return func() { conn.RemoveEventSink("ApplicationCache.applicationCacheStatusUpdated", sink) }
 }
 
 type NetworkStateUpdatedEvent struct {
 	IsNowOnline bool `json:"isNowOnline"`
 }
 
-func OnNetworkStateUpdated(conn *hc.Conn, cb func(evt *NetworkStateUpdatedEvent)) {
+// Calls cb for each ApplicationCache.networkStateUpdated event, till the returned func is called.
+func OnNetworkStateUpdated(conn hc.EventSource, cb func(evt *NetworkStateUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &NetworkStateUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("ApplicationCache.networkStateUpdated", sink)
+	return func() { conn.RemoveEventSink("ApplicationCache.networkStateUpdated", sink) }
+}
+
+// Like OnNetworkStateUpdated, but cb also gets hc.EventMeta.
+func OnNetworkStateUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *NetworkStateUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &NetworkStateUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("ApplicationCache.networkStateUpdated", sink)
+	return func() { conn.RemoveEventSink("ApplicationCache.networkStateUpdated", sink) }
 }
@@ -0,0 +1,60 @@
+package protocol
+
+// Not generated. Accessors of the interleaved name/value arrays of attributes.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Returned by AttributesMap when the interleaved array of names and values is misaligned, e.g.
+// has an odd length, as some Chromium builds send for values with unusual characters.
+type MalformedAttributesError struct {
+	// 0 if unknown, e.g. for GetAttributesResult.
+	NodeId NodeId
+	// The array as received.
+	Attributes []string
+	// Number of attributes recovered anyway.
+	Recovered int
+}
+
+func (e *MalformedAttributesError) Error() string {
+	return fmt.Sprintf("Malformed attributes of node %d, %d recovered: %q", e.NodeId, e.Recovered,
+		e.Attributes)
+}
+
+// Returns the attributes by name. If the array is malformed, returns those recovered along with
+// *MalformedAttributesError, whose NodeId the caller should set.
+func (r *GetAttributesResult) AttributesMap() (map[string]string, error) {
+	return attributesMap(0, r.Attributes)
+}
+
+// Like GetAttributesResult.AttributesMap.
+func (n *Node) AttributesMap() (map[string]string, error) {
+	return attributesMap(n.NodeId, n.Attributes)
+}
+
+// Pairs are taken from the start as long as the names are valid, then from the end. So a missing
+// or extra entry which shifts a value into the place of a name only loses the attributes around
+// it. A shift going unnoticed that way is at least reported for odd lengths.
+func attributesMap(nodeId NodeId, attrs []string) (map[string]string, error) {
+	m := make(map[string]string, len(attrs)/2)
+	start, end := 0, len(attrs)
+	for ; start+2 <= end && validAttributeName(attrs[start]); start += 2 {
+		m[attrs[start]] = attrs[start+1]
+	}
+	if start == end {
+		return m, nil
+	}
+	for ; end-2 >= start && validAttributeName(attrs[end-2]); end -= 2 {
+		if _, ok := m[attrs[end-2]]; !ok {
+			m[attrs[end-2]] = attrs[end-1]
+		}
+	}
+	return m, &MalformedAttributesError{NodeId: nodeId, Attributes: attrs, Recovered: len(m)}
+}
+
+// Names never are empty or contain whitespace, while values may.
+func validAttributeName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n\f\r")
+}
@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttributesMap(t *testing.T) {
+	for _, c := range []struct {
+		attrs     []string
+		want      map[string]string
+		malformed bool
+	}{
+		{nil, map[string]string{}, false},
+		{[]string{"id", "a", "class", "b c"}, map[string]string{"id": "a", "class": "b c"}, false},
+		// A value missing at the end.
+		{[]string{"id", "a", "class"}, map[string]string{"id": "a"}, true},
+		// An extra entry in the middle shifts a value into a name, which has a space.
+		{[]string{"id", "a", "title", "x", "y z", "href", "/"},
+			map[string]string{"id": "a", "title": "x", "href": "/"}, true},
+		// A name missing at the start.
+		{[]string{"b c", "id", "a"}, map[string]string{"id": "a"}, true},
+	} {
+		node := &Node{NodeId: 7, Attributes: c.attrs}
+		got, err := node.AttributesMap()
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Got %v for %q, not %v", got, c.attrs, c.want)
+		}
+		malformed, ok := err.(*MalformedAttributesError)
+		if ok != c.malformed {
+			t.Errorf("Got error %v for %q", err, c.attrs)
+		} else if ok && (malformed.NodeId != 7 || malformed.Recovered != len(c.want)) {
+			t.Errorf("Got %+v for %q", malformed, c.attrs)
+		}
+	}
+}
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -32,7 +32,6 @@ type RequestCacheNamesResult struct {
 }
 
 // Requests cache names.
-
 type RequestCacheNamesCommand struct {
 	params *RequestCacheNamesParams
 	result RequestCacheNamesResult
@@ -54,32 +53,40 @@ func (cmd *RequestCacheNamesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestCacheNamesCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestCacheNamesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestCacheNames(params *RequestCacheNamesParams, conn *hc.Conn) (result *RequestCacheNamesResult, err error) {
+func RequestCacheNames(params *RequestCacheNamesParams, conn hc.CommandRunner) (result *RequestCacheNamesResult, err error) {
 	cmd := NewRequestCacheNamesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestCacheNamesCB func(result *RequestCacheNamesResult, err error)
 
 // Requests cache names.
-
 type AsyncRequestCacheNamesCommand struct {
 	params *RequestCacheNamesParams
 	cb     RequestCacheNamesCB
+	result *RequestCacheNamesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestCacheNamesCommand(params *RequestCacheNamesParams, cb RequestCacheNamesCB) *AsyncRequestCacheNamesCommand {
 	return &AsyncRequestCacheNamesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -108,13 +115,31 @@ func (cmd *AsyncRequestCacheNamesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestCacheNamesCommand) Wait(ctx context.Context) (*RequestCacheNamesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestCacheNamesCommand) Send(conn hc.CommandRunner) *AsyncRequestCacheNamesCommand {
+	async := NewAsyncRequestCacheNamesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RequestEntriesParams struct {
@@ -129,7 +154,6 @@ type RequestEntriesResult struct {
 }
 
 // Requests data from cache.
-
 type RequestEntriesCommand struct {
 	params *RequestEntriesParams
 	result RequestEntriesResult
@@ -151,32 +175,40 @@ func (cmd *RequestEntriesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestEntriesCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestEntriesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestEntries(params *RequestEntriesParams, conn *hc.Conn) (result *RequestEntriesResult, err error) {
+func RequestEntries(params *RequestEntriesParams, conn hc.CommandRunner) (result *RequestEntriesResult, err error) {
 	cmd := NewRequestEntriesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestEntriesCB func(result *RequestEntriesResult, err error)
 
 // Requests data from cache.
-
 type AsyncRequestEntriesCommand struct {
 	params *RequestEntriesParams
 	cb     RequestEntriesCB
+	result *RequestEntriesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestEntriesCommand(params *RequestEntriesParams, cb RequestEntriesCB) *AsyncRequestEntriesCommand {
 	return &AsyncRequestEntriesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -205,21 +237,38 @@ func (cmd *AsyncRequestEntriesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestEntriesCommand) Wait(ctx context.Context) (*RequestEntriesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestEntriesCommand) Send(conn hc.CommandRunner) *AsyncRequestEntriesCommand {
+	async := NewAsyncRequestEntriesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type DeleteCacheParams struct {
 	CacheId CacheId `json:"cacheId"` // Id of cache for deletion.
 }
 
 // Deletes a cache.
-
 type DeleteCacheCommand struct {
 	params *DeleteCacheParams
 	wg     sync.WaitGroup
@@ -240,32 +289,36 @@ func (cmd *DeleteCacheCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DeleteCacheCommand) Run(conn *hc.Conn) error {
+func (cmd *DeleteCacheCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DeleteCache(params *DeleteCacheParams, conn *hc.Conn) (err error) {
+func DeleteCache(params *DeleteCacheParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDeleteCacheCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DeleteCacheCB func(err error)
 
 // Deletes a cache.
-
 type AsyncDeleteCacheCommand struct {
 	params *DeleteCacheParams
 	cb     DeleteCacheCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDeleteCacheCommand(params *DeleteCacheParams, cb DeleteCacheCB) *AsyncDeleteCacheCommand {
 	return &AsyncDeleteCacheCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -283,7 +336,28 @@ func (cmd *DeleteCacheCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDeleteCacheCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDeleteCacheCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DeleteCacheCommand) Send(conn hc.CommandRunner) *AsyncDeleteCacheCommand {
+	async := NewAsyncDeleteCacheCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DeleteEntryParams struct {
@@ -292,7 +366,6 @@ type DeleteEntryParams struct {
 }
 
 // Deletes a cache entry.
-
 type DeleteEntryCommand struct {
 	params *DeleteEntryParams
 	wg     sync.WaitGroup
@@ -313,32 +386,36 @@ func (cmd *DeleteEntryCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DeleteEntryCommand) Run(conn *hc.Conn) error {
+func (cmd *DeleteEntryCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DeleteEntry(params *DeleteEntryParams, conn *hc.Conn) (err error) {
+func DeleteEntry(params *DeleteEntryParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDeleteEntryCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DeleteEntryCB func(err error)
 
 // Deletes a cache entry.
-
 type AsyncDeleteEntryCommand struct {
 	params *DeleteEntryParams
 	cb     DeleteEntryCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDeleteEntryCommand(params *DeleteEntryParams, cb DeleteEntryCB) *AsyncDeleteEntryCommand {
 	return &AsyncDeleteEntryCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -356,5 +433,26 @@ func (cmd *DeleteEntryCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDeleteEntryCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDeleteEntryCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DeleteEntryCommand) Send(conn hc.CommandRunner) *AsyncDeleteEntryCommand {
+	async := NewAsyncDeleteEntryCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
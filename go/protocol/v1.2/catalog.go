@@ -0,0 +1,584 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"strings"
+	"sync"
+)
+
+// A field of params, results or events.
+type FieldSpec struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+type CommandSpec struct {
+	Method          string
+	Params, Results []FieldSpec
+	Experimental    bool
+	// Kinds of targets supporting the command.
+	Targets hc.TargetKinds
+
+	newParams, newResult func() interface{}
+}
+
+type EventSpec struct {
+	Method       string
+	Params       []FieldSpec
+	Experimental bool
+	// Kinds of targets firing the event.
+	Targets hc.TargetKinds
+
+	newEvent func() interface{}
+}
+
+// A command built at runtime by NewCommandFromJSON.
+type DynamicCommand struct {
+	method string
+	params interface{}
+	result interface{}
+	wg     sync.WaitGroup
+	err    error
+}
+
+// Builds the command of method, with params in JSON. The result is unmarshaled into the type
+// generated for it, e.g. *NavigateResult.
+func NewCommandFromJSON(method string, params json.RawMessage) (*DynamicCommand, error) {
+	spec := Commands[method]
+	if spec == nil {
+		return nil, fmt.Errorf("Unknown command '%s'", method)
+	}
+	cmd := &DynamicCommand{method: method}
+	if spec.newParams != nil {
+		cmd.params = spec.newParams()
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, cmd.params); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if spec.newResult != nil {
+		cmd.result = spec.newResult()
+	}
+	return cmd, nil
+}
+
+func (cmd *DynamicCommand) Name() string {
+	return cmd.method
+}
+
+func (cmd *DynamicCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *DynamicCommand) Done(data []byte, err error) {
+	if err == nil && cmd.result != nil {
+		err = json.Unmarshal(data, cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+// Runs the command and returns its result, which is nil if the command has no result.
+func (cmd *DynamicCommand) Run(conn hc.CommandRunner) (interface{}, error) {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return nil, err
+	}
+	cmd.wg.Wait()
+	return cmd.result, cmd.err
+}
+
+// Unmarshals params of event method into the type generated for it, e.g. *LoadEventFiredEvent.
+func DecodeEvent(method string, params json.RawMessage) (interface{}, error) {
+	spec := Events[method]
+	if spec == nil {
+		return nil, fmt.Errorf("Unknown event '%s'", method)
+	}
+	evt := spec.newEvent()
+	if err := json.Unmarshal(params, evt); err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+func init() {
+	hc.SetSchemaChecker(checkSchema)
+	hc.SetTargetKindsFunc(targetKinds)
+}
+
+// Returns the kinds of targets supporting command or event method, or 0 if it's unknown.
+func targetKinds(method string) hc.TargetKinds {
+	if spec := Commands[method]; spec != nil {
+		return spec.Targets
+	}
+	if spec := Events[method]; spec != nil {
+		return spec.Targets
+	}
+	return 0
+}
+
+// Checks a command result or event for unknown fields at any level, and for missing required
+// fields at the top level.
+func checkSchema(method string, event bool, data []byte) *hc.SchemaError {
+	var fields []FieldSpec
+	var v interface{}
+	if event {
+		spec := Events[method]
+		if spec == nil {
+			return nil
+		}
+		fields, v = spec.Params, spec.newEvent()
+	} else {
+		spec := Commands[method]
+		if spec == nil || spec.newResult == nil {
+			return nil
+		}
+		fields, v = spec.Results, spec.newResult()
+	}
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var field string
+		if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+			field = strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), "\"")
+		}
+		return &hc.SchemaError{Method: method, Event: event, Field: field, Err: err}
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return &hc.SchemaError{Method: method, Event: event, Err: err}
+	}
+	for _, field := range fields {
+		if _, ok := present[field.Name]; !ok && !field.Optional {
+			return &hc.SchemaError{Method: method, Event: event, Field: field.Name,
+				Err: fmt.Errorf("Missing required field")}
+		}
+	}
+	return nil
+}
+
+var Commands = map[string]*CommandSpec{
+	"Accessibility.getPartialAXTree":                   {Method: "Accessibility.getPartialAXTree", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"fetchRelatives", "bool", true}}, Results: []FieldSpec{{"nodes", "[]*AXNode", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetPartialAXTreeParams{} }, newResult: func() interface{} { return &GetPartialAXTreeResult{} }},
+	"Animation.disable":                                {Method: "Animation.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Animation.enable":                                 {Method: "Animation.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Animation.getCurrentTime":                         {Method: "Animation.getCurrentTime", Params: []FieldSpec{{"id", "string", false}}, Results: []FieldSpec{{"currentTime", "float64", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetCurrentTimeParams{} }, newResult: func() interface{} { return &GetCurrentTimeResult{} }},
+	"Animation.getPlaybackRate":                        {Method: "Animation.getPlaybackRate", Params: nil, Results: []FieldSpec{{"playbackRate", "float64", true}}, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetPlaybackRateResult{} }},
+	"Animation.releaseAnimations":                      {Method: "Animation.releaseAnimations", Params: []FieldSpec{{"animations", "[]string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ReleaseAnimationsParams{} }, newResult: nil},
+	"Animation.resolveAnimation":                       {Method: "Animation.resolveAnimation", Params: []FieldSpec{{"animationId", "string", false}}, Results: []FieldSpec{{"remoteObject", "*RemoteObject", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ResolveAnimationParams{} }, newResult: func() interface{} { return &ResolveAnimationResult{} }},
+	"Animation.seekAnimations":                         {Method: "Animation.seekAnimations", Params: []FieldSpec{{"animations", "[]string", false}, {"currentTime", "float64", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SeekAnimationsParams{} }, newResult: nil},
+	"Animation.setPaused":                              {Method: "Animation.setPaused", Params: []FieldSpec{{"animations", "[]string", false}, {"paused", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetPausedParams{} }, newResult: nil},
+	"Animation.setPlaybackRate":                        {Method: "Animation.setPlaybackRate", Params: []FieldSpec{{"playbackRate", "float64", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetPlaybackRateParams{} }, newResult: nil},
+	"Animation.setTiming":                              {Method: "Animation.setTiming", Params: []FieldSpec{{"animationId", "string", false}, {"duration", "float64", false}, {"delay", "float64", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetTimingParams{} }, newResult: nil},
+	"ApplicationCache.enable":                          {Method: "ApplicationCache.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"ApplicationCache.getApplicationCacheForFrame":     {Method: "ApplicationCache.getApplicationCacheForFrame", Params: []FieldSpec{{"frameId", "FrameId", false}}, Results: []FieldSpec{{"applicationCache", "*ApplicationCache", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetApplicationCacheForFrameParams{} }, newResult: func() interface{} { return &GetApplicationCacheForFrameResult{} }},
+	"ApplicationCache.getFramesWithManifests":          {Method: "ApplicationCache.getFramesWithManifests", Params: nil, Results: []FieldSpec{{"frameIds", "[]*FrameWithManifest", true}}, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetFramesWithManifestsResult{} }},
+	"ApplicationCache.getManifestForFrame":             {Method: "ApplicationCache.getManifestForFrame", Params: []FieldSpec{{"frameId", "FrameId", false}}, Results: []FieldSpec{{"manifestURL", "string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetManifestForFrameParams{} }, newResult: func() interface{} { return &GetManifestForFrameResult{} }},
+	"CSS.addRule":                                      {Method: "CSS.addRule", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}, {"ruleText", "string", false}, {"location", "*SourceRange", false}}, Results: []FieldSpec{{"rule", "*CSSRule", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &AddRuleParams{} }, newResult: func() interface{} { return &AddRuleResult{} }},
+	"CSS.collectClassNames":                            {Method: "CSS.collectClassNames", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}}, Results: []FieldSpec{{"classNames", "[]string", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &CollectClassNamesParams{} }, newResult: func() interface{} { return &CollectClassNamesResult{} }},
+	"CSS.createStyleSheet":                             {Method: "CSS.createStyleSheet", Params: []FieldSpec{{"frameId", "FrameId", false}}, Results: []FieldSpec{{"styleSheetId", "StyleSheetId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &CreateStyleSheetParams{} }, newResult: func() interface{} { return &CreateStyleSheetResult{} }},
+	"CSS.disable":                                      {Method: "CSS.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"CSS.enable":                                       {Method: "CSS.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"CSS.forcePseudoState":                             {Method: "CSS.forcePseudoState", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"forcedPseudoClasses", "[]string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ForcePseudoStateParams{} }, newResult: nil},
+	"CSS.getBackgroundColors":                          {Method: "CSS.getBackgroundColors", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"backgroundColors", "[]string", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetBackgroundColorsParams{} }, newResult: func() interface{} { return &GetBackgroundColorsResult{} }},
+	"CSS.getComputedStyleForNode":                      {Method: "CSS.getComputedStyleForNode", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"computedStyle", "[]*CSSComputedStyleProperty", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetComputedStyleForNodeParams{} }, newResult: func() interface{} { return &GetComputedStyleForNodeResult{} }},
+	"CSS.getInlineStylesForNode":                       {Method: "CSS.getInlineStylesForNode", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"inlineStyle", "*CSSStyle", true}, {"attributesStyle", "*CSSStyle", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetInlineStylesForNodeParams{} }, newResult: func() interface{} { return &GetInlineStylesForNodeResult{} }},
+	"CSS.getLayoutTreeAndStyles":                       {Method: "CSS.getLayoutTreeAndStyles", Params: []FieldSpec{{"computedStyleWhitelist", "[]string", false}}, Results: []FieldSpec{{"layoutTreeNodes", "[]*LayoutTreeNode", true}, {"computedStyles", "[]*ComputedStyle", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetLayoutTreeAndStylesParams{} }, newResult: func() interface{} { return &GetLayoutTreeAndStylesResult{} }},
+	"CSS.getMatchedStylesForNode":                      {Method: "CSS.getMatchedStylesForNode", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"inlineStyle", "*CSSStyle", true}, {"attributesStyle", "*CSSStyle", true}, {"matchedCSSRules", "[]*RuleMatch", true}, {"pseudoElements", "[]*PseudoElementMatches", true}, {"inherited", "[]*InheritedStyleEntry", true}, {"cssKeyframesRules", "[]*CSSKeyframesRule", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetMatchedStylesForNodeParams{} }, newResult: func() interface{} { return &GetMatchedStylesForNodeResult{} }},
+	"CSS.getMediaQueries":                              {Method: "CSS.getMediaQueries", Params: nil, Results: []FieldSpec{{"medias", "[]*CSSMedia", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetMediaQueriesResult{} }},
+	"CSS.getPlatformFontsForNode":                      {Method: "CSS.getPlatformFontsForNode", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"fonts", "[]*PlatformFontUsage", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetPlatformFontsForNodeParams{} }, newResult: func() interface{} { return &GetPlatformFontsForNodeResult{} }},
+	"CSS.getStyleSheetText":                            {Method: "CSS.getStyleSheetText", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}}, Results: []FieldSpec{{"text", "string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetStyleSheetTextParams{} }, newResult: func() interface{} { return &GetStyleSheetTextResult{} }},
+	"CSS.setEffectivePropertyValueForNode":             {Method: "CSS.setEffectivePropertyValueForNode", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"propertyName", "string", false}, {"value", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetEffectivePropertyValueForNodeParams{} }, newResult: nil},
+	"CSS.setKeyframeKey":                               {Method: "CSS.setKeyframeKey", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}, {"range", "*SourceRange", false}, {"keyText", "string", false}}, Results: []FieldSpec{{"keyText", "*Value", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetKeyframeKeyParams{} }, newResult: func() interface{} { return &SetKeyframeKeyResult{} }},
+	"CSS.setMediaText":                                 {Method: "CSS.setMediaText", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}, {"range", "*SourceRange", false}, {"text", "string", false}}, Results: []FieldSpec{{"media", "*CSSMedia", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetMediaTextParams{} }, newResult: func() interface{} { return &SetMediaTextResult{} }},
+	"CSS.setRuleSelector":                              {Method: "CSS.setRuleSelector", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}, {"range", "*SourceRange", false}, {"selector", "string", false}}, Results: []FieldSpec{{"selectorList", "*SelectorList", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetRuleSelectorParams{} }, newResult: func() interface{} { return &SetRuleSelectorResult{} }},
+	"CSS.setStyleSheetText":                            {Method: "CSS.setStyleSheetText", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", false}, {"text", "string", false}}, Results: []FieldSpec{{"sourceMapURL", "string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetStyleSheetTextParams{} }, newResult: func() interface{} { return &SetStyleSheetTextResult{} }},
+	"CSS.setStyleTexts":                                {Method: "CSS.setStyleTexts", Params: []FieldSpec{{"edits", "[]*StyleDeclarationEdit", false}}, Results: []FieldSpec{{"styles", "[]*CSSStyle", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetStyleTextsParams{} }, newResult: func() interface{} { return &SetStyleTextsResult{} }},
+	"CSS.startRuleUsageTracking":                       {Method: "CSS.startRuleUsageTracking", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"CSS.stopRuleUsageTracking":                        {Method: "CSS.stopRuleUsageTracking", Params: nil, Results: []FieldSpec{{"ruleUsage", "[]*RuleUsage", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &StopRuleUsageTrackingResult{} }},
+	"CacheStorage.deleteCache":                         {Method: "CacheStorage.deleteCache", Params: []FieldSpec{{"cacheId", "CacheId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DeleteCacheParams{} }, newResult: nil},
+	"CacheStorage.deleteEntry":                         {Method: "CacheStorage.deleteEntry", Params: []FieldSpec{{"cacheId", "CacheId", false}, {"request", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DeleteEntryParams{} }, newResult: nil},
+	"CacheStorage.requestCacheNames":                   {Method: "CacheStorage.requestCacheNames", Params: []FieldSpec{{"securityOrigin", "string", false}}, Results: []FieldSpec{{"caches", "[]*Cache", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestCacheNamesParams{} }, newResult: func() interface{} { return &RequestCacheNamesResult{} }},
+	"CacheStorage.requestEntries":                      {Method: "CacheStorage.requestEntries", Params: []FieldSpec{{"cacheId", "CacheId", false}, {"skipCount", "int", false}, {"pageSize", "int", false}}, Results: []FieldSpec{{"cacheDataEntries", "[]*CacheStorageDataEntry", true}, {"hasMore", "bool", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestEntriesParams{} }, newResult: func() interface{} { return &RequestEntriesResult{} }},
+	"Console.clearMessages":                            {Method: "Console.clearMessages", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Console.disable":                                  {Method: "Console.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Console.enable":                                   {Method: "Console.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"DOM.collectClassNamesFromSubtree":                 {Method: "DOM.collectClassNamesFromSubtree", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"classNames", "[]string", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &CollectClassNamesFromSubtreeParams{} }, newResult: func() interface{} { return &CollectClassNamesFromSubtreeResult{} }},
+	"DOM.copyTo":                                       {Method: "DOM.copyTo", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"targetNodeId", "NodeId", false}, {"insertBeforeNodeId", "NodeId", true}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &CopyToParams{} }, newResult: func() interface{} { return &CopyToResult{} }},
+	"DOM.disable":                                      {Method: "DOM.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOM.discardSearchResults":                         {Method: "DOM.discardSearchResults", Params: []FieldSpec{{"searchId", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &DiscardSearchResultsParams{} }, newResult: nil},
+	"DOM.enable":                                       {Method: "DOM.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOM.focus":                                        {Method: "DOM.focus", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &FocusParams{} }, newResult: nil},
+	"DOM.getAttributes":                                {Method: "DOM.getAttributes", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"attributes", "[]string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetAttributesParams{} }, newResult: func() interface{} { return &GetAttributesResult{} }},
+	"DOM.getBoxModel":                                  {Method: "DOM.getBoxModel", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"model", "*BoxModel", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetBoxModelParams{} }, newResult: func() interface{} { return &GetBoxModelResult{} }},
+	"DOM.getDocument":                                  {Method: "DOM.getDocument", Params: []FieldSpec{{"depth", "int", true}, {"pierce", "bool", true}}, Results: []FieldSpec{{"root", "*Node", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetDocumentParams{} }, newResult: func() interface{} { return &GetDocumentResult{} }},
+	"DOM.getHighlightObjectForTest":                    {Method: "DOM.getHighlightObjectForTest", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"highlight", "json.RawMessage", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetHighlightObjectForTestParams{} }, newResult: func() interface{} { return &GetHighlightObjectForTestResult{} }},
+	"DOM.getNodeForLocation":                           {Method: "DOM.getNodeForLocation", Params: []FieldSpec{{"x", "int", false}, {"y", "int", false}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetNodeForLocationParams{} }, newResult: func() interface{} { return &GetNodeForLocationResult{} }},
+	"DOM.getOuterHTML":                                 {Method: "DOM.getOuterHTML", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"outerHTML", "string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetOuterHTMLParams{} }, newResult: func() interface{} { return &GetOuterHTMLResult{} }},
+	"DOM.getRelayoutBoundary":                          {Method: "DOM.getRelayoutBoundary", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetRelayoutBoundaryParams{} }, newResult: func() interface{} { return &GetRelayoutBoundaryResult{} }},
+	"DOM.getSearchResults":                             {Method: "DOM.getSearchResults", Params: []FieldSpec{{"searchId", "string", false}, {"fromIndex", "int", false}, {"toIndex", "int", false}}, Results: []FieldSpec{{"nodeIds", "[]NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetSearchResultsParams{} }, newResult: func() interface{} { return &GetSearchResultsResult{} }},
+	"DOM.hideHighlight":                                {Method: "DOM.hideHighlight", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOM.highlightFrame":                               {Method: "DOM.highlightFrame", Params: []FieldSpec{{"frameId", "FrameId", false}, {"contentColor", "*RGBA", true}, {"contentOutlineColor", "*RGBA", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &HighlightFrameParams{} }, newResult: nil},
+	"DOM.highlightNode":                                {Method: "DOM.highlightNode", Params: []FieldSpec{{"highlightConfig", "*HighlightConfig", false}, {"nodeId", "NodeId", true}, {"backendNodeId", "BackendNodeId", true}, {"objectId", "RemoteObjectId", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &HighlightNodeParams{} }, newResult: nil},
+	"DOM.highlightQuad":                                {Method: "DOM.highlightQuad", Params: []FieldSpec{{"quad", "Quad", false}, {"color", "*RGBA", true}, {"outlineColor", "*RGBA", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &HighlightQuadParams{} }, newResult: nil},
+	"DOM.highlightRect":                                {Method: "DOM.highlightRect", Params: []FieldSpec{{"x", "int", false}, {"y", "int", false}, {"width", "int", false}, {"height", "int", false}, {"color", "*RGBA", true}, {"outlineColor", "*RGBA", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &HighlightRectParams{} }, newResult: nil},
+	"DOM.markUndoableState":                            {Method: "DOM.markUndoableState", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOM.moveTo":                                       {Method: "DOM.moveTo", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"targetNodeId", "NodeId", false}, {"insertBeforeNodeId", "NodeId", true}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &MoveToParams{} }, newResult: func() interface{} { return &MoveToResult{} }},
+	"DOM.performSearch":                                {Method: "DOM.performSearch", Params: []FieldSpec{{"query", "string", false}, {"includeUserAgentShadowDOM", "bool", true}}, Results: []FieldSpec{{"searchId", "string", true}, {"resultCount", "int", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PerformSearchParams{} }, newResult: func() interface{} { return &PerformSearchResult{} }},
+	"DOM.pushNodeByPathToFrontend":                     {Method: "DOM.pushNodeByPathToFrontend", Params: []FieldSpec{{"path", "string", false}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PushNodeByPathToFrontendParams{} }, newResult: func() interface{} { return &PushNodeByPathToFrontendResult{} }},
+	"DOM.pushNodesByBackendIdsToFrontend":              {Method: "DOM.pushNodesByBackendIdsToFrontend", Params: []FieldSpec{{"backendNodeIds", "[]BackendNodeId", false}}, Results: []FieldSpec{{"nodeIds", "[]NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PushNodesByBackendIdsToFrontendParams{} }, newResult: func() interface{} { return &PushNodesByBackendIdsToFrontendResult{} }},
+	"DOM.querySelector":                                {Method: "DOM.querySelector", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"selector", "string", false}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &QuerySelectorParams{} }, newResult: func() interface{} { return &QuerySelectorResult{} }},
+	"DOM.querySelectorAll":                             {Method: "DOM.querySelectorAll", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"selector", "string", false}}, Results: []FieldSpec{{"nodeIds", "[]NodeId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &QuerySelectorAllParams{} }, newResult: func() interface{} { return &QuerySelectorAllResult{} }},
+	"DOM.redo":                                         {Method: "DOM.redo", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOM.removeAttribute":                              {Method: "DOM.removeAttribute", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"name", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveAttributeParams{} }, newResult: nil},
+	"DOM.removeNode":                                   {Method: "DOM.removeNode", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveNodeParams{} }, newResult: nil},
+	"DOM.requestChildNodes":                            {Method: "DOM.requestChildNodes", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"depth", "int", true}, {"pierce", "bool", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestChildNodesParams{} }, newResult: nil},
+	"DOM.requestNode":                                  {Method: "DOM.requestNode", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestNodeParams{} }, newResult: func() interface{} { return &RequestNodeResult{} }},
+	"DOM.resolveNode":                                  {Method: "DOM.resolveNode", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"objectGroup", "string", true}}, Results: []FieldSpec{{"object", "*RemoteObject", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ResolveNodeParams{} }, newResult: func() interface{} { return &ResolveNodeResult{} }},
+	"DOM.setAttributeValue":                            {Method: "DOM.setAttributeValue", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"name", "string", false}, {"value", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetAttributeValueParams{} }, newResult: nil},
+	"DOM.setAttributesAsText":                          {Method: "DOM.setAttributesAsText", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"text", "string", false}, {"name", "string", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetAttributesAsTextParams{} }, newResult: nil},
+	"DOM.setFileInputFiles":                            {Method: "DOM.setFileInputFiles", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"files", "[]string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetFileInputFilesParams{} }, newResult: nil},
+	"DOM.setInspectMode":                               {Method: "DOM.setInspectMode", Params: []FieldSpec{{"mode", "InspectMode", false}, {"highlightConfig", "*HighlightConfig", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetInspectModeParams{} }, newResult: nil},
+	"DOM.setInspectedNode":                             {Method: "DOM.setInspectedNode", Params: []FieldSpec{{"nodeId", "NodeId", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetInspectedNodeParams{} }, newResult: nil},
+	"DOM.setNodeName":                                  {Method: "DOM.setNodeName", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"name", "string", false}}, Results: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetNodeNameParams{} }, newResult: func() interface{} { return &SetNodeNameResult{} }},
+	"DOM.setNodeValue":                                 {Method: "DOM.setNodeValue", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"value", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetNodeValueParams{} }, newResult: nil},
+	"DOM.setOuterHTML":                                 {Method: "DOM.setOuterHTML", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"outerHTML", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetOuterHTMLParams{} }, newResult: nil},
+	"DOM.undo":                                         {Method: "DOM.undo", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOMDebugger.getEventListeners":                    {Method: "DOMDebugger.getEventListeners", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}}, Results: []FieldSpec{{"listeners", "[]*EventListener", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetEventListenersParams{} }, newResult: func() interface{} { return &GetEventListenersResult{} }},
+	"DOMDebugger.removeDOMBreakpoint":                  {Method: "DOMDebugger.removeDOMBreakpoint", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"type", "DOMBreakpointType", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveDOMBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.removeEventListenerBreakpoint":        {Method: "DOMDebugger.removeEventListenerBreakpoint", Params: []FieldSpec{{"eventName", "string", false}, {"targetName", "string", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveEventListenerBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.removeInstrumentationBreakpoint":      {Method: "DOMDebugger.removeInstrumentationBreakpoint", Params: []FieldSpec{{"eventName", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveInstrumentationBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.removeXHRBreakpoint":                  {Method: "DOMDebugger.removeXHRBreakpoint", Params: []FieldSpec{{"url", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveXHRBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.setDOMBreakpoint":                     {Method: "DOMDebugger.setDOMBreakpoint", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"type", "DOMBreakpointType", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetDOMBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.setEventListenerBreakpoint":           {Method: "DOMDebugger.setEventListenerBreakpoint", Params: []FieldSpec{{"eventName", "string", false}, {"targetName", "string", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetEventListenerBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.setInstrumentationBreakpoint":         {Method: "DOMDebugger.setInstrumentationBreakpoint", Params: []FieldSpec{{"eventName", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetInstrumentationBreakpointParams{} }, newResult: nil},
+	"DOMDebugger.setXHRBreakpoint":                     {Method: "DOMDebugger.setXHRBreakpoint", Params: []FieldSpec{{"url", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetXHRBreakpointParams{} }, newResult: nil},
+	"DOMStorage.disable":                               {Method: "DOMStorage.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOMStorage.enable":                                {Method: "DOMStorage.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DOMStorage.getDOMStorageItems":                    {Method: "DOMStorage.getDOMStorageItems", Params: []FieldSpec{{"storageId", "*StorageId", false}}, Results: []FieldSpec{{"entries", "[]Item", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetDOMStorageItemsParams{} }, newResult: func() interface{} { return &GetDOMStorageItemsResult{} }},
+	"DOMStorage.removeDOMStorageItem":                  {Method: "DOMStorage.removeDOMStorageItem", Params: []FieldSpec{{"storageId", "*StorageId", false}, {"key", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveDOMStorageItemParams{} }, newResult: nil},
+	"DOMStorage.setDOMStorageItem":                     {Method: "DOMStorage.setDOMStorageItem", Params: []FieldSpec{{"storageId", "*StorageId", false}, {"key", "string", false}, {"value", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetDOMStorageItemParams{} }, newResult: nil},
+	"Database.disable":                                 {Method: "Database.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Database.enable":                                  {Method: "Database.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Database.executeSQL":                              {Method: "Database.executeSQL", Params: []FieldSpec{{"databaseId", "DatabaseId", false}, {"query", "string", false}}, Results: []FieldSpec{{"columnNames", "[]string", true}, {"values", "[]json.RawMessage", true}, {"sqlError", "*Error", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ExecuteSQLParams{} }, newResult: func() interface{} { return &ExecuteSQLResult{} }},
+	"Database.getDatabaseTableNames":                   {Method: "Database.getDatabaseTableNames", Params: []FieldSpec{{"databaseId", "DatabaseId", false}}, Results: []FieldSpec{{"tableNames", "[]string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &GetDatabaseTableNamesParams{} }, newResult: func() interface{} { return &GetDatabaseTableNamesResult{} }},
+	"Debugger.continueToLocation":                      {Method: "Debugger.continueToLocation", Params: []FieldSpec{{"location", "*Location", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &ContinueToLocationParams{} }, newResult: nil},
+	"Debugger.disable":                                 {Method: "Debugger.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Debugger.enable":                                  {Method: "Debugger.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Debugger.evaluateOnCallFrame":                     {Method: "Debugger.evaluateOnCallFrame", Params: []FieldSpec{{"callFrameId", "CallFrameId", false}, {"expression", "string", false}, {"objectGroup", "string", true}, {"includeCommandLineAPI", "bool", true}, {"silent", "bool", true}, {"returnByValue", "bool", true}, {"generatePreview", "bool", true}}, Results: []FieldSpec{{"result", "*RemoteObject", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &EvaluateOnCallFrameParams{} }, newResult: func() interface{} { return &EvaluateOnCallFrameResult{} }},
+	"Debugger.getPossibleBreakpoints":                  {Method: "Debugger.getPossibleBreakpoints", Params: []FieldSpec{{"start", "*Location", false}, {"end", "*Location", true}}, Results: []FieldSpec{{"locations", "[]*Location", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetPossibleBreakpointsParams{} }, newResult: func() interface{} { return &GetPossibleBreakpointsResult{} }},
+	"Debugger.getScriptSource":                         {Method: "Debugger.getScriptSource", Params: []FieldSpec{{"scriptId", "ScriptId", false}}, Results: []FieldSpec{{"scriptSource", "string", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetScriptSourceParams{} }, newResult: func() interface{} { return &GetScriptSourceResult{} }},
+	"Debugger.pause":                                   {Method: "Debugger.pause", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Debugger.removeBreakpoint":                        {Method: "Debugger.removeBreakpoint", Params: []FieldSpec{{"breakpointId", "BreakpointId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &RemoveBreakpointParams{} }, newResult: nil},
+	"Debugger.restartFrame":                            {Method: "Debugger.restartFrame", Params: []FieldSpec{{"callFrameId", "CallFrameId", false}}, Results: []FieldSpec{{"callFrames", "[]*DebuggerCallFrame", true}, {"asyncStackTrace", "*StackTrace", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &RestartFrameParams{} }, newResult: func() interface{} { return &RestartFrameResult{} }},
+	"Debugger.resume":                                  {Method: "Debugger.resume", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Debugger.searchInContent":                         {Method: "Debugger.searchInContent", Params: []FieldSpec{{"scriptId", "ScriptId", false}, {"query", "string", false}, {"caseSensitive", "bool", true}, {"isRegex", "bool", true}}, Results: []FieldSpec{{"result", "[]*SearchMatch", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SearchInContentParams{} }, newResult: func() interface{} { return &SearchInContentResult{} }},
+	"Debugger.setAsyncCallStackDepth":                  {Method: "Debugger.setAsyncCallStackDepth", Params: []FieldSpec{{"maxDepth", "int", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetAsyncCallStackDepthParams{} }, newResult: nil},
+	"Debugger.setBlackboxPatterns":                     {Method: "Debugger.setBlackboxPatterns", Params: []FieldSpec{{"patterns", "[]string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetBlackboxPatternsParams{} }, newResult: nil},
+	"Debugger.setBlackboxedRanges":                     {Method: "Debugger.setBlackboxedRanges", Params: []FieldSpec{{"scriptId", "ScriptId", false}, {"positions", "[]*ScriptPosition", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetBlackboxedRangesParams{} }, newResult: nil},
+	"Debugger.setBreakpoint":                           {Method: "Debugger.setBreakpoint", Params: []FieldSpec{{"location", "*Location", false}, {"condition", "string", true}}, Results: []FieldSpec{{"breakpointId", "BreakpointId", true}, {"actualLocation", "*Location", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetBreakpointParams{} }, newResult: func() interface{} { return &SetBreakpointResult{} }},
+	"Debugger.setBreakpointByUrl":                      {Method: "Debugger.setBreakpointByUrl", Params: []FieldSpec{{"lineNumber", "int", false}, {"url", "string", true}, {"urlRegex", "string", true}, {"columnNumber", "int", true}, {"condition", "string", true}}, Results: []FieldSpec{{"breakpointId", "BreakpointId", true}, {"locations", "[]*Location", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetBreakpointByUrlParams{} }, newResult: func() interface{} { return &SetBreakpointByUrlResult{} }},
+	"Debugger.setBreakpointsActive":                    {Method: "Debugger.setBreakpointsActive", Params: []FieldSpec{{"active", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetBreakpointsActiveParams{} }, newResult: nil},
+	"Debugger.setPauseOnExceptions":                    {Method: "Debugger.setPauseOnExceptions", Params: []FieldSpec{{"state", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetPauseOnExceptionsParams{} }, newResult: nil},
+	"Debugger.setScriptSource":                         {Method: "Debugger.setScriptSource", Params: []FieldSpec{{"scriptId", "ScriptId", false}, {"scriptSource", "string", false}, {"dryRun", "bool", true}}, Results: []FieldSpec{{"callFrames", "[]*DebuggerCallFrame", true}, {"stackChanged", "bool", true}, {"asyncStackTrace", "*StackTrace", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetScriptSourceParams{} }, newResult: func() interface{} { return &SetScriptSourceResult{} }},
+	"Debugger.setSkipAllPauses":                        {Method: "Debugger.setSkipAllPauses", Params: []FieldSpec{{"skip", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetSkipAllPausesParams{} }, newResult: nil},
+	"Debugger.setVariableValue":                        {Method: "Debugger.setVariableValue", Params: []FieldSpec{{"scopeNumber", "int", false}, {"variableName", "string", false}, {"newValue", "*CallArgument", false}, {"callFrameId", "CallFrameId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetVariableValueParams{} }, newResult: nil},
+	"Debugger.stepInto":                                {Method: "Debugger.stepInto", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Debugger.stepOut":                                 {Method: "Debugger.stepOut", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Debugger.stepOver":                                {Method: "Debugger.stepOver", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"DeviceOrientation.clearDeviceOrientationOverride": {Method: "DeviceOrientation.clearDeviceOrientationOverride", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"DeviceOrientation.setDeviceOrientationOverride":   {Method: "DeviceOrientation.setDeviceOrientationOverride", Params: []FieldSpec{{"alpha", "float64", false}, {"beta", "float64", false}, {"gamma", "float64", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DeviceOrientationSetDeviceOrientationOverrideParams{} }, newResult: nil},
+	"Emulation.canEmulate":                             {Method: "Emulation.canEmulate", Params: nil, Results: []FieldSpec{{"result", "bool", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &CanEmulateResult{} }},
+	"Emulation.clearDeviceMetricsOverride":             {Method: "Emulation.clearDeviceMetricsOverride", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Emulation.clearGeolocationOverride":               {Method: "Emulation.clearGeolocationOverride", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Emulation.forceViewport":                          {Method: "Emulation.forceViewport", Params: []FieldSpec{{"x", "float64", false}, {"y", "float64", false}, {"scale", "float64", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &ForceViewportParams{} }, newResult: nil},
+	"Emulation.resetPageScaleFactor":                   {Method: "Emulation.resetPageScaleFactor", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Emulation.resetViewport":                          {Method: "Emulation.resetViewport", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Emulation.setCPUThrottlingRate":                   {Method: "Emulation.setCPUThrottlingRate", Params: []FieldSpec{{"rate", "float64", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetCPUThrottlingRateParams{} }, newResult: nil},
+	"Emulation.setDeviceMetricsOverride":               {Method: "Emulation.setDeviceMetricsOverride", Params: []FieldSpec{{"width", "int", false}, {"height", "int", false}, {"deviceScaleFactor", "float64", false}, {"mobile", "bool", false}, {"fitWindow", "bool", false}, {"scale", "float64", true}, {"offsetX", "float64", true}, {"offsetY", "float64", true}, {"screenWidth", "int", true}, {"screenHeight", "int", true}, {"positionX", "int", true}, {"positionY", "int", true}, {"screenOrientation", "*ScreenOrientation", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &EmulationSetDeviceMetricsOverrideParams{} }, newResult: nil},
+	"Emulation.setEmulatedMedia":                       {Method: "Emulation.setEmulatedMedia", Params: []FieldSpec{{"media", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetEmulatedMediaParams{} }, newResult: nil},
+	"Emulation.setGeolocationOverride":                 {Method: "Emulation.setGeolocationOverride", Params: []FieldSpec{{"latitude", "float64", true}, {"longitude", "float64", true}, {"accuracy", "float64", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &EmulationSetGeolocationOverrideParams{} }, newResult: nil},
+	"Emulation.setPageScaleFactor":                     {Method: "Emulation.setPageScaleFactor", Params: []FieldSpec{{"pageScaleFactor", "float64", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetPageScaleFactorParams{} }, newResult: nil},
+	"Emulation.setScriptExecutionDisabled":             {Method: "Emulation.setScriptExecutionDisabled", Params: []FieldSpec{{"value", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetScriptExecutionDisabledParams{} }, newResult: nil},
+	"Emulation.setTouchEmulationEnabled":               {Method: "Emulation.setTouchEmulationEnabled", Params: []FieldSpec{{"enabled", "bool", false}, {"configuration", "string", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &EmulationSetTouchEmulationEnabledParams{} }, newResult: nil},
+	"Emulation.setVirtualTimePolicy":                   {Method: "Emulation.setVirtualTimePolicy", Params: []FieldSpec{{"policy", "VirtualTimePolicy", false}, {"budget", "int", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetVirtualTimePolicyParams{} }, newResult: nil},
+	"Emulation.setVisibleSize":                         {Method: "Emulation.setVisibleSize", Params: []FieldSpec{{"width", "int", false}, {"height", "int", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetVisibleSizeParams{} }, newResult: nil},
+	"HeapProfiler.addInspectedHeapObject":              {Method: "HeapProfiler.addInspectedHeapObject", Params: []FieldSpec{{"heapObjectId", "HeapSnapshotObjectId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &AddInspectedHeapObjectParams{} }, newResult: nil},
+	"HeapProfiler.collectGarbage":                      {Method: "HeapProfiler.collectGarbage", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"HeapProfiler.disable":                             {Method: "HeapProfiler.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"HeapProfiler.enable":                              {Method: "HeapProfiler.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"HeapProfiler.getHeapObjectId":                     {Method: "HeapProfiler.getHeapObjectId", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}}, Results: []FieldSpec{{"heapSnapshotObjectId", "HeapSnapshotObjectId", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetHeapObjectIdParams{} }, newResult: func() interface{} { return &GetHeapObjectIdResult{} }},
+	"HeapProfiler.getObjectByHeapObjectId":             {Method: "HeapProfiler.getObjectByHeapObjectId", Params: []FieldSpec{{"objectId", "HeapSnapshotObjectId", false}, {"objectGroup", "string", true}}, Results: []FieldSpec{{"result", "*RemoteObject", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetObjectByHeapObjectIdParams{} }, newResult: func() interface{} { return &GetObjectByHeapObjectIdResult{} }},
+	"HeapProfiler.startSampling":                       {Method: "HeapProfiler.startSampling", Params: []FieldSpec{{"samplingInterval", "float64", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &StartSamplingParams{} }, newResult: nil},
+	"HeapProfiler.startTrackingHeapObjects":            {Method: "HeapProfiler.startTrackingHeapObjects", Params: []FieldSpec{{"trackAllocations", "bool", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &StartTrackingHeapObjectsParams{} }, newResult: nil},
+	"HeapProfiler.stopSampling":                        {Method: "HeapProfiler.stopSampling", Params: nil, Results: []FieldSpec{{"profile", "*SamplingHeapProfile", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &StopSamplingResult{} }},
+	"HeapProfiler.stopTrackingHeapObjects":             {Method: "HeapProfiler.stopTrackingHeapObjects", Params: []FieldSpec{{"reportProgress", "bool", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &StopTrackingHeapObjectsParams{} }, newResult: nil},
+	"HeapProfiler.takeHeapSnapshot":                    {Method: "HeapProfiler.takeHeapSnapshot", Params: []FieldSpec{{"reportProgress", "bool", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &TakeHeapSnapshotParams{} }, newResult: nil},
+	"IO.close":                                         {Method: "IO.close", Params: []FieldSpec{{"handle", "StreamHandle", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &CloseParams{} }, newResult: nil},
+	"IO.read":                                          {Method: "IO.read", Params: []FieldSpec{{"handle", "StreamHandle", false}, {"offset", "int", true}, {"size", "int", true}}, Results: []FieldSpec{{"data", "string", true}, {"eof", "bool", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &ReadParams{} }, newResult: func() interface{} { return &ReadResult{} }},
+	"IndexedDB.clearObjectStore":                       {Method: "IndexedDB.clearObjectStore", Params: []FieldSpec{{"securityOrigin", "string", false}, {"databaseName", "string", false}, {"objectStoreName", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ClearObjectStoreParams{} }, newResult: nil},
+	"IndexedDB.disable":                                {Method: "IndexedDB.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"IndexedDB.enable":                                 {Method: "IndexedDB.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"IndexedDB.requestData":                            {Method: "IndexedDB.requestData", Params: []FieldSpec{{"securityOrigin", "string", false}, {"databaseName", "string", false}, {"objectStoreName", "string", false}, {"indexName", "string", false}, {"skipCount", "int", false}, {"pageSize", "int", false}, {"keyRange", "*KeyRange", true}}, Results: []FieldSpec{{"objectStoreDataEntries", "[]*IndexedDBDataEntry", true}, {"hasMore", "bool", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestDataParams{} }, newResult: func() interface{} { return &RequestDataResult{} }},
+	"IndexedDB.requestDatabase":                        {Method: "IndexedDB.requestDatabase", Params: []FieldSpec{{"securityOrigin", "string", false}, {"databaseName", "string", false}}, Results: []FieldSpec{{"databaseWithObjectStores", "*DatabaseWithObjectStores", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestDatabaseParams{} }, newResult: func() interface{} { return &RequestDatabaseResult{} }},
+	"IndexedDB.requestDatabaseNames":                   {Method: "IndexedDB.requestDatabaseNames", Params: []FieldSpec{{"securityOrigin", "string", false}}, Results: []FieldSpec{{"databaseNames", "[]string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestDatabaseNamesParams{} }, newResult: func() interface{} { return &RequestDatabaseNamesResult{} }},
+	"Input.dispatchKeyEvent":                           {Method: "Input.dispatchKeyEvent", Params: []FieldSpec{{"type", "string", false}, {"modifiers", "int", true}, {"timestamp", "float64", true}, {"text", "string", true}, {"unmodifiedText", "string", true}, {"keyIdentifier", "string", true}, {"code", "string", true}, {"key", "string", true}, {"windowsVirtualKeyCode", "int", true}, {"nativeVirtualKeyCode", "int", true}, {"autoRepeat", "bool", true}, {"isKeypad", "bool", true}, {"isSystemKey", "bool", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DispatchKeyEventParams{} }, newResult: nil},
+	"Input.dispatchMouseEvent":                         {Method: "Input.dispatchMouseEvent", Params: []FieldSpec{{"type", "string", false}, {"x", "int", false}, {"y", "int", false}, {"modifiers", "int", true}, {"timestamp", "float64", true}, {"button", "string", true}, {"clickCount", "int", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DispatchMouseEventParams{} }, newResult: nil},
+	"Input.dispatchTouchEvent":                         {Method: "Input.dispatchTouchEvent", Params: []FieldSpec{{"type", "string", false}, {"touchPoints", "[]*TouchPoint", false}, {"modifiers", "int", true}, {"timestamp", "float64", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &DispatchTouchEventParams{} }, newResult: nil},
+	"Input.emulateTouchFromMouseEvent":                 {Method: "Input.emulateTouchFromMouseEvent", Params: []FieldSpec{{"type", "string", false}, {"x", "int", false}, {"y", "int", false}, {"timestamp", "float64", false}, {"button", "string", false}, {"deltaX", "float64", true}, {"deltaY", "float64", true}, {"modifiers", "int", true}, {"clickCount", "int", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &EmulateTouchFromMouseEventParams{} }, newResult: nil},
+	"Input.synthesizePinchGesture":                     {Method: "Input.synthesizePinchGesture", Params: []FieldSpec{{"x", "int", false}, {"y", "int", false}, {"scaleFactor", "float64", false}, {"relativeSpeed", "int", true}, {"gestureSourceType", "GestureSourceType", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SynthesizePinchGestureParams{} }, newResult: nil},
+	"Input.synthesizeScrollGesture":                    {Method: "Input.synthesizeScrollGesture", Params: []FieldSpec{{"x", "int", false}, {"y", "int", false}, {"xDistance", "int", true}, {"yDistance", "int", true}, {"xOverscroll", "int", true}, {"yOverscroll", "int", true}, {"preventFling", "bool", true}, {"speed", "int", true}, {"gestureSourceType", "GestureSourceType", true}, {"repeatCount", "int", true}, {"repeatDelayMs", "int", true}, {"interactionMarkerName", "string", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SynthesizeScrollGestureParams{} }, newResult: nil},
+	"Input.synthesizeTapGesture":                       {Method: "Input.synthesizeTapGesture", Params: []FieldSpec{{"x", "int", false}, {"y", "int", false}, {"duration", "int", true}, {"tapCount", "int", true}, {"gestureSourceType", "GestureSourceType", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SynthesizeTapGestureParams{} }, newResult: nil},
+	"Inspector.disable":                                {Method: "Inspector.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Inspector.enable":                                 {Method: "Inspector.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"LayerTree.compositingReasons":                     {Method: "LayerTree.compositingReasons", Params: []FieldSpec{{"layerId", "LayerId", false}}, Results: []FieldSpec{{"compositingReasons", "[]string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &CompositingReasonsParams{} }, newResult: func() interface{} { return &CompositingReasonsResult{} }},
+	"LayerTree.disable":                                {Method: "LayerTree.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"LayerTree.enable":                                 {Method: "LayerTree.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"LayerTree.loadSnapshot":                           {Method: "LayerTree.loadSnapshot", Params: []FieldSpec{{"tiles", "[]*PictureTile", false}}, Results: []FieldSpec{{"snapshotId", "SnapshotId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &LoadSnapshotParams{} }, newResult: func() interface{} { return &LoadSnapshotResult{} }},
+	"LayerTree.makeSnapshot":                           {Method: "LayerTree.makeSnapshot", Params: []FieldSpec{{"layerId", "LayerId", false}}, Results: []FieldSpec{{"snapshotId", "SnapshotId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &MakeSnapshotParams{} }, newResult: func() interface{} { return &MakeSnapshotResult{} }},
+	"LayerTree.profileSnapshot":                        {Method: "LayerTree.profileSnapshot", Params: []FieldSpec{{"snapshotId", "SnapshotId", false}, {"minRepeatCount", "int", true}, {"minDuration", "float64", true}, {"clipRect", "*Rect", true}}, Results: []FieldSpec{{"timings", "[]PaintProfile", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ProfileSnapshotParams{} }, newResult: func() interface{} { return &ProfileSnapshotResult{} }},
+	"LayerTree.releaseSnapshot":                        {Method: "LayerTree.releaseSnapshot", Params: []FieldSpec{{"snapshotId", "SnapshotId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ReleaseSnapshotParams{} }, newResult: nil},
+	"LayerTree.replaySnapshot":                         {Method: "LayerTree.replaySnapshot", Params: []FieldSpec{{"snapshotId", "SnapshotId", false}, {"fromStep", "int", true}, {"toStep", "int", true}, {"scale", "float64", true}}, Results: []FieldSpec{{"dataURL", "string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ReplaySnapshotParams{} }, newResult: func() interface{} { return &ReplaySnapshotResult{} }},
+	"LayerTree.snapshotCommandLog":                     {Method: "LayerTree.snapshotCommandLog", Params: []FieldSpec{{"snapshotId", "SnapshotId", false}}, Results: []FieldSpec{{"commandLog", "[]json.RawMessage", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SnapshotCommandLogParams{} }, newResult: func() interface{} { return &SnapshotCommandLogResult{} }},
+	"Log.clear":                                        {Method: "Log.clear", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Log.disable":                                      {Method: "Log.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Log.enable":                                       {Method: "Log.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Log.startViolationsReport":                        {Method: "Log.startViolationsReport", Params: []FieldSpec{{"config", "[]*ViolationSetting", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &StartViolationsReportParams{} }, newResult: nil},
+	"Log.stopViolationsReport":                         {Method: "Log.stopViolationsReport", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Memory.getDOMCounters":                            {Method: "Memory.getDOMCounters", Params: nil, Results: []FieldSpec{{"documents", "int", true}, {"nodes", "int", true}, {"jsEventListeners", "int", true}}, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetDOMCountersResult{} }},
+	"Memory.setPressureNotificationsSuppressed":        {Method: "Memory.setPressureNotificationsSuppressed", Params: []FieldSpec{{"suppressed", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetPressureNotificationsSuppressedParams{} }, newResult: nil},
+	"Memory.simulatePressureNotification":              {Method: "Memory.simulatePressureNotification", Params: []FieldSpec{{"level", "PressureLevel", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SimulatePressureNotificationParams{} }, newResult: nil},
+	"Network.addBlockedURL":                            {Method: "Network.addBlockedURL", Params: []FieldSpec{{"url", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &AddBlockedURLParams{} }, newResult: nil},
+	"Network.canClearBrowserCache":                     {Method: "Network.canClearBrowserCache", Params: nil, Results: []FieldSpec{{"result", "bool", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &CanClearBrowserCacheResult{} }},
+	"Network.canClearBrowserCookies":                   {Method: "Network.canClearBrowserCookies", Params: nil, Results: []FieldSpec{{"result", "bool", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &CanClearBrowserCookiesResult{} }},
+	"Network.canEmulateNetworkConditions":              {Method: "Network.canEmulateNetworkConditions", Params: nil, Results: []FieldSpec{{"result", "bool", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &CanEmulateNetworkConditionsResult{} }},
+	"Network.clearBrowserCache":                        {Method: "Network.clearBrowserCache", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Network.clearBrowserCookies":                      {Method: "Network.clearBrowserCookies", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Network.deleteCookie":                             {Method: "Network.deleteCookie", Params: []FieldSpec{{"cookieName", "string", false}, {"url", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &NetworkDeleteCookieParams{} }, newResult: nil},
+	"Network.disable":                                  {Method: "Network.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Network.emulateNetworkConditions":                 {Method: "Network.emulateNetworkConditions", Params: []FieldSpec{{"offline", "bool", false}, {"latency", "float64", false}, {"downloadThroughput", "float64", false}, {"uploadThroughput", "float64", false}, {"connectionType", "ConnectionType", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &EmulateNetworkConditionsParams{} }, newResult: nil},
+	"Network.enable":                                   {Method: "Network.enable", Params: []FieldSpec{{"maxTotalBufferSize", "int", true}, {"maxResourceBufferSize", "int", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &NetworkEnableParams{} }, newResult: nil},
+	"Network.getAllCookies":                            {Method: "Network.getAllCookies", Params: nil, Results: []FieldSpec{{"cookies", "[]*Cookie", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &GetAllCookiesResult{} }},
+	"Network.getCertificate":                           {Method: "Network.getCertificate", Params: []FieldSpec{{"origin", "string", false}}, Results: []FieldSpec{{"tableNames", "[]string", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetCertificateParams{} }, newResult: func() interface{} { return &GetCertificateResult{} }},
+	"Network.getCookies":                               {Method: "Network.getCookies", Params: nil, Results: []FieldSpec{{"cookies", "[]*Cookie", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &NetworkGetCookiesResult{} }},
+	"Network.getResponseBody":                          {Method: "Network.getResponseBody", Params: []FieldSpec{{"requestId", "RequestId", false}}, Results: []FieldSpec{{"body", "string", true}, {"base64Encoded", "bool", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetResponseBodyParams{} }, newResult: func() interface{} { return &GetResponseBodyResult{} }},
+	"Network.removeBlockedURL":                         {Method: "Network.removeBlockedURL", Params: []FieldSpec{{"url", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &RemoveBlockedURLParams{} }, newResult: nil},
+	"Network.replayXHR":                                {Method: "Network.replayXHR", Params: []FieldSpec{{"requestId", "RequestId", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &ReplayXHRParams{} }, newResult: nil},
+	"Network.setBypassServiceWorker":                   {Method: "Network.setBypassServiceWorker", Params: []FieldSpec{{"bypass", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetBypassServiceWorkerParams{} }, newResult: nil},
+	"Network.setCacheDisabled":                         {Method: "Network.setCacheDisabled", Params: []FieldSpec{{"cacheDisabled", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetCacheDisabledParams{} }, newResult: nil},
+	"Network.setCookie":                                {Method: "Network.setCookie", Params: []FieldSpec{{"url", "string", false}, {"name", "string", false}, {"value", "string", false}, {"domain", "string", true}, {"path", "string", true}, {"secure", "bool", true}, {"httpOnly", "bool", true}, {"sameSite", "CookieSameSite", true}, {"expirationDate", "NetworkTimestamp", true}}, Results: []FieldSpec{{"success", "bool", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetCookieParams{} }, newResult: func() interface{} { return &SetCookieResult{} }},
+	"Network.setDataSizeLimitsForTest":                 {Method: "Network.setDataSizeLimitsForTest", Params: []FieldSpec{{"maxTotalSize", "int", false}, {"maxResourceSize", "int", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetDataSizeLimitsForTestParams{} }, newResult: nil},
+	"Network.setExtraHTTPHeaders":                      {Method: "Network.setExtraHTTPHeaders", Params: []FieldSpec{{"headers", "Headers", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetExtraHTTPHeadersParams{} }, newResult: nil},
+	"Network.setMonitoringXHREnabled":                  {Method: "Network.setMonitoringXHREnabled", Params: []FieldSpec{{"enabled", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetMonitoringXHREnabledParams{} }, newResult: nil},
+	"Network.setUserAgentOverride":                     {Method: "Network.setUserAgentOverride", Params: []FieldSpec{{"userAgent", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetUserAgentOverrideParams{} }, newResult: nil},
+	"Page.addScriptToEvaluateOnLoad":                   {Method: "Page.addScriptToEvaluateOnLoad", Params: []FieldSpec{{"scriptSource", "string", false}}, Results: []FieldSpec{{"identifier", "ScriptIdentifier", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &AddScriptToEvaluateOnLoadParams{} }, newResult: func() interface{} { return &AddScriptToEvaluateOnLoadResult{} }},
+	"Page.captureScreenshot":                           {Method: "Page.captureScreenshot", Params: nil, Results: []FieldSpec{{"data", "string", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &CaptureScreenshotResult{} }},
+	"Page.clearDeviceMetricsOverride":                  {Method: "Page.clearDeviceMetricsOverride", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.clearDeviceOrientationOverride":              {Method: "Page.clearDeviceOrientationOverride", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.clearGeolocationOverride":                    {Method: "Page.clearGeolocationOverride", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.configureOverlay":                            {Method: "Page.configureOverlay", Params: []FieldSpec{{"suspended", "bool", true}, {"message", "string", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &ConfigureOverlayParams{} }, newResult: nil},
+	"Page.createIsolatedWorld":                         {Method: "Page.createIsolatedWorld", Params: []FieldSpec{{"frameId", "FrameId", false}, {"worldName", "string", true}, {"grantUniveralAccess", "bool", true}}, Results: []FieldSpec{{"executionContextId", "ExecutionContextId", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &CreateIsolatedWorldParams{} }, newResult: func() interface{} { return &CreateIsolatedWorldResult{} }},
+	"Page.deleteCookie":                                {Method: "Page.deleteCookie", Params: []FieldSpec{{"cookieName", "string", false}, {"url", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PageDeleteCookieParams{} }, newResult: nil},
+	"Page.disable":                                     {Method: "Page.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.enable":                                      {Method: "Page.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.getAppManifest":                              {Method: "Page.getAppManifest", Params: nil, Results: []FieldSpec{{"url", "string", true}, {"errors", "[]*AppManifestError", true}, {"data", "string", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetAppManifestResult{} }},
+	"Page.getCookies":                                  {Method: "Page.getCookies", Params: nil, Results: []FieldSpec{{"cookies", "[]*Cookie", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &PageGetCookiesResult{} }},
+	"Page.getLayoutMetrics":                            {Method: "Page.getLayoutMetrics", Params: nil, Results: []FieldSpec{{"layoutViewport", "*LayoutViewport", true}, {"visualViewport", "*VisualViewport", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetLayoutMetricsResult{} }},
+	"Page.getNavigationHistory":                        {Method: "Page.getNavigationHistory", Params: nil, Results: []FieldSpec{{"currentIndex", "int", true}, {"entries", "[]*NavigationEntry", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetNavigationHistoryResult{} }},
+	"Page.getResourceContent":                          {Method: "Page.getResourceContent", Params: []FieldSpec{{"frameId", "FrameId", false}, {"url", "string", false}}, Results: []FieldSpec{{"content", "string", true}, {"base64Encoded", "bool", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &GetResourceContentParams{} }, newResult: func() interface{} { return &GetResourceContentResult{} }},
+	"Page.getResourceTree":                             {Method: "Page.getResourceTree", Params: nil, Results: []FieldSpec{{"frameTree", "*FrameResourceTree", true}}, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetResourceTreeResult{} }},
+	"Page.handleJavaScriptDialog":                      {Method: "Page.handleJavaScriptDialog", Params: []FieldSpec{{"accept", "bool", false}, {"promptText", "string", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &HandleJavaScriptDialogParams{} }, newResult: nil},
+	"Page.navigate":                                    {Method: "Page.navigate", Params: []FieldSpec{{"url", "string", false}}, Results: []FieldSpec{{"frameId", "FrameId", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &NavigateParams{} }, newResult: func() interface{} { return &NavigateResult{} }},
+	"Page.navigateToHistoryEntry":                      {Method: "Page.navigateToHistoryEntry", Params: []FieldSpec{{"entryId", "int", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &NavigateToHistoryEntryParams{} }, newResult: nil},
+	"Page.processNavigation":                           {Method: "Page.processNavigation", Params: []FieldSpec{{"response", "NavigationResponse", false}, {"navigationId", "int", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &ProcessNavigationParams{} }, newResult: nil},
+	"Page.reload":                                      {Method: "Page.reload", Params: []FieldSpec{{"ignoreCache", "bool", true}, {"scriptToEvaluateOnLoad", "string", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ReloadParams{} }, newResult: nil},
+	"Page.removeScriptToEvaluateOnLoad":                {Method: "Page.removeScriptToEvaluateOnLoad", Params: []FieldSpec{{"identifier", "ScriptIdentifier", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &RemoveScriptToEvaluateOnLoadParams{} }, newResult: nil},
+	"Page.requestAppBanner":                            {Method: "Page.requestAppBanner", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.screencastFrameAck":                          {Method: "Page.screencastFrameAck", Params: []FieldSpec{{"sessionId", "int", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &ScreencastFrameAckParams{} }, newResult: nil},
+	"Page.searchInResource":                            {Method: "Page.searchInResource", Params: []FieldSpec{{"frameId", "FrameId", false}, {"url", "string", false}, {"query", "string", false}, {"caseSensitive", "bool", true}, {"isRegex", "bool", true}}, Results: []FieldSpec{{"result", "[]*SearchMatch", true}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SearchInResourceParams{} }, newResult: func() interface{} { return &SearchInResourceResult{} }},
+	"Page.setAutoAttachToCreatedPages":                 {Method: "Page.setAutoAttachToCreatedPages", Params: []FieldSpec{{"autoAttach", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetAutoAttachToCreatedPagesParams{} }, newResult: nil},
+	"Page.setColorPickerEnabled":                       {Method: "Page.setColorPickerEnabled", Params: []FieldSpec{{"enabled", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetColorPickerEnabledParams{} }, newResult: nil},
+	"Page.setControlNavigations":                       {Method: "Page.setControlNavigations", Params: []FieldSpec{{"enabled", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetControlNavigationsParams{} }, newResult: nil},
+	"Page.setDeviceMetricsOverride":                    {Method: "Page.setDeviceMetricsOverride", Params: []FieldSpec{{"width", "int", false}, {"height", "int", false}, {"deviceScaleFactor", "float64", false}, {"mobile", "bool", false}, {"fitWindow", "bool", false}, {"scale", "float64", true}, {"offsetX", "float64", true}, {"offsetY", "float64", true}, {"screenWidth", "int", true}, {"screenHeight", "int", true}, {"positionX", "int", true}, {"positionY", "int", true}, {"screenOrientation", "*ScreenOrientation", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PageSetDeviceMetricsOverrideParams{} }, newResult: nil},
+	"Page.setDeviceOrientationOverride":                {Method: "Page.setDeviceOrientationOverride", Params: []FieldSpec{{"alpha", "float64", false}, {"beta", "float64", false}, {"gamma", "float64", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PageSetDeviceOrientationOverrideParams{} }, newResult: nil},
+	"Page.setDocumentContent":                          {Method: "Page.setDocumentContent", Params: []FieldSpec{{"frameId", "FrameId", false}, {"html", "string", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetDocumentContentParams{} }, newResult: nil},
+	"Page.setGeolocationOverride":                      {Method: "Page.setGeolocationOverride", Params: []FieldSpec{{"latitude", "float64", true}, {"longitude", "float64", true}, {"accuracy", "float64", true}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &PageSetGeolocationOverrideParams{} }, newResult: nil},
+	"Page.setTouchEmulationEnabled":                    {Method: "Page.setTouchEmulationEnabled", Params: []FieldSpec{{"enabled", "bool", false}, {"configuration", "string", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &PageSetTouchEmulationEnabledParams{} }, newResult: nil},
+	"Page.startScreencast":                             {Method: "Page.startScreencast", Params: []FieldSpec{{"format", "string", true}, {"quality", "int", true}, {"maxWidth", "int", true}, {"maxHeight", "int", true}, {"everyNthFrame", "int", true}}, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &StartScreencastParams{} }, newResult: nil},
+	"Page.stopLoading":                                 {Method: "Page.stopLoading", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.stopScreencast":                              {Method: "Page.stopScreencast", Params: nil, Results: nil, Experimental: true, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Profiler.disable":                                 {Method: "Profiler.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Profiler.enable":                                  {Method: "Profiler.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Profiler.setSamplingInterval":                     {Method: "Profiler.setSamplingInterval", Params: []FieldSpec{{"interval", "int", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetSamplingIntervalParams{} }, newResult: nil},
+	"Profiler.start":                                   {Method: "Profiler.start", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Profiler.stop":                                    {Method: "Profiler.stop", Params: nil, Results: []FieldSpec{{"profile", "*Profile", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &StopResult{} }},
+	"Rendering.setShowDebugBorders":                    {Method: "Rendering.setShowDebugBorders", Params: []FieldSpec{{"show", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetShowDebugBordersParams{} }, newResult: nil},
+	"Rendering.setShowFPSCounter":                      {Method: "Rendering.setShowFPSCounter", Params: []FieldSpec{{"show", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetShowFPSCounterParams{} }, newResult: nil},
+	"Rendering.setShowPaintRects":                      {Method: "Rendering.setShowPaintRects", Params: []FieldSpec{{"result", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetShowPaintRectsParams{} }, newResult: nil},
+	"Rendering.setShowScrollBottleneckRects":           {Method: "Rendering.setShowScrollBottleneckRects", Params: []FieldSpec{{"show", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetShowScrollBottleneckRectsParams{} }, newResult: nil},
+	"Rendering.setShowViewportSizeOnResize":            {Method: "Rendering.setShowViewportSizeOnResize", Params: []FieldSpec{{"show", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetShowViewportSizeOnResizeParams{} }, newResult: nil},
+	"Runtime.awaitPromise":                             {Method: "Runtime.awaitPromise", Params: []FieldSpec{{"promiseObjectId", "RemoteObjectId", false}, {"returnByValue", "bool", true}, {"generatePreview", "bool", true}}, Results: []FieldSpec{{"result", "*RemoteObject", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &AwaitPromiseParams{} }, newResult: func() interface{} { return &AwaitPromiseResult{} }},
+	"Runtime.callFunctionOn":                           {Method: "Runtime.callFunctionOn", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}, {"functionDeclaration", "string", false}, {"arguments", "[]*CallArgument", true}, {"silent", "bool", true}, {"returnByValue", "bool", true}, {"generatePreview", "bool", true}, {"userGesture", "bool", true}, {"awaitPromise", "bool", true}}, Results: []FieldSpec{{"result", "*RemoteObject", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &CallFunctionOnParams{} }, newResult: func() interface{} { return &CallFunctionOnResult{} }},
+	"Runtime.compileScript":                            {Method: "Runtime.compileScript", Params: []FieldSpec{{"expression", "string", false}, {"sourceURL", "string", false}, {"persistScript", "bool", false}, {"executionContextId", "ExecutionContextId", true}}, Results: []FieldSpec{{"scriptId", "ScriptId", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &CompileScriptParams{} }, newResult: func() interface{} { return &CompileScriptResult{} }},
+	"Runtime.disable":                                  {Method: "Runtime.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Runtime.discardConsoleEntries":                    {Method: "Runtime.discardConsoleEntries", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Runtime.enable":                                   {Method: "Runtime.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Runtime.evaluate":                                 {Method: "Runtime.evaluate", Params: []FieldSpec{{"expression", "string", false}, {"objectGroup", "string", true}, {"includeCommandLineAPI", "bool", true}, {"silent", "bool", true}, {"contextId", "ExecutionContextId", true}, {"returnByValue", "bool", true}, {"generatePreview", "bool", true}, {"userGesture", "bool", true}, {"awaitPromise", "bool", true}}, Results: []FieldSpec{{"result", "*RemoteObject", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &EvaluateParams{} }, newResult: func() interface{} { return &EvaluateResult{} }},
+	"Runtime.getProperties":                            {Method: "Runtime.getProperties", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}, {"ownProperties", "bool", true}, {"accessorPropertiesOnly", "bool", true}, {"generatePreview", "bool", true}}, Results: []FieldSpec{{"result", "[]*PropertyDescriptor", true}, {"internalProperties", "[]*InternalPropertyDescriptor", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetPropertiesParams{} }, newResult: func() interface{} { return &GetPropertiesResult{} }},
+	"Runtime.releaseObject":                            {Method: "Runtime.releaseObject", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &ReleaseObjectParams{} }, newResult: nil},
+	"Runtime.releaseObjectGroup":                       {Method: "Runtime.releaseObjectGroup", Params: []FieldSpec{{"objectGroup", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &ReleaseObjectGroupParams{} }, newResult: nil},
+	"Runtime.runIfWaitingForDebugger":                  {Method: "Runtime.runIfWaitingForDebugger", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Runtime.runScript":                                {Method: "Runtime.runScript", Params: []FieldSpec{{"scriptId", "ScriptId", false}, {"executionContextId", "ExecutionContextId", true}, {"objectGroup", "string", true}, {"silent", "bool", true}, {"includeCommandLineAPI", "bool", true}, {"returnByValue", "bool", true}, {"generatePreview", "bool", true}, {"awaitPromise", "bool", true}}, Results: []FieldSpec{{"result", "*RemoteObject", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &RunScriptParams{} }, newResult: func() interface{} { return &RunScriptResult{} }},
+	"Runtime.setCustomObjectFormatterEnabled":          {Method: "Runtime.setCustomObjectFormatterEnabled", Params: []FieldSpec{{"enabled", "bool", false}}, Results: nil, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &SetCustomObjectFormatterEnabledParams{} }, newResult: nil},
+	"Schema.getDomains":                                {Method: "Schema.getDomains", Params: nil, Results: []FieldSpec{{"domains", "[]*Domain", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: func() interface{} { return &GetDomainsResult{} }},
+	"Security.disable":                                 {Method: "Security.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Security.enable":                                  {Method: "Security.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Security.showCertificateViewer":                   {Method: "Security.showCertificateViewer", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"ServiceWorker.deliverPushMessage":                 {Method: "ServiceWorker.deliverPushMessage", Params: []FieldSpec{{"origin", "string", false}, {"registrationId", "string", false}, {"data", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DeliverPushMessageParams{} }, newResult: nil},
+	"ServiceWorker.disable":                            {Method: "ServiceWorker.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"ServiceWorker.dispatchSyncEvent":                  {Method: "ServiceWorker.dispatchSyncEvent", Params: []FieldSpec{{"origin", "string", false}, {"registrationId", "string", false}, {"tag", "string", false}, {"lastChance", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &DispatchSyncEventParams{} }, newResult: nil},
+	"ServiceWorker.enable":                             {Method: "ServiceWorker.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"ServiceWorker.inspectWorker":                      {Method: "ServiceWorker.inspectWorker", Params: []FieldSpec{{"versionId", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &InspectWorkerParams{} }, newResult: nil},
+	"ServiceWorker.setForceUpdateOnPageLoad":           {Method: "ServiceWorker.setForceUpdateOnPageLoad", Params: []FieldSpec{{"forceUpdateOnPageLoad", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SetForceUpdateOnPageLoadParams{} }, newResult: nil},
+	"ServiceWorker.skipWaiting":                        {Method: "ServiceWorker.skipWaiting", Params: []FieldSpec{{"scopeURL", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &SkipWaitingParams{} }, newResult: nil},
+	"ServiceWorker.startWorker":                        {Method: "ServiceWorker.startWorker", Params: []FieldSpec{{"scopeURL", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &StartWorkerParams{} }, newResult: nil},
+	"ServiceWorker.stopWorker":                         {Method: "ServiceWorker.stopWorker", Params: []FieldSpec{{"versionId", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &StopWorkerParams{} }, newResult: nil},
+	"ServiceWorker.unregister":                         {Method: "ServiceWorker.unregister", Params: []FieldSpec{{"scopeURL", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &UnregisterParams{} }, newResult: nil},
+	"ServiceWorker.updateRegistration":                 {Method: "ServiceWorker.updateRegistration", Params: []FieldSpec{{"scopeURL", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &UpdateRegistrationParams{} }, newResult: nil},
+	"Storage.clearDataForOrigin":                       {Method: "Storage.clearDataForOrigin", Params: []FieldSpec{{"origin", "string", false}, {"storageTypes", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ClearDataForOriginParams{} }, newResult: nil},
+	"SystemInfo.getInfo":                               {Method: "SystemInfo.getInfo", Params: nil, Results: []FieldSpec{{"gpu", "*GPUInfo", true}, {"modelName", "string", true}, {"modelVersion", "string", true}}, Experimental: false, Targets: hc.TargetBrowser, newParams: nil, newResult: func() interface{} { return &GetInfoResult{} }},
+	"Target.activateTarget":                            {Method: "Target.activateTarget", Params: []FieldSpec{{"targetId", "TargetID", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &ActivateTargetParams{} }, newResult: nil},
+	"Target.attachToTarget":                            {Method: "Target.attachToTarget", Params: []FieldSpec{{"targetId", "TargetID", false}}, Results: []FieldSpec{{"success", "bool", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &AttachToTargetParams{} }, newResult: func() interface{} { return &AttachToTargetResult{} }},
+	"Target.closeTarget":                               {Method: "Target.closeTarget", Params: []FieldSpec{{"targetId", "TargetID", false}}, Results: []FieldSpec{{"success", "bool", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &CloseTargetParams{} }, newResult: func() interface{} { return &CloseTargetResult{} }},
+	"Target.createBrowserContext":                      {Method: "Target.createBrowserContext", Params: nil, Results: []FieldSpec{{"browserContextId", "BrowserContextID", true}}, Experimental: false, Targets: hc.TargetBrowser, newParams: nil, newResult: func() interface{} { return &CreateBrowserContextResult{} }},
+	"Target.createTarget":                              {Method: "Target.createTarget", Params: []FieldSpec{{"url", "string", false}, {"width", "int", true}, {"height", "int", true}, {"browserContextId", "BrowserContextID", true}}, Results: []FieldSpec{{"targetId", "TargetID", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &CreateTargetParams{} }, newResult: func() interface{} { return &CreateTargetResult{} }},
+	"Target.detachFromTarget":                          {Method: "Target.detachFromTarget", Params: []FieldSpec{{"targetId", "TargetID", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &DetachFromTargetParams{} }, newResult: nil},
+	"Target.disposeBrowserContext":                     {Method: "Target.disposeBrowserContext", Params: []FieldSpec{{"browserContextId", "BrowserContextID", false}}, Results: []FieldSpec{{"success", "bool", true}}, Experimental: false, Targets: hc.TargetBrowser, newParams: func() interface{} { return &DisposeBrowserContextParams{} }, newResult: func() interface{} { return &DisposeBrowserContextResult{} }},
+	"Target.getTargetInfo":                             {Method: "Target.getTargetInfo", Params: []FieldSpec{{"targetId", "TargetID", false}}, Results: []FieldSpec{{"targetInfo", "*TargetInfo", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &GetTargetInfoParams{} }, newResult: func() interface{} { return &GetTargetInfoResult{} }},
+	"Target.getTargets":                                {Method: "Target.getTargets", Params: nil, Results: []FieldSpec{{"targetInfos", "[]*TargetInfo", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetTargetsResult{} }},
+	"Target.sendMessageToTarget":                       {Method: "Target.sendMessageToTarget", Params: []FieldSpec{{"targetId", "string", false}, {"message", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &SendMessageToTargetParams{} }, newResult: nil},
+	"Target.setAttachToFrames":                         {Method: "Target.setAttachToFrames", Params: []FieldSpec{{"value", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &SetAttachToFramesParams{} }, newResult: nil},
+	"Target.setAutoAttach":                             {Method: "Target.setAutoAttach", Params: []FieldSpec{{"autoAttach", "bool", false}, {"waitForDebuggerOnStart", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &SetAutoAttachParams{} }, newResult: nil},
+	"Target.setDiscoverTargets":                        {Method: "Target.setDiscoverTargets", Params: []FieldSpec{{"discover", "bool", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &SetDiscoverTargetsParams{} }, newResult: nil},
+	"Target.setRemoteLocations":                        {Method: "Target.setRemoteLocations", Params: []FieldSpec{{"locations", "[]*RemoteLocation", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser, newParams: func() interface{} { return &SetRemoteLocationsParams{} }, newResult: nil},
+	"Tethering.bind":                                   {Method: "Tethering.bind", Params: []FieldSpec{{"port", "int", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser, newParams: func() interface{} { return &BindParams{} }, newResult: nil},
+	"Tethering.unbind":                                 {Method: "Tethering.unbind", Params: []FieldSpec{{"port", "int", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser, newParams: func() interface{} { return &UnbindParams{} }, newResult: nil},
+	"Tracing.end":                                      {Method: "Tracing.end", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: nil, newResult: nil},
+	"Tracing.getCategories":                            {Method: "Tracing.getCategories", Params: nil, Results: []FieldSpec{{"categories", "[]string", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: nil, newResult: func() interface{} { return &GetCategoriesResult{} }},
+	"Tracing.recordClockSyncMarker":                    {Method: "Tracing.recordClockSyncMarker", Params: []FieldSpec{{"syncId", "string", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &RecordClockSyncMarkerParams{} }, newResult: nil},
+	"Tracing.requestMemoryDump":                        {Method: "Tracing.requestMemoryDump", Params: nil, Results: []FieldSpec{{"dumpGuid", "string", true}, {"success", "bool", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: nil, newResult: func() interface{} { return &RequestMemoryDumpResult{} }},
+	"Tracing.start":                                    {Method: "Tracing.start", Params: []FieldSpec{{"categories", "string", true}, {"options", "string", true}, {"bufferUsageReportingInterval", "float64", true}, {"transferMode", "string", true}, {"traceConfig", "*TraceConfig", true}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newParams: func() interface{} { return &TracingStartParams{} }, newResult: nil},
+}
+
+var Events = map[string]*EventSpec{
+	"Animation.animationCanceled":                    {Method: "Animation.animationCanceled", Params: []FieldSpec{{"id", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &AnimationCanceledEvent{} }},
+	"Animation.animationCreated":                     {Method: "Animation.animationCreated", Params: []FieldSpec{{"id", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &AnimationCreatedEvent{} }},
+	"Animation.animationStarted":                     {Method: "Animation.animationStarted", Params: []FieldSpec{{"animation", "*Animation", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &AnimationStartedEvent{} }},
+	"ApplicationCache.applicationCacheStatusUpdated": {Method: "ApplicationCache.applicationCacheStatusUpdated", Params: []FieldSpec{{"frameId", "FrameId", true}, {"manifestURL", "string", true}, {"status", "int", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &ApplicationCacheStatusUpdatedEvent{} }},
+	"ApplicationCache.networkStateUpdated":           {Method: "ApplicationCache.networkStateUpdated", Params: []FieldSpec{{"isNowOnline", "bool", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &NetworkStateUpdatedEvent{} }},
+	"CSS.fontsUpdated":                               {Method: "CSS.fontsUpdated", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &FontsUpdatedEvent{} }},
+	"CSS.mediaQueryResultChanged":                    {Method: "CSS.mediaQueryResultChanged", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &MediaQueryResultChangedEvent{} }},
+	"CSS.styleSheetAdded":                            {Method: "CSS.styleSheetAdded", Params: []FieldSpec{{"header", "*CSSStyleSheetHeader", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &StyleSheetAddedEvent{} }},
+	"CSS.styleSheetChanged":                          {Method: "CSS.styleSheetChanged", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &StyleSheetChangedEvent{} }},
+	"CSS.styleSheetRemoved":                          {Method: "CSS.styleSheetRemoved", Params: []FieldSpec{{"styleSheetId", "StyleSheetId", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &StyleSheetRemovedEvent{} }},
+	"Console.messageAdded":                           {Method: "Console.messageAdded", Params: []FieldSpec{{"message", "*ConsoleMessage", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &MessageAddedEvent{} }},
+	"DOM.attributeModified":                          {Method: "DOM.attributeModified", Params: []FieldSpec{{"nodeId", "NodeId", true}, {"name", "string", true}, {"value", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &AttributeModifiedEvent{} }},
+	"DOM.attributeRemoved":                           {Method: "DOM.attributeRemoved", Params: []FieldSpec{{"nodeId", "NodeId", true}, {"name", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &AttributeRemovedEvent{} }},
+	"DOM.characterDataModified":                      {Method: "DOM.characterDataModified", Params: []FieldSpec{{"nodeId", "NodeId", true}, {"characterData", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &CharacterDataModifiedEvent{} }},
+	"DOM.childNodeCountUpdated":                      {Method: "DOM.childNodeCountUpdated", Params: []FieldSpec{{"nodeId", "NodeId", true}, {"childNodeCount", "int", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &ChildNodeCountUpdatedEvent{} }},
+	"DOM.childNodeInserted":                          {Method: "DOM.childNodeInserted", Params: []FieldSpec{{"parentNodeId", "NodeId", true}, {"previousNodeId", "NodeId", true}, {"node", "*Node", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &ChildNodeInsertedEvent{} }},
+	"DOM.childNodeRemoved":                           {Method: "DOM.childNodeRemoved", Params: []FieldSpec{{"parentNodeId", "NodeId", true}, {"nodeId", "NodeId", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &ChildNodeRemovedEvent{} }},
+	"DOM.distributedNodesUpdated":                    {Method: "DOM.distributedNodesUpdated", Params: []FieldSpec{{"insertionPointId", "NodeId", true}, {"distributedNodes", "[]*BackendNode", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &DistributedNodesUpdatedEvent{} }},
+	"DOM.documentUpdated":                            {Method: "DOM.documentUpdated", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DocumentUpdatedEvent{} }},
+	"DOM.inlineStyleInvalidated":                     {Method: "DOM.inlineStyleInvalidated", Params: []FieldSpec{{"nodeIds", "[]NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &InlineStyleInvalidatedEvent{} }},
+	"DOM.inspectNodeRequested":                       {Method: "DOM.inspectNodeRequested", Params: []FieldSpec{{"backendNodeId", "BackendNodeId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &InspectNodeRequestedEvent{} }},
+	"DOM.nodeHighlightRequested":                     {Method: "DOM.nodeHighlightRequested", Params: []FieldSpec{{"nodeId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &NodeHighlightRequestedEvent{} }},
+	"DOM.pseudoElementAdded":                         {Method: "DOM.pseudoElementAdded", Params: []FieldSpec{{"parentId", "NodeId", true}, {"pseudoElement", "*Node", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &PseudoElementAddedEvent{} }},
+	"DOM.pseudoElementRemoved":                       {Method: "DOM.pseudoElementRemoved", Params: []FieldSpec{{"parentId", "NodeId", true}, {"pseudoElementId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &PseudoElementRemovedEvent{} }},
+	"DOM.setChildNodes":                              {Method: "DOM.setChildNodes", Params: []FieldSpec{{"parentId", "NodeId", true}, {"nodes", "[]*Node", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &SetChildNodesEvent{} }},
+	"DOM.shadowRootPopped":                           {Method: "DOM.shadowRootPopped", Params: []FieldSpec{{"hostId", "NodeId", true}, {"rootId", "NodeId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &ShadowRootPoppedEvent{} }},
+	"DOM.shadowRootPushed":                           {Method: "DOM.shadowRootPushed", Params: []FieldSpec{{"hostId", "NodeId", true}, {"root", "*Node", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &ShadowRootPushedEvent{} }},
+	"DOMStorage.domStorageItemAdded":                 {Method: "DOMStorage.domStorageItemAdded", Params: []FieldSpec{{"storageId", "*StorageId", true}, {"key", "string", true}, {"newValue", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DomStorageItemAddedEvent{} }},
+	"DOMStorage.domStorageItemRemoved":               {Method: "DOMStorage.domStorageItemRemoved", Params: []FieldSpec{{"storageId", "*StorageId", true}, {"key", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DomStorageItemRemovedEvent{} }},
+	"DOMStorage.domStorageItemUpdated":               {Method: "DOMStorage.domStorageItemUpdated", Params: []FieldSpec{{"storageId", "*StorageId", true}, {"key", "string", true}, {"oldValue", "string", true}, {"newValue", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DomStorageItemUpdatedEvent{} }},
+	"DOMStorage.domStorageItemsCleared":              {Method: "DOMStorage.domStorageItemsCleared", Params: []FieldSpec{{"storageId", "*StorageId", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DomStorageItemsClearedEvent{} }},
+	"Database.addDatabase":                           {Method: "Database.addDatabase", Params: []FieldSpec{{"database", "*Database", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &AddDatabaseEvent{} }},
+	"Debugger.breakpointResolved":                    {Method: "Debugger.breakpointResolved", Params: []FieldSpec{{"breakpointId", "BreakpointId", true}, {"location", "*Location", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &BreakpointResolvedEvent{} }},
+	"Debugger.paused":                                {Method: "Debugger.paused", Params: []FieldSpec{{"callFrames", "[]*DebuggerCallFrame", true}, {"reason", "string", true}, {"data", "json.RawMessage", true}, {"hitBreakpoints", "[]string", true}, {"asyncStackTrace", "*StackTrace", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &PausedEvent{} }},
+	"Debugger.resumed":                               {Method: "Debugger.resumed", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ResumedEvent{} }},
+	"Debugger.scriptFailedToParse":                   {Method: "Debugger.scriptFailedToParse", Params: []FieldSpec{{"scriptId", "ScriptId", true}, {"url", "string", true}, {"startLine", "int", true}, {"startColumn", "int", true}, {"endLine", "int", true}, {"endColumn", "int", true}, {"executionContextId", "ExecutionContextId", true}, {"hash", "string", true}, {"executionContextAuxData", "json.RawMessage", true}, {"sourceMapURL", "string", true}, {"hasSourceURL", "bool", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ScriptFailedToParseEvent{} }},
+	"Debugger.scriptParsed":                          {Method: "Debugger.scriptParsed", Params: []FieldSpec{{"scriptId", "ScriptId", true}, {"url", "string", true}, {"startLine", "int", true}, {"startColumn", "int", true}, {"endLine", "int", true}, {"endColumn", "int", true}, {"executionContextId", "ExecutionContextId", true}, {"hash", "string", true}, {"executionContextAuxData", "json.RawMessage", true}, {"isLiveEdit", "bool", true}, {"sourceMapURL", "string", true}, {"hasSourceURL", "bool", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ScriptParsedEvent{} }},
+	"Emulation.virtualTimeBudgetExpired":             {Method: "Emulation.virtualTimeBudgetExpired", Params: []FieldSpec{}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &VirtualTimeBudgetExpiredEvent{} }},
+	"HeapProfiler.addHeapSnapshotChunk":              {Method: "HeapProfiler.addHeapSnapshotChunk", Params: []FieldSpec{{"chunk", "string", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &AddHeapSnapshotChunkEvent{} }},
+	"HeapProfiler.heapStatsUpdate":                   {Method: "HeapProfiler.heapStatsUpdate", Params: []FieldSpec{{"statsUpdate", "[]int", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &HeapStatsUpdateEvent{} }},
+	"HeapProfiler.lastSeenObjectId":                  {Method: "HeapProfiler.lastSeenObjectId", Params: []FieldSpec{{"lastSeenObjectId", "int", true}, {"timestamp", "float64", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &LastSeenObjectIdEvent{} }},
+	"HeapProfiler.reportHeapSnapshotProgress":        {Method: "HeapProfiler.reportHeapSnapshotProgress", Params: []FieldSpec{{"done", "int", true}, {"total", "int", true}, {"finished", "bool", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ReportHeapSnapshotProgressEvent{} }},
+	"HeapProfiler.resetProfiles":                     {Method: "HeapProfiler.resetProfiles", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ResetProfilesEvent{} }},
+	"Inspector.detached":                             {Method: "Inspector.detached", Params: []FieldSpec{{"reason", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DetachedEvent{} }},
+	"Inspector.targetCrashed":                        {Method: "Inspector.targetCrashed", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &TargetCrashedEvent{} }},
+	"LayerTree.layerPainted":                         {Method: "LayerTree.layerPainted", Params: []FieldSpec{{"layerId", "LayerId", true}, {"clip", "*Rect", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &LayerPaintedEvent{} }},
+	"LayerTree.layerTreeDidChange":                   {Method: "LayerTree.layerTreeDidChange", Params: []FieldSpec{{"layers", "[]*Layer", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &LayerTreeDidChangeEvent{} }},
+	"Log.entryAdded":                                 {Method: "Log.entryAdded", Params: []FieldSpec{{"entry", "*LogEntry", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &EntryAddedEvent{} }},
+	"Network.dataReceived":                           {Method: "Network.dataReceived", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"dataLength", "int", true}, {"encodedDataLength", "int", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &DataReceivedEvent{} }},
+	"Network.eventSourceMessageReceived":             {Method: "Network.eventSourceMessageReceived", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"eventName", "string", true}, {"eventId", "string", true}, {"data", "string", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &EventSourceMessageReceivedEvent{} }},
+	"Network.loadingFailed":                          {Method: "Network.loadingFailed", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"type", "ResourceType", true}, {"errorText", "string", true}, {"canceled", "bool", true}, {"blockedReason", "BlockedReason", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &LoadingFailedEvent{} }},
+	"Network.loadingFinished":                        {Method: "Network.loadingFinished", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"encodedDataLength", "float64", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &LoadingFinishedEvent{} }},
+	"Network.requestServedFromCache":                 {Method: "Network.requestServedFromCache", Params: []FieldSpec{{"requestId", "RequestId", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &RequestServedFromCacheEvent{} }},
+	"Network.requestWillBeSent":                      {Method: "Network.requestWillBeSent", Params: []FieldSpec{{"requestId", "RequestId", true}, {"frameId", "FrameId", true}, {"loaderId", "LoaderId", true}, {"documentURL", "string", true}, {"request", "*Request", true}, {"timestamp", "NetworkTimestamp", true}, {"wallTime", "NetworkTimestamp", true}, {"initiator", "*Initiator", true}, {"redirectResponse", "*Response", true}, {"type", "ResourceType", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &RequestWillBeSentEvent{} }},
+	"Network.resourceChangedPriority":                {Method: "Network.resourceChangedPriority", Params: []FieldSpec{{"requestId", "RequestId", true}, {"newPriority", "ResourcePriority", true}, {"timestamp", "NetworkTimestamp", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ResourceChangedPriorityEvent{} }},
+	"Network.responseReceived":                       {Method: "Network.responseReceived", Params: []FieldSpec{{"requestId", "RequestId", true}, {"frameId", "FrameId", true}, {"loaderId", "LoaderId", true}, {"timestamp", "NetworkTimestamp", true}, {"type", "ResourceType", true}, {"response", "*Response", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ResponseReceivedEvent{} }},
+	"Network.webSocketClosed":                        {Method: "Network.webSocketClosed", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketClosedEvent{} }},
+	"Network.webSocketCreated":                       {Method: "Network.webSocketCreated", Params: []FieldSpec{{"requestId", "RequestId", true}, {"url", "string", true}, {"initiator", "*Initiator", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketCreatedEvent{} }},
+	"Network.webSocketFrameError":                    {Method: "Network.webSocketFrameError", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"errorMessage", "string", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketFrameErrorEvent{} }},
+	"Network.webSocketFrameReceived":                 {Method: "Network.webSocketFrameReceived", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"response", "*WebSocketFrame", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketFrameReceivedEvent{} }},
+	"Network.webSocketFrameSent":                     {Method: "Network.webSocketFrameSent", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"response", "*WebSocketFrame", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketFrameSentEvent{} }},
+	"Network.webSocketHandshakeResponseReceived":     {Method: "Network.webSocketHandshakeResponseReceived", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"response", "*WebSocketResponse", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketHandshakeResponseReceivedEvent{} }},
+	"Network.webSocketWillSendHandshakeRequest":      {Method: "Network.webSocketWillSendHandshakeRequest", Params: []FieldSpec{{"requestId", "RequestId", true}, {"timestamp", "NetworkTimestamp", true}, {"wallTime", "NetworkTimestamp", true}, {"request", "*WebSocketRequest", true}}, Experimental: true, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &WebSocketWillSendHandshakeRequestEvent{} }},
+	"Page.colorPicked":                               {Method: "Page.colorPicked", Params: []FieldSpec{{"color", "*RGBA", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &ColorPickedEvent{} }},
+	"Page.domContentEventFired":                      {Method: "Page.domContentEventFired", Params: []FieldSpec{{"timestamp", "float64", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &DomContentEventFiredEvent{} }},
+	"Page.frameAttached":                             {Method: "Page.frameAttached", Params: []FieldSpec{{"frameId", "FrameId", true}, {"parentFrameId", "FrameId", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameAttachedEvent{} }},
+	"Page.frameClearedScheduledNavigation":           {Method: "Page.frameClearedScheduledNavigation", Params: []FieldSpec{{"frameId", "FrameId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameClearedScheduledNavigationEvent{} }},
+	"Page.frameDetached":                             {Method: "Page.frameDetached", Params: []FieldSpec{{"frameId", "FrameId", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameDetachedEvent{} }},
+	"Page.frameNavigated":                            {Method: "Page.frameNavigated", Params: []FieldSpec{{"frame", "*Frame", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameNavigatedEvent{} }},
+	"Page.frameResized":                              {Method: "Page.frameResized", Params: []FieldSpec{}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameResizedEvent{} }},
+	"Page.frameScheduledNavigation":                  {Method: "Page.frameScheduledNavigation", Params: []FieldSpec{{"frameId", "FrameId", true}, {"delay", "float64", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameScheduledNavigationEvent{} }},
+	"Page.frameStartedLoading":                       {Method: "Page.frameStartedLoading", Params: []FieldSpec{{"frameId", "FrameId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameStartedLoadingEvent{} }},
+	"Page.frameStoppedLoading":                       {Method: "Page.frameStoppedLoading", Params: []FieldSpec{{"frameId", "FrameId", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &FrameStoppedLoadingEvent{} }},
+	"Page.interstitialHidden":                        {Method: "Page.interstitialHidden", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &InterstitialHiddenEvent{} }},
+	"Page.interstitialShown":                         {Method: "Page.interstitialShown", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &InterstitialShownEvent{} }},
+	"Page.javascriptDialogClosed":                    {Method: "Page.javascriptDialogClosed", Params: []FieldSpec{{"result", "bool", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &JavascriptDialogClosedEvent{} }},
+	"Page.javascriptDialogOpening":                   {Method: "Page.javascriptDialogOpening", Params: []FieldSpec{{"message", "string", true}, {"type", "DialogType", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &JavascriptDialogOpeningEvent{} }},
+	"Page.loadEventFired":                            {Method: "Page.loadEventFired", Params: []FieldSpec{{"timestamp", "float64", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &LoadEventFiredEvent{} }},
+	"Page.navigationRequested":                       {Method: "Page.navigationRequested", Params: []FieldSpec{{"isInMainFrame", "bool", true}, {"isRedirect", "bool", true}, {"navigationId", "int", true}, {"url", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &NavigationRequestedEvent{} }},
+	"Page.screencastFrame":                           {Method: "Page.screencastFrame", Params: []FieldSpec{{"data", "string", true}, {"metadata", "*ScreencastFrameMetadata", true}, {"sessionId", "int", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &ScreencastFrameEvent{} }},
+	"Page.screencastVisibilityChanged":               {Method: "Page.screencastVisibilityChanged", Params: []FieldSpec{{"visible", "bool", true}}, Experimental: true, Targets: hc.TargetPage, newEvent: func() interface{} { return &ScreencastVisibilityChangedEvent{} }},
+	"Profiler.consoleProfileFinished":                {Method: "Profiler.consoleProfileFinished", Params: []FieldSpec{{"id", "string", true}, {"location", "*Location", true}, {"profile", "*Profile", true}, {"title", "string", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ConsoleProfileFinishedEvent{} }},
+	"Profiler.consoleProfileStarted":                 {Method: "Profiler.consoleProfileStarted", Params: []FieldSpec{{"id", "string", true}, {"location", "*Location", true}, {"title", "string", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ConsoleProfileStartedEvent{} }},
+	"Runtime.consoleAPICalled":                       {Method: "Runtime.consoleAPICalled", Params: []FieldSpec{{"type", "string", true}, {"args", "[]*RemoteObject", true}, {"executionContextId", "ExecutionContextId", true}, {"timestamp", "RuntimeTimestamp", true}, {"stackTrace", "*StackTrace", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ConsoleAPICalledEvent{} }},
+	"Runtime.exceptionRevoked":                       {Method: "Runtime.exceptionRevoked", Params: []FieldSpec{{"reason", "string", true}, {"exceptionId", "int", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ExceptionRevokedEvent{} }},
+	"Runtime.exceptionThrown":                        {Method: "Runtime.exceptionThrown", Params: []FieldSpec{{"timestamp", "RuntimeTimestamp", true}, {"exceptionDetails", "*ExceptionDetails", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ExceptionThrownEvent{} }},
+	"Runtime.executionContextCreated":                {Method: "Runtime.executionContextCreated", Params: []FieldSpec{{"context", "*ExecutionContextDescription", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ExecutionContextCreatedEvent{} }},
+	"Runtime.executionContextDestroyed":              {Method: "Runtime.executionContextDestroyed", Params: []FieldSpec{{"executionContextId", "ExecutionContextId", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ExecutionContextDestroyedEvent{} }},
+	"Runtime.executionContextsCleared":               {Method: "Runtime.executionContextsCleared", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ExecutionContextsClearedEvent{} }},
+	"Runtime.inspectRequested":                       {Method: "Runtime.inspectRequested", Params: []FieldSpec{{"object", "*RemoteObject", true}, {"hints", "json.RawMessage", true}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &InspectRequestedEvent{} }},
+	"Security.securityStateChanged":                  {Method: "Security.securityStateChanged", Params: []FieldSpec{{"securityState", "SecurityState", true}, {"schemeIsCryptographic", "bool", true}, {"explanations", "[]*SecurityStateExplanation", true}, {"insecureContentStatus", "*InsecureContentStatus", true}, {"summary", "string", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &SecurityStateChangedEvent{} }},
+	"ServiceWorker.workerErrorReported":              {Method: "ServiceWorker.workerErrorReported", Params: []FieldSpec{{"errorMessage", "*ServiceWorkerErrorMessage", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &WorkerErrorReportedEvent{} }},
+	"ServiceWorker.workerRegistrationUpdated":        {Method: "ServiceWorker.workerRegistrationUpdated", Params: []FieldSpec{{"registrations", "[]*ServiceWorkerRegistration", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &WorkerRegistrationUpdatedEvent{} }},
+	"ServiceWorker.workerVersionUpdated":             {Method: "ServiceWorker.workerVersionUpdated", Params: []FieldSpec{{"versions", "[]*ServiceWorkerVersion", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &WorkerVersionUpdatedEvent{} }},
+	"Target.attachedToTarget":                        {Method: "Target.attachedToTarget", Params: []FieldSpec{{"targetInfo", "*TargetInfo", true}, {"waitingForDebugger", "bool", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &AttachedToTargetEvent{} }},
+	"Target.detachedFromTarget":                      {Method: "Target.detachedFromTarget", Params: []FieldSpec{{"targetId", "TargetID", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &DetachedFromTargetEvent{} }},
+	"Target.receivedMessageFromTarget":               {Method: "Target.receivedMessageFromTarget", Params: []FieldSpec{{"targetId", "TargetID", true}, {"message", "string", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &ReceivedMessageFromTargetEvent{} }},
+	"Target.targetCreated":                           {Method: "Target.targetCreated", Params: []FieldSpec{{"targetInfo", "*TargetInfo", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &TargetCreatedEvent{} }},
+	"Target.targetDestroyed":                         {Method: "Target.targetDestroyed", Params: []FieldSpec{{"targetId", "TargetID", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &TargetDestroyedEvent{} }},
+	"Tethering.accepted":                             {Method: "Tethering.accepted", Params: []FieldSpec{{"port", "int", true}, {"connectionId", "string", true}}, Experimental: false, Targets: hc.TargetBrowser, newEvent: func() interface{} { return &AcceptedEvent{} }},
+	"Tracing.bufferUsage":                            {Method: "Tracing.bufferUsage", Params: []FieldSpec{{"percentFull", "float64", true}, {"eventCount", "float64", true}, {"value", "float64", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &BufferUsageEvent{} }},
+	"Tracing.dataCollected":                          {Method: "Tracing.dataCollected", Params: []FieldSpec{{"value", "[]json.RawMessage", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &DataCollectedEvent{} }},
+	"Tracing.tracingComplete":                        {Method: "Tracing.tracingComplete", Params: []FieldSpec{{"stream", "StreamHandle", true}}, Experimental: false, Targets: hc.TargetBrowser | hc.TargetPage, newEvent: func() interface{} { return &TracingCompleteEvent{} }},
+}
@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	Commands["Test.run"] = &CommandSpec{Method: "Test.run",
+		Results:   []FieldSpec{{"nodeId", "NodeId", false}, {"name", "string", true}},
+		newResult: func() interface{} { return &struct{ NodeId, Name interface{} }{} }}
+	defer delete(Commands, "Test.run")
+
+	for _, c := range []struct {
+		data  string
+		field string
+	}{
+		{`{"nodeId": 1, "name": "a"}`, ""},
+		{`{"nodeId": 1}`, ""},
+		{`{"name": "a"}`, "nodeId"},
+		{`{"nodeId": 1, "extra": true}`, "extra"},
+	} {
+		err := checkSchema("Test.run", false, []byte(c.data))
+		if c.field == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.data, err)
+			}
+		} else if err == nil || err.Field != c.field || err.Method != "Test.run" {
+			t.Errorf("%s: got %v, want %s reported", c.data, err, c.field)
+		}
+	}
+	if err := checkSchema("Test.unknown", false, []byte(`{"a": 1}`)); err != nil {
+		t.Errorf("Checked unknown method: %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -18,7 +18,6 @@ type ConsoleMessage struct {
 }
 
 // Enables console domain, sends the messages collected so far to the client by means of the messageAdded notification.
-
 type ConsoleEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -36,30 +35,34 @@ func (cmd *ConsoleEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ConsoleEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *ConsoleEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ConsoleEnable(conn *hc.Conn) (err error) {
+func ConsoleEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewConsoleEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ConsoleEnableCB func(err error)
 
 // Enables console domain, sends the messages collected so far to the client by means of the messageAdded notification.
-
 type AsyncConsoleEnableCommand struct {
-	cb ConsoleEnableCB
+	cb   ConsoleEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncConsoleEnableCommand(cb ConsoleEnableCB) *AsyncConsoleEnableCommand {
 	return &AsyncConsoleEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -77,11 +80,31 @@ func (cmd *ConsoleEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncConsoleEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables console domain, prevents further console messages from being reported to the client.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncConsoleEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ConsoleEnableCommand) Send(conn hc.CommandRunner) *AsyncConsoleEnableCommand {
+	async := NewAsyncConsoleEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables console domain, prevents further console messages from being reported to the client.
 type ConsoleDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -99,30 +122,34 @@ func (cmd *ConsoleDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ConsoleDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *ConsoleDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ConsoleDisable(conn *hc.Conn) (err error) {
+func ConsoleDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewConsoleDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ConsoleDisableCB func(err error)
 
 // Disables console domain, prevents further console messages from being reported to the client.
-
 type AsyncConsoleDisableCommand struct {
-	cb ConsoleDisableCB
+	cb   ConsoleDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncConsoleDisableCommand(cb ConsoleDisableCB) *AsyncConsoleDisableCommand {
 	return &AsyncConsoleDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -140,11 +167,31 @@ func (cmd *ConsoleDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncConsoleDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Does nothing.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncConsoleDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ConsoleDisableCommand) Send(conn hc.CommandRunner) *AsyncConsoleDisableCommand {
+	async := NewAsyncConsoleDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Does nothing.
 type ClearMessagesCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -162,30 +209,34 @@ func (cmd *ClearMessagesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ClearMessagesCommand) Run(conn *hc.Conn) error {
+func (cmd *ClearMessagesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ClearMessages(conn *hc.Conn) (err error) {
+func ClearMessages(conn hc.CommandRunner) (err error) {
 	cmd := NewClearMessagesCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ClearMessagesCB func(err error)
 
 // Does nothing.
-
 type AsyncClearMessagesCommand struct {
-	cb ClearMessagesCB
+	cb   ClearMessagesCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncClearMessagesCommand(cb ClearMessagesCB) *AsyncClearMessagesCommand {
 	return &AsyncClearMessagesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -203,23 +254,59 @@ func (cmd *ClearMessagesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncClearMessagesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Issued when new console message is added.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearMessagesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearMessagesCommand) Send(conn hc.CommandRunner) *AsyncClearMessagesCommand {
+	async := NewAsyncClearMessagesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Issued when new console message is added.
 type MessageAddedEvent struct {
 	Message *ConsoleMessage `json:"message"` // Console message that has been added.
 }
 
-func OnMessageAdded(conn *hc.Conn, cb func(evt *MessageAddedEvent)) {
+// Calls cb for each Console.messageAdded event, till the returned func is called.
+func OnMessageAdded(conn hc.EventSource, cb func(evt *MessageAddedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &MessageAddedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Console.messageAdded", sink)
+	return func() { conn.RemoveEventSink("Console.messageAdded", sink) }
+}
+
+// Like OnMessageAdded, but cb also gets hc.EventMeta.
+func OnMessageAddedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *MessageAddedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &MessageAddedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Console.messageAdded", sink)
+	return func() { conn.RemoveEventSink("Console.messageAdded", sink) }
 }
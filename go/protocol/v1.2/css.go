@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -19,7 +19,7 @@ const StyleSheetOriginRegular StyleSheetOrigin = "regular"
 
 // CSS rule collection for a single pseudo style.
 type PseudoElementMatches struct {
-	PseudoType *PseudoType  `json:"pseudoType"` // Pseudo element type.
+	PseudoType PseudoType   `json:"pseudoType"` // Pseudo element type.
 	Matches    []*RuleMatch `json:"matches"`    // Matches of CSS rules applicable to the pseudo style.
 }
 
@@ -50,12 +50,12 @@ type SelectorList struct {
 // CSS stylesheet metainformation.
 type CSSStyleSheetHeader struct {
 	StyleSheetId StyleSheetId     `json:"styleSheetId"`           // The stylesheet identifier.
-	FrameId      *FrameId         `json:"frameId"`                // Owner frame identifier.
+	FrameId      FrameId          `json:"frameId"`                // Owner frame identifier.
 	SourceURL    string           `json:"sourceURL"`              // Stylesheet resource URL.
 	SourceMapURL string           `json:"sourceMapURL,omitempty"` // URL of source map associated with the stylesheet (if any).
 	Origin       StyleSheetOrigin `json:"origin"`                 // Stylesheet origin.
 	Title        string           `json:"title"`                  // Stylesheet title.
-	OwnerNode    *BackendNodeId   `json:"ownerNode,omitempty"`    // The backend id for the owner node of the stylesheet.
+	OwnerNode    BackendNodeId    `json:"ownerNode,omitempty"`    // The backend id for the owner node of the stylesheet.
 	Disabled     bool             `json:"disabled"`               // Denotes whether the stylesheet is disabled.
 	HasSourceURL bool             `json:"hasSourceURL,omitempty"` // Whether the sourceURL field value comes from the sourceURL comment.
 	IsInline     bool             `json:"isInline"`               // Whether this stylesheet is created for STYLE tag by parser. This flag is not set for document.written STYLE tags.
@@ -187,7 +187,7 @@ type InlineTextBox struct {
 // Details of an element in the DOM tree with a LayoutObject.
 // @experimental
 type LayoutTreeNode struct {
-	NodeId          *NodeId          `json:"nodeId"`                    // The id of the related DOM node matching one from DOM.GetDocument.
+	NodeId          NodeId           `json:"nodeId"`                    // The id of the related DOM node matching one from DOM.GetDocument.
 	BoundingBox     *Rect            `json:"boundingBox"`               // The absolute position bounding box.
 	LayoutText      string           `json:"layoutText,omitempty"`      // Contents of the LayoutText if any
 	InlineTextNodes []*InlineTextBox `json:"inlineTextNodes,omitempty"` // The post layout inline text nodes, if any.
@@ -201,7 +201,6 @@ type ComputedStyle struct {
 }
 
 // Enables the CSS agent for the given page. Clients should not assume that the CSS agent has been enabled until the result of this command is received.
-
 type CSSEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -219,30 +218,34 @@ func (cmd *CSSEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CSSEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *CSSEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CSSEnable(conn *hc.Conn) (err error) {
+func CSSEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewCSSEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type CSSEnableCB func(err error)
 
 // Enables the CSS agent for the given page. Clients should not assume that the CSS agent has been enabled until the result of this command is received.
-
 type AsyncCSSEnableCommand struct {
-	cb CSSEnableCB
+	cb   CSSEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCSSEnableCommand(cb CSSEnableCB) *AsyncCSSEnableCommand {
 	return &AsyncCSSEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -260,11 +263,31 @@ func (cmd *CSSEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncCSSEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables the CSS agent for the given page.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCSSEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CSSEnableCommand) Send(conn hc.CommandRunner) *AsyncCSSEnableCommand {
+	async := NewAsyncCSSEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables the CSS agent for the given page.
 type CSSDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -282,30 +305,34 @@ func (cmd *CSSDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CSSDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *CSSDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CSSDisable(conn *hc.Conn) (err error) {
+func CSSDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewCSSDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type CSSDisableCB func(err error)
 
 // Disables the CSS agent for the given page.
-
 type AsyncCSSDisableCommand struct {
-	cb CSSDisableCB
+	cb   CSSDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCSSDisableCommand(cb CSSDisableCB) *AsyncCSSDisableCommand {
 	return &AsyncCSSDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -323,11 +350,32 @@ func (cmd *CSSDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncCSSDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCSSDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CSSDisableCommand) Send(conn hc.CommandRunner) *AsyncCSSDisableCommand {
+	async := NewAsyncCSSDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetMatchedStylesForNodeParams struct {
-	NodeId *NodeId `json:"nodeId"`
+	NodeId NodeId `json:"nodeId"`
 }
 
 type GetMatchedStylesForNodeResult struct {
@@ -340,7 +388,6 @@ type GetMatchedStylesForNodeResult struct {
 }
 
 // Returns requested styles for a DOM node identified by nodeId.
-
 type GetMatchedStylesForNodeCommand struct {
 	params *GetMatchedStylesForNodeParams
 	result GetMatchedStylesForNodeResult
@@ -362,32 +409,40 @@ func (cmd *GetMatchedStylesForNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetMatchedStylesForNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetMatchedStylesForNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetMatchedStylesForNode(params *GetMatchedStylesForNodeParams, conn *hc.Conn) (result *GetMatchedStylesForNodeResult, err error) {
+func GetMatchedStylesForNode(params *GetMatchedStylesForNodeParams, conn hc.CommandRunner) (result *GetMatchedStylesForNodeResult, err error) {
 	cmd := NewGetMatchedStylesForNodeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetMatchedStylesForNodeCB func(result *GetMatchedStylesForNodeResult, err error)
 
 // Returns requested styles for a DOM node identified by nodeId.
-
 type AsyncGetMatchedStylesForNodeCommand struct {
 	params *GetMatchedStylesForNodeParams
 	cb     GetMatchedStylesForNodeCB
+	result *GetMatchedStylesForNodeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetMatchedStylesForNodeCommand(params *GetMatchedStylesForNodeParams, cb GetMatchedStylesForNodeCB) *AsyncGetMatchedStylesForNodeCommand {
 	return &AsyncGetMatchedStylesForNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -416,17 +471,35 @@ func (cmd *AsyncGetMatchedStylesForNodeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetMatchedStylesForNodeCommand) Wait(ctx context.Context) (*GetMatchedStylesForNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetMatchedStylesForNodeCommand) Send(conn hc.CommandRunner) *AsyncGetMatchedStylesForNodeCommand {
+	async := NewAsyncGetMatchedStylesForNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetInlineStylesForNodeParams struct {
-	NodeId *NodeId `json:"nodeId"`
+	NodeId NodeId `json:"nodeId"`
 }
 
 type GetInlineStylesForNodeResult struct {
@@ -435,7 +508,6 @@ type GetInlineStylesForNodeResult struct {
 }
 
 // Returns the styles defined inline (explicitly in the "style" attribute and implicitly, using DOM attributes) for a DOM node identified by nodeId.
-
 type GetInlineStylesForNodeCommand struct {
 	params *GetInlineStylesForNodeParams
 	result GetInlineStylesForNodeResult
@@ -457,32 +529,40 @@ func (cmd *GetInlineStylesForNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetInlineStylesForNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetInlineStylesForNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetInlineStylesForNode(params *GetInlineStylesForNodeParams, conn *hc.Conn) (result *GetInlineStylesForNodeResult, err error) {
+func GetInlineStylesForNode(params *GetInlineStylesForNodeParams, conn hc.CommandRunner) (result *GetInlineStylesForNodeResult, err error) {
 	cmd := NewGetInlineStylesForNodeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetInlineStylesForNodeCB func(result *GetInlineStylesForNodeResult, err error)
 
 // Returns the styles defined inline (explicitly in the "style" attribute and implicitly, using DOM attributes) for a DOM node identified by nodeId.
-
 type AsyncGetInlineStylesForNodeCommand struct {
 	params *GetInlineStylesForNodeParams
 	cb     GetInlineStylesForNodeCB
+	result *GetInlineStylesForNodeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetInlineStylesForNodeCommand(params *GetInlineStylesForNodeParams, cb GetInlineStylesForNodeCB) *AsyncGetInlineStylesForNodeCommand {
 	return &AsyncGetInlineStylesForNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -511,17 +591,35 @@ func (cmd *AsyncGetInlineStylesForNodeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetInlineStylesForNodeCommand) Wait(ctx context.Context) (*GetInlineStylesForNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetInlineStylesForNodeCommand) Send(conn hc.CommandRunner) *AsyncGetInlineStylesForNodeCommand {
+	async := NewAsyncGetInlineStylesForNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetComputedStyleForNodeParams struct {
-	NodeId *NodeId `json:"nodeId"`
+	NodeId NodeId `json:"nodeId"`
 }
 
 type GetComputedStyleForNodeResult struct {
@@ -529,7 +627,6 @@ type GetComputedStyleForNodeResult struct {
 }
 
 // Returns the computed style for a DOM node identified by nodeId.
-
 type GetComputedStyleForNodeCommand struct {
 	params *GetComputedStyleForNodeParams
 	result GetComputedStyleForNodeResult
@@ -551,32 +648,40 @@ func (cmd *GetComputedStyleForNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetComputedStyleForNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetComputedStyleForNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetComputedStyleForNode(params *GetComputedStyleForNodeParams, conn *hc.Conn) (result *GetComputedStyleForNodeResult, err error) {
+func GetComputedStyleForNode(params *GetComputedStyleForNodeParams, conn hc.CommandRunner) (result *GetComputedStyleForNodeResult, err error) {
 	cmd := NewGetComputedStyleForNodeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetComputedStyleForNodeCB func(result *GetComputedStyleForNodeResult, err error)
 
 // Returns the computed style for a DOM node identified by nodeId.
-
 type AsyncGetComputedStyleForNodeCommand struct {
 	params *GetComputedStyleForNodeParams
 	cb     GetComputedStyleForNodeCB
+	result *GetComputedStyleForNodeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetComputedStyleForNodeCommand(params *GetComputedStyleForNodeParams, cb GetComputedStyleForNodeCB) *AsyncGetComputedStyleForNodeCommand {
 	return &AsyncGetComputedStyleForNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -605,17 +710,35 @@ func (cmd *AsyncGetComputedStyleForNodeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetComputedStyleForNodeCommand) Wait(ctx context.Context) (*GetComputedStyleForNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetComputedStyleForNodeCommand) Send(conn hc.CommandRunner) *AsyncGetComputedStyleForNodeCommand {
+	async := NewAsyncGetComputedStyleForNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetPlatformFontsForNodeParams struct {
-	NodeId *NodeId `json:"nodeId"`
+	NodeId NodeId `json:"nodeId"`
 }
 
 type GetPlatformFontsForNodeResult struct {
@@ -645,17 +768,21 @@ func (cmd *GetPlatformFontsForNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetPlatformFontsForNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetPlatformFontsForNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetPlatformFontsForNode(params *GetPlatformFontsForNodeParams, conn *hc.Conn) (result *GetPlatformFontsForNodeResult, err error) {
+func GetPlatformFontsForNode(params *GetPlatformFontsForNodeParams, conn hc.CommandRunner) (result *GetPlatformFontsForNodeResult, err error) {
 	cmd := NewGetPlatformFontsForNodeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetPlatformFontsForNodeCB func(result *GetPlatformFontsForNodeResult, err error)
@@ -665,12 +792,17 @@ type GetPlatformFontsForNodeCB func(result *GetPlatformFontsForNodeResult, err e
 type AsyncGetPlatformFontsForNodeCommand struct {
 	params *GetPlatformFontsForNodeParams
 	cb     GetPlatformFontsForNodeCB
+	result *GetPlatformFontsForNodeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetPlatformFontsForNodeCommand(params *GetPlatformFontsForNodeParams, cb GetPlatformFontsForNodeCB) *AsyncGetPlatformFontsForNodeCommand {
 	return &AsyncGetPlatformFontsForNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -699,15 +831,33 @@ func (cmd *AsyncGetPlatformFontsForNodeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetPlatformFontsForNodeCommand) Wait(ctx context.Context) (*GetPlatformFontsForNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetPlatformFontsForNodeCommand) Send(conn hc.CommandRunner) *AsyncGetPlatformFontsForNodeCommand {
+	async := NewAsyncGetPlatformFontsForNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetStyleSheetTextParams struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"`
 }
@@ -717,7 +867,6 @@ type GetStyleSheetTextResult struct {
 }
 
 // Returns the current textual content and the URL for a stylesheet.
-
 type GetStyleSheetTextCommand struct {
 	params *GetStyleSheetTextParams
 	result GetStyleSheetTextResult
@@ -739,32 +888,40 @@ func (cmd *GetStyleSheetTextCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetStyleSheetTextCommand) Run(conn *hc.Conn) error {
+func (cmd *GetStyleSheetTextCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetStyleSheetText(params *GetStyleSheetTextParams, conn *hc.Conn) (result *GetStyleSheetTextResult, err error) {
+func GetStyleSheetText(params *GetStyleSheetTextParams, conn hc.CommandRunner) (result *GetStyleSheetTextResult, err error) {
 	cmd := NewGetStyleSheetTextCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetStyleSheetTextCB func(result *GetStyleSheetTextResult, err error)
 
 // Returns the current textual content and the URL for a stylesheet.
-
 type AsyncGetStyleSheetTextCommand struct {
 	params *GetStyleSheetTextParams
 	cb     GetStyleSheetTextCB
+	result *GetStyleSheetTextResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetStyleSheetTextCommand(params *GetStyleSheetTextParams, cb GetStyleSheetTextCB) *AsyncGetStyleSheetTextCommand {
 	return &AsyncGetStyleSheetTextCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -793,15 +950,33 @@ func (cmd *AsyncGetStyleSheetTextCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetStyleSheetTextCommand) Wait(ctx context.Context) (*GetStyleSheetTextResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetStyleSheetTextCommand) Send(conn hc.CommandRunner) *AsyncGetStyleSheetTextCommand {
+	async := NewAsyncGetStyleSheetTextCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CollectClassNamesParams struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"`
 }
@@ -833,17 +1008,21 @@ func (cmd *CollectClassNamesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CollectClassNamesCommand) Run(conn *hc.Conn) error {
+func (cmd *CollectClassNamesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CollectClassNames(params *CollectClassNamesParams, conn *hc.Conn) (result *CollectClassNamesResult, err error) {
+func CollectClassNames(params *CollectClassNamesParams, conn hc.CommandRunner) (result *CollectClassNamesResult, err error) {
 	cmd := NewCollectClassNamesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CollectClassNamesCB func(result *CollectClassNamesResult, err error)
@@ -853,12 +1032,17 @@ type CollectClassNamesCB func(result *CollectClassNamesResult, err error)
 type AsyncCollectClassNamesCommand struct {
 	params *CollectClassNamesParams
 	cb     CollectClassNamesCB
+	result *CollectClassNamesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCollectClassNamesCommand(params *CollectClassNamesParams, cb CollectClassNamesCB) *AsyncCollectClassNamesCommand {
 	return &AsyncCollectClassNamesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -887,15 +1071,33 @@ func (cmd *AsyncCollectClassNamesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCollectClassNamesCommand) Wait(ctx context.Context) (*CollectClassNamesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CollectClassNamesCommand) Send(conn hc.CommandRunner) *AsyncCollectClassNamesCommand {
+	async := NewAsyncCollectClassNamesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetStyleSheetTextParams struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"`
 	Text         string       `json:"text"`
@@ -906,7 +1108,6 @@ type SetStyleSheetTextResult struct {
 }
 
 // Sets the new stylesheet text.
-
 type SetStyleSheetTextCommand struct {
 	params *SetStyleSheetTextParams
 	result SetStyleSheetTextResult
@@ -928,32 +1129,40 @@ func (cmd *SetStyleSheetTextCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetStyleSheetTextCommand) Run(conn *hc.Conn) error {
+func (cmd *SetStyleSheetTextCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetStyleSheetText(params *SetStyleSheetTextParams, conn *hc.Conn) (result *SetStyleSheetTextResult, err error) {
+func SetStyleSheetText(params *SetStyleSheetTextParams, conn hc.CommandRunner) (result *SetStyleSheetTextResult, err error) {
 	cmd := NewSetStyleSheetTextCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetStyleSheetTextCB func(result *SetStyleSheetTextResult, err error)
 
 // Sets the new stylesheet text.
-
 type AsyncSetStyleSheetTextCommand struct {
 	params *SetStyleSheetTextParams
 	cb     SetStyleSheetTextCB
+	result *SetStyleSheetTextResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetStyleSheetTextCommand(params *SetStyleSheetTextParams, cb SetStyleSheetTextCB) *AsyncSetStyleSheetTextCommand {
 	return &AsyncSetStyleSheetTextCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -982,15 +1191,33 @@ func (cmd *AsyncSetStyleSheetTextCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetStyleSheetTextCommand) Wait(ctx context.Context) (*SetStyleSheetTextResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetStyleSheetTextCommand) Send(conn hc.CommandRunner) *AsyncSetStyleSheetTextCommand {
+	async := NewAsyncSetStyleSheetTextCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetRuleSelectorParams struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"`
 	Range        *SourceRange `json:"range"`
@@ -1002,7 +1229,6 @@ type SetRuleSelectorResult struct {
 }
 
 // Modifies the rule selector.
-
 type SetRuleSelectorCommand struct {
 	params *SetRuleSelectorParams
 	result SetRuleSelectorResult
@@ -1024,32 +1250,40 @@ func (cmd *SetRuleSelectorCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetRuleSelectorCommand) Run(conn *hc.Conn) error {
+func (cmd *SetRuleSelectorCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetRuleSelector(params *SetRuleSelectorParams, conn *hc.Conn) (result *SetRuleSelectorResult, err error) {
+func SetRuleSelector(params *SetRuleSelectorParams, conn hc.CommandRunner) (result *SetRuleSelectorResult, err error) {
 	cmd := NewSetRuleSelectorCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetRuleSelectorCB func(result *SetRuleSelectorResult, err error)
 
 // Modifies the rule selector.
-
 type AsyncSetRuleSelectorCommand struct {
 	params *SetRuleSelectorParams
 	cb     SetRuleSelectorCB
+	result *SetRuleSelectorResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetRuleSelectorCommand(params *SetRuleSelectorParams, cb SetRuleSelectorCB) *AsyncSetRuleSelectorCommand {
 	return &AsyncSetRuleSelectorCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1078,13 +1312,31 @@ func (cmd *AsyncSetRuleSelectorCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetRuleSelectorCommand) Wait(ctx context.Context) (*SetRuleSelectorResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetRuleSelectorCommand) Send(conn hc.CommandRunner) *AsyncSetRuleSelectorCommand {
+	async := NewAsyncSetRuleSelectorCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetKeyframeKeyParams struct {
@@ -1098,7 +1350,6 @@ type SetKeyframeKeyResult struct {
 }
 
 // Modifies the keyframe rule key text.
-
 type SetKeyframeKeyCommand struct {
 	params *SetKeyframeKeyParams
 	result SetKeyframeKeyResult
@@ -1120,32 +1371,40 @@ func (cmd *SetKeyframeKeyCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetKeyframeKeyCommand) Run(conn *hc.Conn) error {
+func (cmd *SetKeyframeKeyCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetKeyframeKey(params *SetKeyframeKeyParams, conn *hc.Conn) (result *SetKeyframeKeyResult, err error) {
+func SetKeyframeKey(params *SetKeyframeKeyParams, conn hc.CommandRunner) (result *SetKeyframeKeyResult, err error) {
 	cmd := NewSetKeyframeKeyCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetKeyframeKeyCB func(result *SetKeyframeKeyResult, err error)
 
 // Modifies the keyframe rule key text.
-
 type AsyncSetKeyframeKeyCommand struct {
 	params *SetKeyframeKeyParams
 	cb     SetKeyframeKeyCB
+	result *SetKeyframeKeyResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetKeyframeKeyCommand(params *SetKeyframeKeyParams, cb SetKeyframeKeyCB) *AsyncSetKeyframeKeyCommand {
 	return &AsyncSetKeyframeKeyCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1174,13 +1433,31 @@ func (cmd *AsyncSetKeyframeKeyCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetKeyframeKeyCommand) Wait(ctx context.Context) (*SetKeyframeKeyResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetKeyframeKeyCommand) Send(conn hc.CommandRunner) *AsyncSetKeyframeKeyCommand {
+	async := NewAsyncSetKeyframeKeyCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetStyleTextsParams struct {
@@ -1192,7 +1469,6 @@ type SetStyleTextsResult struct {
 }
 
 // Applies specified style edits one after another in the given order.
-
 type SetStyleTextsCommand struct {
 	params *SetStyleTextsParams
 	result SetStyleTextsResult
@@ -1214,32 +1490,40 @@ func (cmd *SetStyleTextsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetStyleTextsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetStyleTextsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetStyleTexts(params *SetStyleTextsParams, conn *hc.Conn) (result *SetStyleTextsResult, err error) {
+func SetStyleTexts(params *SetStyleTextsParams, conn hc.CommandRunner) (result *SetStyleTextsResult, err error) {
 	cmd := NewSetStyleTextsCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetStyleTextsCB func(result *SetStyleTextsResult, err error)
 
 // Applies specified style edits one after another in the given order.
-
 type AsyncSetStyleTextsCommand struct {
 	params *SetStyleTextsParams
 	cb     SetStyleTextsCB
+	result *SetStyleTextsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetStyleTextsCommand(params *SetStyleTextsParams, cb SetStyleTextsCB) *AsyncSetStyleTextsCommand {
 	return &AsyncSetStyleTextsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1268,15 +1552,33 @@ func (cmd *AsyncSetStyleTextsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetStyleTextsCommand) Wait(ctx context.Context) (*SetStyleTextsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetStyleTextsCommand) Send(conn hc.CommandRunner) *AsyncSetStyleTextsCommand {
+	async := NewAsyncSetStyleTextsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetMediaTextParams struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"`
 	Range        *SourceRange `json:"range"`
@@ -1288,7 +1590,6 @@ type SetMediaTextResult struct {
 }
 
 // Modifies the rule selector.
-
 type SetMediaTextCommand struct {
 	params *SetMediaTextParams
 	result SetMediaTextResult
@@ -1310,32 +1611,40 @@ func (cmd *SetMediaTextCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetMediaTextCommand) Run(conn *hc.Conn) error {
+func (cmd *SetMediaTextCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetMediaText(params *SetMediaTextParams, conn *hc.Conn) (result *SetMediaTextResult, err error) {
+func SetMediaText(params *SetMediaTextParams, conn hc.CommandRunner) (result *SetMediaTextResult, err error) {
 	cmd := NewSetMediaTextCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetMediaTextCB func(result *SetMediaTextResult, err error)
 
 // Modifies the rule selector.
-
 type AsyncSetMediaTextCommand struct {
 	params *SetMediaTextParams
 	cb     SetMediaTextCB
+	result *SetMediaTextResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetMediaTextCommand(params *SetMediaTextParams, cb SetMediaTextCB) *AsyncSetMediaTextCommand {
 	return &AsyncSetMediaTextCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1364,17 +1673,35 @@ func (cmd *AsyncSetMediaTextCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetMediaTextCommand) Wait(ctx context.Context) (*SetMediaTextResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetMediaTextCommand) Send(conn hc.CommandRunner) *AsyncSetMediaTextCommand {
+	async := NewAsyncSetMediaTextCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CreateStyleSheetParams struct {
-	FrameId *FrameId `json:"frameId"` // Identifier of the frame where "via-inspector" stylesheet should be created.
+	FrameId FrameId `json:"frameId"` // Identifier of the frame where "via-inspector" stylesheet should be created.
 }
 
 type CreateStyleSheetResult struct {
@@ -1382,7 +1709,6 @@ type CreateStyleSheetResult struct {
 }
 
 // Creates a new special "via-inspector" stylesheet in the frame with given frameId.
-
 type CreateStyleSheetCommand struct {
 	params *CreateStyleSheetParams
 	result CreateStyleSheetResult
@@ -1404,32 +1730,40 @@ func (cmd *CreateStyleSheetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CreateStyleSheetCommand) Run(conn *hc.Conn) error {
+func (cmd *CreateStyleSheetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CreateStyleSheet(params *CreateStyleSheetParams, conn *hc.Conn) (result *CreateStyleSheetResult, err error) {
+func CreateStyleSheet(params *CreateStyleSheetParams, conn hc.CommandRunner) (result *CreateStyleSheetResult, err error) {
 	cmd := NewCreateStyleSheetCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CreateStyleSheetCB func(result *CreateStyleSheetResult, err error)
 
 // Creates a new special "via-inspector" stylesheet in the frame with given frameId.
-
 type AsyncCreateStyleSheetCommand struct {
 	params *CreateStyleSheetParams
 	cb     CreateStyleSheetCB
+	result *CreateStyleSheetResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCreateStyleSheetCommand(params *CreateStyleSheetParams, cb CreateStyleSheetCB) *AsyncCreateStyleSheetCommand {
 	return &AsyncCreateStyleSheetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1458,15 +1792,33 @@ func (cmd *AsyncCreateStyleSheetCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCreateStyleSheetCommand) Wait(ctx context.Context) (*CreateStyleSheetResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CreateStyleSheetCommand) Send(conn hc.CommandRunner) *AsyncCreateStyleSheetCommand {
+	async := NewAsyncCreateStyleSheetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AddRuleParams struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"` // The css style sheet identifier where a new rule should be inserted.
 	RuleText     string       `json:"ruleText"`     // The text of a new rule.
@@ -1478,7 +1830,6 @@ type AddRuleResult struct {
 }
 
 // Inserts a new rule with the given ruleText in a stylesheet with given styleSheetId, at the position specified by location.
-
 type AddRuleCommand struct {
 	params *AddRuleParams
 	result AddRuleResult
@@ -1500,32 +1851,40 @@ func (cmd *AddRuleCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *AddRuleCommand) Run(conn *hc.Conn) error {
+func (cmd *AddRuleCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AddRule(params *AddRuleParams, conn *hc.Conn) (result *AddRuleResult, err error) {
+func AddRule(params *AddRuleParams, conn hc.CommandRunner) (result *AddRuleResult, err error) {
 	cmd := NewAddRuleCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type AddRuleCB func(result *AddRuleResult, err error)
 
 // Inserts a new rule with the given ruleText in a stylesheet with given styleSheetId, at the position specified by location.
-
 type AsyncAddRuleCommand struct {
 	params *AddRuleParams
 	cb     AddRuleCB
+	result *AddRuleResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAddRuleCommand(params *AddRuleParams, cb AddRuleCB) *AsyncAddRuleCommand {
 	return &AsyncAddRuleCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1554,22 +1913,39 @@ func (cmd *AsyncAddRuleCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAddRuleCommand) Wait(ctx context.Context) (*AddRuleResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AddRuleCommand) Send(conn hc.CommandRunner) *AsyncAddRuleCommand {
+	async := NewAsyncAddRuleCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ForcePseudoStateParams struct {
-	NodeId              *NodeId  `json:"nodeId"`              // The element id for which to force the pseudo state.
+	NodeId              NodeId   `json:"nodeId"`              // The element id for which to force the pseudo state.
 	ForcedPseudoClasses []string `json:"forcedPseudoClasses"` // Element pseudo classes to force when computing the element's style.
 }
 
 // Ensures that the given node will have specified pseudo-classes whenever its style is computed by the browser.
-
 type ForcePseudoStateCommand struct {
 	params *ForcePseudoStateParams
 	wg     sync.WaitGroup
@@ -1590,32 +1966,36 @@ func (cmd *ForcePseudoStateCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ForcePseudoStateCommand) Run(conn *hc.Conn) error {
+func (cmd *ForcePseudoStateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ForcePseudoState(params *ForcePseudoStateParams, conn *hc.Conn) (err error) {
+func ForcePseudoState(params *ForcePseudoStateParams, conn hc.CommandRunner) (err error) {
 	cmd := NewForcePseudoStateCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ForcePseudoStateCB func(err error)
 
 // Ensures that the given node will have specified pseudo-classes whenever its style is computed by the browser.
-
 type AsyncForcePseudoStateCommand struct {
 	params *ForcePseudoStateParams
 	cb     ForcePseudoStateCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncForcePseudoStateCommand(params *ForcePseudoStateParams, cb ForcePseudoStateCB) *AsyncForcePseudoStateCommand {
 	return &AsyncForcePseudoStateCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1633,7 +2013,28 @@ func (cmd *ForcePseudoStateCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncForcePseudoStateCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncForcePseudoStateCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ForcePseudoStateCommand) Send(conn hc.CommandRunner) *AsyncForcePseudoStateCommand {
+	async := NewAsyncForcePseudoStateCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetMediaQueriesResult struct {
@@ -1660,17 +2061,21 @@ func (cmd *GetMediaQueriesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetMediaQueriesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetMediaQueriesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetMediaQueries(conn *hc.Conn) (result *GetMediaQueriesResult, err error) {
+func GetMediaQueries(conn hc.CommandRunner) (result *GetMediaQueriesResult, err error) {
 	cmd := NewGetMediaQueriesCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetMediaQueriesCB func(result *GetMediaQueriesResult, err error)
@@ -1678,12 +2083,17 @@ type GetMediaQueriesCB func(result *GetMediaQueriesResult, err error)
 // Returns all media queries parsed by the rendering engine.
 // @experimental
 type AsyncGetMediaQueriesCommand struct {
-	cb GetMediaQueriesCB
+	cb     GetMediaQueriesCB
+	result *GetMediaQueriesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetMediaQueriesCommand(cb GetMediaQueriesCB) *AsyncGetMediaQueriesCommand {
 	return &AsyncGetMediaQueriesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1712,19 +2122,37 @@ func (cmd *AsyncGetMediaQueriesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetMediaQueriesCommand) Wait(ctx context.Context) (*GetMediaQueriesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetMediaQueriesCommand) Send(conn hc.CommandRunner) *AsyncGetMediaQueriesCommand {
+	async := NewAsyncGetMediaQueriesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetEffectivePropertyValueForNodeParams struct {
-	NodeId       *NodeId `json:"nodeId"` // The element id for which to set property.
-	PropertyName string  `json:"propertyName"`
-	Value        string  `json:"value"`
+	NodeId       NodeId `json:"nodeId"` // The element id for which to set property.
+	PropertyName string `json:"propertyName"`
+	Value        string `json:"value"`
 }
 
 // Find a rule with the given active property for the given node and set the new value for this property
@@ -1749,17 +2177,18 @@ func (cmd *SetEffectivePropertyValueForNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetEffectivePropertyValueForNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *SetEffectivePropertyValueForNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetEffectivePropertyValueForNode(params *SetEffectivePropertyValueForNodeParams, conn *hc.Conn) (err error) {
+func SetEffectivePropertyValueForNode(params *SetEffectivePropertyValueForNodeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetEffectivePropertyValueForNodeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetEffectivePropertyValueForNodeCB func(err error)
@@ -1769,12 +2198,16 @@ type SetEffectivePropertyValueForNodeCB func(err error)
 type AsyncSetEffectivePropertyValueForNodeCommand struct {
 	params *SetEffectivePropertyValueForNodeParams
 	cb     SetEffectivePropertyValueForNodeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetEffectivePropertyValueForNodeCommand(params *SetEffectivePropertyValueForNodeParams, cb SetEffectivePropertyValueForNodeCB) *AsyncSetEffectivePropertyValueForNodeCommand {
 	return &AsyncSetEffectivePropertyValueForNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1792,11 +2225,32 @@ func (cmd *SetEffectivePropertyValueForNodeCommand) Done(data []byte, err error)
 }
 
 func (cmd *AsyncSetEffectivePropertyValueForNodeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetEffectivePropertyValueForNodeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetEffectivePropertyValueForNodeCommand) Send(conn hc.CommandRunner) *AsyncSetEffectivePropertyValueForNodeCommand {
+	async := NewAsyncSetEffectivePropertyValueForNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetBackgroundColorsParams struct {
-	NodeId *NodeId `json:"nodeId"` // Id of the node to get background colors for.
+	NodeId NodeId `json:"nodeId"` // Id of the node to get background colors for.
 }
 
 type GetBackgroundColorsResult struct {
@@ -1825,17 +2279,21 @@ func (cmd *GetBackgroundColorsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetBackgroundColorsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetBackgroundColorsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetBackgroundColors(params *GetBackgroundColorsParams, conn *hc.Conn) (result *GetBackgroundColorsResult, err error) {
+func GetBackgroundColors(params *GetBackgroundColorsParams, conn hc.CommandRunner) (result *GetBackgroundColorsResult, err error) {
 	cmd := NewGetBackgroundColorsCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetBackgroundColorsCB func(result *GetBackgroundColorsResult, err error)
@@ -1844,12 +2302,17 @@ type GetBackgroundColorsCB func(result *GetBackgroundColorsResult, err error)
 type AsyncGetBackgroundColorsCommand struct {
 	params *GetBackgroundColorsParams
 	cb     GetBackgroundColorsCB
+	result *GetBackgroundColorsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetBackgroundColorsCommand(params *GetBackgroundColorsParams, cb GetBackgroundColorsCB) *AsyncGetBackgroundColorsCommand {
 	return &AsyncGetBackgroundColorsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1878,13 +2341,31 @@ func (cmd *AsyncGetBackgroundColorsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetBackgroundColorsCommand) Wait(ctx context.Context) (*GetBackgroundColorsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetBackgroundColorsCommand) Send(conn hc.CommandRunner) *AsyncGetBackgroundColorsCommand {
+	async := NewAsyncGetBackgroundColorsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetLayoutTreeAndStylesParams struct {
@@ -1919,17 +2400,21 @@ func (cmd *GetLayoutTreeAndStylesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetLayoutTreeAndStylesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetLayoutTreeAndStylesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetLayoutTreeAndStyles(params *GetLayoutTreeAndStylesParams, conn *hc.Conn) (result *GetLayoutTreeAndStylesResult, err error) {
+func GetLayoutTreeAndStyles(params *GetLayoutTreeAndStylesParams, conn hc.CommandRunner) (result *GetLayoutTreeAndStylesResult, err error) {
 	cmd := NewGetLayoutTreeAndStylesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetLayoutTreeAndStylesCB func(result *GetLayoutTreeAndStylesResult, err error)
@@ -1939,12 +2424,17 @@ type GetLayoutTreeAndStylesCB func(result *GetLayoutTreeAndStylesResult, err err
 type AsyncGetLayoutTreeAndStylesCommand struct {
 	params *GetLayoutTreeAndStylesParams
 	cb     GetLayoutTreeAndStylesCB
+	result *GetLayoutTreeAndStylesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetLayoutTreeAndStylesCommand(params *GetLayoutTreeAndStylesParams, cb GetLayoutTreeAndStylesCB) *AsyncGetLayoutTreeAndStylesCommand {
 	return &AsyncGetLayoutTreeAndStylesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1973,15 +2463,33 @@ func (cmd *AsyncGetLayoutTreeAndStylesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetLayoutTreeAndStylesCommand) Wait(ctx context.Context) (*GetLayoutTreeAndStylesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetLayoutTreeAndStylesCommand) Send(conn hc.CommandRunner) *AsyncGetLayoutTreeAndStylesCommand {
+	async := NewAsyncGetLayoutTreeAndStylesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 // Enables the selector recording.
 // @experimental
 type StartRuleUsageTrackingCommand struct {
@@ -2001,17 +2509,18 @@ func (cmd *StartRuleUsageTrackingCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StartRuleUsageTrackingCommand) Run(conn *hc.Conn) error {
+func (cmd *StartRuleUsageTrackingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StartRuleUsageTracking(conn *hc.Conn) (err error) {
+func StartRuleUsageTracking(conn hc.CommandRunner) (err error) {
 	cmd := NewStartRuleUsageTrackingCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StartRuleUsageTrackingCB func(err error)
@@ -2019,12 +2528,16 @@ type StartRuleUsageTrackingCB func(err error)
 // Enables the selector recording.
 // @experimental
 type AsyncStartRuleUsageTrackingCommand struct {
-	cb StartRuleUsageTrackingCB
+	cb   StartRuleUsageTrackingCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStartRuleUsageTrackingCommand(cb StartRuleUsageTrackingCB) *AsyncStartRuleUsageTrackingCommand {
 	return &AsyncStartRuleUsageTrackingCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2042,7 +2555,28 @@ func (cmd *StartRuleUsageTrackingCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStartRuleUsageTrackingCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStartRuleUsageTrackingCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StartRuleUsageTrackingCommand) Send(conn hc.CommandRunner) *AsyncStartRuleUsageTrackingCommand {
+	async := NewAsyncStartRuleUsageTrackingCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StopRuleUsageTrackingResult struct {
@@ -2069,17 +2603,21 @@ func (cmd *StopRuleUsageTrackingCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StopRuleUsageTrackingCommand) Run(conn *hc.Conn) error {
+func (cmd *StopRuleUsageTrackingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopRuleUsageTracking(conn *hc.Conn) (result *StopRuleUsageTrackingResult, err error) {
+func StopRuleUsageTracking(conn hc.CommandRunner) (result *StopRuleUsageTrackingResult, err error) {
 	cmd := NewStopRuleUsageTrackingCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type StopRuleUsageTrackingCB func(result *StopRuleUsageTrackingResult, err error)
@@ -2087,12 +2625,17 @@ type StopRuleUsageTrackingCB func(result *StopRuleUsageTrackingResult, err error
 // The list of rules with an indication of whether these were used
 // @experimental
 type AsyncStopRuleUsageTrackingCommand struct {
-	cb StopRuleUsageTrackingCB
+	cb     StopRuleUsageTrackingCB
+	result *StopRuleUsageTrackingResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopRuleUsageTrackingCommand(cb StopRuleUsageTrackingCB) *AsyncStopRuleUsageTrackingCommand {
 	return &AsyncStopRuleUsageTrackingCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2121,99 +2664,192 @@ func (cmd *AsyncStopRuleUsageTrackingCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Fires whenever a MediaQuery result changes (for example, after a browser window has been resized.) The current implementation considers only viewport-dependent media features.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopRuleUsageTrackingCommand) Wait(ctx context.Context) (*StopRuleUsageTrackingResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopRuleUsageTrackingCommand) Send(conn hc.CommandRunner) *AsyncStopRuleUsageTrackingCommand {
+	async := NewAsyncStopRuleUsageTrackingCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Fires whenever a MediaQuery result changes (for example, after a browser window has been resized.) The current implementation considers only viewport-dependent media features.
 type MediaQueryResultChangedEvent struct {
 }
 
-func OnMediaQueryResultChanged(conn *hc.Conn, cb func(evt *MediaQueryResultChangedEvent)) {
+// Calls cb for each CSS.mediaQueryResultChanged event, till the returned func is called.
+func OnMediaQueryResultChanged(conn hc.EventSource, cb func(evt *MediaQueryResultChangedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &MediaQueryResultChangedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("CSS.mediaQueryResultChanged", sink)
+	return func() { conn.RemoveEventSink("CSS.mediaQueryResultChanged", sink) }
 }
 
-// Fires whenever a web font gets loaded.
+// Like OnMediaQueryResultChanged, but cb also gets hc.EventMeta.
+func OnMediaQueryResultChangedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *MediaQueryResultChangedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &MediaQueryResultChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("CSS.mediaQueryResultChanged", sink)
+	return func() { conn.RemoveEventSink("CSS.mediaQueryResultChanged", sink) }
+}
 
+// Fires whenever a web font gets loaded.
 type FontsUpdatedEvent struct {
 }
 
-func OnFontsUpdated(conn *hc.Conn, cb func(evt *FontsUpdatedEvent)) {
+// Calls cb for each CSS.fontsUpdated event, till the returned func is called.
+func OnFontsUpdated(conn hc.EventSource, cb func(evt *FontsUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FontsUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("CSS.fontsUpdated", sink)
+	return func() { conn.RemoveEventSink("CSS.fontsUpdated", sink) }
 }
 
-// Fired whenever a stylesheet is changed as a result of the client operation.
+// Like OnFontsUpdated, but cb also gets hc.EventMeta.
+func OnFontsUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FontsUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FontsUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("CSS.fontsUpdated", sink)
+	return func() { conn.RemoveEventSink("CSS.fontsUpdated", sink) }
+}
 
+// Fired whenever a stylesheet is changed as a result of the client operation.
 type StyleSheetChangedEvent struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"`
 }
 
-func OnStyleSheetChanged(conn *hc.Conn, cb func(evt *StyleSheetChangedEvent)) {
+// Calls cb for each CSS.styleSheetChanged event, till the returned func is called.
+func OnStyleSheetChanged(conn hc.EventSource, cb func(evt *StyleSheetChangedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &StyleSheetChangedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("CSS.styleSheetChanged", sink)
+	return func() { conn.RemoveEventSink("CSS.styleSheetChanged", sink) }
 }
 
-// Fired whenever an active document stylesheet is added.
+// Like OnStyleSheetChanged, but cb also gets hc.EventMeta.
+func OnStyleSheetChangedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *StyleSheetChangedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &StyleSheetChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("CSS.styleSheetChanged", sink)
+	return func() { conn.RemoveEventSink("CSS.styleSheetChanged", sink) }
+}
 
+// Fired whenever an active document stylesheet is added.
 type StyleSheetAddedEvent struct {
 	Header *CSSStyleSheetHeader `json:"header"` // Added stylesheet metainfo.
 }
 
-func OnStyleSheetAdded(conn *hc.Conn, cb func(evt *StyleSheetAddedEvent)) {
+// Calls cb for each CSS.styleSheetAdded event, till the returned func is called.
+func OnStyleSheetAdded(conn hc.EventSource, cb func(evt *StyleSheetAddedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &StyleSheetAddedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("CSS.styleSheetAdded", sink)
+	return func() { conn.RemoveEventSink("CSS.styleSheetAdded", sink) }
 }
 
-// Fired whenever an active document stylesheet is removed.
+// Like OnStyleSheetAdded, but cb also gets hc.EventMeta.
+func OnStyleSheetAddedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *StyleSheetAddedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &StyleSheetAddedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("CSS.styleSheetAdded", sink)
+	return func() { conn.RemoveEventSink("CSS.styleSheetAdded", sink) }
+}
 
+// Fired whenever an active document stylesheet is removed.
 type StyleSheetRemovedEvent struct {
 	StyleSheetId StyleSheetId `json:"styleSheetId"` // Identifier of the removed stylesheet.
 }
 
-func OnStyleSheetRemoved(conn *hc.Conn, cb func(evt *StyleSheetRemovedEvent)) {
+// Calls cb for each CSS.styleSheetRemoved event, till the returned func is called.
+func OnStyleSheetRemoved(conn hc.EventSource, cb func(evt *StyleSheetRemovedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &StyleSheetRemovedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("CSS.styleSheetRemoved", sink)
+	return func() { conn.RemoveEventSink("CSS.styleSheetRemoved", sink) }
+}
+
+// Like OnStyleSheetRemoved, but cb also gets hc.EventMeta.
+func OnStyleSheetRemovedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *StyleSheetRemovedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &StyleSheetRemovedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("CSS.styleSheetRemoved", sink)
+	return func() { conn.RemoveEventSink("CSS.styleSheetRemoved", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -27,7 +27,6 @@ type Error struct {
 }
 
 // Enables database tracking, database events will now be delivered to the client.
-
 type DatabaseEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -45,30 +44,34 @@ func (cmd *DatabaseEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DatabaseEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *DatabaseEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DatabaseEnable(conn *hc.Conn) (err error) {
+func DatabaseEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewDatabaseEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DatabaseEnableCB func(err error)
 
 // Enables database tracking, database events will now be delivered to the client.
-
 type AsyncDatabaseEnableCommand struct {
-	cb DatabaseEnableCB
+	cb   DatabaseEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDatabaseEnableCommand(cb DatabaseEnableCB) *AsyncDatabaseEnableCommand {
 	return &AsyncDatabaseEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -86,11 +89,31 @@ func (cmd *DatabaseEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDatabaseEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables database tracking, prevents database events from being sent to the client.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDatabaseEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DatabaseEnableCommand) Send(conn hc.CommandRunner) *AsyncDatabaseEnableCommand {
+	async := NewAsyncDatabaseEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables database tracking, prevents database events from being sent to the client.
 type DatabaseDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -108,30 +131,34 @@ func (cmd *DatabaseDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DatabaseDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *DatabaseDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DatabaseDisable(conn *hc.Conn) (err error) {
+func DatabaseDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewDatabaseDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DatabaseDisableCB func(err error)
 
 // Disables database tracking, prevents database events from being sent to the client.
-
 type AsyncDatabaseDisableCommand struct {
-	cb DatabaseDisableCB
+	cb   DatabaseDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDatabaseDisableCommand(cb DatabaseDisableCB) *AsyncDatabaseDisableCommand {
 	return &AsyncDatabaseDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -149,7 +176,28 @@ func (cmd *DatabaseDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDatabaseDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDatabaseDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DatabaseDisableCommand) Send(conn hc.CommandRunner) *AsyncDatabaseDisableCommand {
+	async := NewAsyncDatabaseDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetDatabaseTableNamesParams struct {
@@ -181,17 +229,21 @@ func (cmd *GetDatabaseTableNamesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetDatabaseTableNamesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetDatabaseTableNamesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetDatabaseTableNames(params *GetDatabaseTableNamesParams, conn *hc.Conn) (result *GetDatabaseTableNamesResult, err error) {
+func GetDatabaseTableNames(params *GetDatabaseTableNamesParams, conn hc.CommandRunner) (result *GetDatabaseTableNamesResult, err error) {
 	cmd := NewGetDatabaseTableNamesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetDatabaseTableNamesCB func(result *GetDatabaseTableNamesResult, err error)
@@ -199,12 +251,17 @@ type GetDatabaseTableNamesCB func(result *GetDatabaseTableNamesResult, err error
 type AsyncGetDatabaseTableNamesCommand struct {
 	params *GetDatabaseTableNamesParams
 	cb     GetDatabaseTableNamesCB
+	result *GetDatabaseTableNamesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetDatabaseTableNamesCommand(params *GetDatabaseTableNamesParams, cb GetDatabaseTableNamesCB) *AsyncGetDatabaseTableNamesCommand {
 	return &AsyncGetDatabaseTableNamesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -233,15 +290,33 @@ func (cmd *AsyncGetDatabaseTableNamesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetDatabaseTableNamesCommand) Wait(ctx context.Context) (*GetDatabaseTableNamesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetDatabaseTableNamesCommand) Send(conn hc.CommandRunner) *AsyncGetDatabaseTableNamesCommand {
+	async := NewAsyncGetDatabaseTableNamesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ExecuteSQLParams struct {
 	DatabaseId DatabaseId `json:"databaseId"`
 	Query      string     `json:"query"`
@@ -274,17 +349,21 @@ func (cmd *ExecuteSQLCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ExecuteSQLCommand) Run(conn *hc.Conn) error {
+func (cmd *ExecuteSQLCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ExecuteSQL(params *ExecuteSQLParams, conn *hc.Conn) (result *ExecuteSQLResult, err error) {
+func ExecuteSQL(params *ExecuteSQLParams, conn hc.CommandRunner) (result *ExecuteSQLResult, err error) {
 	cmd := NewExecuteSQLCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type ExecuteSQLCB func(result *ExecuteSQLResult, err error)
@@ -292,12 +371,17 @@ type ExecuteSQLCB func(result *ExecuteSQLResult, err error)
 type AsyncExecuteSQLCommand struct {
 	params *ExecuteSQLParams
 	cb     ExecuteSQLCB
+	result *ExecuteSQLResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncExecuteSQLCommand(params *ExecuteSQLParams, cb ExecuteSQLCB) *AsyncExecuteSQLCommand {
 	return &AsyncExecuteSQLCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -326,27 +410,61 @@ func (cmd *AsyncExecuteSQLCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncExecuteSQLCommand) Wait(ctx context.Context) (*ExecuteSQLResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ExecuteSQLCommand) Send(conn hc.CommandRunner) *AsyncExecuteSQLCommand {
+	async := NewAsyncExecuteSQLCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AddDatabaseEvent struct {
 	Database *Database `json:"database"`
 }
 
-func OnAddDatabase(conn *hc.Conn, cb func(evt *AddDatabaseEvent)) {
+// Calls cb for each Database.addDatabase event, till the returned func is called.
+func OnAddDatabase(conn hc.EventSource, cb func(evt *AddDatabaseEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AddDatabaseEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Database.addDatabase", sink)
+	return func() { conn.RemoveEventSink("Database.addDatabase", sink) }
+}
+
+// Like OnAddDatabase, but cb also gets hc.EventMeta.
+func OnAddDatabaseMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AddDatabaseEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AddDatabaseEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Database.addDatabase", sink)
+	return func() { conn.RemoveEventSink("Database.addDatabase", sink) }
 }
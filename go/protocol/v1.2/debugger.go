@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -15,9 +15,9 @@ type CallFrameId string
 
 // Location in the source code.
 type Location struct {
-	ScriptId     *ScriptId `json:"scriptId"`               // Script identifier as reported in the Debugger.scriptParsed.
-	LineNumber   int       `json:"lineNumber"`             // Line number in the script (0-based).
-	ColumnNumber int       `json:"columnNumber,omitempty"` // Column number in the script (0-based).
+	ScriptId     ScriptId `json:"scriptId"`               // Script identifier as reported in the Debugger.scriptParsed.
+	LineNumber   int      `json:"lineNumber"`             // Line number in the script (0-based).
+	ColumnNumber int      `json:"columnNumber,omitempty"` // Column number in the script (0-based).
 }
 
 // Location in the source code.
@@ -55,7 +55,6 @@ type SearchMatch struct {
 }
 
 // Enables debugger for the given page. Clients should not assume that the debugging has been enabled until the result for this command is received.
-
 type DebuggerEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -73,30 +72,34 @@ func (cmd *DebuggerEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DebuggerEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *DebuggerEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DebuggerEnable(conn *hc.Conn) (err error) {
+func DebuggerEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewDebuggerEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DebuggerEnableCB func(err error)
 
 // Enables debugger for the given page. Clients should not assume that the debugging has been enabled until the result for this command is received.
-
 type AsyncDebuggerEnableCommand struct {
-	cb DebuggerEnableCB
+	cb   DebuggerEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDebuggerEnableCommand(cb DebuggerEnableCB) *AsyncDebuggerEnableCommand {
 	return &AsyncDebuggerEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -114,11 +117,31 @@ func (cmd *DebuggerEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDebuggerEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables debugger for given page.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDebuggerEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DebuggerEnableCommand) Send(conn hc.CommandRunner) *AsyncDebuggerEnableCommand {
+	async := NewAsyncDebuggerEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables debugger for given page.
 type DebuggerDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -136,30 +159,34 @@ func (cmd *DebuggerDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DebuggerDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *DebuggerDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DebuggerDisable(conn *hc.Conn) (err error) {
+func DebuggerDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewDebuggerDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DebuggerDisableCB func(err error)
 
 // Disables debugger for given page.
-
 type AsyncDebuggerDisableCommand struct {
-	cb DebuggerDisableCB
+	cb   DebuggerDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDebuggerDisableCommand(cb DebuggerDisableCB) *AsyncDebuggerDisableCommand {
 	return &AsyncDebuggerDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -177,7 +204,28 @@ func (cmd *DebuggerDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDebuggerDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDebuggerDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DebuggerDisableCommand) Send(conn hc.CommandRunner) *AsyncDebuggerDisableCommand {
+	async := NewAsyncDebuggerDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetBreakpointsActiveParams struct {
@@ -185,7 +233,6 @@ type SetBreakpointsActiveParams struct {
 }
 
 // Activates / deactivates all breakpoints on the page.
-
 type SetBreakpointsActiveCommand struct {
 	params *SetBreakpointsActiveParams
 	wg     sync.WaitGroup
@@ -206,32 +253,36 @@ func (cmd *SetBreakpointsActiveCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetBreakpointsActiveCommand) Run(conn *hc.Conn) error {
+func (cmd *SetBreakpointsActiveCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetBreakpointsActive(params *SetBreakpointsActiveParams, conn *hc.Conn) (err error) {
+func SetBreakpointsActive(params *SetBreakpointsActiveParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetBreakpointsActiveCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetBreakpointsActiveCB func(err error)
 
 // Activates / deactivates all breakpoints on the page.
-
 type AsyncSetBreakpointsActiveCommand struct {
 	params *SetBreakpointsActiveParams
 	cb     SetBreakpointsActiveCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetBreakpointsActiveCommand(params *SetBreakpointsActiveParams, cb SetBreakpointsActiveCB) *AsyncSetBreakpointsActiveCommand {
 	return &AsyncSetBreakpointsActiveCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -249,7 +300,28 @@ func (cmd *SetBreakpointsActiveCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetBreakpointsActiveCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetBreakpointsActiveCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetBreakpointsActiveCommand) Send(conn hc.CommandRunner) *AsyncSetBreakpointsActiveCommand {
+	async := NewAsyncSetBreakpointsActiveCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetSkipAllPausesParams struct {
@@ -257,7 +329,6 @@ type SetSkipAllPausesParams struct {
 }
 
 // Makes page not interrupt on any pauses (breakpoint, exception, dom exception etc).
-
 type SetSkipAllPausesCommand struct {
 	params *SetSkipAllPausesParams
 	wg     sync.WaitGroup
@@ -278,32 +349,36 @@ func (cmd *SetSkipAllPausesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetSkipAllPausesCommand) Run(conn *hc.Conn) error {
+func (cmd *SetSkipAllPausesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetSkipAllPauses(params *SetSkipAllPausesParams, conn *hc.Conn) (err error) {
+func SetSkipAllPauses(params *SetSkipAllPausesParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetSkipAllPausesCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetSkipAllPausesCB func(err error)
 
 // Makes page not interrupt on any pauses (breakpoint, exception, dom exception etc).
-
 type AsyncSetSkipAllPausesCommand struct {
 	params *SetSkipAllPausesParams
 	cb     SetSkipAllPausesCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetSkipAllPausesCommand(params *SetSkipAllPausesParams, cb SetSkipAllPausesCB) *AsyncSetSkipAllPausesCommand {
 	return &AsyncSetSkipAllPausesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -321,7 +396,28 @@ func (cmd *SetSkipAllPausesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetSkipAllPausesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetSkipAllPausesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetSkipAllPausesCommand) Send(conn hc.CommandRunner) *AsyncSetSkipAllPausesCommand {
+	async := NewAsyncSetSkipAllPausesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetBreakpointByUrlParams struct {
@@ -338,7 +434,6 @@ type SetBreakpointByUrlResult struct {
 }
 
 // Sets JavaScript breakpoint at given location specified either by URL or URL regex. Once this command is issued, all existing parsed scripts will have breakpoints resolved and returned in locations property. Further matching script parsing will result in subsequent breakpointResolved events issued. This logical breakpoint will survive page reloads.
-
 type SetBreakpointByUrlCommand struct {
 	params *SetBreakpointByUrlParams
 	result SetBreakpointByUrlResult
@@ -360,32 +455,40 @@ func (cmd *SetBreakpointByUrlCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetBreakpointByUrlCommand) Run(conn *hc.Conn) error {
+func (cmd *SetBreakpointByUrlCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetBreakpointByUrl(params *SetBreakpointByUrlParams, conn *hc.Conn) (result *SetBreakpointByUrlResult, err error) {
+func SetBreakpointByUrl(params *SetBreakpointByUrlParams, conn hc.CommandRunner) (result *SetBreakpointByUrlResult, err error) {
 	cmd := NewSetBreakpointByUrlCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetBreakpointByUrlCB func(result *SetBreakpointByUrlResult, err error)
 
 // Sets JavaScript breakpoint at given location specified either by URL or URL regex. Once this command is issued, all existing parsed scripts will have breakpoints resolved and returned in locations property. Further matching script parsing will result in subsequent breakpointResolved events issued. This logical breakpoint will survive page reloads.
-
 type AsyncSetBreakpointByUrlCommand struct {
 	params *SetBreakpointByUrlParams
 	cb     SetBreakpointByUrlCB
+	result *SetBreakpointByUrlResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetBreakpointByUrlCommand(params *SetBreakpointByUrlParams, cb SetBreakpointByUrlCB) *AsyncSetBreakpointByUrlCommand {
 	return &AsyncSetBreakpointByUrlCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -414,15 +517,33 @@ func (cmd *AsyncSetBreakpointByUrlCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetBreakpointByUrlCommand) Wait(ctx context.Context) (*SetBreakpointByUrlResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetBreakpointByUrlCommand) Send(conn hc.CommandRunner) *AsyncSetBreakpointByUrlCommand {
+	async := NewAsyncSetBreakpointByUrlCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetBreakpointParams struct {
 	Location  *Location `json:"location"`            // Location to set breakpoint in.
 	Condition string    `json:"condition,omitempty"` // Expression to use as a breakpoint condition. When specified, debugger will only stop on the breakpoint if this expression evaluates to true.
@@ -434,7 +555,6 @@ type SetBreakpointResult struct {
 }
 
 // Sets JavaScript breakpoint at a given location.
-
 type SetBreakpointCommand struct {
 	params *SetBreakpointParams
 	result SetBreakpointResult
@@ -456,32 +576,40 @@ func (cmd *SetBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *SetBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetBreakpoint(params *SetBreakpointParams, conn *hc.Conn) (result *SetBreakpointResult, err error) {
+func SetBreakpoint(params *SetBreakpointParams, conn hc.CommandRunner) (result *SetBreakpointResult, err error) {
 	cmd := NewSetBreakpointCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetBreakpointCB func(result *SetBreakpointResult, err error)
 
 // Sets JavaScript breakpoint at a given location.
-
 type AsyncSetBreakpointCommand struct {
 	params *SetBreakpointParams
 	cb     SetBreakpointCB
+	result *SetBreakpointResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetBreakpointCommand(params *SetBreakpointParams, cb SetBreakpointCB) *AsyncSetBreakpointCommand {
 	return &AsyncSetBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -510,21 +638,38 @@ func (cmd *AsyncSetBreakpointCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetBreakpointCommand) Wait(ctx context.Context) (*SetBreakpointResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetBreakpointCommand) Send(conn hc.CommandRunner) *AsyncSetBreakpointCommand {
+	async := NewAsyncSetBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RemoveBreakpointParams struct {
 	BreakpointId BreakpointId `json:"breakpointId"`
 }
 
 // Removes JavaScript breakpoint.
-
 type RemoveBreakpointCommand struct {
 	params *RemoveBreakpointParams
 	wg     sync.WaitGroup
@@ -545,32 +690,36 @@ func (cmd *RemoveBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveBreakpoint(params *RemoveBreakpointParams, conn *hc.Conn) (err error) {
+func RemoveBreakpoint(params *RemoveBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveBreakpointCB func(err error)
 
 // Removes JavaScript breakpoint.
-
 type AsyncRemoveBreakpointCommand struct {
 	params *RemoveBreakpointParams
 	cb     RemoveBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveBreakpointCommand(params *RemoveBreakpointParams, cb RemoveBreakpointCB) *AsyncRemoveBreakpointCommand {
 	return &AsyncRemoveBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -588,7 +737,28 @@ func (cmd *RemoveBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveBreakpointCommand) Send(conn hc.CommandRunner) *AsyncRemoveBreakpointCommand {
+	async := NewAsyncRemoveBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetPossibleBreakpointsParams struct {
@@ -623,17 +793,21 @@ func (cmd *GetPossibleBreakpointsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetPossibleBreakpointsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetPossibleBreakpointsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetPossibleBreakpoints(params *GetPossibleBreakpointsParams, conn *hc.Conn) (result *GetPossibleBreakpointsResult, err error) {
+func GetPossibleBreakpoints(params *GetPossibleBreakpointsParams, conn hc.CommandRunner) (result *GetPossibleBreakpointsResult, err error) {
 	cmd := NewGetPossibleBreakpointsCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetPossibleBreakpointsCB func(result *GetPossibleBreakpointsResult, err error)
@@ -643,12 +817,17 @@ type GetPossibleBreakpointsCB func(result *GetPossibleBreakpointsResult, err err
 type AsyncGetPossibleBreakpointsCommand struct {
 	params *GetPossibleBreakpointsParams
 	cb     GetPossibleBreakpointsCB
+	result *GetPossibleBreakpointsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetPossibleBreakpointsCommand(params *GetPossibleBreakpointsParams, cb GetPossibleBreakpointsCB) *AsyncGetPossibleBreakpointsCommand {
 	return &AsyncGetPossibleBreakpointsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -677,21 +856,38 @@ func (cmd *AsyncGetPossibleBreakpointsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetPossibleBreakpointsCommand) Wait(ctx context.Context) (*GetPossibleBreakpointsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetPossibleBreakpointsCommand) Send(conn hc.CommandRunner) *AsyncGetPossibleBreakpointsCommand {
+	async := NewAsyncGetPossibleBreakpointsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ContinueToLocationParams struct {
 	Location *Location `json:"location"` // Location to continue to.
 }
 
 // Continues execution until specific location is reached.
-
 type ContinueToLocationCommand struct {
 	params *ContinueToLocationParams
 	wg     sync.WaitGroup
@@ -712,32 +908,36 @@ func (cmd *ContinueToLocationCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ContinueToLocationCommand) Run(conn *hc.Conn) error {
+func (cmd *ContinueToLocationCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ContinueToLocation(params *ContinueToLocationParams, conn *hc.Conn) (err error) {
+func ContinueToLocation(params *ContinueToLocationParams, conn hc.CommandRunner) (err error) {
 	cmd := NewContinueToLocationCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ContinueToLocationCB func(err error)
 
 // Continues execution until specific location is reached.
-
 type AsyncContinueToLocationCommand struct {
 	params *ContinueToLocationParams
 	cb     ContinueToLocationCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncContinueToLocationCommand(params *ContinueToLocationParams, cb ContinueToLocationCB) *AsyncContinueToLocationCommand {
 	return &AsyncContinueToLocationCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -755,11 +955,31 @@ func (cmd *ContinueToLocationCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncContinueToLocationCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Steps over the statement.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncContinueToLocationCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ContinueToLocationCommand) Send(conn hc.CommandRunner) *AsyncContinueToLocationCommand {
+	async := NewAsyncContinueToLocationCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Steps over the statement.
 type StepOverCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -777,30 +997,34 @@ func (cmd *StepOverCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StepOverCommand) Run(conn *hc.Conn) error {
+func (cmd *StepOverCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StepOver(conn *hc.Conn) (err error) {
+func StepOver(conn hc.CommandRunner) (err error) {
 	cmd := NewStepOverCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StepOverCB func(err error)
 
 // Steps over the statement.
-
 type AsyncStepOverCommand struct {
-	cb StepOverCB
+	cb   StepOverCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStepOverCommand(cb StepOverCB) *AsyncStepOverCommand {
 	return &AsyncStepOverCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -818,11 +1042,31 @@ func (cmd *StepOverCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStepOverCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Steps into the function call.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStepOverCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StepOverCommand) Send(conn hc.CommandRunner) *AsyncStepOverCommand {
+	async := NewAsyncStepOverCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Steps into the function call.
 type StepIntoCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -840,30 +1084,34 @@ func (cmd *StepIntoCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StepIntoCommand) Run(conn *hc.Conn) error {
+func (cmd *StepIntoCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StepInto(conn *hc.Conn) (err error) {
+func StepInto(conn hc.CommandRunner) (err error) {
 	cmd := NewStepIntoCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StepIntoCB func(err error)
 
 // Steps into the function call.
-
 type AsyncStepIntoCommand struct {
-	cb StepIntoCB
+	cb   StepIntoCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStepIntoCommand(cb StepIntoCB) *AsyncStepIntoCommand {
 	return &AsyncStepIntoCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -881,11 +1129,31 @@ func (cmd *StepIntoCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStepIntoCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Steps out of the function call.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStepIntoCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StepIntoCommand) Send(conn hc.CommandRunner) *AsyncStepIntoCommand {
+	async := NewAsyncStepIntoCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Steps out of the function call.
 type StepOutCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -903,30 +1171,34 @@ func (cmd *StepOutCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StepOutCommand) Run(conn *hc.Conn) error {
+func (cmd *StepOutCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StepOut(conn *hc.Conn) (err error) {
+func StepOut(conn hc.CommandRunner) (err error) {
 	cmd := NewStepOutCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StepOutCB func(err error)
 
 // Steps out of the function call.
-
 type AsyncStepOutCommand struct {
-	cb StepOutCB
+	cb   StepOutCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStepOutCommand(cb StepOutCB) *AsyncStepOutCommand {
 	return &AsyncStepOutCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -944,11 +1216,31 @@ func (cmd *StepOutCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStepOutCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Stops on the next JavaScript statement.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStepOutCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StepOutCommand) Send(conn hc.CommandRunner) *AsyncStepOutCommand {
+	async := NewAsyncStepOutCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Stops on the next JavaScript statement.
 type PauseCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -966,30 +1258,34 @@ func (cmd *PauseCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PauseCommand) Run(conn *hc.Conn) error {
+func (cmd *PauseCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Pause(conn *hc.Conn) (err error) {
+func Pause(conn hc.CommandRunner) (err error) {
 	cmd := NewPauseCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PauseCB func(err error)
 
 // Stops on the next JavaScript statement.
-
 type AsyncPauseCommand struct {
-	cb PauseCB
+	cb   PauseCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPauseCommand(cb PauseCB) *AsyncPauseCommand {
 	return &AsyncPauseCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1007,11 +1303,31 @@ func (cmd *PauseCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPauseCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Resumes JavaScript execution.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPauseCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PauseCommand) Send(conn hc.CommandRunner) *AsyncPauseCommand {
+	async := NewAsyncPauseCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Resumes JavaScript execution.
 type ResumeCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -1029,30 +1345,34 @@ func (cmd *ResumeCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ResumeCommand) Run(conn *hc.Conn) error {
+func (cmd *ResumeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Resume(conn *hc.Conn) (err error) {
+func Resume(conn hc.CommandRunner) (err error) {
 	cmd := NewResumeCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ResumeCB func(err error)
 
 // Resumes JavaScript execution.
-
 type AsyncResumeCommand struct {
-	cb ResumeCB
+	cb   ResumeCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncResumeCommand(cb ResumeCB) *AsyncResumeCommand {
 	return &AsyncResumeCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1070,14 +1390,35 @@ func (cmd *ResumeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncResumeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncResumeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ResumeCommand) Send(conn hc.CommandRunner) *AsyncResumeCommand {
+	async := NewAsyncResumeCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SearchInContentParams struct {
-	ScriptId      *ScriptId `json:"scriptId"`                // Id of the script to search in.
-	Query         string    `json:"query"`                   // String to search for.
-	CaseSensitive bool      `json:"caseSensitive,omitempty"` // If true, search is case sensitive.
-	IsRegex       bool      `json:"isRegex,omitempty"`       // If true, treats string parameter as regex.
+	ScriptId      ScriptId `json:"scriptId"`                // Id of the script to search in.
+	Query         string   `json:"query"`                   // String to search for.
+	CaseSensitive bool     `json:"caseSensitive,omitempty"` // If true, search is case sensitive.
+	IsRegex       bool     `json:"isRegex,omitempty"`       // If true, treats string parameter as regex.
 }
 
 type SearchInContentResult struct {
@@ -1107,17 +1448,21 @@ func (cmd *SearchInContentCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SearchInContentCommand) Run(conn *hc.Conn) error {
+func (cmd *SearchInContentCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SearchInContent(params *SearchInContentParams, conn *hc.Conn) (result *SearchInContentResult, err error) {
+func SearchInContent(params *SearchInContentParams, conn hc.CommandRunner) (result *SearchInContentResult, err error) {
 	cmd := NewSearchInContentCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SearchInContentCB func(result *SearchInContentResult, err error)
@@ -1127,12 +1472,17 @@ type SearchInContentCB func(result *SearchInContentResult, err error)
 type AsyncSearchInContentCommand struct {
 	params *SearchInContentParams
 	cb     SearchInContentCB
+	result *SearchInContentResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSearchInContentCommand(params *SearchInContentParams, cb SearchInContentCB) *AsyncSearchInContentCommand {
 	return &AsyncSearchInContentCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1161,19 +1511,37 @@ func (cmd *AsyncSearchInContentCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSearchInContentCommand) Wait(ctx context.Context) (*SearchInContentResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SearchInContentCommand) Send(conn hc.CommandRunner) *AsyncSearchInContentCommand {
+	async := NewAsyncSearchInContentCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetScriptSourceParams struct {
-	ScriptId     *ScriptId `json:"scriptId"`         // Id of the script to edit.
-	ScriptSource string    `json:"scriptSource"`     // New content of the script.
-	DryRun       bool      `json:"dryRun,omitempty"` //  If true the change will not actually be applied. Dry run may be used to get result description without actually modifying the code.
+	ScriptId     ScriptId `json:"scriptId"`         // Id of the script to edit.
+	ScriptSource string   `json:"scriptSource"`     // New content of the script.
+	DryRun       bool     `json:"dryRun,omitempty"` //  If true the change will not actually be applied. Dry run may be used to get result description without actually modifying the code.
 }
 
 type SetScriptSourceResult struct {
@@ -1184,7 +1552,6 @@ type SetScriptSourceResult struct {
 }
 
 // Edits JavaScript source live.
-
 type SetScriptSourceCommand struct {
 	params *SetScriptSourceParams
 	result SetScriptSourceResult
@@ -1206,32 +1573,40 @@ func (cmd *SetScriptSourceCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetScriptSourceCommand) Run(conn *hc.Conn) error {
+func (cmd *SetScriptSourceCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetScriptSource(params *SetScriptSourceParams, conn *hc.Conn) (result *SetScriptSourceResult, err error) {
+func SetScriptSource(params *SetScriptSourceParams, conn hc.CommandRunner) (result *SetScriptSourceResult, err error) {
 	cmd := NewSetScriptSourceCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetScriptSourceCB func(result *SetScriptSourceResult, err error)
 
 // Edits JavaScript source live.
-
 type AsyncSetScriptSourceCommand struct {
 	params *SetScriptSourceParams
 	cb     SetScriptSourceCB
+	result *SetScriptSourceResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetScriptSourceCommand(params *SetScriptSourceParams, cb SetScriptSourceCB) *AsyncSetScriptSourceCommand {
 	return &AsyncSetScriptSourceCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1260,15 +1635,33 @@ func (cmd *AsyncSetScriptSourceCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetScriptSourceCommand) Wait(ctx context.Context) (*SetScriptSourceResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetScriptSourceCommand) Send(conn hc.CommandRunner) *AsyncSetScriptSourceCommand {
+	async := NewAsyncSetScriptSourceCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RestartFrameParams struct {
 	CallFrameId CallFrameId `json:"callFrameId"` // Call frame identifier to evaluate on.
 }
@@ -1279,7 +1672,6 @@ type RestartFrameResult struct {
 }
 
 // Restarts particular call frame from the beginning.
-
 type RestartFrameCommand struct {
 	params *RestartFrameParams
 	result RestartFrameResult
@@ -1301,32 +1693,40 @@ func (cmd *RestartFrameCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RestartFrameCommand) Run(conn *hc.Conn) error {
+func (cmd *RestartFrameCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RestartFrame(params *RestartFrameParams, conn *hc.Conn) (result *RestartFrameResult, err error) {
+func RestartFrame(params *RestartFrameParams, conn hc.CommandRunner) (result *RestartFrameResult, err error) {
 	cmd := NewRestartFrameCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RestartFrameCB func(result *RestartFrameResult, err error)
 
 // Restarts particular call frame from the beginning.
-
 type AsyncRestartFrameCommand struct {
 	params *RestartFrameParams
 	cb     RestartFrameCB
+	result *RestartFrameResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRestartFrameCommand(params *RestartFrameParams, cb RestartFrameCB) *AsyncRestartFrameCommand {
 	return &AsyncRestartFrameCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1355,17 +1755,35 @@ func (cmd *AsyncRestartFrameCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRestartFrameCommand) Wait(ctx context.Context) (*RestartFrameResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RestartFrameCommand) Send(conn hc.CommandRunner) *AsyncRestartFrameCommand {
+	async := NewAsyncRestartFrameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetScriptSourceParams struct {
-	ScriptId *ScriptId `json:"scriptId"` // Id of the script to get source for.
+	ScriptId ScriptId `json:"scriptId"` // Id of the script to get source for.
 }
 
 type GetScriptSourceResult struct {
@@ -1373,7 +1791,6 @@ type GetScriptSourceResult struct {
 }
 
 // Returns source for the script with given id.
-
 type GetScriptSourceCommand struct {
 	params *GetScriptSourceParams
 	result GetScriptSourceResult
@@ -1395,32 +1812,40 @@ func (cmd *GetScriptSourceCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetScriptSourceCommand) Run(conn *hc.Conn) error {
+func (cmd *GetScriptSourceCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetScriptSource(params *GetScriptSourceParams, conn *hc.Conn) (result *GetScriptSourceResult, err error) {
+func GetScriptSource(params *GetScriptSourceParams, conn hc.CommandRunner) (result *GetScriptSourceResult, err error) {
 	cmd := NewGetScriptSourceCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetScriptSourceCB func(result *GetScriptSourceResult, err error)
 
 // Returns source for the script with given id.
-
 type AsyncGetScriptSourceCommand struct {
 	params *GetScriptSourceParams
 	cb     GetScriptSourceCB
+	result *GetScriptSourceResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetScriptSourceCommand(params *GetScriptSourceParams, cb GetScriptSourceCB) *AsyncGetScriptSourceCommand {
 	return &AsyncGetScriptSourceCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1449,21 +1874,38 @@ func (cmd *AsyncGetScriptSourceCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetScriptSourceCommand) Wait(ctx context.Context) (*GetScriptSourceResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetScriptSourceCommand) Send(conn hc.CommandRunner) *AsyncGetScriptSourceCommand {
+	async := NewAsyncGetScriptSourceCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetPauseOnExceptionsParams struct {
 	State string `json:"state"` // Pause on exceptions mode.
 }
 
 // Defines pause on exceptions state. Can be set to stop on all exceptions, uncaught exceptions or no exceptions. Initial pause on exceptions state is none.
-
 type SetPauseOnExceptionsCommand struct {
 	params *SetPauseOnExceptionsParams
 	wg     sync.WaitGroup
@@ -1484,32 +1926,36 @@ func (cmd *SetPauseOnExceptionsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetPauseOnExceptionsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetPauseOnExceptionsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetPauseOnExceptions(params *SetPauseOnExceptionsParams, conn *hc.Conn) (err error) {
+func SetPauseOnExceptions(params *SetPauseOnExceptionsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetPauseOnExceptionsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetPauseOnExceptionsCB func(err error)
 
 // Defines pause on exceptions state. Can be set to stop on all exceptions, uncaught exceptions or no exceptions. Initial pause on exceptions state is none.
-
 type AsyncSetPauseOnExceptionsCommand struct {
 	params *SetPauseOnExceptionsParams
 	cb     SetPauseOnExceptionsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetPauseOnExceptionsCommand(params *SetPauseOnExceptionsParams, cb SetPauseOnExceptionsCB) *AsyncSetPauseOnExceptionsCommand {
 	return &AsyncSetPauseOnExceptionsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1527,7 +1973,28 @@ func (cmd *SetPauseOnExceptionsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetPauseOnExceptionsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetPauseOnExceptionsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetPauseOnExceptionsCommand) Send(conn hc.CommandRunner) *AsyncSetPauseOnExceptionsCommand {
+	async := NewAsyncSetPauseOnExceptionsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type EvaluateOnCallFrameParams struct {
@@ -1546,7 +2013,6 @@ type EvaluateOnCallFrameResult struct {
 }
 
 // Evaluates expression on a given call frame.
-
 type EvaluateOnCallFrameCommand struct {
 	params *EvaluateOnCallFrameParams
 	result EvaluateOnCallFrameResult
@@ -1568,32 +2034,40 @@ func (cmd *EvaluateOnCallFrameCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EvaluateOnCallFrameCommand) Run(conn *hc.Conn) error {
+func (cmd *EvaluateOnCallFrameCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EvaluateOnCallFrame(params *EvaluateOnCallFrameParams, conn *hc.Conn) (result *EvaluateOnCallFrameResult, err error) {
+func EvaluateOnCallFrame(params *EvaluateOnCallFrameParams, conn hc.CommandRunner) (result *EvaluateOnCallFrameResult, err error) {
 	cmd := NewEvaluateOnCallFrameCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type EvaluateOnCallFrameCB func(result *EvaluateOnCallFrameResult, err error)
 
 // Evaluates expression on a given call frame.
-
 type AsyncEvaluateOnCallFrameCommand struct {
 	params *EvaluateOnCallFrameParams
 	cb     EvaluateOnCallFrameCB
+	result *EvaluateOnCallFrameResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEvaluateOnCallFrameCommand(params *EvaluateOnCallFrameParams, cb EvaluateOnCallFrameCB) *AsyncEvaluateOnCallFrameCommand {
 	return &AsyncEvaluateOnCallFrameCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1622,15 +2096,33 @@ func (cmd *AsyncEvaluateOnCallFrameCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEvaluateOnCallFrameCommand) Wait(ctx context.Context) (*EvaluateOnCallFrameResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EvaluateOnCallFrameCommand) Send(conn hc.CommandRunner) *AsyncEvaluateOnCallFrameCommand {
+	async := NewAsyncEvaluateOnCallFrameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetVariableValueParams struct {
 	ScopeNumber  int           `json:"scopeNumber"`  // 0-based number of scope as was listed in scope chain. Only 'local', 'closure' and 'catch' scope types are allowed. Other scopes could be manipulated manually.
 	VariableName string        `json:"variableName"` // Variable name.
@@ -1639,7 +2131,6 @@ type SetVariableValueParams struct {
 }
 
 // Changes value of variable in a callframe. Object-based scopes are not supported and must be mutated manually.
-
 type SetVariableValueCommand struct {
 	params *SetVariableValueParams
 	wg     sync.WaitGroup
@@ -1660,32 +2151,36 @@ func (cmd *SetVariableValueCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetVariableValueCommand) Run(conn *hc.Conn) error {
+func (cmd *SetVariableValueCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetVariableValue(params *SetVariableValueParams, conn *hc.Conn) (err error) {
+func SetVariableValue(params *SetVariableValueParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetVariableValueCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetVariableValueCB func(err error)
 
 // Changes value of variable in a callframe. Object-based scopes are not supported and must be mutated manually.
-
 type AsyncSetVariableValueCommand struct {
 	params *SetVariableValueParams
 	cb     SetVariableValueCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetVariableValueCommand(params *SetVariableValueParams, cb SetVariableValueCB) *AsyncSetVariableValueCommand {
 	return &AsyncSetVariableValueCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1703,7 +2198,28 @@ func (cmd *SetVariableValueCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetVariableValueCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetVariableValueCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetVariableValueCommand) Send(conn hc.CommandRunner) *AsyncSetVariableValueCommand {
+	async := NewAsyncSetVariableValueCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetAsyncCallStackDepthParams struct {
@@ -1711,7 +2227,6 @@ type SetAsyncCallStackDepthParams struct {
 }
 
 // Enables or disables async call stacks tracking.
-
 type SetAsyncCallStackDepthCommand struct {
 	params *SetAsyncCallStackDepthParams
 	wg     sync.WaitGroup
@@ -1732,32 +2247,36 @@ func (cmd *SetAsyncCallStackDepthCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetAsyncCallStackDepthCommand) Run(conn *hc.Conn) error {
+func (cmd *SetAsyncCallStackDepthCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetAsyncCallStackDepth(params *SetAsyncCallStackDepthParams, conn *hc.Conn) (err error) {
+func SetAsyncCallStackDepth(params *SetAsyncCallStackDepthParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetAsyncCallStackDepthCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetAsyncCallStackDepthCB func(err error)
 
 // Enables or disables async call stacks tracking.
-
 type AsyncSetAsyncCallStackDepthCommand struct {
 	params *SetAsyncCallStackDepthParams
 	cb     SetAsyncCallStackDepthCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetAsyncCallStackDepthCommand(params *SetAsyncCallStackDepthParams, cb SetAsyncCallStackDepthCB) *AsyncSetAsyncCallStackDepthCommand {
 	return &AsyncSetAsyncCallStackDepthCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1775,7 +2294,28 @@ func (cmd *SetAsyncCallStackDepthCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetAsyncCallStackDepthCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetAsyncCallStackDepthCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetAsyncCallStackDepthCommand) Send(conn hc.CommandRunner) *AsyncSetAsyncCallStackDepthCommand {
+	async := NewAsyncSetAsyncCallStackDepthCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetBlackboxPatternsParams struct {
@@ -1804,17 +2344,18 @@ func (cmd *SetBlackboxPatternsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetBlackboxPatternsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetBlackboxPatternsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetBlackboxPatterns(params *SetBlackboxPatternsParams, conn *hc.Conn) (err error) {
+func SetBlackboxPatterns(params *SetBlackboxPatternsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetBlackboxPatternsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetBlackboxPatternsCB func(err error)
@@ -1824,12 +2365,16 @@ type SetBlackboxPatternsCB func(err error)
 type AsyncSetBlackboxPatternsCommand struct {
 	params *SetBlackboxPatternsParams
 	cb     SetBlackboxPatternsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetBlackboxPatternsCommand(params *SetBlackboxPatternsParams, cb SetBlackboxPatternsCB) *AsyncSetBlackboxPatternsCommand {
 	return &AsyncSetBlackboxPatternsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1847,11 +2392,32 @@ func (cmd *SetBlackboxPatternsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetBlackboxPatternsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetBlackboxPatternsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetBlackboxPatternsCommand) Send(conn hc.CommandRunner) *AsyncSetBlackboxPatternsCommand {
+	async := NewAsyncSetBlackboxPatternsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetBlackboxedRangesParams struct {
-	ScriptId  *ScriptId         `json:"scriptId"` // Id of the script.
+	ScriptId  ScriptId          `json:"scriptId"` // Id of the script.
 	Positions []*ScriptPosition `json:"positions"`
 }
 
@@ -1877,17 +2443,18 @@ func (cmd *SetBlackboxedRangesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetBlackboxedRangesCommand) Run(conn *hc.Conn) error {
+func (cmd *SetBlackboxedRangesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetBlackboxedRanges(params *SetBlackboxedRangesParams, conn *hc.Conn) (err error) {
+func SetBlackboxedRanges(params *SetBlackboxedRangesParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetBlackboxedRangesCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetBlackboxedRangesCB func(err error)
@@ -1897,12 +2464,16 @@ type SetBlackboxedRangesCB func(err error)
 type AsyncSetBlackboxedRangesCommand struct {
 	params *SetBlackboxedRangesParams
 	cb     SetBlackboxedRangesCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetBlackboxedRangesCommand(params *SetBlackboxedRangesParams, cb SetBlackboxedRangesCB) *AsyncSetBlackboxedRangesCommand {
 	return &AsyncSetBlackboxedRangesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1920,120 +2491,216 @@ func (cmd *SetBlackboxedRangesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetBlackboxedRangesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Fired when virtual machine parses script. This event is also fired for all known and uncollected scripts upon enabling debugger.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetBlackboxedRangesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetBlackboxedRangesCommand) Send(conn hc.CommandRunner) *AsyncSetBlackboxedRangesCommand {
+	async := NewAsyncSetBlackboxedRangesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Fired when virtual machine parses script. This event is also fired for all known and uncollected scripts upon enabling debugger.
 type ScriptParsedEvent struct {
-	ScriptId                *ScriptId           `json:"scriptId"`                // Identifier of the script parsed.
-	Url                     string              `json:"url"`                     // URL or name of the script parsed (if any).
-	StartLine               int                 `json:"startLine"`               // Line offset of the script within the resource with given URL (for script tags).
-	StartColumn             int                 `json:"startColumn"`             // Column offset of the script within the resource with given URL.
-	EndLine                 int                 `json:"endLine"`                 // Last line of the script.
-	EndColumn               int                 `json:"endColumn"`               // Length of the last line of the script.
-	ExecutionContextId      *ExecutionContextId `json:"executionContextId"`      // Specifies script creation context.
-	Hash                    string              `json:"hash"`                    // Content hash of the script.
-	ExecutionContextAuxData map[string]string   `json:"executionContextAuxData"` // Embedder-specific auxiliary data.
-	IsLiveEdit              bool                `json:"isLiveEdit"`              // True, if this script is generated as a result of the live edit operation.
-	SourceMapURL            string              `json:"sourceMapURL"`            // URL of source map associated with script (if any).
-	HasSourceURL            bool                `json:"hasSourceURL"`            // True, if this script has sourceURL.
-}
-
-func OnScriptParsed(conn *hc.Conn, cb func(evt *ScriptParsedEvent)) {
+	ScriptId                ScriptId           `json:"scriptId"`                // Identifier of the script parsed.
+	Url                     string             `json:"url"`                     // URL or name of the script parsed (if any).
+	StartLine               int                `json:"startLine"`               // Line offset of the script within the resource with given URL (for script tags).
+	StartColumn             int                `json:"startColumn"`             // Column offset of the script within the resource with given URL.
+	EndLine                 int                `json:"endLine"`                 // Last line of the script.
+	EndColumn               int                `json:"endColumn"`               // Length of the last line of the script.
+	ExecutionContextId      ExecutionContextId `json:"executionContextId"`      // Specifies script creation context.
+	Hash                    string             `json:"hash"`                    // Content hash of the script.
+	ExecutionContextAuxData json.RawMessage    `json:"executionContextAuxData"` // Embedder-specific auxiliary data.
+	IsLiveEdit              bool               `json:"isLiveEdit"`              // True, if this script is generated as a result of the live edit operation.
+	SourceMapURL            string             `json:"sourceMapURL"`            // URL of source map associated with script (if any).
+	HasSourceURL            bool               `json:"hasSourceURL"`            // True, if this script has sourceURL.
+}
+
+// Calls cb for each Debugger.scriptParsed event, till the returned func is called.
+func OnScriptParsed(conn hc.EventSource, cb func(evt *ScriptParsedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ScriptParsedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Debugger.scriptParsed", sink)
+	return func() { conn.RemoveEventSink("Debugger.scriptParsed", sink) }
 }
 
-// Fired when virtual machine fails to parse the script.
+// Like OnScriptParsed, but cb also gets hc.EventMeta.
+func OnScriptParsedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ScriptParsedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ScriptParsedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Debugger.scriptParsed", sink)
+	return func() { conn.RemoveEventSink("Debugger.scriptParsed", sink) }
+}
 
+// Fired when virtual machine fails to parse the script.
 type ScriptFailedToParseEvent struct {
-	ScriptId                *ScriptId           `json:"scriptId"`                // Identifier of the script parsed.
-	Url                     string              `json:"url"`                     // URL or name of the script parsed (if any).
-	StartLine               int                 `json:"startLine"`               // Line offset of the script within the resource with given URL (for script tags).
-	StartColumn             int                 `json:"startColumn"`             // Column offset of the script within the resource with given URL.
-	EndLine                 int                 `json:"endLine"`                 // Last line of the script.
-	EndColumn               int                 `json:"endColumn"`               // Length of the last line of the script.
-	ExecutionContextId      *ExecutionContextId `json:"executionContextId"`      // Specifies script creation context.
-	Hash                    string              `json:"hash"`                    // Content hash of the script.
-	ExecutionContextAuxData map[string]string   `json:"executionContextAuxData"` // Embedder-specific auxiliary data.
-	SourceMapURL            string              `json:"sourceMapURL"`            // URL of source map associated with script (if any).
-	HasSourceURL            bool                `json:"hasSourceURL"`            // True, if this script has sourceURL.
-}
-
-func OnScriptFailedToParse(conn *hc.Conn, cb func(evt *ScriptFailedToParseEvent)) {
+	ScriptId                ScriptId           `json:"scriptId"`                // Identifier of the script parsed.
+	Url                     string             `json:"url"`                     // URL or name of the script parsed (if any).
+	StartLine               int                `json:"startLine"`               // Line offset of the script within the resource with given URL (for script tags).
+	StartColumn             int                `json:"startColumn"`             // Column offset of the script within the resource with given URL.
+	EndLine                 int                `json:"endLine"`                 // Last line of the script.
+	EndColumn               int                `json:"endColumn"`               // Length of the last line of the script.
+	ExecutionContextId      ExecutionContextId `json:"executionContextId"`      // Specifies script creation context.
+	Hash                    string             `json:"hash"`                    // Content hash of the script.
+	ExecutionContextAuxData json.RawMessage    `json:"executionContextAuxData"` // Embedder-specific auxiliary data.
+	SourceMapURL            string             `json:"sourceMapURL"`            // URL of source map associated with script (if any).
+	HasSourceURL            bool               `json:"hasSourceURL"`            // True, if this script has sourceURL.
+}
+
+// Calls cb for each Debugger.scriptFailedToParse event, till the returned func is called.
+func OnScriptFailedToParse(conn hc.EventSource, cb func(evt *ScriptFailedToParseEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ScriptFailedToParseEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Debugger.scriptFailedToParse", sink)
+	return func() { conn.RemoveEventSink("Debugger.scriptFailedToParse", sink) }
 }
 
-// Fired when breakpoint is resolved to an actual script and location.
+// Like OnScriptFailedToParse, but cb also gets hc.EventMeta.
+func OnScriptFailedToParseMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ScriptFailedToParseEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ScriptFailedToParseEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Debugger.scriptFailedToParse", sink)
+	return func() { conn.RemoveEventSink("Debugger.scriptFailedToParse", sink) }
+}
 
+// Fired when breakpoint is resolved to an actual script and location.
 type BreakpointResolvedEvent struct {
 	BreakpointId BreakpointId `json:"breakpointId"` // Breakpoint unique identifier.
 	Location     *Location    `json:"location"`     // Actual breakpoint location.
 }
 
-func OnBreakpointResolved(conn *hc.Conn, cb func(evt *BreakpointResolvedEvent)) {
+// Calls cb for each Debugger.breakpointResolved event, till the returned func is called.
+func OnBreakpointResolved(conn hc.EventSource, cb func(evt *BreakpointResolvedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &BreakpointResolvedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Debugger.breakpointResolved", sink)
+	return func() { conn.RemoveEventSink("Debugger.breakpointResolved", sink) }
 }
 
-// Fired when the virtual machine stopped on breakpoint or exception or any other stop criteria.
+// Like OnBreakpointResolved, but cb also gets hc.EventMeta.
+func OnBreakpointResolvedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *BreakpointResolvedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &BreakpointResolvedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Debugger.breakpointResolved", sink)
+	return func() { conn.RemoveEventSink("Debugger.breakpointResolved", sink) }
+}
 
+// Fired when the virtual machine stopped on breakpoint or exception or any other stop criteria.
 type PausedEvent struct {
 	CallFrames      []*DebuggerCallFrame `json:"callFrames"`      // Call stack the virtual machine stopped on.
 	Reason          string               `json:"reason"`          // Pause reason.
-	Data            map[string]string    `json:"data"`            // Object containing break-specific auxiliary properties.
+	Data            json.RawMessage      `json:"data"`            // Object containing break-specific auxiliary properties.
 	HitBreakpoints  []string             `json:"hitBreakpoints"`  // Hit breakpoints IDs
 	AsyncStackTrace *StackTrace          `json:"asyncStackTrace"` // Async stack trace, if any.
 }
 
-func OnPaused(conn *hc.Conn, cb func(evt *PausedEvent)) {
+// Calls cb for each Debugger.paused event, till the returned func is called.
+func OnPaused(conn hc.EventSource, cb func(evt *PausedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &PausedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Debugger.paused", sink)
+	return func() { conn.RemoveEventSink("Debugger.paused", sink) }
 }
 
-// Fired when the virtual machine resumed execution.
+// Like OnPaused, but cb also gets hc.EventMeta.
+func OnPausedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *PausedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &PausedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Debugger.paused", sink)
+	return func() { conn.RemoveEventSink("Debugger.paused", sink) }
+}
 
+// Fired when the virtual machine resumed execution.
 type ResumedEvent struct {
 }
 
-func OnResumed(conn *hc.Conn, cb func(evt *ResumedEvent)) {
+// Calls cb for each Debugger.resumed event, till the returned func is called.
+func OnResumed(conn hc.EventSource, cb func(evt *ResumedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ResumedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Debugger.resumed", sink)
+	return func() { conn.RemoveEventSink("Debugger.resumed", sink) }
+}
+
+// Like OnResumed, but cb also gets hc.EventMeta.
+func OnResumedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ResumedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ResumedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Debugger.resumed", sink)
+	return func() { conn.RemoveEventSink("Debugger.resumed", sink) }
 }
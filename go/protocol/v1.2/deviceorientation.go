@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -12,7 +13,6 @@ type DeviceOrientationSetDeviceOrientationOverrideParams struct {
 }
 
 // Overrides the Device Orientation.
-
 type DeviceOrientationSetDeviceOrientationOverrideCommand struct {
 	params *DeviceOrientationSetDeviceOrientationOverrideParams
 	wg     sync.WaitGroup
@@ -33,32 +33,36 @@ func (cmd *DeviceOrientationSetDeviceOrientationOverrideCommand) Params() interf
 	return cmd.params
 }
 
-func (cmd *DeviceOrientationSetDeviceOrientationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *DeviceOrientationSetDeviceOrientationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DeviceOrientationSetDeviceOrientationOverride(params *DeviceOrientationSetDeviceOrientationOverrideParams, conn *hc.Conn) (err error) {
+func DeviceOrientationSetDeviceOrientationOverride(params *DeviceOrientationSetDeviceOrientationOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDeviceOrientationSetDeviceOrientationOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DeviceOrientationSetDeviceOrientationOverrideCB func(err error)
 
 // Overrides the Device Orientation.
-
 type AsyncDeviceOrientationSetDeviceOrientationOverrideCommand struct {
 	params *DeviceOrientationSetDeviceOrientationOverrideParams
 	cb     DeviceOrientationSetDeviceOrientationOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDeviceOrientationSetDeviceOrientationOverrideCommand(params *DeviceOrientationSetDeviceOrientationOverrideParams, cb DeviceOrientationSetDeviceOrientationOverrideCB) *AsyncDeviceOrientationSetDeviceOrientationOverrideCommand {
 	return &AsyncDeviceOrientationSetDeviceOrientationOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -76,11 +80,31 @@ func (cmd *DeviceOrientationSetDeviceOrientationOverrideCommand) Done(data []byt
 }
 
 func (cmd *AsyncDeviceOrientationSetDeviceOrientationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Clears the overridden Device Orientation.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDeviceOrientationSetDeviceOrientationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DeviceOrientationSetDeviceOrientationOverrideCommand) Send(conn hc.CommandRunner) *AsyncDeviceOrientationSetDeviceOrientationOverrideCommand {
+	async := NewAsyncDeviceOrientationSetDeviceOrientationOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Clears the overridden Device Orientation.
 type DeviceOrientationClearDeviceOrientationOverrideCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -98,30 +122,34 @@ func (cmd *DeviceOrientationClearDeviceOrientationOverrideCommand) Params() inte
 	return nil
 }
 
-func (cmd *DeviceOrientationClearDeviceOrientationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *DeviceOrientationClearDeviceOrientationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DeviceOrientationClearDeviceOrientationOverride(conn *hc.Conn) (err error) {
+func DeviceOrientationClearDeviceOrientationOverride(conn hc.CommandRunner) (err error) {
 	cmd := NewDeviceOrientationClearDeviceOrientationOverrideCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DeviceOrientationClearDeviceOrientationOverrideCB func(err error)
 
 // Clears the overridden Device Orientation.
-
 type AsyncDeviceOrientationClearDeviceOrientationOverrideCommand struct {
-	cb DeviceOrientationClearDeviceOrientationOverrideCB
+	cb   DeviceOrientationClearDeviceOrientationOverrideCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDeviceOrientationClearDeviceOrientationOverrideCommand(cb DeviceOrientationClearDeviceOrientationOverrideCB) *AsyncDeviceOrientationClearDeviceOrientationOverrideCommand {
 	return &AsyncDeviceOrientationClearDeviceOrientationOverrideCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -139,5 +167,26 @@ func (cmd *DeviceOrientationClearDeviceOrientationOverrideCommand) Done(data []b
 }
 
 func (cmd *AsyncDeviceOrientationClearDeviceOrientationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDeviceOrientationClearDeviceOrientationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DeviceOrientationClearDeviceOrientationOverrideCommand) Send(conn hc.CommandRunner) *AsyncDeviceOrientationClearDeviceOrientationOverrideCommand {
+	async := NewAsyncDeviceOrientationClearDeviceOrientationOverrideCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -69,7 +69,7 @@ type Node struct {
 	Value            string         `json:"value,omitempty"`            // Attr's value.
 	PseudoType       PseudoType     `json:"pseudoType,omitempty"`       // Pseudo element type for this node.
 	ShadowRootType   ShadowRootType `json:"shadowRootType,omitempty"`   // Shadow root type.
-	FrameId          *FrameId       `json:"frameId,omitempty"`          // Frame ID for frame owner elements.
+	FrameId          FrameId        `json:"frameId,omitempty"`          // Frame ID for frame owner elements.
 	ContentDocument  *Node          `json:"contentDocument,omitempty"`  // Content document for frame owner elements.
 	ShadowRoots      []*Node        `json:"shadowRoots,omitempty"`      // Shadow root list for given element host.
 	TemplateContent  *Node          `json:"templateContent,omitempty"`  // Content document fragment for template elements.
@@ -144,7 +144,6 @@ const InspectModeSearchForUAShadowDOM InspectMode = "searchForUAShadowDOM"
 const InspectModeNone InspectMode = "none"
 
 // Enables DOM agent for the given page.
-
 type DOMEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -162,30 +161,34 @@ func (cmd *DOMEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DOMEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *DOMEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DOMEnable(conn *hc.Conn) (err error) {
+func DOMEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewDOMEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DOMEnableCB func(err error)
 
 // Enables DOM agent for the given page.
-
 type AsyncDOMEnableCommand struct {
-	cb DOMEnableCB
+	cb   DOMEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDOMEnableCommand(cb DOMEnableCB) *AsyncDOMEnableCommand {
 	return &AsyncDOMEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -203,11 +206,31 @@ func (cmd *DOMEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDOMEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables DOM agent for the given page.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDOMEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DOMEnableCommand) Send(conn hc.CommandRunner) *AsyncDOMEnableCommand {
+	async := NewAsyncDOMEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables DOM agent for the given page.
 type DOMDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -225,30 +248,34 @@ func (cmd *DOMDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DOMDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *DOMDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DOMDisable(conn *hc.Conn) (err error) {
+func DOMDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewDOMDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DOMDisableCB func(err error)
 
 // Disables DOM agent for the given page.
-
 type AsyncDOMDisableCommand struct {
-	cb DOMDisableCB
+	cb   DOMDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDOMDisableCommand(cb DOMDisableCB) *AsyncDOMDisableCommand {
 	return &AsyncDOMDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -266,7 +293,28 @@ func (cmd *DOMDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDOMDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDOMDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DOMDisableCommand) Send(conn hc.CommandRunner) *AsyncDOMDisableCommand {
+	async := NewAsyncDOMDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetDocumentParams struct {
@@ -279,7 +327,6 @@ type GetDocumentResult struct {
 }
 
 // Returns the root DOM node (and optionally the subtree) to the caller.
-
 type GetDocumentCommand struct {
 	params *GetDocumentParams
 	result GetDocumentResult
@@ -301,32 +348,40 @@ func (cmd *GetDocumentCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetDocumentCommand) Run(conn *hc.Conn) error {
+func (cmd *GetDocumentCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetDocument(params *GetDocumentParams, conn *hc.Conn) (result *GetDocumentResult, err error) {
+func GetDocument(params *GetDocumentParams, conn hc.CommandRunner) (result *GetDocumentResult, err error) {
 	cmd := NewGetDocumentCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetDocumentCB func(result *GetDocumentResult, err error)
 
 // Returns the root DOM node (and optionally the subtree) to the caller.
-
 type AsyncGetDocumentCommand struct {
 	params *GetDocumentParams
 	cb     GetDocumentCB
+	result *GetDocumentResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetDocumentCommand(params *GetDocumentParams, cb GetDocumentCB) *AsyncGetDocumentCommand {
 	return &AsyncGetDocumentCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -355,15 +410,33 @@ func (cmd *AsyncGetDocumentCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetDocumentCommand) Wait(ctx context.Context) (*GetDocumentResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetDocumentCommand) Send(conn hc.CommandRunner) *AsyncGetDocumentCommand {
+	async := NewAsyncGetDocumentCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CollectClassNamesFromSubtreeParams struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node to collect class names.
 }
@@ -395,17 +468,21 @@ func (cmd *CollectClassNamesFromSubtreeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CollectClassNamesFromSubtreeCommand) Run(conn *hc.Conn) error {
+func (cmd *CollectClassNamesFromSubtreeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CollectClassNamesFromSubtree(params *CollectClassNamesFromSubtreeParams, conn *hc.Conn) (result *CollectClassNamesFromSubtreeResult, err error) {
+func CollectClassNamesFromSubtree(params *CollectClassNamesFromSubtreeParams, conn hc.CommandRunner) (result *CollectClassNamesFromSubtreeResult, err error) {
 	cmd := NewCollectClassNamesFromSubtreeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CollectClassNamesFromSubtreeCB func(result *CollectClassNamesFromSubtreeResult, err error)
@@ -415,12 +492,17 @@ type CollectClassNamesFromSubtreeCB func(result *CollectClassNamesFromSubtreeRes
 type AsyncCollectClassNamesFromSubtreeCommand struct {
 	params *CollectClassNamesFromSubtreeParams
 	cb     CollectClassNamesFromSubtreeCB
+	result *CollectClassNamesFromSubtreeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCollectClassNamesFromSubtreeCommand(params *CollectClassNamesFromSubtreeParams, cb CollectClassNamesFromSubtreeCB) *AsyncCollectClassNamesFromSubtreeCommand {
 	return &AsyncCollectClassNamesFromSubtreeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -449,15 +531,33 @@ func (cmd *AsyncCollectClassNamesFromSubtreeCommand) Done(data []byte, err error
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCollectClassNamesFromSubtreeCommand) Wait(ctx context.Context) (*CollectClassNamesFromSubtreeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CollectClassNamesFromSubtreeCommand) Send(conn hc.CommandRunner) *AsyncCollectClassNamesFromSubtreeCommand {
+	async := NewAsyncCollectClassNamesFromSubtreeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RequestChildNodesParams struct {
 	NodeId NodeId `json:"nodeId"`           // Id of the node to get children for.
 	Depth  int    `json:"depth,omitempty"`  // The maximum depth at which children should be retrieved, defaults to 1. Use -1 for the entire subtree or provide an integer larger than 0.
@@ -465,7 +565,6 @@ type RequestChildNodesParams struct {
 }
 
 // Requests that children of the node with given id are returned to the caller in form of setChildNodes events where not only immediate children are retrieved, but all children down to the specified depth.
-
 type RequestChildNodesCommand struct {
 	params *RequestChildNodesParams
 	wg     sync.WaitGroup
@@ -486,32 +585,36 @@ func (cmd *RequestChildNodesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestChildNodesCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestChildNodesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestChildNodes(params *RequestChildNodesParams, conn *hc.Conn) (err error) {
+func RequestChildNodes(params *RequestChildNodesParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRequestChildNodesCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RequestChildNodesCB func(err error)
 
 // Requests that children of the node with given id are returned to the caller in form of setChildNodes events where not only immediate children are retrieved, but all children down to the specified depth.
-
 type AsyncRequestChildNodesCommand struct {
 	params *RequestChildNodesParams
 	cb     RequestChildNodesCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestChildNodesCommand(params *RequestChildNodesParams, cb RequestChildNodesCB) *AsyncRequestChildNodesCommand {
 	return &AsyncRequestChildNodesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -529,7 +632,28 @@ func (cmd *RequestChildNodesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRequestChildNodesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestChildNodesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestChildNodesCommand) Send(conn hc.CommandRunner) *AsyncRequestChildNodesCommand {
+	async := NewAsyncRequestChildNodesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type QuerySelectorParams struct {
@@ -542,7 +666,6 @@ type QuerySelectorResult struct {
 }
 
 // Executes querySelector on a given node.
-
 type QuerySelectorCommand struct {
 	params *QuerySelectorParams
 	result QuerySelectorResult
@@ -564,32 +687,40 @@ func (cmd *QuerySelectorCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *QuerySelectorCommand) Run(conn *hc.Conn) error {
+func (cmd *QuerySelectorCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func QuerySelector(params *QuerySelectorParams, conn *hc.Conn) (result *QuerySelectorResult, err error) {
+func QuerySelector(params *QuerySelectorParams, conn hc.CommandRunner) (result *QuerySelectorResult, err error) {
 	cmd := NewQuerySelectorCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type QuerySelectorCB func(result *QuerySelectorResult, err error)
 
 // Executes querySelector on a given node.
-
 type AsyncQuerySelectorCommand struct {
 	params *QuerySelectorParams
 	cb     QuerySelectorCB
+	result *QuerySelectorResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncQuerySelectorCommand(params *QuerySelectorParams, cb QuerySelectorCB) *AsyncQuerySelectorCommand {
 	return &AsyncQuerySelectorCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -618,15 +749,33 @@ func (cmd *AsyncQuerySelectorCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncQuerySelectorCommand) Wait(ctx context.Context) (*QuerySelectorResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *QuerySelectorCommand) Send(conn hc.CommandRunner) *AsyncQuerySelectorCommand {
+	async := NewAsyncQuerySelectorCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type QuerySelectorAllParams struct {
 	NodeId   NodeId `json:"nodeId"`   // Id of the node to query upon.
 	Selector string `json:"selector"` // Selector string.
@@ -637,7 +786,6 @@ type QuerySelectorAllResult struct {
 }
 
 // Executes querySelectorAll on a given node.
-
 type QuerySelectorAllCommand struct {
 	params *QuerySelectorAllParams
 	result QuerySelectorAllResult
@@ -659,32 +807,40 @@ func (cmd *QuerySelectorAllCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *QuerySelectorAllCommand) Run(conn *hc.Conn) error {
+func (cmd *QuerySelectorAllCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func QuerySelectorAll(params *QuerySelectorAllParams, conn *hc.Conn) (result *QuerySelectorAllResult, err error) {
+func QuerySelectorAll(params *QuerySelectorAllParams, conn hc.CommandRunner) (result *QuerySelectorAllResult, err error) {
 	cmd := NewQuerySelectorAllCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type QuerySelectorAllCB func(result *QuerySelectorAllResult, err error)
 
 // Executes querySelectorAll on a given node.
-
 type AsyncQuerySelectorAllCommand struct {
 	params *QuerySelectorAllParams
 	cb     QuerySelectorAllCB
+	result *QuerySelectorAllResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncQuerySelectorAllCommand(params *QuerySelectorAllParams, cb QuerySelectorAllCB) *AsyncQuerySelectorAllCommand {
 	return &AsyncQuerySelectorAllCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -713,15 +869,33 @@ func (cmd *AsyncQuerySelectorAllCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncQuerySelectorAllCommand) Wait(ctx context.Context) (*QuerySelectorAllResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *QuerySelectorAllCommand) Send(conn hc.CommandRunner) *AsyncQuerySelectorAllCommand {
+	async := NewAsyncQuerySelectorAllCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetNodeNameParams struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node to set name for.
 	Name   string `json:"name"`   // New node's name.
@@ -732,7 +906,6 @@ type SetNodeNameResult struct {
 }
 
 // Sets node name for a node with given id.
-
 type SetNodeNameCommand struct {
 	params *SetNodeNameParams
 	result SetNodeNameResult
@@ -754,32 +927,40 @@ func (cmd *SetNodeNameCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetNodeNameCommand) Run(conn *hc.Conn) error {
+func (cmd *SetNodeNameCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetNodeName(params *SetNodeNameParams, conn *hc.Conn) (result *SetNodeNameResult, err error) {
+func SetNodeName(params *SetNodeNameParams, conn hc.CommandRunner) (result *SetNodeNameResult, err error) {
 	cmd := NewSetNodeNameCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetNodeNameCB func(result *SetNodeNameResult, err error)
 
 // Sets node name for a node with given id.
-
 type AsyncSetNodeNameCommand struct {
 	params *SetNodeNameParams
 	cb     SetNodeNameCB
+	result *SetNodeNameResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetNodeNameCommand(params *SetNodeNameParams, cb SetNodeNameCB) *AsyncSetNodeNameCommand {
 	return &AsyncSetNodeNameCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -808,22 +989,39 @@ func (cmd *AsyncSetNodeNameCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetNodeNameCommand) Wait(ctx context.Context) (*SetNodeNameResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetNodeNameCommand) Send(conn hc.CommandRunner) *AsyncSetNodeNameCommand {
+	async := NewAsyncSetNodeNameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetNodeValueParams struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node to set value for.
 	Value  string `json:"value"`  // New node's value.
 }
 
 // Sets node value for a node with given id.
-
 type SetNodeValueCommand struct {
 	params *SetNodeValueParams
 	wg     sync.WaitGroup
@@ -844,32 +1042,36 @@ func (cmd *SetNodeValueCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetNodeValueCommand) Run(conn *hc.Conn) error {
+func (cmd *SetNodeValueCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetNodeValue(params *SetNodeValueParams, conn *hc.Conn) (err error) {
+func SetNodeValue(params *SetNodeValueParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetNodeValueCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetNodeValueCB func(err error)
 
 // Sets node value for a node with given id.
-
 type AsyncSetNodeValueCommand struct {
 	params *SetNodeValueParams
 	cb     SetNodeValueCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetNodeValueCommand(params *SetNodeValueParams, cb SetNodeValueCB) *AsyncSetNodeValueCommand {
 	return &AsyncSetNodeValueCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -887,7 +1089,28 @@ func (cmd *SetNodeValueCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetNodeValueCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetNodeValueCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetNodeValueCommand) Send(conn hc.CommandRunner) *AsyncSetNodeValueCommand {
+	async := NewAsyncSetNodeValueCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveNodeParams struct {
@@ -895,7 +1118,6 @@ type RemoveNodeParams struct {
 }
 
 // Removes node with given id.
-
 type RemoveNodeCommand struct {
 	params *RemoveNodeParams
 	wg     sync.WaitGroup
@@ -916,32 +1138,36 @@ func (cmd *RemoveNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveNode(params *RemoveNodeParams, conn *hc.Conn) (err error) {
+func RemoveNode(params *RemoveNodeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveNodeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveNodeCB func(err error)
 
 // Removes node with given id.
-
 type AsyncRemoveNodeCommand struct {
 	params *RemoveNodeParams
 	cb     RemoveNodeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveNodeCommand(params *RemoveNodeParams, cb RemoveNodeCB) *AsyncRemoveNodeCommand {
 	return &AsyncRemoveNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -959,7 +1185,28 @@ func (cmd *RemoveNodeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveNodeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveNodeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveNodeCommand) Send(conn hc.CommandRunner) *AsyncRemoveNodeCommand {
+	async := NewAsyncRemoveNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetAttributeValueParams struct {
@@ -969,7 +1216,6 @@ type SetAttributeValueParams struct {
 }
 
 // Sets attribute for an element with given id.
-
 type SetAttributeValueCommand struct {
 	params *SetAttributeValueParams
 	wg     sync.WaitGroup
@@ -990,32 +1236,36 @@ func (cmd *SetAttributeValueCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetAttributeValueCommand) Run(conn *hc.Conn) error {
+func (cmd *SetAttributeValueCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetAttributeValue(params *SetAttributeValueParams, conn *hc.Conn) (err error) {
+func SetAttributeValue(params *SetAttributeValueParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetAttributeValueCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetAttributeValueCB func(err error)
 
 // Sets attribute for an element with given id.
-
 type AsyncSetAttributeValueCommand struct {
 	params *SetAttributeValueParams
 	cb     SetAttributeValueCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetAttributeValueCommand(params *SetAttributeValueParams, cb SetAttributeValueCB) *AsyncSetAttributeValueCommand {
 	return &AsyncSetAttributeValueCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1033,7 +1283,28 @@ func (cmd *SetAttributeValueCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetAttributeValueCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetAttributeValueCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetAttributeValueCommand) Send(conn hc.CommandRunner) *AsyncSetAttributeValueCommand {
+	async := NewAsyncSetAttributeValueCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetAttributesAsTextParams struct {
@@ -1043,7 +1314,6 @@ type SetAttributesAsTextParams struct {
 }
 
 // Sets attributes on element with given id. This method is useful when user edits some existing attribute value and types in several attribute name/value pairs.
-
 type SetAttributesAsTextCommand struct {
 	params *SetAttributesAsTextParams
 	wg     sync.WaitGroup
@@ -1064,32 +1334,36 @@ func (cmd *SetAttributesAsTextCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetAttributesAsTextCommand) Run(conn *hc.Conn) error {
+func (cmd *SetAttributesAsTextCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetAttributesAsText(params *SetAttributesAsTextParams, conn *hc.Conn) (err error) {
+func SetAttributesAsText(params *SetAttributesAsTextParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetAttributesAsTextCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetAttributesAsTextCB func(err error)
 
 // Sets attributes on element with given id. This method is useful when user edits some existing attribute value and types in several attribute name/value pairs.
-
 type AsyncSetAttributesAsTextCommand struct {
 	params *SetAttributesAsTextParams
 	cb     SetAttributesAsTextCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetAttributesAsTextCommand(params *SetAttributesAsTextParams, cb SetAttributesAsTextCB) *AsyncSetAttributesAsTextCommand {
 	return &AsyncSetAttributesAsTextCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1107,7 +1381,28 @@ func (cmd *SetAttributesAsTextCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetAttributesAsTextCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetAttributesAsTextCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetAttributesAsTextCommand) Send(conn hc.CommandRunner) *AsyncSetAttributesAsTextCommand {
+	async := NewAsyncSetAttributesAsTextCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveAttributeParams struct {
@@ -1116,7 +1411,6 @@ type RemoveAttributeParams struct {
 }
 
 // Removes attribute with given name from an element with given id.
-
 type RemoveAttributeCommand struct {
 	params *RemoveAttributeParams
 	wg     sync.WaitGroup
@@ -1137,32 +1431,36 @@ func (cmd *RemoveAttributeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveAttributeCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveAttributeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveAttribute(params *RemoveAttributeParams, conn *hc.Conn) (err error) {
+func RemoveAttribute(params *RemoveAttributeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveAttributeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveAttributeCB func(err error)
 
 // Removes attribute with given name from an element with given id.
-
 type AsyncRemoveAttributeCommand struct {
 	params *RemoveAttributeParams
 	cb     RemoveAttributeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveAttributeCommand(params *RemoveAttributeParams, cb RemoveAttributeCB) *AsyncRemoveAttributeCommand {
 	return &AsyncRemoveAttributeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1180,7 +1478,28 @@ func (cmd *RemoveAttributeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveAttributeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveAttributeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveAttributeCommand) Send(conn hc.CommandRunner) *AsyncRemoveAttributeCommand {
+	async := NewAsyncRemoveAttributeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetOuterHTMLParams struct {
@@ -1192,7 +1511,6 @@ type GetOuterHTMLResult struct {
 }
 
 // Returns node's HTML markup.
-
 type GetOuterHTMLCommand struct {
 	params *GetOuterHTMLParams
 	result GetOuterHTMLResult
@@ -1214,32 +1532,40 @@ func (cmd *GetOuterHTMLCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetOuterHTMLCommand) Run(conn *hc.Conn) error {
+func (cmd *GetOuterHTMLCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetOuterHTML(params *GetOuterHTMLParams, conn *hc.Conn) (result *GetOuterHTMLResult, err error) {
+func GetOuterHTML(params *GetOuterHTMLParams, conn hc.CommandRunner) (result *GetOuterHTMLResult, err error) {
 	cmd := NewGetOuterHTMLCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetOuterHTMLCB func(result *GetOuterHTMLResult, err error)
 
 // Returns node's HTML markup.
-
 type AsyncGetOuterHTMLCommand struct {
 	params *GetOuterHTMLParams
 	cb     GetOuterHTMLCB
+	result *GetOuterHTMLResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetOuterHTMLCommand(params *GetOuterHTMLParams, cb GetOuterHTMLCB) *AsyncGetOuterHTMLCommand {
 	return &AsyncGetOuterHTMLCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1268,22 +1594,39 @@ func (cmd *AsyncGetOuterHTMLCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetOuterHTMLCommand) Wait(ctx context.Context) (*GetOuterHTMLResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetOuterHTMLCommand) Send(conn hc.CommandRunner) *AsyncGetOuterHTMLCommand {
+	async := NewAsyncGetOuterHTMLCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetOuterHTMLParams struct {
 	NodeId    NodeId `json:"nodeId"`    // Id of the node to set markup for.
 	OuterHTML string `json:"outerHTML"` // Outer HTML markup to set.
 }
 
 // Sets node HTML markup, returns new node id.
-
 type SetOuterHTMLCommand struct {
 	params *SetOuterHTMLParams
 	wg     sync.WaitGroup
@@ -1304,32 +1647,36 @@ func (cmd *SetOuterHTMLCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetOuterHTMLCommand) Run(conn *hc.Conn) error {
+func (cmd *SetOuterHTMLCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetOuterHTML(params *SetOuterHTMLParams, conn *hc.Conn) (err error) {
+func SetOuterHTML(params *SetOuterHTMLParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetOuterHTMLCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetOuterHTMLCB func(err error)
 
 // Sets node HTML markup, returns new node id.
-
 type AsyncSetOuterHTMLCommand struct {
 	params *SetOuterHTMLParams
 	cb     SetOuterHTMLCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetOuterHTMLCommand(params *SetOuterHTMLParams, cb SetOuterHTMLCB) *AsyncSetOuterHTMLCommand {
 	return &AsyncSetOuterHTMLCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1347,7 +1694,28 @@ func (cmd *SetOuterHTMLCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetOuterHTMLCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetOuterHTMLCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetOuterHTMLCommand) Send(conn hc.CommandRunner) *AsyncSetOuterHTMLCommand {
+	async := NewAsyncSetOuterHTMLCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PerformSearchParams struct {
@@ -1383,17 +1751,21 @@ func (cmd *PerformSearchCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PerformSearchCommand) Run(conn *hc.Conn) error {
+func (cmd *PerformSearchCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PerformSearch(params *PerformSearchParams, conn *hc.Conn) (result *PerformSearchResult, err error) {
+func PerformSearch(params *PerformSearchParams, conn hc.CommandRunner) (result *PerformSearchResult, err error) {
 	cmd := NewPerformSearchCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type PerformSearchCB func(result *PerformSearchResult, err error)
@@ -1403,12 +1775,17 @@ type PerformSearchCB func(result *PerformSearchResult, err error)
 type AsyncPerformSearchCommand struct {
 	params *PerformSearchParams
 	cb     PerformSearchCB
+	result *PerformSearchResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPerformSearchCommand(params *PerformSearchParams, cb PerformSearchCB) *AsyncPerformSearchCommand {
 	return &AsyncPerformSearchCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1437,15 +1814,33 @@ func (cmd *AsyncPerformSearchCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPerformSearchCommand) Wait(ctx context.Context) (*PerformSearchResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PerformSearchCommand) Send(conn hc.CommandRunner) *AsyncPerformSearchCommand {
+	async := NewAsyncPerformSearchCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetSearchResultsParams struct {
 	SearchId  string `json:"searchId"`  // Unique search session identifier.
 	FromIndex int    `json:"fromIndex"` // Start index of the search result to be returned.
@@ -1479,17 +1874,21 @@ func (cmd *GetSearchResultsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetSearchResultsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetSearchResultsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetSearchResults(params *GetSearchResultsParams, conn *hc.Conn) (result *GetSearchResultsResult, err error) {
+func GetSearchResults(params *GetSearchResultsParams, conn hc.CommandRunner) (result *GetSearchResultsResult, err error) {
 	cmd := NewGetSearchResultsCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetSearchResultsCB func(result *GetSearchResultsResult, err error)
@@ -1499,12 +1898,17 @@ type GetSearchResultsCB func(result *GetSearchResultsResult, err error)
 type AsyncGetSearchResultsCommand struct {
 	params *GetSearchResultsParams
 	cb     GetSearchResultsCB
+	result *GetSearchResultsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetSearchResultsCommand(params *GetSearchResultsParams, cb GetSearchResultsCB) *AsyncGetSearchResultsCommand {
 	return &AsyncGetSearchResultsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1533,15 +1937,33 @@ func (cmd *AsyncGetSearchResultsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetSearchResultsCommand) Wait(ctx context.Context) (*GetSearchResultsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetSearchResultsCommand) Send(conn hc.CommandRunner) *AsyncGetSearchResultsCommand {
+	async := NewAsyncGetSearchResultsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type DiscardSearchResultsParams struct {
 	SearchId string `json:"searchId"` // Unique search session identifier.
 }
@@ -1568,17 +1990,18 @@ func (cmd *DiscardSearchResultsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DiscardSearchResultsCommand) Run(conn *hc.Conn) error {
+func (cmd *DiscardSearchResultsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DiscardSearchResults(params *DiscardSearchResultsParams, conn *hc.Conn) (err error) {
+func DiscardSearchResults(params *DiscardSearchResultsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDiscardSearchResultsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DiscardSearchResultsCB func(err error)
@@ -1588,12 +2011,16 @@ type DiscardSearchResultsCB func(err error)
 type AsyncDiscardSearchResultsCommand struct {
 	params *DiscardSearchResultsParams
 	cb     DiscardSearchResultsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDiscardSearchResultsCommand(params *DiscardSearchResultsParams, cb DiscardSearchResultsCB) *AsyncDiscardSearchResultsCommand {
 	return &AsyncDiscardSearchResultsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1611,11 +2038,32 @@ func (cmd *DiscardSearchResultsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDiscardSearchResultsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDiscardSearchResultsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DiscardSearchResultsCommand) Send(conn hc.CommandRunner) *AsyncDiscardSearchResultsCommand {
+	async := NewAsyncDiscardSearchResultsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RequestNodeParams struct {
-	ObjectId *RemoteObjectId `json:"objectId"` // JavaScript object id to convert into node.
+	ObjectId RemoteObjectId `json:"objectId"` // JavaScript object id to convert into node.
 }
 
 type RequestNodeResult struct {
@@ -1623,7 +2071,6 @@ type RequestNodeResult struct {
 }
 
 // Requests that the node is sent to the caller given the JavaScript node object reference. All nodes that form the path from the node to the root are also sent to the client as a series of setChildNodes notifications.
-
 type RequestNodeCommand struct {
 	params *RequestNodeParams
 	result RequestNodeResult
@@ -1645,32 +2092,40 @@ func (cmd *RequestNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestNode(params *RequestNodeParams, conn *hc.Conn) (result *RequestNodeResult, err error) {
+func RequestNode(params *RequestNodeParams, conn hc.CommandRunner) (result *RequestNodeResult, err error) {
 	cmd := NewRequestNodeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestNodeCB func(result *RequestNodeResult, err error)
 
 // Requests that the node is sent to the caller given the JavaScript node object reference. All nodes that form the path from the node to the root are also sent to the client as a series of setChildNodes notifications.
-
 type AsyncRequestNodeCommand struct {
 	params *RequestNodeParams
 	cb     RequestNodeCB
+	result *RequestNodeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestNodeCommand(params *RequestNodeParams, cb RequestNodeCB) *AsyncRequestNodeCommand {
 	return &AsyncRequestNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1699,15 +2154,33 @@ func (cmd *AsyncRequestNodeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestNodeCommand) Wait(ctx context.Context) (*RequestNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestNodeCommand) Send(conn hc.CommandRunner) *AsyncRequestNodeCommand {
+	async := NewAsyncRequestNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetInspectModeParams struct {
 	Mode            InspectMode      `json:"mode"`                      // Set an inspection mode.
 	HighlightConfig *HighlightConfig `json:"highlightConfig,omitempty"` // A descriptor for the highlight appearance of hovered-over nodes. May be omitted if enabled == false.
@@ -1735,17 +2208,18 @@ func (cmd *SetInspectModeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetInspectModeCommand) Run(conn *hc.Conn) error {
+func (cmd *SetInspectModeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetInspectMode(params *SetInspectModeParams, conn *hc.Conn) (err error) {
+func SetInspectMode(params *SetInspectModeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetInspectModeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetInspectModeCB func(err error)
@@ -1755,12 +2229,16 @@ type SetInspectModeCB func(err error)
 type AsyncSetInspectModeCommand struct {
 	params *SetInspectModeParams
 	cb     SetInspectModeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetInspectModeCommand(params *SetInspectModeParams, cb SetInspectModeCB) *AsyncSetInspectModeCommand {
 	return &AsyncSetInspectModeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1778,7 +2256,28 @@ func (cmd *SetInspectModeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetInspectModeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetInspectModeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetInspectModeCommand) Send(conn hc.CommandRunner) *AsyncSetInspectModeCommand {
+	async := NewAsyncSetInspectModeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type HighlightRectParams struct {
@@ -1791,7 +2290,6 @@ type HighlightRectParams struct {
 }
 
 // Highlights given rectangle. Coordinates are absolute with respect to the main frame viewport.
-
 type HighlightRectCommand struct {
 	params *HighlightRectParams
 	wg     sync.WaitGroup
@@ -1812,32 +2310,36 @@ func (cmd *HighlightRectCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *HighlightRectCommand) Run(conn *hc.Conn) error {
+func (cmd *HighlightRectCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HighlightRect(params *HighlightRectParams, conn *hc.Conn) (err error) {
+func HighlightRect(params *HighlightRectParams, conn hc.CommandRunner) (err error) {
 	cmd := NewHighlightRectCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HighlightRectCB func(err error)
 
 // Highlights given rectangle. Coordinates are absolute with respect to the main frame viewport.
-
 type AsyncHighlightRectCommand struct {
 	params *HighlightRectParams
 	cb     HighlightRectCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHighlightRectCommand(params *HighlightRectParams, cb HighlightRectCB) *AsyncHighlightRectCommand {
 	return &AsyncHighlightRectCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1855,7 +2357,28 @@ func (cmd *HighlightRectCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHighlightRectCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHighlightRectCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HighlightRectCommand) Send(conn hc.CommandRunner) *AsyncHighlightRectCommand {
+	async := NewAsyncHighlightRectCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type HighlightQuadParams struct {
@@ -1886,17 +2409,18 @@ func (cmd *HighlightQuadCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *HighlightQuadCommand) Run(conn *hc.Conn) error {
+func (cmd *HighlightQuadCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HighlightQuad(params *HighlightQuadParams, conn *hc.Conn) (err error) {
+func HighlightQuad(params *HighlightQuadParams, conn hc.CommandRunner) (err error) {
 	cmd := NewHighlightQuadCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HighlightQuadCB func(err error)
@@ -1906,12 +2430,16 @@ type HighlightQuadCB func(err error)
 type AsyncHighlightQuadCommand struct {
 	params *HighlightQuadParams
 	cb     HighlightQuadCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHighlightQuadCommand(params *HighlightQuadParams, cb HighlightQuadCB) *AsyncHighlightQuadCommand {
 	return &AsyncHighlightQuadCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1929,18 +2457,38 @@ func (cmd *HighlightQuadCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHighlightQuadCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-type HighlightNodeParams struct {
-	HighlightConfig *HighlightConfig `json:"highlightConfig"`         // A descriptor for the highlight appearance.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHighlightQuadCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HighlightQuadCommand) Send(conn hc.CommandRunner) *AsyncHighlightQuadCommand {
+	async := NewAsyncHighlightQuadCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+type HighlightNodeParams struct {
+	HighlightConfig *HighlightConfig `json:"highlightConfig"`         // A descriptor for the highlight appearance.
 	NodeId          NodeId           `json:"nodeId,omitempty"`        // Identifier of the node to highlight.
 	BackendNodeId   BackendNodeId    `json:"backendNodeId,omitempty"` // Identifier of the backend node to highlight.
-	ObjectId        *RemoteObjectId  `json:"objectId,omitempty"`      // JavaScript object id of the node to be highlighted.
+	ObjectId        RemoteObjectId   `json:"objectId,omitempty"`      // JavaScript object id of the node to be highlighted.
 }
 
 // Highlights DOM node with given id or with the given JavaScript object wrapper. Either nodeId or objectId must be specified.
-
 type HighlightNodeCommand struct {
 	params *HighlightNodeParams
 	wg     sync.WaitGroup
@@ -1961,32 +2509,36 @@ func (cmd *HighlightNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *HighlightNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *HighlightNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HighlightNode(params *HighlightNodeParams, conn *hc.Conn) (err error) {
+func HighlightNode(params *HighlightNodeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewHighlightNodeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HighlightNodeCB func(err error)
 
 // Highlights DOM node with given id or with the given JavaScript object wrapper. Either nodeId or objectId must be specified.
-
 type AsyncHighlightNodeCommand struct {
 	params *HighlightNodeParams
 	cb     HighlightNodeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHighlightNodeCommand(params *HighlightNodeParams, cb HighlightNodeCB) *AsyncHighlightNodeCommand {
 	return &AsyncHighlightNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2004,11 +2556,31 @@ func (cmd *HighlightNodeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHighlightNodeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Hides DOM node highlight.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHighlightNodeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HighlightNodeCommand) Send(conn hc.CommandRunner) *AsyncHighlightNodeCommand {
+	async := NewAsyncHighlightNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Hides DOM node highlight.
 type HideHighlightCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -2026,30 +2598,34 @@ func (cmd *HideHighlightCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *HideHighlightCommand) Run(conn *hc.Conn) error {
+func (cmd *HideHighlightCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HideHighlight(conn *hc.Conn) (err error) {
+func HideHighlight(conn hc.CommandRunner) (err error) {
 	cmd := NewHideHighlightCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HideHighlightCB func(err error)
 
 // Hides DOM node highlight.
-
 type AsyncHideHighlightCommand struct {
-	cb HideHighlightCB
+	cb   HideHighlightCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHideHighlightCommand(cb HideHighlightCB) *AsyncHideHighlightCommand {
 	return &AsyncHideHighlightCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2067,13 +2643,34 @@ func (cmd *HideHighlightCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHideHighlightCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHideHighlightCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HideHighlightCommand) Send(conn hc.CommandRunner) *AsyncHideHighlightCommand {
+	async := NewAsyncHideHighlightCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type HighlightFrameParams struct {
-	FrameId             *FrameId `json:"frameId"`                       // Identifier of the frame to highlight.
-	ContentColor        *RGBA    `json:"contentColor,omitempty"`        // The content box highlight fill color (default: transparent).
-	ContentOutlineColor *RGBA    `json:"contentOutlineColor,omitempty"` // The content box highlight outline color (default: transparent).
+	FrameId             FrameId `json:"frameId"`                       // Identifier of the frame to highlight.
+	ContentColor        *RGBA   `json:"contentColor,omitempty"`        // The content box highlight fill color (default: transparent).
+	ContentOutlineColor *RGBA   `json:"contentOutlineColor,omitempty"` // The content box highlight outline color (default: transparent).
 }
 
 // Highlights owner element of the frame with given id.
@@ -2098,17 +2695,18 @@ func (cmd *HighlightFrameCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *HighlightFrameCommand) Run(conn *hc.Conn) error {
+func (cmd *HighlightFrameCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HighlightFrame(params *HighlightFrameParams, conn *hc.Conn) (err error) {
+func HighlightFrame(params *HighlightFrameParams, conn hc.CommandRunner) (err error) {
 	cmd := NewHighlightFrameCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HighlightFrameCB func(err error)
@@ -2118,12 +2716,16 @@ type HighlightFrameCB func(err error)
 type AsyncHighlightFrameCommand struct {
 	params *HighlightFrameParams
 	cb     HighlightFrameCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHighlightFrameCommand(params *HighlightFrameParams, cb HighlightFrameCB) *AsyncHighlightFrameCommand {
 	return &AsyncHighlightFrameCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2141,7 +2743,28 @@ func (cmd *HighlightFrameCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHighlightFrameCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHighlightFrameCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HighlightFrameCommand) Send(conn hc.CommandRunner) *AsyncHighlightFrameCommand {
+	async := NewAsyncHighlightFrameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PushNodeByPathToFrontendParams struct {
@@ -2175,17 +2798,21 @@ func (cmd *PushNodeByPathToFrontendCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PushNodeByPathToFrontendCommand) Run(conn *hc.Conn) error {
+func (cmd *PushNodeByPathToFrontendCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PushNodeByPathToFrontend(params *PushNodeByPathToFrontendParams, conn *hc.Conn) (result *PushNodeByPathToFrontendResult, err error) {
+func PushNodeByPathToFrontend(params *PushNodeByPathToFrontendParams, conn hc.CommandRunner) (result *PushNodeByPathToFrontendResult, err error) {
 	cmd := NewPushNodeByPathToFrontendCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type PushNodeByPathToFrontendCB func(result *PushNodeByPathToFrontendResult, err error)
@@ -2195,12 +2822,17 @@ type PushNodeByPathToFrontendCB func(result *PushNodeByPathToFrontendResult, err
 type AsyncPushNodeByPathToFrontendCommand struct {
 	params *PushNodeByPathToFrontendParams
 	cb     PushNodeByPathToFrontendCB
+	result *PushNodeByPathToFrontendResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPushNodeByPathToFrontendCommand(params *PushNodeByPathToFrontendParams, cb PushNodeByPathToFrontendCB) *AsyncPushNodeByPathToFrontendCommand {
 	return &AsyncPushNodeByPathToFrontendCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2229,15 +2861,33 @@ func (cmd *AsyncPushNodeByPathToFrontendCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPushNodeByPathToFrontendCommand) Wait(ctx context.Context) (*PushNodeByPathToFrontendResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PushNodeByPathToFrontendCommand) Send(conn hc.CommandRunner) *AsyncPushNodeByPathToFrontendCommand {
+	async := NewAsyncPushNodeByPathToFrontendCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type PushNodesByBackendIdsToFrontendParams struct {
 	BackendNodeIds []BackendNodeId `json:"backendNodeIds"` // The array of backend node ids.
 }
@@ -2269,17 +2919,21 @@ func (cmd *PushNodesByBackendIdsToFrontendCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PushNodesByBackendIdsToFrontendCommand) Run(conn *hc.Conn) error {
+func (cmd *PushNodesByBackendIdsToFrontendCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PushNodesByBackendIdsToFrontend(params *PushNodesByBackendIdsToFrontendParams, conn *hc.Conn) (result *PushNodesByBackendIdsToFrontendResult, err error) {
+func PushNodesByBackendIdsToFrontend(params *PushNodesByBackendIdsToFrontendParams, conn hc.CommandRunner) (result *PushNodesByBackendIdsToFrontendResult, err error) {
 	cmd := NewPushNodesByBackendIdsToFrontendCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type PushNodesByBackendIdsToFrontendCB func(result *PushNodesByBackendIdsToFrontendResult, err error)
@@ -2289,12 +2943,17 @@ type PushNodesByBackendIdsToFrontendCB func(result *PushNodesByBackendIdsToFront
 type AsyncPushNodesByBackendIdsToFrontendCommand struct {
 	params *PushNodesByBackendIdsToFrontendParams
 	cb     PushNodesByBackendIdsToFrontendCB
+	result *PushNodesByBackendIdsToFrontendResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPushNodesByBackendIdsToFrontendCommand(params *PushNodesByBackendIdsToFrontendParams, cb PushNodesByBackendIdsToFrontendCB) *AsyncPushNodesByBackendIdsToFrontendCommand {
 	return &AsyncPushNodesByBackendIdsToFrontendCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2323,15 +2982,33 @@ func (cmd *AsyncPushNodesByBackendIdsToFrontendCommand) Done(data []byte, err er
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPushNodesByBackendIdsToFrontendCommand) Wait(ctx context.Context) (*PushNodesByBackendIdsToFrontendResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PushNodesByBackendIdsToFrontendCommand) Send(conn hc.CommandRunner) *AsyncPushNodesByBackendIdsToFrontendCommand {
+	async := NewAsyncPushNodesByBackendIdsToFrontendCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetInspectedNodeParams struct {
 	NodeId NodeId `json:"nodeId"` // DOM node id to be accessible by means of $x command line API.
 }
@@ -2358,17 +3035,18 @@ func (cmd *SetInspectedNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetInspectedNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *SetInspectedNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetInspectedNode(params *SetInspectedNodeParams, conn *hc.Conn) (err error) {
+func SetInspectedNode(params *SetInspectedNodeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetInspectedNodeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetInspectedNodeCB func(err error)
@@ -2378,12 +3056,16 @@ type SetInspectedNodeCB func(err error)
 type AsyncSetInspectedNodeCommand struct {
 	params *SetInspectedNodeParams
 	cb     SetInspectedNodeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetInspectedNodeCommand(params *SetInspectedNodeParams, cb SetInspectedNodeCB) *AsyncSetInspectedNodeCommand {
 	return &AsyncSetInspectedNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2401,7 +3083,28 @@ func (cmd *SetInspectedNodeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetInspectedNodeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetInspectedNodeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetInspectedNodeCommand) Send(conn hc.CommandRunner) *AsyncSetInspectedNodeCommand {
+	async := NewAsyncSetInspectedNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ResolveNodeParams struct {
@@ -2414,7 +3117,6 @@ type ResolveNodeResult struct {
 }
 
 // Resolves JavaScript node object for given node id.
-
 type ResolveNodeCommand struct {
 	params *ResolveNodeParams
 	result ResolveNodeResult
@@ -2436,32 +3138,40 @@ func (cmd *ResolveNodeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ResolveNodeCommand) Run(conn *hc.Conn) error {
+func (cmd *ResolveNodeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ResolveNode(params *ResolveNodeParams, conn *hc.Conn) (result *ResolveNodeResult, err error) {
+func ResolveNode(params *ResolveNodeParams, conn hc.CommandRunner) (result *ResolveNodeResult, err error) {
 	cmd := NewResolveNodeCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type ResolveNodeCB func(result *ResolveNodeResult, err error)
 
 // Resolves JavaScript node object for given node id.
-
 type AsyncResolveNodeCommand struct {
 	params *ResolveNodeParams
 	cb     ResolveNodeCB
+	result *ResolveNodeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncResolveNodeCommand(params *ResolveNodeParams, cb ResolveNodeCB) *AsyncResolveNodeCommand {
 	return &AsyncResolveNodeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2490,15 +3200,33 @@ func (cmd *AsyncResolveNodeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncResolveNodeCommand) Wait(ctx context.Context) (*ResolveNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ResolveNodeCommand) Send(conn hc.CommandRunner) *AsyncResolveNodeCommand {
+	async := NewAsyncResolveNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetAttributesParams struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node to retrieve attibutes for.
 }
@@ -2508,7 +3236,6 @@ type GetAttributesResult struct {
 }
 
 // Returns attributes for the specified node.
-
 type GetAttributesCommand struct {
 	params *GetAttributesParams
 	result GetAttributesResult
@@ -2530,32 +3257,40 @@ func (cmd *GetAttributesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetAttributesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetAttributesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetAttributes(params *GetAttributesParams, conn *hc.Conn) (result *GetAttributesResult, err error) {
+func GetAttributes(params *GetAttributesParams, conn hc.CommandRunner) (result *GetAttributesResult, err error) {
 	cmd := NewGetAttributesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetAttributesCB func(result *GetAttributesResult, err error)
 
 // Returns attributes for the specified node.
-
 type AsyncGetAttributesCommand struct {
 	params *GetAttributesParams
 	cb     GetAttributesCB
+	result *GetAttributesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetAttributesCommand(params *GetAttributesParams, cb GetAttributesCB) *AsyncGetAttributesCommand {
 	return &AsyncGetAttributesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2584,15 +3319,33 @@ func (cmd *AsyncGetAttributesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetAttributesCommand) Wait(ctx context.Context) (*GetAttributesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetAttributesCommand) Send(conn hc.CommandRunner) *AsyncGetAttributesCommand {
+	async := NewAsyncGetAttributesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CopyToParams struct {
 	NodeId             NodeId `json:"nodeId"`                       // Id of the node to copy.
 	TargetNodeId       NodeId `json:"targetNodeId"`                 // Id of the element to drop the copy into.
@@ -2626,17 +3379,21 @@ func (cmd *CopyToCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CopyToCommand) Run(conn *hc.Conn) error {
+func (cmd *CopyToCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CopyTo(params *CopyToParams, conn *hc.Conn) (result *CopyToResult, err error) {
+func CopyTo(params *CopyToParams, conn hc.CommandRunner) (result *CopyToResult, err error) {
 	cmd := NewCopyToCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CopyToCB func(result *CopyToResult, err error)
@@ -2646,12 +3403,17 @@ type CopyToCB func(result *CopyToResult, err error)
 type AsyncCopyToCommand struct {
 	params *CopyToParams
 	cb     CopyToCB
+	result *CopyToResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCopyToCommand(params *CopyToParams, cb CopyToCB) *AsyncCopyToCommand {
 	return &AsyncCopyToCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2680,15 +3442,33 @@ func (cmd *AsyncCopyToCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCopyToCommand) Wait(ctx context.Context) (*CopyToResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CopyToCommand) Send(conn hc.CommandRunner) *AsyncCopyToCommand {
+	async := NewAsyncCopyToCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type MoveToParams struct {
 	NodeId             NodeId `json:"nodeId"`                       // Id of the node to move.
 	TargetNodeId       NodeId `json:"targetNodeId"`                 // Id of the element to drop the moved node into.
@@ -2700,7 +3480,6 @@ type MoveToResult struct {
 }
 
 // Moves node into the new container, places it before the given anchor.
-
 type MoveToCommand struct {
 	params *MoveToParams
 	result MoveToResult
@@ -2722,32 +3501,40 @@ func (cmd *MoveToCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *MoveToCommand) Run(conn *hc.Conn) error {
+func (cmd *MoveToCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func MoveTo(params *MoveToParams, conn *hc.Conn) (result *MoveToResult, err error) {
+func MoveTo(params *MoveToParams, conn hc.CommandRunner) (result *MoveToResult, err error) {
 	cmd := NewMoveToCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type MoveToCB func(result *MoveToResult, err error)
 
 // Moves node into the new container, places it before the given anchor.
-
 type AsyncMoveToCommand struct {
 	params *MoveToParams
 	cb     MoveToCB
+	result *MoveToResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncMoveToCommand(params *MoveToParams, cb MoveToCB) *AsyncMoveToCommand {
 	return &AsyncMoveToCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2776,13 +3563,31 @@ func (cmd *AsyncMoveToCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncMoveToCommand) Wait(ctx context.Context) (*MoveToResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *MoveToCommand) Send(conn hc.CommandRunner) *AsyncMoveToCommand {
+	async := NewAsyncMoveToCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Undoes the last performed action.
@@ -2804,17 +3609,18 @@ func (cmd *UndoCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *UndoCommand) Run(conn *hc.Conn) error {
+func (cmd *UndoCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Undo(conn *hc.Conn) (err error) {
+func Undo(conn hc.CommandRunner) (err error) {
 	cmd := NewUndoCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type UndoCB func(err error)
@@ -2822,12 +3628,16 @@ type UndoCB func(err error)
 // Undoes the last performed action.
 // @experimental
 type AsyncUndoCommand struct {
-	cb UndoCB
+	cb   UndoCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncUndoCommand(cb UndoCB) *AsyncUndoCommand {
 	return &AsyncUndoCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2845,7 +3655,28 @@ func (cmd *UndoCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncUndoCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncUndoCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *UndoCommand) Send(conn hc.CommandRunner) *AsyncUndoCommand {
+	async := NewAsyncUndoCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Re-does the last undone action.
@@ -2867,17 +3698,18 @@ func (cmd *RedoCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *RedoCommand) Run(conn *hc.Conn) error {
+func (cmd *RedoCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Redo(conn *hc.Conn) (err error) {
+func Redo(conn hc.CommandRunner) (err error) {
 	cmd := NewRedoCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RedoCB func(err error)
@@ -2885,12 +3717,16 @@ type RedoCB func(err error)
 // Re-does the last undone action.
 // @experimental
 type AsyncRedoCommand struct {
-	cb RedoCB
+	cb   RedoCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRedoCommand(cb RedoCB) *AsyncRedoCommand {
 	return &AsyncRedoCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2908,7 +3744,28 @@ func (cmd *RedoCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRedoCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRedoCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RedoCommand) Send(conn hc.CommandRunner) *AsyncRedoCommand {
+	async := NewAsyncRedoCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Marks last undoable state.
@@ -2930,17 +3787,18 @@ func (cmd *MarkUndoableStateCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *MarkUndoableStateCommand) Run(conn *hc.Conn) error {
+func (cmd *MarkUndoableStateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func MarkUndoableState(conn *hc.Conn) (err error) {
+func MarkUndoableState(conn hc.CommandRunner) (err error) {
 	cmd := NewMarkUndoableStateCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type MarkUndoableStateCB func(err error)
@@ -2948,12 +3806,16 @@ type MarkUndoableStateCB func(err error)
 // Marks last undoable state.
 // @experimental
 type AsyncMarkUndoableStateCommand struct {
-	cb MarkUndoableStateCB
+	cb   MarkUndoableStateCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncMarkUndoableStateCommand(cb MarkUndoableStateCB) *AsyncMarkUndoableStateCommand {
 	return &AsyncMarkUndoableStateCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2971,7 +3833,28 @@ func (cmd *MarkUndoableStateCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncMarkUndoableStateCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncMarkUndoableStateCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *MarkUndoableStateCommand) Send(conn hc.CommandRunner) *AsyncMarkUndoableStateCommand {
+	async := NewAsyncMarkUndoableStateCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type FocusParams struct {
@@ -3000,17 +3883,18 @@ func (cmd *FocusCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *FocusCommand) Run(conn *hc.Conn) error {
+func (cmd *FocusCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Focus(params *FocusParams, conn *hc.Conn) (err error) {
+func Focus(params *FocusParams, conn hc.CommandRunner) (err error) {
 	cmd := NewFocusCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type FocusCB func(err error)
@@ -3020,12 +3904,16 @@ type FocusCB func(err error)
 type AsyncFocusCommand struct {
 	params *FocusParams
 	cb     FocusCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncFocusCommand(params *FocusParams, cb FocusCB) *AsyncFocusCommand {
 	return &AsyncFocusCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -3043,7 +3931,28 @@ func (cmd *FocusCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncFocusCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncFocusCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *FocusCommand) Send(conn hc.CommandRunner) *AsyncFocusCommand {
+	async := NewAsyncFocusCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetFileInputFilesParams struct {
@@ -3073,17 +3982,18 @@ func (cmd *SetFileInputFilesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetFileInputFilesCommand) Run(conn *hc.Conn) error {
+func (cmd *SetFileInputFilesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetFileInputFiles(params *SetFileInputFilesParams, conn *hc.Conn) (err error) {
+func SetFileInputFiles(params *SetFileInputFilesParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetFileInputFilesCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetFileInputFilesCB func(err error)
@@ -3093,12 +4003,16 @@ type SetFileInputFilesCB func(err error)
 type AsyncSetFileInputFilesCommand struct {
 	params *SetFileInputFilesParams
 	cb     SetFileInputFilesCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetFileInputFilesCommand(params *SetFileInputFilesParams, cb SetFileInputFilesCB) *AsyncSetFileInputFilesCommand {
 	return &AsyncSetFileInputFilesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -3116,7 +4030,28 @@ func (cmd *SetFileInputFilesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetFileInputFilesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetFileInputFilesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetFileInputFilesCommand) Send(conn hc.CommandRunner) *AsyncSetFileInputFilesCommand {
+	async := NewAsyncSetFileInputFilesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetBoxModelParams struct {
@@ -3150,17 +4085,21 @@ func (cmd *GetBoxModelCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetBoxModelCommand) Run(conn *hc.Conn) error {
+func (cmd *GetBoxModelCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetBoxModel(params *GetBoxModelParams, conn *hc.Conn) (result *GetBoxModelResult, err error) {
+func GetBoxModel(params *GetBoxModelParams, conn hc.CommandRunner) (result *GetBoxModelResult, err error) {
 	cmd := NewGetBoxModelCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetBoxModelCB func(result *GetBoxModelResult, err error)
@@ -3170,12 +4109,17 @@ type GetBoxModelCB func(result *GetBoxModelResult, err error)
 type AsyncGetBoxModelCommand struct {
 	params *GetBoxModelParams
 	cb     GetBoxModelCB
+	result *GetBoxModelResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetBoxModelCommand(params *GetBoxModelParams, cb GetBoxModelCB) *AsyncGetBoxModelCommand {
 	return &AsyncGetBoxModelCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -3204,15 +4148,33 @@ func (cmd *AsyncGetBoxModelCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetBoxModelCommand) Wait(ctx context.Context) (*GetBoxModelResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetBoxModelCommand) Send(conn hc.CommandRunner) *AsyncGetBoxModelCommand {
+	async := NewAsyncGetBoxModelCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetNodeForLocationParams struct {
 	X int `json:"x"` // X coordinate.
 	Y int `json:"y"` // Y coordinate.
@@ -3245,17 +4207,21 @@ func (cmd *GetNodeForLocationCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetNodeForLocationCommand) Run(conn *hc.Conn) error {
+func (cmd *GetNodeForLocationCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetNodeForLocation(params *GetNodeForLocationParams, conn *hc.Conn) (result *GetNodeForLocationResult, err error) {
+func GetNodeForLocation(params *GetNodeForLocationParams, conn hc.CommandRunner) (result *GetNodeForLocationResult, err error) {
 	cmd := NewGetNodeForLocationCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetNodeForLocationCB func(result *GetNodeForLocationResult, err error)
@@ -3265,12 +4231,17 @@ type GetNodeForLocationCB func(result *GetNodeForLocationResult, err error)
 type AsyncGetNodeForLocationCommand struct {
 	params *GetNodeForLocationParams
 	cb     GetNodeForLocationCB
+	result *GetNodeForLocationResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetNodeForLocationCommand(params *GetNodeForLocationParams, cb GetNodeForLocationCB) *AsyncGetNodeForLocationCommand {
 	return &AsyncGetNodeForLocationCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -3299,15 +4270,33 @@ func (cmd *AsyncGetNodeForLocationCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetNodeForLocationCommand) Wait(ctx context.Context) (*GetNodeForLocationResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetNodeForLocationCommand) Send(conn hc.CommandRunner) *AsyncGetNodeForLocationCommand {
+	async := NewAsyncGetNodeForLocationCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetRelayoutBoundaryParams struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node.
 }
@@ -3339,17 +4328,21 @@ func (cmd *GetRelayoutBoundaryCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetRelayoutBoundaryCommand) Run(conn *hc.Conn) error {
+func (cmd *GetRelayoutBoundaryCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetRelayoutBoundary(params *GetRelayoutBoundaryParams, conn *hc.Conn) (result *GetRelayoutBoundaryResult, err error) {
+func GetRelayoutBoundary(params *GetRelayoutBoundaryParams, conn hc.CommandRunner) (result *GetRelayoutBoundaryResult, err error) {
 	cmd := NewGetRelayoutBoundaryCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetRelayoutBoundaryCB func(result *GetRelayoutBoundaryResult, err error)
@@ -3359,12 +4352,17 @@ type GetRelayoutBoundaryCB func(result *GetRelayoutBoundaryResult, err error)
 type AsyncGetRelayoutBoundaryCommand struct {
 	params *GetRelayoutBoundaryParams
 	cb     GetRelayoutBoundaryCB
+	result *GetRelayoutBoundaryResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetRelayoutBoundaryCommand(params *GetRelayoutBoundaryParams, cb GetRelayoutBoundaryCB) *AsyncGetRelayoutBoundaryCommand {
 	return &AsyncGetRelayoutBoundaryCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -3393,21 +4391,39 @@ func (cmd *AsyncGetRelayoutBoundaryCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetRelayoutBoundaryCommand) Wait(ctx context.Context) (*GetRelayoutBoundaryResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetRelayoutBoundaryCommand) Send(conn hc.CommandRunner) *AsyncGetRelayoutBoundaryCommand {
+	async := NewAsyncGetRelayoutBoundaryCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetHighlightObjectForTestParams struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node to get highlight object for.
 }
 
 type GetHighlightObjectForTestResult struct {
-	Highlight map[string]string `json:"highlight"` // Highlight data for the node.
+	Highlight json.RawMessage `json:"highlight"` // Highlight data for the node.
 }
 
 // For testing.
@@ -3433,17 +4449,21 @@ func (cmd *GetHighlightObjectForTestCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetHighlightObjectForTestCommand) Run(conn *hc.Conn) error {
+func (cmd *GetHighlightObjectForTestCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetHighlightObjectForTest(params *GetHighlightObjectForTestParams, conn *hc.Conn) (result *GetHighlightObjectForTestResult, err error) {
+func GetHighlightObjectForTest(params *GetHighlightObjectForTestParams, conn hc.CommandRunner) (result *GetHighlightObjectForTestResult, err error) {
 	cmd := NewGetHighlightObjectForTestCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetHighlightObjectForTestCB func(result *GetHighlightObjectForTestResult, err error)
@@ -3453,12 +4473,17 @@ type GetHighlightObjectForTestCB func(result *GetHighlightObjectForTestResult, e
 type AsyncGetHighlightObjectForTestCommand struct {
 	params *GetHighlightObjectForTestParams
 	cb     GetHighlightObjectForTestCB
+	result *GetHighlightObjectForTestResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetHighlightObjectForTestCommand(params *GetHighlightObjectForTestParams, cb GetHighlightObjectForTestCB) *AsyncGetHighlightObjectForTestCommand {
 	return &AsyncGetHighlightObjectForTestCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -3487,30 +4512,63 @@ func (cmd *AsyncGetHighlightObjectForTestCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetHighlightObjectForTestCommand) Wait(ctx context.Context) (*GetHighlightObjectForTestResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
-// Fired when Document has been totally updated. Node ids are no longer valid.
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetHighlightObjectForTestCommand) Send(conn hc.CommandRunner) *AsyncGetHighlightObjectForTestCommand {
+	async := NewAsyncGetHighlightObjectForTestCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Fired when Document has been totally updated. Node ids are no longer valid.
 type DocumentUpdatedEvent struct {
 }
 
-func OnDocumentUpdated(conn *hc.Conn, cb func(evt *DocumentUpdatedEvent)) {
+// Calls cb for each DOM.documentUpdated event, till the returned func is called.
+func OnDocumentUpdated(conn hc.EventSource, cb func(evt *DocumentUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DocumentUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.documentUpdated", sink)
+	return func() { conn.RemoveEventSink("DOM.documentUpdated", sink) }
+}
+
+// Like OnDocumentUpdated, but cb also gets hc.EventMeta.
+func OnDocumentUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DocumentUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DocumentUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.documentUpdated", sink)
+	return func() { conn.RemoveEventSink("DOM.documentUpdated", sink) }
 }
 
 // Fired when the node should be inspected. This happens after call to setInspectMode.
@@ -3519,74 +4577,135 @@ type InspectNodeRequestedEvent struct {
 	BackendNodeId BackendNodeId `json:"backendNodeId"` // Id of the node to inspect.
 }
 
-func OnInspectNodeRequested(conn *hc.Conn, cb func(evt *InspectNodeRequestedEvent)) {
+// Calls cb for each DOM.inspectNodeRequested event, till the returned func is called.
+func OnInspectNodeRequested(conn hc.EventSource, cb func(evt *InspectNodeRequestedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &InspectNodeRequestedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.inspectNodeRequested", sink)
+	return func() { conn.RemoveEventSink("DOM.inspectNodeRequested", sink) }
 }
 
-// Fired when backend wants to provide client with the missing DOM structure. This happens upon most of the calls requesting node ids.
+// Like OnInspectNodeRequested, but cb also gets hc.EventMeta.
+func OnInspectNodeRequestedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *InspectNodeRequestedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &InspectNodeRequestedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.inspectNodeRequested", sink)
+	return func() { conn.RemoveEventSink("DOM.inspectNodeRequested", sink) }
+}
 
+// Fired when backend wants to provide client with the missing DOM structure. This happens upon most of the calls requesting node ids.
 type SetChildNodesEvent struct {
 	ParentId NodeId  `json:"parentId"` // Parent node id to populate with children.
 	Nodes    []*Node `json:"nodes"`    // Child nodes array.
 }
 
-func OnSetChildNodes(conn *hc.Conn, cb func(evt *SetChildNodesEvent)) {
+// Calls cb for each DOM.setChildNodes event, till the returned func is called.
+func OnSetChildNodes(conn hc.EventSource, cb func(evt *SetChildNodesEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &SetChildNodesEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.setChildNodes", sink)
+	return func() { conn.RemoveEventSink("DOM.setChildNodes", sink) }
 }
 
-// Fired when Element's attribute is modified.
+// Like OnSetChildNodes, but cb also gets hc.EventMeta.
+func OnSetChildNodesMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *SetChildNodesEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &SetChildNodesEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.setChildNodes", sink)
+	return func() { conn.RemoveEventSink("DOM.setChildNodes", sink) }
+}
 
+// Fired when Element's attribute is modified.
 type AttributeModifiedEvent struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node that has changed.
 	Name   string `json:"name"`   // Attribute name.
 	Value  string `json:"value"`  // Attribute value.
 }
 
-func OnAttributeModified(conn *hc.Conn, cb func(evt *AttributeModifiedEvent)) {
+// Calls cb for each DOM.attributeModified event, till the returned func is called.
+func OnAttributeModified(conn hc.EventSource, cb func(evt *AttributeModifiedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AttributeModifiedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.attributeModified", sink)
+	return func() { conn.RemoveEventSink("DOM.attributeModified", sink) }
 }
 
-// Fired when Element's attribute is removed.
+// Like OnAttributeModified, but cb also gets hc.EventMeta.
+func OnAttributeModifiedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AttributeModifiedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AttributeModifiedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.attributeModified", sink)
+	return func() { conn.RemoveEventSink("DOM.attributeModified", sink) }
+}
 
+// Fired when Element's attribute is removed.
 type AttributeRemovedEvent struct {
 	NodeId NodeId `json:"nodeId"` // Id of the node that has changed.
 	Name   string `json:"name"`   // A ttribute name.
 }
 
-func OnAttributeRemoved(conn *hc.Conn, cb func(evt *AttributeRemovedEvent)) {
+// Calls cb for each DOM.attributeRemoved event, till the returned func is called.
+func OnAttributeRemoved(conn hc.EventSource, cb func(evt *AttributeRemovedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AttributeRemovedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.attributeRemoved", sink)
+	return func() { conn.RemoveEventSink("DOM.attributeRemoved", sink) }
+}
+
+// Like OnAttributeRemoved, but cb also gets hc.EventMeta.
+func OnAttributeRemovedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AttributeRemovedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AttributeRemovedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.attributeRemoved", sink)
+	return func() { conn.RemoveEventSink("DOM.attributeRemoved", sink) }
 }
 
 // Fired when Element's inline style is modified via a CSS property modification.
@@ -3595,93 +4714,169 @@ type InlineStyleInvalidatedEvent struct {
 	NodeIds []NodeId `json:"nodeIds"` // Ids of the nodes for which the inline styles have been invalidated.
 }
 
-func OnInlineStyleInvalidated(conn *hc.Conn, cb func(evt *InlineStyleInvalidatedEvent)) {
+// Calls cb for each DOM.inlineStyleInvalidated event, till the returned func is called.
+func OnInlineStyleInvalidated(conn hc.EventSource, cb func(evt *InlineStyleInvalidatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &InlineStyleInvalidatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.inlineStyleInvalidated", sink)
+	return func() { conn.RemoveEventSink("DOM.inlineStyleInvalidated", sink) }
 }
 
-// Mirrors DOMCharacterDataModified event.
+// Like OnInlineStyleInvalidated, but cb also gets hc.EventMeta.
+func OnInlineStyleInvalidatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *InlineStyleInvalidatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &InlineStyleInvalidatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.inlineStyleInvalidated", sink)
+	return func() { conn.RemoveEventSink("DOM.inlineStyleInvalidated", sink) }
+}
 
+// Mirrors DOMCharacterDataModified event.
 type CharacterDataModifiedEvent struct {
 	NodeId        NodeId `json:"nodeId"`        // Id of the node that has changed.
 	CharacterData string `json:"characterData"` // New text value.
 }
 
-func OnCharacterDataModified(conn *hc.Conn, cb func(evt *CharacterDataModifiedEvent)) {
+// Calls cb for each DOM.characterDataModified event, till the returned func is called.
+func OnCharacterDataModified(conn hc.EventSource, cb func(evt *CharacterDataModifiedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &CharacterDataModifiedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.characterDataModified", sink)
+	return func() { conn.RemoveEventSink("DOM.characterDataModified", sink) }
 }
 
-// Fired when Container's child node count has changed.
+// Like OnCharacterDataModified, but cb also gets hc.EventMeta.
+func OnCharacterDataModifiedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *CharacterDataModifiedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &CharacterDataModifiedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.characterDataModified", sink)
+	return func() { conn.RemoveEventSink("DOM.characterDataModified", sink) }
+}
 
+// Fired when Container's child node count has changed.
 type ChildNodeCountUpdatedEvent struct {
 	NodeId         NodeId `json:"nodeId"`         // Id of the node that has changed.
 	ChildNodeCount int    `json:"childNodeCount"` // New node count.
 }
 
-func OnChildNodeCountUpdated(conn *hc.Conn, cb func(evt *ChildNodeCountUpdatedEvent)) {
+// Calls cb for each DOM.childNodeCountUpdated event, till the returned func is called.
+func OnChildNodeCountUpdated(conn hc.EventSource, cb func(evt *ChildNodeCountUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ChildNodeCountUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.childNodeCountUpdated", sink)
+	return func() { conn.RemoveEventSink("DOM.childNodeCountUpdated", sink) }
 }
 
-// Mirrors DOMNodeInserted event.
+// Like OnChildNodeCountUpdated, but cb also gets hc.EventMeta.
+func OnChildNodeCountUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ChildNodeCountUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ChildNodeCountUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.childNodeCountUpdated", sink)
+	return func() { conn.RemoveEventSink("DOM.childNodeCountUpdated", sink) }
+}
 
+// Mirrors DOMNodeInserted event.
 type ChildNodeInsertedEvent struct {
 	ParentNodeId   NodeId `json:"parentNodeId"`   // Id of the node that has changed.
 	PreviousNodeId NodeId `json:"previousNodeId"` // If of the previous siblint.
 	Node           *Node  `json:"node"`           // Inserted node data.
 }
 
-func OnChildNodeInserted(conn *hc.Conn, cb func(evt *ChildNodeInsertedEvent)) {
+// Calls cb for each DOM.childNodeInserted event, till the returned func is called.
+func OnChildNodeInserted(conn hc.EventSource, cb func(evt *ChildNodeInsertedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ChildNodeInsertedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.childNodeInserted", sink)
+	return func() { conn.RemoveEventSink("DOM.childNodeInserted", sink) }
 }
 
-// Mirrors DOMNodeRemoved event.
+// Like OnChildNodeInserted, but cb also gets hc.EventMeta.
+func OnChildNodeInsertedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ChildNodeInsertedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ChildNodeInsertedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.childNodeInserted", sink)
+	return func() { conn.RemoveEventSink("DOM.childNodeInserted", sink) }
+}
 
+// Mirrors DOMNodeRemoved event.
 type ChildNodeRemovedEvent struct {
 	ParentNodeId NodeId `json:"parentNodeId"` // Parent id.
 	NodeId       NodeId `json:"nodeId"`       // Id of the node that has been removed.
 }
 
-func OnChildNodeRemoved(conn *hc.Conn, cb func(evt *ChildNodeRemovedEvent)) {
+// Calls cb for each DOM.childNodeRemoved event, till the returned func is called.
+func OnChildNodeRemoved(conn hc.EventSource, cb func(evt *ChildNodeRemovedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ChildNodeRemovedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.childNodeRemoved", sink)
+	return func() { conn.RemoveEventSink("DOM.childNodeRemoved", sink) }
+}
+
+// Like OnChildNodeRemoved, but cb also gets hc.EventMeta.
+func OnChildNodeRemovedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ChildNodeRemovedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ChildNodeRemovedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.childNodeRemoved", sink)
+	return func() { conn.RemoveEventSink("DOM.childNodeRemoved", sink) }
 }
 
 // Called when shadow root is pushed into the element.
@@ -3691,16 +4886,32 @@ type ShadowRootPushedEvent struct {
 	Root   *Node  `json:"root"`   // Shadow root.
 }
 
-func OnShadowRootPushed(conn *hc.Conn, cb func(evt *ShadowRootPushedEvent)) {
+// Calls cb for each DOM.shadowRootPushed event, till the returned func is called.
+func OnShadowRootPushed(conn hc.EventSource, cb func(evt *ShadowRootPushedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ShadowRootPushedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.shadowRootPushed", sink)
+	return func() { conn.RemoveEventSink("DOM.shadowRootPushed", sink) }
+}
+
+// Like OnShadowRootPushed, but cb also gets hc.EventMeta.
+func OnShadowRootPushedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ShadowRootPushedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ShadowRootPushedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.shadowRootPushed", sink)
+	return func() { conn.RemoveEventSink("DOM.shadowRootPushed", sink) }
 }
 
 // Called when shadow root is popped from the element.
@@ -3710,16 +4921,32 @@ type ShadowRootPoppedEvent struct {
 	RootId NodeId `json:"rootId"` // Shadow root id.
 }
 
-func OnShadowRootPopped(conn *hc.Conn, cb func(evt *ShadowRootPoppedEvent)) {
+// Calls cb for each DOM.shadowRootPopped event, till the returned func is called.
+func OnShadowRootPopped(conn hc.EventSource, cb func(evt *ShadowRootPoppedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ShadowRootPoppedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.shadowRootPopped", sink)
+	return func() { conn.RemoveEventSink("DOM.shadowRootPopped", sink) }
+}
+
+// Like OnShadowRootPopped, but cb also gets hc.EventMeta.
+func OnShadowRootPoppedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ShadowRootPoppedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ShadowRootPoppedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.shadowRootPopped", sink)
+	return func() { conn.RemoveEventSink("DOM.shadowRootPopped", sink) }
 }
 
 // Called when a pseudo element is added to an element.
@@ -3729,16 +4956,32 @@ type PseudoElementAddedEvent struct {
 	PseudoElement *Node  `json:"pseudoElement"` // The added pseudo element.
 }
 
-func OnPseudoElementAdded(conn *hc.Conn, cb func(evt *PseudoElementAddedEvent)) {
+// Calls cb for each DOM.pseudoElementAdded event, till the returned func is called.
+func OnPseudoElementAdded(conn hc.EventSource, cb func(evt *PseudoElementAddedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &PseudoElementAddedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.pseudoElementAdded", sink)
+	return func() { conn.RemoveEventSink("DOM.pseudoElementAdded", sink) }
+}
+
+// Like OnPseudoElementAdded, but cb also gets hc.EventMeta.
+func OnPseudoElementAddedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *PseudoElementAddedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &PseudoElementAddedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.pseudoElementAdded", sink)
+	return func() { conn.RemoveEventSink("DOM.pseudoElementAdded", sink) }
 }
 
 // Called when a pseudo element is removed from an element.
@@ -3748,16 +4991,32 @@ type PseudoElementRemovedEvent struct {
 	PseudoElementId NodeId `json:"pseudoElementId"` // The removed pseudo element id.
 }
 
-func OnPseudoElementRemoved(conn *hc.Conn, cb func(evt *PseudoElementRemovedEvent)) {
+// Calls cb for each DOM.pseudoElementRemoved event, till the returned func is called.
+func OnPseudoElementRemoved(conn hc.EventSource, cb func(evt *PseudoElementRemovedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &PseudoElementRemovedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.pseudoElementRemoved", sink)
+	return func() { conn.RemoveEventSink("DOM.pseudoElementRemoved", sink) }
+}
+
+// Like OnPseudoElementRemoved, but cb also gets hc.EventMeta.
+func OnPseudoElementRemovedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *PseudoElementRemovedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &PseudoElementRemovedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.pseudoElementRemoved", sink)
+	return func() { conn.RemoveEventSink("DOM.pseudoElementRemoved", sink) }
 }
 
 // Called when distrubution is changed.
@@ -3767,16 +5026,32 @@ type DistributedNodesUpdatedEvent struct {
 	DistributedNodes []*BackendNode `json:"distributedNodes"` // Distributed nodes for given insertion point.
 }
 
-func OnDistributedNodesUpdated(conn *hc.Conn, cb func(evt *DistributedNodesUpdatedEvent)) {
+// Calls cb for each DOM.distributedNodesUpdated event, till the returned func is called.
+func OnDistributedNodesUpdated(conn hc.EventSource, cb func(evt *DistributedNodesUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DistributedNodesUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.distributedNodesUpdated", sink)
+	return func() { conn.RemoveEventSink("DOM.distributedNodesUpdated", sink) }
+}
+
+// Like OnDistributedNodesUpdated, but cb also gets hc.EventMeta.
+func OnDistributedNodesUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DistributedNodesUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DistributedNodesUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.distributedNodesUpdated", sink)
+	return func() { conn.RemoveEventSink("DOM.distributedNodesUpdated", sink) }
 }
 
 // @experimental
@@ -3784,14 +5059,30 @@ type NodeHighlightRequestedEvent struct {
 	NodeId NodeId `json:"nodeId"`
 }
 
-func OnNodeHighlightRequested(conn *hc.Conn, cb func(evt *NodeHighlightRequestedEvent)) {
+// Calls cb for each DOM.nodeHighlightRequested event, till the returned func is called.
+func OnNodeHighlightRequested(conn hc.EventSource, cb func(evt *NodeHighlightRequestedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &NodeHighlightRequestedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOM.nodeHighlightRequested", sink)
+	return func() { conn.RemoveEventSink("DOM.nodeHighlightRequested", sink) }
+}
+
+// Like OnNodeHighlightRequested, but cb also gets hc.EventMeta.
+func OnNodeHighlightRequestedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *NodeHighlightRequestedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &NodeHighlightRequestedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOM.nodeHighlightRequested", sink)
+	return func() { conn.RemoveEventSink("DOM.nodeHighlightRequested", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -21,7 +21,7 @@ type EventListener struct {
 	UseCapture      bool          `json:"useCapture"`                // EventListener's useCapture.
 	Passive         bool          `json:"passive"`                   // EventListener's passive flag.
 	Once            bool          `json:"once"`                      // EventListener's once flag.
-	ScriptId        *ScriptId     `json:"scriptId"`                  // Script id of the handler code.
+	ScriptId        ScriptId      `json:"scriptId"`                  // Script id of the handler code.
 	LineNumber      int           `json:"lineNumber"`                // Line number in the script (0-based).
 	ColumnNumber    int           `json:"columnNumber"`              // Column number in the script (0-based).
 	Handler         *RemoteObject `json:"handler,omitempty"`         // Event handler function value.
@@ -30,12 +30,11 @@ type EventListener struct {
 }
 
 type SetDOMBreakpointParams struct {
-	NodeId *NodeId           `json:"nodeId"` // Identifier of the node to set breakpoint on.
+	NodeId NodeId            `json:"nodeId"` // Identifier of the node to set breakpoint on.
 	Type   DOMBreakpointType `json:"type"`   // Type of the operation to stop upon.
 }
 
 // Sets breakpoint on particular operation with DOM.
-
 type SetDOMBreakpointCommand struct {
 	params *SetDOMBreakpointParams
 	wg     sync.WaitGroup
@@ -56,32 +55,36 @@ func (cmd *SetDOMBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetDOMBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *SetDOMBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetDOMBreakpoint(params *SetDOMBreakpointParams, conn *hc.Conn) (err error) {
+func SetDOMBreakpoint(params *SetDOMBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetDOMBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetDOMBreakpointCB func(err error)
 
 // Sets breakpoint on particular operation with DOM.
-
 type AsyncSetDOMBreakpointCommand struct {
 	params *SetDOMBreakpointParams
 	cb     SetDOMBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetDOMBreakpointCommand(params *SetDOMBreakpointParams, cb SetDOMBreakpointCB) *AsyncSetDOMBreakpointCommand {
 	return &AsyncSetDOMBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -99,16 +102,36 @@ func (cmd *SetDOMBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetDOMBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetDOMBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetDOMBreakpointCommand) Send(conn hc.CommandRunner) *AsyncSetDOMBreakpointCommand {
+	async := NewAsyncSetDOMBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveDOMBreakpointParams struct {
-	NodeId *NodeId           `json:"nodeId"` // Identifier of the node to remove breakpoint from.
+	NodeId NodeId            `json:"nodeId"` // Identifier of the node to remove breakpoint from.
 	Type   DOMBreakpointType `json:"type"`   // Type of the breakpoint to remove.
 }
 
 // Removes DOM breakpoint that was set using setDOMBreakpoint.
-
 type RemoveDOMBreakpointCommand struct {
 	params *RemoveDOMBreakpointParams
 	wg     sync.WaitGroup
@@ -129,32 +152,36 @@ func (cmd *RemoveDOMBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveDOMBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveDOMBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveDOMBreakpoint(params *RemoveDOMBreakpointParams, conn *hc.Conn) (err error) {
+func RemoveDOMBreakpoint(params *RemoveDOMBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveDOMBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveDOMBreakpointCB func(err error)
 
 // Removes DOM breakpoint that was set using setDOMBreakpoint.
-
 type AsyncRemoveDOMBreakpointCommand struct {
 	params *RemoveDOMBreakpointParams
 	cb     RemoveDOMBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveDOMBreakpointCommand(params *RemoveDOMBreakpointParams, cb RemoveDOMBreakpointCB) *AsyncRemoveDOMBreakpointCommand {
 	return &AsyncRemoveDOMBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -172,7 +199,28 @@ func (cmd *RemoveDOMBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveDOMBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveDOMBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveDOMBreakpointCommand) Send(conn hc.CommandRunner) *AsyncRemoveDOMBreakpointCommand {
+	async := NewAsyncRemoveDOMBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetEventListenerBreakpointParams struct {
@@ -181,7 +229,6 @@ type SetEventListenerBreakpointParams struct {
 }
 
 // Sets breakpoint on particular DOM event.
-
 type SetEventListenerBreakpointCommand struct {
 	params *SetEventListenerBreakpointParams
 	wg     sync.WaitGroup
@@ -202,32 +249,36 @@ func (cmd *SetEventListenerBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetEventListenerBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *SetEventListenerBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetEventListenerBreakpoint(params *SetEventListenerBreakpointParams, conn *hc.Conn) (err error) {
+func SetEventListenerBreakpoint(params *SetEventListenerBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetEventListenerBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetEventListenerBreakpointCB func(err error)
 
 // Sets breakpoint on particular DOM event.
-
 type AsyncSetEventListenerBreakpointCommand struct {
 	params *SetEventListenerBreakpointParams
 	cb     SetEventListenerBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetEventListenerBreakpointCommand(params *SetEventListenerBreakpointParams, cb SetEventListenerBreakpointCB) *AsyncSetEventListenerBreakpointCommand {
 	return &AsyncSetEventListenerBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -245,7 +296,28 @@ func (cmd *SetEventListenerBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetEventListenerBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetEventListenerBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetEventListenerBreakpointCommand) Send(conn hc.CommandRunner) *AsyncSetEventListenerBreakpointCommand {
+	async := NewAsyncSetEventListenerBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveEventListenerBreakpointParams struct {
@@ -254,7 +326,6 @@ type RemoveEventListenerBreakpointParams struct {
 }
 
 // Removes breakpoint on particular DOM event.
-
 type RemoveEventListenerBreakpointCommand struct {
 	params *RemoveEventListenerBreakpointParams
 	wg     sync.WaitGroup
@@ -275,32 +346,36 @@ func (cmd *RemoveEventListenerBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveEventListenerBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveEventListenerBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveEventListenerBreakpoint(params *RemoveEventListenerBreakpointParams, conn *hc.Conn) (err error) {
+func RemoveEventListenerBreakpoint(params *RemoveEventListenerBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveEventListenerBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveEventListenerBreakpointCB func(err error)
 
 // Removes breakpoint on particular DOM event.
-
 type AsyncRemoveEventListenerBreakpointCommand struct {
 	params *RemoveEventListenerBreakpointParams
 	cb     RemoveEventListenerBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveEventListenerBreakpointCommand(params *RemoveEventListenerBreakpointParams, cb RemoveEventListenerBreakpointCB) *AsyncRemoveEventListenerBreakpointCommand {
 	return &AsyncRemoveEventListenerBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -318,7 +393,28 @@ func (cmd *RemoveEventListenerBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveEventListenerBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveEventListenerBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveEventListenerBreakpointCommand) Send(conn hc.CommandRunner) *AsyncRemoveEventListenerBreakpointCommand {
+	async := NewAsyncRemoveEventListenerBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetInstrumentationBreakpointParams struct {
@@ -347,17 +443,18 @@ func (cmd *SetInstrumentationBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetInstrumentationBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *SetInstrumentationBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetInstrumentationBreakpoint(params *SetInstrumentationBreakpointParams, conn *hc.Conn) (err error) {
+func SetInstrumentationBreakpoint(params *SetInstrumentationBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetInstrumentationBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetInstrumentationBreakpointCB func(err error)
@@ -367,12 +464,16 @@ type SetInstrumentationBreakpointCB func(err error)
 type AsyncSetInstrumentationBreakpointCommand struct {
 	params *SetInstrumentationBreakpointParams
 	cb     SetInstrumentationBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetInstrumentationBreakpointCommand(params *SetInstrumentationBreakpointParams, cb SetInstrumentationBreakpointCB) *AsyncSetInstrumentationBreakpointCommand {
 	return &AsyncSetInstrumentationBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -390,7 +491,28 @@ func (cmd *SetInstrumentationBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetInstrumentationBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetInstrumentationBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetInstrumentationBreakpointCommand) Send(conn hc.CommandRunner) *AsyncSetInstrumentationBreakpointCommand {
+	async := NewAsyncSetInstrumentationBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveInstrumentationBreakpointParams struct {
@@ -419,17 +541,18 @@ func (cmd *RemoveInstrumentationBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveInstrumentationBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveInstrumentationBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveInstrumentationBreakpoint(params *RemoveInstrumentationBreakpointParams, conn *hc.Conn) (err error) {
+func RemoveInstrumentationBreakpoint(params *RemoveInstrumentationBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveInstrumentationBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveInstrumentationBreakpointCB func(err error)
@@ -439,12 +562,16 @@ type RemoveInstrumentationBreakpointCB func(err error)
 type AsyncRemoveInstrumentationBreakpointCommand struct {
 	params *RemoveInstrumentationBreakpointParams
 	cb     RemoveInstrumentationBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveInstrumentationBreakpointCommand(params *RemoveInstrumentationBreakpointParams, cb RemoveInstrumentationBreakpointCB) *AsyncRemoveInstrumentationBreakpointCommand {
 	return &AsyncRemoveInstrumentationBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -462,7 +589,28 @@ func (cmd *RemoveInstrumentationBreakpointCommand) Done(data []byte, err error)
 }
 
 func (cmd *AsyncRemoveInstrumentationBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveInstrumentationBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveInstrumentationBreakpointCommand) Send(conn hc.CommandRunner) *AsyncRemoveInstrumentationBreakpointCommand {
+	async := NewAsyncRemoveInstrumentationBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetXHRBreakpointParams struct {
@@ -470,7 +618,6 @@ type SetXHRBreakpointParams struct {
 }
 
 // Sets breakpoint on XMLHttpRequest.
-
 type SetXHRBreakpointCommand struct {
 	params *SetXHRBreakpointParams
 	wg     sync.WaitGroup
@@ -491,32 +638,36 @@ func (cmd *SetXHRBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetXHRBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *SetXHRBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetXHRBreakpoint(params *SetXHRBreakpointParams, conn *hc.Conn) (err error) {
+func SetXHRBreakpoint(params *SetXHRBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetXHRBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetXHRBreakpointCB func(err error)
 
 // Sets breakpoint on XMLHttpRequest.
-
 type AsyncSetXHRBreakpointCommand struct {
 	params *SetXHRBreakpointParams
 	cb     SetXHRBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetXHRBreakpointCommand(params *SetXHRBreakpointParams, cb SetXHRBreakpointCB) *AsyncSetXHRBreakpointCommand {
 	return &AsyncSetXHRBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -534,7 +685,28 @@ func (cmd *SetXHRBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetXHRBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetXHRBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetXHRBreakpointCommand) Send(conn hc.CommandRunner) *AsyncSetXHRBreakpointCommand {
+	async := NewAsyncSetXHRBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveXHRBreakpointParams struct {
@@ -542,7 +714,6 @@ type RemoveXHRBreakpointParams struct {
 }
 
 // Removes breakpoint from XMLHttpRequest.
-
 type RemoveXHRBreakpointCommand struct {
 	params *RemoveXHRBreakpointParams
 	wg     sync.WaitGroup
@@ -563,32 +734,36 @@ func (cmd *RemoveXHRBreakpointCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveXHRBreakpointCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveXHRBreakpointCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveXHRBreakpoint(params *RemoveXHRBreakpointParams, conn *hc.Conn) (err error) {
+func RemoveXHRBreakpoint(params *RemoveXHRBreakpointParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveXHRBreakpointCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveXHRBreakpointCB func(err error)
 
 // Removes breakpoint from XMLHttpRequest.
-
 type AsyncRemoveXHRBreakpointCommand struct {
 	params *RemoveXHRBreakpointParams
 	cb     RemoveXHRBreakpointCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveXHRBreakpointCommand(params *RemoveXHRBreakpointParams, cb RemoveXHRBreakpointCB) *AsyncRemoveXHRBreakpointCommand {
 	return &AsyncRemoveXHRBreakpointCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -606,11 +781,32 @@ func (cmd *RemoveXHRBreakpointCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveXHRBreakpointCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveXHRBreakpointCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveXHRBreakpointCommand) Send(conn hc.CommandRunner) *AsyncRemoveXHRBreakpointCommand {
+	async := NewAsyncRemoveXHRBreakpointCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetEventListenersParams struct {
-	ObjectId *RemoteObjectId `json:"objectId"` // Identifier of the object to return listeners for.
+	ObjectId RemoteObjectId `json:"objectId"` // Identifier of the object to return listeners for.
 }
 
 type GetEventListenersResult struct {
@@ -640,17 +836,21 @@ func (cmd *GetEventListenersCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetEventListenersCommand) Run(conn *hc.Conn) error {
+func (cmd *GetEventListenersCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetEventListeners(params *GetEventListenersParams, conn *hc.Conn) (result *GetEventListenersResult, err error) {
+func GetEventListeners(params *GetEventListenersParams, conn hc.CommandRunner) (result *GetEventListenersResult, err error) {
 	cmd := NewGetEventListenersCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetEventListenersCB func(result *GetEventListenersResult, err error)
@@ -660,12 +860,17 @@ type GetEventListenersCB func(result *GetEventListenersResult, err error)
 type AsyncGetEventListenersCommand struct {
 	params *GetEventListenersParams
 	cb     GetEventListenersCB
+	result *GetEventListenersResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetEventListenersCommand(params *GetEventListenersParams, cb GetEventListenersCB) *AsyncGetEventListenersCommand {
 	return &AsyncGetEventListenersCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -694,11 +899,29 @@ func (cmd *AsyncGetEventListenersCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetEventListenersCommand) Wait(ctx context.Context) (*GetEventListenersResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetEventListenersCommand) Send(conn hc.CommandRunner) *AsyncGetEventListenersCommand {
+	async := NewAsyncGetEventListenersCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -19,7 +19,6 @@ type StorageId struct {
 type Item []string
 
 // Enables storage tracking, storage events will now be delivered to the client.
-
 type DOMStorageEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -37,30 +36,34 @@ func (cmd *DOMStorageEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DOMStorageEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *DOMStorageEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DOMStorageEnable(conn *hc.Conn) (err error) {
+func DOMStorageEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewDOMStorageEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DOMStorageEnableCB func(err error)
 
 // Enables storage tracking, storage events will now be delivered to the client.
-
 type AsyncDOMStorageEnableCommand struct {
-	cb DOMStorageEnableCB
+	cb   DOMStorageEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDOMStorageEnableCommand(cb DOMStorageEnableCB) *AsyncDOMStorageEnableCommand {
 	return &AsyncDOMStorageEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -78,11 +81,31 @@ func (cmd *DOMStorageEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDOMStorageEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables storage tracking, prevents storage events from being sent to the client.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDOMStorageEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DOMStorageEnableCommand) Send(conn hc.CommandRunner) *AsyncDOMStorageEnableCommand {
+	async := NewAsyncDOMStorageEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables storage tracking, prevents storage events from being sent to the client.
 type DOMStorageDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -100,30 +123,34 @@ func (cmd *DOMStorageDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DOMStorageDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *DOMStorageDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DOMStorageDisable(conn *hc.Conn) (err error) {
+func DOMStorageDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewDOMStorageDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DOMStorageDisableCB func(err error)
 
 // Disables storage tracking, prevents storage events from being sent to the client.
-
 type AsyncDOMStorageDisableCommand struct {
-	cb DOMStorageDisableCB
+	cb   DOMStorageDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDOMStorageDisableCommand(cb DOMStorageDisableCB) *AsyncDOMStorageDisableCommand {
 	return &AsyncDOMStorageDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -141,7 +168,28 @@ func (cmd *DOMStorageDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDOMStorageDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDOMStorageDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DOMStorageDisableCommand) Send(conn hc.CommandRunner) *AsyncDOMStorageDisableCommand {
+	async := NewAsyncDOMStorageDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetDOMStorageItemsParams struct {
@@ -173,17 +221,21 @@ func (cmd *GetDOMStorageItemsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetDOMStorageItemsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetDOMStorageItemsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetDOMStorageItems(params *GetDOMStorageItemsParams, conn *hc.Conn) (result *GetDOMStorageItemsResult, err error) {
+func GetDOMStorageItems(params *GetDOMStorageItemsParams, conn hc.CommandRunner) (result *GetDOMStorageItemsResult, err error) {
 	cmd := NewGetDOMStorageItemsCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetDOMStorageItemsCB func(result *GetDOMStorageItemsResult, err error)
@@ -191,12 +243,17 @@ type GetDOMStorageItemsCB func(result *GetDOMStorageItemsResult, err error)
 type AsyncGetDOMStorageItemsCommand struct {
 	params *GetDOMStorageItemsParams
 	cb     GetDOMStorageItemsCB
+	result *GetDOMStorageItemsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetDOMStorageItemsCommand(params *GetDOMStorageItemsParams, cb GetDOMStorageItemsCB) *AsyncGetDOMStorageItemsCommand {
 	return &AsyncGetDOMStorageItemsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -225,15 +282,33 @@ func (cmd *AsyncGetDOMStorageItemsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetDOMStorageItemsCommand) Wait(ctx context.Context) (*GetDOMStorageItemsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetDOMStorageItemsCommand) Send(conn hc.CommandRunner) *AsyncGetDOMStorageItemsCommand {
+	async := NewAsyncGetDOMStorageItemsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetDOMStorageItemParams struct {
 	StorageId *StorageId `json:"storageId"`
 	Key       string     `json:"key"`
@@ -260,17 +335,18 @@ func (cmd *SetDOMStorageItemCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetDOMStorageItemCommand) Run(conn *hc.Conn) error {
+func (cmd *SetDOMStorageItemCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetDOMStorageItem(params *SetDOMStorageItemParams, conn *hc.Conn) (err error) {
+func SetDOMStorageItem(params *SetDOMStorageItemParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetDOMStorageItemCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetDOMStorageItemCB func(err error)
@@ -278,12 +354,16 @@ type SetDOMStorageItemCB func(err error)
 type AsyncSetDOMStorageItemCommand struct {
 	params *SetDOMStorageItemParams
 	cb     SetDOMStorageItemCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetDOMStorageItemCommand(params *SetDOMStorageItemParams, cb SetDOMStorageItemCB) *AsyncSetDOMStorageItemCommand {
 	return &AsyncSetDOMStorageItemCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -301,7 +381,28 @@ func (cmd *SetDOMStorageItemCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetDOMStorageItemCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetDOMStorageItemCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetDOMStorageItemCommand) Send(conn hc.CommandRunner) *AsyncSetDOMStorageItemCommand {
+	async := NewAsyncSetDOMStorageItemCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveDOMStorageItemParams struct {
@@ -329,17 +430,18 @@ func (cmd *RemoveDOMStorageItemCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveDOMStorageItemCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveDOMStorageItemCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveDOMStorageItem(params *RemoveDOMStorageItemParams, conn *hc.Conn) (err error) {
+func RemoveDOMStorageItem(params *RemoveDOMStorageItemParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveDOMStorageItemCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveDOMStorageItemCB func(err error)
@@ -347,12 +449,16 @@ type RemoveDOMStorageItemCB func(err error)
 type AsyncRemoveDOMStorageItemCommand struct {
 	params *RemoveDOMStorageItemParams
 	cb     RemoveDOMStorageItemCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveDOMStorageItemCommand(params *RemoveDOMStorageItemParams, cb RemoveDOMStorageItemCB) *AsyncRemoveDOMStorageItemCommand {
 	return &AsyncRemoveDOMStorageItemCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -370,23 +476,60 @@ func (cmd *RemoveDOMStorageItemCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveDOMStorageItemCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveDOMStorageItemCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveDOMStorageItemCommand) Send(conn hc.CommandRunner) *AsyncRemoveDOMStorageItemCommand {
+	async := NewAsyncRemoveDOMStorageItemCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DomStorageItemsClearedEvent struct {
 	StorageId *StorageId `json:"storageId"`
 }
 
-func OnDomStorageItemsCleared(conn *hc.Conn, cb func(evt *DomStorageItemsClearedEvent)) {
+// Calls cb for each DOMStorage.domStorageItemsCleared event, till the returned func is called.
+func OnDomStorageItemsCleared(conn hc.EventSource, cb func(evt *DomStorageItemsClearedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DomStorageItemsClearedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOMStorage.domStorageItemsCleared", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemsCleared", sink) }
+}
+
+// Like OnDomStorageItemsCleared, but cb also gets hc.EventMeta.
+func OnDomStorageItemsClearedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DomStorageItemsClearedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DomStorageItemsClearedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOMStorage.domStorageItemsCleared", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemsCleared", sink) }
 }
 
 type DomStorageItemRemovedEvent struct {
@@ -394,16 +537,32 @@ type DomStorageItemRemovedEvent struct {
 	Key       string     `json:"key"`
 }
 
-func OnDomStorageItemRemoved(conn *hc.Conn, cb func(evt *DomStorageItemRemovedEvent)) {
+// Calls cb for each DOMStorage.domStorageItemRemoved event, till the returned func is called.
+func OnDomStorageItemRemoved(conn hc.EventSource, cb func(evt *DomStorageItemRemovedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DomStorageItemRemovedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOMStorage.domStorageItemRemoved", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemRemoved", sink) }
+}
+
+// Like OnDomStorageItemRemoved, but cb also gets hc.EventMeta.
+func OnDomStorageItemRemovedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DomStorageItemRemovedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DomStorageItemRemovedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOMStorage.domStorageItemRemoved", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemRemoved", sink) }
 }
 
 type DomStorageItemAddedEvent struct {
@@ -412,16 +571,32 @@ type DomStorageItemAddedEvent struct {
 	NewValue  string     `json:"newValue"`
 }
 
-func OnDomStorageItemAdded(conn *hc.Conn, cb func(evt *DomStorageItemAddedEvent)) {
+// Calls cb for each DOMStorage.domStorageItemAdded event, till the returned func is called.
+func OnDomStorageItemAdded(conn hc.EventSource, cb func(evt *DomStorageItemAddedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DomStorageItemAddedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOMStorage.domStorageItemAdded", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemAdded", sink) }
+}
+
+// Like OnDomStorageItemAdded, but cb also gets hc.EventMeta.
+func OnDomStorageItemAddedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DomStorageItemAddedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DomStorageItemAddedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOMStorage.domStorageItemAdded", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemAdded", sink) }
 }
 
 type DomStorageItemUpdatedEvent struct {
@@ -431,14 +606,30 @@ type DomStorageItemUpdatedEvent struct {
 	NewValue  string     `json:"newValue"`
 }
 
-func OnDomStorageItemUpdated(conn *hc.Conn, cb func(evt *DomStorageItemUpdatedEvent)) {
+// Calls cb for each DOMStorage.domStorageItemUpdated event, till the returned func is called.
+func OnDomStorageItemUpdated(conn hc.EventSource, cb func(evt *DomStorageItemUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DomStorageItemUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("DOMStorage.domStorageItemUpdated", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemUpdated", sink) }
+}
+
+// Like OnDomStorageItemUpdated, but cb also gets hc.EventMeta.
+func OnDomStorageItemUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DomStorageItemUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DomStorageItemUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("DOMStorage.domStorageItemUpdated", sink)
+	return func() { conn.RemoveEventSink("DOMStorage.domStorageItemUpdated", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -38,7 +38,6 @@ type EmulationSetDeviceMetricsOverrideParams struct {
 }
 
 // Overrides the values of device screen dimensions (window.screen.width, window.screen.height, window.innerWidth, window.innerHeight, and "device-width"/"device-height"-related CSS media query results).
-
 type EmulationSetDeviceMetricsOverrideCommand struct {
 	params *EmulationSetDeviceMetricsOverrideParams
 	wg     sync.WaitGroup
@@ -59,32 +58,36 @@ func (cmd *EmulationSetDeviceMetricsOverrideCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EmulationSetDeviceMetricsOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulationSetDeviceMetricsOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulationSetDeviceMetricsOverride(params *EmulationSetDeviceMetricsOverrideParams, conn *hc.Conn) (err error) {
+func EmulationSetDeviceMetricsOverride(params *EmulationSetDeviceMetricsOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewEmulationSetDeviceMetricsOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulationSetDeviceMetricsOverrideCB func(err error)
 
 // Overrides the values of device screen dimensions (window.screen.width, window.screen.height, window.innerWidth, window.innerHeight, and "device-width"/"device-height"-related CSS media query results).
-
 type AsyncEmulationSetDeviceMetricsOverrideCommand struct {
 	params *EmulationSetDeviceMetricsOverrideParams
 	cb     EmulationSetDeviceMetricsOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulationSetDeviceMetricsOverrideCommand(params *EmulationSetDeviceMetricsOverrideParams, cb EmulationSetDeviceMetricsOverrideCB) *AsyncEmulationSetDeviceMetricsOverrideCommand {
 	return &AsyncEmulationSetDeviceMetricsOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -102,11 +105,31 @@ func (cmd *EmulationSetDeviceMetricsOverrideCommand) Done(data []byte, err error
 }
 
 func (cmd *AsyncEmulationSetDeviceMetricsOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Clears the overriden device metrics.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulationSetDeviceMetricsOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulationSetDeviceMetricsOverrideCommand) Send(conn hc.CommandRunner) *AsyncEmulationSetDeviceMetricsOverrideCommand {
+	async := NewAsyncEmulationSetDeviceMetricsOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Clears the overriden device metrics.
 type EmulationClearDeviceMetricsOverrideCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -124,30 +147,34 @@ func (cmd *EmulationClearDeviceMetricsOverrideCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *EmulationClearDeviceMetricsOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulationClearDeviceMetricsOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulationClearDeviceMetricsOverride(conn *hc.Conn) (err error) {
+func EmulationClearDeviceMetricsOverride(conn hc.CommandRunner) (err error) {
 	cmd := NewEmulationClearDeviceMetricsOverrideCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulationClearDeviceMetricsOverrideCB func(err error)
 
 // Clears the overriden device metrics.
-
 type AsyncEmulationClearDeviceMetricsOverrideCommand struct {
-	cb EmulationClearDeviceMetricsOverrideCB
+	cb   EmulationClearDeviceMetricsOverrideCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulationClearDeviceMetricsOverrideCommand(cb EmulationClearDeviceMetricsOverrideCB) *AsyncEmulationClearDeviceMetricsOverrideCommand {
 	return &AsyncEmulationClearDeviceMetricsOverrideCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -165,7 +192,28 @@ func (cmd *EmulationClearDeviceMetricsOverrideCommand) Done(data []byte, err err
 }
 
 func (cmd *AsyncEmulationClearDeviceMetricsOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulationClearDeviceMetricsOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulationClearDeviceMetricsOverrideCommand) Send(conn hc.CommandRunner) *AsyncEmulationClearDeviceMetricsOverrideCommand {
+	async := NewAsyncEmulationClearDeviceMetricsOverrideCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ForceViewportParams struct {
@@ -196,17 +244,18 @@ func (cmd *ForceViewportCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ForceViewportCommand) Run(conn *hc.Conn) error {
+func (cmd *ForceViewportCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ForceViewport(params *ForceViewportParams, conn *hc.Conn) (err error) {
+func ForceViewport(params *ForceViewportParams, conn hc.CommandRunner) (err error) {
 	cmd := NewForceViewportCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ForceViewportCB func(err error)
@@ -216,12 +265,16 @@ type ForceViewportCB func(err error)
 type AsyncForceViewportCommand struct {
 	params *ForceViewportParams
 	cb     ForceViewportCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncForceViewportCommand(params *ForceViewportParams, cb ForceViewportCB) *AsyncForceViewportCommand {
 	return &AsyncForceViewportCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -239,7 +292,28 @@ func (cmd *ForceViewportCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncForceViewportCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncForceViewportCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ForceViewportCommand) Send(conn hc.CommandRunner) *AsyncForceViewportCommand {
+	async := NewAsyncForceViewportCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Resets the visible area of the page to the original viewport, undoing any effects of the forceViewport command.
@@ -261,17 +335,18 @@ func (cmd *ResetViewportCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ResetViewportCommand) Run(conn *hc.Conn) error {
+func (cmd *ResetViewportCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ResetViewport(conn *hc.Conn) (err error) {
+func ResetViewport(conn hc.CommandRunner) (err error) {
 	cmd := NewResetViewportCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ResetViewportCB func(err error)
@@ -279,12 +354,16 @@ type ResetViewportCB func(err error)
 // Resets the visible area of the page to the original viewport, undoing any effects of the forceViewport command.
 // @experimental
 type AsyncResetViewportCommand struct {
-	cb ResetViewportCB
+	cb   ResetViewportCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncResetViewportCommand(cb ResetViewportCB) *AsyncResetViewportCommand {
 	return &AsyncResetViewportCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -302,7 +381,28 @@ func (cmd *ResetViewportCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncResetViewportCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncResetViewportCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ResetViewportCommand) Send(conn hc.CommandRunner) *AsyncResetViewportCommand {
+	async := NewAsyncResetViewportCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Requests that page scale factor is reset to initial values.
@@ -324,17 +424,18 @@ func (cmd *ResetPageScaleFactorCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ResetPageScaleFactorCommand) Run(conn *hc.Conn) error {
+func (cmd *ResetPageScaleFactorCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ResetPageScaleFactor(conn *hc.Conn) (err error) {
+func ResetPageScaleFactor(conn hc.CommandRunner) (err error) {
 	cmd := NewResetPageScaleFactorCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ResetPageScaleFactorCB func(err error)
@@ -342,12 +443,16 @@ type ResetPageScaleFactorCB func(err error)
 // Requests that page scale factor is reset to initial values.
 // @experimental
 type AsyncResetPageScaleFactorCommand struct {
-	cb ResetPageScaleFactorCB
+	cb   ResetPageScaleFactorCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncResetPageScaleFactorCommand(cb ResetPageScaleFactorCB) *AsyncResetPageScaleFactorCommand {
 	return &AsyncResetPageScaleFactorCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -365,7 +470,28 @@ func (cmd *ResetPageScaleFactorCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncResetPageScaleFactorCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncResetPageScaleFactorCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ResetPageScaleFactorCommand) Send(conn hc.CommandRunner) *AsyncResetPageScaleFactorCommand {
+	async := NewAsyncResetPageScaleFactorCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetPageScaleFactorParams struct {
@@ -394,17 +520,18 @@ func (cmd *SetPageScaleFactorCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetPageScaleFactorCommand) Run(conn *hc.Conn) error {
+func (cmd *SetPageScaleFactorCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetPageScaleFactor(params *SetPageScaleFactorParams, conn *hc.Conn) (err error) {
+func SetPageScaleFactor(params *SetPageScaleFactorParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetPageScaleFactorCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetPageScaleFactorCB func(err error)
@@ -414,12 +541,16 @@ type SetPageScaleFactorCB func(err error)
 type AsyncSetPageScaleFactorCommand struct {
 	params *SetPageScaleFactorParams
 	cb     SetPageScaleFactorCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetPageScaleFactorCommand(params *SetPageScaleFactorParams, cb SetPageScaleFactorCB) *AsyncSetPageScaleFactorCommand {
 	return &AsyncSetPageScaleFactorCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -437,7 +568,28 @@ func (cmd *SetPageScaleFactorCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetPageScaleFactorCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetPageScaleFactorCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetPageScaleFactorCommand) Send(conn hc.CommandRunner) *AsyncSetPageScaleFactorCommand {
+	async := NewAsyncSetPageScaleFactorCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetVisibleSizeParams struct {
@@ -467,17 +619,18 @@ func (cmd *SetVisibleSizeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetVisibleSizeCommand) Run(conn *hc.Conn) error {
+func (cmd *SetVisibleSizeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetVisibleSize(params *SetVisibleSizeParams, conn *hc.Conn) (err error) {
+func SetVisibleSize(params *SetVisibleSizeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetVisibleSizeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetVisibleSizeCB func(err error)
@@ -487,12 +640,16 @@ type SetVisibleSizeCB func(err error)
 type AsyncSetVisibleSizeCommand struct {
 	params *SetVisibleSizeParams
 	cb     SetVisibleSizeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetVisibleSizeCommand(params *SetVisibleSizeParams, cb SetVisibleSizeCB) *AsyncSetVisibleSizeCommand {
 	return &AsyncSetVisibleSizeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -510,7 +667,28 @@ func (cmd *SetVisibleSizeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetVisibleSizeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetVisibleSizeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetVisibleSizeCommand) Send(conn hc.CommandRunner) *AsyncSetVisibleSizeCommand {
+	async := NewAsyncSetVisibleSizeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetScriptExecutionDisabledParams struct {
@@ -539,17 +717,18 @@ func (cmd *SetScriptExecutionDisabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetScriptExecutionDisabledCommand) Run(conn *hc.Conn) error {
+func (cmd *SetScriptExecutionDisabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetScriptExecutionDisabled(params *SetScriptExecutionDisabledParams, conn *hc.Conn) (err error) {
+func SetScriptExecutionDisabled(params *SetScriptExecutionDisabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetScriptExecutionDisabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetScriptExecutionDisabledCB func(err error)
@@ -559,12 +738,16 @@ type SetScriptExecutionDisabledCB func(err error)
 type AsyncSetScriptExecutionDisabledCommand struct {
 	params *SetScriptExecutionDisabledParams
 	cb     SetScriptExecutionDisabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetScriptExecutionDisabledCommand(params *SetScriptExecutionDisabledParams, cb SetScriptExecutionDisabledCB) *AsyncSetScriptExecutionDisabledCommand {
 	return &AsyncSetScriptExecutionDisabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -582,7 +765,28 @@ func (cmd *SetScriptExecutionDisabledCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetScriptExecutionDisabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetScriptExecutionDisabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetScriptExecutionDisabledCommand) Send(conn hc.CommandRunner) *AsyncSetScriptExecutionDisabledCommand {
+	async := NewAsyncSetScriptExecutionDisabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type EmulationSetGeolocationOverrideParams struct {
@@ -613,17 +817,18 @@ func (cmd *EmulationSetGeolocationOverrideCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EmulationSetGeolocationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulationSetGeolocationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulationSetGeolocationOverride(params *EmulationSetGeolocationOverrideParams, conn *hc.Conn) (err error) {
+func EmulationSetGeolocationOverride(params *EmulationSetGeolocationOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewEmulationSetGeolocationOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulationSetGeolocationOverrideCB func(err error)
@@ -633,12 +838,16 @@ type EmulationSetGeolocationOverrideCB func(err error)
 type AsyncEmulationSetGeolocationOverrideCommand struct {
 	params *EmulationSetGeolocationOverrideParams
 	cb     EmulationSetGeolocationOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulationSetGeolocationOverrideCommand(params *EmulationSetGeolocationOverrideParams, cb EmulationSetGeolocationOverrideCB) *AsyncEmulationSetGeolocationOverrideCommand {
 	return &AsyncEmulationSetGeolocationOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -656,7 +865,28 @@ func (cmd *EmulationSetGeolocationOverrideCommand) Done(data []byte, err error)
 }
 
 func (cmd *AsyncEmulationSetGeolocationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulationSetGeolocationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulationSetGeolocationOverrideCommand) Send(conn hc.CommandRunner) *AsyncEmulationSetGeolocationOverrideCommand {
+	async := NewAsyncEmulationSetGeolocationOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Clears the overriden Geolocation Position and Error.
@@ -678,17 +908,18 @@ func (cmd *EmulationClearGeolocationOverrideCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *EmulationClearGeolocationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulationClearGeolocationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulationClearGeolocationOverride(conn *hc.Conn) (err error) {
+func EmulationClearGeolocationOverride(conn hc.CommandRunner) (err error) {
 	cmd := NewEmulationClearGeolocationOverrideCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulationClearGeolocationOverrideCB func(err error)
@@ -696,12 +927,16 @@ type EmulationClearGeolocationOverrideCB func(err error)
 // Clears the overriden Geolocation Position and Error.
 // @experimental
 type AsyncEmulationClearGeolocationOverrideCommand struct {
-	cb EmulationClearGeolocationOverrideCB
+	cb   EmulationClearGeolocationOverrideCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulationClearGeolocationOverrideCommand(cb EmulationClearGeolocationOverrideCB) *AsyncEmulationClearGeolocationOverrideCommand {
 	return &AsyncEmulationClearGeolocationOverrideCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -719,7 +954,28 @@ func (cmd *EmulationClearGeolocationOverrideCommand) Done(data []byte, err error
 }
 
 func (cmd *AsyncEmulationClearGeolocationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulationClearGeolocationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulationClearGeolocationOverrideCommand) Send(conn hc.CommandRunner) *AsyncEmulationClearGeolocationOverrideCommand {
+	async := NewAsyncEmulationClearGeolocationOverrideCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type EmulationSetTouchEmulationEnabledParams struct {
@@ -728,7 +984,6 @@ type EmulationSetTouchEmulationEnabledParams struct {
 }
 
 // Toggles mouse event-based touch event emulation.
-
 type EmulationSetTouchEmulationEnabledCommand struct {
 	params *EmulationSetTouchEmulationEnabledParams
 	wg     sync.WaitGroup
@@ -749,32 +1004,36 @@ func (cmd *EmulationSetTouchEmulationEnabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EmulationSetTouchEmulationEnabledCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulationSetTouchEmulationEnabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulationSetTouchEmulationEnabled(params *EmulationSetTouchEmulationEnabledParams, conn *hc.Conn) (err error) {
+func EmulationSetTouchEmulationEnabled(params *EmulationSetTouchEmulationEnabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewEmulationSetTouchEmulationEnabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulationSetTouchEmulationEnabledCB func(err error)
 
 // Toggles mouse event-based touch event emulation.
-
 type AsyncEmulationSetTouchEmulationEnabledCommand struct {
 	params *EmulationSetTouchEmulationEnabledParams
 	cb     EmulationSetTouchEmulationEnabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulationSetTouchEmulationEnabledCommand(params *EmulationSetTouchEmulationEnabledParams, cb EmulationSetTouchEmulationEnabledCB) *AsyncEmulationSetTouchEmulationEnabledCommand {
 	return &AsyncEmulationSetTouchEmulationEnabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -792,7 +1051,28 @@ func (cmd *EmulationSetTouchEmulationEnabledCommand) Done(data []byte, err error
 }
 
 func (cmd *AsyncEmulationSetTouchEmulationEnabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulationSetTouchEmulationEnabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulationSetTouchEmulationEnabledCommand) Send(conn hc.CommandRunner) *AsyncEmulationSetTouchEmulationEnabledCommand {
+	async := NewAsyncEmulationSetTouchEmulationEnabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetEmulatedMediaParams struct {
@@ -800,7 +1080,6 @@ type SetEmulatedMediaParams struct {
 }
 
 // Emulates the given media for CSS media queries.
-
 type SetEmulatedMediaCommand struct {
 	params *SetEmulatedMediaParams
 	wg     sync.WaitGroup
@@ -821,32 +1100,36 @@ func (cmd *SetEmulatedMediaCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetEmulatedMediaCommand) Run(conn *hc.Conn) error {
+func (cmd *SetEmulatedMediaCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetEmulatedMedia(params *SetEmulatedMediaParams, conn *hc.Conn) (err error) {
+func SetEmulatedMedia(params *SetEmulatedMediaParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetEmulatedMediaCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetEmulatedMediaCB func(err error)
 
 // Emulates the given media for CSS media queries.
-
 type AsyncSetEmulatedMediaCommand struct {
 	params *SetEmulatedMediaParams
 	cb     SetEmulatedMediaCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetEmulatedMediaCommand(params *SetEmulatedMediaParams, cb SetEmulatedMediaCB) *AsyncSetEmulatedMediaCommand {
 	return &AsyncSetEmulatedMediaCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -864,7 +1147,28 @@ func (cmd *SetEmulatedMediaCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetEmulatedMediaCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetEmulatedMediaCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetEmulatedMediaCommand) Send(conn hc.CommandRunner) *AsyncSetEmulatedMediaCommand {
+	async := NewAsyncSetEmulatedMediaCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetCPUThrottlingRateParams struct {
@@ -893,17 +1197,18 @@ func (cmd *SetCPUThrottlingRateCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetCPUThrottlingRateCommand) Run(conn *hc.Conn) error {
+func (cmd *SetCPUThrottlingRateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetCPUThrottlingRate(params *SetCPUThrottlingRateParams, conn *hc.Conn) (err error) {
+func SetCPUThrottlingRate(params *SetCPUThrottlingRateParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetCPUThrottlingRateCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetCPUThrottlingRateCB func(err error)
@@ -913,12 +1218,16 @@ type SetCPUThrottlingRateCB func(err error)
 type AsyncSetCPUThrottlingRateCommand struct {
 	params *SetCPUThrottlingRateParams
 	cb     SetCPUThrottlingRateCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetCPUThrottlingRateCommand(params *SetCPUThrottlingRateParams, cb SetCPUThrottlingRateCB) *AsyncSetCPUThrottlingRateCommand {
 	return &AsyncSetCPUThrottlingRateCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -936,7 +1245,28 @@ func (cmd *SetCPUThrottlingRateCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetCPUThrottlingRateCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetCPUThrottlingRateCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetCPUThrottlingRateCommand) Send(conn hc.CommandRunner) *AsyncSetCPUThrottlingRateCommand {
+	async := NewAsyncSetCPUThrottlingRateCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CanEmulateResult struct {
@@ -963,17 +1293,21 @@ func (cmd *CanEmulateCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CanEmulateCommand) Run(conn *hc.Conn) error {
+func (cmd *CanEmulateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CanEmulate(conn *hc.Conn) (result *CanEmulateResult, err error) {
+func CanEmulate(conn hc.CommandRunner) (result *CanEmulateResult, err error) {
 	cmd := NewCanEmulateCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CanEmulateCB func(result *CanEmulateResult, err error)
@@ -981,12 +1315,17 @@ type CanEmulateCB func(result *CanEmulateResult, err error)
 // Tells whether emulation is supported.
 // @experimental
 type AsyncCanEmulateCommand struct {
-	cb CanEmulateCB
+	cb     CanEmulateCB
+	result *CanEmulateResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCanEmulateCommand(cb CanEmulateCB) *AsyncCanEmulateCommand {
 	return &AsyncCanEmulateCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1015,15 +1354,33 @@ func (cmd *AsyncCanEmulateCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCanEmulateCommand) Wait(ctx context.Context) (*CanEmulateResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CanEmulateCommand) Send(conn hc.CommandRunner) *AsyncCanEmulateCommand {
+	async := NewAsyncCanEmulateCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetVirtualTimePolicyParams struct {
 	Policy VirtualTimePolicy `json:"policy"`
 	Budget int               `json:"budget,omitempty"` // If set, after this many virtual milliseconds have elapsed virtual time will be paused and a virtualTimeBudgetExpired event is sent.
@@ -1051,17 +1408,18 @@ func (cmd *SetVirtualTimePolicyCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetVirtualTimePolicyCommand) Run(conn *hc.Conn) error {
+func (cmd *SetVirtualTimePolicyCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetVirtualTimePolicy(params *SetVirtualTimePolicyParams, conn *hc.Conn) (err error) {
+func SetVirtualTimePolicy(params *SetVirtualTimePolicyParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetVirtualTimePolicyCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetVirtualTimePolicyCB func(err error)
@@ -1071,12 +1429,16 @@ type SetVirtualTimePolicyCB func(err error)
 type AsyncSetVirtualTimePolicyCommand struct {
 	params *SetVirtualTimePolicyParams
 	cb     SetVirtualTimePolicyCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetVirtualTimePolicyCommand(params *SetVirtualTimePolicyParams, cb SetVirtualTimePolicyCB) *AsyncSetVirtualTimePolicyCommand {
 	return &AsyncSetVirtualTimePolicyCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1094,7 +1456,28 @@ func (cmd *SetVirtualTimePolicyCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetVirtualTimePolicyCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetVirtualTimePolicyCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetVirtualTimePolicyCommand) Send(conn hc.CommandRunner) *AsyncSetVirtualTimePolicyCommand {
+	async := NewAsyncSetVirtualTimePolicyCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Notification sent after the virual time budget for the current VirtualTimePolicy has run out.
@@ -1102,14 +1485,30 @@ func (cmd *AsyncSetVirtualTimePolicyCommand) Done(data []byte, err error) {
 type VirtualTimeBudgetExpiredEvent struct {
 }
 
-func OnVirtualTimeBudgetExpired(conn *hc.Conn, cb func(evt *VirtualTimeBudgetExpiredEvent)) {
+// Calls cb for each Emulation.virtualTimeBudgetExpired event, till the returned func is called.
+func OnVirtualTimeBudgetExpired(conn hc.EventSource, cb func(evt *VirtualTimeBudgetExpiredEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &VirtualTimeBudgetExpiredEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Emulation.virtualTimeBudgetExpired", sink)
+	return func() { conn.RemoveEventSink("Emulation.virtualTimeBudgetExpired", sink) }
+}
+
+// Like OnVirtualTimeBudgetExpired, but cb also gets hc.EventMeta.
+func OnVirtualTimeBudgetExpiredMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *VirtualTimeBudgetExpiredEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &VirtualTimeBudgetExpiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Emulation.virtualTimeBudgetExpired", sink)
+	return func() { conn.RemoveEventSink("Emulation.virtualTimeBudgetExpired", sink) }
 }
@@ -0,0 +1,405 @@
+package protocol
+
+// Not generated. The HeadlessExperimental domain came after v1.2 of the protocol, so it isn't in
+// the protocol definition the rest of this package is generated from. Written like generated
+// code, and registered in Commands and Events by init below, so that it can be replaced by
+// generated code as is.
+
+import (
+	"context"
+	"encoding/json"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"sync"
+)
+
+// Encoding options for a screenshot.
+type ScreenshotParams struct {
+	Format  string `json:"format,omitempty"`  // Image compression format (defaults to png).
+	Quality int    `json:"quality,omitempty"` // Compression quality from range [0..100] (jpeg only).
+}
+type BeginFrameParams struct {
+	FrameTime  float64           `json:"frameTime,omitempty"`  // Timestamp of this BeginFrame (milliseconds since epoch). If not set, the current time will be used.
+	Deadline   float64           `json:"deadline,omitempty"`   // Deadline of this BeginFrame (milliseconds since epoch). If not set, the deadline will be calculated from the frameTime and interval.
+	Interval   float64           `json:"interval,omitempty"`   // The interval between BeginFrames that is reported to the compositor, in milliseconds. Defaults to a 60 frames/second interval, i.e. about 16.666 milliseconds.
+	Screenshot *ScreenshotParams `json:"screenshot,omitempty"` // If set, a screenshot of the frame will be captured and returned in the response. Otherwise, no screenshot will be captured.
+}
+
+type BeginFrameResult struct {
+	HasDamage               bool   `json:"hasDamage"`               // Whether the BeginFrame resulted in damage and, thus, a new frame was committed to the display.
+	MainFrameContentUpdated bool   `json:"mainFrameContentUpdated"` // Whether the main frame submitted a new display frame in response to this BeginFrame.
+	ScreenshotData          string `json:"screenshotData"`          // Base64-encoded image data of the screenshot, if one was requested and successfully taken.
+}
+
+// Sends a BeginFrame to the target and returns when the frame was completed. Optionally captures a screenshot from the resulting frame. Requires that the target was created with enabled BeginFrameControl.
+
+type BeginFrameCommand struct {
+	params *BeginFrameParams
+	result BeginFrameResult
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewBeginFrameCommand(params *BeginFrameParams) *BeginFrameCommand {
+	return &BeginFrameCommand{
+		params: params,
+	}
+}
+
+func (cmd *BeginFrameCommand) Name() string {
+	return "HeadlessExperimental.beginFrame"
+}
+
+func (cmd *BeginFrameCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *BeginFrameCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func BeginFrame(params *BeginFrameParams, conn hc.CommandRunner) (result *BeginFrameResult, err error) {
+	cmd := NewBeginFrameCommand(params)
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
+}
+
+type BeginFrameCB func(result *BeginFrameResult, err error)
+
+// Sends a BeginFrame to the target and returns when the frame was completed. Optionally captures a screenshot from the resulting frame. Requires that the target was created with enabled BeginFrameControl.
+
+type AsyncBeginFrameCommand struct {
+	params *BeginFrameParams
+	cb     BeginFrameCB
+	result *BeginFrameResult
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncBeginFrameCommand(params *BeginFrameParams, cb BeginFrameCB) *AsyncBeginFrameCommand {
+	return &AsyncBeginFrameCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncBeginFrameCommand) Name() string {
+	return "HeadlessExperimental.beginFrame"
+}
+
+func (cmd *AsyncBeginFrameCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *BeginFrameCommand) Result() *BeginFrameResult {
+	return &cmd.result
+}
+
+func (cmd *BeginFrameCommand) Done(data []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(data, &cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncBeginFrameCommand) Done(data []byte, err error) {
+	var result BeginFrameResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncBeginFrameCommand) Wait(ctx context.Context) (*BeginFrameResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *BeginFrameCommand) Send(conn hc.CommandRunner) *AsyncBeginFrameCommand {
+	async := NewAsyncBeginFrameCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Enables headless events for the target.
+
+type HeadlessExperimentalEnableCommand struct {
+	wg  sync.WaitGroup
+	err error
+}
+
+func NewHeadlessExperimentalEnableCommand() *HeadlessExperimentalEnableCommand {
+	return &HeadlessExperimentalEnableCommand{}
+}
+
+func (cmd *HeadlessExperimentalEnableCommand) Name() string {
+	return "HeadlessExperimental.enable"
+}
+
+func (cmd *HeadlessExperimentalEnableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *HeadlessExperimentalEnableCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func HeadlessExperimentalEnable(conn hc.CommandRunner) (err error) {
+	cmd := NewHeadlessExperimentalEnableCommand()
+	return cmd.Run(conn)
+}
+
+type HeadlessExperimentalEnableCB func(err error)
+
+// Enables headless events for the target.
+
+type AsyncHeadlessExperimentalEnableCommand struct {
+	cb   HeadlessExperimentalEnableCB
+	done chan struct{}
+	err  error
+}
+
+// cb is optional. See Wait.
+func NewAsyncHeadlessExperimentalEnableCommand(cb HeadlessExperimentalEnableCB) *AsyncHeadlessExperimentalEnableCommand {
+	return &AsyncHeadlessExperimentalEnableCommand{
+		cb:   cb,
+		done: make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncHeadlessExperimentalEnableCommand) Name() string {
+	return "HeadlessExperimental.enable"
+}
+
+func (cmd *AsyncHeadlessExperimentalEnableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *HeadlessExperimentalEnableCommand) Done(data []byte, err error) {
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncHeadlessExperimentalEnableCommand) Done(data []byte, err error) {
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHeadlessExperimentalEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HeadlessExperimentalEnableCommand) Send(conn hc.CommandRunner) *AsyncHeadlessExperimentalEnableCommand {
+	async := NewAsyncHeadlessExperimentalEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Disables headless events for the target.
+
+type HeadlessExperimentalDisableCommand struct {
+	wg  sync.WaitGroup
+	err error
+}
+
+func NewHeadlessExperimentalDisableCommand() *HeadlessExperimentalDisableCommand {
+	return &HeadlessExperimentalDisableCommand{}
+}
+
+func (cmd *HeadlessExperimentalDisableCommand) Name() string {
+	return "HeadlessExperimental.disable"
+}
+
+func (cmd *HeadlessExperimentalDisableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *HeadlessExperimentalDisableCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func HeadlessExperimentalDisable(conn hc.CommandRunner) (err error) {
+	cmd := NewHeadlessExperimentalDisableCommand()
+	return cmd.Run(conn)
+}
+
+type HeadlessExperimentalDisableCB func(err error)
+
+// Disables headless events for the target.
+
+type AsyncHeadlessExperimentalDisableCommand struct {
+	cb   HeadlessExperimentalDisableCB
+	done chan struct{}
+	err  error
+}
+
+// cb is optional. See Wait.
+func NewAsyncHeadlessExperimentalDisableCommand(cb HeadlessExperimentalDisableCB) *AsyncHeadlessExperimentalDisableCommand {
+	return &AsyncHeadlessExperimentalDisableCommand{
+		cb:   cb,
+		done: make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncHeadlessExperimentalDisableCommand) Name() string {
+	return "HeadlessExperimental.disable"
+}
+
+func (cmd *AsyncHeadlessExperimentalDisableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *HeadlessExperimentalDisableCommand) Done(data []byte, err error) {
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncHeadlessExperimentalDisableCommand) Done(data []byte, err error) {
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHeadlessExperimentalDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HeadlessExperimentalDisableCommand) Send(conn hc.CommandRunner) *AsyncHeadlessExperimentalDisableCommand {
+	async := NewAsyncHeadlessExperimentalDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Issued when the target starts or stops needing BeginFrames.
+
+type NeedsBeginFramesChangedEvent struct {
+	NeedsBeginFrames bool `json:"needsBeginFrames"` // True if BeginFrames are needed, false otherwise.
+}
+
+// Calls cb for each HeadlessExperimental.needsBeginFramesChanged event, till the returned func is called.
+func OnNeedsBeginFramesChanged(conn hc.EventSource, cb func(evt *NeedsBeginFramesChangedEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &NeedsBeginFramesChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddEventSink("HeadlessExperimental.needsBeginFramesChanged", sink)
+	return func() { conn.RemoveEventSink("HeadlessExperimental.needsBeginFramesChanged", sink) }
+}
+
+// Like OnNeedsBeginFramesChanged, but cb also gets hc.EventMeta.
+func OnNeedsBeginFramesChangedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *NeedsBeginFramesChangedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &NeedsBeginFramesChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeadlessExperimental.needsBeginFramesChanged", sink)
+	return func() { conn.RemoveEventSink("HeadlessExperimental.needsBeginFramesChanged", sink) }
+}
+
+// Issued when the main frame has first submitted a frame to the browser. May only be fired while a BeginFrame is in flight. Before this event, screenshotting requests may fail.
+
+type MainFrameReadyForScreenshotsEvent struct {
+}
+
+// Calls cb for each HeadlessExperimental.mainFrameReadyForScreenshots event, till the returned func is called.
+func OnMainFrameReadyForScreenshots(conn hc.EventSource, cb func(evt *MainFrameReadyForScreenshotsEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &MainFrameReadyForScreenshotsEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddEventSink("HeadlessExperimental.mainFrameReadyForScreenshots", sink)
+	return func() { conn.RemoveEventSink("HeadlessExperimental.mainFrameReadyForScreenshots", sink) }
+}
+
+// Like OnMainFrameReadyForScreenshots, but cb also gets hc.EventMeta.
+func OnMainFrameReadyForScreenshotsMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *MainFrameReadyForScreenshotsEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &MainFrameReadyForScreenshotsEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeadlessExperimental.mainFrameReadyForScreenshots", sink)
+	return func() { conn.RemoveEventSink("HeadlessExperimental.mainFrameReadyForScreenshots", sink) }
+}
+
+func init() {
+	for _, spec := range []*CommandSpec{
+		{Method: "HeadlessExperimental.beginFrame", Params: []FieldSpec{{"frameTime", "float64", true}, {"deadline", "float64", true}, {"interval", "float64", true}, {"screenshot", "*ScreenshotParams", true}}, Results: []FieldSpec{{"hasDamage", "bool", false}, {"mainFrameContentUpdated", "bool", true}, {"screenshotData", "string", true}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &BeginFrameParams{} }, newResult: func() interface{} { return &BeginFrameResult{} }},
+		{Method: "HeadlessExperimental.disable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+		{Method: "HeadlessExperimental.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	} {
+		Commands[spec.Method] = spec
+	}
+	for _, spec := range []*EventSpec{
+		{Method: "HeadlessExperimental.mainFrameReadyForScreenshots", Params: []FieldSpec{}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &MainFrameReadyForScreenshotsEvent{} }},
+		{Method: "HeadlessExperimental.needsBeginFramesChanged", Params: []FieldSpec{{"needsBeginFrames", "bool", true}}, Experimental: false, Targets: hc.TargetPage, newEvent: func() interface{} { return &NeedsBeginFramesChangedEvent{} }},
+	} {
+		Events[spec.Method] = spec
+	}
+}
@@ -0,0 +1,37 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+)
+
+// The hand-written HeadlessExperimental specs are found like generated ones.
+func TestHeadlessExperimentalSpecs(t *testing.T) {
+	for _, method := range []string{"HeadlessExperimental.beginFrame",
+		"HeadlessExperimental.enable", "HeadlessExperimental.disable"} {
+		if spec := Commands[method]; spec == nil || spec.Method != method {
+			t.Errorf("%s: got %+v", method, spec)
+		}
+	}
+	if err := hc.CheckTargetKind(hc.TargetWorker, "HeadlessExperimental.beginFrame"); err == nil {
+		t.Error("beginFrame allowed on workers")
+	}
+	cmd, err := NewCommandFromJSON("HeadlessExperimental.beginFrame",
+		json.RawMessage(`{"interval": 16}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params := cmd.Params().(*BeginFrameParams); params.Interval != 16 {
+		t.Errorf("Got %+v", params)
+	}
+	evt, err := DecodeEvent("HeadlessExperimental.needsBeginFramesChanged",
+		json.RawMessage(`{"needsBeginFrames": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := evt.(*NeedsBeginFramesChangedEvent); !ok || !e.NeedsBeginFrames {
+		t.Errorf("Got %+v", evt)
+	}
+}
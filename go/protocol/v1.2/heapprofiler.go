@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -39,28 +39,33 @@ func (cmd *HeapProfilerEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *HeapProfilerEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *HeapProfilerEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HeapProfilerEnable(conn *hc.Conn) (err error) {
+func HeapProfilerEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewHeapProfilerEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HeapProfilerEnableCB func(err error)
 
 type AsyncHeapProfilerEnableCommand struct {
-	cb HeapProfilerEnableCB
+	cb   HeapProfilerEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHeapProfilerEnableCommand(cb HeapProfilerEnableCB) *AsyncHeapProfilerEnableCommand {
 	return &AsyncHeapProfilerEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -78,7 +83,28 @@ func (cmd *HeapProfilerEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHeapProfilerEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHeapProfilerEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HeapProfilerEnableCommand) Send(conn hc.CommandRunner) *AsyncHeapProfilerEnableCommand {
+	async := NewAsyncHeapProfilerEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type HeapProfilerDisableCommand struct {
@@ -98,28 +124,33 @@ func (cmd *HeapProfilerDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *HeapProfilerDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *HeapProfilerDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HeapProfilerDisable(conn *hc.Conn) (err error) {
+func HeapProfilerDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewHeapProfilerDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HeapProfilerDisableCB func(err error)
 
 type AsyncHeapProfilerDisableCommand struct {
-	cb HeapProfilerDisableCB
+	cb   HeapProfilerDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHeapProfilerDisableCommand(cb HeapProfilerDisableCB) *AsyncHeapProfilerDisableCommand {
 	return &AsyncHeapProfilerDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -137,7 +168,28 @@ func (cmd *HeapProfilerDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHeapProfilerDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHeapProfilerDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HeapProfilerDisableCommand) Send(conn hc.CommandRunner) *AsyncHeapProfilerDisableCommand {
+	async := NewAsyncHeapProfilerDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StartTrackingHeapObjectsParams struct {
@@ -164,17 +216,18 @@ func (cmd *StartTrackingHeapObjectsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StartTrackingHeapObjectsCommand) Run(conn *hc.Conn) error {
+func (cmd *StartTrackingHeapObjectsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StartTrackingHeapObjects(params *StartTrackingHeapObjectsParams, conn *hc.Conn) (err error) {
+func StartTrackingHeapObjects(params *StartTrackingHeapObjectsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStartTrackingHeapObjectsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StartTrackingHeapObjectsCB func(err error)
@@ -182,12 +235,16 @@ type StartTrackingHeapObjectsCB func(err error)
 type AsyncStartTrackingHeapObjectsCommand struct {
 	params *StartTrackingHeapObjectsParams
 	cb     StartTrackingHeapObjectsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStartTrackingHeapObjectsCommand(params *StartTrackingHeapObjectsParams, cb StartTrackingHeapObjectsCB) *AsyncStartTrackingHeapObjectsCommand {
 	return &AsyncStartTrackingHeapObjectsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -205,7 +262,28 @@ func (cmd *StartTrackingHeapObjectsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStartTrackingHeapObjectsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStartTrackingHeapObjectsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StartTrackingHeapObjectsCommand) Send(conn hc.CommandRunner) *AsyncStartTrackingHeapObjectsCommand {
+	async := NewAsyncStartTrackingHeapObjectsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StopTrackingHeapObjectsParams struct {
@@ -232,17 +310,18 @@ func (cmd *StopTrackingHeapObjectsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StopTrackingHeapObjectsCommand) Run(conn *hc.Conn) error {
+func (cmd *StopTrackingHeapObjectsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopTrackingHeapObjects(params *StopTrackingHeapObjectsParams, conn *hc.Conn) (err error) {
+func StopTrackingHeapObjects(params *StopTrackingHeapObjectsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStopTrackingHeapObjectsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StopTrackingHeapObjectsCB func(err error)
@@ -250,12 +329,16 @@ type StopTrackingHeapObjectsCB func(err error)
 type AsyncStopTrackingHeapObjectsCommand struct {
 	params *StopTrackingHeapObjectsParams
 	cb     StopTrackingHeapObjectsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopTrackingHeapObjectsCommand(params *StopTrackingHeapObjectsParams, cb StopTrackingHeapObjectsCB) *AsyncStopTrackingHeapObjectsCommand {
 	return &AsyncStopTrackingHeapObjectsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -273,7 +356,28 @@ func (cmd *StopTrackingHeapObjectsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStopTrackingHeapObjectsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopTrackingHeapObjectsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopTrackingHeapObjectsCommand) Send(conn hc.CommandRunner) *AsyncStopTrackingHeapObjectsCommand {
+	async := NewAsyncStopTrackingHeapObjectsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type TakeHeapSnapshotParams struct {
@@ -300,17 +404,18 @@ func (cmd *TakeHeapSnapshotCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *TakeHeapSnapshotCommand) Run(conn *hc.Conn) error {
+func (cmd *TakeHeapSnapshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func TakeHeapSnapshot(params *TakeHeapSnapshotParams, conn *hc.Conn) (err error) {
+func TakeHeapSnapshot(params *TakeHeapSnapshotParams, conn hc.CommandRunner) (err error) {
 	cmd := NewTakeHeapSnapshotCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type TakeHeapSnapshotCB func(err error)
@@ -318,12 +423,16 @@ type TakeHeapSnapshotCB func(err error)
 type AsyncTakeHeapSnapshotCommand struct {
 	params *TakeHeapSnapshotParams
 	cb     TakeHeapSnapshotCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncTakeHeapSnapshotCommand(params *TakeHeapSnapshotParams, cb TakeHeapSnapshotCB) *AsyncTakeHeapSnapshotCommand {
 	return &AsyncTakeHeapSnapshotCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -341,7 +450,28 @@ func (cmd *TakeHeapSnapshotCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncTakeHeapSnapshotCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncTakeHeapSnapshotCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *TakeHeapSnapshotCommand) Send(conn hc.CommandRunner) *AsyncTakeHeapSnapshotCommand {
+	async := NewAsyncTakeHeapSnapshotCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CollectGarbageCommand struct {
@@ -361,28 +491,33 @@ func (cmd *CollectGarbageCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CollectGarbageCommand) Run(conn *hc.Conn) error {
+func (cmd *CollectGarbageCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CollectGarbage(conn *hc.Conn) (err error) {
+func CollectGarbage(conn hc.CommandRunner) (err error) {
 	cmd := NewCollectGarbageCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type CollectGarbageCB func(err error)
 
 type AsyncCollectGarbageCommand struct {
-	cb CollectGarbageCB
+	cb   CollectGarbageCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCollectGarbageCommand(cb CollectGarbageCB) *AsyncCollectGarbageCommand {
 	return &AsyncCollectGarbageCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -400,7 +535,28 @@ func (cmd *CollectGarbageCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncCollectGarbageCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCollectGarbageCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CollectGarbageCommand) Send(conn hc.CommandRunner) *AsyncCollectGarbageCommand {
+	async := NewAsyncCollectGarbageCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetObjectByHeapObjectIdParams struct {
@@ -433,17 +589,21 @@ func (cmd *GetObjectByHeapObjectIdCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetObjectByHeapObjectIdCommand) Run(conn *hc.Conn) error {
+func (cmd *GetObjectByHeapObjectIdCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetObjectByHeapObjectId(params *GetObjectByHeapObjectIdParams, conn *hc.Conn) (result *GetObjectByHeapObjectIdResult, err error) {
+func GetObjectByHeapObjectId(params *GetObjectByHeapObjectIdParams, conn hc.CommandRunner) (result *GetObjectByHeapObjectIdResult, err error) {
 	cmd := NewGetObjectByHeapObjectIdCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetObjectByHeapObjectIdCB func(result *GetObjectByHeapObjectIdResult, err error)
@@ -451,12 +611,17 @@ type GetObjectByHeapObjectIdCB func(result *GetObjectByHeapObjectIdResult, err e
 type AsyncGetObjectByHeapObjectIdCommand struct {
 	params *GetObjectByHeapObjectIdParams
 	cb     GetObjectByHeapObjectIdCB
+	result *GetObjectByHeapObjectIdResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetObjectByHeapObjectIdCommand(params *GetObjectByHeapObjectIdParams, cb GetObjectByHeapObjectIdCB) *AsyncGetObjectByHeapObjectIdCommand {
 	return &AsyncGetObjectByHeapObjectIdCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -485,21 +650,38 @@ func (cmd *AsyncGetObjectByHeapObjectIdCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetObjectByHeapObjectIdCommand) Wait(ctx context.Context) (*GetObjectByHeapObjectIdResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetObjectByHeapObjectIdCommand) Send(conn hc.CommandRunner) *AsyncGetObjectByHeapObjectIdCommand {
+	async := NewAsyncGetObjectByHeapObjectIdCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AddInspectedHeapObjectParams struct {
 	HeapObjectId HeapSnapshotObjectId `json:"heapObjectId"` // Heap snapshot object id to be accessible by means of $x command line API.
 }
 
 // Enables console to refer to the node with given id via $x (see Command Line API for more details $x functions).
-
 type AddInspectedHeapObjectCommand struct {
 	params *AddInspectedHeapObjectParams
 	wg     sync.WaitGroup
@@ -520,32 +702,36 @@ func (cmd *AddInspectedHeapObjectCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *AddInspectedHeapObjectCommand) Run(conn *hc.Conn) error {
+func (cmd *AddInspectedHeapObjectCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AddInspectedHeapObject(params *AddInspectedHeapObjectParams, conn *hc.Conn) (err error) {
+func AddInspectedHeapObject(params *AddInspectedHeapObjectParams, conn hc.CommandRunner) (err error) {
 	cmd := NewAddInspectedHeapObjectCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type AddInspectedHeapObjectCB func(err error)
 
 // Enables console to refer to the node with given id via $x (see Command Line API for more details $x functions).
-
 type AsyncAddInspectedHeapObjectCommand struct {
 	params *AddInspectedHeapObjectParams
 	cb     AddInspectedHeapObjectCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAddInspectedHeapObjectCommand(params *AddInspectedHeapObjectParams, cb AddInspectedHeapObjectCB) *AsyncAddInspectedHeapObjectCommand {
 	return &AsyncAddInspectedHeapObjectCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -563,11 +749,32 @@ func (cmd *AddInspectedHeapObjectCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncAddInspectedHeapObjectCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAddInspectedHeapObjectCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AddInspectedHeapObjectCommand) Send(conn hc.CommandRunner) *AsyncAddInspectedHeapObjectCommand {
+	async := NewAsyncAddInspectedHeapObjectCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetHeapObjectIdParams struct {
-	ObjectId *RemoteObjectId `json:"objectId"` // Identifier of the object to get heap object id for.
+	ObjectId RemoteObjectId `json:"objectId"` // Identifier of the object to get heap object id for.
 }
 
 type GetHeapObjectIdResult struct {
@@ -595,17 +802,21 @@ func (cmd *GetHeapObjectIdCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetHeapObjectIdCommand) Run(conn *hc.Conn) error {
+func (cmd *GetHeapObjectIdCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetHeapObjectId(params *GetHeapObjectIdParams, conn *hc.Conn) (result *GetHeapObjectIdResult, err error) {
+func GetHeapObjectId(params *GetHeapObjectIdParams, conn hc.CommandRunner) (result *GetHeapObjectIdResult, err error) {
 	cmd := NewGetHeapObjectIdCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetHeapObjectIdCB func(result *GetHeapObjectIdResult, err error)
@@ -613,12 +824,17 @@ type GetHeapObjectIdCB func(result *GetHeapObjectIdResult, err error)
 type AsyncGetHeapObjectIdCommand struct {
 	params *GetHeapObjectIdParams
 	cb     GetHeapObjectIdCB
+	result *GetHeapObjectIdResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetHeapObjectIdCommand(params *GetHeapObjectIdParams, cb GetHeapObjectIdCB) *AsyncGetHeapObjectIdCommand {
 	return &AsyncGetHeapObjectIdCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -647,15 +863,33 @@ func (cmd *AsyncGetHeapObjectIdCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetHeapObjectIdCommand) Wait(ctx context.Context) (*GetHeapObjectIdResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetHeapObjectIdCommand) Send(conn hc.CommandRunner) *AsyncGetHeapObjectIdCommand {
+	async := NewAsyncGetHeapObjectIdCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type StartSamplingParams struct {
 	SamplingInterval float64 `json:"samplingInterval,omitempty"` // Average sample interval in bytes. Poisson distribution is used for the intervals. The default value is 32768 bytes.
 }
@@ -680,17 +914,18 @@ func (cmd *StartSamplingCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StartSamplingCommand) Run(conn *hc.Conn) error {
+func (cmd *StartSamplingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StartSampling(params *StartSamplingParams, conn *hc.Conn) (err error) {
+func StartSampling(params *StartSamplingParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStartSamplingCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StartSamplingCB func(err error)
@@ -698,12 +933,16 @@ type StartSamplingCB func(err error)
 type AsyncStartSamplingCommand struct {
 	params *StartSamplingParams
 	cb     StartSamplingCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStartSamplingCommand(params *StartSamplingParams, cb StartSamplingCB) *AsyncStartSamplingCommand {
 	return &AsyncStartSamplingCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -721,7 +960,28 @@ func (cmd *StartSamplingCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStartSamplingCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStartSamplingCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StartSamplingCommand) Send(conn hc.CommandRunner) *AsyncStartSamplingCommand {
+	async := NewAsyncStartSamplingCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StopSamplingResult struct {
@@ -746,28 +1006,37 @@ func (cmd *StopSamplingCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StopSamplingCommand) Run(conn *hc.Conn) error {
+func (cmd *StopSamplingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopSampling(conn *hc.Conn) (result *StopSamplingResult, err error) {
+func StopSampling(conn hc.CommandRunner) (result *StopSamplingResult, err error) {
 	cmd := NewStopSamplingCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type StopSamplingCB func(result *StopSamplingResult, err error)
 
 type AsyncStopSamplingCommand struct {
-	cb StopSamplingCB
+	cb     StopSamplingCB
+	result *StopSamplingResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopSamplingCommand(cb StopSamplingCB) *AsyncStopSamplingCommand {
 	return &AsyncStopSamplingCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -796,44 +1065,94 @@ func (cmd *AsyncStopSamplingCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopSamplingCommand) Wait(ctx context.Context) (*StopSamplingResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopSamplingCommand) Send(conn hc.CommandRunner) *AsyncStopSamplingCommand {
+	async := NewAsyncStopSamplingCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AddHeapSnapshotChunkEvent struct {
 	Chunk string `json:"chunk"`
 }
 
-func OnAddHeapSnapshotChunk(conn *hc.Conn, cb func(evt *AddHeapSnapshotChunkEvent)) {
+// Calls cb for each HeapProfiler.addHeapSnapshotChunk event, till the returned func is called.
+func OnAddHeapSnapshotChunk(conn hc.EventSource, cb func(evt *AddHeapSnapshotChunkEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AddHeapSnapshotChunkEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("HeapProfiler.addHeapSnapshotChunk", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.addHeapSnapshotChunk", sink) }
+}
+
+// Like OnAddHeapSnapshotChunk, but cb also gets hc.EventMeta.
+func OnAddHeapSnapshotChunkMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AddHeapSnapshotChunkEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AddHeapSnapshotChunkEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeapProfiler.addHeapSnapshotChunk", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.addHeapSnapshotChunk", sink) }
 }
 
 type ResetProfilesEvent struct {
 }
 
-func OnResetProfiles(conn *hc.Conn, cb func(evt *ResetProfilesEvent)) {
+// Calls cb for each HeapProfiler.resetProfiles event, till the returned func is called.
+func OnResetProfiles(conn hc.EventSource, cb func(evt *ResetProfilesEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ResetProfilesEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("HeapProfiler.resetProfiles", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.resetProfiles", sink) }
+}
+
+// Like OnResetProfiles, but cb also gets hc.EventMeta.
+func OnResetProfilesMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ResetProfilesEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ResetProfilesEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeapProfiler.resetProfiles", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.resetProfiles", sink) }
 }
 
 type ReportHeapSnapshotProgressEvent struct {
@@ -842,51 +1161,97 @@ type ReportHeapSnapshotProgressEvent struct {
 	Finished bool `json:"finished"`
 }
 
-func OnReportHeapSnapshotProgress(conn *hc.Conn, cb func(evt *ReportHeapSnapshotProgressEvent)) {
+// Calls cb for each HeapProfiler.reportHeapSnapshotProgress event, till the returned func is called.
+func OnReportHeapSnapshotProgress(conn hc.EventSource, cb func(evt *ReportHeapSnapshotProgressEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ReportHeapSnapshotProgressEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("HeapProfiler.reportHeapSnapshotProgress", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.reportHeapSnapshotProgress", sink) }
 }
 
-// If heap objects tracking has been started then backend regulary sends a current value for last seen object id and corresponding timestamp. If the were changes in the heap since last event then one or more heapStatsUpdate events will be sent before a new lastSeenObjectId event.
+// Like OnReportHeapSnapshotProgress, but cb also gets hc.EventMeta.
+func OnReportHeapSnapshotProgressMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ReportHeapSnapshotProgressEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ReportHeapSnapshotProgressEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeapProfiler.reportHeapSnapshotProgress", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.reportHeapSnapshotProgress", sink) }
+}
 
+// If heap objects tracking has been started then backend regulary sends a current value for last seen object id and corresponding timestamp. If the were changes in the heap since last event then one or more heapStatsUpdate events will be sent before a new lastSeenObjectId event.
 type LastSeenObjectIdEvent struct {
 	LastSeenObjectId int     `json:"lastSeenObjectId"`
 	Timestamp        float64 `json:"timestamp"`
 }
 
-func OnLastSeenObjectId(conn *hc.Conn, cb func(evt *LastSeenObjectIdEvent)) {
+// Calls cb for each HeapProfiler.lastSeenObjectId event, till the returned func is called.
+func OnLastSeenObjectId(conn hc.EventSource, cb func(evt *LastSeenObjectIdEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &LastSeenObjectIdEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("HeapProfiler.lastSeenObjectId", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.lastSeenObjectId", sink) }
 }
 
-// If heap objects tracking has been started then backend may send update for one or more fragments
+// Like OnLastSeenObjectId, but cb also gets hc.EventMeta.
+func OnLastSeenObjectIdMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LastSeenObjectIdEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LastSeenObjectIdEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeapProfiler.lastSeenObjectId", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.lastSeenObjectId", sink) }
+}
 
+// If heap objects tracking has been started then backend may send update for one or more fragments
 type HeapStatsUpdateEvent struct {
 	StatsUpdate []int `json:"statsUpdate"` // An array of triplets. Each triplet describes a fragment. The first integer is the fragment index, the second integer is a total count of objects for the fragment, the third integer is a total size of the objects for the fragment.
 }
 
-func OnHeapStatsUpdate(conn *hc.Conn, cb func(evt *HeapStatsUpdateEvent)) {
+// Calls cb for each HeapProfiler.heapStatsUpdate event, till the returned func is called.
+func OnHeapStatsUpdate(conn hc.EventSource, cb func(evt *HeapStatsUpdateEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &HeapStatsUpdateEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("HeapProfiler.heapStatsUpdate", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.heapStatsUpdate", sink) }
+}
+
+// Like OnHeapStatsUpdate, but cb also gets hc.EventMeta.
+func OnHeapStatsUpdateMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *HeapStatsUpdateEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &HeapStatsUpdateEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("HeapProfiler.heapStatsUpdate", sink)
+	return func() { conn.RemoveEventSink("HeapProfiler.heapStatsUpdate", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -62,7 +62,6 @@ type KeyPath struct {
 }
 
 // Enables events from backend.
-
 type IndexedDBEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -80,30 +79,34 @@ func (cmd *IndexedDBEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *IndexedDBEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *IndexedDBEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func IndexedDBEnable(conn *hc.Conn) (err error) {
+func IndexedDBEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewIndexedDBEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type IndexedDBEnableCB func(err error)
 
 // Enables events from backend.
-
 type AsyncIndexedDBEnableCommand struct {
-	cb IndexedDBEnableCB
+	cb   IndexedDBEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncIndexedDBEnableCommand(cb IndexedDBEnableCB) *AsyncIndexedDBEnableCommand {
 	return &AsyncIndexedDBEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -121,11 +124,31 @@ func (cmd *IndexedDBEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncIndexedDBEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables events from backend.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncIndexedDBEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *IndexedDBEnableCommand) Send(conn hc.CommandRunner) *AsyncIndexedDBEnableCommand {
+	async := NewAsyncIndexedDBEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Disables events from backend.
 type IndexedDBDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -143,30 +166,34 @@ func (cmd *IndexedDBDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *IndexedDBDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *IndexedDBDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func IndexedDBDisable(conn *hc.Conn) (err error) {
+func IndexedDBDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewIndexedDBDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type IndexedDBDisableCB func(err error)
 
 // Disables events from backend.
-
 type AsyncIndexedDBDisableCommand struct {
-	cb IndexedDBDisableCB
+	cb   IndexedDBDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncIndexedDBDisableCommand(cb IndexedDBDisableCB) *AsyncIndexedDBDisableCommand {
 	return &AsyncIndexedDBDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -184,7 +211,28 @@ func (cmd *IndexedDBDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncIndexedDBDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncIndexedDBDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *IndexedDBDisableCommand) Send(conn hc.CommandRunner) *AsyncIndexedDBDisableCommand {
+	async := NewAsyncIndexedDBDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RequestDatabaseNamesParams struct {
@@ -196,7 +244,6 @@ type RequestDatabaseNamesResult struct {
 }
 
 // Requests database names for given security origin.
-
 type RequestDatabaseNamesCommand struct {
 	params *RequestDatabaseNamesParams
 	result RequestDatabaseNamesResult
@@ -218,32 +265,40 @@ func (cmd *RequestDatabaseNamesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestDatabaseNamesCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestDatabaseNamesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestDatabaseNames(params *RequestDatabaseNamesParams, conn *hc.Conn) (result *RequestDatabaseNamesResult, err error) {
+func RequestDatabaseNames(params *RequestDatabaseNamesParams, conn hc.CommandRunner) (result *RequestDatabaseNamesResult, err error) {
 	cmd := NewRequestDatabaseNamesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestDatabaseNamesCB func(result *RequestDatabaseNamesResult, err error)
 
 // Requests database names for given security origin.
-
 type AsyncRequestDatabaseNamesCommand struct {
 	params *RequestDatabaseNamesParams
 	cb     RequestDatabaseNamesCB
+	result *RequestDatabaseNamesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestDatabaseNamesCommand(params *RequestDatabaseNamesParams, cb RequestDatabaseNamesCB) *AsyncRequestDatabaseNamesCommand {
 	return &AsyncRequestDatabaseNamesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -272,15 +327,33 @@ func (cmd *AsyncRequestDatabaseNamesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestDatabaseNamesCommand) Wait(ctx context.Context) (*RequestDatabaseNamesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestDatabaseNamesCommand) Send(conn hc.CommandRunner) *AsyncRequestDatabaseNamesCommand {
+	async := NewAsyncRequestDatabaseNamesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RequestDatabaseParams struct {
 	SecurityOrigin string `json:"securityOrigin"` // Security origin.
 	DatabaseName   string `json:"databaseName"`   // Database name.
@@ -291,7 +364,6 @@ type RequestDatabaseResult struct {
 }
 
 // Requests database with given name in given frame.
-
 type RequestDatabaseCommand struct {
 	params *RequestDatabaseParams
 	result RequestDatabaseResult
@@ -313,32 +385,40 @@ func (cmd *RequestDatabaseCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestDatabaseCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestDatabaseCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestDatabase(params *RequestDatabaseParams, conn *hc.Conn) (result *RequestDatabaseResult, err error) {
+func RequestDatabase(params *RequestDatabaseParams, conn hc.CommandRunner) (result *RequestDatabaseResult, err error) {
 	cmd := NewRequestDatabaseCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestDatabaseCB func(result *RequestDatabaseResult, err error)
 
 // Requests database with given name in given frame.
-
 type AsyncRequestDatabaseCommand struct {
 	params *RequestDatabaseParams
 	cb     RequestDatabaseCB
+	result *RequestDatabaseResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestDatabaseCommand(params *RequestDatabaseParams, cb RequestDatabaseCB) *AsyncRequestDatabaseCommand {
 	return &AsyncRequestDatabaseCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -367,15 +447,33 @@ func (cmd *AsyncRequestDatabaseCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestDatabaseCommand) Wait(ctx context.Context) (*RequestDatabaseResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestDatabaseCommand) Send(conn hc.CommandRunner) *AsyncRequestDatabaseCommand {
+	async := NewAsyncRequestDatabaseCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RequestDataParams struct {
 	SecurityOrigin  string    `json:"securityOrigin"`     // Security origin.
 	DatabaseName    string    `json:"databaseName"`       // Database name.
@@ -392,7 +490,6 @@ type RequestDataResult struct {
 }
 
 // Requests data from object store or index.
-
 type RequestDataCommand struct {
 	params *RequestDataParams
 	result RequestDataResult
@@ -414,32 +511,40 @@ func (cmd *RequestDataCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RequestDataCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestDataCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestData(params *RequestDataParams, conn *hc.Conn) (result *RequestDataResult, err error) {
+func RequestData(params *RequestDataParams, conn hc.CommandRunner) (result *RequestDataResult, err error) {
 	cmd := NewRequestDataCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestDataCB func(result *RequestDataResult, err error)
 
 // Requests data from object store or index.
-
 type AsyncRequestDataCommand struct {
 	params *RequestDataParams
 	cb     RequestDataCB
+	result *RequestDataResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestDataCommand(params *RequestDataParams, cb RequestDataCB) *AsyncRequestDataCommand {
 	return &AsyncRequestDataCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -468,15 +573,33 @@ func (cmd *AsyncRequestDataCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestDataCommand) Wait(ctx context.Context) (*RequestDataResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestDataCommand) Send(conn hc.CommandRunner) *AsyncRequestDataCommand {
+	async := NewAsyncRequestDataCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ClearObjectStoreParams struct {
 	SecurityOrigin  string `json:"securityOrigin"`  // Security origin.
 	DatabaseName    string `json:"databaseName"`    // Database name.
@@ -484,7 +607,6 @@ type ClearObjectStoreParams struct {
 }
 
 // Clears all entries from an object store.
-
 type ClearObjectStoreCommand struct {
 	params *ClearObjectStoreParams
 	wg     sync.WaitGroup
@@ -505,32 +627,36 @@ func (cmd *ClearObjectStoreCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ClearObjectStoreCommand) Run(conn *hc.Conn) error {
+func (cmd *ClearObjectStoreCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ClearObjectStore(params *ClearObjectStoreParams, conn *hc.Conn) (err error) {
+func ClearObjectStore(params *ClearObjectStoreParams, conn hc.CommandRunner) (err error) {
 	cmd := NewClearObjectStoreCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ClearObjectStoreCB func(err error)
 
 // Clears all entries from an object store.
-
 type AsyncClearObjectStoreCommand struct {
 	params *ClearObjectStoreParams
 	cb     ClearObjectStoreCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncClearObjectStoreCommand(params *ClearObjectStoreParams, cb ClearObjectStoreCB) *AsyncClearObjectStoreCommand {
 	return &AsyncClearObjectStoreCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -548,5 +674,26 @@ func (cmd *ClearObjectStoreCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncClearObjectStoreCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearObjectStoreCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearObjectStoreCommand) Send(conn hc.CommandRunner) *AsyncClearObjectStoreCommand {
+	async := NewAsyncClearObjectStoreCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
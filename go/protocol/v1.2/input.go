@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -41,7 +42,6 @@ type DispatchKeyEventParams struct {
 }
 
 // Dispatches a key event to the page.
-
 type DispatchKeyEventCommand struct {
 	params *DispatchKeyEventParams
 	wg     sync.WaitGroup
@@ -62,32 +62,36 @@ func (cmd *DispatchKeyEventCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DispatchKeyEventCommand) Run(conn *hc.Conn) error {
+func (cmd *DispatchKeyEventCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DispatchKeyEvent(params *DispatchKeyEventParams, conn *hc.Conn) (err error) {
+func DispatchKeyEvent(params *DispatchKeyEventParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDispatchKeyEventCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DispatchKeyEventCB func(err error)
 
 // Dispatches a key event to the page.
-
 type AsyncDispatchKeyEventCommand struct {
 	params *DispatchKeyEventParams
 	cb     DispatchKeyEventCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDispatchKeyEventCommand(params *DispatchKeyEventParams, cb DispatchKeyEventCB) *AsyncDispatchKeyEventCommand {
 	return &AsyncDispatchKeyEventCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -105,7 +109,28 @@ func (cmd *DispatchKeyEventCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDispatchKeyEventCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDispatchKeyEventCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DispatchKeyEventCommand) Send(conn hc.CommandRunner) *AsyncDispatchKeyEventCommand {
+	async := NewAsyncDispatchKeyEventCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DispatchMouseEventParams struct {
@@ -119,7 +144,6 @@ type DispatchMouseEventParams struct {
 }
 
 // Dispatches a mouse event to the page.
-
 type DispatchMouseEventCommand struct {
 	params *DispatchMouseEventParams
 	wg     sync.WaitGroup
@@ -140,32 +164,36 @@ func (cmd *DispatchMouseEventCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DispatchMouseEventCommand) Run(conn *hc.Conn) error {
+func (cmd *DispatchMouseEventCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DispatchMouseEvent(params *DispatchMouseEventParams, conn *hc.Conn) (err error) {
+func DispatchMouseEvent(params *DispatchMouseEventParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDispatchMouseEventCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DispatchMouseEventCB func(err error)
 
 // Dispatches a mouse event to the page.
-
 type AsyncDispatchMouseEventCommand struct {
 	params *DispatchMouseEventParams
 	cb     DispatchMouseEventCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDispatchMouseEventCommand(params *DispatchMouseEventParams, cb DispatchMouseEventCB) *AsyncDispatchMouseEventCommand {
 	return &AsyncDispatchMouseEventCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -183,7 +211,28 @@ func (cmd *DispatchMouseEventCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDispatchMouseEventCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDispatchMouseEventCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DispatchMouseEventCommand) Send(conn hc.CommandRunner) *AsyncDispatchMouseEventCommand {
+	async := NewAsyncDispatchMouseEventCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DispatchTouchEventParams struct {
@@ -215,17 +264,18 @@ func (cmd *DispatchTouchEventCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DispatchTouchEventCommand) Run(conn *hc.Conn) error {
+func (cmd *DispatchTouchEventCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DispatchTouchEvent(params *DispatchTouchEventParams, conn *hc.Conn) (err error) {
+func DispatchTouchEvent(params *DispatchTouchEventParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDispatchTouchEventCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DispatchTouchEventCB func(err error)
@@ -235,12 +285,16 @@ type DispatchTouchEventCB func(err error)
 type AsyncDispatchTouchEventCommand struct {
 	params *DispatchTouchEventParams
 	cb     DispatchTouchEventCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDispatchTouchEventCommand(params *DispatchTouchEventParams, cb DispatchTouchEventCB) *AsyncDispatchTouchEventCommand {
 	return &AsyncDispatchTouchEventCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -258,7 +312,28 @@ func (cmd *DispatchTouchEventCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDispatchTouchEventCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDispatchTouchEventCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DispatchTouchEventCommand) Send(conn hc.CommandRunner) *AsyncDispatchTouchEventCommand {
+	async := NewAsyncDispatchTouchEventCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type EmulateTouchFromMouseEventParams struct {
@@ -295,17 +370,18 @@ func (cmd *EmulateTouchFromMouseEventCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EmulateTouchFromMouseEventCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulateTouchFromMouseEventCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulateTouchFromMouseEvent(params *EmulateTouchFromMouseEventParams, conn *hc.Conn) (err error) {
+func EmulateTouchFromMouseEvent(params *EmulateTouchFromMouseEventParams, conn hc.CommandRunner) (err error) {
 	cmd := NewEmulateTouchFromMouseEventCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulateTouchFromMouseEventCB func(err error)
@@ -315,12 +391,16 @@ type EmulateTouchFromMouseEventCB func(err error)
 type AsyncEmulateTouchFromMouseEventCommand struct {
 	params *EmulateTouchFromMouseEventParams
 	cb     EmulateTouchFromMouseEventCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulateTouchFromMouseEventCommand(params *EmulateTouchFromMouseEventParams, cb EmulateTouchFromMouseEventCB) *AsyncEmulateTouchFromMouseEventCommand {
 	return &AsyncEmulateTouchFromMouseEventCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -338,7 +418,28 @@ func (cmd *EmulateTouchFromMouseEventCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncEmulateTouchFromMouseEventCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulateTouchFromMouseEventCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulateTouchFromMouseEventCommand) Send(conn hc.CommandRunner) *AsyncEmulateTouchFromMouseEventCommand {
+	async := NewAsyncEmulateTouchFromMouseEventCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SynthesizePinchGestureParams struct {
@@ -371,17 +472,18 @@ func (cmd *SynthesizePinchGestureCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SynthesizePinchGestureCommand) Run(conn *hc.Conn) error {
+func (cmd *SynthesizePinchGestureCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SynthesizePinchGesture(params *SynthesizePinchGestureParams, conn *hc.Conn) (err error) {
+func SynthesizePinchGesture(params *SynthesizePinchGestureParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSynthesizePinchGestureCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SynthesizePinchGestureCB func(err error)
@@ -391,12 +493,16 @@ type SynthesizePinchGestureCB func(err error)
 type AsyncSynthesizePinchGestureCommand struct {
 	params *SynthesizePinchGestureParams
 	cb     SynthesizePinchGestureCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSynthesizePinchGestureCommand(params *SynthesizePinchGestureParams, cb SynthesizePinchGestureCB) *AsyncSynthesizePinchGestureCommand {
 	return &AsyncSynthesizePinchGestureCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -414,7 +520,28 @@ func (cmd *SynthesizePinchGestureCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSynthesizePinchGestureCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSynthesizePinchGestureCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SynthesizePinchGestureCommand) Send(conn hc.CommandRunner) *AsyncSynthesizePinchGestureCommand {
+	async := NewAsyncSynthesizePinchGestureCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SynthesizeScrollGestureParams struct {
@@ -454,17 +581,18 @@ func (cmd *SynthesizeScrollGestureCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SynthesizeScrollGestureCommand) Run(conn *hc.Conn) error {
+func (cmd *SynthesizeScrollGestureCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SynthesizeScrollGesture(params *SynthesizeScrollGestureParams, conn *hc.Conn) (err error) {
+func SynthesizeScrollGesture(params *SynthesizeScrollGestureParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSynthesizeScrollGestureCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SynthesizeScrollGestureCB func(err error)
@@ -474,12 +602,16 @@ type SynthesizeScrollGestureCB func(err error)
 type AsyncSynthesizeScrollGestureCommand struct {
 	params *SynthesizeScrollGestureParams
 	cb     SynthesizeScrollGestureCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSynthesizeScrollGestureCommand(params *SynthesizeScrollGestureParams, cb SynthesizeScrollGestureCB) *AsyncSynthesizeScrollGestureCommand {
 	return &AsyncSynthesizeScrollGestureCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -497,7 +629,28 @@ func (cmd *SynthesizeScrollGestureCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSynthesizeScrollGestureCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSynthesizeScrollGestureCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SynthesizeScrollGestureCommand) Send(conn hc.CommandRunner) *AsyncSynthesizeScrollGestureCommand {
+	async := NewAsyncSynthesizeScrollGestureCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SynthesizeTapGestureParams struct {
@@ -530,17 +683,18 @@ func (cmd *SynthesizeTapGestureCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SynthesizeTapGestureCommand) Run(conn *hc.Conn) error {
+func (cmd *SynthesizeTapGestureCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SynthesizeTapGesture(params *SynthesizeTapGestureParams, conn *hc.Conn) (err error) {
+func SynthesizeTapGesture(params *SynthesizeTapGestureParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSynthesizeTapGestureCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SynthesizeTapGestureCB func(err error)
@@ -550,12 +704,16 @@ type SynthesizeTapGestureCB func(err error)
 type AsyncSynthesizeTapGestureCommand struct {
 	params *SynthesizeTapGestureParams
 	cb     SynthesizeTapGestureCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSynthesizeTapGestureCommand(params *SynthesizeTapGestureParams, cb SynthesizeTapGestureCB) *AsyncSynthesizeTapGestureCommand {
 	return &AsyncSynthesizeTapGestureCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -573,5 +731,26 @@ func (cmd *SynthesizeTapGestureCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSynthesizeTapGestureCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSynthesizeTapGestureCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SynthesizeTapGestureCommand) Send(conn hc.CommandRunner) *AsyncSynthesizeTapGestureCommand {
+	async := NewAsyncSynthesizeTapGestureCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,14 +1,13 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
 
 // Enables inspector domain notifications.
-
 type InspectorEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -26,30 +25,34 @@ func (cmd *InspectorEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *InspectorEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *InspectorEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func InspectorEnable(conn *hc.Conn) (err error) {
+func InspectorEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewInspectorEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type InspectorEnableCB func(err error)
 
 // Enables inspector domain notifications.
-
 type AsyncInspectorEnableCommand struct {
-	cb InspectorEnableCB
+	cb   InspectorEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncInspectorEnableCommand(cb InspectorEnableCB) *AsyncInspectorEnableCommand {
 	return &AsyncInspectorEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -67,11 +70,31 @@ func (cmd *InspectorEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncInspectorEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables inspector domain notifications.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncInspectorEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *InspectorEnableCommand) Send(conn hc.CommandRunner) *AsyncInspectorEnableCommand {
+	async := NewAsyncInspectorEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables inspector domain notifications.
 type InspectorDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -89,30 +112,34 @@ func (cmd *InspectorDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *InspectorDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *InspectorDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func InspectorDisable(conn *hc.Conn) (err error) {
+func InspectorDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewInspectorDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type InspectorDisableCB func(err error)
 
 // Disables inspector domain notifications.
-
 type AsyncInspectorDisableCommand struct {
-	cb InspectorDisableCB
+	cb   InspectorDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncInspectorDisableCommand(cb InspectorDisableCB) *AsyncInspectorDisableCommand {
 	return &AsyncInspectorDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -130,40 +157,91 @@ func (cmd *InspectorDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncInspectorDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Fired when remote debugging connection is about to be terminated. Contains detach reason.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncInspectorDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *InspectorDisableCommand) Send(conn hc.CommandRunner) *AsyncInspectorDisableCommand {
+	async := NewAsyncInspectorDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Fired when remote debugging connection is about to be terminated. Contains detach reason.
 type DetachedEvent struct {
 	Reason string `json:"reason"` // The reason why connection has been terminated.
 }
 
-func OnDetached(conn *hc.Conn, cb func(evt *DetachedEvent)) {
+// Calls cb for each Inspector.detached event, till the returned func is called.
+func OnDetached(conn hc.EventSource, cb func(evt *DetachedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DetachedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Inspector.detached", sink)
+	return func() { conn.RemoveEventSink("Inspector.detached", sink) }
 }
 
-// Fired when debugging target has crashed
+// Like OnDetached, but cb also gets hc.EventMeta.
+func OnDetachedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DetachedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DetachedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Inspector.detached", sink)
+	return func() { conn.RemoveEventSink("Inspector.detached", sink) }
+}
 
+// Fired when debugging target has crashed
 type TargetCrashedEvent struct {
 }
 
-func OnTargetCrashed(conn *hc.Conn, cb func(evt *TargetCrashedEvent)) {
+// Calls cb for each Inspector.targetCrashed event, till the returned func is called.
+func OnTargetCrashed(conn hc.EventSource, cb func(evt *TargetCrashedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &TargetCrashedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Inspector.targetCrashed", sink)
+	return func() { conn.RemoveEventSink("Inspector.targetCrashed", sink) }
+}
+
+// Like OnTargetCrashed, but cb also gets hc.EventMeta.
+func OnTargetCrashedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *TargetCrashedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &TargetCrashedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Inspector.targetCrashed", sink)
+	return func() { conn.RemoveEventSink("Inspector.targetCrashed", sink) }
 }
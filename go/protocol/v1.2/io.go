@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -21,7 +21,6 @@ type ReadResult struct {
 }
 
 // Read a chunk of the stream
-
 type ReadCommand struct {
 	params *ReadParams
 	result ReadResult
@@ -43,32 +42,40 @@ func (cmd *ReadCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReadCommand) Run(conn *hc.Conn) error {
+func (cmd *ReadCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Read(params *ReadParams, conn *hc.Conn) (result *ReadResult, err error) {
+func Read(params *ReadParams, conn hc.CommandRunner) (result *ReadResult, err error) {
 	cmd := NewReadCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type ReadCB func(result *ReadResult, err error)
 
 // Read a chunk of the stream
-
 type AsyncReadCommand struct {
 	params *ReadParams
 	cb     ReadCB
+	result *ReadResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReadCommand(params *ReadParams, cb ReadCB) *AsyncReadCommand {
 	return &AsyncReadCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -97,13 +104,31 @@ func (cmd *AsyncReadCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReadCommand) Wait(ctx context.Context) (*ReadResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReadCommand) Send(conn hc.CommandRunner) *AsyncReadCommand {
+	async := NewAsyncReadCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CloseParams struct {
@@ -111,7 +136,6 @@ type CloseParams struct {
 }
 
 // Close the stream, discard any temporary backing storage.
-
 type CloseCommand struct {
 	params *CloseParams
 	wg     sync.WaitGroup
@@ -132,32 +156,36 @@ func (cmd *CloseCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CloseCommand) Run(conn *hc.Conn) error {
+func (cmd *CloseCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Close(params *CloseParams, conn *hc.Conn) (err error) {
+func Close(params *CloseParams, conn hc.CommandRunner) (err error) {
 	cmd := NewCloseCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type CloseCB func(err error)
 
 // Close the stream, discard any temporary backing storage.
-
 type AsyncCloseCommand struct {
 	params *CloseParams
 	cb     CloseCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCloseCommand(params *CloseParams, cb CloseCB) *AsyncCloseCommand {
 	return &AsyncCloseCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -175,5 +203,26 @@ func (cmd *CloseCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncCloseCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCloseCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CloseCommand) Send(conn hc.CommandRunner) *AsyncCloseCommand {
+	async := NewAsyncCloseCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -28,28 +28,27 @@ type PictureTile struct {
 
 // Information about a compositing layer.
 type Layer struct {
-	LayerId       LayerId        `json:"layerId"`                 // The unique id for this layer.
-	ParentLayerId LayerId        `json:"parentLayerId,omitempty"` // The id of parent (not present for root).
-	BackendNodeId *BackendNodeId `json:"backendNodeId,omitempty"` // The backend id for the node associated with this layer.
-	OffsetX       float64        `json:"offsetX"`                 // Offset from parent layer, X coordinate.
-	OffsetY       float64        `json:"offsetY"`                 // Offset from parent layer, Y coordinate.
-	Width         float64        `json:"width"`                   // Layer width.
-	Height        float64        `json:"height"`                  // Layer height.
-	Transform     []float64      `json:"transform,omitempty"`     // Transformation matrix for layer, default is identity matrix
-	AnchorX       float64        `json:"anchorX,omitempty"`       // Transform anchor point X, absent if no transform specified
-	AnchorY       float64        `json:"anchorY,omitempty"`       // Transform anchor point Y, absent if no transform specified
-	AnchorZ       float64        `json:"anchorZ,omitempty"`       // Transform anchor point Z, absent if no transform specified
-	PaintCount    int            `json:"paintCount"`              // Indicates how many time this layer has painted.
-	DrawsContent  bool           `json:"drawsContent"`            // Indicates whether this layer hosts any content, rather than being used for transform/scrolling purposes only.
-	Invisible     bool           `json:"invisible,omitempty"`     // Set if layer is not visible.
-	ScrollRects   []*ScrollRect  `json:"scrollRects,omitempty"`   // Rectangles scrolling on main thread only.
+	LayerId       LayerId       `json:"layerId"`                 // The unique id for this layer.
+	ParentLayerId LayerId       `json:"parentLayerId,omitempty"` // The id of parent (not present for root).
+	BackendNodeId BackendNodeId `json:"backendNodeId,omitempty"` // The backend id for the node associated with this layer.
+	OffsetX       float64       `json:"offsetX"`                 // Offset from parent layer, X coordinate.
+	OffsetY       float64       `json:"offsetY"`                 // Offset from parent layer, Y coordinate.
+	Width         float64       `json:"width"`                   // Layer width.
+	Height        float64       `json:"height"`                  // Layer height.
+	Transform     []float64     `json:"transform,omitempty"`     // Transformation matrix for layer, default is identity matrix
+	AnchorX       float64       `json:"anchorX,omitempty"`       // Transform anchor point X, absent if no transform specified
+	AnchorY       float64       `json:"anchorY,omitempty"`       // Transform anchor point Y, absent if no transform specified
+	AnchorZ       float64       `json:"anchorZ,omitempty"`       // Transform anchor point Z, absent if no transform specified
+	PaintCount    int           `json:"paintCount"`              // Indicates how many time this layer has painted.
+	DrawsContent  bool          `json:"drawsContent"`            // Indicates whether this layer hosts any content, rather than being used for transform/scrolling purposes only.
+	Invisible     bool          `json:"invisible,omitempty"`     // Set if layer is not visible.
+	ScrollRects   []*ScrollRect `json:"scrollRects,omitempty"`   // Rectangles scrolling on main thread only.
 }
 
 // Array of timings, one per paint step.
 type PaintProfile []float64
 
 // Enables compositing tree inspection.
-
 type LayerTreeEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -67,30 +66,34 @@ func (cmd *LayerTreeEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *LayerTreeEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *LayerTreeEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func LayerTreeEnable(conn *hc.Conn) (err error) {
+func LayerTreeEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewLayerTreeEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type LayerTreeEnableCB func(err error)
 
 // Enables compositing tree inspection.
-
 type AsyncLayerTreeEnableCommand struct {
-	cb LayerTreeEnableCB
+	cb   LayerTreeEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncLayerTreeEnableCommand(cb LayerTreeEnableCB) *AsyncLayerTreeEnableCommand {
 	return &AsyncLayerTreeEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -108,11 +111,31 @@ func (cmd *LayerTreeEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncLayerTreeEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables compositing tree inspection.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncLayerTreeEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *LayerTreeEnableCommand) Send(conn hc.CommandRunner) *AsyncLayerTreeEnableCommand {
+	async := NewAsyncLayerTreeEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables compositing tree inspection.
 type LayerTreeDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -130,30 +153,34 @@ func (cmd *LayerTreeDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *LayerTreeDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *LayerTreeDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func LayerTreeDisable(conn *hc.Conn) (err error) {
+func LayerTreeDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewLayerTreeDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type LayerTreeDisableCB func(err error)
 
 // Disables compositing tree inspection.
-
 type AsyncLayerTreeDisableCommand struct {
-	cb LayerTreeDisableCB
+	cb   LayerTreeDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncLayerTreeDisableCommand(cb LayerTreeDisableCB) *AsyncLayerTreeDisableCommand {
 	return &AsyncLayerTreeDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -171,7 +198,28 @@ func (cmd *LayerTreeDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncLayerTreeDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncLayerTreeDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *LayerTreeDisableCommand) Send(conn hc.CommandRunner) *AsyncLayerTreeDisableCommand {
+	async := NewAsyncLayerTreeDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CompositingReasonsParams struct {
@@ -183,7 +231,6 @@ type CompositingReasonsResult struct {
 }
 
 // Provides the reasons why the given layer was composited.
-
 type CompositingReasonsCommand struct {
 	params *CompositingReasonsParams
 	result CompositingReasonsResult
@@ -205,32 +252,40 @@ func (cmd *CompositingReasonsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CompositingReasonsCommand) Run(conn *hc.Conn) error {
+func (cmd *CompositingReasonsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CompositingReasons(params *CompositingReasonsParams, conn *hc.Conn) (result *CompositingReasonsResult, err error) {
+func CompositingReasons(params *CompositingReasonsParams, conn hc.CommandRunner) (result *CompositingReasonsResult, err error) {
 	cmd := NewCompositingReasonsCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CompositingReasonsCB func(result *CompositingReasonsResult, err error)
 
 // Provides the reasons why the given layer was composited.
-
 type AsyncCompositingReasonsCommand struct {
 	params *CompositingReasonsParams
 	cb     CompositingReasonsCB
+	result *CompositingReasonsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCompositingReasonsCommand(params *CompositingReasonsParams, cb CompositingReasonsCB) *AsyncCompositingReasonsCommand {
 	return &AsyncCompositingReasonsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -259,15 +314,33 @@ func (cmd *AsyncCompositingReasonsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCompositingReasonsCommand) Wait(ctx context.Context) (*CompositingReasonsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CompositingReasonsCommand) Send(conn hc.CommandRunner) *AsyncCompositingReasonsCommand {
+	async := NewAsyncCompositingReasonsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type MakeSnapshotParams struct {
 	LayerId LayerId `json:"layerId"` // The id of the layer.
 }
@@ -277,7 +350,6 @@ type MakeSnapshotResult struct {
 }
 
 // Returns the layer snapshot identifier.
-
 type MakeSnapshotCommand struct {
 	params *MakeSnapshotParams
 	result MakeSnapshotResult
@@ -299,32 +371,40 @@ func (cmd *MakeSnapshotCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *MakeSnapshotCommand) Run(conn *hc.Conn) error {
+func (cmd *MakeSnapshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func MakeSnapshot(params *MakeSnapshotParams, conn *hc.Conn) (result *MakeSnapshotResult, err error) {
+func MakeSnapshot(params *MakeSnapshotParams, conn hc.CommandRunner) (result *MakeSnapshotResult, err error) {
 	cmd := NewMakeSnapshotCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type MakeSnapshotCB func(result *MakeSnapshotResult, err error)
 
 // Returns the layer snapshot identifier.
-
 type AsyncMakeSnapshotCommand struct {
 	params *MakeSnapshotParams
 	cb     MakeSnapshotCB
+	result *MakeSnapshotResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncMakeSnapshotCommand(params *MakeSnapshotParams, cb MakeSnapshotCB) *AsyncMakeSnapshotCommand {
 	return &AsyncMakeSnapshotCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -353,15 +433,33 @@ func (cmd *AsyncMakeSnapshotCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncMakeSnapshotCommand) Wait(ctx context.Context) (*MakeSnapshotResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *MakeSnapshotCommand) Send(conn hc.CommandRunner) *AsyncMakeSnapshotCommand {
+	async := NewAsyncMakeSnapshotCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type LoadSnapshotParams struct {
 	Tiles []*PictureTile `json:"tiles"` // An array of tiles composing the snapshot.
 }
@@ -371,7 +469,6 @@ type LoadSnapshotResult struct {
 }
 
 // Returns the snapshot identifier.
-
 type LoadSnapshotCommand struct {
 	params *LoadSnapshotParams
 	result LoadSnapshotResult
@@ -393,32 +490,40 @@ func (cmd *LoadSnapshotCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *LoadSnapshotCommand) Run(conn *hc.Conn) error {
+func (cmd *LoadSnapshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func LoadSnapshot(params *LoadSnapshotParams, conn *hc.Conn) (result *LoadSnapshotResult, err error) {
+func LoadSnapshot(params *LoadSnapshotParams, conn hc.CommandRunner) (result *LoadSnapshotResult, err error) {
 	cmd := NewLoadSnapshotCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type LoadSnapshotCB func(result *LoadSnapshotResult, err error)
 
 // Returns the snapshot identifier.
-
 type AsyncLoadSnapshotCommand struct {
 	params *LoadSnapshotParams
 	cb     LoadSnapshotCB
+	result *LoadSnapshotResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncLoadSnapshotCommand(params *LoadSnapshotParams, cb LoadSnapshotCB) *AsyncLoadSnapshotCommand {
 	return &AsyncLoadSnapshotCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -447,21 +552,38 @@ func (cmd *AsyncLoadSnapshotCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncLoadSnapshotCommand) Wait(ctx context.Context) (*LoadSnapshotResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *LoadSnapshotCommand) Send(conn hc.CommandRunner) *AsyncLoadSnapshotCommand {
+	async := NewAsyncLoadSnapshotCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ReleaseSnapshotParams struct {
 	SnapshotId SnapshotId `json:"snapshotId"` // The id of the layer snapshot.
 }
 
 // Releases layer snapshot captured by the back-end.
-
 type ReleaseSnapshotCommand struct {
 	params *ReleaseSnapshotParams
 	wg     sync.WaitGroup
@@ -482,32 +604,36 @@ func (cmd *ReleaseSnapshotCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReleaseSnapshotCommand) Run(conn *hc.Conn) error {
+func (cmd *ReleaseSnapshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ReleaseSnapshot(params *ReleaseSnapshotParams, conn *hc.Conn) (err error) {
+func ReleaseSnapshot(params *ReleaseSnapshotParams, conn hc.CommandRunner) (err error) {
 	cmd := NewReleaseSnapshotCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ReleaseSnapshotCB func(err error)
 
 // Releases layer snapshot captured by the back-end.
-
 type AsyncReleaseSnapshotCommand struct {
 	params *ReleaseSnapshotParams
 	cb     ReleaseSnapshotCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReleaseSnapshotCommand(params *ReleaseSnapshotParams, cb ReleaseSnapshotCB) *AsyncReleaseSnapshotCommand {
 	return &AsyncReleaseSnapshotCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -525,7 +651,28 @@ func (cmd *ReleaseSnapshotCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncReleaseSnapshotCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReleaseSnapshotCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReleaseSnapshotCommand) Send(conn hc.CommandRunner) *AsyncReleaseSnapshotCommand {
+	async := NewAsyncReleaseSnapshotCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ProfileSnapshotParams struct {
@@ -560,17 +707,21 @@ func (cmd *ProfileSnapshotCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ProfileSnapshotCommand) Run(conn *hc.Conn) error {
+func (cmd *ProfileSnapshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ProfileSnapshot(params *ProfileSnapshotParams, conn *hc.Conn) (result *ProfileSnapshotResult, err error) {
+func ProfileSnapshot(params *ProfileSnapshotParams, conn hc.CommandRunner) (result *ProfileSnapshotResult, err error) {
 	cmd := NewProfileSnapshotCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type ProfileSnapshotCB func(result *ProfileSnapshotResult, err error)
@@ -578,12 +729,17 @@ type ProfileSnapshotCB func(result *ProfileSnapshotResult, err error)
 type AsyncProfileSnapshotCommand struct {
 	params *ProfileSnapshotParams
 	cb     ProfileSnapshotCB
+	result *ProfileSnapshotResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncProfileSnapshotCommand(params *ProfileSnapshotParams, cb ProfileSnapshotCB) *AsyncProfileSnapshotCommand {
 	return &AsyncProfileSnapshotCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -612,15 +768,33 @@ func (cmd *AsyncProfileSnapshotCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncProfileSnapshotCommand) Wait(ctx context.Context) (*ProfileSnapshotResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ProfileSnapshotCommand) Send(conn hc.CommandRunner) *AsyncProfileSnapshotCommand {
+	async := NewAsyncProfileSnapshotCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ReplaySnapshotParams struct {
 	SnapshotId SnapshotId `json:"snapshotId"`         // The id of the layer snapshot.
 	FromStep   int        `json:"fromStep,omitempty"` // The first step to replay from (replay from the very start if not specified).
@@ -633,7 +807,6 @@ type ReplaySnapshotResult struct {
 }
 
 // Replays the layer snapshot and returns the resulting bitmap.
-
 type ReplaySnapshotCommand struct {
 	params *ReplaySnapshotParams
 	result ReplaySnapshotResult
@@ -655,32 +828,40 @@ func (cmd *ReplaySnapshotCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReplaySnapshotCommand) Run(conn *hc.Conn) error {
+func (cmd *ReplaySnapshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ReplaySnapshot(params *ReplaySnapshotParams, conn *hc.Conn) (result *ReplaySnapshotResult, err error) {
+func ReplaySnapshot(params *ReplaySnapshotParams, conn hc.CommandRunner) (result *ReplaySnapshotResult, err error) {
 	cmd := NewReplaySnapshotCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type ReplaySnapshotCB func(result *ReplaySnapshotResult, err error)
 
 // Replays the layer snapshot and returns the resulting bitmap.
-
 type AsyncReplaySnapshotCommand struct {
 	params *ReplaySnapshotParams
 	cb     ReplaySnapshotCB
+	result *ReplaySnapshotResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReplaySnapshotCommand(params *ReplaySnapshotParams, cb ReplaySnapshotCB) *AsyncReplaySnapshotCommand {
 	return &AsyncReplaySnapshotCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -709,25 +890,42 @@ func (cmd *AsyncReplaySnapshotCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReplaySnapshotCommand) Wait(ctx context.Context) (*ReplaySnapshotResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReplaySnapshotCommand) Send(conn hc.CommandRunner) *AsyncReplaySnapshotCommand {
+	async := NewAsyncReplaySnapshotCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SnapshotCommandLogParams struct {
 	SnapshotId SnapshotId `json:"snapshotId"` // The id of the layer snapshot.
 }
 
 type SnapshotCommandLogResult struct {
-	CommandLog []map[string]string `json:"commandLog"` // The array of canvas function calls.
+	CommandLog []json.RawMessage `json:"commandLog"` // The array of canvas function calls.
 }
 
 // Replays the layer snapshot and returns canvas log.
-
 type SnapshotCommandLogCommand struct {
 	params *SnapshotCommandLogParams
 	result SnapshotCommandLogResult
@@ -749,32 +947,40 @@ func (cmd *SnapshotCommandLogCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SnapshotCommandLogCommand) Run(conn *hc.Conn) error {
+func (cmd *SnapshotCommandLogCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SnapshotCommandLog(params *SnapshotCommandLogParams, conn *hc.Conn) (result *SnapshotCommandLogResult, err error) {
+func SnapshotCommandLog(params *SnapshotCommandLogParams, conn hc.CommandRunner) (result *SnapshotCommandLogResult, err error) {
 	cmd := NewSnapshotCommandLogCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SnapshotCommandLogCB func(result *SnapshotCommandLogResult, err error)
 
 // Replays the layer snapshot and returns canvas log.
-
 type AsyncSnapshotCommandLogCommand struct {
 	params *SnapshotCommandLogParams
 	cb     SnapshotCommandLogCB
+	result *SnapshotCommandLogResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSnapshotCommandLogCommand(params *SnapshotCommandLogParams, cb SnapshotCommandLogCB) *AsyncSnapshotCommandLogCommand {
 	return &AsyncSnapshotCommandLogCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -803,29 +1009,63 @@ func (cmd *AsyncSnapshotCommandLogCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSnapshotCommandLogCommand) Wait(ctx context.Context) (*SnapshotCommandLogResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SnapshotCommandLogCommand) Send(conn hc.CommandRunner) *AsyncSnapshotCommandLogCommand {
+	async := NewAsyncSnapshotCommandLogCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type LayerTreeDidChangeEvent struct {
 	Layers []*Layer `json:"layers"` // Layer tree, absent if not in the comspositing mode.
 }
 
-func OnLayerTreeDidChange(conn *hc.Conn, cb func(evt *LayerTreeDidChangeEvent)) {
+// Calls cb for each LayerTree.layerTreeDidChange event, till the returned func is called.
+func OnLayerTreeDidChange(conn hc.EventSource, cb func(evt *LayerTreeDidChangeEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &LayerTreeDidChangeEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("LayerTree.layerTreeDidChange", sink)
+	return func() { conn.RemoveEventSink("LayerTree.layerTreeDidChange", sink) }
+}
+
+// Like OnLayerTreeDidChange, but cb also gets hc.EventMeta.
+func OnLayerTreeDidChangeMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LayerTreeDidChangeEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LayerTreeDidChangeEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("LayerTree.layerTreeDidChange", sink)
+	return func() { conn.RemoveEventSink("LayerTree.layerTreeDidChange", sink) }
 }
 
 type LayerPaintedEvent struct {
@@ -833,14 +1073,30 @@ type LayerPaintedEvent struct {
 	Clip    *Rect   `json:"clip"`    // Clip rectangle.
 }
 
-func OnLayerPainted(conn *hc.Conn, cb func(evt *LayerPaintedEvent)) {
+// Calls cb for each LayerTree.layerPainted event, till the returned func is called.
+func OnLayerPainted(conn hc.EventSource, cb func(evt *LayerPaintedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &LayerPaintedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("LayerTree.layerPainted", sink)
+	return func() { conn.RemoveEventSink("LayerTree.layerPainted", sink) }
+}
+
+// Like OnLayerPainted, but cb also gets hc.EventMeta.
+func OnLayerPaintedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LayerPaintedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LayerPaintedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("LayerTree.layerPainted", sink)
+	return func() { conn.RemoveEventSink("LayerTree.layerPainted", sink) }
 }
@@ -1,23 +1,23 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
 
 // Log entry.
 type LogEntry struct {
-	Source           string            `json:"source"`                     // Log entry source.
-	Level            string            `json:"level"`                      // Log entry severity.
-	Text             string            `json:"text"`                       // Logged text.
-	Timestamp        *RuntimeTimestamp `json:"timestamp"`                  // Timestamp when this entry was added.
-	Url              string            `json:"url,omitempty"`              // URL of the resource if known.
-	LineNumber       int               `json:"lineNumber,omitempty"`       // Line number in the resource.
-	StackTrace       *StackTrace       `json:"stackTrace,omitempty"`       // JavaScript stack trace.
-	NetworkRequestId *RequestId        `json:"networkRequestId,omitempty"` // Identifier of the network request associated with this entry.
-	WorkerId         string            `json:"workerId,omitempty"`         // Identifier of the worker associated with this entry.
+	Source           string           `json:"source"`                     // Log entry source.
+	Level            string           `json:"level"`                      // Log entry severity.
+	Text             string           `json:"text"`                       // Logged text.
+	Timestamp        RuntimeTimestamp `json:"timestamp"`                  // Timestamp when this entry was added.
+	Url              string           `json:"url,omitempty"`              // URL of the resource if known.
+	LineNumber       int              `json:"lineNumber,omitempty"`       // Line number in the resource.
+	StackTrace       *StackTrace      `json:"stackTrace,omitempty"`       // JavaScript stack trace.
+	NetworkRequestId RequestId        `json:"networkRequestId,omitempty"` // Identifier of the network request associated with this entry.
+	WorkerId         string           `json:"workerId,omitempty"`         // Identifier of the worker associated with this entry.
 }
 
 // Violation configuration setting.
@@ -27,7 +27,6 @@ type ViolationSetting struct {
 }
 
 // Enables log domain, sends the entries collected so far to the client by means of the entryAdded notification.
-
 type LogEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -45,30 +44,34 @@ func (cmd *LogEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *LogEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *LogEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func LogEnable(conn *hc.Conn) (err error) {
+func LogEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewLogEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type LogEnableCB func(err error)
 
 // Enables log domain, sends the entries collected so far to the client by means of the entryAdded notification.
-
 type AsyncLogEnableCommand struct {
-	cb LogEnableCB
+	cb   LogEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncLogEnableCommand(cb LogEnableCB) *AsyncLogEnableCommand {
 	return &AsyncLogEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -86,11 +89,31 @@ func (cmd *LogEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncLogEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables log domain, prevents further log entries from being reported to the client.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncLogEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *LogEnableCommand) Send(conn hc.CommandRunner) *AsyncLogEnableCommand {
+	async := NewAsyncLogEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Disables log domain, prevents further log entries from being reported to the client.
 type LogDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -108,30 +131,34 @@ func (cmd *LogDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *LogDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *LogDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func LogDisable(conn *hc.Conn) (err error) {
+func LogDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewLogDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type LogDisableCB func(err error)
 
 // Disables log domain, prevents further log entries from being reported to the client.
-
 type AsyncLogDisableCommand struct {
-	cb LogDisableCB
+	cb   LogDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncLogDisableCommand(cb LogDisableCB) *AsyncLogDisableCommand {
 	return &AsyncLogDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -149,11 +176,31 @@ func (cmd *LogDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncLogDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Clears the log.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncLogDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *LogDisableCommand) Send(conn hc.CommandRunner) *AsyncLogDisableCommand {
+	async := NewAsyncLogDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Clears the log.
 type ClearCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -171,30 +218,34 @@ func (cmd *ClearCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ClearCommand) Run(conn *hc.Conn) error {
+func (cmd *ClearCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Clear(conn *hc.Conn) (err error) {
+func Clear(conn hc.CommandRunner) (err error) {
 	cmd := NewClearCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ClearCB func(err error)
 
 // Clears the log.
-
 type AsyncClearCommand struct {
-	cb ClearCB
+	cb   ClearCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncClearCommand(cb ClearCB) *AsyncClearCommand {
 	return &AsyncClearCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -212,7 +263,28 @@ func (cmd *ClearCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncClearCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearCommand) Send(conn hc.CommandRunner) *AsyncClearCommand {
+	async := NewAsyncClearCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StartViolationsReportParams struct {
@@ -220,7 +292,6 @@ type StartViolationsReportParams struct {
 }
 
 // start violation reporting.
-
 type StartViolationsReportCommand struct {
 	params *StartViolationsReportParams
 	wg     sync.WaitGroup
@@ -241,32 +312,36 @@ func (cmd *StartViolationsReportCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StartViolationsReportCommand) Run(conn *hc.Conn) error {
+func (cmd *StartViolationsReportCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StartViolationsReport(params *StartViolationsReportParams, conn *hc.Conn) (err error) {
+func StartViolationsReport(params *StartViolationsReportParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStartViolationsReportCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StartViolationsReportCB func(err error)
 
 // start violation reporting.
-
 type AsyncStartViolationsReportCommand struct {
 	params *StartViolationsReportParams
 	cb     StartViolationsReportCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStartViolationsReportCommand(params *StartViolationsReportParams, cb StartViolationsReportCB) *AsyncStartViolationsReportCommand {
 	return &AsyncStartViolationsReportCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -284,11 +359,31 @@ func (cmd *StartViolationsReportCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStartViolationsReportCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Stop violation reporting.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStartViolationsReportCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StartViolationsReportCommand) Send(conn hc.CommandRunner) *AsyncStartViolationsReportCommand {
+	async := NewAsyncStartViolationsReportCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Stop violation reporting.
 type StopViolationsReportCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -306,30 +401,34 @@ func (cmd *StopViolationsReportCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StopViolationsReportCommand) Run(conn *hc.Conn) error {
+func (cmd *StopViolationsReportCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopViolationsReport(conn *hc.Conn) (err error) {
+func StopViolationsReport(conn hc.CommandRunner) (err error) {
 	cmd := NewStopViolationsReportCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StopViolationsReportCB func(err error)
 
 // Stop violation reporting.
-
 type AsyncStopViolationsReportCommand struct {
-	cb StopViolationsReportCB
+	cb   StopViolationsReportCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopViolationsReportCommand(cb StopViolationsReportCB) *AsyncStopViolationsReportCommand {
 	return &AsyncStopViolationsReportCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -347,23 +446,59 @@ func (cmd *StopViolationsReportCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStopViolationsReportCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Issued when new message was logged.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopViolationsReportCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopViolationsReportCommand) Send(conn hc.CommandRunner) *AsyncStopViolationsReportCommand {
+	async := NewAsyncStopViolationsReportCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Issued when new message was logged.
 type EntryAddedEvent struct {
 	Entry *LogEntry `json:"entry"` // The entry.
 }
 
-func OnEntryAdded(conn *hc.Conn, cb func(evt *EntryAddedEvent)) {
+// Calls cb for each Log.entryAdded event, till the returned func is called.
+func OnEntryAdded(conn hc.EventSource, cb func(evt *EntryAddedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &EntryAddedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Log.entryAdded", sink)
+	return func() { conn.RemoveEventSink("Log.entryAdded", sink) }
+}
+
+// Like OnEntryAdded, but cb also gets hc.EventMeta.
+func OnEntryAddedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *EntryAddedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &EntryAddedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Log.entryAdded", sink)
+	return func() { conn.RemoveEventSink("Log.entryAdded", sink) }
 }
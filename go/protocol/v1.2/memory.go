@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -37,28 +37,37 @@ func (cmd *GetDOMCountersCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetDOMCountersCommand) Run(conn *hc.Conn) error {
+func (cmd *GetDOMCountersCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetDOMCounters(conn *hc.Conn) (result *GetDOMCountersResult, err error) {
+func GetDOMCounters(conn hc.CommandRunner) (result *GetDOMCountersResult, err error) {
 	cmd := NewGetDOMCountersCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetDOMCountersCB func(result *GetDOMCountersResult, err error)
 
 type AsyncGetDOMCountersCommand struct {
-	cb GetDOMCountersCB
+	cb     GetDOMCountersCB
+	result *GetDOMCountersResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetDOMCountersCommand(cb GetDOMCountersCB) *AsyncGetDOMCountersCommand {
 	return &AsyncGetDOMCountersCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -87,21 +96,38 @@ func (cmd *AsyncGetDOMCountersCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetDOMCountersCommand) Wait(ctx context.Context) (*GetDOMCountersResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetDOMCountersCommand) Send(conn hc.CommandRunner) *AsyncGetDOMCountersCommand {
+	async := NewAsyncGetDOMCountersCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetPressureNotificationsSuppressedParams struct {
 	Suppressed bool `json:"suppressed"` // If true, memory pressure notifications will be suppressed.
 }
 
 // Enable/disable suppressing memory pressure notifications in all processes.
-
 type SetPressureNotificationsSuppressedCommand struct {
 	params *SetPressureNotificationsSuppressedParams
 	wg     sync.WaitGroup
@@ -122,32 +148,36 @@ func (cmd *SetPressureNotificationsSuppressedCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetPressureNotificationsSuppressedCommand) Run(conn *hc.Conn) error {
+func (cmd *SetPressureNotificationsSuppressedCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetPressureNotificationsSuppressed(params *SetPressureNotificationsSuppressedParams, conn *hc.Conn) (err error) {
+func SetPressureNotificationsSuppressed(params *SetPressureNotificationsSuppressedParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetPressureNotificationsSuppressedCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetPressureNotificationsSuppressedCB func(err error)
 
 // Enable/disable suppressing memory pressure notifications in all processes.
-
 type AsyncSetPressureNotificationsSuppressedCommand struct {
 	params *SetPressureNotificationsSuppressedParams
 	cb     SetPressureNotificationsSuppressedCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetPressureNotificationsSuppressedCommand(params *SetPressureNotificationsSuppressedParams, cb SetPressureNotificationsSuppressedCB) *AsyncSetPressureNotificationsSuppressedCommand {
 	return &AsyncSetPressureNotificationsSuppressedCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -165,7 +195,28 @@ func (cmd *SetPressureNotificationsSuppressedCommand) Done(data []byte, err erro
 }
 
 func (cmd *AsyncSetPressureNotificationsSuppressedCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetPressureNotificationsSuppressedCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetPressureNotificationsSuppressedCommand) Send(conn hc.CommandRunner) *AsyncSetPressureNotificationsSuppressedCommand {
+	async := NewAsyncSetPressureNotificationsSuppressedCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SimulatePressureNotificationParams struct {
@@ -173,7 +224,6 @@ type SimulatePressureNotificationParams struct {
 }
 
 // Simulate a memory pressure notification in all processes.
-
 type SimulatePressureNotificationCommand struct {
 	params *SimulatePressureNotificationParams
 	wg     sync.WaitGroup
@@ -194,32 +244,36 @@ func (cmd *SimulatePressureNotificationCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SimulatePressureNotificationCommand) Run(conn *hc.Conn) error {
+func (cmd *SimulatePressureNotificationCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SimulatePressureNotification(params *SimulatePressureNotificationParams, conn *hc.Conn) (err error) {
+func SimulatePressureNotification(params *SimulatePressureNotificationParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSimulatePressureNotificationCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SimulatePressureNotificationCB func(err error)
 
 // Simulate a memory pressure notification in all processes.
-
 type AsyncSimulatePressureNotificationCommand struct {
 	params *SimulatePressureNotificationParams
 	cb     SimulatePressureNotificationCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSimulatePressureNotificationCommand(params *SimulatePressureNotificationParams, cb SimulatePressureNotificationCB) *AsyncSimulatePressureNotificationCommand {
 	return &AsyncSimulatePressureNotificationCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -237,5 +291,26 @@ func (cmd *SimulatePressureNotificationCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSimulatePressureNotificationCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSimulatePressureNotificationCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SimulatePressureNotificationCommand) Send(conn hc.CommandRunner) *AsyncSimulatePressureNotificationCommand {
+	async := NewAsyncSimulatePressureNotificationCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -17,8 +17,7 @@ type RequestId string
 type NetworkTimestamp float64
 
 // Request / response headers as keys / values of JSON object.
-type Headers struct {
-}
+type Headers map[string]string
 
 // Loading priority of a resource request.
 type ConnectionType string
@@ -72,7 +71,7 @@ const ResourcePriorityVeryHigh ResourcePriority = "VeryHigh"
 type Request struct {
 	Url              string           `json:"url"`                        // Request URL.
 	Method           string           `json:"method"`                     // HTTP request method.
-	Headers          *Headers         `json:"headers"`                    // HTTP request headers.
+	Headers          Headers          `json:"headers"`                    // HTTP request headers.
 	PostData         string           `json:"postData,omitempty"`         // HTTP POST request data.
 	MixedContentType string           `json:"mixedContentType,omitempty"` // The mixed content status of the request, as defined in http://www.w3.org/TR/mixed-content/
 	InitialPriority  ResourcePriority `json:"initialPriority"`            // Priority of the resource request at the time request is sent.
@@ -98,7 +97,7 @@ type SecurityDetails struct {
 	KeyExchangeGroup               string                        `json:"keyExchangeGroup,omitempty"`     // (EC)DH group used by the connection, if applicable.
 	Cipher                         string                        `json:"cipher"`                         // Cipher name.
 	Mac                            string                        `json:"mac,omitempty"`                  // TLS MAC. Note that AEAD ciphers do not have separate MACs.
-	CertificateId                  *CertificateId                `json:"certificateId"`                  // Certificate ID value.
+	CertificateId                  CertificateId                 `json:"certificateId"`                  // Certificate ID value.
 	SubjectName                    string                        `json:"subjectName"`                    // Certificate subject name.
 	SanList                        []string                      `json:"sanList"`                        // Subject Alternative Name (SAN) DNS names and IP addresses.
 	Issuer                         string                        `json:"issuer"`                         // Name of the issuing CA.
@@ -123,10 +122,10 @@ type Response struct {
 	Url                string           `json:"url"`                          // Response URL. This URL can be different from CachedResource.url in case of redirect.
 	Status             float64          `json:"status"`                       // HTTP response status code.
 	StatusText         string           `json:"statusText"`                   // HTTP response status text.
-	Headers            *Headers         `json:"headers"`                      // HTTP response headers.
+	Headers            Headers          `json:"headers"`                      // HTTP response headers.
 	HeadersText        string           `json:"headersText,omitempty"`        // HTTP response headers text.
 	MimeType           string           `json:"mimeType"`                     // Resource mimeType as determined by the browser.
-	RequestHeaders     *Headers         `json:"requestHeaders,omitempty"`     // Refined HTTP request headers that were actually transmitted over the network.
+	RequestHeaders     Headers          `json:"requestHeaders,omitempty"`     // Refined HTTP request headers that were actually transmitted over the network.
 	RequestHeadersText string           `json:"requestHeadersText,omitempty"` // HTTP request headers text.
 	ConnectionReused   bool             `json:"connectionReused"`             // Specifies whether physical connection was actually reused for this request.
 	ConnectionId       float64          `json:"connectionId"`                 // Physical connection id that was actually used for this request.
@@ -137,25 +136,25 @@ type Response struct {
 	EncodedDataLength  float64          `json:"encodedDataLength"`            // Total number of bytes received for this request so far.
 	Timing             *ResourceTiming  `json:"timing,omitempty"`             // Timing information for the given request.
 	Protocol           string           `json:"protocol,omitempty"`           // Protocol used to fetch this request.
-	SecurityState      *SecurityState   `json:"securityState"`                // Security state of the request resource.
+	SecurityState      SecurityState    `json:"securityState"`                // Security state of the request resource.
 	SecurityDetails    *SecurityDetails `json:"securityDetails,omitempty"`    // Security details for the request.
 }
 
 // WebSocket request data.
 // @experimental
 type WebSocketRequest struct {
-	Headers *Headers `json:"headers"` // HTTP request headers.
+	Headers Headers `json:"headers"` // HTTP request headers.
 }
 
 // WebSocket response data.
 // @experimental
 type WebSocketResponse struct {
-	Status             float64  `json:"status"`                       // HTTP response status code.
-	StatusText         string   `json:"statusText"`                   // HTTP response status text.
-	Headers            *Headers `json:"headers"`                      // HTTP response headers.
-	HeadersText        string   `json:"headersText,omitempty"`        // HTTP response headers text.
-	RequestHeaders     *Headers `json:"requestHeaders,omitempty"`     // HTTP request headers.
-	RequestHeadersText string   `json:"requestHeadersText,omitempty"` // HTTP request headers text.
+	Status             float64 `json:"status"`                       // HTTP response status code.
+	StatusText         string  `json:"statusText"`                   // HTTP response status text.
+	Headers            Headers `json:"headers"`                      // HTTP response headers.
+	HeadersText        string  `json:"headersText,omitempty"`        // HTTP response headers text.
+	RequestHeaders     Headers `json:"requestHeaders,omitempty"`     // HTTP request headers.
+	RequestHeadersText string  `json:"requestHeadersText,omitempty"` // HTTP request headers text.
 }
 
 // WebSocket frame data.
@@ -168,10 +167,10 @@ type WebSocketFrame struct {
 
 // Information about the cached resource.
 type CachedResource struct {
-	Url      string        `json:"url"`                // Resource URL. This is the url of the original network request.
-	Type     *ResourceType `json:"type"`               // Type of this resource.
-	Response *Response     `json:"response,omitempty"` // Cached response data.
-	BodySize float64       `json:"bodySize"`           // Cached response body size.
+	Url      string       `json:"url"`                // Resource URL. This is the url of the original network request.
+	Type     ResourceType `json:"type"`               // Type of this resource.
+	Response *Response    `json:"response,omitempty"` // Cached response data.
+	BodySize float64      `json:"bodySize"`           // Cached response body size.
 }
 
 // Information about the request initiator.
@@ -203,7 +202,6 @@ type NetworkEnableParams struct {
 }
 
 // Enables network tracking, network events will now be delivered to the client.
-
 type NetworkEnableCommand struct {
 	params *NetworkEnableParams
 	wg     sync.WaitGroup
@@ -224,32 +222,36 @@ func (cmd *NetworkEnableCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *NetworkEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *NetworkEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func NetworkEnable(params *NetworkEnableParams, conn *hc.Conn) (err error) {
+func NetworkEnable(params *NetworkEnableParams, conn hc.CommandRunner) (err error) {
 	cmd := NewNetworkEnableCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type NetworkEnableCB func(err error)
 
 // Enables network tracking, network events will now be delivered to the client.
-
 type AsyncNetworkEnableCommand struct {
 	params *NetworkEnableParams
 	cb     NetworkEnableCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncNetworkEnableCommand(params *NetworkEnableParams, cb NetworkEnableCB) *AsyncNetworkEnableCommand {
 	return &AsyncNetworkEnableCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -267,11 +269,31 @@ func (cmd *NetworkEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncNetworkEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables network tracking, prevents network events from being sent to the client.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncNetworkEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *NetworkEnableCommand) Send(conn hc.CommandRunner) *AsyncNetworkEnableCommand {
+	async := NewAsyncNetworkEnableCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables network tracking, prevents network events from being sent to the client.
 type NetworkDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -289,30 +311,34 @@ func (cmd *NetworkDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *NetworkDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *NetworkDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func NetworkDisable(conn *hc.Conn) (err error) {
+func NetworkDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewNetworkDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type NetworkDisableCB func(err error)
 
 // Disables network tracking, prevents network events from being sent to the client.
-
 type AsyncNetworkDisableCommand struct {
-	cb NetworkDisableCB
+	cb   NetworkDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncNetworkDisableCommand(cb NetworkDisableCB) *AsyncNetworkDisableCommand {
 	return &AsyncNetworkDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -330,7 +356,28 @@ func (cmd *NetworkDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncNetworkDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncNetworkDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *NetworkDisableCommand) Send(conn hc.CommandRunner) *AsyncNetworkDisableCommand {
+	async := NewAsyncNetworkDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetUserAgentOverrideParams struct {
@@ -338,7 +385,6 @@ type SetUserAgentOverrideParams struct {
 }
 
 // Allows overriding user agent with the given string.
-
 type SetUserAgentOverrideCommand struct {
 	params *SetUserAgentOverrideParams
 	wg     sync.WaitGroup
@@ -359,32 +405,36 @@ func (cmd *SetUserAgentOverrideCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetUserAgentOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *SetUserAgentOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetUserAgentOverride(params *SetUserAgentOverrideParams, conn *hc.Conn) (err error) {
+func SetUserAgentOverride(params *SetUserAgentOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetUserAgentOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetUserAgentOverrideCB func(err error)
 
 // Allows overriding user agent with the given string.
-
 type AsyncSetUserAgentOverrideCommand struct {
 	params *SetUserAgentOverrideParams
 	cb     SetUserAgentOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetUserAgentOverrideCommand(params *SetUserAgentOverrideParams, cb SetUserAgentOverrideCB) *AsyncSetUserAgentOverrideCommand {
 	return &AsyncSetUserAgentOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -402,15 +452,35 @@ func (cmd *SetUserAgentOverrideCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetUserAgentOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetUserAgentOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetUserAgentOverrideCommand) Send(conn hc.CommandRunner) *AsyncSetUserAgentOverrideCommand {
+	async := NewAsyncSetUserAgentOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetExtraHTTPHeadersParams struct {
-	Headers *Headers `json:"headers"` // Map with extra HTTP headers.
+	Headers Headers `json:"headers"` // Map with extra HTTP headers.
 }
 
 // Specifies whether to always send extra HTTP headers with the requests from this page.
-
 type SetExtraHTTPHeadersCommand struct {
 	params *SetExtraHTTPHeadersParams
 	wg     sync.WaitGroup
@@ -431,32 +501,36 @@ func (cmd *SetExtraHTTPHeadersCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetExtraHTTPHeadersCommand) Run(conn *hc.Conn) error {
+func (cmd *SetExtraHTTPHeadersCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetExtraHTTPHeaders(params *SetExtraHTTPHeadersParams, conn *hc.Conn) (err error) {
+func SetExtraHTTPHeaders(params *SetExtraHTTPHeadersParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetExtraHTTPHeadersCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetExtraHTTPHeadersCB func(err error)
 
 // Specifies whether to always send extra HTTP headers with the requests from this page.
-
 type AsyncSetExtraHTTPHeadersCommand struct {
 	params *SetExtraHTTPHeadersParams
 	cb     SetExtraHTTPHeadersCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetExtraHTTPHeadersCommand(params *SetExtraHTTPHeadersParams, cb SetExtraHTTPHeadersCB) *AsyncSetExtraHTTPHeadersCommand {
 	return &AsyncSetExtraHTTPHeadersCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -474,7 +548,28 @@ func (cmd *SetExtraHTTPHeadersCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetExtraHTTPHeadersCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetExtraHTTPHeadersCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetExtraHTTPHeadersCommand) Send(conn hc.CommandRunner) *AsyncSetExtraHTTPHeadersCommand {
+	async := NewAsyncSetExtraHTTPHeadersCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetResponseBodyParams struct {
@@ -487,7 +582,6 @@ type GetResponseBodyResult struct {
 }
 
 // Returns content served for the given request.
-
 type GetResponseBodyCommand struct {
 	params *GetResponseBodyParams
 	result GetResponseBodyResult
@@ -509,32 +603,40 @@ func (cmd *GetResponseBodyCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetResponseBodyCommand) Run(conn *hc.Conn) error {
+func (cmd *GetResponseBodyCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetResponseBody(params *GetResponseBodyParams, conn *hc.Conn) (result *GetResponseBodyResult, err error) {
+func GetResponseBody(params *GetResponseBodyParams, conn hc.CommandRunner) (result *GetResponseBodyResult, err error) {
 	cmd := NewGetResponseBodyCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetResponseBodyCB func(result *GetResponseBodyResult, err error)
 
 // Returns content served for the given request.
-
 type AsyncGetResponseBodyCommand struct {
 	params *GetResponseBodyParams
 	cb     GetResponseBodyCB
+	result *GetResponseBodyResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetResponseBodyCommand(params *GetResponseBodyParams, cb GetResponseBodyCB) *AsyncGetResponseBodyCommand {
 	return &AsyncGetResponseBodyCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -563,15 +665,33 @@ func (cmd *AsyncGetResponseBodyCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetResponseBodyCommand) Wait(ctx context.Context) (*GetResponseBodyResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetResponseBodyCommand) Send(conn hc.CommandRunner) *AsyncGetResponseBodyCommand {
+	async := NewAsyncGetResponseBodyCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AddBlockedURLParams struct {
 	Url string `json:"url"` // URL to block.
 }
@@ -598,17 +718,18 @@ func (cmd *AddBlockedURLCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *AddBlockedURLCommand) Run(conn *hc.Conn) error {
+func (cmd *AddBlockedURLCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AddBlockedURL(params *AddBlockedURLParams, conn *hc.Conn) (err error) {
+func AddBlockedURL(params *AddBlockedURLParams, conn hc.CommandRunner) (err error) {
 	cmd := NewAddBlockedURLCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type AddBlockedURLCB func(err error)
@@ -618,12 +739,16 @@ type AddBlockedURLCB func(err error)
 type AsyncAddBlockedURLCommand struct {
 	params *AddBlockedURLParams
 	cb     AddBlockedURLCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAddBlockedURLCommand(params *AddBlockedURLParams, cb AddBlockedURLCB) *AsyncAddBlockedURLCommand {
 	return &AsyncAddBlockedURLCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -641,7 +766,28 @@ func (cmd *AddBlockedURLCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncAddBlockedURLCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAddBlockedURLCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AddBlockedURLCommand) Send(conn hc.CommandRunner) *AsyncAddBlockedURLCommand {
+	async := NewAsyncAddBlockedURLCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type RemoveBlockedURLParams struct {
@@ -670,17 +816,18 @@ func (cmd *RemoveBlockedURLCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveBlockedURLCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveBlockedURLCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveBlockedURL(params *RemoveBlockedURLParams, conn *hc.Conn) (err error) {
+func RemoveBlockedURL(params *RemoveBlockedURLParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveBlockedURLCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveBlockedURLCB func(err error)
@@ -690,12 +837,16 @@ type RemoveBlockedURLCB func(err error)
 type AsyncRemoveBlockedURLCommand struct {
 	params *RemoveBlockedURLParams
 	cb     RemoveBlockedURLCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveBlockedURLCommand(params *RemoveBlockedURLParams, cb RemoveBlockedURLCB) *AsyncRemoveBlockedURLCommand {
 	return &AsyncRemoveBlockedURLCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -713,7 +864,28 @@ func (cmd *RemoveBlockedURLCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveBlockedURLCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveBlockedURLCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveBlockedURLCommand) Send(conn hc.CommandRunner) *AsyncRemoveBlockedURLCommand {
+	async := NewAsyncRemoveBlockedURLCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ReplayXHRParams struct {
@@ -742,17 +914,18 @@ func (cmd *ReplayXHRCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReplayXHRCommand) Run(conn *hc.Conn) error {
+func (cmd *ReplayXHRCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ReplayXHR(params *ReplayXHRParams, conn *hc.Conn) (err error) {
+func ReplayXHR(params *ReplayXHRParams, conn hc.CommandRunner) (err error) {
 	cmd := NewReplayXHRCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ReplayXHRCB func(err error)
@@ -762,12 +935,16 @@ type ReplayXHRCB func(err error)
 type AsyncReplayXHRCommand struct {
 	params *ReplayXHRParams
 	cb     ReplayXHRCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReplayXHRCommand(params *ReplayXHRParams, cb ReplayXHRCB) *AsyncReplayXHRCommand {
 	return &AsyncReplayXHRCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -785,7 +962,28 @@ func (cmd *ReplayXHRCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncReplayXHRCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReplayXHRCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReplayXHRCommand) Send(conn hc.CommandRunner) *AsyncReplayXHRCommand {
+	async := NewAsyncReplayXHRCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetMonitoringXHREnabledParams struct {
@@ -814,17 +1012,18 @@ func (cmd *SetMonitoringXHREnabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetMonitoringXHREnabledCommand) Run(conn *hc.Conn) error {
+func (cmd *SetMonitoringXHREnabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetMonitoringXHREnabled(params *SetMonitoringXHREnabledParams, conn *hc.Conn) (err error) {
+func SetMonitoringXHREnabled(params *SetMonitoringXHREnabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetMonitoringXHREnabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetMonitoringXHREnabledCB func(err error)
@@ -834,12 +1033,16 @@ type SetMonitoringXHREnabledCB func(err error)
 type AsyncSetMonitoringXHREnabledCommand struct {
 	params *SetMonitoringXHREnabledParams
 	cb     SetMonitoringXHREnabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetMonitoringXHREnabledCommand(params *SetMonitoringXHREnabledParams, cb SetMonitoringXHREnabledCB) *AsyncSetMonitoringXHREnabledCommand {
 	return &AsyncSetMonitoringXHREnabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -857,7 +1060,28 @@ func (cmd *SetMonitoringXHREnabledCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetMonitoringXHREnabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetMonitoringXHREnabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetMonitoringXHREnabledCommand) Send(conn hc.CommandRunner) *AsyncSetMonitoringXHREnabledCommand {
+	async := NewAsyncSetMonitoringXHREnabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CanClearBrowserCacheResult struct {
@@ -865,7 +1089,6 @@ type CanClearBrowserCacheResult struct {
 }
 
 // Tells whether clearing browser cache is supported.
-
 type CanClearBrowserCacheCommand struct {
 	result CanClearBrowserCacheResult
 	wg     sync.WaitGroup
@@ -884,30 +1107,38 @@ func (cmd *CanClearBrowserCacheCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CanClearBrowserCacheCommand) Run(conn *hc.Conn) error {
+func (cmd *CanClearBrowserCacheCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CanClearBrowserCache(conn *hc.Conn) (result *CanClearBrowserCacheResult, err error) {
+func CanClearBrowserCache(conn hc.CommandRunner) (result *CanClearBrowserCacheResult, err error) {
 	cmd := NewCanClearBrowserCacheCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CanClearBrowserCacheCB func(result *CanClearBrowserCacheResult, err error)
 
 // Tells whether clearing browser cache is supported.
-
 type AsyncCanClearBrowserCacheCommand struct {
-	cb CanClearBrowserCacheCB
+	cb     CanClearBrowserCacheCB
+	result *CanClearBrowserCacheResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCanClearBrowserCacheCommand(cb CanClearBrowserCacheCB) *AsyncCanClearBrowserCacheCommand {
 	return &AsyncCanClearBrowserCacheCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -936,17 +1167,34 @@ func (cmd *AsyncCanClearBrowserCacheCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Clears browser cache.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCanClearBrowserCacheCommand) Wait(ctx context.Context) (*CanClearBrowserCacheResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CanClearBrowserCacheCommand) Send(conn hc.CommandRunner) *AsyncCanClearBrowserCacheCommand {
+	async := NewAsyncCanClearBrowserCacheCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Clears browser cache.
 type ClearBrowserCacheCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -964,30 +1212,34 @@ func (cmd *ClearBrowserCacheCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ClearBrowserCacheCommand) Run(conn *hc.Conn) error {
+func (cmd *ClearBrowserCacheCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ClearBrowserCache(conn *hc.Conn) (err error) {
+func ClearBrowserCache(conn hc.CommandRunner) (err error) {
 	cmd := NewClearBrowserCacheCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ClearBrowserCacheCB func(err error)
 
 // Clears browser cache.
-
 type AsyncClearBrowserCacheCommand struct {
-	cb ClearBrowserCacheCB
+	cb   ClearBrowserCacheCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncClearBrowserCacheCommand(cb ClearBrowserCacheCB) *AsyncClearBrowserCacheCommand {
 	return &AsyncClearBrowserCacheCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1005,7 +1257,28 @@ func (cmd *ClearBrowserCacheCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncClearBrowserCacheCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearBrowserCacheCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearBrowserCacheCommand) Send(conn hc.CommandRunner) *AsyncClearBrowserCacheCommand {
+	async := NewAsyncClearBrowserCacheCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CanClearBrowserCookiesResult struct {
@@ -1013,7 +1286,6 @@ type CanClearBrowserCookiesResult struct {
 }
 
 // Tells whether clearing browser cookies is supported.
-
 type CanClearBrowserCookiesCommand struct {
 	result CanClearBrowserCookiesResult
 	wg     sync.WaitGroup
@@ -1032,30 +1304,38 @@ func (cmd *CanClearBrowserCookiesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CanClearBrowserCookiesCommand) Run(conn *hc.Conn) error {
+func (cmd *CanClearBrowserCookiesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CanClearBrowserCookies(conn *hc.Conn) (result *CanClearBrowserCookiesResult, err error) {
+func CanClearBrowserCookies(conn hc.CommandRunner) (result *CanClearBrowserCookiesResult, err error) {
 	cmd := NewCanClearBrowserCookiesCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CanClearBrowserCookiesCB func(result *CanClearBrowserCookiesResult, err error)
 
 // Tells whether clearing browser cookies is supported.
-
 type AsyncCanClearBrowserCookiesCommand struct {
-	cb CanClearBrowserCookiesCB
+	cb     CanClearBrowserCookiesCB
+	result *CanClearBrowserCookiesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCanClearBrowserCookiesCommand(cb CanClearBrowserCookiesCB) *AsyncCanClearBrowserCookiesCommand {
 	return &AsyncCanClearBrowserCookiesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1084,17 +1364,34 @@ func (cmd *AsyncCanClearBrowserCookiesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Clears browser cookies.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCanClearBrowserCookiesCommand) Wait(ctx context.Context) (*CanClearBrowserCookiesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CanClearBrowserCookiesCommand) Send(conn hc.CommandRunner) *AsyncCanClearBrowserCookiesCommand {
+	async := NewAsyncCanClearBrowserCookiesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Clears browser cookies.
 type ClearBrowserCookiesCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -1112,30 +1409,34 @@ func (cmd *ClearBrowserCookiesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ClearBrowserCookiesCommand) Run(conn *hc.Conn) error {
+func (cmd *ClearBrowserCookiesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ClearBrowserCookies(conn *hc.Conn) (err error) {
+func ClearBrowserCookies(conn hc.CommandRunner) (err error) {
 	cmd := NewClearBrowserCookiesCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ClearBrowserCookiesCB func(err error)
 
 // Clears browser cookies.
-
 type AsyncClearBrowserCookiesCommand struct {
-	cb ClearBrowserCookiesCB
+	cb   ClearBrowserCookiesCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncClearBrowserCookiesCommand(cb ClearBrowserCookiesCB) *AsyncClearBrowserCookiesCommand {
 	return &AsyncClearBrowserCookiesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1153,7 +1454,28 @@ func (cmd *ClearBrowserCookiesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncClearBrowserCookiesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearBrowserCookiesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearBrowserCookiesCommand) Send(conn hc.CommandRunner) *AsyncClearBrowserCookiesCommand {
+	async := NewAsyncClearBrowserCookiesCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type NetworkGetCookiesResult struct {
@@ -1180,17 +1502,21 @@ func (cmd *NetworkGetCookiesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *NetworkGetCookiesCommand) Run(conn *hc.Conn) error {
+func (cmd *NetworkGetCookiesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func NetworkGetCookies(conn *hc.Conn) (result *NetworkGetCookiesResult, err error) {
+func NetworkGetCookies(conn hc.CommandRunner) (result *NetworkGetCookiesResult, err error) {
 	cmd := NewNetworkGetCookiesCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type NetworkGetCookiesCB func(result *NetworkGetCookiesResult, err error)
@@ -1198,12 +1524,17 @@ type NetworkGetCookiesCB func(result *NetworkGetCookiesResult, err error)
 // Returns all browser cookies for the current URL. Depending on the backend support, will return detailed cookie information in the cookies field.
 // @experimental
 type AsyncNetworkGetCookiesCommand struct {
-	cb NetworkGetCookiesCB
+	cb     NetworkGetCookiesCB
+	result *NetworkGetCookiesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncNetworkGetCookiesCommand(cb NetworkGetCookiesCB) *AsyncNetworkGetCookiesCommand {
 	return &AsyncNetworkGetCookiesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1232,15 +1563,33 @@ func (cmd *AsyncNetworkGetCookiesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncNetworkGetCookiesCommand) Wait(ctx context.Context) (*NetworkGetCookiesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *NetworkGetCookiesCommand) Send(conn hc.CommandRunner) *AsyncNetworkGetCookiesCommand {
+	async := NewAsyncNetworkGetCookiesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetAllCookiesResult struct {
 	Cookies []*Cookie `json:"cookies"` // Array of cookie objects.
 }
@@ -1265,17 +1614,21 @@ func (cmd *GetAllCookiesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetAllCookiesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetAllCookiesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetAllCookies(conn *hc.Conn) (result *GetAllCookiesResult, err error) {
+func GetAllCookies(conn hc.CommandRunner) (result *GetAllCookiesResult, err error) {
 	cmd := NewGetAllCookiesCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetAllCookiesCB func(result *GetAllCookiesResult, err error)
@@ -1283,12 +1636,17 @@ type GetAllCookiesCB func(result *GetAllCookiesResult, err error)
 // Returns all browser cookies. Depending on the backend support, will return detailed cookie information in the cookies field.
 // @experimental
 type AsyncGetAllCookiesCommand struct {
-	cb GetAllCookiesCB
+	cb     GetAllCookiesCB
+	result *GetAllCookiesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetAllCookiesCommand(cb GetAllCookiesCB) *AsyncGetAllCookiesCommand {
 	return &AsyncGetAllCookiesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1317,15 +1675,33 @@ func (cmd *AsyncGetAllCookiesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetAllCookiesCommand) Wait(ctx context.Context) (*GetAllCookiesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetAllCookiesCommand) Send(conn hc.CommandRunner) *AsyncGetAllCookiesCommand {
+	async := NewAsyncGetAllCookiesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type NetworkDeleteCookieParams struct {
 	CookieName string `json:"cookieName"` // Name of the cookie to remove.
 	Url        string `json:"url"`        // URL to match cooke domain and path.
@@ -1353,17 +1729,18 @@ func (cmd *NetworkDeleteCookieCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *NetworkDeleteCookieCommand) Run(conn *hc.Conn) error {
+func (cmd *NetworkDeleteCookieCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func NetworkDeleteCookie(params *NetworkDeleteCookieParams, conn *hc.Conn) (err error) {
+func NetworkDeleteCookie(params *NetworkDeleteCookieParams, conn hc.CommandRunner) (err error) {
 	cmd := NewNetworkDeleteCookieCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type NetworkDeleteCookieCB func(err error)
@@ -1373,12 +1750,16 @@ type NetworkDeleteCookieCB func(err error)
 type AsyncNetworkDeleteCookieCommand struct {
 	params *NetworkDeleteCookieParams
 	cb     NetworkDeleteCookieCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncNetworkDeleteCookieCommand(params *NetworkDeleteCookieParams, cb NetworkDeleteCookieCB) *AsyncNetworkDeleteCookieCommand {
 	return &AsyncNetworkDeleteCookieCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1396,7 +1777,28 @@ func (cmd *NetworkDeleteCookieCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncNetworkDeleteCookieCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncNetworkDeleteCookieCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *NetworkDeleteCookieCommand) Send(conn hc.CommandRunner) *AsyncNetworkDeleteCookieCommand {
+	async := NewAsyncNetworkDeleteCookieCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetCookieParams struct {
@@ -1438,17 +1840,21 @@ func (cmd *SetCookieCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetCookieCommand) Run(conn *hc.Conn) error {
+func (cmd *SetCookieCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetCookie(params *SetCookieParams, conn *hc.Conn) (result *SetCookieResult, err error) {
+func SetCookie(params *SetCookieParams, conn hc.CommandRunner) (result *SetCookieResult, err error) {
 	cmd := NewSetCookieCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SetCookieCB func(result *SetCookieResult, err error)
@@ -1458,12 +1864,17 @@ type SetCookieCB func(result *SetCookieResult, err error)
 type AsyncSetCookieCommand struct {
 	params *SetCookieParams
 	cb     SetCookieCB
+	result *SetCookieResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetCookieCommand(params *SetCookieParams, cb SetCookieCB) *AsyncSetCookieCommand {
 	return &AsyncSetCookieCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1492,15 +1903,33 @@ func (cmd *AsyncSetCookieCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetCookieCommand) Wait(ctx context.Context) (*SetCookieResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetCookieCommand) Send(conn hc.CommandRunner) *AsyncSetCookieCommand {
+	async := NewAsyncSetCookieCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CanEmulateNetworkConditionsResult struct {
 	Result bool `json:"result"` // True if emulation of network conditions is supported.
 }
@@ -1525,17 +1954,21 @@ func (cmd *CanEmulateNetworkConditionsCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CanEmulateNetworkConditionsCommand) Run(conn *hc.Conn) error {
+func (cmd *CanEmulateNetworkConditionsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CanEmulateNetworkConditions(conn *hc.Conn) (result *CanEmulateNetworkConditionsResult, err error) {
+func CanEmulateNetworkConditions(conn hc.CommandRunner) (result *CanEmulateNetworkConditionsResult, err error) {
 	cmd := NewCanEmulateNetworkConditionsCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CanEmulateNetworkConditionsCB func(result *CanEmulateNetworkConditionsResult, err error)
@@ -1543,12 +1976,17 @@ type CanEmulateNetworkConditionsCB func(result *CanEmulateNetworkConditionsResul
 // Tells whether emulation of network conditions is supported.
 // @experimental
 type AsyncCanEmulateNetworkConditionsCommand struct {
-	cb CanEmulateNetworkConditionsCB
+	cb     CanEmulateNetworkConditionsCB
+	result *CanEmulateNetworkConditionsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCanEmulateNetworkConditionsCommand(cb CanEmulateNetworkConditionsCB) *AsyncCanEmulateNetworkConditionsCommand {
 	return &AsyncCanEmulateNetworkConditionsCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1577,15 +2015,33 @@ func (cmd *AsyncCanEmulateNetworkConditionsCommand) Done(data []byte, err error)
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCanEmulateNetworkConditionsCommand) Wait(ctx context.Context) (*CanEmulateNetworkConditionsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CanEmulateNetworkConditionsCommand) Send(conn hc.CommandRunner) *AsyncCanEmulateNetworkConditionsCommand {
+	async := NewAsyncCanEmulateNetworkConditionsCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type EmulateNetworkConditionsParams struct {
 	Offline            bool           `json:"offline"`                  // True to emulate internet disconnection.
 	Latency            float64        `json:"latency"`                  // Additional latency (ms).
@@ -1595,7 +2051,6 @@ type EmulateNetworkConditionsParams struct {
 }
 
 // Activates emulation of network conditions.
-
 type EmulateNetworkConditionsCommand struct {
 	params *EmulateNetworkConditionsParams
 	wg     sync.WaitGroup
@@ -1616,32 +2071,36 @@ func (cmd *EmulateNetworkConditionsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EmulateNetworkConditionsCommand) Run(conn *hc.Conn) error {
+func (cmd *EmulateNetworkConditionsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func EmulateNetworkConditions(params *EmulateNetworkConditionsParams, conn *hc.Conn) (err error) {
+func EmulateNetworkConditions(params *EmulateNetworkConditionsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewEmulateNetworkConditionsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EmulateNetworkConditionsCB func(err error)
 
 // Activates emulation of network conditions.
-
 type AsyncEmulateNetworkConditionsCommand struct {
 	params *EmulateNetworkConditionsParams
 	cb     EmulateNetworkConditionsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEmulateNetworkConditionsCommand(params *EmulateNetworkConditionsParams, cb EmulateNetworkConditionsCB) *AsyncEmulateNetworkConditionsCommand {
 	return &AsyncEmulateNetworkConditionsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1659,7 +2118,28 @@ func (cmd *EmulateNetworkConditionsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncEmulateNetworkConditionsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEmulateNetworkConditionsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EmulateNetworkConditionsCommand) Send(conn hc.CommandRunner) *AsyncEmulateNetworkConditionsCommand {
+	async := NewAsyncEmulateNetworkConditionsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetCacheDisabledParams struct {
@@ -1667,7 +2147,6 @@ type SetCacheDisabledParams struct {
 }
 
 // Toggles ignoring cache for each request. If true, cache will not be used.
-
 type SetCacheDisabledCommand struct {
 	params *SetCacheDisabledParams
 	wg     sync.WaitGroup
@@ -1688,32 +2167,36 @@ func (cmd *SetCacheDisabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetCacheDisabledCommand) Run(conn *hc.Conn) error {
+func (cmd *SetCacheDisabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetCacheDisabled(params *SetCacheDisabledParams, conn *hc.Conn) (err error) {
+func SetCacheDisabled(params *SetCacheDisabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetCacheDisabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetCacheDisabledCB func(err error)
 
 // Toggles ignoring cache for each request. If true, cache will not be used.
-
 type AsyncSetCacheDisabledCommand struct {
 	params *SetCacheDisabledParams
 	cb     SetCacheDisabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetCacheDisabledCommand(params *SetCacheDisabledParams, cb SetCacheDisabledCB) *AsyncSetCacheDisabledCommand {
 	return &AsyncSetCacheDisabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1731,7 +2214,28 @@ func (cmd *SetCacheDisabledCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetCacheDisabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetCacheDisabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetCacheDisabledCommand) Send(conn hc.CommandRunner) *AsyncSetCacheDisabledCommand {
+	async := NewAsyncSetCacheDisabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetBypassServiceWorkerParams struct {
@@ -1760,17 +2264,18 @@ func (cmd *SetBypassServiceWorkerCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetBypassServiceWorkerCommand) Run(conn *hc.Conn) error {
+func (cmd *SetBypassServiceWorkerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetBypassServiceWorker(params *SetBypassServiceWorkerParams, conn *hc.Conn) (err error) {
+func SetBypassServiceWorker(params *SetBypassServiceWorkerParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetBypassServiceWorkerCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetBypassServiceWorkerCB func(err error)
@@ -1780,12 +2285,16 @@ type SetBypassServiceWorkerCB func(err error)
 type AsyncSetBypassServiceWorkerCommand struct {
 	params *SetBypassServiceWorkerParams
 	cb     SetBypassServiceWorkerCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetBypassServiceWorkerCommand(params *SetBypassServiceWorkerParams, cb SetBypassServiceWorkerCB) *AsyncSetBypassServiceWorkerCommand {
 	return &AsyncSetBypassServiceWorkerCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1803,7 +2312,28 @@ func (cmd *SetBypassServiceWorkerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetBypassServiceWorkerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetBypassServiceWorkerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetBypassServiceWorkerCommand) Send(conn hc.CommandRunner) *AsyncSetBypassServiceWorkerCommand {
+	async := NewAsyncSetBypassServiceWorkerCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetDataSizeLimitsForTestParams struct {
@@ -1833,17 +2363,18 @@ func (cmd *SetDataSizeLimitsForTestCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetDataSizeLimitsForTestCommand) Run(conn *hc.Conn) error {
+func (cmd *SetDataSizeLimitsForTestCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetDataSizeLimitsForTest(params *SetDataSizeLimitsForTestParams, conn *hc.Conn) (err error) {
+func SetDataSizeLimitsForTest(params *SetDataSizeLimitsForTestParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetDataSizeLimitsForTestCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetDataSizeLimitsForTestCB func(err error)
@@ -1853,12 +2384,16 @@ type SetDataSizeLimitsForTestCB func(err error)
 type AsyncSetDataSizeLimitsForTestCommand struct {
 	params *SetDataSizeLimitsForTestParams
 	cb     SetDataSizeLimitsForTestCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetDataSizeLimitsForTestCommand(params *SetDataSizeLimitsForTestParams, cb SetDataSizeLimitsForTestCB) *AsyncSetDataSizeLimitsForTestCommand {
 	return &AsyncSetDataSizeLimitsForTestCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1876,7 +2411,28 @@ func (cmd *SetDataSizeLimitsForTestCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetDataSizeLimitsForTestCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetDataSizeLimitsForTestCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetDataSizeLimitsForTestCommand) Send(conn hc.CommandRunner) *AsyncSetDataSizeLimitsForTestCommand {
+	async := NewAsyncSetDataSizeLimitsForTestCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetCertificateParams struct {
@@ -1910,17 +2466,21 @@ func (cmd *GetCertificateCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetCertificateCommand) Run(conn *hc.Conn) error {
+func (cmd *GetCertificateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetCertificate(params *GetCertificateParams, conn *hc.Conn) (result *GetCertificateResult, err error) {
+func GetCertificate(params *GetCertificateParams, conn hc.CommandRunner) (result *GetCertificateResult, err error) {
 	cmd := NewGetCertificateCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetCertificateCB func(result *GetCertificateResult, err error)
@@ -1930,12 +2490,17 @@ type GetCertificateCB func(result *GetCertificateResult, err error)
 type AsyncGetCertificateCommand struct {
 	params *GetCertificateParams
 	cb     GetCertificateCB
+	result *GetCertificateResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetCertificateCommand(params *GetCertificateParams, cb GetCertificateCB) *AsyncGetCertificateCommand {
 	return &AsyncGetCertificateCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1964,15 +2529,33 @@ func (cmd *AsyncGetCertificateCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetCertificateCommand) Wait(ctx context.Context) (*GetCertificateResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetCertificateCommand) Send(conn hc.CommandRunner) *AsyncGetCertificateCommand {
+	async := NewAsyncGetCertificateCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 // Fired when resource loading priority is changed
 // @experimental
 type ResourceChangedPriorityEvent struct {
@@ -1981,23 +2564,38 @@ type ResourceChangedPriorityEvent struct {
 	Timestamp   NetworkTimestamp `json:"timestamp"`   // Timestamp.
 }
 
-func OnResourceChangedPriority(conn *hc.Conn, cb func(evt *ResourceChangedPriorityEvent)) {
+// Calls cb for each Network.resourceChangedPriority event, till the returned func is called.
+func OnResourceChangedPriority(conn hc.EventSource, cb func(evt *ResourceChangedPriorityEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ResourceChangedPriorityEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.resourceChangedPriority", sink)
+	return func() { conn.RemoveEventSink("Network.resourceChangedPriority", sink) }
 }
 
-// Fired when page is about to send HTTP request.
+// Like OnResourceChangedPriority, but cb also gets hc.EventMeta.
+func OnResourceChangedPriorityMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ResourceChangedPriorityEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ResourceChangedPriorityEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.resourceChangedPriority", sink)
+	return func() { conn.RemoveEventSink("Network.resourceChangedPriority", sink) }
+}
 
+// Fired when page is about to send HTTP request.
 type RequestWillBeSentEvent struct {
 	RequestId        RequestId        `json:"requestId"`        // Request identifier.
-	FrameId          *FrameId         `json:"frameId"`          // Frame identifier.
+	FrameId          FrameId          `json:"frameId"`          // Frame identifier.
 	LoaderId         LoaderId         `json:"loaderId"`         // Loader identifier.
 	DocumentURL      string           `json:"documentURL"`      // URL of the document this request is loaded for.
 	Request          *Request         `json:"request"`          // Request data.
@@ -2005,64 +2603,109 @@ type RequestWillBeSentEvent struct {
 	WallTime         NetworkTimestamp `json:"wallTime"`         // UTC Timestamp.
 	Initiator        *Initiator       `json:"initiator"`        // Request initiator.
 	RedirectResponse *Response        `json:"redirectResponse"` // Redirect response data.
-	Type             *ResourceType    `json:"type"`             // Type of this resource.
+	Type             ResourceType     `json:"type"`             // Type of this resource.
 }
 
-func OnRequestWillBeSent(conn *hc.Conn, cb func(evt *RequestWillBeSentEvent)) {
+// Calls cb for each Network.requestWillBeSent event, till the returned func is called.
+func OnRequestWillBeSent(conn hc.EventSource, cb func(evt *RequestWillBeSentEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &RequestWillBeSentEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.requestWillBeSent", sink)
+	return func() { conn.RemoveEventSink("Network.requestWillBeSent", sink) }
 }
 
-// Fired if request ended up loading from cache.
+// Like OnRequestWillBeSent, but cb also gets hc.EventMeta.
+func OnRequestWillBeSentMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *RequestWillBeSentEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &RequestWillBeSentEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.requestWillBeSent", sink)
+	return func() { conn.RemoveEventSink("Network.requestWillBeSent", sink) }
+}
 
+// Fired if request ended up loading from cache.
 type RequestServedFromCacheEvent struct {
 	RequestId RequestId `json:"requestId"` // Request identifier.
 }
 
-func OnRequestServedFromCache(conn *hc.Conn, cb func(evt *RequestServedFromCacheEvent)) {
+// Calls cb for each Network.requestServedFromCache event, till the returned func is called.
+func OnRequestServedFromCache(conn hc.EventSource, cb func(evt *RequestServedFromCacheEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &RequestServedFromCacheEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.requestServedFromCache", sink)
+	return func() { conn.RemoveEventSink("Network.requestServedFromCache", sink) }
 }
 
-// Fired when HTTP response is available.
+// Like OnRequestServedFromCache, but cb also gets hc.EventMeta.
+func OnRequestServedFromCacheMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *RequestServedFromCacheEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &RequestServedFromCacheEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.requestServedFromCache", sink)
+	return func() { conn.RemoveEventSink("Network.requestServedFromCache", sink) }
+}
 
+// Fired when HTTP response is available.
 type ResponseReceivedEvent struct {
 	RequestId RequestId        `json:"requestId"` // Request identifier.
-	FrameId   *FrameId         `json:"frameId"`   // Frame identifier.
+	FrameId   FrameId          `json:"frameId"`   // Frame identifier.
 	LoaderId  LoaderId         `json:"loaderId"`  // Loader identifier.
 	Timestamp NetworkTimestamp `json:"timestamp"` // Timestamp.
-	Type      *ResourceType    `json:"type"`      // Resource type.
+	Type      ResourceType     `json:"type"`      // Resource type.
 	Response  *Response        `json:"response"`  // Response data.
 }
 
-func OnResponseReceived(conn *hc.Conn, cb func(evt *ResponseReceivedEvent)) {
+// Calls cb for each Network.responseReceived event, till the returned func is called.
+func OnResponseReceived(conn hc.EventSource, cb func(evt *ResponseReceivedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ResponseReceivedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.responseReceived", sink)
+	return func() { conn.RemoveEventSink("Network.responseReceived", sink) }
 }
 
-// Fired when data chunk was received over the network.
+// Like OnResponseReceived, but cb also gets hc.EventMeta.
+func OnResponseReceivedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ResponseReceivedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ResponseReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.responseReceived", sink)
+	return func() { conn.RemoveEventSink("Network.responseReceived", sink) }
+}
 
+// Fired when data chunk was received over the network.
 type DataReceivedEvent struct {
 	RequestId         RequestId        `json:"requestId"`         // Request identifier.
 	Timestamp         NetworkTimestamp `json:"timestamp"`         // Timestamp.
@@ -2070,59 +2713,105 @@ type DataReceivedEvent struct {
 	EncodedDataLength int              `json:"encodedDataLength"` // Actual bytes received (might be less than dataLength for compressed encodings).
 }
 
-func OnDataReceived(conn *hc.Conn, cb func(evt *DataReceivedEvent)) {
+// Calls cb for each Network.dataReceived event, till the returned func is called.
+func OnDataReceived(conn hc.EventSource, cb func(evt *DataReceivedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DataReceivedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.dataReceived", sink)
+	return func() { conn.RemoveEventSink("Network.dataReceived", sink) }
 }
 
-// Fired when HTTP request has finished loading.
+// Like OnDataReceived, but cb also gets hc.EventMeta.
+func OnDataReceivedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DataReceivedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DataReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.dataReceived", sink)
+	return func() { conn.RemoveEventSink("Network.dataReceived", sink) }
+}
 
+// Fired when HTTP request has finished loading.
 type LoadingFinishedEvent struct {
 	RequestId         RequestId        `json:"requestId"`         // Request identifier.
 	Timestamp         NetworkTimestamp `json:"timestamp"`         // Timestamp.
 	EncodedDataLength float64          `json:"encodedDataLength"` // Total number of bytes received for this request.
 }
 
-func OnLoadingFinished(conn *hc.Conn, cb func(evt *LoadingFinishedEvent)) {
+// Calls cb for each Network.loadingFinished event, till the returned func is called.
+func OnLoadingFinished(conn hc.EventSource, cb func(evt *LoadingFinishedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &LoadingFinishedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.loadingFinished", sink)
+	return func() { conn.RemoveEventSink("Network.loadingFinished", sink) }
 }
 
-// Fired when HTTP request has failed to load.
+// Like OnLoadingFinished, but cb also gets hc.EventMeta.
+func OnLoadingFinishedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LoadingFinishedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LoadingFinishedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.loadingFinished", sink)
+	return func() { conn.RemoveEventSink("Network.loadingFinished", sink) }
+}
 
+// Fired when HTTP request has failed to load.
 type LoadingFailedEvent struct {
 	RequestId     RequestId        `json:"requestId"`     // Request identifier.
 	Timestamp     NetworkTimestamp `json:"timestamp"`     // Timestamp.
-	Type          *ResourceType    `json:"type"`          // Resource type.
+	Type          ResourceType     `json:"type"`          // Resource type.
 	ErrorText     string           `json:"errorText"`     // User friendly error message.
 	Canceled      bool             `json:"canceled"`      // True if loading was canceled.
 	BlockedReason BlockedReason    `json:"blockedReason"` // The reason why loading was blocked, if any.
 }
 
-func OnLoadingFailed(conn *hc.Conn, cb func(evt *LoadingFailedEvent)) {
+// Calls cb for each Network.loadingFailed event, till the returned func is called.
+func OnLoadingFailed(conn hc.EventSource, cb func(evt *LoadingFailedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &LoadingFailedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.loadingFailed", sink)
+	return func() { conn.RemoveEventSink("Network.loadingFailed", sink) }
+}
+
+// Like OnLoadingFailed, but cb also gets hc.EventMeta.
+func OnLoadingFailedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LoadingFailedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LoadingFailedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.loadingFailed", sink)
+	return func() { conn.RemoveEventSink("Network.loadingFailed", sink) }
 }
 
 // Fired when WebSocket is about to initiate handshake.
@@ -2134,16 +2823,32 @@ type WebSocketWillSendHandshakeRequestEvent struct {
 	Request   *WebSocketRequest `json:"request"`   // WebSocket request data.
 }
 
-func OnWebSocketWillSendHandshakeRequest(conn *hc.Conn, cb func(evt *WebSocketWillSendHandshakeRequestEvent)) {
+// Calls cb for each Network.webSocketWillSendHandshakeRequest event, till the returned func is called.
+func OnWebSocketWillSendHandshakeRequest(conn hc.EventSource, cb func(evt *WebSocketWillSendHandshakeRequestEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketWillSendHandshakeRequestEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketWillSendHandshakeRequest", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketWillSendHandshakeRequest", sink) }
+}
+
+// Like OnWebSocketWillSendHandshakeRequest, but cb also gets hc.EventMeta.
+func OnWebSocketWillSendHandshakeRequestMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketWillSendHandshakeRequestEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketWillSendHandshakeRequestEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketWillSendHandshakeRequest", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketWillSendHandshakeRequest", sink) }
 }
 
 // Fired when WebSocket handshake response becomes available.
@@ -2154,16 +2859,32 @@ type WebSocketHandshakeResponseReceivedEvent struct {
 	Response  *WebSocketResponse `json:"response"`  // WebSocket response data.
 }
 
-func OnWebSocketHandshakeResponseReceived(conn *hc.Conn, cb func(evt *WebSocketHandshakeResponseReceivedEvent)) {
+// Calls cb for each Network.webSocketHandshakeResponseReceived event, till the returned func is called.
+func OnWebSocketHandshakeResponseReceived(conn hc.EventSource, cb func(evt *WebSocketHandshakeResponseReceivedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketHandshakeResponseReceivedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketHandshakeResponseReceived", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketHandshakeResponseReceived", sink) }
+}
+
+// Like OnWebSocketHandshakeResponseReceived, but cb also gets hc.EventMeta.
+func OnWebSocketHandshakeResponseReceivedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketHandshakeResponseReceivedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketHandshakeResponseReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketHandshakeResponseReceived", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketHandshakeResponseReceived", sink) }
 }
 
 // Fired upon WebSocket creation.
@@ -2174,16 +2895,32 @@ type WebSocketCreatedEvent struct {
 	Initiator *Initiator `json:"initiator"` // Request initiator.
 }
 
-func OnWebSocketCreated(conn *hc.Conn, cb func(evt *WebSocketCreatedEvent)) {
+// Calls cb for each Network.webSocketCreated event, till the returned func is called.
+func OnWebSocketCreated(conn hc.EventSource, cb func(evt *WebSocketCreatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketCreatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketCreated", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketCreated", sink) }
+}
+
+// Like OnWebSocketCreated, but cb also gets hc.EventMeta.
+func OnWebSocketCreatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketCreatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketCreatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketCreated", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketCreated", sink) }
 }
 
 // Fired when WebSocket is closed.
@@ -2193,16 +2930,32 @@ type WebSocketClosedEvent struct {
 	Timestamp NetworkTimestamp `json:"timestamp"` // Timestamp.
 }
 
-func OnWebSocketClosed(conn *hc.Conn, cb func(evt *WebSocketClosedEvent)) {
+// Calls cb for each Network.webSocketClosed event, till the returned func is called.
+func OnWebSocketClosed(conn hc.EventSource, cb func(evt *WebSocketClosedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketClosedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketClosed", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketClosed", sink) }
+}
+
+// Like OnWebSocketClosed, but cb also gets hc.EventMeta.
+func OnWebSocketClosedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketClosedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketClosedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketClosed", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketClosed", sink) }
 }
 
 // Fired when WebSocket frame is received.
@@ -2213,16 +2966,32 @@ type WebSocketFrameReceivedEvent struct {
 	Response  *WebSocketFrame  `json:"response"`  // WebSocket response data.
 }
 
-func OnWebSocketFrameReceived(conn *hc.Conn, cb func(evt *WebSocketFrameReceivedEvent)) {
+// Calls cb for each Network.webSocketFrameReceived event, till the returned func is called.
+func OnWebSocketFrameReceived(conn hc.EventSource, cb func(evt *WebSocketFrameReceivedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketFrameReceivedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketFrameReceived", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketFrameReceived", sink) }
+}
+
+// Like OnWebSocketFrameReceived, but cb also gets hc.EventMeta.
+func OnWebSocketFrameReceivedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketFrameReceivedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketFrameReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketFrameReceived", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketFrameReceived", sink) }
 }
 
 // Fired when WebSocket frame error occurs.
@@ -2233,16 +3002,32 @@ type WebSocketFrameErrorEvent struct {
 	ErrorMessage string           `json:"errorMessage"` // WebSocket frame error message.
 }
 
-func OnWebSocketFrameError(conn *hc.Conn, cb func(evt *WebSocketFrameErrorEvent)) {
+// Calls cb for each Network.webSocketFrameError event, till the returned func is called.
+func OnWebSocketFrameError(conn hc.EventSource, cb func(evt *WebSocketFrameErrorEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketFrameErrorEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketFrameError", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketFrameError", sink) }
+}
+
+// Like OnWebSocketFrameError, but cb also gets hc.EventMeta.
+func OnWebSocketFrameErrorMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketFrameErrorEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketFrameErrorEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketFrameError", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketFrameError", sink) }
 }
 
 // Fired when WebSocket frame is sent.
@@ -2253,16 +3038,32 @@ type WebSocketFrameSentEvent struct {
 	Response  *WebSocketFrame  `json:"response"`  // WebSocket response data.
 }
 
-func OnWebSocketFrameSent(conn *hc.Conn, cb func(evt *WebSocketFrameSentEvent)) {
+// Calls cb for each Network.webSocketFrameSent event, till the returned func is called.
+func OnWebSocketFrameSent(conn hc.EventSource, cb func(evt *WebSocketFrameSentEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WebSocketFrameSentEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.webSocketFrameSent", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketFrameSent", sink) }
+}
+
+// Like OnWebSocketFrameSent, but cb also gets hc.EventMeta.
+func OnWebSocketFrameSentMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WebSocketFrameSentEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WebSocketFrameSentEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.webSocketFrameSent", sink)
+	return func() { conn.RemoveEventSink("Network.webSocketFrameSent", sink) }
 }
 
 // Fired when EventSource message is received.
@@ -2275,14 +3076,30 @@ type EventSourceMessageReceivedEvent struct {
 	Data      string           `json:"data"`      // Message content.
 }
 
-func OnEventSourceMessageReceived(conn *hc.Conn, cb func(evt *EventSourceMessageReceivedEvent)) {
+// Calls cb for each Network.eventSourceMessageReceived event, till the returned func is called.
+func OnEventSourceMessageReceived(conn hc.EventSource, cb func(evt *EventSourceMessageReceivedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &EventSourceMessageReceivedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Network.eventSourceMessageReceived", sink)
+	return func() { conn.RemoveEventSink("Network.eventSourceMessageReceived", sink) }
+}
+
+// Like OnEventSourceMessageReceived, but cb also gets hc.EventMeta.
+func OnEventSourceMessageReceivedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *EventSourceMessageReceivedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &EventSourceMessageReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.eventSourceMessageReceived", sink)
+	return func() { conn.RemoveEventSink("Network.eventSourceMessageReceived", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -29,25 +29,25 @@ type FrameId string
 
 // Information about the Frame on the page.
 type Frame struct {
-	Id             string    `json:"id"`                 // Frame unique identifier.
-	ParentId       string    `json:"parentId,omitempty"` // Parent frame identifier.
-	LoaderId       *LoaderId `json:"loaderId"`           // Identifier of the loader associated with this frame.
-	Name           string    `json:"name,omitempty"`     // Frame's name as specified in the tag.
-	Url            string    `json:"url"`                // Frame document's URL.
-	SecurityOrigin string    `json:"securityOrigin"`     // Frame document's security origin.
-	MimeType       string    `json:"mimeType"`           // Frame document's mimeType as determined by the browser.
+	Id             string   `json:"id"`                 // Frame unique identifier.
+	ParentId       string   `json:"parentId,omitempty"` // Parent frame identifier.
+	LoaderId       LoaderId `json:"loaderId"`           // Identifier of the loader associated with this frame.
+	Name           string   `json:"name,omitempty"`     // Frame's name as specified in the tag.
+	Url            string   `json:"url"`                // Frame document's URL.
+	SecurityOrigin string   `json:"securityOrigin"`     // Frame document's security origin.
+	MimeType       string   `json:"mimeType"`           // Frame document's mimeType as determined by the browser.
 }
 
 // Information about the Resource on the page.
 // @experimental
 type FrameResource struct {
-	Url          string            `json:"url"`                    // Resource URL.
-	Type         ResourceType      `json:"type"`                   // Type of this resource.
-	MimeType     string            `json:"mimeType"`               // Resource mimeType as determined by the browser.
-	LastModified *NetworkTimestamp `json:"lastModified,omitempty"` // last-modified timestamp as reported by server.
-	ContentSize  float64           `json:"contentSize,omitempty"`  // Resource content size.
-	Failed       bool              `json:"failed,omitempty"`       // True if the resource failed to load.
-	Canceled     bool              `json:"canceled,omitempty"`     // True if the resource was canceled during loading.
+	Url          string           `json:"url"`                    // Resource URL.
+	Type         ResourceType     `json:"type"`                   // Type of this resource.
+	MimeType     string           `json:"mimeType"`               // Resource mimeType as determined by the browser.
+	LastModified NetworkTimestamp `json:"lastModified,omitempty"` // last-modified timestamp as reported by server.
+	ContentSize  float64          `json:"contentSize,omitempty"`  // Resource content size.
+	Failed       bool             `json:"failed,omitempty"`       // True if the resource failed to load.
+	Canceled     bool             `json:"canceled,omitempty"`     // True if the resource was canceled during loading.
 }
 
 // Information about the Frame hierarchy along with their cached resources.
@@ -130,7 +130,6 @@ type VisualViewport struct {
 }
 
 // Enables page domain notifications.
-
 type PageEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -148,30 +147,34 @@ func (cmd *PageEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PageEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *PageEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageEnable(conn *hc.Conn) (err error) {
+func PageEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewPageEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageEnableCB func(err error)
 
 // Enables page domain notifications.
-
 type AsyncPageEnableCommand struct {
-	cb PageEnableCB
+	cb   PageEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageEnableCommand(cb PageEnableCB) *AsyncPageEnableCommand {
 	return &AsyncPageEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -189,11 +192,31 @@ func (cmd *PageEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables page domain notifications.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageEnableCommand) Send(conn hc.CommandRunner) *AsyncPageEnableCommand {
+	async := NewAsyncPageEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables page domain notifications.
 type PageDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -211,30 +234,34 @@ func (cmd *PageDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PageDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *PageDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageDisable(conn *hc.Conn) (err error) {
+func PageDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewPageDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageDisableCB func(err error)
 
 // Disables page domain notifications.
-
 type AsyncPageDisableCommand struct {
-	cb PageDisableCB
+	cb   PageDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageDisableCommand(cb PageDisableCB) *AsyncPageDisableCommand {
 	return &AsyncPageDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -252,7 +279,28 @@ func (cmd *PageDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageDisableCommand) Send(conn hc.CommandRunner) *AsyncPageDisableCommand {
+	async := NewAsyncPageDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type AddScriptToEvaluateOnLoadParams struct {
@@ -285,17 +333,21 @@ func (cmd *AddScriptToEvaluateOnLoadCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *AddScriptToEvaluateOnLoadCommand) Run(conn *hc.Conn) error {
+func (cmd *AddScriptToEvaluateOnLoadCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AddScriptToEvaluateOnLoad(params *AddScriptToEvaluateOnLoadParams, conn *hc.Conn) (result *AddScriptToEvaluateOnLoadResult, err error) {
+func AddScriptToEvaluateOnLoad(params *AddScriptToEvaluateOnLoadParams, conn hc.CommandRunner) (result *AddScriptToEvaluateOnLoadResult, err error) {
 	cmd := NewAddScriptToEvaluateOnLoadCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type AddScriptToEvaluateOnLoadCB func(result *AddScriptToEvaluateOnLoadResult, err error)
@@ -304,12 +356,17 @@ type AddScriptToEvaluateOnLoadCB func(result *AddScriptToEvaluateOnLoadResult, e
 type AsyncAddScriptToEvaluateOnLoadCommand struct {
 	params *AddScriptToEvaluateOnLoadParams
 	cb     AddScriptToEvaluateOnLoadCB
+	result *AddScriptToEvaluateOnLoadResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAddScriptToEvaluateOnLoadCommand(params *AddScriptToEvaluateOnLoadParams, cb AddScriptToEvaluateOnLoadCB) *AsyncAddScriptToEvaluateOnLoadCommand {
 	return &AsyncAddScriptToEvaluateOnLoadCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -338,15 +395,33 @@ func (cmd *AsyncAddScriptToEvaluateOnLoadCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAddScriptToEvaluateOnLoadCommand) Wait(ctx context.Context) (*AddScriptToEvaluateOnLoadResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AddScriptToEvaluateOnLoadCommand) Send(conn hc.CommandRunner) *AsyncAddScriptToEvaluateOnLoadCommand {
+	async := NewAsyncAddScriptToEvaluateOnLoadCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RemoveScriptToEvaluateOnLoadParams struct {
 	Identifier ScriptIdentifier `json:"identifier"`
 }
@@ -372,17 +447,18 @@ func (cmd *RemoveScriptToEvaluateOnLoadCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RemoveScriptToEvaluateOnLoadCommand) Run(conn *hc.Conn) error {
+func (cmd *RemoveScriptToEvaluateOnLoadCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RemoveScriptToEvaluateOnLoad(params *RemoveScriptToEvaluateOnLoadParams, conn *hc.Conn) (err error) {
+func RemoveScriptToEvaluateOnLoad(params *RemoveScriptToEvaluateOnLoadParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRemoveScriptToEvaluateOnLoadCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RemoveScriptToEvaluateOnLoadCB func(err error)
@@ -391,12 +467,16 @@ type RemoveScriptToEvaluateOnLoadCB func(err error)
 type AsyncRemoveScriptToEvaluateOnLoadCommand struct {
 	params *RemoveScriptToEvaluateOnLoadParams
 	cb     RemoveScriptToEvaluateOnLoadCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRemoveScriptToEvaluateOnLoadCommand(params *RemoveScriptToEvaluateOnLoadParams, cb RemoveScriptToEvaluateOnLoadCB) *AsyncRemoveScriptToEvaluateOnLoadCommand {
 	return &AsyncRemoveScriptToEvaluateOnLoadCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -414,7 +494,28 @@ func (cmd *RemoveScriptToEvaluateOnLoadCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRemoveScriptToEvaluateOnLoadCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRemoveScriptToEvaluateOnLoadCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RemoveScriptToEvaluateOnLoadCommand) Send(conn hc.CommandRunner) *AsyncRemoveScriptToEvaluateOnLoadCommand {
+	async := NewAsyncRemoveScriptToEvaluateOnLoadCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetAutoAttachToCreatedPagesParams struct {
@@ -443,17 +544,18 @@ func (cmd *SetAutoAttachToCreatedPagesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetAutoAttachToCreatedPagesCommand) Run(conn *hc.Conn) error {
+func (cmd *SetAutoAttachToCreatedPagesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetAutoAttachToCreatedPages(params *SetAutoAttachToCreatedPagesParams, conn *hc.Conn) (err error) {
+func SetAutoAttachToCreatedPages(params *SetAutoAttachToCreatedPagesParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetAutoAttachToCreatedPagesCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetAutoAttachToCreatedPagesCB func(err error)
@@ -463,12 +565,16 @@ type SetAutoAttachToCreatedPagesCB func(err error)
 type AsyncSetAutoAttachToCreatedPagesCommand struct {
 	params *SetAutoAttachToCreatedPagesParams
 	cb     SetAutoAttachToCreatedPagesCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetAutoAttachToCreatedPagesCommand(params *SetAutoAttachToCreatedPagesParams, cb SetAutoAttachToCreatedPagesCB) *AsyncSetAutoAttachToCreatedPagesCommand {
 	return &AsyncSetAutoAttachToCreatedPagesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -486,7 +592,28 @@ func (cmd *SetAutoAttachToCreatedPagesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetAutoAttachToCreatedPagesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetAutoAttachToCreatedPagesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetAutoAttachToCreatedPagesCommand) Send(conn hc.CommandRunner) *AsyncSetAutoAttachToCreatedPagesCommand {
+	async := NewAsyncSetAutoAttachToCreatedPagesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ReloadParams struct {
@@ -495,7 +622,6 @@ type ReloadParams struct {
 }
 
 // Reloads given page optionally ignoring the cache.
-
 type ReloadCommand struct {
 	params *ReloadParams
 	wg     sync.WaitGroup
@@ -516,32 +642,36 @@ func (cmd *ReloadCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReloadCommand) Run(conn *hc.Conn) error {
+func (cmd *ReloadCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Reload(params *ReloadParams, conn *hc.Conn) (err error) {
+func Reload(params *ReloadParams, conn hc.CommandRunner) (err error) {
 	cmd := NewReloadCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ReloadCB func(err error)
 
 // Reloads given page optionally ignoring the cache.
-
 type AsyncReloadCommand struct {
 	params *ReloadParams
 	cb     ReloadCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReloadCommand(params *ReloadParams, cb ReloadCB) *AsyncReloadCommand {
 	return &AsyncReloadCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -559,7 +689,28 @@ func (cmd *ReloadCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncReloadCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReloadCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReloadCommand) Send(conn hc.CommandRunner) *AsyncReloadCommand {
+	async := NewAsyncReloadCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type NavigateParams struct {
@@ -571,7 +722,6 @@ type NavigateResult struct {
 }
 
 // Navigates current page to the given URL.
-
 type NavigateCommand struct {
 	params *NavigateParams
 	result NavigateResult
@@ -593,32 +743,40 @@ func (cmd *NavigateCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *NavigateCommand) Run(conn *hc.Conn) error {
+func (cmd *NavigateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Navigate(params *NavigateParams, conn *hc.Conn) (result *NavigateResult, err error) {
+func Navigate(params *NavigateParams, conn hc.CommandRunner) (result *NavigateResult, err error) {
 	cmd := NewNavigateCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type NavigateCB func(result *NavigateResult, err error)
 
 // Navigates current page to the given URL.
-
 type AsyncNavigateCommand struct {
 	params *NavigateParams
 	cb     NavigateCB
+	result *NavigateResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncNavigateCommand(params *NavigateParams, cb NavigateCB) *AsyncNavigateCommand {
 	return &AsyncNavigateCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -647,15 +805,33 @@ func (cmd *AsyncNavigateCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncNavigateCommand) Wait(ctx context.Context) (*NavigateResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *NavigateCommand) Send(conn hc.CommandRunner) *AsyncNavigateCommand {
+	async := NewAsyncNavigateCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 // Force the page stop all navigations and pending resource fetches.
 // @experimental
 type StopLoadingCommand struct {
@@ -675,17 +851,18 @@ func (cmd *StopLoadingCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StopLoadingCommand) Run(conn *hc.Conn) error {
+func (cmd *StopLoadingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopLoading(conn *hc.Conn) (err error) {
+func StopLoading(conn hc.CommandRunner) (err error) {
 	cmd := NewStopLoadingCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StopLoadingCB func(err error)
@@ -693,12 +870,16 @@ type StopLoadingCB func(err error)
 // Force the page stop all navigations and pending resource fetches.
 // @experimental
 type AsyncStopLoadingCommand struct {
-	cb StopLoadingCB
+	cb   StopLoadingCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopLoadingCommand(cb StopLoadingCB) *AsyncStopLoadingCommand {
 	return &AsyncStopLoadingCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -716,7 +897,28 @@ func (cmd *StopLoadingCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStopLoadingCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopLoadingCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopLoadingCommand) Send(conn hc.CommandRunner) *AsyncStopLoadingCommand {
+	async := NewAsyncStopLoadingCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetNavigationHistoryResult struct {
@@ -744,17 +946,21 @@ func (cmd *GetNavigationHistoryCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetNavigationHistoryCommand) Run(conn *hc.Conn) error {
+func (cmd *GetNavigationHistoryCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetNavigationHistory(conn *hc.Conn) (result *GetNavigationHistoryResult, err error) {
+func GetNavigationHistory(conn hc.CommandRunner) (result *GetNavigationHistoryResult, err error) {
 	cmd := NewGetNavigationHistoryCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetNavigationHistoryCB func(result *GetNavigationHistoryResult, err error)
@@ -762,12 +968,17 @@ type GetNavigationHistoryCB func(result *GetNavigationHistoryResult, err error)
 // Returns navigation history for the current page.
 // @experimental
 type AsyncGetNavigationHistoryCommand struct {
-	cb GetNavigationHistoryCB
+	cb     GetNavigationHistoryCB
+	result *GetNavigationHistoryResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetNavigationHistoryCommand(cb GetNavigationHistoryCB) *AsyncGetNavigationHistoryCommand {
 	return &AsyncGetNavigationHistoryCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -796,15 +1007,33 @@ func (cmd *AsyncGetNavigationHistoryCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetNavigationHistoryCommand) Wait(ctx context.Context) (*GetNavigationHistoryResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetNavigationHistoryCommand) Send(conn hc.CommandRunner) *AsyncGetNavigationHistoryCommand {
+	async := NewAsyncGetNavigationHistoryCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type NavigateToHistoryEntryParams struct {
 	EntryId int `json:"entryId"` // Unique id of the entry to navigate to.
 }
@@ -831,17 +1060,18 @@ func (cmd *NavigateToHistoryEntryCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *NavigateToHistoryEntryCommand) Run(conn *hc.Conn) error {
+func (cmd *NavigateToHistoryEntryCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func NavigateToHistoryEntry(params *NavigateToHistoryEntryParams, conn *hc.Conn) (err error) {
+func NavigateToHistoryEntry(params *NavigateToHistoryEntryParams, conn hc.CommandRunner) (err error) {
 	cmd := NewNavigateToHistoryEntryCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type NavigateToHistoryEntryCB func(err error)
@@ -851,12 +1081,16 @@ type NavigateToHistoryEntryCB func(err error)
 type AsyncNavigateToHistoryEntryCommand struct {
 	params *NavigateToHistoryEntryParams
 	cb     NavigateToHistoryEntryCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncNavigateToHistoryEntryCommand(params *NavigateToHistoryEntryParams, cb NavigateToHistoryEntryCB) *AsyncNavigateToHistoryEntryCommand {
 	return &AsyncNavigateToHistoryEntryCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -874,7 +1108,28 @@ func (cmd *NavigateToHistoryEntryCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncNavigateToHistoryEntryCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncNavigateToHistoryEntryCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *NavigateToHistoryEntryCommand) Send(conn hc.CommandRunner) *AsyncNavigateToHistoryEntryCommand {
+	async := NewAsyncNavigateToHistoryEntryCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PageGetCookiesResult struct {
@@ -901,17 +1156,21 @@ func (cmd *PageGetCookiesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PageGetCookiesCommand) Run(conn *hc.Conn) error {
+func (cmd *PageGetCookiesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageGetCookies(conn *hc.Conn) (result *PageGetCookiesResult, err error) {
+func PageGetCookies(conn hc.CommandRunner) (result *PageGetCookiesResult, err error) {
 	cmd := NewPageGetCookiesCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type PageGetCookiesCB func(result *PageGetCookiesResult, err error)
@@ -919,12 +1178,17 @@ type PageGetCookiesCB func(result *PageGetCookiesResult, err error)
 // Returns all browser cookies. Depending on the backend support, will return detailed cookie information in the cookies field.
 // @experimental
 type AsyncPageGetCookiesCommand struct {
-	cb PageGetCookiesCB
+	cb     PageGetCookiesCB
+	result *PageGetCookiesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageGetCookiesCommand(cb PageGetCookiesCB) *AsyncPageGetCookiesCommand {
 	return &AsyncPageGetCookiesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -953,15 +1217,33 @@ func (cmd *AsyncPageGetCookiesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageGetCookiesCommand) Wait(ctx context.Context) (*PageGetCookiesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageGetCookiesCommand) Send(conn hc.CommandRunner) *AsyncPageGetCookiesCommand {
+	async := NewAsyncPageGetCookiesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type PageDeleteCookieParams struct {
 	CookieName string `json:"cookieName"` // Name of the cookie to remove.
 	Url        string `json:"url"`        // URL to match cooke domain and path.
@@ -989,17 +1271,18 @@ func (cmd *PageDeleteCookieCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PageDeleteCookieCommand) Run(conn *hc.Conn) error {
+func (cmd *PageDeleteCookieCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageDeleteCookie(params *PageDeleteCookieParams, conn *hc.Conn) (err error) {
+func PageDeleteCookie(params *PageDeleteCookieParams, conn hc.CommandRunner) (err error) {
 	cmd := NewPageDeleteCookieCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageDeleteCookieCB func(err error)
@@ -1009,12 +1292,16 @@ type PageDeleteCookieCB func(err error)
 type AsyncPageDeleteCookieCommand struct {
 	params *PageDeleteCookieParams
 	cb     PageDeleteCookieCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageDeleteCookieCommand(params *PageDeleteCookieParams, cb PageDeleteCookieCB) *AsyncPageDeleteCookieCommand {
 	return &AsyncPageDeleteCookieCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1032,7 +1319,28 @@ func (cmd *PageDeleteCookieCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageDeleteCookieCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageDeleteCookieCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageDeleteCookieCommand) Send(conn hc.CommandRunner) *AsyncPageDeleteCookieCommand {
+	async := NewAsyncPageDeleteCookieCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetResourceTreeResult struct {
@@ -1059,17 +1367,21 @@ func (cmd *GetResourceTreeCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetResourceTreeCommand) Run(conn *hc.Conn) error {
+func (cmd *GetResourceTreeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetResourceTree(conn *hc.Conn) (result *GetResourceTreeResult, err error) {
+func GetResourceTree(conn hc.CommandRunner) (result *GetResourceTreeResult, err error) {
 	cmd := NewGetResourceTreeCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetResourceTreeCB func(result *GetResourceTreeResult, err error)
@@ -1077,12 +1389,17 @@ type GetResourceTreeCB func(result *GetResourceTreeResult, err error)
 // Returns present frame / resource tree structure.
 // @experimental
 type AsyncGetResourceTreeCommand struct {
-	cb GetResourceTreeCB
+	cb     GetResourceTreeCB
+	result *GetResourceTreeResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetResourceTreeCommand(cb GetResourceTreeCB) *AsyncGetResourceTreeCommand {
 	return &AsyncGetResourceTreeCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1111,15 +1428,33 @@ func (cmd *AsyncGetResourceTreeCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetResourceTreeCommand) Wait(ctx context.Context) (*GetResourceTreeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetResourceTreeCommand) Send(conn hc.CommandRunner) *AsyncGetResourceTreeCommand {
+	async := NewAsyncGetResourceTreeCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetResourceContentParams struct {
 	FrameId FrameId `json:"frameId"` // Frame id to get resource for.
 	Url     string  `json:"url"`     // URL of the resource to get content for.
@@ -1153,17 +1488,21 @@ func (cmd *GetResourceContentCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetResourceContentCommand) Run(conn *hc.Conn) error {
+func (cmd *GetResourceContentCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetResourceContent(params *GetResourceContentParams, conn *hc.Conn) (result *GetResourceContentResult, err error) {
+func GetResourceContent(params *GetResourceContentParams, conn hc.CommandRunner) (result *GetResourceContentResult, err error) {
 	cmd := NewGetResourceContentCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetResourceContentCB func(result *GetResourceContentResult, err error)
@@ -1173,12 +1512,17 @@ type GetResourceContentCB func(result *GetResourceContentResult, err error)
 type AsyncGetResourceContentCommand struct {
 	params *GetResourceContentParams
 	cb     GetResourceContentCB
+	result *GetResourceContentResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetResourceContentCommand(params *GetResourceContentParams, cb GetResourceContentCB) *AsyncGetResourceContentCommand {
 	return &AsyncGetResourceContentCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1207,15 +1551,33 @@ func (cmd *AsyncGetResourceContentCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetResourceContentCommand) Wait(ctx context.Context) (*GetResourceContentResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetResourceContentCommand) Send(conn hc.CommandRunner) *AsyncGetResourceContentCommand {
+	async := NewAsyncGetResourceContentCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SearchInResourceParams struct {
 	FrameId       FrameId `json:"frameId"`                 // Frame id for resource to search in.
 	Url           string  `json:"url"`                     // URL of the resource to search in.
@@ -1251,17 +1613,21 @@ func (cmd *SearchInResourceCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SearchInResourceCommand) Run(conn *hc.Conn) error {
+func (cmd *SearchInResourceCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SearchInResource(params *SearchInResourceParams, conn *hc.Conn) (result *SearchInResourceResult, err error) {
+func SearchInResource(params *SearchInResourceParams, conn hc.CommandRunner) (result *SearchInResourceResult, err error) {
 	cmd := NewSearchInResourceCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type SearchInResourceCB func(result *SearchInResourceResult, err error)
@@ -1271,12 +1637,17 @@ type SearchInResourceCB func(result *SearchInResourceResult, err error)
 type AsyncSearchInResourceCommand struct {
 	params *SearchInResourceParams
 	cb     SearchInResourceCB
+	result *SearchInResourceResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSearchInResourceCommand(params *SearchInResourceParams, cb SearchInResourceCB) *AsyncSearchInResourceCommand {
 	return &AsyncSearchInResourceCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1305,15 +1676,33 @@ func (cmd *AsyncSearchInResourceCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSearchInResourceCommand) Wait(ctx context.Context) (*SearchInResourceResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SearchInResourceCommand) Send(conn hc.CommandRunner) *AsyncSearchInResourceCommand {
+	async := NewAsyncSearchInResourceCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type SetDocumentContentParams struct {
 	FrameId FrameId `json:"frameId"` // Frame id to set HTML for.
 	Html    string  `json:"html"`    // HTML content to set.
@@ -1341,17 +1730,18 @@ func (cmd *SetDocumentContentCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetDocumentContentCommand) Run(conn *hc.Conn) error {
+func (cmd *SetDocumentContentCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetDocumentContent(params *SetDocumentContentParams, conn *hc.Conn) (err error) {
+func SetDocumentContent(params *SetDocumentContentParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetDocumentContentCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetDocumentContentCB func(err error)
@@ -1361,12 +1751,16 @@ type SetDocumentContentCB func(err error)
 type AsyncSetDocumentContentCommand struct {
 	params *SetDocumentContentParams
 	cb     SetDocumentContentCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetDocumentContentCommand(params *SetDocumentContentParams, cb SetDocumentContentCB) *AsyncSetDocumentContentCommand {
 	return &AsyncSetDocumentContentCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1384,7 +1778,28 @@ func (cmd *SetDocumentContentCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetDocumentContentCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetDocumentContentCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetDocumentContentCommand) Send(conn hc.CommandRunner) *AsyncSetDocumentContentCommand {
+	async := NewAsyncSetDocumentContentCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PageSetDeviceMetricsOverrideParams struct {
@@ -1425,17 +1840,18 @@ func (cmd *PageSetDeviceMetricsOverrideCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PageSetDeviceMetricsOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *PageSetDeviceMetricsOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageSetDeviceMetricsOverride(params *PageSetDeviceMetricsOverrideParams, conn *hc.Conn) (err error) {
+func PageSetDeviceMetricsOverride(params *PageSetDeviceMetricsOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewPageSetDeviceMetricsOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageSetDeviceMetricsOverrideCB func(err error)
@@ -1445,12 +1861,16 @@ type PageSetDeviceMetricsOverrideCB func(err error)
 type AsyncPageSetDeviceMetricsOverrideCommand struct {
 	params *PageSetDeviceMetricsOverrideParams
 	cb     PageSetDeviceMetricsOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageSetDeviceMetricsOverrideCommand(params *PageSetDeviceMetricsOverrideParams, cb PageSetDeviceMetricsOverrideCB) *AsyncPageSetDeviceMetricsOverrideCommand {
 	return &AsyncPageSetDeviceMetricsOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1468,7 +1888,28 @@ func (cmd *PageSetDeviceMetricsOverrideCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageSetDeviceMetricsOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageSetDeviceMetricsOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageSetDeviceMetricsOverrideCommand) Send(conn hc.CommandRunner) *AsyncPageSetDeviceMetricsOverrideCommand {
+	async := NewAsyncPageSetDeviceMetricsOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Clears the overriden device metrics.
@@ -1490,17 +1931,18 @@ func (cmd *PageClearDeviceMetricsOverrideCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PageClearDeviceMetricsOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *PageClearDeviceMetricsOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageClearDeviceMetricsOverride(conn *hc.Conn) (err error) {
+func PageClearDeviceMetricsOverride(conn hc.CommandRunner) (err error) {
 	cmd := NewPageClearDeviceMetricsOverrideCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageClearDeviceMetricsOverrideCB func(err error)
@@ -1508,12 +1950,16 @@ type PageClearDeviceMetricsOverrideCB func(err error)
 // Clears the overriden device metrics.
 // @experimental
 type AsyncPageClearDeviceMetricsOverrideCommand struct {
-	cb PageClearDeviceMetricsOverrideCB
+	cb   PageClearDeviceMetricsOverrideCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageClearDeviceMetricsOverrideCommand(cb PageClearDeviceMetricsOverrideCB) *AsyncPageClearDeviceMetricsOverrideCommand {
 	return &AsyncPageClearDeviceMetricsOverrideCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1531,7 +1977,28 @@ func (cmd *PageClearDeviceMetricsOverrideCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageClearDeviceMetricsOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageClearDeviceMetricsOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageClearDeviceMetricsOverrideCommand) Send(conn hc.CommandRunner) *AsyncPageClearDeviceMetricsOverrideCommand {
+	async := NewAsyncPageClearDeviceMetricsOverrideCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PageSetGeolocationOverrideParams struct {
@@ -1541,7 +2008,6 @@ type PageSetGeolocationOverrideParams struct {
 }
 
 // Overrides the Geolocation Position or Error. Omitting any of the parameters emulates position unavailable.
-
 type PageSetGeolocationOverrideCommand struct {
 	params *PageSetGeolocationOverrideParams
 	wg     sync.WaitGroup
@@ -1562,32 +2028,36 @@ func (cmd *PageSetGeolocationOverrideCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PageSetGeolocationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *PageSetGeolocationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageSetGeolocationOverride(params *PageSetGeolocationOverrideParams, conn *hc.Conn) (err error) {
+func PageSetGeolocationOverride(params *PageSetGeolocationOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewPageSetGeolocationOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageSetGeolocationOverrideCB func(err error)
 
 // Overrides the Geolocation Position or Error. Omitting any of the parameters emulates position unavailable.
-
 type AsyncPageSetGeolocationOverrideCommand struct {
 	params *PageSetGeolocationOverrideParams
 	cb     PageSetGeolocationOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageSetGeolocationOverrideCommand(params *PageSetGeolocationOverrideParams, cb PageSetGeolocationOverrideCB) *AsyncPageSetGeolocationOverrideCommand {
 	return &AsyncPageSetGeolocationOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1605,11 +2075,31 @@ func (cmd *PageSetGeolocationOverrideCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageSetGeolocationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Clears the overriden Geolocation Position and Error.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageSetGeolocationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageSetGeolocationOverrideCommand) Send(conn hc.CommandRunner) *AsyncPageSetGeolocationOverrideCommand {
+	async := NewAsyncPageSetGeolocationOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Clears the overriden Geolocation Position and Error.
 type PageClearGeolocationOverrideCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -1627,30 +2117,34 @@ func (cmd *PageClearGeolocationOverrideCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PageClearGeolocationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *PageClearGeolocationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageClearGeolocationOverride(conn *hc.Conn) (err error) {
+func PageClearGeolocationOverride(conn hc.CommandRunner) (err error) {
 	cmd := NewPageClearGeolocationOverrideCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageClearGeolocationOverrideCB func(err error)
 
 // Clears the overriden Geolocation Position and Error.
-
 type AsyncPageClearGeolocationOverrideCommand struct {
-	cb PageClearGeolocationOverrideCB
+	cb   PageClearGeolocationOverrideCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageClearGeolocationOverrideCommand(cb PageClearGeolocationOverrideCB) *AsyncPageClearGeolocationOverrideCommand {
 	return &AsyncPageClearGeolocationOverrideCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1668,7 +2162,28 @@ func (cmd *PageClearGeolocationOverrideCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageClearGeolocationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageClearGeolocationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageClearGeolocationOverrideCommand) Send(conn hc.CommandRunner) *AsyncPageClearGeolocationOverrideCommand {
+	async := NewAsyncPageClearGeolocationOverrideCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PageSetDeviceOrientationOverrideParams struct {
@@ -1699,17 +2214,18 @@ func (cmd *PageSetDeviceOrientationOverrideCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PageSetDeviceOrientationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *PageSetDeviceOrientationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageSetDeviceOrientationOverride(params *PageSetDeviceOrientationOverrideParams, conn *hc.Conn) (err error) {
+func PageSetDeviceOrientationOverride(params *PageSetDeviceOrientationOverrideParams, conn hc.CommandRunner) (err error) {
 	cmd := NewPageSetDeviceOrientationOverrideCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageSetDeviceOrientationOverrideCB func(err error)
@@ -1719,12 +2235,16 @@ type PageSetDeviceOrientationOverrideCB func(err error)
 type AsyncPageSetDeviceOrientationOverrideCommand struct {
 	params *PageSetDeviceOrientationOverrideParams
 	cb     PageSetDeviceOrientationOverrideCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageSetDeviceOrientationOverrideCommand(params *PageSetDeviceOrientationOverrideParams, cb PageSetDeviceOrientationOverrideCB) *AsyncPageSetDeviceOrientationOverrideCommand {
 	return &AsyncPageSetDeviceOrientationOverrideCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1742,7 +2262,28 @@ func (cmd *PageSetDeviceOrientationOverrideCommand) Done(data []byte, err error)
 }
 
 func (cmd *AsyncPageSetDeviceOrientationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageSetDeviceOrientationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageSetDeviceOrientationOverrideCommand) Send(conn hc.CommandRunner) *AsyncPageSetDeviceOrientationOverrideCommand {
+	async := NewAsyncPageSetDeviceOrientationOverrideCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Clears the overridden Device Orientation.
@@ -1764,17 +2305,18 @@ func (cmd *PageClearDeviceOrientationOverrideCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *PageClearDeviceOrientationOverrideCommand) Run(conn *hc.Conn) error {
+func (cmd *PageClearDeviceOrientationOverrideCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageClearDeviceOrientationOverride(conn *hc.Conn) (err error) {
+func PageClearDeviceOrientationOverride(conn hc.CommandRunner) (err error) {
 	cmd := NewPageClearDeviceOrientationOverrideCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageClearDeviceOrientationOverrideCB func(err error)
@@ -1782,12 +2324,16 @@ type PageClearDeviceOrientationOverrideCB func(err error)
 // Clears the overridden Device Orientation.
 // @experimental
 type AsyncPageClearDeviceOrientationOverrideCommand struct {
-	cb PageClearDeviceOrientationOverrideCB
+	cb   PageClearDeviceOrientationOverrideCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageClearDeviceOrientationOverrideCommand(cb PageClearDeviceOrientationOverrideCB) *AsyncPageClearDeviceOrientationOverrideCommand {
 	return &AsyncPageClearDeviceOrientationOverrideCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1805,7 +2351,28 @@ func (cmd *PageClearDeviceOrientationOverrideCommand) Done(data []byte, err erro
 }
 
 func (cmd *AsyncPageClearDeviceOrientationOverrideCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageClearDeviceOrientationOverrideCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageClearDeviceOrientationOverrideCommand) Send(conn hc.CommandRunner) *AsyncPageClearDeviceOrientationOverrideCommand {
+	async := NewAsyncPageClearDeviceOrientationOverrideCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type PageSetTouchEmulationEnabledParams struct {
@@ -1835,17 +2402,18 @@ func (cmd *PageSetTouchEmulationEnabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *PageSetTouchEmulationEnabledCommand) Run(conn *hc.Conn) error {
+func (cmd *PageSetTouchEmulationEnabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func PageSetTouchEmulationEnabled(params *PageSetTouchEmulationEnabledParams, conn *hc.Conn) (err error) {
+func PageSetTouchEmulationEnabled(params *PageSetTouchEmulationEnabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewPageSetTouchEmulationEnabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type PageSetTouchEmulationEnabledCB func(err error)
@@ -1855,12 +2423,16 @@ type PageSetTouchEmulationEnabledCB func(err error)
 type AsyncPageSetTouchEmulationEnabledCommand struct {
 	params *PageSetTouchEmulationEnabledParams
 	cb     PageSetTouchEmulationEnabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncPageSetTouchEmulationEnabledCommand(params *PageSetTouchEmulationEnabledParams, cb PageSetTouchEmulationEnabledCB) *AsyncPageSetTouchEmulationEnabledCommand {
 	return &AsyncPageSetTouchEmulationEnabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1878,7 +2450,28 @@ func (cmd *PageSetTouchEmulationEnabledCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncPageSetTouchEmulationEnabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncPageSetTouchEmulationEnabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *PageSetTouchEmulationEnabledCommand) Send(conn hc.CommandRunner) *AsyncPageSetTouchEmulationEnabledCommand {
+	async := NewAsyncPageSetTouchEmulationEnabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CaptureScreenshotResult struct {
@@ -1905,17 +2498,21 @@ func (cmd *CaptureScreenshotCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CaptureScreenshotCommand) Run(conn *hc.Conn) error {
+func (cmd *CaptureScreenshotCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CaptureScreenshot(conn *hc.Conn) (result *CaptureScreenshotResult, err error) {
+func CaptureScreenshot(conn hc.CommandRunner) (result *CaptureScreenshotResult, err error) {
 	cmd := NewCaptureScreenshotCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CaptureScreenshotCB func(result *CaptureScreenshotResult, err error)
@@ -1923,12 +2520,17 @@ type CaptureScreenshotCB func(result *CaptureScreenshotResult, err error)
 // Capture page screenshot.
 // @experimental
 type AsyncCaptureScreenshotCommand struct {
-	cb CaptureScreenshotCB
+	cb     CaptureScreenshotCB
+	result *CaptureScreenshotResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCaptureScreenshotCommand(cb CaptureScreenshotCB) *AsyncCaptureScreenshotCommand {
 	return &AsyncCaptureScreenshotCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1957,15 +2559,33 @@ func (cmd *AsyncCaptureScreenshotCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCaptureScreenshotCommand) Wait(ctx context.Context) (*CaptureScreenshotResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CaptureScreenshotCommand) Send(conn hc.CommandRunner) *AsyncCaptureScreenshotCommand {
+	async := NewAsyncCaptureScreenshotCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type StartScreencastParams struct {
 	Format        string `json:"format,omitempty"`        // Image compression format.
 	Quality       int    `json:"quality,omitempty"`       // Compression quality from range [0..100].
@@ -1996,17 +2616,18 @@ func (cmd *StartScreencastCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StartScreencastCommand) Run(conn *hc.Conn) error {
+func (cmd *StartScreencastCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StartScreencast(params *StartScreencastParams, conn *hc.Conn) (err error) {
+func StartScreencast(params *StartScreencastParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStartScreencastCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StartScreencastCB func(err error)
@@ -2016,12 +2637,16 @@ type StartScreencastCB func(err error)
 type AsyncStartScreencastCommand struct {
 	params *StartScreencastParams
 	cb     StartScreencastCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStartScreencastCommand(params *StartScreencastParams, cb StartScreencastCB) *AsyncStartScreencastCommand {
 	return &AsyncStartScreencastCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2039,7 +2664,28 @@ func (cmd *StartScreencastCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStartScreencastCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStartScreencastCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StartScreencastCommand) Send(conn hc.CommandRunner) *AsyncStartScreencastCommand {
+	async := NewAsyncStartScreencastCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 // Stops sending each frame in the screencastFrame.
@@ -2061,17 +2707,18 @@ func (cmd *StopScreencastCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StopScreencastCommand) Run(conn *hc.Conn) error {
+func (cmd *StopScreencastCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopScreencast(conn *hc.Conn) (err error) {
+func StopScreencast(conn hc.CommandRunner) (err error) {
 	cmd := NewStopScreencastCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StopScreencastCB func(err error)
@@ -2079,12 +2726,16 @@ type StopScreencastCB func(err error)
 // Stops sending each frame in the screencastFrame.
 // @experimental
 type AsyncStopScreencastCommand struct {
-	cb StopScreencastCB
+	cb   StopScreencastCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopScreencastCommand(cb StopScreencastCB) *AsyncStopScreencastCommand {
 	return &AsyncStopScreencastCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2102,7 +2753,28 @@ func (cmd *StopScreencastCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStopScreencastCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopScreencastCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopScreencastCommand) Send(conn hc.CommandRunner) *AsyncStopScreencastCommand {
+	async := NewAsyncStopScreencastCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ScreencastFrameAckParams struct {
@@ -2131,17 +2803,18 @@ func (cmd *ScreencastFrameAckCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ScreencastFrameAckCommand) Run(conn *hc.Conn) error {
+func (cmd *ScreencastFrameAckCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ScreencastFrameAck(params *ScreencastFrameAckParams, conn *hc.Conn) (err error) {
+func ScreencastFrameAck(params *ScreencastFrameAckParams, conn hc.CommandRunner) (err error) {
 	cmd := NewScreencastFrameAckCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ScreencastFrameAckCB func(err error)
@@ -2151,12 +2824,16 @@ type ScreencastFrameAckCB func(err error)
 type AsyncScreencastFrameAckCommand struct {
 	params *ScreencastFrameAckParams
 	cb     ScreencastFrameAckCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncScreencastFrameAckCommand(params *ScreencastFrameAckParams, cb ScreencastFrameAckCB) *AsyncScreencastFrameAckCommand {
 	return &AsyncScreencastFrameAckCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2174,7 +2851,28 @@ func (cmd *ScreencastFrameAckCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncScreencastFrameAckCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncScreencastFrameAckCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ScreencastFrameAckCommand) Send(conn hc.CommandRunner) *AsyncScreencastFrameAckCommand {
+	async := NewAsyncScreencastFrameAckCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type HandleJavaScriptDialogParams struct {
@@ -2183,7 +2881,6 @@ type HandleJavaScriptDialogParams struct {
 }
 
 // Accepts or dismisses a JavaScript initiated dialog (alert, confirm, prompt, or onbeforeunload).
-
 type HandleJavaScriptDialogCommand struct {
 	params *HandleJavaScriptDialogParams
 	wg     sync.WaitGroup
@@ -2204,32 +2901,36 @@ func (cmd *HandleJavaScriptDialogCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *HandleJavaScriptDialogCommand) Run(conn *hc.Conn) error {
+func (cmd *HandleJavaScriptDialogCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func HandleJavaScriptDialog(params *HandleJavaScriptDialogParams, conn *hc.Conn) (err error) {
+func HandleJavaScriptDialog(params *HandleJavaScriptDialogParams, conn hc.CommandRunner) (err error) {
 	cmd := NewHandleJavaScriptDialogCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type HandleJavaScriptDialogCB func(err error)
 
 // Accepts or dismisses a JavaScript initiated dialog (alert, confirm, prompt, or onbeforeunload).
-
 type AsyncHandleJavaScriptDialogCommand struct {
 	params *HandleJavaScriptDialogParams
 	cb     HandleJavaScriptDialogCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncHandleJavaScriptDialogCommand(params *HandleJavaScriptDialogParams, cb HandleJavaScriptDialogCB) *AsyncHandleJavaScriptDialogCommand {
 	return &AsyncHandleJavaScriptDialogCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2247,7 +2948,28 @@ func (cmd *HandleJavaScriptDialogCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncHandleJavaScriptDialogCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncHandleJavaScriptDialogCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *HandleJavaScriptDialogCommand) Send(conn hc.CommandRunner) *AsyncHandleJavaScriptDialogCommand {
+	async := NewAsyncHandleJavaScriptDialogCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetColorPickerEnabledParams struct {
@@ -2276,17 +2998,18 @@ func (cmd *SetColorPickerEnabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetColorPickerEnabledCommand) Run(conn *hc.Conn) error {
+func (cmd *SetColorPickerEnabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetColorPickerEnabled(params *SetColorPickerEnabledParams, conn *hc.Conn) (err error) {
+func SetColorPickerEnabled(params *SetColorPickerEnabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetColorPickerEnabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetColorPickerEnabledCB func(err error)
@@ -2296,12 +3019,16 @@ type SetColorPickerEnabledCB func(err error)
 type AsyncSetColorPickerEnabledCommand struct {
 	params *SetColorPickerEnabledParams
 	cb     SetColorPickerEnabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetColorPickerEnabledCommand(params *SetColorPickerEnabledParams, cb SetColorPickerEnabledCB) *AsyncSetColorPickerEnabledCommand {
 	return &AsyncSetColorPickerEnabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2319,7 +3046,28 @@ func (cmd *SetColorPickerEnabledCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetColorPickerEnabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetColorPickerEnabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetColorPickerEnabledCommand) Send(conn hc.CommandRunner) *AsyncSetColorPickerEnabledCommand {
+	async := NewAsyncSetColorPickerEnabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ConfigureOverlayParams struct {
@@ -2349,17 +3097,18 @@ func (cmd *ConfigureOverlayCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ConfigureOverlayCommand) Run(conn *hc.Conn) error {
+func (cmd *ConfigureOverlayCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ConfigureOverlay(params *ConfigureOverlayParams, conn *hc.Conn) (err error) {
+func ConfigureOverlay(params *ConfigureOverlayParams, conn hc.CommandRunner) (err error) {
 	cmd := NewConfigureOverlayCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ConfigureOverlayCB func(err error)
@@ -2369,12 +3118,16 @@ type ConfigureOverlayCB func(err error)
 type AsyncConfigureOverlayCommand struct {
 	params *ConfigureOverlayParams
 	cb     ConfigureOverlayCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncConfigureOverlayCommand(params *ConfigureOverlayParams, cb ConfigureOverlayCB) *AsyncConfigureOverlayCommand {
 	return &AsyncConfigureOverlayCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2392,7 +3145,28 @@ func (cmd *ConfigureOverlayCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncConfigureOverlayCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncConfigureOverlayCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ConfigureOverlayCommand) Send(conn hc.CommandRunner) *AsyncConfigureOverlayCommand {
+	async := NewAsyncConfigureOverlayCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetAppManifestResult struct {
@@ -2420,29 +3194,38 @@ func (cmd *GetAppManifestCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetAppManifestCommand) Run(conn *hc.Conn) error {
+func (cmd *GetAppManifestCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetAppManifest(conn *hc.Conn) (result *GetAppManifestResult, err error) {
+func GetAppManifest(conn hc.CommandRunner) (result *GetAppManifestResult, err error) {
 	cmd := NewGetAppManifestCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetAppManifestCB func(result *GetAppManifestResult, err error)
 
 // @experimental
 type AsyncGetAppManifestCommand struct {
-	cb GetAppManifestCB
+	cb     GetAppManifestCB
+	result *GetAppManifestResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetAppManifestCommand(cb GetAppManifestCB) *AsyncGetAppManifestCommand {
 	return &AsyncGetAppManifestCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2471,15 +3254,33 @@ func (cmd *AsyncGetAppManifestCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetAppManifestCommand) Wait(ctx context.Context) (*GetAppManifestResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetAppManifestCommand) Send(conn hc.CommandRunner) *AsyncGetAppManifestCommand {
+	async := NewAsyncGetAppManifestCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 // @experimental
 type RequestAppBannerCommand struct {
 	wg  sync.WaitGroup
@@ -2498,29 +3299,34 @@ func (cmd *RequestAppBannerCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *RequestAppBannerCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestAppBannerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestAppBanner(conn *hc.Conn) (err error) {
+func RequestAppBanner(conn hc.CommandRunner) (err error) {
 	cmd := NewRequestAppBannerCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RequestAppBannerCB func(err error)
 
 // @experimental
 type AsyncRequestAppBannerCommand struct {
-	cb RequestAppBannerCB
+	cb   RequestAppBannerCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestAppBannerCommand(cb RequestAppBannerCB) *AsyncRequestAppBannerCommand {
 	return &AsyncRequestAppBannerCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2538,7 +3344,28 @@ func (cmd *RequestAppBannerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRequestAppBannerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestAppBannerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestAppBannerCommand) Send(conn hc.CommandRunner) *AsyncRequestAppBannerCommand {
+	async := NewAsyncRequestAppBannerCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetControlNavigationsParams struct {
@@ -2567,17 +3394,18 @@ func (cmd *SetControlNavigationsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetControlNavigationsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetControlNavigationsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetControlNavigations(params *SetControlNavigationsParams, conn *hc.Conn) (err error) {
+func SetControlNavigations(params *SetControlNavigationsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetControlNavigationsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetControlNavigationsCB func(err error)
@@ -2587,12 +3415,16 @@ type SetControlNavigationsCB func(err error)
 type AsyncSetControlNavigationsCommand struct {
 	params *SetControlNavigationsParams
 	cb     SetControlNavigationsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetControlNavigationsCommand(params *SetControlNavigationsParams, cb SetControlNavigationsCB) *AsyncSetControlNavigationsCommand {
 	return &AsyncSetControlNavigationsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2610,7 +3442,28 @@ func (cmd *SetControlNavigationsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetControlNavigationsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetControlNavigationsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetControlNavigationsCommand) Send(conn hc.CommandRunner) *AsyncSetControlNavigationsCommand {
+	async := NewAsyncSetControlNavigationsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ProcessNavigationParams struct {
@@ -2640,17 +3493,18 @@ func (cmd *ProcessNavigationCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ProcessNavigationCommand) Run(conn *hc.Conn) error {
+func (cmd *ProcessNavigationCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ProcessNavigation(params *ProcessNavigationParams, conn *hc.Conn) (err error) {
+func ProcessNavigation(params *ProcessNavigationParams, conn hc.CommandRunner) (err error) {
 	cmd := NewProcessNavigationCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ProcessNavigationCB func(err error)
@@ -2660,12 +3514,16 @@ type ProcessNavigationCB func(err error)
 type AsyncProcessNavigationCommand struct {
 	params *ProcessNavigationParams
 	cb     ProcessNavigationCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncProcessNavigationCommand(params *ProcessNavigationParams, cb ProcessNavigationCB) *AsyncProcessNavigationCommand {
 	return &AsyncProcessNavigationCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -2683,7 +3541,28 @@ func (cmd *ProcessNavigationCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncProcessNavigationCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncProcessNavigationCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ProcessNavigationCommand) Send(conn hc.CommandRunner) *AsyncProcessNavigationCommand {
+	async := NewAsyncProcessNavigationCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetLayoutMetricsResult struct {
@@ -2711,17 +3590,21 @@ func (cmd *GetLayoutMetricsCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetLayoutMetricsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetLayoutMetricsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetLayoutMetrics(conn *hc.Conn) (result *GetLayoutMetricsResult, err error) {
+func GetLayoutMetrics(conn hc.CommandRunner) (result *GetLayoutMetricsResult, err error) {
 	cmd := NewGetLayoutMetricsCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetLayoutMetricsCB func(result *GetLayoutMetricsResult, err error)
@@ -2729,12 +3612,17 @@ type GetLayoutMetricsCB func(result *GetLayoutMetricsResult, err error)
 // Returns metrics relating to the layouting of the page, such as viewport bounds/scale.
 // @experimental
 type AsyncGetLayoutMetricsCommand struct {
-	cb GetLayoutMetricsCB
+	cb     GetLayoutMetricsCB
+	result *GetLayoutMetricsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetLayoutMetricsCommand(cb GetLayoutMetricsCB) *AsyncGetLayoutMetricsCommand {
 	return &AsyncGetLayoutMetricsCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -2763,100 +3651,346 @@ func (cmd *AsyncGetLayoutMetricsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetLayoutMetricsCommand) Wait(ctx context.Context) (*GetLayoutMetricsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetLayoutMetricsCommand) Send(conn hc.CommandRunner) *AsyncGetLayoutMetricsCommand {
+	async := NewAsyncGetLayoutMetricsCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+type CreateIsolatedWorldParams struct {
+	FrameId             FrameId `json:"frameId"`                       // Id of the frame in which the isolated world should be created.
+	WorldName           string  `json:"worldName,omitempty"`           // An optional name which is reported in the Execution Context.
+	GrantUniveralAccess bool    `json:"grantUniveralAccess,omitempty"` // Whether or not universal access should be granted to the isolated world. This is a powerful option, use with caution.
+}
+
+type CreateIsolatedWorldResult struct {
+	ExecutionContextId ExecutionContextId `json:"executionContextId"` // Execution context of the isolated world.
+}
+
+// Creates an isolated world for the given frame.
+// @experimental
+type CreateIsolatedWorldCommand struct {
+	params *CreateIsolatedWorldParams
+	result CreateIsolatedWorldResult
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewCreateIsolatedWorldCommand(params *CreateIsolatedWorldParams) *CreateIsolatedWorldCommand {
+	return &CreateIsolatedWorldCommand{
+		params: params,
+	}
+}
+
+func (cmd *CreateIsolatedWorldCommand) Name() string {
+	return "Page.createIsolatedWorld"
+}
+
+func (cmd *CreateIsolatedWorldCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *CreateIsolatedWorldCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func CreateIsolatedWorld(params *CreateIsolatedWorldParams, conn hc.CommandRunner) (result *CreateIsolatedWorldResult, err error) {
+	cmd := NewCreateIsolatedWorldCommand(params)
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
+}
+
+type CreateIsolatedWorldCB func(result *CreateIsolatedWorldResult, err error)
+
+// Creates an isolated world for the given frame.
+// @experimental
+type AsyncCreateIsolatedWorldCommand struct {
+	params *CreateIsolatedWorldParams
+	cb     CreateIsolatedWorldCB
+	result *CreateIsolatedWorldResult
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncCreateIsolatedWorldCommand(params *CreateIsolatedWorldParams, cb CreateIsolatedWorldCB) *AsyncCreateIsolatedWorldCommand {
+	return &AsyncCreateIsolatedWorldCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncCreateIsolatedWorldCommand) Name() string {
+	return "Page.createIsolatedWorld"
+}
+
+func (cmd *AsyncCreateIsolatedWorldCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *CreateIsolatedWorldCommand) Result() *CreateIsolatedWorldResult {
+	return &cmd.result
+}
+
+func (cmd *CreateIsolatedWorldCommand) Done(data []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(data, &cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncCreateIsolatedWorldCommand) Done(data []byte, err error) {
+	var result CreateIsolatedWorldResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCreateIsolatedWorldCommand) Wait(ctx context.Context) (*CreateIsolatedWorldResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CreateIsolatedWorldCommand) Send(conn hc.CommandRunner) *AsyncCreateIsolatedWorldCommand {
+	async := NewAsyncCreateIsolatedWorldCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type DomContentEventFiredEvent struct {
 	Timestamp float64 `json:"timestamp"`
 }
 
-func OnDomContentEventFired(conn *hc.Conn, cb func(evt *DomContentEventFiredEvent)) {
+// Calls cb for each Page.domContentEventFired event, till the returned func is called.
+func OnDomContentEventFired(conn hc.EventSource, cb func(evt *DomContentEventFiredEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DomContentEventFiredEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.domContentEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.domContentEventFired", sink) }
+}
+
+// Like OnDomContentEventFired, but cb also gets hc.EventMeta.
+func OnDomContentEventFiredMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DomContentEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DomContentEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.domContentEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.domContentEventFired", sink) }
+}
+
+// Like OnDomContentEventFired, but cb is called right away if the event already fired for the current document.
+func OnDomContentEventFiredSticky(conn *hc.Conn, cb func(evt *DomContentEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &DomContentEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddStickyEventSink("Page.domContentEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.domContentEventFired", sink) }
 }
 
 type LoadEventFiredEvent struct {
 	Timestamp float64 `json:"timestamp"`
 }
 
-func OnLoadEventFired(conn *hc.Conn, cb func(evt *LoadEventFiredEvent)) {
+// Calls cb for each Page.loadEventFired event, till the returned func is called.
+func OnLoadEventFired(conn hc.EventSource, cb func(evt *LoadEventFiredEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &LoadEventFiredEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.loadEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.loadEventFired", sink) }
 }
 
-// Fired when frame has been attached to its parent.
+// Like OnLoadEventFired, but cb also gets hc.EventMeta.
+func OnLoadEventFiredMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LoadEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LoadEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.loadEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.loadEventFired", sink) }
+}
 
+// Like OnLoadEventFired, but cb is called right away if the event already fired for the current document.
+func OnLoadEventFiredSticky(conn *hc.Conn, cb func(evt *LoadEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &LoadEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddStickyEventSink("Page.loadEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.loadEventFired", sink) }
+}
+
+// Fired when frame has been attached to its parent.
 type FrameAttachedEvent struct {
 	FrameId       FrameId `json:"frameId"`       // Id of the frame that has been attached.
 	ParentFrameId FrameId `json:"parentFrameId"` // Parent frame identifier.
 }
 
-func OnFrameAttached(conn *hc.Conn, cb func(evt *FrameAttachedEvent)) {
+// Calls cb for each Page.frameAttached event, till the returned func is called.
+func OnFrameAttached(conn hc.EventSource, cb func(evt *FrameAttachedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameAttachedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameAttached", sink)
+	return func() { conn.RemoveEventSink("Page.frameAttached", sink) }
 }
 
-// Fired once navigation of the frame has completed. Frame is now associated with the new loader.
+// Like OnFrameAttached, but cb also gets hc.EventMeta.
+func OnFrameAttachedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameAttachedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameAttachedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameAttached", sink)
+	return func() { conn.RemoveEventSink("Page.frameAttached", sink) }
+}
 
+// Fired once navigation of the frame has completed. Frame is now associated with the new loader.
 type FrameNavigatedEvent struct {
 	Frame *Frame `json:"frame"` // Frame object.
 }
 
-func OnFrameNavigated(conn *hc.Conn, cb func(evt *FrameNavigatedEvent)) {
+// Calls cb for each Page.frameNavigated event, till the returned func is called.
+func OnFrameNavigated(conn hc.EventSource, cb func(evt *FrameNavigatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameNavigatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameNavigated", sink)
+	return func() { conn.RemoveEventSink("Page.frameNavigated", sink) }
 }
 
-// Fired when frame has been detached from its parent.
+// Like OnFrameNavigated, but cb also gets hc.EventMeta.
+func OnFrameNavigatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameNavigatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameNavigatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameNavigated", sink)
+	return func() { conn.RemoveEventSink("Page.frameNavigated", sink) }
+}
 
+// Fired when frame has been detached from its parent.
 type FrameDetachedEvent struct {
 	FrameId FrameId `json:"frameId"` // Id of the frame that has been detached.
 }
 
-func OnFrameDetached(conn *hc.Conn, cb func(evt *FrameDetachedEvent)) {
+// Calls cb for each Page.frameDetached event, till the returned func is called.
+func OnFrameDetached(conn hc.EventSource, cb func(evt *FrameDetachedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameDetachedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameDetached", sink)
+	return func() { conn.RemoveEventSink("Page.frameDetached", sink) }
+}
+
+// Like OnFrameDetached, but cb also gets hc.EventMeta.
+func OnFrameDetachedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameDetachedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameDetachedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameDetached", sink)
+	return func() { conn.RemoveEventSink("Page.frameDetached", sink) }
 }
 
 // Fired when frame has started loading.
@@ -2865,16 +3999,32 @@ type FrameStartedLoadingEvent struct {
 	FrameId FrameId `json:"frameId"` // Id of the frame that has started loading.
 }
 
-func OnFrameStartedLoading(conn *hc.Conn, cb func(evt *FrameStartedLoadingEvent)) {
+// Calls cb for each Page.frameStartedLoading event, till the returned func is called.
+func OnFrameStartedLoading(conn hc.EventSource, cb func(evt *FrameStartedLoadingEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameStartedLoadingEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameStartedLoading", sink)
+	return func() { conn.RemoveEventSink("Page.frameStartedLoading", sink) }
+}
+
+// Like OnFrameStartedLoading, but cb also gets hc.EventMeta.
+func OnFrameStartedLoadingMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameStartedLoadingEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameStartedLoadingEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameStartedLoading", sink)
+	return func() { conn.RemoveEventSink("Page.frameStartedLoading", sink) }
 }
 
 // Fired when frame has stopped loading.
@@ -2883,16 +4033,46 @@ type FrameStoppedLoadingEvent struct {
 	FrameId FrameId `json:"frameId"` // Id of the frame that has stopped loading.
 }
 
-func OnFrameStoppedLoading(conn *hc.Conn, cb func(evt *FrameStoppedLoadingEvent)) {
+// Calls cb for each Page.frameStoppedLoading event, till the returned func is called.
+func OnFrameStoppedLoading(conn hc.EventSource, cb func(evt *FrameStoppedLoadingEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameStoppedLoadingEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameStoppedLoading", sink)
+	return func() { conn.RemoveEventSink("Page.frameStoppedLoading", sink) }
+}
+
+// Like OnFrameStoppedLoading, but cb also gets hc.EventMeta.
+func OnFrameStoppedLoadingMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameStoppedLoadingEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameStoppedLoadingEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameStoppedLoading", sink)
+	return func() { conn.RemoveEventSink("Page.frameStoppedLoading", sink) }
+}
+
+// Like OnFrameStoppedLoading, but cb is called right away if the event already fired for the current document.
+func OnFrameStoppedLoadingSticky(conn *hc.Conn, cb func(evt *FrameStoppedLoadingEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &FrameStoppedLoadingEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddStickyEventSink("Page.frameStoppedLoading", sink)
+	return func() { conn.RemoveEventSink("Page.frameStoppedLoading", sink) }
 }
 
 // Fired when frame schedules a potential navigation.
@@ -2902,16 +4082,32 @@ type FrameScheduledNavigationEvent struct {
 	Delay   float64 `json:"delay"`   // Delay (in seconds) until the navigation is scheduled to begin. The navigation is not guaranteed to start.
 }
 
-func OnFrameScheduledNavigation(conn *hc.Conn, cb func(evt *FrameScheduledNavigationEvent)) {
+// Calls cb for each Page.frameScheduledNavigation event, till the returned func is called.
+func OnFrameScheduledNavigation(conn hc.EventSource, cb func(evt *FrameScheduledNavigationEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameScheduledNavigationEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameScheduledNavigation", sink)
+	return func() { conn.RemoveEventSink("Page.frameScheduledNavigation", sink) }
+}
+
+// Like OnFrameScheduledNavigation, but cb also gets hc.EventMeta.
+func OnFrameScheduledNavigationMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameScheduledNavigationEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameScheduledNavigationEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameScheduledNavigation", sink)
+	return func() { conn.RemoveEventSink("Page.frameScheduledNavigation", sink) }
 }
 
 // Fired when frame no longer has a scheduled navigation.
@@ -2920,69 +4116,131 @@ type FrameClearedScheduledNavigationEvent struct {
 	FrameId FrameId `json:"frameId"` // Id of the frame that has cleared its scheduled navigation.
 }
 
-func OnFrameClearedScheduledNavigation(conn *hc.Conn, cb func(evt *FrameClearedScheduledNavigationEvent)) {
+// Calls cb for each Page.frameClearedScheduledNavigation event, till the returned func is called.
+func OnFrameClearedScheduledNavigation(conn hc.EventSource, cb func(evt *FrameClearedScheduledNavigationEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameClearedScheduledNavigationEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameClearedScheduledNavigation", sink)
+	return func() { conn.RemoveEventSink("Page.frameClearedScheduledNavigation", sink) }
+}
+
+// Like OnFrameClearedScheduledNavigation, but cb also gets hc.EventMeta.
+func OnFrameClearedScheduledNavigationMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameClearedScheduledNavigationEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameClearedScheduledNavigationEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameClearedScheduledNavigation", sink)
+	return func() { conn.RemoveEventSink("Page.frameClearedScheduledNavigation", sink) }
 }
 
 // @experimental
 type FrameResizedEvent struct {
 }
 
-func OnFrameResized(conn *hc.Conn, cb func(evt *FrameResizedEvent)) {
+// Calls cb for each Page.frameResized event, till the returned func is called.
+func OnFrameResized(conn hc.EventSource, cb func(evt *FrameResizedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &FrameResizedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.frameResized", sink)
+	return func() { conn.RemoveEventSink("Page.frameResized", sink) }
 }
 
-// Fired when a JavaScript initiated dialog (alert, confirm, prompt, or onbeforeunload) is about to open.
+// Like OnFrameResized, but cb also gets hc.EventMeta.
+func OnFrameResizedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *FrameResizedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &FrameResizedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.frameResized", sink)
+	return func() { conn.RemoveEventSink("Page.frameResized", sink) }
+}
 
+// Fired when a JavaScript initiated dialog (alert, confirm, prompt, or onbeforeunload) is about to open.
 type JavascriptDialogOpeningEvent struct {
 	Message string     `json:"message"` // Message that will be displayed by the dialog.
 	Type    DialogType `json:"type"`    // Dialog type.
 }
 
-func OnJavascriptDialogOpening(conn *hc.Conn, cb func(evt *JavascriptDialogOpeningEvent)) {
+// Calls cb for each Page.javascriptDialogOpening event, till the returned func is called.
+func OnJavascriptDialogOpening(conn hc.EventSource, cb func(evt *JavascriptDialogOpeningEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &JavascriptDialogOpeningEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.javascriptDialogOpening", sink)
+	return func() { conn.RemoveEventSink("Page.javascriptDialogOpening", sink) }
 }
 
-// Fired when a JavaScript initiated dialog (alert, confirm, prompt, or onbeforeunload) has been closed.
+// Like OnJavascriptDialogOpening, but cb also gets hc.EventMeta.
+func OnJavascriptDialogOpeningMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *JavascriptDialogOpeningEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &JavascriptDialogOpeningEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.javascriptDialogOpening", sink)
+	return func() { conn.RemoveEventSink("Page.javascriptDialogOpening", sink) }
+}
 
+// Fired when a JavaScript initiated dialog (alert, confirm, prompt, or onbeforeunload) has been closed.
 type JavascriptDialogClosedEvent struct {
 	Result bool `json:"result"` // Whether dialog was confirmed.
 }
 
-func OnJavascriptDialogClosed(conn *hc.Conn, cb func(evt *JavascriptDialogClosedEvent)) {
+// Calls cb for each Page.javascriptDialogClosed event, till the returned func is called.
+func OnJavascriptDialogClosed(conn hc.EventSource, cb func(evt *JavascriptDialogClosedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &JavascriptDialogClosedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.javascriptDialogClosed", sink)
+	return func() { conn.RemoveEventSink("Page.javascriptDialogClosed", sink) }
+}
+
+// Like OnJavascriptDialogClosed, but cb also gets hc.EventMeta.
+func OnJavascriptDialogClosedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *JavascriptDialogClosedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &JavascriptDialogClosedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.javascriptDialogClosed", sink)
+	return func() { conn.RemoveEventSink("Page.javascriptDialogClosed", sink) }
 }
 
 // Compressed image data requested by the startScreencast.
@@ -2993,16 +4251,32 @@ type ScreencastFrameEvent struct {
 	SessionId int                      `json:"sessionId"` // Frame number.
 }
 
-func OnScreencastFrame(conn *hc.Conn, cb func(evt *ScreencastFrameEvent)) {
+// Calls cb for each Page.screencastFrame event, till the returned func is called.
+func OnScreencastFrame(conn hc.EventSource, cb func(evt *ScreencastFrameEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ScreencastFrameEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.screencastFrame", sink)
+	return func() { conn.RemoveEventSink("Page.screencastFrame", sink) }
+}
+
+// Like OnScreencastFrame, but cb also gets hc.EventMeta.
+func OnScreencastFrameMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ScreencastFrameEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ScreencastFrameEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.screencastFrame", sink)
+	return func() { conn.RemoveEventSink("Page.screencastFrame", sink) }
 }
 
 // Fired when the page with currently enabled screencast was shown or hidden .
@@ -3011,16 +4285,32 @@ type ScreencastVisibilityChangedEvent struct {
 	Visible bool `json:"visible"` // True if the page is visible.
 }
 
-func OnScreencastVisibilityChanged(conn *hc.Conn, cb func(evt *ScreencastVisibilityChangedEvent)) {
+// Calls cb for each Page.screencastVisibilityChanged event, till the returned func is called.
+func OnScreencastVisibilityChanged(conn hc.EventSource, cb func(evt *ScreencastVisibilityChangedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ScreencastVisibilityChangedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.screencastVisibilityChanged", sink)
+	return func() { conn.RemoveEventSink("Page.screencastVisibilityChanged", sink) }
+}
+
+// Like OnScreencastVisibilityChanged, but cb also gets hc.EventMeta.
+func OnScreencastVisibilityChangedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ScreencastVisibilityChangedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ScreencastVisibilityChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.screencastVisibilityChanged", sink)
+	return func() { conn.RemoveEventSink("Page.screencastVisibilityChanged", sink) }
 }
 
 // Fired when a color has been picked.
@@ -3029,54 +4319,99 @@ type ColorPickedEvent struct {
 	Color *RGBA `json:"color"` // RGBA of the picked color.
 }
 
-func OnColorPicked(conn *hc.Conn, cb func(evt *ColorPickedEvent)) {
+// Calls cb for each Page.colorPicked event, till the returned func is called.
+func OnColorPicked(conn hc.EventSource, cb func(evt *ColorPickedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ColorPickedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.colorPicked", sink)
+	return func() { conn.RemoveEventSink("Page.colorPicked", sink) }
 }
 
-// Fired when interstitial page was shown
+// Like OnColorPicked, but cb also gets hc.EventMeta.
+func OnColorPickedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ColorPickedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ColorPickedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.colorPicked", sink)
+	return func() { conn.RemoveEventSink("Page.colorPicked", sink) }
+}
 
+// Fired when interstitial page was shown
 type InterstitialShownEvent struct {
 }
 
-func OnInterstitialShown(conn *hc.Conn, cb func(evt *InterstitialShownEvent)) {
+// Calls cb for each Page.interstitialShown event, till the returned func is called.
+func OnInterstitialShown(conn hc.EventSource, cb func(evt *InterstitialShownEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &InterstitialShownEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.interstitialShown", sink)
+	return func() { conn.RemoveEventSink("Page.interstitialShown", sink) }
 }
 
-// Fired when interstitial page was hidden
+// Like OnInterstitialShown, but cb also gets hc.EventMeta.
+func OnInterstitialShownMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *InterstitialShownEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &InterstitialShownEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.interstitialShown", sink)
+	return func() { conn.RemoveEventSink("Page.interstitialShown", sink) }
+}
 
+// Fired when interstitial page was hidden
 type InterstitialHiddenEvent struct {
 }
 
-func OnInterstitialHidden(conn *hc.Conn, cb func(evt *InterstitialHiddenEvent)) {
+// Calls cb for each Page.interstitialHidden event, till the returned func is called.
+func OnInterstitialHidden(conn hc.EventSource, cb func(evt *InterstitialHiddenEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &InterstitialHiddenEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.interstitialHidden", sink)
+	return func() { conn.RemoveEventSink("Page.interstitialHidden", sink) }
 }
 
-// Fired when a navigation is started if navigation throttles are enabled.  The navigation will be deferred until processNavigation is called.
+// Like OnInterstitialHidden, but cb also gets hc.EventMeta.
+func OnInterstitialHiddenMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *InterstitialHiddenEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &InterstitialHiddenEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.interstitialHidden", sink)
+	return func() { conn.RemoveEventSink("Page.interstitialHidden", sink) }
+}
 
+// Fired when a navigation is started if navigation throttles are enabled.  The navigation will be deferred until processNavigation is called.
 type NavigationRequestedEvent struct {
 	IsInMainFrame bool   `json:"isInMainFrame"` // Whether the navigation is taking place in the main frame or in a subframe.
 	IsRedirect    bool   `json:"isRedirect"`    // Whether the navigation has encountered a server redirect or not.
@@ -3084,14 +4419,30 @@ type NavigationRequestedEvent struct {
 	Url           string `json:"url"` // URL of requested navigation.
 }
 
-func OnNavigationRequested(conn *hc.Conn, cb func(evt *NavigationRequestedEvent)) {
+// Calls cb for each Page.navigationRequested event, till the returned func is called.
+func OnNavigationRequested(conn hc.EventSource, cb func(evt *NavigationRequestedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &NavigationRequestedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Page.navigationRequested", sink)
+	return func() { conn.RemoveEventSink("Page.navigationRequested", sink) }
+}
+
+// Like OnNavigationRequested, but cb also gets hc.EventMeta.
+func OnNavigationRequestedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *NavigationRequestedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &NavigationRequestedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.navigationRequested", sink)
+	return func() { conn.RemoveEventSink("Page.navigationRequested", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -50,28 +50,33 @@ func (cmd *ProfilerEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ProfilerEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *ProfilerEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ProfilerEnable(conn *hc.Conn) (err error) {
+func ProfilerEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewProfilerEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ProfilerEnableCB func(err error)
 
 type AsyncProfilerEnableCommand struct {
-	cb ProfilerEnableCB
+	cb   ProfilerEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncProfilerEnableCommand(cb ProfilerEnableCB) *AsyncProfilerEnableCommand {
 	return &AsyncProfilerEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -89,7 +94,28 @@ func (cmd *ProfilerEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncProfilerEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncProfilerEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ProfilerEnableCommand) Send(conn hc.CommandRunner) *AsyncProfilerEnableCommand {
+	async := NewAsyncProfilerEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ProfilerDisableCommand struct {
@@ -109,28 +135,33 @@ func (cmd *ProfilerDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ProfilerDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *ProfilerDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ProfilerDisable(conn *hc.Conn) (err error) {
+func ProfilerDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewProfilerDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ProfilerDisableCB func(err error)
 
 type AsyncProfilerDisableCommand struct {
-	cb ProfilerDisableCB
+	cb   ProfilerDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncProfilerDisableCommand(cb ProfilerDisableCB) *AsyncProfilerDisableCommand {
 	return &AsyncProfilerDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -148,7 +179,28 @@ func (cmd *ProfilerDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncProfilerDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncProfilerDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ProfilerDisableCommand) Send(conn hc.CommandRunner) *AsyncProfilerDisableCommand {
+	async := NewAsyncProfilerDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetSamplingIntervalParams struct {
@@ -156,7 +208,6 @@ type SetSamplingIntervalParams struct {
 }
 
 // Changes CPU profiler sampling interval. Must be called before CPU profiles recording started.
-
 type SetSamplingIntervalCommand struct {
 	params *SetSamplingIntervalParams
 	wg     sync.WaitGroup
@@ -177,32 +228,36 @@ func (cmd *SetSamplingIntervalCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetSamplingIntervalCommand) Run(conn *hc.Conn) error {
+func (cmd *SetSamplingIntervalCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetSamplingInterval(params *SetSamplingIntervalParams, conn *hc.Conn) (err error) {
+func SetSamplingInterval(params *SetSamplingIntervalParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetSamplingIntervalCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetSamplingIntervalCB func(err error)
 
 // Changes CPU profiler sampling interval. Must be called before CPU profiles recording started.
-
 type AsyncSetSamplingIntervalCommand struct {
 	params *SetSamplingIntervalParams
 	cb     SetSamplingIntervalCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetSamplingIntervalCommand(params *SetSamplingIntervalParams, cb SetSamplingIntervalCB) *AsyncSetSamplingIntervalCommand {
 	return &AsyncSetSamplingIntervalCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -220,7 +275,28 @@ func (cmd *SetSamplingIntervalCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetSamplingIntervalCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetSamplingIntervalCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetSamplingIntervalCommand) Send(conn hc.CommandRunner) *AsyncSetSamplingIntervalCommand {
+	async := NewAsyncSetSamplingIntervalCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ProfilerStartCommand struct {
@@ -240,28 +316,33 @@ func (cmd *ProfilerStartCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ProfilerStartCommand) Run(conn *hc.Conn) error {
+func (cmd *ProfilerStartCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ProfilerStart(conn *hc.Conn) (err error) {
+func ProfilerStart(conn hc.CommandRunner) (err error) {
 	cmd := NewProfilerStartCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ProfilerStartCB func(err error)
 
 type AsyncProfilerStartCommand struct {
-	cb ProfilerStartCB
+	cb   ProfilerStartCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncProfilerStartCommand(cb ProfilerStartCB) *AsyncProfilerStartCommand {
 	return &AsyncProfilerStartCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -279,7 +360,28 @@ func (cmd *ProfilerStartCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncProfilerStartCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncProfilerStartCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ProfilerStartCommand) Send(conn hc.CommandRunner) *AsyncProfilerStartCommand {
+	async := NewAsyncProfilerStartCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StopResult struct {
@@ -304,28 +406,37 @@ func (cmd *StopCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *StopCommand) Run(conn *hc.Conn) error {
+func (cmd *StopCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Stop(conn *hc.Conn) (result *StopResult, err error) {
+func Stop(conn hc.CommandRunner) (result *StopResult, err error) {
 	cmd := NewStopCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type StopCB func(result *StopResult, err error)
 
 type AsyncStopCommand struct {
-	cb StopCB
+	cb     StopCB
+	result *StopResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopCommand(cb StopCB) *AsyncStopCommand {
 	return &AsyncStopCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -354,33 +465,66 @@ func (cmd *AsyncStopCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Sent when new profile recodring is started using console.profile() call.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopCommand) Wait(ctx context.Context) (*StopResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopCommand) Send(conn hc.CommandRunner) *AsyncStopCommand {
+	async := NewAsyncStopCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Sent when new profile recodring is started using console.profile() call.
 type ConsoleProfileStartedEvent struct {
 	Id       string    `json:"id"`
 	Location *Location `json:"location"` // Location of console.profile().
 	Title    string    `json:"title"`    // Profile title passed as an argument to console.profile().
 }
 
-func OnConsoleProfileStarted(conn *hc.Conn, cb func(evt *ConsoleProfileStartedEvent)) {
+// Calls cb for each Profiler.consoleProfileStarted event, till the returned func is called.
+func OnConsoleProfileStarted(conn hc.EventSource, cb func(evt *ConsoleProfileStartedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ConsoleProfileStartedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Profiler.consoleProfileStarted", sink)
+	return func() { conn.RemoveEventSink("Profiler.consoleProfileStarted", sink) }
+}
+
+// Like OnConsoleProfileStarted, but cb also gets hc.EventMeta.
+func OnConsoleProfileStartedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ConsoleProfileStartedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ConsoleProfileStartedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Profiler.consoleProfileStarted", sink)
+	return func() { conn.RemoveEventSink("Profiler.consoleProfileStarted", sink) }
 }
 
 type ConsoleProfileFinishedEvent struct {
@@ -390,14 +534,30 @@ type ConsoleProfileFinishedEvent struct {
 	Title    string    `json:"title"` // Profile title passed as an argument to console.profile().
 }
 
-func OnConsoleProfileFinished(conn *hc.Conn, cb func(evt *ConsoleProfileFinishedEvent)) {
+// Calls cb for each Profiler.consoleProfileFinished event, till the returned func is called.
+func OnConsoleProfileFinished(conn hc.EventSource, cb func(evt *ConsoleProfileFinishedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ConsoleProfileFinishedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Profiler.consoleProfileFinished", sink)
+	return func() { conn.RemoveEventSink("Profiler.consoleProfileFinished", sink) }
+}
+
+// Like OnConsoleProfileFinished, but cb also gets hc.EventMeta.
+func OnConsoleProfileFinishedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ConsoleProfileFinishedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ConsoleProfileFinishedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Profiler.consoleProfileFinished", sink)
+	return func() { conn.RemoveEventSink("Profiler.consoleProfileFinished", sink) }
 }
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -10,7 +11,6 @@ type SetShowPaintRectsParams struct {
 }
 
 // Requests that backend shows paint rectangles
-
 type SetShowPaintRectsCommand struct {
 	params *SetShowPaintRectsParams
 	wg     sync.WaitGroup
@@ -31,32 +31,36 @@ func (cmd *SetShowPaintRectsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetShowPaintRectsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetShowPaintRectsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetShowPaintRects(params *SetShowPaintRectsParams, conn *hc.Conn) (err error) {
+func SetShowPaintRects(params *SetShowPaintRectsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetShowPaintRectsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetShowPaintRectsCB func(err error)
 
 // Requests that backend shows paint rectangles
-
 type AsyncSetShowPaintRectsCommand struct {
 	params *SetShowPaintRectsParams
 	cb     SetShowPaintRectsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetShowPaintRectsCommand(params *SetShowPaintRectsParams, cb SetShowPaintRectsCB) *AsyncSetShowPaintRectsCommand {
 	return &AsyncSetShowPaintRectsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -74,7 +78,28 @@ func (cmd *SetShowPaintRectsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetShowPaintRectsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetShowPaintRectsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetShowPaintRectsCommand) Send(conn hc.CommandRunner) *AsyncSetShowPaintRectsCommand {
+	async := NewAsyncSetShowPaintRectsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetShowDebugBordersParams struct {
@@ -82,7 +107,6 @@ type SetShowDebugBordersParams struct {
 }
 
 // Requests that backend shows debug borders on layers
-
 type SetShowDebugBordersCommand struct {
 	params *SetShowDebugBordersParams
 	wg     sync.WaitGroup
@@ -103,32 +127,36 @@ func (cmd *SetShowDebugBordersCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetShowDebugBordersCommand) Run(conn *hc.Conn) error {
+func (cmd *SetShowDebugBordersCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetShowDebugBorders(params *SetShowDebugBordersParams, conn *hc.Conn) (err error) {
+func SetShowDebugBorders(params *SetShowDebugBordersParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetShowDebugBordersCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetShowDebugBordersCB func(err error)
 
 // Requests that backend shows debug borders on layers
-
 type AsyncSetShowDebugBordersCommand struct {
 	params *SetShowDebugBordersParams
 	cb     SetShowDebugBordersCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetShowDebugBordersCommand(params *SetShowDebugBordersParams, cb SetShowDebugBordersCB) *AsyncSetShowDebugBordersCommand {
 	return &AsyncSetShowDebugBordersCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -146,7 +174,28 @@ func (cmd *SetShowDebugBordersCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetShowDebugBordersCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetShowDebugBordersCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetShowDebugBordersCommand) Send(conn hc.CommandRunner) *AsyncSetShowDebugBordersCommand {
+	async := NewAsyncSetShowDebugBordersCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetShowFPSCounterParams struct {
@@ -154,7 +203,6 @@ type SetShowFPSCounterParams struct {
 }
 
 // Requests that backend shows the FPS counter
-
 type SetShowFPSCounterCommand struct {
 	params *SetShowFPSCounterParams
 	wg     sync.WaitGroup
@@ -175,32 +223,36 @@ func (cmd *SetShowFPSCounterCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetShowFPSCounterCommand) Run(conn *hc.Conn) error {
+func (cmd *SetShowFPSCounterCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetShowFPSCounter(params *SetShowFPSCounterParams, conn *hc.Conn) (err error) {
+func SetShowFPSCounter(params *SetShowFPSCounterParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetShowFPSCounterCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetShowFPSCounterCB func(err error)
 
 // Requests that backend shows the FPS counter
-
 type AsyncSetShowFPSCounterCommand struct {
 	params *SetShowFPSCounterParams
 	cb     SetShowFPSCounterCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetShowFPSCounterCommand(params *SetShowFPSCounterParams, cb SetShowFPSCounterCB) *AsyncSetShowFPSCounterCommand {
 	return &AsyncSetShowFPSCounterCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -218,7 +270,28 @@ func (cmd *SetShowFPSCounterCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetShowFPSCounterCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetShowFPSCounterCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetShowFPSCounterCommand) Send(conn hc.CommandRunner) *AsyncSetShowFPSCounterCommand {
+	async := NewAsyncSetShowFPSCounterCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetShowScrollBottleneckRectsParams struct {
@@ -226,7 +299,6 @@ type SetShowScrollBottleneckRectsParams struct {
 }
 
 // Requests that backend shows scroll bottleneck rects
-
 type SetShowScrollBottleneckRectsCommand struct {
 	params *SetShowScrollBottleneckRectsParams
 	wg     sync.WaitGroup
@@ -247,32 +319,36 @@ func (cmd *SetShowScrollBottleneckRectsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetShowScrollBottleneckRectsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetShowScrollBottleneckRectsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetShowScrollBottleneckRects(params *SetShowScrollBottleneckRectsParams, conn *hc.Conn) (err error) {
+func SetShowScrollBottleneckRects(params *SetShowScrollBottleneckRectsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetShowScrollBottleneckRectsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetShowScrollBottleneckRectsCB func(err error)
 
 // Requests that backend shows scroll bottleneck rects
-
 type AsyncSetShowScrollBottleneckRectsCommand struct {
 	params *SetShowScrollBottleneckRectsParams
 	cb     SetShowScrollBottleneckRectsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetShowScrollBottleneckRectsCommand(params *SetShowScrollBottleneckRectsParams, cb SetShowScrollBottleneckRectsCB) *AsyncSetShowScrollBottleneckRectsCommand {
 	return &AsyncSetShowScrollBottleneckRectsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -290,7 +366,28 @@ func (cmd *SetShowScrollBottleneckRectsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetShowScrollBottleneckRectsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetShowScrollBottleneckRectsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetShowScrollBottleneckRectsCommand) Send(conn hc.CommandRunner) *AsyncSetShowScrollBottleneckRectsCommand {
+	async := NewAsyncSetShowScrollBottleneckRectsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetShowViewportSizeOnResizeParams struct {
@@ -298,7 +395,6 @@ type SetShowViewportSizeOnResizeParams struct {
 }
 
 // Paints viewport size upon main frame resize.
-
 type SetShowViewportSizeOnResizeCommand struct {
 	params *SetShowViewportSizeOnResizeParams
 	wg     sync.WaitGroup
@@ -319,32 +415,36 @@ func (cmd *SetShowViewportSizeOnResizeCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetShowViewportSizeOnResizeCommand) Run(conn *hc.Conn) error {
+func (cmd *SetShowViewportSizeOnResizeCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetShowViewportSizeOnResize(params *SetShowViewportSizeOnResizeParams, conn *hc.Conn) (err error) {
+func SetShowViewportSizeOnResize(params *SetShowViewportSizeOnResizeParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetShowViewportSizeOnResizeCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetShowViewportSizeOnResizeCB func(err error)
 
 // Paints viewport size upon main frame resize.
-
 type AsyncSetShowViewportSizeOnResizeCommand struct {
 	params *SetShowViewportSizeOnResizeParams
 	cb     SetShowViewportSizeOnResizeCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetShowViewportSizeOnResizeCommand(params *SetShowViewportSizeOnResizeParams, cb SetShowViewportSizeOnResizeCB) *AsyncSetShowViewportSizeOnResizeCommand {
 	return &AsyncSetShowViewportSizeOnResizeCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -362,5 +462,26 @@ func (cmd *SetShowViewportSizeOnResizeCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetShowViewportSizeOnResizeCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetShowViewportSizeOnResizeCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetShowViewportSizeOnResizeCommand) Send(conn hc.CommandRunner) *AsyncSetShowViewportSizeOnResizeCommand {
+	async := NewAsyncSetShowViewportSizeOnResizeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -104,7 +104,7 @@ type ExecutionContextDescription struct {
 	Id      ExecutionContextId `json:"id"`                // Unique id of the execution context. It can be used to specify in which execution context script evaluation should be performed.
 	Origin  string             `json:"origin"`            // Execution context origin.
 	Name    string             `json:"name"`              // Human readable name describing given context.
-	AuxData map[string]string  `json:"auxData,omitempty"` // Embedder-specific auxiliary data.
+	AuxData json.RawMessage    `json:"auxData,omitempty"` // Embedder-specific auxiliary data.
 }
 
 // Detailed information about exception (or error) that was thrown during script compilation or execution.
@@ -157,7 +157,6 @@ type EvaluateResult struct {
 }
 
 // Evaluates expression on global object.
-
 type EvaluateCommand struct {
 	params *EvaluateParams
 	result EvaluateResult
@@ -179,32 +178,40 @@ func (cmd *EvaluateCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *EvaluateCommand) Run(conn *hc.Conn) error {
+func (cmd *EvaluateCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Evaluate(params *EvaluateParams, conn *hc.Conn) (result *EvaluateResult, err error) {
+func Evaluate(params *EvaluateParams, conn hc.CommandRunner) (result *EvaluateResult, err error) {
 	cmd := NewEvaluateCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type EvaluateCB func(result *EvaluateResult, err error)
 
 // Evaluates expression on global object.
-
 type AsyncEvaluateCommand struct {
 	params *EvaluateParams
 	cb     EvaluateCB
+	result *EvaluateResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEvaluateCommand(params *EvaluateParams, cb EvaluateCB) *AsyncEvaluateCommand {
 	return &AsyncEvaluateCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -233,15 +240,33 @@ func (cmd *AsyncEvaluateCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEvaluateCommand) Wait(ctx context.Context) (*EvaluateResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EvaluateCommand) Send(conn hc.CommandRunner) *AsyncEvaluateCommand {
+	async := NewAsyncEvaluateCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AwaitPromiseParams struct {
 	PromiseObjectId RemoteObjectId `json:"promiseObjectId"`           // Identifier of the promise.
 	ReturnByValue   bool           `json:"returnByValue,omitempty"`   // Whether the result is expected to be a JSON object that should be sent by value.
@@ -254,7 +279,6 @@ type AwaitPromiseResult struct {
 }
 
 // Add handler to promise with given promise object id.
-
 type AwaitPromiseCommand struct {
 	params *AwaitPromiseParams
 	result AwaitPromiseResult
@@ -276,32 +300,40 @@ func (cmd *AwaitPromiseCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *AwaitPromiseCommand) Run(conn *hc.Conn) error {
+func (cmd *AwaitPromiseCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AwaitPromise(params *AwaitPromiseParams, conn *hc.Conn) (result *AwaitPromiseResult, err error) {
+func AwaitPromise(params *AwaitPromiseParams, conn hc.CommandRunner) (result *AwaitPromiseResult, err error) {
 	cmd := NewAwaitPromiseCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type AwaitPromiseCB func(result *AwaitPromiseResult, err error)
 
 // Add handler to promise with given promise object id.
-
 type AsyncAwaitPromiseCommand struct {
 	params *AwaitPromiseParams
 	cb     AwaitPromiseCB
+	result *AwaitPromiseResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAwaitPromiseCommand(params *AwaitPromiseParams, cb AwaitPromiseCB) *AsyncAwaitPromiseCommand {
 	return &AsyncAwaitPromiseCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -330,15 +362,33 @@ func (cmd *AsyncAwaitPromiseCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAwaitPromiseCommand) Wait(ctx context.Context) (*AwaitPromiseResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AwaitPromiseCommand) Send(conn hc.CommandRunner) *AsyncAwaitPromiseCommand {
+	async := NewAsyncAwaitPromiseCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CallFunctionOnParams struct {
 	ObjectId            RemoteObjectId  `json:"objectId"`                  // Identifier of the object to call function on.
 	FunctionDeclaration string          `json:"functionDeclaration"`       // Declaration of the function to call.
@@ -356,7 +406,6 @@ type CallFunctionOnResult struct {
 }
 
 // Calls function with given declaration on the given object. Object group of the result is inherited from the target object.
-
 type CallFunctionOnCommand struct {
 	params *CallFunctionOnParams
 	result CallFunctionOnResult
@@ -378,32 +427,40 @@ func (cmd *CallFunctionOnCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CallFunctionOnCommand) Run(conn *hc.Conn) error {
+func (cmd *CallFunctionOnCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CallFunctionOn(params *CallFunctionOnParams, conn *hc.Conn) (result *CallFunctionOnResult, err error) {
+func CallFunctionOn(params *CallFunctionOnParams, conn hc.CommandRunner) (result *CallFunctionOnResult, err error) {
 	cmd := NewCallFunctionOnCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CallFunctionOnCB func(result *CallFunctionOnResult, err error)
 
 // Calls function with given declaration on the given object. Object group of the result is inherited from the target object.
-
 type AsyncCallFunctionOnCommand struct {
 	params *CallFunctionOnParams
 	cb     CallFunctionOnCB
+	result *CallFunctionOnResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCallFunctionOnCommand(params *CallFunctionOnParams, cb CallFunctionOnCB) *AsyncCallFunctionOnCommand {
 	return &AsyncCallFunctionOnCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -432,15 +489,33 @@ func (cmd *AsyncCallFunctionOnCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCallFunctionOnCommand) Wait(ctx context.Context) (*CallFunctionOnResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CallFunctionOnCommand) Send(conn hc.CommandRunner) *AsyncCallFunctionOnCommand {
+	async := NewAsyncCallFunctionOnCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetPropertiesParams struct {
 	ObjectId               RemoteObjectId `json:"objectId"`                         // Identifier of the object to return properties for.
 	OwnProperties          bool           `json:"ownProperties,omitempty"`          // If true, returns properties belonging only to the element itself, not to its prototype chain.
@@ -455,7 +530,6 @@ type GetPropertiesResult struct {
 }
 
 // Returns properties of a given object. Object group of the result is inherited from the target object.
-
 type GetPropertiesCommand struct {
 	params *GetPropertiesParams
 	result GetPropertiesResult
@@ -477,32 +551,40 @@ func (cmd *GetPropertiesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetPropertiesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetPropertiesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetProperties(params *GetPropertiesParams, conn *hc.Conn) (result *GetPropertiesResult, err error) {
+func GetProperties(params *GetPropertiesParams, conn hc.CommandRunner) (result *GetPropertiesResult, err error) {
 	cmd := NewGetPropertiesCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetPropertiesCB func(result *GetPropertiesResult, err error)
 
 // Returns properties of a given object. Object group of the result is inherited from the target object.
-
 type AsyncGetPropertiesCommand struct {
 	params *GetPropertiesParams
 	cb     GetPropertiesCB
+	result *GetPropertiesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetPropertiesCommand(params *GetPropertiesParams, cb GetPropertiesCB) *AsyncGetPropertiesCommand {
 	return &AsyncGetPropertiesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -531,21 +613,38 @@ func (cmd *AsyncGetPropertiesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetPropertiesCommand) Wait(ctx context.Context) (*GetPropertiesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetPropertiesCommand) Send(conn hc.CommandRunner) *AsyncGetPropertiesCommand {
+	async := NewAsyncGetPropertiesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ReleaseObjectParams struct {
 	ObjectId RemoteObjectId `json:"objectId"` // Identifier of the object to release.
 }
 
 // Releases remote object with given id.
-
 type ReleaseObjectCommand struct {
 	params *ReleaseObjectParams
 	wg     sync.WaitGroup
@@ -566,32 +665,36 @@ func (cmd *ReleaseObjectCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReleaseObjectCommand) Run(conn *hc.Conn) error {
+func (cmd *ReleaseObjectCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ReleaseObject(params *ReleaseObjectParams, conn *hc.Conn) (err error) {
+func ReleaseObject(params *ReleaseObjectParams, conn hc.CommandRunner) (err error) {
 	cmd := NewReleaseObjectCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ReleaseObjectCB func(err error)
 
 // Releases remote object with given id.
-
 type AsyncReleaseObjectCommand struct {
 	params *ReleaseObjectParams
 	cb     ReleaseObjectCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReleaseObjectCommand(params *ReleaseObjectParams, cb ReleaseObjectCB) *AsyncReleaseObjectCommand {
 	return &AsyncReleaseObjectCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -609,7 +712,28 @@ func (cmd *ReleaseObjectCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncReleaseObjectCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReleaseObjectCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReleaseObjectCommand) Send(conn hc.CommandRunner) *AsyncReleaseObjectCommand {
+	async := NewAsyncReleaseObjectCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ReleaseObjectGroupParams struct {
@@ -617,7 +741,6 @@ type ReleaseObjectGroupParams struct {
 }
 
 // Releases all remote objects that belong to a given group.
-
 type ReleaseObjectGroupCommand struct {
 	params *ReleaseObjectGroupParams
 	wg     sync.WaitGroup
@@ -638,32 +761,36 @@ func (cmd *ReleaseObjectGroupCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ReleaseObjectGroupCommand) Run(conn *hc.Conn) error {
+func (cmd *ReleaseObjectGroupCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ReleaseObjectGroup(params *ReleaseObjectGroupParams, conn *hc.Conn) (err error) {
+func ReleaseObjectGroup(params *ReleaseObjectGroupParams, conn hc.CommandRunner) (err error) {
 	cmd := NewReleaseObjectGroupCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ReleaseObjectGroupCB func(err error)
 
 // Releases all remote objects that belong to a given group.
-
 type AsyncReleaseObjectGroupCommand struct {
 	params *ReleaseObjectGroupParams
 	cb     ReleaseObjectGroupCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncReleaseObjectGroupCommand(params *ReleaseObjectGroupParams, cb ReleaseObjectGroupCB) *AsyncReleaseObjectGroupCommand {
 	return &AsyncReleaseObjectGroupCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -681,11 +808,31 @@ func (cmd *ReleaseObjectGroupCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncReleaseObjectGroupCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Tells inspected instance to run if it was waiting for debugger to attach.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncReleaseObjectGroupCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ReleaseObjectGroupCommand) Send(conn hc.CommandRunner) *AsyncReleaseObjectGroupCommand {
+	async := NewAsyncReleaseObjectGroupCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Tells inspected instance to run if it was waiting for debugger to attach.
 type RunIfWaitingForDebuggerCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -703,30 +850,34 @@ func (cmd *RunIfWaitingForDebuggerCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *RunIfWaitingForDebuggerCommand) Run(conn *hc.Conn) error {
+func (cmd *RunIfWaitingForDebuggerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RunIfWaitingForDebugger(conn *hc.Conn) (err error) {
+func RunIfWaitingForDebugger(conn hc.CommandRunner) (err error) {
 	cmd := NewRunIfWaitingForDebuggerCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RunIfWaitingForDebuggerCB func(err error)
 
 // Tells inspected instance to run if it was waiting for debugger to attach.
-
 type AsyncRunIfWaitingForDebuggerCommand struct {
-	cb RunIfWaitingForDebuggerCB
+	cb   RunIfWaitingForDebuggerCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRunIfWaitingForDebuggerCommand(cb RunIfWaitingForDebuggerCB) *AsyncRunIfWaitingForDebuggerCommand {
 	return &AsyncRunIfWaitingForDebuggerCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -744,11 +895,31 @@ func (cmd *RunIfWaitingForDebuggerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRunIfWaitingForDebuggerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Enables reporting of execution contexts creation by means of executionContextCreated event. When the reporting gets enabled the event will be sent immediately for each existing execution context.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRunIfWaitingForDebuggerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RunIfWaitingForDebuggerCommand) Send(conn hc.CommandRunner) *AsyncRunIfWaitingForDebuggerCommand {
+	async := NewAsyncRunIfWaitingForDebuggerCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Enables reporting of execution contexts creation by means of executionContextCreated event. When the reporting gets enabled the event will be sent immediately for each existing execution context.
 type RuntimeEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -766,30 +937,34 @@ func (cmd *RuntimeEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *RuntimeEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *RuntimeEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RuntimeEnable(conn *hc.Conn) (err error) {
+func RuntimeEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewRuntimeEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RuntimeEnableCB func(err error)
 
 // Enables reporting of execution contexts creation by means of executionContextCreated event. When the reporting gets enabled the event will be sent immediately for each existing execution context.
-
 type AsyncRuntimeEnableCommand struct {
-	cb RuntimeEnableCB
+	cb   RuntimeEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRuntimeEnableCommand(cb RuntimeEnableCB) *AsyncRuntimeEnableCommand {
 	return &AsyncRuntimeEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -807,11 +982,31 @@ func (cmd *RuntimeEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRuntimeEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables reporting of execution contexts creation.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRuntimeEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RuntimeEnableCommand) Send(conn hc.CommandRunner) *AsyncRuntimeEnableCommand {
+	async := NewAsyncRuntimeEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Disables reporting of execution contexts creation.
 type RuntimeDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -829,30 +1024,34 @@ func (cmd *RuntimeDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *RuntimeDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *RuntimeDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RuntimeDisable(conn *hc.Conn) (err error) {
+func RuntimeDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewRuntimeDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RuntimeDisableCB func(err error)
 
 // Disables reporting of execution contexts creation.
-
 type AsyncRuntimeDisableCommand struct {
-	cb RuntimeDisableCB
+	cb   RuntimeDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRuntimeDisableCommand(cb RuntimeDisableCB) *AsyncRuntimeDisableCommand {
 	return &AsyncRuntimeDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -870,11 +1069,31 @@ func (cmd *RuntimeDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRuntimeDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Discards collected exceptions and console API calls.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRuntimeDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RuntimeDisableCommand) Send(conn hc.CommandRunner) *AsyncRuntimeDisableCommand {
+	async := NewAsyncRuntimeDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Discards collected exceptions and console API calls.
 type DiscardConsoleEntriesCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -892,30 +1111,34 @@ func (cmd *DiscardConsoleEntriesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *DiscardConsoleEntriesCommand) Run(conn *hc.Conn) error {
+func (cmd *DiscardConsoleEntriesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DiscardConsoleEntries(conn *hc.Conn) (err error) {
+func DiscardConsoleEntries(conn hc.CommandRunner) (err error) {
 	cmd := NewDiscardConsoleEntriesCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DiscardConsoleEntriesCB func(err error)
 
 // Discards collected exceptions and console API calls.
-
 type AsyncDiscardConsoleEntriesCommand struct {
-	cb DiscardConsoleEntriesCB
+	cb   DiscardConsoleEntriesCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDiscardConsoleEntriesCommand(cb DiscardConsoleEntriesCB) *AsyncDiscardConsoleEntriesCommand {
 	return &AsyncDiscardConsoleEntriesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -933,7 +1156,28 @@ func (cmd *DiscardConsoleEntriesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDiscardConsoleEntriesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDiscardConsoleEntriesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DiscardConsoleEntriesCommand) Send(conn hc.CommandRunner) *AsyncDiscardConsoleEntriesCommand {
+	async := NewAsyncDiscardConsoleEntriesCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetCustomObjectFormatterEnabledParams struct {
@@ -961,17 +1205,18 @@ func (cmd *SetCustomObjectFormatterEnabledCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetCustomObjectFormatterEnabledCommand) Run(conn *hc.Conn) error {
+func (cmd *SetCustomObjectFormatterEnabledCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetCustomObjectFormatterEnabled(params *SetCustomObjectFormatterEnabledParams, conn *hc.Conn) (err error) {
+func SetCustomObjectFormatterEnabled(params *SetCustomObjectFormatterEnabledParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetCustomObjectFormatterEnabledCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetCustomObjectFormatterEnabledCB func(err error)
@@ -980,12 +1225,16 @@ type SetCustomObjectFormatterEnabledCB func(err error)
 type AsyncSetCustomObjectFormatterEnabledCommand struct {
 	params *SetCustomObjectFormatterEnabledParams
 	cb     SetCustomObjectFormatterEnabledCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetCustomObjectFormatterEnabledCommand(params *SetCustomObjectFormatterEnabledParams, cb SetCustomObjectFormatterEnabledCB) *AsyncSetCustomObjectFormatterEnabledCommand {
 	return &AsyncSetCustomObjectFormatterEnabledCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1003,7 +1252,28 @@ func (cmd *SetCustomObjectFormatterEnabledCommand) Done(data []byte, err error)
 }
 
 func (cmd *AsyncSetCustomObjectFormatterEnabledCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetCustomObjectFormatterEnabledCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetCustomObjectFormatterEnabledCommand) Send(conn hc.CommandRunner) *AsyncSetCustomObjectFormatterEnabledCommand {
+	async := NewAsyncSetCustomObjectFormatterEnabledCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CompileScriptParams struct {
@@ -1019,7 +1289,6 @@ type CompileScriptResult struct {
 }
 
 // Compiles expression.
-
 type CompileScriptCommand struct {
 	params *CompileScriptParams
 	result CompileScriptResult
@@ -1041,32 +1310,40 @@ func (cmd *CompileScriptCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CompileScriptCommand) Run(conn *hc.Conn) error {
+func (cmd *CompileScriptCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CompileScript(params *CompileScriptParams, conn *hc.Conn) (result *CompileScriptResult, err error) {
+func CompileScript(params *CompileScriptParams, conn hc.CommandRunner) (result *CompileScriptResult, err error) {
 	cmd := NewCompileScriptCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CompileScriptCB func(result *CompileScriptResult, err error)
 
 // Compiles expression.
-
 type AsyncCompileScriptCommand struct {
 	params *CompileScriptParams
 	cb     CompileScriptCB
+	result *CompileScriptResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCompileScriptCommand(params *CompileScriptParams, cb CompileScriptCB) *AsyncCompileScriptCommand {
 	return &AsyncCompileScriptCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1095,15 +1372,33 @@ func (cmd *AsyncCompileScriptCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCompileScriptCommand) Wait(ctx context.Context) (*CompileScriptResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CompileScriptCommand) Send(conn hc.CommandRunner) *AsyncCompileScriptCommand {
+	async := NewAsyncCompileScriptCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RunScriptParams struct {
 	ScriptId              ScriptId           `json:"scriptId"`                        // Id of the script to run.
 	ExecutionContextId    ExecutionContextId `json:"executionContextId,omitempty"`    // Specifies in which execution context to perform script run. If the parameter is omitted the evaluation will be performed in the context of the inspected page.
@@ -1121,7 +1416,6 @@ type RunScriptResult struct {
 }
 
 // Runs script with given id in a given context.
-
 type RunScriptCommand struct {
 	params *RunScriptParams
 	result RunScriptResult
@@ -1143,32 +1437,40 @@ func (cmd *RunScriptCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RunScriptCommand) Run(conn *hc.Conn) error {
+func (cmd *RunScriptCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RunScript(params *RunScriptParams, conn *hc.Conn) (result *RunScriptResult, err error) {
+func RunScript(params *RunScriptParams, conn hc.CommandRunner) (result *RunScriptResult, err error) {
 	cmd := NewRunScriptCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RunScriptCB func(result *RunScriptResult, err error)
 
 // Runs script with given id in a given context.
-
 type AsyncRunScriptCommand struct {
 	params *RunScriptParams
 	cb     RunScriptCB
+	result *RunScriptResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRunScriptCommand(params *RunScriptParams, cb RunScriptCB) *AsyncRunScriptCommand {
 	return &AsyncRunScriptCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1197,108 +1499,200 @@ func (cmd *AsyncRunScriptCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Issued when new execution context is created.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRunScriptCommand) Wait(ctx context.Context) (*RunScriptResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RunScriptCommand) Send(conn hc.CommandRunner) *AsyncRunScriptCommand {
+	async := NewAsyncRunScriptCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Issued when new execution context is created.
 type ExecutionContextCreatedEvent struct {
 	Context *ExecutionContextDescription `json:"context"` // A newly created execution contex.
 }
 
-func OnExecutionContextCreated(conn *hc.Conn, cb func(evt *ExecutionContextCreatedEvent)) {
+// Calls cb for each Runtime.executionContextCreated event, till the returned func is called.
+func OnExecutionContextCreated(conn hc.EventSource, cb func(evt *ExecutionContextCreatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ExecutionContextCreatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.executionContextCreated", sink)
+	return func() { conn.RemoveEventSink("Runtime.executionContextCreated", sink) }
 }
 
-// Issued when execution context is destroyed.
+// Like OnExecutionContextCreated, but cb also gets hc.EventMeta.
+func OnExecutionContextCreatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ExecutionContextCreatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ExecutionContextCreatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.executionContextCreated", sink)
+	return func() { conn.RemoveEventSink("Runtime.executionContextCreated", sink) }
+}
 
+// Issued when execution context is destroyed.
 type ExecutionContextDestroyedEvent struct {
 	ExecutionContextId ExecutionContextId `json:"executionContextId"` // Id of the destroyed context
 }
 
-func OnExecutionContextDestroyed(conn *hc.Conn, cb func(evt *ExecutionContextDestroyedEvent)) {
+// Calls cb for each Runtime.executionContextDestroyed event, till the returned func is called.
+func OnExecutionContextDestroyed(conn hc.EventSource, cb func(evt *ExecutionContextDestroyedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ExecutionContextDestroyedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.executionContextDestroyed", sink)
+	return func() { conn.RemoveEventSink("Runtime.executionContextDestroyed", sink) }
 }
 
-// Issued when all executionContexts were cleared in browser
+// Like OnExecutionContextDestroyed, but cb also gets hc.EventMeta.
+func OnExecutionContextDestroyedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ExecutionContextDestroyedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ExecutionContextDestroyedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.executionContextDestroyed", sink)
+	return func() { conn.RemoveEventSink("Runtime.executionContextDestroyed", sink) }
+}
 
+// Issued when all executionContexts were cleared in browser
 type ExecutionContextsClearedEvent struct {
 }
 
-func OnExecutionContextsCleared(conn *hc.Conn, cb func(evt *ExecutionContextsClearedEvent)) {
+// Calls cb for each Runtime.executionContextsCleared event, till the returned func is called.
+func OnExecutionContextsCleared(conn hc.EventSource, cb func(evt *ExecutionContextsClearedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ExecutionContextsClearedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.executionContextsCleared", sink)
+	return func() { conn.RemoveEventSink("Runtime.executionContextsCleared", sink) }
 }
 
-// Issued when exception was thrown and unhandled.
+// Like OnExecutionContextsCleared, but cb also gets hc.EventMeta.
+func OnExecutionContextsClearedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ExecutionContextsClearedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ExecutionContextsClearedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.executionContextsCleared", sink)
+	return func() { conn.RemoveEventSink("Runtime.executionContextsCleared", sink) }
+}
 
+// Issued when exception was thrown and unhandled.
 type ExceptionThrownEvent struct {
 	Timestamp        RuntimeTimestamp  `json:"timestamp"` // Timestamp of the exception.
 	ExceptionDetails *ExceptionDetails `json:"exceptionDetails"`
 }
 
-func OnExceptionThrown(conn *hc.Conn, cb func(evt *ExceptionThrownEvent)) {
+// Calls cb for each Runtime.exceptionThrown event, till the returned func is called.
+func OnExceptionThrown(conn hc.EventSource, cb func(evt *ExceptionThrownEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ExceptionThrownEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.exceptionThrown", sink)
+	return func() { conn.RemoveEventSink("Runtime.exceptionThrown", sink) }
 }
 
-// Issued when unhandled exception was revoked.
+// Like OnExceptionThrown, but cb also gets hc.EventMeta.
+func OnExceptionThrownMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ExceptionThrownEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ExceptionThrownEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.exceptionThrown", sink)
+	return func() { conn.RemoveEventSink("Runtime.exceptionThrown", sink) }
+}
 
+// Issued when unhandled exception was revoked.
 type ExceptionRevokedEvent struct {
 	Reason      string `json:"reason"`      // Reason describing why exception was revoked.
 	ExceptionId int    `json:"exceptionId"` // The id of revoked exception, as reported in exceptionUnhandled.
 }
 
-func OnExceptionRevoked(conn *hc.Conn, cb func(evt *ExceptionRevokedEvent)) {
+// Calls cb for each Runtime.exceptionRevoked event, till the returned func is called.
+func OnExceptionRevoked(conn hc.EventSource, cb func(evt *ExceptionRevokedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ExceptionRevokedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.exceptionRevoked", sink)
+	return func() { conn.RemoveEventSink("Runtime.exceptionRevoked", sink) }
 }
 
-// Issued when console API was called.
+// Like OnExceptionRevoked, but cb also gets hc.EventMeta.
+func OnExceptionRevokedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ExceptionRevokedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ExceptionRevokedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.exceptionRevoked", sink)
+	return func() { conn.RemoveEventSink("Runtime.exceptionRevoked", sink) }
+}
 
+// Issued when console API was called.
 type ConsoleAPICalledEvent struct {
 	Type               string             `json:"type"`               // Type of the call.
 	Args               []*RemoteObject    `json:"args"`               // Call arguments.
@@ -1307,33 +1701,64 @@ type ConsoleAPICalledEvent struct {
 	StackTrace         *StackTrace        `json:"stackTrace"`         // Stack trace captured when the call was made.
 }
 
-func OnConsoleAPICalled(conn *hc.Conn, cb func(evt *ConsoleAPICalledEvent)) {
+// Calls cb for each Runtime.consoleAPICalled event, till the returned func is called.
+func OnConsoleAPICalled(conn hc.EventSource, cb func(evt *ConsoleAPICalledEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ConsoleAPICalledEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.consoleAPICalled", sink)
+	return func() { conn.RemoveEventSink("Runtime.consoleAPICalled", sink) }
 }
 
-// Issued when object should be inspected (for example, as a result of inspect() command line API call).
+// Like OnConsoleAPICalled, but cb also gets hc.EventMeta.
+func OnConsoleAPICalledMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ConsoleAPICalledEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ConsoleAPICalledEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.consoleAPICalled", sink)
+	return func() { conn.RemoveEventSink("Runtime.consoleAPICalled", sink) }
+}
 
+// Issued when object should be inspected (for example, as a result of inspect() command line API call).
 type InspectRequestedEvent struct {
-	Object *RemoteObject     `json:"object"`
-	Hints  map[string]string `json:"hints"`
+	Object *RemoteObject   `json:"object"`
+	Hints  json.RawMessage `json:"hints"`
 }
 
-func OnInspectRequested(conn *hc.Conn, cb func(evt *InspectRequestedEvent)) {
+// Calls cb for each Runtime.inspectRequested event, till the returned func is called.
+func OnInspectRequested(conn hc.EventSource, cb func(evt *InspectRequestedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &InspectRequestedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Runtime.inspectRequested", sink)
+	return func() { conn.RemoveEventSink("Runtime.inspectRequested", sink) }
+}
+
+// Like OnInspectRequested, but cb also gets hc.EventMeta.
+func OnInspectRequestedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *InspectRequestedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &InspectRequestedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Runtime.inspectRequested", sink)
+	return func() { conn.RemoveEventSink("Runtime.inspectRequested", sink) }
 }
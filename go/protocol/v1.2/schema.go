@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -18,7 +18,6 @@ type GetDomainsResult struct {
 }
 
 // Returns supported domains.
-
 type GetDomainsCommand struct {
 	result GetDomainsResult
 	wg     sync.WaitGroup
@@ -37,30 +36,38 @@ func (cmd *GetDomainsCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetDomainsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetDomainsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetDomains(conn *hc.Conn) (result *GetDomainsResult, err error) {
+func GetDomains(conn hc.CommandRunner) (result *GetDomainsResult, err error) {
 	cmd := NewGetDomainsCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetDomainsCB func(result *GetDomainsResult, err error)
 
 // Returns supported domains.
-
 type AsyncGetDomainsCommand struct {
-	cb GetDomainsCB
+	cb     GetDomainsCB
+	result *GetDomainsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetDomainsCommand(cb GetDomainsCB) *AsyncGetDomainsCommand {
 	return &AsyncGetDomainsCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -89,11 +96,29 @@ func (cmd *AsyncGetDomainsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetDomainsCommand) Wait(ctx context.Context) (*GetDomainsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetDomainsCommand) Send(conn hc.CommandRunner) *AsyncGetDomainsCommand {
+	async := NewAsyncGetDomainsCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
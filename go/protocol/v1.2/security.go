@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -39,7 +39,6 @@ type InsecureContentStatus struct {
 }
 
 // Enables tracking security state changes.
-
 type SecurityEnableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -57,30 +56,34 @@ func (cmd *SecurityEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *SecurityEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *SecurityEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SecurityEnable(conn *hc.Conn) (err error) {
+func SecurityEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewSecurityEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SecurityEnableCB func(err error)
 
 // Enables tracking security state changes.
-
 type AsyncSecurityEnableCommand struct {
-	cb SecurityEnableCB
+	cb   SecurityEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSecurityEnableCommand(cb SecurityEnableCB) *AsyncSecurityEnableCommand {
 	return &AsyncSecurityEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -98,11 +101,31 @@ func (cmd *SecurityEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSecurityEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Disables tracking security state changes.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSecurityEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SecurityEnableCommand) Send(conn hc.CommandRunner) *AsyncSecurityEnableCommand {
+	async := NewAsyncSecurityEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Disables tracking security state changes.
 type SecurityDisableCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -120,30 +143,34 @@ func (cmd *SecurityDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *SecurityDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *SecurityDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SecurityDisable(conn *hc.Conn) (err error) {
+func SecurityDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewSecurityDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SecurityDisableCB func(err error)
 
 // Disables tracking security state changes.
-
 type AsyncSecurityDisableCommand struct {
-	cb SecurityDisableCB
+	cb   SecurityDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSecurityDisableCommand(cb SecurityDisableCB) *AsyncSecurityDisableCommand {
 	return &AsyncSecurityDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -161,11 +188,31 @@ func (cmd *SecurityDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSecurityDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Displays native dialog with the certificate details.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSecurityDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SecurityDisableCommand) Send(conn hc.CommandRunner) *AsyncSecurityDisableCommand {
+	async := NewAsyncSecurityDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Displays native dialog with the certificate details.
 type ShowCertificateViewerCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -183,30 +230,34 @@ func (cmd *ShowCertificateViewerCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ShowCertificateViewerCommand) Run(conn *hc.Conn) error {
+func (cmd *ShowCertificateViewerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ShowCertificateViewer(conn *hc.Conn) (err error) {
+func ShowCertificateViewer(conn hc.CommandRunner) (err error) {
 	cmd := NewShowCertificateViewerCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ShowCertificateViewerCB func(err error)
 
 // Displays native dialog with the certificate details.
-
 type AsyncShowCertificateViewerCommand struct {
-	cb ShowCertificateViewerCB
+	cb   ShowCertificateViewerCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncShowCertificateViewerCommand(cb ShowCertificateViewerCB) *AsyncShowCertificateViewerCommand {
 	return &AsyncShowCertificateViewerCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -224,11 +275,31 @@ func (cmd *ShowCertificateViewerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncShowCertificateViewerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// The security state of the page changed.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncShowCertificateViewerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ShowCertificateViewerCommand) Send(conn hc.CommandRunner) *AsyncShowCertificateViewerCommand {
+	async := NewAsyncShowCertificateViewerCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// The security state of the page changed.
 type SecurityStateChangedEvent struct {
 	SecurityState         SecurityState               `json:"securityState"`         // Security state.
 	SchemeIsCryptographic bool                        `json:"schemeIsCryptographic"` // True if the page was loaded over cryptographic transport such as HTTPS.
@@ -237,14 +308,30 @@ type SecurityStateChangedEvent struct {
 	Summary               string                      `json:"summary"`               // Overrides user-visible description of the state.
 }
 
-func OnSecurityStateChanged(conn *hc.Conn, cb func(evt *SecurityStateChangedEvent)) {
+// Calls cb for each Security.securityStateChanged event, till the returned func is called.
+func OnSecurityStateChanged(conn hc.EventSource, cb func(evt *SecurityStateChangedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &SecurityStateChangedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Security.securityStateChanged", sink)
+	return func() { conn.RemoveEventSink("Security.securityStateChanged", sink) }
+}
+
+// Like OnSecurityStateChanged, but cb also gets hc.EventMeta.
+func OnSecurityStateChangedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *SecurityStateChangedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &SecurityStateChangedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Security.securityStateChanged", sink)
+	return func() { conn.RemoveEventSink("Security.securityStateChanged", sink) }
 }
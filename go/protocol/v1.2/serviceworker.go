@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -39,8 +39,8 @@ type ServiceWorkerVersion struct {
 	Status             ServiceWorkerVersionStatus        `json:"status"`
 	ScriptLastModified float64                           `json:"scriptLastModified,omitempty"` // The Last-Modified header value of the main script.
 	ScriptResponseTime float64                           `json:"scriptResponseTime,omitempty"` // The time at which the response headers of the main script were received from the server.  For cached script it is the last time the cache entry was validated.
-	ControlledClients  []*TargetID                       `json:"controlledClients,omitempty"`
-	TargetId           *TargetID                         `json:"targetId,omitempty"`
+	ControlledClients  []TargetID                        `json:"controlledClients,omitempty"`
+	TargetId           TargetID                          `json:"targetId,omitempty"`
 }
 
 // ServiceWorker error message.
@@ -70,28 +70,33 @@ func (cmd *ServiceWorkerEnableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ServiceWorkerEnableCommand) Run(conn *hc.Conn) error {
+func (cmd *ServiceWorkerEnableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ServiceWorkerEnable(conn *hc.Conn) (err error) {
+func ServiceWorkerEnable(conn hc.CommandRunner) (err error) {
 	cmd := NewServiceWorkerEnableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ServiceWorkerEnableCB func(err error)
 
 type AsyncServiceWorkerEnableCommand struct {
-	cb ServiceWorkerEnableCB
+	cb   ServiceWorkerEnableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncServiceWorkerEnableCommand(cb ServiceWorkerEnableCB) *AsyncServiceWorkerEnableCommand {
 	return &AsyncServiceWorkerEnableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -109,7 +114,28 @@ func (cmd *ServiceWorkerEnableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncServiceWorkerEnableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncServiceWorkerEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ServiceWorkerEnableCommand) Send(conn hc.CommandRunner) *AsyncServiceWorkerEnableCommand {
+	async := NewAsyncServiceWorkerEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type ServiceWorkerDisableCommand struct {
@@ -129,28 +155,33 @@ func (cmd *ServiceWorkerDisableCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *ServiceWorkerDisableCommand) Run(conn *hc.Conn) error {
+func (cmd *ServiceWorkerDisableCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ServiceWorkerDisable(conn *hc.Conn) (err error) {
+func ServiceWorkerDisable(conn hc.CommandRunner) (err error) {
 	cmd := NewServiceWorkerDisableCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ServiceWorkerDisableCB func(err error)
 
 type AsyncServiceWorkerDisableCommand struct {
-	cb ServiceWorkerDisableCB
+	cb   ServiceWorkerDisableCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncServiceWorkerDisableCommand(cb ServiceWorkerDisableCB) *AsyncServiceWorkerDisableCommand {
 	return &AsyncServiceWorkerDisableCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -168,7 +199,28 @@ func (cmd *ServiceWorkerDisableCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncServiceWorkerDisableCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncServiceWorkerDisableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ServiceWorkerDisableCommand) Send(conn hc.CommandRunner) *AsyncServiceWorkerDisableCommand {
+	async := NewAsyncServiceWorkerDisableCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type UnregisterParams struct {
@@ -195,17 +247,18 @@ func (cmd *UnregisterCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *UnregisterCommand) Run(conn *hc.Conn) error {
+func (cmd *UnregisterCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Unregister(params *UnregisterParams, conn *hc.Conn) (err error) {
+func Unregister(params *UnregisterParams, conn hc.CommandRunner) (err error) {
 	cmd := NewUnregisterCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type UnregisterCB func(err error)
@@ -213,12 +266,16 @@ type UnregisterCB func(err error)
 type AsyncUnregisterCommand struct {
 	params *UnregisterParams
 	cb     UnregisterCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncUnregisterCommand(params *UnregisterParams, cb UnregisterCB) *AsyncUnregisterCommand {
 	return &AsyncUnregisterCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -236,7 +293,28 @@ func (cmd *UnregisterCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncUnregisterCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncUnregisterCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *UnregisterCommand) Send(conn hc.CommandRunner) *AsyncUnregisterCommand {
+	async := NewAsyncUnregisterCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type UpdateRegistrationParams struct {
@@ -263,17 +341,18 @@ func (cmd *UpdateRegistrationCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *UpdateRegistrationCommand) Run(conn *hc.Conn) error {
+func (cmd *UpdateRegistrationCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func UpdateRegistration(params *UpdateRegistrationParams, conn *hc.Conn) (err error) {
+func UpdateRegistration(params *UpdateRegistrationParams, conn hc.CommandRunner) (err error) {
 	cmd := NewUpdateRegistrationCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type UpdateRegistrationCB func(err error)
@@ -281,12 +360,16 @@ type UpdateRegistrationCB func(err error)
 type AsyncUpdateRegistrationCommand struct {
 	params *UpdateRegistrationParams
 	cb     UpdateRegistrationCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncUpdateRegistrationCommand(params *UpdateRegistrationParams, cb UpdateRegistrationCB) *AsyncUpdateRegistrationCommand {
 	return &AsyncUpdateRegistrationCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -304,7 +387,28 @@ func (cmd *UpdateRegistrationCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncUpdateRegistrationCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncUpdateRegistrationCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *UpdateRegistrationCommand) Send(conn hc.CommandRunner) *AsyncUpdateRegistrationCommand {
+	async := NewAsyncUpdateRegistrationCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StartWorkerParams struct {
@@ -331,17 +435,18 @@ func (cmd *StartWorkerCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StartWorkerCommand) Run(conn *hc.Conn) error {
+func (cmd *StartWorkerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StartWorker(params *StartWorkerParams, conn *hc.Conn) (err error) {
+func StartWorker(params *StartWorkerParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStartWorkerCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StartWorkerCB func(err error)
@@ -349,12 +454,16 @@ type StartWorkerCB func(err error)
 type AsyncStartWorkerCommand struct {
 	params *StartWorkerParams
 	cb     StartWorkerCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStartWorkerCommand(params *StartWorkerParams, cb StartWorkerCB) *AsyncStartWorkerCommand {
 	return &AsyncStartWorkerCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -372,7 +481,28 @@ func (cmd *StartWorkerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStartWorkerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStartWorkerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StartWorkerCommand) Send(conn hc.CommandRunner) *AsyncStartWorkerCommand {
+	async := NewAsyncStartWorkerCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SkipWaitingParams struct {
@@ -399,17 +529,18 @@ func (cmd *SkipWaitingCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SkipWaitingCommand) Run(conn *hc.Conn) error {
+func (cmd *SkipWaitingCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SkipWaiting(params *SkipWaitingParams, conn *hc.Conn) (err error) {
+func SkipWaiting(params *SkipWaitingParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSkipWaitingCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SkipWaitingCB func(err error)
@@ -417,12 +548,16 @@ type SkipWaitingCB func(err error)
 type AsyncSkipWaitingCommand struct {
 	params *SkipWaitingParams
 	cb     SkipWaitingCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSkipWaitingCommand(params *SkipWaitingParams, cb SkipWaitingCB) *AsyncSkipWaitingCommand {
 	return &AsyncSkipWaitingCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -440,7 +575,28 @@ func (cmd *SkipWaitingCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSkipWaitingCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSkipWaitingCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SkipWaitingCommand) Send(conn hc.CommandRunner) *AsyncSkipWaitingCommand {
+	async := NewAsyncSkipWaitingCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type StopWorkerParams struct {
@@ -467,17 +623,18 @@ func (cmd *StopWorkerCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *StopWorkerCommand) Run(conn *hc.Conn) error {
+func (cmd *StopWorkerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func StopWorker(params *StopWorkerParams, conn *hc.Conn) (err error) {
+func StopWorker(params *StopWorkerParams, conn hc.CommandRunner) (err error) {
 	cmd := NewStopWorkerCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type StopWorkerCB func(err error)
@@ -485,12 +642,16 @@ type StopWorkerCB func(err error)
 type AsyncStopWorkerCommand struct {
 	params *StopWorkerParams
 	cb     StopWorkerCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncStopWorkerCommand(params *StopWorkerParams, cb StopWorkerCB) *AsyncStopWorkerCommand {
 	return &AsyncStopWorkerCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -508,7 +669,28 @@ func (cmd *StopWorkerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncStopWorkerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncStopWorkerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *StopWorkerCommand) Send(conn hc.CommandRunner) *AsyncStopWorkerCommand {
+	async := NewAsyncStopWorkerCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type InspectWorkerParams struct {
@@ -535,17 +717,18 @@ func (cmd *InspectWorkerCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *InspectWorkerCommand) Run(conn *hc.Conn) error {
+func (cmd *InspectWorkerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func InspectWorker(params *InspectWorkerParams, conn *hc.Conn) (err error) {
+func InspectWorker(params *InspectWorkerParams, conn hc.CommandRunner) (err error) {
 	cmd := NewInspectWorkerCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type InspectWorkerCB func(err error)
@@ -553,12 +736,16 @@ type InspectWorkerCB func(err error)
 type AsyncInspectWorkerCommand struct {
 	params *InspectWorkerParams
 	cb     InspectWorkerCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncInspectWorkerCommand(params *InspectWorkerParams, cb InspectWorkerCB) *AsyncInspectWorkerCommand {
 	return &AsyncInspectWorkerCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -576,7 +763,28 @@ func (cmd *InspectWorkerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncInspectWorkerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncInspectWorkerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *InspectWorkerCommand) Send(conn hc.CommandRunner) *AsyncInspectWorkerCommand {
+	async := NewAsyncInspectWorkerCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetForceUpdateOnPageLoadParams struct {
@@ -603,17 +811,18 @@ func (cmd *SetForceUpdateOnPageLoadCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetForceUpdateOnPageLoadCommand) Run(conn *hc.Conn) error {
+func (cmd *SetForceUpdateOnPageLoadCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetForceUpdateOnPageLoad(params *SetForceUpdateOnPageLoadParams, conn *hc.Conn) (err error) {
+func SetForceUpdateOnPageLoad(params *SetForceUpdateOnPageLoadParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetForceUpdateOnPageLoadCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetForceUpdateOnPageLoadCB func(err error)
@@ -621,12 +830,16 @@ type SetForceUpdateOnPageLoadCB func(err error)
 type AsyncSetForceUpdateOnPageLoadCommand struct {
 	params *SetForceUpdateOnPageLoadParams
 	cb     SetForceUpdateOnPageLoadCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetForceUpdateOnPageLoadCommand(params *SetForceUpdateOnPageLoadParams, cb SetForceUpdateOnPageLoadCB) *AsyncSetForceUpdateOnPageLoadCommand {
 	return &AsyncSetForceUpdateOnPageLoadCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -644,7 +857,28 @@ func (cmd *SetForceUpdateOnPageLoadCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetForceUpdateOnPageLoadCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetForceUpdateOnPageLoadCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetForceUpdateOnPageLoadCommand) Send(conn hc.CommandRunner) *AsyncSetForceUpdateOnPageLoadCommand {
+	async := NewAsyncSetForceUpdateOnPageLoadCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DeliverPushMessageParams struct {
@@ -673,17 +907,18 @@ func (cmd *DeliverPushMessageCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DeliverPushMessageCommand) Run(conn *hc.Conn) error {
+func (cmd *DeliverPushMessageCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DeliverPushMessage(params *DeliverPushMessageParams, conn *hc.Conn) (err error) {
+func DeliverPushMessage(params *DeliverPushMessageParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDeliverPushMessageCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DeliverPushMessageCB func(err error)
@@ -691,12 +926,16 @@ type DeliverPushMessageCB func(err error)
 type AsyncDeliverPushMessageCommand struct {
 	params *DeliverPushMessageParams
 	cb     DeliverPushMessageCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDeliverPushMessageCommand(params *DeliverPushMessageParams, cb DeliverPushMessageCB) *AsyncDeliverPushMessageCommand {
 	return &AsyncDeliverPushMessageCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -714,7 +953,28 @@ func (cmd *DeliverPushMessageCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDeliverPushMessageCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDeliverPushMessageCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DeliverPushMessageCommand) Send(conn hc.CommandRunner) *AsyncDeliverPushMessageCommand {
+	async := NewAsyncDeliverPushMessageCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DispatchSyncEventParams struct {
@@ -744,17 +1004,18 @@ func (cmd *DispatchSyncEventCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DispatchSyncEventCommand) Run(conn *hc.Conn) error {
+func (cmd *DispatchSyncEventCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DispatchSyncEvent(params *DispatchSyncEventParams, conn *hc.Conn) (err error) {
+func DispatchSyncEvent(params *DispatchSyncEventParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDispatchSyncEventCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DispatchSyncEventCB func(err error)
@@ -762,12 +1023,16 @@ type DispatchSyncEventCB func(err error)
 type AsyncDispatchSyncEventCommand struct {
 	params *DispatchSyncEventParams
 	cb     DispatchSyncEventCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDispatchSyncEventCommand(params *DispatchSyncEventParams, cb DispatchSyncEventCB) *AsyncDispatchSyncEventCommand {
 	return &AsyncDispatchSyncEventCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -785,53 +1050,122 @@ func (cmd *DispatchSyncEventCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDispatchSyncEventCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDispatchSyncEventCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DispatchSyncEventCommand) Send(conn hc.CommandRunner) *AsyncDispatchSyncEventCommand {
+	async := NewAsyncDispatchSyncEventCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type WorkerRegistrationUpdatedEvent struct {
 	Registrations []*ServiceWorkerRegistration `json:"registrations"`
 }
 
-func OnWorkerRegistrationUpdated(conn *hc.Conn, cb func(evt *WorkerRegistrationUpdatedEvent)) {
+// Calls cb for each ServiceWorker.workerRegistrationUpdated event, till the returned func is called.
+func OnWorkerRegistrationUpdated(conn hc.EventSource, cb func(evt *WorkerRegistrationUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WorkerRegistrationUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("ServiceWorker.workerRegistrationUpdated", sink)
+	return func() { conn.RemoveEventSink("ServiceWorker.workerRegistrationUpdated", sink) }
+}
+
+// Like OnWorkerRegistrationUpdated, but cb also gets hc.EventMeta.
+func OnWorkerRegistrationUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WorkerRegistrationUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WorkerRegistrationUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("ServiceWorker.workerRegistrationUpdated", sink)
+	return func() { conn.RemoveEventSink("ServiceWorker.workerRegistrationUpdated", sink) }
 }
 
 type WorkerVersionUpdatedEvent struct {
 	Versions []*ServiceWorkerVersion `json:"versions"`
 }
 
-func OnWorkerVersionUpdated(conn *hc.Conn, cb func(evt *WorkerVersionUpdatedEvent)) {
+// Calls cb for each ServiceWorker.workerVersionUpdated event, till the returned func is called.
+func OnWorkerVersionUpdated(conn hc.EventSource, cb func(evt *WorkerVersionUpdatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WorkerVersionUpdatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("ServiceWorker.workerVersionUpdated", sink)
+	return func() { conn.RemoveEventSink("ServiceWorker.workerVersionUpdated", sink) }
+}
+
+// Like OnWorkerVersionUpdated, but cb also gets hc.EventMeta.
+func OnWorkerVersionUpdatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WorkerVersionUpdatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WorkerVersionUpdatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("ServiceWorker.workerVersionUpdated", sink)
+	return func() { conn.RemoveEventSink("ServiceWorker.workerVersionUpdated", sink) }
 }
 
 type WorkerErrorReportedEvent struct {
 	ErrorMessage *ServiceWorkerErrorMessage `json:"errorMessage"`
 }
 
-func OnWorkerErrorReported(conn *hc.Conn, cb func(evt *WorkerErrorReportedEvent)) {
+// Calls cb for each ServiceWorker.workerErrorReported event, till the returned func is called.
+func OnWorkerErrorReported(conn hc.EventSource, cb func(evt *WorkerErrorReportedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &WorkerErrorReportedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("ServiceWorker.workerErrorReported", sink)
+	return func() { conn.RemoveEventSink("ServiceWorker.workerErrorReported", sink) }
+}
+
+// Like OnWorkerErrorReported, but cb also gets hc.EventMeta.
+func OnWorkerErrorReportedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *WorkerErrorReportedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &WorkerErrorReportedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("ServiceWorker.workerErrorReported", sink)
+	return func() { conn.RemoveEventSink("ServiceWorker.workerErrorReported", sink) }
 }
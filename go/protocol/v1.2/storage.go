@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -25,7 +26,6 @@ type ClearDataForOriginParams struct {
 }
 
 // Clears storage for origin.
-
 type ClearDataForOriginCommand struct {
 	params *ClearDataForOriginParams
 	wg     sync.WaitGroup
@@ -46,32 +46,36 @@ func (cmd *ClearDataForOriginCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ClearDataForOriginCommand) Run(conn *hc.Conn) error {
+func (cmd *ClearDataForOriginCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ClearDataForOrigin(params *ClearDataForOriginParams, conn *hc.Conn) (err error) {
+func ClearDataForOrigin(params *ClearDataForOriginParams, conn hc.CommandRunner) (err error) {
 	cmd := NewClearDataForOriginCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ClearDataForOriginCB func(err error)
 
 // Clears storage for origin.
-
 type AsyncClearDataForOriginCommand struct {
 	params *ClearDataForOriginParams
 	cb     ClearDataForOriginCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncClearDataForOriginCommand(params *ClearDataForOriginParams, cb ClearDataForOriginCB) *AsyncClearDataForOriginCommand {
 	return &AsyncClearDataForOriginCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -89,5 +93,26 @@ func (cmd *ClearDataForOriginCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncClearDataForOriginCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearDataForOriginCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearDataForOriginCommand) Send(conn hc.CommandRunner) *AsyncClearDataForOriginCommand {
+	async := NewAsyncClearDataForOriginCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -18,7 +18,7 @@ type GPUDevice struct {
 // Provides information about the GPU(s) on the system.
 type GPUInfo struct {
 	Devices              []*GPUDevice      `json:"devices"`                 // The graphics devices on the system. Element 0 is the primary GPU.
-	AuxAttributes        map[string]string `json:"auxAttributes,omitempty"` // An optional dictionary of additional GPU related attributes.
+	AuxAttributes        json.RawMessage   `json:"auxAttributes,omitempty"` // An optional dictionary of additional GPU related attributes.
 	FeatureStatus        map[string]string `json:"featureStatus,omitempty"` // An optional dictionary of graphics features and their status.
 	DriverBugWorkarounds []string          `json:"driverBugWorkarounds"`    // An optional array of GPU driver bug workarounds.
 }
@@ -30,7 +30,6 @@ type GetInfoResult struct {
 }
 
 // Returns information about the system.
-
 type GetInfoCommand struct {
 	result GetInfoResult
 	wg     sync.WaitGroup
@@ -49,30 +48,38 @@ func (cmd *GetInfoCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetInfoCommand) Run(conn *hc.Conn) error {
+func (cmd *GetInfoCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetInfo(conn *hc.Conn) (result *GetInfoResult, err error) {
+func GetInfo(conn hc.CommandRunner) (result *GetInfoResult, err error) {
 	cmd := NewGetInfoCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetInfoCB func(result *GetInfoResult, err error)
 
 // Returns information about the system.
-
 type AsyncGetInfoCommand struct {
-	cb GetInfoCB
+	cb     GetInfoCB
+	result *GetInfoResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetInfoCommand(cb GetInfoCB) *AsyncGetInfoCommand {
 	return &AsyncGetInfoCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -101,11 +108,29 @@ func (cmd *AsyncGetInfoCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetInfoCommand) Wait(ctx context.Context) (*GetInfoResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetInfoCommand) Send(conn hc.CommandRunner) *AsyncGetInfoCommand {
+	async := NewAsyncGetInfoCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -28,7 +28,6 @@ type SetDiscoverTargetsParams struct {
 }
 
 // Controls whether to discover available targets and notify via targetCreated/targetDestroyed events.
-
 type SetDiscoverTargetsCommand struct {
 	params *SetDiscoverTargetsParams
 	wg     sync.WaitGroup
@@ -49,32 +48,36 @@ func (cmd *SetDiscoverTargetsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetDiscoverTargetsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetDiscoverTargetsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetDiscoverTargets(params *SetDiscoverTargetsParams, conn *hc.Conn) (err error) {
+func SetDiscoverTargets(params *SetDiscoverTargetsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetDiscoverTargetsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetDiscoverTargetsCB func(err error)
 
 // Controls whether to discover available targets and notify via targetCreated/targetDestroyed events.
-
 type AsyncSetDiscoverTargetsCommand struct {
 	params *SetDiscoverTargetsParams
 	cb     SetDiscoverTargetsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetDiscoverTargetsCommand(params *SetDiscoverTargetsParams, cb SetDiscoverTargetsCB) *AsyncSetDiscoverTargetsCommand {
 	return &AsyncSetDiscoverTargetsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -92,7 +95,28 @@ func (cmd *SetDiscoverTargetsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetDiscoverTargetsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetDiscoverTargetsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetDiscoverTargetsCommand) Send(conn hc.CommandRunner) *AsyncSetDiscoverTargetsCommand {
+	async := NewAsyncSetDiscoverTargetsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetAutoAttachParams struct {
@@ -101,7 +125,6 @@ type SetAutoAttachParams struct {
 }
 
 // Controls whether to automatically attach to new targets which are considered to be related to this one. When turned on, attaches to all existing related targets as well. When turned off, automatically detaches from all currently attached targets.
-
 type SetAutoAttachCommand struct {
 	params *SetAutoAttachParams
 	wg     sync.WaitGroup
@@ -122,32 +145,36 @@ func (cmd *SetAutoAttachCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetAutoAttachCommand) Run(conn *hc.Conn) error {
+func (cmd *SetAutoAttachCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetAutoAttach(params *SetAutoAttachParams, conn *hc.Conn) (err error) {
+func SetAutoAttach(params *SetAutoAttachParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetAutoAttachCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetAutoAttachCB func(err error)
 
 // Controls whether to automatically attach to new targets which are considered to be related to this one. When turned on, attaches to all existing related targets as well. When turned off, automatically detaches from all currently attached targets.
-
 type AsyncSetAutoAttachCommand struct {
 	params *SetAutoAttachParams
 	cb     SetAutoAttachCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetAutoAttachCommand(params *SetAutoAttachParams, cb SetAutoAttachCB) *AsyncSetAutoAttachCommand {
 	return &AsyncSetAutoAttachCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -165,7 +192,28 @@ func (cmd *SetAutoAttachCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetAutoAttachCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetAutoAttachCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetAutoAttachCommand) Send(conn hc.CommandRunner) *AsyncSetAutoAttachCommand {
+	async := NewAsyncSetAutoAttachCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetAttachToFramesParams struct {
@@ -192,17 +240,18 @@ func (cmd *SetAttachToFramesCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetAttachToFramesCommand) Run(conn *hc.Conn) error {
+func (cmd *SetAttachToFramesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetAttachToFrames(params *SetAttachToFramesParams, conn *hc.Conn) (err error) {
+func SetAttachToFrames(params *SetAttachToFramesParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetAttachToFramesCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetAttachToFramesCB func(err error)
@@ -210,12 +259,16 @@ type SetAttachToFramesCB func(err error)
 type AsyncSetAttachToFramesCommand struct {
 	params *SetAttachToFramesParams
 	cb     SetAttachToFramesCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetAttachToFramesCommand(params *SetAttachToFramesParams, cb SetAttachToFramesCB) *AsyncSetAttachToFramesCommand {
 	return &AsyncSetAttachToFramesCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -233,7 +286,28 @@ func (cmd *SetAttachToFramesCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetAttachToFramesCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetAttachToFramesCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetAttachToFramesCommand) Send(conn hc.CommandRunner) *AsyncSetAttachToFramesCommand {
+	async := NewAsyncSetAttachToFramesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SetRemoteLocationsParams struct {
@@ -241,7 +315,6 @@ type SetRemoteLocationsParams struct {
 }
 
 // Enables target discovery for the specified locations, when setDiscoverTargets was set to true.
-
 type SetRemoteLocationsCommand struct {
 	params *SetRemoteLocationsParams
 	wg     sync.WaitGroup
@@ -262,32 +335,36 @@ func (cmd *SetRemoteLocationsCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SetRemoteLocationsCommand) Run(conn *hc.Conn) error {
+func (cmd *SetRemoteLocationsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SetRemoteLocations(params *SetRemoteLocationsParams, conn *hc.Conn) (err error) {
+func SetRemoteLocations(params *SetRemoteLocationsParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSetRemoteLocationsCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SetRemoteLocationsCB func(err error)
 
 // Enables target discovery for the specified locations, when setDiscoverTargets was set to true.
-
 type AsyncSetRemoteLocationsCommand struct {
 	params *SetRemoteLocationsParams
 	cb     SetRemoteLocationsCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSetRemoteLocationsCommand(params *SetRemoteLocationsParams, cb SetRemoteLocationsCB) *AsyncSetRemoteLocationsCommand {
 	return &AsyncSetRemoteLocationsCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -305,7 +382,28 @@ func (cmd *SetRemoteLocationsCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSetRemoteLocationsCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetRemoteLocationsCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetRemoteLocationsCommand) Send(conn hc.CommandRunner) *AsyncSetRemoteLocationsCommand {
+	async := NewAsyncSetRemoteLocationsCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type SendMessageToTargetParams struct {
@@ -314,7 +412,6 @@ type SendMessageToTargetParams struct {
 }
 
 // Sends protocol message to the target with given id.
-
 type SendMessageToTargetCommand struct {
 	params *SendMessageToTargetParams
 	wg     sync.WaitGroup
@@ -335,32 +432,36 @@ func (cmd *SendMessageToTargetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *SendMessageToTargetCommand) Run(conn *hc.Conn) error {
+func (cmd *SendMessageToTargetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func SendMessageToTarget(params *SendMessageToTargetParams, conn *hc.Conn) (err error) {
+func SendMessageToTarget(params *SendMessageToTargetParams, conn hc.CommandRunner) (err error) {
 	cmd := NewSendMessageToTargetCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type SendMessageToTargetCB func(err error)
 
 // Sends protocol message to the target with given id.
-
 type AsyncSendMessageToTargetCommand struct {
 	params *SendMessageToTargetParams
 	cb     SendMessageToTargetCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncSendMessageToTargetCommand(params *SendMessageToTargetParams, cb SendMessageToTargetCB) *AsyncSendMessageToTargetCommand {
 	return &AsyncSendMessageToTargetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -378,7 +479,28 @@ func (cmd *SendMessageToTargetCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncSendMessageToTargetCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSendMessageToTargetCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SendMessageToTargetCommand) Send(conn hc.CommandRunner) *AsyncSendMessageToTargetCommand {
+	async := NewAsyncSendMessageToTargetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetTargetInfoParams struct {
@@ -390,7 +512,6 @@ type GetTargetInfoResult struct {
 }
 
 // Returns information about a target.
-
 type GetTargetInfoCommand struct {
 	params *GetTargetInfoParams
 	result GetTargetInfoResult
@@ -412,32 +533,40 @@ func (cmd *GetTargetInfoCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *GetTargetInfoCommand) Run(conn *hc.Conn) error {
+func (cmd *GetTargetInfoCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetTargetInfo(params *GetTargetInfoParams, conn *hc.Conn) (result *GetTargetInfoResult, err error) {
+func GetTargetInfo(params *GetTargetInfoParams, conn hc.CommandRunner) (result *GetTargetInfoResult, err error) {
 	cmd := NewGetTargetInfoCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetTargetInfoCB func(result *GetTargetInfoResult, err error)
 
 // Returns information about a target.
-
 type AsyncGetTargetInfoCommand struct {
 	params *GetTargetInfoParams
 	cb     GetTargetInfoCB
+	result *GetTargetInfoResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetTargetInfoCommand(params *GetTargetInfoParams, cb GetTargetInfoCB) *AsyncGetTargetInfoCommand {
 	return &AsyncGetTargetInfoCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -466,21 +595,38 @@ func (cmd *AsyncGetTargetInfoCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetTargetInfoCommand) Wait(ctx context.Context) (*GetTargetInfoResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetTargetInfoCommand) Send(conn hc.CommandRunner) *AsyncGetTargetInfoCommand {
+	async := NewAsyncGetTargetInfoCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type ActivateTargetParams struct {
 	TargetId TargetID `json:"targetId"`
 }
 
 // Activates (focuses) the target.
-
 type ActivateTargetCommand struct {
 	params *ActivateTargetParams
 	wg     sync.WaitGroup
@@ -501,32 +647,36 @@ func (cmd *ActivateTargetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *ActivateTargetCommand) Run(conn *hc.Conn) error {
+func (cmd *ActivateTargetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func ActivateTarget(params *ActivateTargetParams, conn *hc.Conn) (err error) {
+func ActivateTarget(params *ActivateTargetParams, conn hc.CommandRunner) (err error) {
 	cmd := NewActivateTargetCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type ActivateTargetCB func(err error)
 
 // Activates (focuses) the target.
-
 type AsyncActivateTargetCommand struct {
 	params *ActivateTargetParams
 	cb     ActivateTargetCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncActivateTargetCommand(params *ActivateTargetParams, cb ActivateTargetCB) *AsyncActivateTargetCommand {
 	return &AsyncActivateTargetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -544,7 +694,28 @@ func (cmd *ActivateTargetCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncActivateTargetCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncActivateTargetCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ActivateTargetCommand) Send(conn hc.CommandRunner) *AsyncActivateTargetCommand {
+	async := NewAsyncActivateTargetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CloseTargetParams struct {
@@ -556,7 +727,6 @@ type CloseTargetResult struct {
 }
 
 // Closes the target. If the target is a page that gets closed too.
-
 type CloseTargetCommand struct {
 	params *CloseTargetParams
 	result CloseTargetResult
@@ -578,32 +748,40 @@ func (cmd *CloseTargetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CloseTargetCommand) Run(conn *hc.Conn) error {
+func (cmd *CloseTargetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CloseTarget(params *CloseTargetParams, conn *hc.Conn) (result *CloseTargetResult, err error) {
+func CloseTarget(params *CloseTargetParams, conn hc.CommandRunner) (result *CloseTargetResult, err error) {
 	cmd := NewCloseTargetCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CloseTargetCB func(result *CloseTargetResult, err error)
 
 // Closes the target. If the target is a page that gets closed too.
-
 type AsyncCloseTargetCommand struct {
 	params *CloseTargetParams
 	cb     CloseTargetCB
+	result *CloseTargetResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCloseTargetCommand(params *CloseTargetParams, cb CloseTargetCB) *AsyncCloseTargetCommand {
 	return &AsyncCloseTargetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -632,15 +810,33 @@ func (cmd *AsyncCloseTargetCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCloseTargetCommand) Wait(ctx context.Context) (*CloseTargetResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CloseTargetCommand) Send(conn hc.CommandRunner) *AsyncCloseTargetCommand {
+	async := NewAsyncCloseTargetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type AttachToTargetParams struct {
 	TargetId TargetID `json:"targetId"`
 }
@@ -650,7 +846,6 @@ type AttachToTargetResult struct {
 }
 
 // Attaches to the target with given id.
-
 type AttachToTargetCommand struct {
 	params *AttachToTargetParams
 	result AttachToTargetResult
@@ -672,32 +867,40 @@ func (cmd *AttachToTargetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *AttachToTargetCommand) Run(conn *hc.Conn) error {
+func (cmd *AttachToTargetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func AttachToTarget(params *AttachToTargetParams, conn *hc.Conn) (result *AttachToTargetResult, err error) {
+func AttachToTarget(params *AttachToTargetParams, conn hc.CommandRunner) (result *AttachToTargetResult, err error) {
 	cmd := NewAttachToTargetCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type AttachToTargetCB func(result *AttachToTargetResult, err error)
 
 // Attaches to the target with given id.
-
 type AsyncAttachToTargetCommand struct {
 	params *AttachToTargetParams
 	cb     AttachToTargetCB
+	result *AttachToTargetResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncAttachToTargetCommand(params *AttachToTargetParams, cb AttachToTargetCB) *AsyncAttachToTargetCommand {
 	return &AsyncAttachToTargetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -726,13 +929,31 @@ func (cmd *AsyncAttachToTargetCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
 	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncAttachToTargetCommand) Wait(ctx context.Context) (*AttachToTargetResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *AttachToTargetCommand) Send(conn hc.CommandRunner) *AsyncAttachToTargetCommand {
+	async := NewAsyncAttachToTargetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type DetachFromTargetParams struct {
@@ -740,7 +961,6 @@ type DetachFromTargetParams struct {
 }
 
 // Detaches from the target with given id.
-
 type DetachFromTargetCommand struct {
 	params *DetachFromTargetParams
 	wg     sync.WaitGroup
@@ -761,32 +981,36 @@ func (cmd *DetachFromTargetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DetachFromTargetCommand) Run(conn *hc.Conn) error {
+func (cmd *DetachFromTargetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DetachFromTarget(params *DetachFromTargetParams, conn *hc.Conn) (err error) {
+func DetachFromTarget(params *DetachFromTargetParams, conn hc.CommandRunner) (err error) {
 	cmd := NewDetachFromTargetCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type DetachFromTargetCB func(err error)
 
 // Detaches from the target with given id.
-
 type AsyncDetachFromTargetCommand struct {
 	params *DetachFromTargetParams
 	cb     DetachFromTargetCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDetachFromTargetCommand(params *DetachFromTargetParams, cb DetachFromTargetCB) *AsyncDetachFromTargetCommand {
 	return &AsyncDetachFromTargetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -804,7 +1028,28 @@ func (cmd *DetachFromTargetCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncDetachFromTargetCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDetachFromTargetCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DetachFromTargetCommand) Send(conn hc.CommandRunner) *AsyncDetachFromTargetCommand {
+	async := NewAsyncDetachFromTargetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type CreateBrowserContextResult struct {
@@ -812,7 +1057,6 @@ type CreateBrowserContextResult struct {
 }
 
 // Creates a new empty BrowserContext. Similar to an incognito profile but you can have more than one.
-
 type CreateBrowserContextCommand struct {
 	result CreateBrowserContextResult
 	wg     sync.WaitGroup
@@ -831,30 +1075,38 @@ func (cmd *CreateBrowserContextCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *CreateBrowserContextCommand) Run(conn *hc.Conn) error {
+func (cmd *CreateBrowserContextCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CreateBrowserContext(conn *hc.Conn) (result *CreateBrowserContextResult, err error) {
+func CreateBrowserContext(conn hc.CommandRunner) (result *CreateBrowserContextResult, err error) {
 	cmd := NewCreateBrowserContextCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CreateBrowserContextCB func(result *CreateBrowserContextResult, err error)
 
 // Creates a new empty BrowserContext. Similar to an incognito profile but you can have more than one.
-
 type AsyncCreateBrowserContextCommand struct {
-	cb CreateBrowserContextCB
+	cb     CreateBrowserContextCB
+	result *CreateBrowserContextResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCreateBrowserContextCommand(cb CreateBrowserContextCB) *AsyncCreateBrowserContextCommand {
 	return &AsyncCreateBrowserContextCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -883,15 +1135,33 @@ func (cmd *AsyncCreateBrowserContextCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCreateBrowserContextCommand) Wait(ctx context.Context) (*CreateBrowserContextResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CreateBrowserContextCommand) Send(conn hc.CommandRunner) *AsyncCreateBrowserContextCommand {
+	async := NewAsyncCreateBrowserContextCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type DisposeBrowserContextParams struct {
 	BrowserContextId BrowserContextID `json:"browserContextId"`
 }
@@ -901,7 +1171,6 @@ type DisposeBrowserContextResult struct {
 }
 
 // Deletes a BrowserContext, will fail of any open page uses it.
-
 type DisposeBrowserContextCommand struct {
 	params *DisposeBrowserContextParams
 	result DisposeBrowserContextResult
@@ -923,32 +1192,40 @@ func (cmd *DisposeBrowserContextCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *DisposeBrowserContextCommand) Run(conn *hc.Conn) error {
+func (cmd *DisposeBrowserContextCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func DisposeBrowserContext(params *DisposeBrowserContextParams, conn *hc.Conn) (result *DisposeBrowserContextResult, err error) {
+func DisposeBrowserContext(params *DisposeBrowserContextParams, conn hc.CommandRunner) (result *DisposeBrowserContextResult, err error) {
 	cmd := NewDisposeBrowserContextCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type DisposeBrowserContextCB func(result *DisposeBrowserContextResult, err error)
 
 // Deletes a BrowserContext, will fail of any open page uses it.
-
 type AsyncDisposeBrowserContextCommand struct {
 	params *DisposeBrowserContextParams
 	cb     DisposeBrowserContextCB
+	result *DisposeBrowserContextResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncDisposeBrowserContextCommand(params *DisposeBrowserContextParams, cb DisposeBrowserContextCB) *AsyncDisposeBrowserContextCommand {
 	return &AsyncDisposeBrowserContextCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -977,15 +1254,33 @@ func (cmd *AsyncDisposeBrowserContextCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncDisposeBrowserContextCommand) Wait(ctx context.Context) (*DisposeBrowserContextResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *DisposeBrowserContextCommand) Send(conn hc.CommandRunner) *AsyncDisposeBrowserContextCommand {
+	async := NewAsyncDisposeBrowserContextCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type CreateTargetParams struct {
 	Url              string           `json:"url"`                        // The initial URL the page will be navigated to.
 	Width            int              `json:"width,omitempty"`            // Frame width in DIP (headless chrome only).
@@ -998,7 +1293,6 @@ type CreateTargetResult struct {
 }
 
 // Creates a new page.
-
 type CreateTargetCommand struct {
 	params *CreateTargetParams
 	result CreateTargetResult
@@ -1020,32 +1314,40 @@ func (cmd *CreateTargetCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *CreateTargetCommand) Run(conn *hc.Conn) error {
+func (cmd *CreateTargetCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func CreateTarget(params *CreateTargetParams, conn *hc.Conn) (result *CreateTargetResult, err error) {
+func CreateTarget(params *CreateTargetParams, conn hc.CommandRunner) (result *CreateTargetResult, err error) {
 	cmd := NewCreateTargetCommand(params)
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type CreateTargetCB func(result *CreateTargetResult, err error)
 
 // Creates a new page.
-
 type AsyncCreateTargetCommand struct {
 	params *CreateTargetParams
 	cb     CreateTargetCB
+	result *CreateTargetResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncCreateTargetCommand(params *CreateTargetParams, cb CreateTargetCB) *AsyncCreateTargetCommand {
 	return &AsyncCreateTargetCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -1074,21 +1376,38 @@ func (cmd *AsyncCreateTargetCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncCreateTargetCommand) Wait(ctx context.Context) (*CreateTargetResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *CreateTargetCommand) Send(conn hc.CommandRunner) *AsyncCreateTargetCommand {
+	async := NewAsyncCreateTargetCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type GetTargetsResult struct {
 	TargetInfos []*TargetInfo `json:"targetInfos"` // The list of targets.
 }
 
 // Retrieves a list of available targets.
-
 type GetTargetsCommand struct {
 	result GetTargetsResult
 	wg     sync.WaitGroup
@@ -1107,30 +1426,38 @@ func (cmd *GetTargetsCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetTargetsCommand) Run(conn *hc.Conn) error {
+func (cmd *GetTargetsCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetTargets(conn *hc.Conn) (result *GetTargetsResult, err error) {
+func GetTargets(conn hc.CommandRunner) (result *GetTargetsResult, err error) {
 	cmd := NewGetTargetsCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetTargetsCB func(result *GetTargetsResult, err error)
 
 // Retrieves a list of available targets.
-
 type AsyncGetTargetsCommand struct {
-	cb GetTargetsCB
+	cb     GetTargetsCB
+	result *GetTargetsResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetTargetsCommand(cb GetTargetsCB) *AsyncGetTargetsCommand {
 	return &AsyncGetTargetsCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -1159,103 +1486,196 @@ func (cmd *AsyncGetTargetsCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
 
-// Issued when a possible inspection target is created.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetTargetsCommand) Wait(ctx context.Context) (*GetTargetsResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetTargetsCommand) Send(conn hc.CommandRunner) *AsyncGetTargetsCommand {
+	async := NewAsyncGetTargetsCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Issued when a possible inspection target is created.
 type TargetCreatedEvent struct {
 	TargetInfo *TargetInfo `json:"targetInfo"`
 }
 
-func OnTargetCreated(conn *hc.Conn, cb func(evt *TargetCreatedEvent)) {
+// Calls cb for each Target.targetCreated event, till the returned func is called.
+func OnTargetCreated(conn hc.EventSource, cb func(evt *TargetCreatedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &TargetCreatedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Target.targetCreated", sink)
+	return func() { conn.RemoveEventSink("Target.targetCreated", sink) }
 }
 
-// Issued when a target is destroyed.
+// Like OnTargetCreated, but cb also gets hc.EventMeta.
+func OnTargetCreatedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *TargetCreatedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &TargetCreatedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Target.targetCreated", sink)
+	return func() { conn.RemoveEventSink("Target.targetCreated", sink) }
+}
 
+// Issued when a target is destroyed.
 type TargetDestroyedEvent struct {
 	TargetId TargetID `json:"targetId"`
 }
 
-func OnTargetDestroyed(conn *hc.Conn, cb func(evt *TargetDestroyedEvent)) {
+// Calls cb for each Target.targetDestroyed event, till the returned func is called.
+func OnTargetDestroyed(conn hc.EventSource, cb func(evt *TargetDestroyedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &TargetDestroyedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Target.targetDestroyed", sink)
+	return func() { conn.RemoveEventSink("Target.targetDestroyed", sink) }
 }
 
-// Issued when attached to target because of auto-attach or attachToTarget command.
+// Like OnTargetDestroyed, but cb also gets hc.EventMeta.
+func OnTargetDestroyedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *TargetDestroyedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &TargetDestroyedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Target.targetDestroyed", sink)
+	return func() { conn.RemoveEventSink("Target.targetDestroyed", sink) }
+}
 
+// Issued when attached to target because of auto-attach or attachToTarget command.
 type AttachedToTargetEvent struct {
 	TargetInfo         *TargetInfo `json:"targetInfo"`
 	WaitingForDebugger bool        `json:"waitingForDebugger"`
 }
 
-func OnAttachedToTarget(conn *hc.Conn, cb func(evt *AttachedToTargetEvent)) {
+// Calls cb for each Target.attachedToTarget event, till the returned func is called.
+func OnAttachedToTarget(conn hc.EventSource, cb func(evt *AttachedToTargetEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AttachedToTargetEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Target.attachedToTarget", sink)
+	return func() { conn.RemoveEventSink("Target.attachedToTarget", sink) }
 }
 
-// Issued when detached from target for any reason (including detachFromTarget command).
+// Like OnAttachedToTarget, but cb also gets hc.EventMeta.
+func OnAttachedToTargetMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AttachedToTargetEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AttachedToTargetEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Target.attachedToTarget", sink)
+	return func() { conn.RemoveEventSink("Target.attachedToTarget", sink) }
+}
 
+// Issued when detached from target for any reason (including detachFromTarget command).
 type DetachedFromTargetEvent struct {
 	TargetId TargetID `json:"targetId"`
 }
 
-func OnDetachedFromTarget(conn *hc.Conn, cb func(evt *DetachedFromTargetEvent)) {
+// Calls cb for each Target.detachedFromTarget event, till the returned func is called.
+func OnDetachedFromTarget(conn hc.EventSource, cb func(evt *DetachedFromTargetEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DetachedFromTargetEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Target.detachedFromTarget", sink)
+	return func() { conn.RemoveEventSink("Target.detachedFromTarget", sink) }
 }
 
-// Notifies about new protocol message from attached target.
+// Like OnDetachedFromTarget, but cb also gets hc.EventMeta.
+func OnDetachedFromTargetMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DetachedFromTargetEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DetachedFromTargetEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Target.detachedFromTarget", sink)
+	return func() { conn.RemoveEventSink("Target.detachedFromTarget", sink) }
+}
 
+// Notifies about new protocol message from attached target.
 type ReceivedMessageFromTargetEvent struct {
 	TargetId TargetID `json:"targetId"`
 	Message  string   `json:"message"`
 }
 
-func OnReceivedMessageFromTarget(conn *hc.Conn, cb func(evt *ReceivedMessageFromTargetEvent)) {
+// Calls cb for each Target.receivedMessageFromTarget event, till the returned func is called.
+func OnReceivedMessageFromTarget(conn hc.EventSource, cb func(evt *ReceivedMessageFromTargetEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &ReceivedMessageFromTargetEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Target.receivedMessageFromTarget", sink)
+	return func() { conn.RemoveEventSink("Target.receivedMessageFromTarget", sink) }
+}
+
+// Like OnReceivedMessageFromTarget, but cb also gets hc.EventMeta.
+func OnReceivedMessageFromTargetMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ReceivedMessageFromTargetEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ReceivedMessageFromTargetEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Target.receivedMessageFromTarget", sink)
+	return func() { conn.RemoveEventSink("Target.receivedMessageFromTarget", sink) }
 }
@@ -1,8 +1,8 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
@@ -12,7 +12,6 @@ type BindParams struct {
 }
 
 // Request browser port binding.
-
 type BindCommand struct {
 	params *BindParams
 	wg     sync.WaitGroup
@@ -33,32 +32,36 @@ func (cmd *BindCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *BindCommand) Run(conn *hc.Conn) error {
+func (cmd *BindCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Bind(params *BindParams, conn *hc.Conn) (err error) {
+func Bind(params *BindParams, conn hc.CommandRunner) (err error) {
 	cmd := NewBindCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type BindCB func(err error)
 
 // Request browser port binding.
-
 type AsyncBindCommand struct {
 	params *BindParams
 	cb     BindCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncBindCommand(params *BindParams, cb BindCB) *AsyncBindCommand {
 	return &AsyncBindCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -76,7 +79,28 @@ func (cmd *BindCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncBindCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncBindCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *BindCommand) Send(conn hc.CommandRunner) *AsyncBindCommand {
+	async := NewAsyncBindCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type UnbindParams struct {
@@ -84,7 +108,6 @@ type UnbindParams struct {
 }
 
 // Request browser port unbinding.
-
 type UnbindCommand struct {
 	params *UnbindParams
 	wg     sync.WaitGroup
@@ -105,32 +128,36 @@ func (cmd *UnbindCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *UnbindCommand) Run(conn *hc.Conn) error {
+func (cmd *UnbindCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func Unbind(params *UnbindParams, conn *hc.Conn) (err error) {
+func Unbind(params *UnbindParams, conn hc.CommandRunner) (err error) {
 	cmd := NewUnbindCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type UnbindCB func(err error)
 
 // Request browser port unbinding.
-
 type AsyncUnbindCommand struct {
 	params *UnbindParams
 	cb     UnbindCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncUnbindCommand(params *UnbindParams, cb UnbindCB) *AsyncUnbindCommand {
 	return &AsyncUnbindCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -148,24 +175,60 @@ func (cmd *UnbindCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncUnbindCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Informs that port was successfully bound and got a specified connection id.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncUnbindCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *UnbindCommand) Send(conn hc.CommandRunner) *AsyncUnbindCommand {
+	async := NewAsyncUnbindCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
 
+// Informs that port was successfully bound and got a specified connection id.
 type AcceptedEvent struct {
 	Port         int    `json:"port"`         // Port number that was successfully bound.
 	ConnectionId string `json:"connectionId"` // Connection id to be used.
 }
 
-func OnAccepted(conn *hc.Conn, cb func(evt *AcceptedEvent)) {
+// Calls cb for each Tethering.accepted event, till the returned func is called.
+func OnAccepted(conn hc.EventSource, cb func(evt *AcceptedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &AcceptedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Tethering.accepted", sink)
+	return func() { conn.RemoveEventSink("Tethering.accepted", sink) }
+}
+
+// Like OnAccepted, but cb also gets hc.EventMeta.
+func OnAcceptedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AcceptedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AcceptedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Tethering.accepted", sink)
+	return func() { conn.RemoveEventSink("Tethering.accepted", sink) }
 }
@@ -1,25 +1,24 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/yijinliu/algo-lib/go/src/logging"
 	hc "github.com/yijinliu/headless-chromium/go"
 	"sync"
 )
 
 // Configuration for memory dump. Used only when "memory-infra" category is enabled.
-type MemoryDumpConfig struct {
-}
+type MemoryDumpConfig map[string]string
 
 type TraceConfig struct {
-	RecordMode           string            `json:"recordMode,omitempty"`           // Controls how the trace buffer stores data.
-	EnableSampling       bool              `json:"enableSampling,omitempty"`       // Turns on JavaScript stack sampling.
-	EnableSystrace       bool              `json:"enableSystrace,omitempty"`       // Turns on system tracing.
-	EnableArgumentFilter bool              `json:"enableArgumentFilter,omitempty"` // Turns on argument filter.
-	IncludedCategories   []string          `json:"includedCategories,omitempty"`   // Included category filters.
-	ExcludedCategories   []string          `json:"excludedCategories,omitempty"`   // Excluded category filters.
-	SyntheticDelays      []string          `json:"syntheticDelays,omitempty"`      // Configuration to synthesize the delays in tracing.
-	MemoryDumpConfig     *MemoryDumpConfig `json:"memoryDumpConfig,omitempty"`     // Configuration for memory dump triggers. Used only when "memory-infra" category is enabled.
+	RecordMode           string           `json:"recordMode,omitempty"`           // Controls how the trace buffer stores data.
+	EnableSampling       bool             `json:"enableSampling,omitempty"`       // Turns on JavaScript stack sampling.
+	EnableSystrace       bool             `json:"enableSystrace,omitempty"`       // Turns on system tracing.
+	EnableArgumentFilter bool             `json:"enableArgumentFilter,omitempty"` // Turns on argument filter.
+	IncludedCategories   []string         `json:"includedCategories,omitempty"`   // Included category filters.
+	ExcludedCategories   []string         `json:"excludedCategories,omitempty"`   // Excluded category filters.
+	SyntheticDelays      []string         `json:"syntheticDelays,omitempty"`      // Configuration to synthesize the delays in tracing.
+	MemoryDumpConfig     MemoryDumpConfig `json:"memoryDumpConfig,omitempty"`     // Configuration for memory dump triggers. Used only when "memory-infra" category is enabled.
 }
 
 type TracingStartParams struct {
@@ -31,7 +30,6 @@ type TracingStartParams struct {
 }
 
 // Start trace events collection.
-
 type TracingStartCommand struct {
 	params *TracingStartParams
 	wg     sync.WaitGroup
@@ -52,32 +50,36 @@ func (cmd *TracingStartCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *TracingStartCommand) Run(conn *hc.Conn) error {
+func (cmd *TracingStartCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func TracingStart(params *TracingStartParams, conn *hc.Conn) (err error) {
+func TracingStart(params *TracingStartParams, conn hc.CommandRunner) (err error) {
 	cmd := NewTracingStartCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type TracingStartCB func(err error)
 
 // Start trace events collection.
-
 type AsyncTracingStartCommand struct {
 	params *TracingStartParams
 	cb     TracingStartCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncTracingStartCommand(params *TracingStartParams, cb TracingStartCB) *AsyncTracingStartCommand {
 	return &AsyncTracingStartCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -95,11 +97,31 @@ func (cmd *TracingStartCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncTracingStartCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Stop trace events collection.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncTracingStartCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *TracingStartCommand) Send(conn hc.CommandRunner) *AsyncTracingStartCommand {
+	async := NewAsyncTracingStartCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Stop trace events collection.
 type EndCommand struct {
 	wg  sync.WaitGroup
 	err error
@@ -117,30 +139,34 @@ func (cmd *EndCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *EndCommand) Run(conn *hc.Conn) error {
+func (cmd *EndCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func End(conn *hc.Conn) (err error) {
+func End(conn hc.CommandRunner) (err error) {
 	cmd := NewEndCommand()
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type EndCB func(err error)
 
 // Stop trace events collection.
-
 type AsyncEndCommand struct {
-	cb EndCB
+	cb   EndCB
+	done chan struct{}
+	err  error
 }
 
+// cb is optional. See Wait.
 func NewAsyncEndCommand(cb EndCB) *AsyncEndCommand {
 	return &AsyncEndCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -158,7 +184,28 @@ func (cmd *EndCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncEndCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEndCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EndCommand) Send(conn hc.CommandRunner) *AsyncEndCommand {
+	async := NewAsyncEndCommand(nil)
+	conn.SendCommand(async)
+	return async
 }
 
 type GetCategoriesResult struct {
@@ -166,7 +213,6 @@ type GetCategoriesResult struct {
 }
 
 // Gets supported tracing categories.
-
 type GetCategoriesCommand struct {
 	result GetCategoriesResult
 	wg     sync.WaitGroup
@@ -185,30 +231,38 @@ func (cmd *GetCategoriesCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *GetCategoriesCommand) Run(conn *hc.Conn) error {
+func (cmd *GetCategoriesCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func GetCategories(conn *hc.Conn) (result *GetCategoriesResult, err error) {
+func GetCategories(conn hc.CommandRunner) (result *GetCategoriesResult, err error) {
 	cmd := NewGetCategoriesCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type GetCategoriesCB func(result *GetCategoriesResult, err error)
 
 // Gets supported tracing categories.
-
 type AsyncGetCategoriesCommand struct {
-	cb GetCategoriesCB
+	cb     GetCategoriesCB
+	result *GetCategoriesResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncGetCategoriesCommand(cb GetCategoriesCB) *AsyncGetCategoriesCommand {
 	return &AsyncGetCategoriesCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -237,22 +291,39 @@ func (cmd *AsyncGetCategoriesCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetCategoriesCommand) Wait(ctx context.Context) (*GetCategoriesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetCategoriesCommand) Send(conn hc.CommandRunner) *AsyncGetCategoriesCommand {
+	async := NewAsyncGetCategoriesCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RequestMemoryDumpResult struct {
 	DumpGuid string `json:"dumpGuid"` // GUID of the resulting global memory dump.
 	Success  bool   `json:"success"`  // True iff the global memory dump succeeded.
 }
 
 // Request a global memory dump.
-
 type RequestMemoryDumpCommand struct {
 	result RequestMemoryDumpResult
 	wg     sync.WaitGroup
@@ -271,30 +342,38 @@ func (cmd *RequestMemoryDumpCommand) Params() interface{} {
 	return nil
 }
 
-func (cmd *RequestMemoryDumpCommand) Run(conn *hc.Conn) error {
+func (cmd *RequestMemoryDumpCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RequestMemoryDump(conn *hc.Conn) (result *RequestMemoryDumpResult, err error) {
+func RequestMemoryDump(conn hc.CommandRunner) (result *RequestMemoryDumpResult, err error) {
 	cmd := NewRequestMemoryDumpCommand()
-	cmd.Run(conn)
-	return &cmd.result, cmd.err
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
 }
 
 type RequestMemoryDumpCB func(result *RequestMemoryDumpResult, err error)
 
 // Request a global memory dump.
-
 type AsyncRequestMemoryDumpCommand struct {
-	cb RequestMemoryDumpCB
+	cb     RequestMemoryDumpCB
+	result *RequestMemoryDumpResult
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRequestMemoryDumpCommand(cb RequestMemoryDumpCB) *AsyncRequestMemoryDumpCommand {
 	return &AsyncRequestMemoryDumpCommand{
-		cb: cb,
+		cb:   cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -323,21 +402,38 @@ func (cmd *AsyncRequestMemoryDumpCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestMemoryDumpCommand) Wait(ctx context.Context) (*RequestMemoryDumpResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestMemoryDumpCommand) Send(conn hc.CommandRunner) *AsyncRequestMemoryDumpCommand {
+	async := NewAsyncRequestMemoryDumpCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
 type RecordClockSyncMarkerParams struct {
 	SyncId string `json:"syncId"` // The ID of this clock sync marker
 }
 
 // Record a clock sync marker in the trace.
-
 type RecordClockSyncMarkerCommand struct {
 	params *RecordClockSyncMarkerParams
 	wg     sync.WaitGroup
@@ -358,32 +454,36 @@ func (cmd *RecordClockSyncMarkerCommand) Params() interface{} {
 	return cmd.params
 }
 
-func (cmd *RecordClockSyncMarkerCommand) Run(conn *hc.Conn) error {
+func (cmd *RecordClockSyncMarkerCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func RecordClockSyncMarker(params *RecordClockSyncMarkerParams, conn *hc.Conn) (err error) {
+func RecordClockSyncMarker(params *RecordClockSyncMarkerParams, conn hc.CommandRunner) (err error) {
 	cmd := NewRecordClockSyncMarkerCommand(params)
-	cmd.Run(conn)
-	return cmd.err
+	return cmd.Run(conn)
 }
 
 type RecordClockSyncMarkerCB func(err error)
 
 // Record a clock sync marker in the trace.
-
 type AsyncRecordClockSyncMarkerCommand struct {
 	params *RecordClockSyncMarkerParams
 	cb     RecordClockSyncMarkerCB
+	done   chan struct{}
+	err    error
 }
 
+// cb is optional. See Wait.
 func NewAsyncRecordClockSyncMarkerCommand(params *RecordClockSyncMarkerParams, cb RecordClockSyncMarkerCB) *AsyncRecordClockSyncMarkerCommand {
 	return &AsyncRecordClockSyncMarkerCommand{
 		params: params,
 		cb:     cb,
+		done:   make(chan struct{}),
 	}
 }
 
@@ -401,43 +501,94 @@ func (cmd *RecordClockSyncMarkerCommand) Done(data []byte, err error) {
 }
 
 func (cmd *AsyncRecordClockSyncMarkerCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
 
-// Contains an bucket of collected trace events. When tracing is stopped collected events will be send as a sequence of dataCollected events followed by tracingComplete event.
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRecordClockSyncMarkerCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RecordClockSyncMarkerCommand) Send(conn hc.CommandRunner) *AsyncRecordClockSyncMarkerCommand {
+	async := NewAsyncRecordClockSyncMarkerCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Contains an bucket of collected trace events. When tracing is stopped collected events will be send as a sequence of dataCollected events followed by tracingComplete event.
 type DataCollectedEvent struct {
-	Value []map[string]string `json:"value"`
+	Value []json.RawMessage `json:"value"`
 }
 
-func OnDataCollected(conn *hc.Conn, cb func(evt *DataCollectedEvent)) {
+// Calls cb for each Tracing.dataCollected event, till the returned func is called.
+func OnDataCollected(conn hc.EventSource, cb func(evt *DataCollectedEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &DataCollectedEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Tracing.dataCollected", sink)
+	return func() { conn.RemoveEventSink("Tracing.dataCollected", sink) }
 }
 
-// Signals that tracing is stopped and there is no trace buffers pending flush, all data were delivered via dataCollected events.
+// Like OnDataCollected, but cb also gets hc.EventMeta.
+func OnDataCollectedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *DataCollectedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &DataCollectedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Tracing.dataCollected", sink)
+	return func() { conn.RemoveEventSink("Tracing.dataCollected", sink) }
+}
 
+// Signals that tracing is stopped and there is no trace buffers pending flush, all data were delivered via dataCollected events.
 type TracingCompleteEvent struct {
-	Stream *StreamHandle `json:"stream"` // A handle of the stream that holds resulting trace data.
+	Stream StreamHandle `json:"stream"` // A handle of the stream that holds resulting trace data.
 }
 
-func OnTracingComplete(conn *hc.Conn, cb func(evt *TracingCompleteEvent)) {
+// Calls cb for each Tracing.tracingComplete event, till the returned func is called.
+func OnTracingComplete(conn hc.EventSource, cb func(evt *TracingCompleteEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &TracingCompleteEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Tracing.tracingComplete", sink)
+	return func() { conn.RemoveEventSink("Tracing.tracingComplete", sink) }
+}
+
+// Like OnTracingComplete, but cb also gets hc.EventMeta.
+func OnTracingCompleteMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *TracingCompleteEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &TracingCompleteEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Tracing.tracingComplete", sink)
+	return func() { conn.RemoveEventSink("Tracing.tracingComplete", sink) }
 }
 
 type BufferUsageEvent struct {
@@ -446,14 +597,30 @@ type BufferUsageEvent struct {
 	Value       float64 `json:"value"`       // A number in range [0..1] that indicates the used size of event buffer as a fraction of its total size.
 }
 
-func OnBufferUsage(conn *hc.Conn, cb func(evt *BufferUsageEvent)) {
+// Calls cb for each Tracing.bufferUsage event, till the returned func is called.
+func OnBufferUsage(conn hc.EventSource, cb func(evt *BufferUsageEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &BufferUsageEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("Tracing.bufferUsage", sink)
+	return func() { conn.RemoveEventSink("Tracing.bufferUsage", sink) }
+}
+
+// Like OnBufferUsage, but cb also gets hc.EventMeta.
+func OnBufferUsageMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *BufferUsageEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &BufferUsageEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Tracing.bufferUsage", sink)
+	return func() { conn.RemoveEventSink("Tracing.bufferUsage", sink) }
 }
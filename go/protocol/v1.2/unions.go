@@ -0,0 +1,91 @@
+package protocol
+
+// Not generated. Accessors of fields kept as json.RawMessage by protocol_parser/type_overrides.json,
+// as their real payloads don't match the protocol definition, e.g. they contain nested objects or
+// booleans where it says a string map.
+
+import (
+	"encoding/json"
+)
+
+// Embedder-specific auxiliary data of execution contexts created by Chromium.
+type ExecutionContextAuxData struct {
+	IsDefault bool    `json:"isDefault"`
+	FrameId   FrameId `json:"frameId"`
+	// "default", "isolated" or "worker" in some versions.
+	Type string `json:"type,omitempty"`
+}
+
+// Unmarshals raw into v, leaving v untouched if raw is absent.
+func decodeRaw(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func decodeAuxData(raw json.RawMessage) (*ExecutionContextAuxData, error) {
+	aux := &ExecutionContextAuxData{}
+	if err := decodeRaw(raw, aux); err != nil {
+		return nil, err
+	}
+	return aux, nil
+}
+
+func decodeObject(raw json.RawMessage) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	err := decodeRaw(raw, &obj)
+	return obj, err
+}
+
+func decodeObjects(raws []json.RawMessage) ([]map[string]interface{}, error) {
+	objs := make([]map[string]interface{}, len(raws))
+	for i, raw := range raws {
+		var err error
+		if objs[i], err = decodeObject(raw); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+func (d *ExecutionContextDescription) Aux() (*ExecutionContextAuxData, error) {
+	return decodeAuxData(d.AuxData)
+}
+
+func (evt *ScriptParsedEvent) ExecutionContextAux() (*ExecutionContextAuxData, error) {
+	return decodeAuxData(evt.ExecutionContextAuxData)
+}
+
+func (evt *ScriptFailedToParseEvent) ExecutionContextAux() (*ExecutionContextAuxData, error) {
+	return decodeAuxData(evt.ExecutionContextAuxData)
+}
+
+// Break-specific properties, e.g. a RemoteObject for exceptions. Use DataAs for a known shape.
+func (evt *PausedEvent) DataObject() (map[string]interface{}, error) {
+	return decodeObject(evt.Data)
+}
+
+func (evt *PausedEvent) DataAs(v interface{}) error {
+	return decodeRaw(evt.Data, v)
+}
+
+func (evt *InspectRequestedEvent) HintsObject() (map[string]interface{}, error) {
+	return decodeObject(evt.Hints)
+}
+
+func (r *GetHighlightObjectForTestResult) HighlightObject() (map[string]interface{}, error) {
+	return decodeObject(r.Highlight)
+}
+
+func (r *SnapshotCommandLogResult) CommandLogObjects() ([]map[string]interface{}, error) {
+	return decodeObjects(r.CommandLog)
+}
+
+func (evt *DataCollectedEvent) ValueObjects() ([]map[string]interface{}, error) {
+	return decodeObjects(evt.Value)
+}
+
+func (info *GPUInfo) AuxAttributesObject() (map[string]interface{}, error) {
+	return decodeObject(info.AuxAttributes)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// Writes a Markdown reference per domain, e.g. page.md, and an index, README.md, under
+// outputDir/v<version>. Commands, events and types get HTML anchors named after their method or
+// type id, e.g. "Page.navigate" or "Page.FrameId", which the comments generated by
+// GolangProtocolHandler refer to.
+type DocsProtocolHandler struct {
+	outputDir string
+
+	curVersion string
+	domains    []*ProtocolDomain
+}
+
+func NewDocsProtocolHandler(outputDir string) *DocsProtocolHandler {
+	return &DocsProtocolHandler{outputDir: outputDir}
+}
+
+// The path of the reference of domain, relative to the repo root.
+func docsPath(version, domain string) string {
+	return fmt.Sprintf("docs/protocol/v%s/%s", version, docsFile(domain))
+}
+
+func docsFile(domain string) string {
+	return strings.ToLower(domain) + ".md"
+}
+
+// The anchor of a command, event or type of domain, e.g. "Page.navigate".
+func docsAnchor(domain, name string) string {
+	return domain + "." + name
+}
+
+func (h *DocsProtocolHandler) StartProtocol(version string) {
+	h.curVersion = version
+	h.domains = nil
+}
+
+func (h *DocsProtocolHandler) OnDomain(domain *ProtocolDomain) {
+	h.domains = append(h.domains, domain)
+}
+
+func (h *DocsProtocolHandler) EndProtocol() {
+	dir := filepath.Join(h.outputDir, "v"+h.curVersion)
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		logging.Fatal(err)
+	}
+	var index bytes.Buffer
+	fmt.Fprintf(&index, "# Protocol v%s\n\n", h.curVersion)
+	index.WriteString("Generated by go/protocol_parser from the same definitions as the Go " +
+		"bindings in go/protocol. Don't edit.\n\n")
+	for _, domain := range h.domains {
+		fmt.Fprintf(&index, "* [%s](%s)%s\n", domain.Domain, docsFile(domain.Domain),
+			markers(domain.Experimental, domain.Deprecated))
+		h.writeFile(filepath.Join(dir, docsFile(domain.Domain)), h.domainDoc(domain))
+	}
+	h.writeFile(filepath.Join(dir, "README.md"), &index)
+}
+
+var blankLinesRegexp = regexp.MustCompile("\n{3,}")
+
+// Sections end with a blank line and start with one too, so runs of them are collapsed.
+func (h *DocsProtocolHandler) writeFile(file string, buf *bytes.Buffer) {
+	content := blankLinesRegexp.ReplaceAll(buf.Bytes(), []byte("\n\n"))
+	if err := ioutil.WriteFile(file, content, os.FileMode(0644)); err != nil {
+		logging.Fatal(err)
+	}
+}
+
+func (h *DocsProtocolHandler) domainDoc(domain *ProtocolDomain) *bytes.Buffer {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s%s\n\n", domain.Domain, markers(domain.Experimental, domain.Deprecated))
+	if domain.Description != "" {
+		fmt.Fprintf(&buf, "%s\n\n", docsDescription(domain.Description))
+	}
+	buf.WriteString("[All domains](README.md)\n")
+
+	if len(domain.Commands) > 0 {
+		buf.WriteString("\n## Commands\n")
+		for _, cmd := range domain.Commands {
+			h.writeHeading(domain.Domain, cmd.Name, cmd.Experimental, cmd.Deprecated,
+				cmd.Description, &buf)
+			if cmd.Redirect != "" {
+				fmt.Fprintf(&buf, "Handled by %s.\n\n", h.refLink(domain.Domain, cmd.Redirect))
+			}
+			h.writeFields(domain.Domain, "Parameters", cmd.Parameters, &buf)
+			h.writeFields(domain.Domain, "Results", cmd.Returns, &buf)
+		}
+	}
+	if len(domain.Events) > 0 {
+		buf.WriteString("\n## Events\n")
+		for _, evt := range domain.Events {
+			h.writeHeading(domain.Domain, evt.Name, evt.Experimental, evt.Deprecated,
+				evt.Description, &buf)
+			h.writeFields(domain.Domain, "Payload", evt.Parameters, &buf)
+		}
+	}
+	if len(domain.Types) > 0 {
+		buf.WriteString("\n## Types\n")
+		for _, tp := range domain.Types {
+			h.writeHeading(domain.Domain, tp.Id, tp.Experimental, tp.Deprecated, tp.Description,
+				&buf)
+			if tp.Type == "object" && len(tp.Properties) > 0 {
+				h.writeFields(domain.Domain, "Properties", tp.Properties, &buf)
+			} else {
+				fmt.Fprintf(&buf, "Type: %s\n\n", h.typeDoc(domain.Domain, &tp.SimpleType))
+			}
+		}
+	}
+	return &buf
+}
+
+func (h *DocsProtocolHandler) writeHeading(domain, name string, experimental, deprecated bool,
+	desc string, buf *bytes.Buffer) {
+	anchor := docsAnchor(domain, name)
+	fmt.Fprintf(buf, "\n<a id=\"%s\"></a>\n### %s%s\n\n", anchor, anchor,
+		markers(experimental, deprecated))
+	if desc != "" {
+		fmt.Fprintf(buf, "%s\n\n", docsDescription(desc))
+	}
+}
+
+// Writes a table of fields. Properties of inline objects follow their field, e.g. "shape.bounds".
+func (h *DocsProtocolHandler) writeFields(domain, title string, fields []*NamedType,
+	buf *bytes.Buffer) {
+	if len(fields) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "**%s**\n\n| Name | Type | Description |\n| --- | --- | --- |\n", title)
+	h.writeFieldRows(domain, "", fields, buf)
+	buf.WriteString("\n")
+}
+
+func (h *DocsProtocolHandler) writeFieldRows(domain, prefix string, fields []*NamedType,
+	buf *bytes.Buffer) {
+	for _, field := range fields {
+		name := "`" + prefix + field.Name + "`"
+		if field.Optional {
+			name += " (optional)"
+		}
+		fmt.Fprintf(buf, "| %s%s | %s | %s |\n", name,
+			markers(field.Experimental, field.Deprecated),
+			h.typeDoc(domain, &field.SimpleType),
+			strings.Replace(docsDescription(field.Description), "|", "\\|", -1))
+		st := &field.SimpleType
+		for st.Type == "array" && st.Items != nil {
+			st = st.Items
+		}
+		if st.Type == "object" && len(st.Properties) > 0 {
+			h.writeFieldRows(domain, prefix+field.Name+".", st.Properties, buf)
+		}
+	}
+}
+
+// Describes st, linking referenced types.
+func (h *DocsProtocolHandler) typeDoc(domain string, st *SimpleType) string {
+	switch st.Type {
+	case "":
+		return h.refLink(domain, st.Ref)
+	case "array":
+		if st.Items == nil {
+			return "array"
+		}
+		return "array of " + h.typeDoc(domain, st.Items)
+	case "string":
+		if len(st.Enum) == 0 {
+			return "string"
+		}
+		values := make([]string, len(st.Enum))
+		for i, value := range st.Enum {
+			values[i] = "`" + value + "`"
+		}
+		return "string, one of " + strings.Join(values, ", ")
+	}
+	return st.Type
+}
+
+// Links ref, which is relative to domain unless it has one, e.g. "Runtime.RemoteObject".
+func (h *DocsProtocolHandler) refLink(domain, ref string) string {
+	refDomain, name := domain, ref
+	if pos := strings.Index(ref, "."); pos >= 0 {
+		refDomain, name = ref[:pos], ref[pos+1:]
+	}
+	anchor := docsAnchor(refDomain, name)
+	if refDomain == domain {
+		return fmt.Sprintf("[%s](#%s)", anchor, anchor)
+	}
+	return fmt.Sprintf("[%s](%s#%s)", anchor, docsFile(refDomain), anchor)
+}
+
+func markers(experimental, deprecated bool) string {
+	var s string
+	if experimental {
+		s += " *(experimental)*"
+	}
+	if deprecated {
+		s += " *(deprecated)*"
+	}
+	return s
+}
+
+// Descriptions are HTML, which Markdown keeps, but they must stay on one line in tables.
+func docsDescription(desc string) string {
+	return strings.Join(strings.Fields(desc), " ")
+}
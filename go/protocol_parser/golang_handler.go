@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -16,23 +17,35 @@ type GolangProtocolHandler struct {
 	outputDir  string
 	handleExpr bool
 	gofmt      string
-
-	curVersion  string
-	domains     []*ProtocolDomain
-	nameCounts  map[string]int
-	imports     map[string]string
-	simpleTypes map[string]bool
-}
-
-func NewGolangProtocolHandler(outputDir string, handleExpr bool) *GolangProtocolHandler {
+	// Key is "Domain.owner.field", where owner is a type id, command or event name. Value is the
+	// Go type to use instead, for fields whose real payloads don't match the protocol definition.
+	typeOverrides map[string]string
+	// Key is a command or event, e.g. "Target.createBrowserContext". Value is the target kinds
+	// supporting it, overriding domainTargetKinds.
+	targetKinds map[string][]string
+
+	curVersion   string
+	domains      []*ProtocolDomain
+	nameCounts   map[string]int
+	imports      map[string]string
+	simpleTypes  map[string]bool   // Key is "Domain.Id".
+	inlineBuf    bytes.Buffer      // Named types generated for inline objects.
+	commandSpecs map[string]string // Key is method. Value is CommandSpec literal.
+	eventSpecs   map[string]string // Key is method. Value is EventSpec literal.
+}
+
+func NewGolangProtocolHandler(outputDir string, handleExpr bool,
+	typeOverrides map[string]string, targetKinds map[string][]string) *GolangProtocolHandler {
 	gofmt, err := exec.LookPath("gofmt")
 	if err != nil {
 		logging.Vlog(0, "Failed to find gofmt binary. Will not run gofmt on generated go files.")
 	}
 	return &GolangProtocolHandler{
-		outputDir:  outputDir,
-		handleExpr: handleExpr,
-		gofmt:      gofmt,
+		outputDir:     outputDir,
+		handleExpr:    handleExpr,
+		gofmt:         gofmt,
+		typeOverrides: typeOverrides,
+		targetKinds:   targetKinds,
 	}
 }
 
@@ -41,6 +54,8 @@ func (h *GolangProtocolHandler) StartProtocol(version string) {
 	h.domains = nil
 	h.nameCounts = make(map[string]int)
 	h.simpleTypes = make(map[string]bool)
+	h.commandSpecs = make(map[string]string)
+	h.eventSpecs = make(map[string]string)
 }
 
 func (h *GolangProtocolHandler) OnDomain(domain *ProtocolDomain) {
@@ -48,8 +63,8 @@ func (h *GolangProtocolHandler) OnDomain(domain *ProtocolDomain) {
 	for _, tp := range domain.Types {
 		name := toGolangType(tp.Id)
 		h.nameCounts[name]++
-		if tp.Type != "object" {
-			h.simpleTypes[name] = true
+		if tp.Type != "object" || len(tp.Properties) == 0 {
+			h.simpleTypes[domain.Domain+"."+tp.Id] = true
 		}
 	}
 	for _, cmd := range domain.Commands {
@@ -66,6 +81,7 @@ func (h *GolangProtocolHandler) EndProtocol() {
 	for _, domain := range h.domains {
 		h.processDomain(domain)
 	}
+	h.writeCatalog()
 }
 
 func (h *GolangProtocolHandler) processDomain(domain *ProtocolDomain) {
@@ -82,6 +98,7 @@ func (h *GolangProtocolHandler) processDomain(domain *ProtocolDomain) {
 
 	var buf bytes.Buffer
 	h.imports = make(map[string]string)
+	h.inlineBuf.Reset()
 
 	// Types.
 	for _, tp := range domain.Types {
@@ -114,8 +131,10 @@ func (h *GolangProtocolHandler) processDomain(domain *ProtocolDomain) {
 			}
 		}
 		h.imports["encoding/json"] = ""
-		h.imports["github.com/yijinliu/algo-lib/go/src/logging"] = ""
 	}
+
+	// Inline types are only known after everything else is generated.
+	h.inlineBuf.WriteTo(&buf)
 	h.writeGoFile(filepath.Join(dir, strings.ToLower(domain.Domain)+".go"), &buf)
 }
 
@@ -128,8 +147,14 @@ func (h *GolangProtocolHandler) writeGoFile(file string, buf *bytes.Buffer) {
 		logging.Fatal(err)
 	}
 	if len(h.imports) > 0 {
+		paths := make([]string, 0, len(h.imports))
+		for path := range h.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
 		fmt.Fprintf(f, "import(\n")
-		for path, name := range h.imports {
+		for _, path := range paths {
+			name := h.imports[path]
 			if name != "" {
 				fmt.Fprintf(f, "\t%s \"%s\"\n", name, path)
 			} else {
@@ -157,6 +182,17 @@ func experimentalTag(experimental bool) string {
 	return ""
 }
 
+// Joins the non-empty comment lines, so that none gets separated from the declaration.
+func commentLines(lines ...string) string {
+	var nonEmpty []string
+	for _, line := range lines {
+		if line != "" {
+			nonEmpty = append(nonEmpty, line)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
 var descReplacer = strings.NewReplacer("<code>", "", "</code>", "")
 
 func descriptionToGolangComment(desc string) string {
@@ -198,21 +234,23 @@ func (h *GolangProtocolHandler) typeName(domain, name string) string {
 
 var refReplacer = strings.NewReplacer(".", "")
 
+// Refs without domain are relative to domain. Simple types are never referred via pointer, no
+// matter which domain they come from.
 func (h *GolangProtocolHandler) refToGolangType(domain, ref string) string {
-	pos := strings.Index(ref, ".")
-	var golangType string
-	if pos == -1 {
-		golangType = h.typeName(domain, ref)
-	} else {
-		golangType = h.typeName(ref[:pos], ref[pos+1:])
+	if !strings.Contains(ref, ".") {
+		ref = domain + "." + ref
 	}
+	pos := strings.Index(ref, ".")
+	golangType := h.typeName(ref[:pos], ref[pos+1:])
 	if h.simpleTypes[ref] {
 		return golangType
 	}
 	return "*" + golangType
 }
 
-func (h *GolangProtocolHandler) simpleTypeToGolangType(domain string, st *SimpleType) string {
+// owner is the name to use for the struct of an inline object, e.g. "ShapeOutsideInfoShape".
+// Array items get "Item" appended.
+func (h *GolangProtocolHandler) simpleTypeToGolangType(domain, owner string, st *SimpleType) string {
 	switch st.Type {
 	case "":
 		if st.Ref == "" {
@@ -231,25 +269,69 @@ func (h *GolangProtocolHandler) simpleTypeToGolangType(domain string, st *Simple
 	case "boolean":
 		return "bool"
 	case "object":
+		if len(st.Properties) > 0 {
+			h.onInlineType(domain, owner, st)
+			return "*" + owner
+		}
 		return "map[string]string"
+	case "array":
+		if st.Items == nil {
+			logging.Fatalf("Array without items '%v'.", st)
+		}
+		return "[]" + h.simpleTypeToGolangType(domain, owner+"Item", st.Items)
 	}
 	logging.Fatalf("Unknown type '%v'.", st)
 	return ""
 }
 
-func (h *GolangProtocolHandler) unnamedTypeToGolangType(domain string, ut *UnnamedType) string {
-	if ut.Type == "array" {
-		return "[]" + h.simpleTypeToGolangType(domain, ut.Items)
+func (h *GolangProtocolHandler) unnamedTypeToGolangType(domain, owner string, ut *UnnamedType) string {
+	return h.simpleTypeToGolangType(domain, owner, &ut.SimpleType)
+}
+
+func (h *GolangProtocolHandler) onInlineType(domain, name string, st *SimpleType) {
+	if h.nameCounts[name] > 0 {
+		logging.Fatalf("Inline type '%s' conflicts with an existing type.", name)
 	}
-	return h.simpleTypeToGolangType(domain, &ut.SimpleType)
+	h.nameCounts[name]++
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\ntype %s struct {\n", descriptionToGolangComment(st.Description), name)
+	h.writeFields(domain, name, name, st.Properties, true, &buf)
+	buf.WriteString("}\n\n")
+	buf.WriteTo(&h.inlineBuf)
+}
+
+// Returns the []FieldSpec literal of fields for the catalog. protocolOwner is the type id, command
+// or event name the fields belong to, to look up typeOverrides.
+func (h *GolangProtocolHandler) writeFields(domain, owner, protocolOwner string,
+	fields []*NamedType, withOmitEmpty bool, buf *bytes.Buffer) string {
+	var specs bytes.Buffer
+	specs.WriteString("[]FieldSpec{")
+	for _, field := range fields {
+		var omitEmpty string
+		if withOmitEmpty && field.Optional {
+			omitEmpty = ",omitempty"
+		}
+		fieldName := toGolangType(field.Name)
+		golangType, ok := h.typeOverrides[domain+"."+protocolOwner+"."+field.Name]
+		if ok {
+			if strings.Contains(golangType, "json.") {
+				h.imports["encoding/json"] = ""
+			}
+		} else {
+			golangType = h.unnamedTypeToGolangType(domain, owner+fieldName, &field.UnnamedType)
+		}
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s%s\"` %s\n", fieldName, golangType, field.Name,
+			omitEmpty, descriptionToGolangComment(field.Description))
+		fmt.Fprintf(&specs, "{%q, %q, %v}, ", field.Name, golangType, field.Optional)
+	}
+	specs.WriteString("}")
+	return specs.String()
 }
 
 func (h *GolangProtocolHandler) onType(domain string, tp *DomainType, buf *bytes.Buffer) {
 	name := h.typeName(domain, tp.Id)
-	fmt.Fprintf(buf, "%s\n", descriptionToGolangComment(tp.Description))
-	if tp.Experimental {
-		fmt.Fprintln(buf, experimentalTag(tp.Experimental))
-	}
+	fmt.Fprintln(buf, commentLines(descriptionToGolangComment(tp.Description),
+		experimentalTag(tp.Experimental)))
 	switch tp.Type {
 	case "string":
 		fmt.Fprintf(buf, "type %s string\n", name)
@@ -260,20 +342,17 @@ func (h *GolangProtocolHandler) onType(domain string, tp *DomainType, buf *bytes
 		}
 		buf.WriteRune('\n')
 	case "object":
-		fmt.Fprintf(buf, "type %s struct {\n", name)
-		for _, prop := range tp.Properties {
-			var omitEmpty string
-			if prop.Optional {
-				omitEmpty = ",omitempty"
-			}
-			fmt.Fprintf(buf, "\t%s %s `json:\"%s%s\"` %s\n", toGolangType(prop.Name),
-				h.unnamedTypeToGolangType(domain, &prop.UnnamedType), prop.Name, omitEmpty,
-				descriptionToGolangComment(prop.Description))
+		if len(tp.Properties) == 0 {
+			// Free-form object, e.g. Network.Headers.
+			fmt.Fprintf(buf, "type %s map[string]string\n\n", name)
+			break
 		}
+		fmt.Fprintf(buf, "type %s struct {\n", name)
+		h.writeFields(domain, name, tp.Id, tp.Properties, true, buf)
 		buf.WriteString("}\n\n")
 	default:
 		fmt.Fprintf(buf, "type %s %s\n\n", name,
-			h.unnamedTypeToGolangType(domain, &tp.UnnamedType))
+			h.unnamedTypeToGolangType(domain, name, &tp.UnnamedType))
 	}
 }
 
@@ -284,17 +363,11 @@ func (h *GolangProtocolHandler) onCommand(domain string, cmd *DomainCommand, buf
 
 	// Params.
 	var paramsField, paramsParam, paramsAssign, paramsValue, paramsName string
+	paramsSpec, newParams := "nil", "nil"
 	if len(cmd.Parameters) > 0 {
 		fmt.Fprintf(buf, "type %sParams struct {\n", name)
-		for _, param := range cmd.Parameters {
-			var omitEmpty string
-			if param.Optional {
-				omitEmpty = ",omitempty"
-			}
-			fmt.Fprintf(buf, "\t%s %s `json:\"%s%s\"` %s\n", toGolangType(param.Name),
-				h.unnamedTypeToGolangType(domain, &param.UnnamedType), param.Name, omitEmpty,
-				descriptionToGolangComment(param.Description))
-		}
+		paramsSpec = h.writeFields(domain, name+"Params", cmd.Name, cmd.Parameters, true, buf)
+		newParams = fmt.Sprintf("func() interface{} { return &%sParams{} }", name)
 		buf.WriteString("}\n\n")
 		paramsField = fmt.Sprintf("params *%sParams\n", name)
 		paramsParam = fmt.Sprintf("params *%sParams, ", name)
@@ -306,23 +379,32 @@ func (h *GolangProtocolHandler) onCommand(domain string, cmd *DomainCommand, buf
 	}
 
 	// Result.
-	var resultField, resultParam, resultValue string
+	var resultField, resultParam, asyncResultField string
+	runBody := "return cmd.Run(conn)"
+	resultSpec, newResult := "nil", "nil"
 	if len(cmd.Returns) > 0 {
 		fmt.Fprintf(buf, "type %sResult struct {\n", name)
-		for _, ret := range cmd.Returns {
-			fmt.Fprintf(buf, "\t%s %s `json:\"%s\"` %s\n", toGolangType(ret.Name),
-				h.unnamedTypeToGolangType(domain, &ret.UnnamedType), ret.Name,
-				descriptionToGolangComment(ret.Description))
-		}
+		resultSpec = h.writeFields(domain, name+"Result", cmd.Name, cmd.Returns, false, buf)
+		newResult = fmt.Sprintf("func() interface{} { return &%sResult{} }", name)
 		buf.WriteString("}\n")
 		resultField = fmt.Sprintf("result %sResult\n", name)
 		resultParam = fmt.Sprintf("result *%sResult, ", name)
-		resultValue = "&cmd.result, "
+		runBody = `if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil`
+		asyncResultField = fmt.Sprintf("result *%sResult\n", name)
 	}
+	method := domain + "." + cmd.Name
+	h.commandSpecs[method] = fmt.Sprintf("{Method: %q, Params: %s, Results: %s, "+
+		"Experimental: %v, Targets: %s, newParams: %s, newResult: %s}",
+		method, paramsSpec, resultSpec, cmd.Experimental, h.targetKindsExpr(domain, method),
+		newParams, newResult)
 
+	comment := commentLines(descriptionToGolangComment(cmd.Description),
+		experimentalTag(cmd.Experimental))
 	fmt.Fprintf(buf, `
 %s
-%s
 type %sCommand struct {
 	%s%swg sync.WaitGroup
 	err error
@@ -342,30 +424,34 @@ func (cmd *%sCommand) Params() interface{} {
 	return %s
 }
 
-func (cmd *%sCommand) Run(conn *hc.Conn) error {
+func (cmd *%sCommand) Run(conn hc.CommandRunner) error {
 	cmd.wg.Add(1)
-	conn.SendCommand(cmd)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
 	cmd.wg.Wait()
 	return cmd.err
 }
 
-func %s(%sconn *hc.Conn) (%serr error) {
+func %s(%sconn hc.CommandRunner) (%serr error) {
 	cmd := New%sCommand(%s)
-	cmd.Run(conn)
-	return %scmd.err
+	%s
 }
 
 type %sCB func(%serr error)
 
-%s
 %s
 type Async%sCommand struct {
 	%scb %sCB
+	%sdone chan struct{}
+	err error
 }
 
+// cb is optional. See Wait.
 func NewAsync%sCommand(%scb %sCB) *Async%sCommand {
 	return &Async%sCommand{
 		%scb: cb,
+		done: make(chan struct{}),
 	}
 }
 
@@ -377,16 +463,16 @@ func (cmd *Async%sCommand) Params() interface{} {
 	return %s
 }
 `,
-		descriptionToGolangComment(cmd.Description), experimentalTag(cmd.Experimental), // comment
+		comment,
 		name, paramsField, resultField, // struct
 		name, paramsParam, name, name, paramsAssign, // constructor
 		name, domain, cmd.Name, // method Name
 		name, paramsValue, // method Params
-		name,                                                          // method Run
-		name, paramsParam, resultParam, name, paramsName, resultValue, // func Run
+		name,                                                      // method Run
+		name, paramsParam, resultParam, name, paramsName, runBody, // func Run
 		name, resultParam, // CB
-		descriptionToGolangComment(cmd.Description), experimentalTag(cmd.Experimental), // comment
-		name, paramsField, name, // struct
+		comment,
+		name, paramsField, name, asyncResultField, // struct
 		name, paramsParam, name, name, name, paramsAssign, // constructor
 		name, domain, cmd.Name, // method Name
 		name, paramsValue) // method Params
@@ -410,17 +496,27 @@ func (cmd *Async%sCommand) Done(data []byte, err error) {
 	if err == nil {
 		err = json.Unmarshal(data, &result)
 	}
-	if cmd.cb == nil {
-		logging.Vlog(-1, err)
-	} else if err != nil {
-		cmd.cb(nil, err)
-	} else {
-		cmd.cb(&result, nil)
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
 	}
 }
-`, name, name, name, name, name)
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *Async%sCommand) Wait(ctx context.Context) (*%sResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+`, name, name, name, name, name, name, name)
 		h.imports["encoding/json"] = ""
-		h.imports["github.com/yijinliu/algo-lib/go/src/logging"] = ""
 	} else {
 		fmt.Fprintf(buf, `
 func (cmd *%sCommand) Done(data []byte, err error) {
@@ -429,36 +525,338 @@ func (cmd *%sCommand) Done(data []byte, err error) {
 }
 
 func (cmd *Async%sCommand) Done(data []byte, err error) {
-	cmd.cb(err)
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
 }
-`, name, name)
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *Async%sCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+`, name, name, name)
+	}
+
+	var asyncParams string
+	if paramsName != "" {
+		asyncParams = "cmd.params, "
 	}
+	fmt.Fprintf(buf, `
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *%sCommand) Send(conn hc.CommandRunner) *Async%sCommand {
+	async := NewAsync%sCommand(%snil)
+	conn.SendCommand(async)
+	return async
+}
+`, name, name, name, asyncParams)
+	h.imports["context"] = ""
 }
 
 func (h *GolangProtocolHandler) onEvent(domain string, evt *DomainEvent, buf *bytes.Buffer) {
 	name := h.typeName(domain, evt.Name)
 
 	// Params.
-	fmt.Fprintf(buf, "%s\n%s\ntype %sEvent struct {\n", descriptionToGolangComment(evt.Description),
-		experimentalTag(evt.Experimental), name)
-	for _, param := range evt.Parameters {
-		fmt.Fprintf(buf, "\t%s %s `json:\"%s\"` %s\n", toGolangType(param.Name),
-			h.unnamedTypeToGolangType(domain, &param.UnnamedType), param.Name,
-			descriptionToGolangComment(param.Description))
-	}
+	fmt.Fprintf(buf, "%s\ntype %sEvent struct {\n", commentLines(
+		descriptionToGolangComment(evt.Description), experimentalTag(evt.Experimental)), name)
+	paramsSpec := h.writeFields(domain, name+"Event", evt.Name, evt.Parameters, false, buf)
 	buf.WriteString("}\n\n")
+	method := domain + "." + evt.Name
+	h.eventSpecs[method] = fmt.Sprintf("{Method: %q, Params: %s, Experimental: %v, Targets: %s, "+
+		"newEvent: func() interface{} { return &%sEvent{} }}",
+		method, paramsSpec, evt.Experimental, h.targetKindsExpr(domain, method), name)
 
 	fmt.Fprintf(buf, `
-func On%s(conn *hc.Conn, cb func(evt *%sEvent)) {
+// Calls cb for each %s.%s event, till the returned func is called.
+func On%s(conn hc.EventSource, cb func(evt *%sEvent)) (remove func()) {
 	sink := hc.FuncToEventSink(func(name string, params []byte) {
 		evt := &%sEvent{}
 		if err := json.Unmarshal(params, evt); err != nil {
-			logging.Vlog(-1, err)
+			conn.ReportEventError(name, params, err)
 		} else {
 			cb(evt)
 		}
 	})
 	conn.AddEventSink("%s.%s", sink)
+	return func() { conn.RemoveEventSink("%s.%s", sink) }
 }
-`, name, name, name, domain, evt.Name)
+`, domain, evt.Name, name, name, name, domain, evt.Name, domain, evt.Name)
+
+	fmt.Fprintf(buf, `
+// Like On%s, but cb also gets hc.EventMeta.
+func On%sMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *%sEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &%sEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("%s.%s", sink)
+	return func() { conn.RemoveEventSink("%s.%s", sink) }
+}
+`, name, name, name, name, domain, evt.Name, domain, evt.Name)
+
+	if stickyEvents[domain+"."+evt.Name] {
+		fmt.Fprintf(buf, `
+// Like On%s, but cb is called right away if the event already fired for the current document.
+func On%sSticky(conn *hc.Conn, cb func(evt *%sEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &%sEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddStickyEventSink("%s.%s", sink)
+	return func() { conn.RemoveEventSink("%s.%s", sink) }
+}
+`, name, name, name, name, domain, evt.Name, domain, evt.Name)
+	}
+}
+
+// Target kinds supporting each domain, as of Chromium 57. The protocol definitions don't say,
+// so domains missing here are assumed to be for pages only. Commands and events which differ
+// from their domain are in target_kinds.json.
+var domainTargetKinds = map[string][]string{
+	"Console":      {"page", "worker"},
+	"Debugger":     {"page", "worker"},
+	"HeapProfiler": {"page", "worker"},
+	"IO":           {"browser", "page"},
+	"Log":          {"page", "worker"},
+	"Network":      {"page", "worker"},
+	"Profiler":     {"page", "worker"},
+	"Runtime":      {"page", "worker"},
+	"Schema":       {"browser", "page", "worker"},
+	"SystemInfo":   {"browser"},
+	"Target":       {"browser", "page"},
+	"Tethering":    {"browser"},
+	"Tracing":      {"browser", "page"},
+}
+
+var targetKindConsts = map[string]string{
+	"browser": "hc.TargetBrowser",
+	"page":    "hc.TargetPage",
+	"worker":  "hc.TargetWorker",
+}
+
+// Returns the hc.TargetKinds expression of the targets supporting method of domain.
+func (h *GolangProtocolHandler) targetKindsExpr(domain, method string) string {
+	kinds, ok := h.targetKinds[method]
+	if !ok {
+		if kinds, ok = domainTargetKinds[domain]; !ok {
+			kinds = []string{"page"}
+		}
+	}
+	consts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		if consts[i] = targetKindConsts[kind]; consts[i] == "" {
+			logging.Fatalf("Unknown target kind '%s' of %s.", kind, method)
+		}
+	}
+	return strings.Join(consts, " | ")
+}
+
+// Events remembered by hc.Conn. See Conn.AddStickyEventSink.
+var stickyEvents = map[string]bool{
+	"Page.loadEventFired":       true,
+	"Page.domContentEventFired": true,
+	"Page.frameStoppedLoading":  true,
+}
+
+// Static part of catalog.go.
+const catalogTypes = `
+// A field of params, results or events.
+type FieldSpec struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+type CommandSpec struct {
+	Method          string
+	Params, Results []FieldSpec
+	Experimental    bool
+	// Kinds of targets supporting the command.
+	Targets hc.TargetKinds
+
+	newParams, newResult func() interface{}
+}
+
+type EventSpec struct {
+	Method       string
+	Params       []FieldSpec
+	Experimental bool
+	// Kinds of targets firing the event.
+	Targets hc.TargetKinds
+
+	newEvent func() interface{}
+}
+
+// A command built at runtime by NewCommandFromJSON.
+type DynamicCommand struct {
+	method string
+	params interface{}
+	result interface{}
+	wg     sync.WaitGroup
+	err    error
+}
+
+// Builds the command of method, with params in JSON. The result is unmarshaled into the type
+// generated for it, e.g. *NavigateResult.
+func NewCommandFromJSON(method string, params json.RawMessage) (*DynamicCommand, error) {
+	spec := Commands[method]
+	if spec == nil {
+		return nil, fmt.Errorf("Unknown command '%s'", method)
+	}
+	cmd := &DynamicCommand{method: method}
+	if spec.newParams != nil {
+		cmd.params = spec.newParams()
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, cmd.params); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if spec.newResult != nil {
+		cmd.result = spec.newResult()
+	}
+	return cmd, nil
+}
+
+func (cmd *DynamicCommand) Name() string {
+	return cmd.method
+}
+
+func (cmd *DynamicCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *DynamicCommand) Done(data []byte, err error) {
+	if err == nil && cmd.result != nil {
+		err = json.Unmarshal(data, cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+// Runs the command and returns its result, which is nil if the command has no result.
+func (cmd *DynamicCommand) Run(conn hc.CommandRunner) (interface{}, error) {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return nil, err
+	}
+	cmd.wg.Wait()
+	return cmd.result, cmd.err
+}
+
+// Unmarshals params of event method into the type generated for it, e.g. *LoadEventFiredEvent.
+func DecodeEvent(method string, params json.RawMessage) (interface{}, error) {
+	spec := Events[method]
+	if spec == nil {
+		return nil, fmt.Errorf("Unknown event '%s'", method)
+	}
+	evt := spec.newEvent()
+	if err := json.Unmarshal(params, evt); err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+func init() {
+	hc.SetSchemaChecker(checkSchema)
+	hc.SetTargetKindsFunc(targetKinds)
+}
+
+// Returns the kinds of targets supporting command or event method, or 0 if it's unknown.
+func targetKinds(method string) hc.TargetKinds {
+	if spec := Commands[method]; spec != nil {
+		return spec.Targets
+	}
+	if spec := Events[method]; spec != nil {
+		return spec.Targets
+	}
+	return 0
+}
+
+// Checks a command result or event for unknown fields at any level, and for missing required
+// fields at the top level.
+func checkSchema(method string, event bool, data []byte) *hc.SchemaError {
+	var fields []FieldSpec
+	var v interface{}
+	if event {
+		spec := Events[method]
+		if spec == nil {
+			return nil
+		}
+		fields, v = spec.Params, spec.newEvent()
+	} else {
+		spec := Commands[method]
+		if spec == nil || spec.newResult == nil {
+			return nil
+		}
+		fields, v = spec.Results, spec.newResult()
+	}
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var field string
+		if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+			field = strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), "\"")
+		}
+		return &hc.SchemaError{Method: method, Event: event, Field: field, Err: err}
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return &hc.SchemaError{Method: method, Event: event, Err: err}
+	}
+	for _, field := range fields {
+		if _, ok := present[field.Name]; !ok && !field.Optional {
+			return &hc.SchemaError{Method: method, Event: event, Field: field.Name,
+				Err: fmt.Errorf("Missing required field")}
+		}
+	}
+	return nil
+}
+`
+
+// Writes catalog.go, which describes all commands and events generated.
+func (h *GolangProtocolHandler) writeCatalog() {
+	var buf bytes.Buffer
+	buf.WriteString(catalogTypes)
+	for _, specs := range []struct {
+		name, tp string
+		specMap  map[string]string
+	}{{"Commands", "CommandSpec", h.commandSpecs}, {"Events", "EventSpec", h.eventSpecs}} {
+		methods := make([]string, 0, len(specs.specMap))
+		for method := range specs.specMap {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		fmt.Fprintf(&buf, "\nvar %s = map[string]*%s{\n", specs.name, specs.tp)
+		for _, method := range methods {
+			fmt.Fprintf(&buf, "\t%q: %s,\n", method, specs.specMap[method])
+		}
+		buf.WriteString("}\n")
+	}
+	h.imports = map[string]string{
+		"bytes":         "",
+		"encoding/json": "",
+		"fmt":           "",
+		"strings":       "",
+		"sync":          "",
+		"github.com/yijinliu/headless-chromium/go": "hc",
+	}
+	h.writeGoFile(filepath.Join(h.outputDir, "v"+h.curVersion, "catalog.go"), &buf)
 }
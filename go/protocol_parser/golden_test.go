@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateFlag = flag.Bool("update", false, "Rewrite the golden files under testdata/golden.")
+
+const (
+	trimmedProtocolFile = "testdata/trimmed_protocol.json"
+	goldenDir           = "testdata/golden"
+)
+
+// Generates the bindings and the reference of trimmed_protocol.json, which has an instance of
+// each construct the generator handles, into dir.
+func generateTrimmed(t *testing.T, dir string) {
+	typeOverrides := map[string]string{"Network.Cookie.expires": "json.Number"}
+	targetKinds := map[string][]string{"Page.loadEventFired": {"page", "worker"}}
+	phs := map[string]ProtocolHandler{
+		"golang": NewGolangProtocolHandler(filepath.Join(dir, "protocol"), true, typeOverrides,
+			targetKinds),
+		"docs": NewDocsProtocolHandler(filepath.Join(dir, "docs")),
+	}
+	if err := generate([]string{trimmedProtocolFile}, phs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns the files under dir, keyed by their paths relative to dir.
+func readTree(t *testing.T, dir string) map[string][]byte {
+	files := make(map[string][]byte)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)], err = ioutil.ReadFile(path)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// Run with -update to accept changes of the output, after checking them.
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "protocol_parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	generateTrimmed(t, dir)
+	generated := readTree(t, dir)
+	if *updateFlag {
+		if err := os.RemoveAll(goldenDir); err != nil {
+			t.Fatal(err)
+		}
+		for rel, content := range generated {
+			path := filepath.Join(goldenDir, rel)
+			if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, content, os.FileMode(0644)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+
+	golden := readTree(t, goldenDir)
+	for rel, content := range generated {
+		if want, ok := golden[rel]; !ok {
+			t.Errorf("%s isn't expected", rel)
+		} else if !bytes.Equal(content, want) {
+			t.Errorf("%s differs from the golden file. Run with -update if that's expected, "+
+				"then check the diff:\n%s", rel, content)
+		}
+	}
+	for rel := range golden {
+		if _, ok := generated[rel]; !ok {
+			t.Errorf("%s isn't generated", rel)
+		}
+	}
+	for rel, content := range generated {
+		if bytes.Contains(content, []byte("TODO")) {
+			t.Errorf("%s has a TODO", rel)
+		}
+	}
+}
+
+// Generating twice gives the same output, even though domains and specs are kept in maps.
+func TestDeterministic(t *testing.T) {
+	var outputs []map[string][]byte
+	for i := 0; i < 2; i++ {
+		dir, err := ioutil.TempDir("", "protocol_parser")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		generateTrimmed(t, dir)
+		outputs = append(outputs, readTree(t, dir))
+	}
+	if len(outputs[0]) != len(outputs[1]) {
+		t.Fatalf("Generated %d files, then %d", len(outputs[0]), len(outputs[1]))
+	}
+	for rel, content := range outputs[0] {
+		if !bytes.Equal(content, outputs[1][rel]) {
+			t.Errorf("%s differs between runs", rel)
+		}
+	}
+}
+
+// Refs across domains resolve, inline objects in arrays get named types, and free-form objects
+// become maps.
+func TestConstructs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "protocol_parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	generateTrimmed(t, dir)
+	files := readTree(t, dir)
+	for file, snippets := range map[string][]string{
+		"protocol/v1.2/page.go": {"Cookie *Cookie `json:\"cookie\"`"},
+		"protocol/v1.2/dom.go": {
+			"Object *RemoteObject `json:\"object\"`",
+			"ObjectId RemoteObjectId `json:\"objectId\"`",
+			"Shape []*ShapeOutsideInfoShapeItem `json:\"shape\"`",
+			"type ShapeOutsideInfoShapeItem struct",
+			"MarginShape []json.RawMessage",
+		},
+		"protocol/v1.2/network.go": {
+			"type Headers map[string]string",
+			"Expires json.Number",
+			"const CookieSameSiteStrict CookieSameSite = \"Strict\"",
+		},
+		"docs/v1.2/dom.md": {"[Runtime.RemoteObject](runtime.md#Runtime.RemoteObject)"},
+	} {
+		content := strings.Join(strings.Fields(string(files[file])), " ")
+		for _, snippet := range snippets {
+			if !strings.Contains(content, strings.Join(strings.Fields(snippet), " ")) {
+				t.Errorf("%s has no %s", file, snippet)
+			}
+		}
+	}
+}
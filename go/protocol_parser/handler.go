@@ -6,15 +6,17 @@ type ProtocolVersion struct {
 }
 
 type SimpleType struct {
-	Type        string `json:"type"`
-	Ref         string `json:"$ref"`
-	Description string `json:"description"`
+	Type        string       `json:"type"`
+	Ref         string       `json:"$ref"`
+	Description string       `json:"description"`
+	Items       *SimpleType  `json:"items"`      // For arrays.
+	Properties  []*NamedType `json:"properties"` // For inline objects.
+	Enum        []string     `json:"enum"`
 }
 
 type UnnamedType struct {
 	SimpleType
-	Items    *SimpleType `json:"items"`
-	Optional bool        `json:"optional"`
+	Optional bool `json:"optional"`
 }
 
 type NamedType struct {
@@ -22,15 +24,15 @@ type NamedType struct {
 	Name         string `json:"name"`
 	Optional     bool   `json:"optional"`
 	Experimental bool   `json:"experimental"`
+	Deprecated   bool   `json:"deprecated"`
 }
 
 type DomainType struct {
 	UnnamedType
-	Id           string       `json:"id"`
-	Enum         []string     `json:"enum"`
-	Properties   []*NamedType `json:"properties"`
-	Experimental bool         `json:"experimental"`
-	Exported     bool         `json:"exported"`
+	Id           string `json:"id"`
+	Experimental bool   `json:"experimental"`
+	Deprecated   bool   `json:"deprecated"`
+	Exported     bool   `json:"exported"`
 }
 
 type DomainCommand struct {
@@ -41,6 +43,7 @@ type DomainCommand struct {
 	Handlers     []string     `json:"handlers"`
 	Redirect     string       `json:"redirect"`
 	Experimental bool         `json:"experimental"`
+	Deprecated   bool         `json:"deprecated"`
 	Async        bool         `json:"async"`
 }
 
@@ -50,11 +53,14 @@ type DomainEvent struct {
 	Parameters   []*NamedType `json:"parameters"`
 	Handlers     []string     `json:"handlers"`
 	Experimental bool         `json:"experimental"`
+	Deprecated   bool         `json:"deprecated"`
 }
 
 type ProtocolDomain struct {
 	Domain       string           `json:"domain"`
 	Experimental bool             `json:"experimental"`
+	Deprecated   bool             `json:"deprecated"`
+	Description  string           `json:"description"`
 	Types        []*DomainType    `json:"types"`
 	Commands     []*DomainCommand `json:"commands"`
 	Events       []*DomainEvent   `json:"events"`
@@ -1,3 +1,5 @@
+// Generates the protocol bindings from protocol JSON definition files. See testdata/README.md
+// for which files the committed ones are generated from.
 package main
 
 import (
@@ -5,17 +7,29 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/yijinliu/algo-lib/go/src/logging"
 )
 
 var outputLangsFlag = flag.String("output-langs", "golang",
-	"Languages separated by comma. Only golang supported for now.")
+	"Languages separated by comma: golang for the Go bindings, docs for their Markdown "+
+		"reference.")
 
 var golangOutputDirFlag = flag.String("golang-output-dir",
 	"src/github.com/yijinliu/headless-chromium/go/protocol", "")
+var docsOutputDirFlag = flag.String("docs-output-dir", "docs/protocol",
+	"Directory of the reference written for --output-langs=docs. The Go bindings refer to it as "+
+		"docs/protocol, relative to the repo root.")
 var golangHandleExperimentalFlag = flag.Bool("golang-handle-experimental", true, "")
+var golangTypeOverridesFlag = flag.String("golang-type-overrides",
+	"src/github.com/yijinliu/headless-chromium/go/protocol_parser/type_overrides.json",
+	"JSON file of Go types overriding the generated ones for some fields. Empty for none.")
+var golangTargetKindsFlag = flag.String("golang-target-kinds",
+	"src/github.com/yijinliu/headless-chromium/go/protocol_parser/target_kinds.json",
+	"JSON file of target kinds overriding those of the domain for some commands and events. "+
+		"Empty for none.")
 
 func main() {
 	flag.Parse()
@@ -25,28 +39,55 @@ func main() {
 	if outputLangs == "" {
 		logging.Fatal("Please specify --output-langs.")
 	}
+	typeOverrides := make(map[string]string)
+	if *golangTypeOverridesFlag != "" {
+		if content, err := ioutil.ReadFile(*golangTypeOverridesFlag); err != nil {
+			logging.Fatal(err)
+		} else if err := json.Unmarshal(content, &typeOverrides); err != nil {
+			logging.Fatal(err)
+		}
+	}
+	targetKinds := make(map[string][]string)
+	if *golangTargetKindsFlag != "" {
+		if content, err := ioutil.ReadFile(*golangTargetKindsFlag); err != nil {
+			logging.Fatal(err)
+		} else if err := json.Unmarshal(content, &targetKinds); err != nil {
+			logging.Fatal(err)
+		}
+	}
 	phs := map[string]ProtocolHandler{}
 	for _, lang := range strings.Split(outputLangs, ",") {
 		switch lang {
 		case "golang":
 			phs[lang] =
-				NewGolangProtocolHandler(*golangOutputDirFlag, *golangHandleExperimentalFlag)
+				NewGolangProtocolHandler(*golangOutputDirFlag, *golangHandleExperimentalFlag,
+					typeOverrides, targetKinds)
+		case "docs":
+			phs[lang] = NewDocsProtocolHandler(*docsOutputDirFlag)
 		default:
 			logging.Fatal("Unknown language: ", lang)
 		}
 	}
 
+	if err := generate(flag.Args(), phs); err != nil {
+		logging.Fatal(err)
+	}
+}
+
+// Parses the protocol JSON definition files, and passes their domains to each handler in a
+// stable order, so regenerating produces no diff when nothing changed.
+func generate(files []string, phs map[string]ProtocolHandler) error {
 	// <protocol version => <domain name => domain> >
 	protocolMap := make(map[string]map[string]*ProtocolDomain)
 
 	// Parse protocol JSON definition files
-	for _, pf := range flag.Args() {
+	for _, pf := range files {
 		logging.Vlogf(1, "Processing '%s' ...", pf)
 		var protocol Protocol
 		if content, err := ioutil.ReadFile(pf); err != nil {
-			logging.Fatal(err)
+			return err
 		} else if err := json.Unmarshal(content, &protocol); err != nil {
-			logging.Fatal(err)
+			return fmt.Errorf("Failed to parse %s: %v", pf, err)
 		}
 		version := fmt.Sprintf("%s.%s", protocol.Version.Major, protocol.Version.Minor)
 		domainMap := protocolMap[version]
@@ -63,15 +104,32 @@ func main() {
 		}
 	}
 
-	// Process.
-	for version, domainMap := range protocolMap {
-		for lang, ph := range phs {
+	versions := make([]string, 0, len(protocolMap))
+	for version := range protocolMap {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	langs := make([]string, 0, len(phs))
+	for lang := range phs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, version := range versions {
+		domainMap := protocolMap[version]
+		domainNames := make([]string, 0, len(domainMap))
+		for name := range domainMap {
+			domainNames = append(domainNames, name)
+		}
+		sort.Strings(domainNames)
+		for _, lang := range langs {
+			ph := phs[lang]
 			logging.Vlogf(1, "Generating protocol v%s for %s ...", version, lang)
 			ph.StartProtocol(version)
-			for _, domain := range domainMap {
-				ph.OnDomain(domain)
+			for _, name := range domainNames {
+				ph.OnDomain(domainMap[name])
 			}
 			ph.EndProtocol()
 		}
 	}
+	return nil
 }
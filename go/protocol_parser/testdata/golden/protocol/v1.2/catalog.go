@@ -0,0 +1,182 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"strings"
+	"sync"
+)
+
+// A field of params, results or events.
+type FieldSpec struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+type CommandSpec struct {
+	Method          string
+	Params, Results []FieldSpec
+	Experimental    bool
+	// Kinds of targets supporting the command.
+	Targets hc.TargetKinds
+
+	newParams, newResult func() interface{}
+}
+
+type EventSpec struct {
+	Method       string
+	Params       []FieldSpec
+	Experimental bool
+	// Kinds of targets firing the event.
+	Targets hc.TargetKinds
+
+	newEvent func() interface{}
+}
+
+// A command built at runtime by NewCommandFromJSON.
+type DynamicCommand struct {
+	method string
+	params interface{}
+	result interface{}
+	wg     sync.WaitGroup
+	err    error
+}
+
+// Builds the command of method, with params in JSON. The result is unmarshaled into the type
+// generated for it, e.g. *NavigateResult.
+func NewCommandFromJSON(method string, params json.RawMessage) (*DynamicCommand, error) {
+	spec := Commands[method]
+	if spec == nil {
+		return nil, fmt.Errorf("Unknown command '%s'", method)
+	}
+	cmd := &DynamicCommand{method: method}
+	if spec.newParams != nil {
+		cmd.params = spec.newParams()
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, cmd.params); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if spec.newResult != nil {
+		cmd.result = spec.newResult()
+	}
+	return cmd, nil
+}
+
+func (cmd *DynamicCommand) Name() string {
+	return cmd.method
+}
+
+func (cmd *DynamicCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *DynamicCommand) Done(data []byte, err error) {
+	if err == nil && cmd.result != nil {
+		err = json.Unmarshal(data, cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+// Runs the command and returns its result, which is nil if the command has no result.
+func (cmd *DynamicCommand) Run(conn hc.CommandRunner) (interface{}, error) {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return nil, err
+	}
+	cmd.wg.Wait()
+	return cmd.result, cmd.err
+}
+
+// Unmarshals params of event method into the type generated for it, e.g. *LoadEventFiredEvent.
+func DecodeEvent(method string, params json.RawMessage) (interface{}, error) {
+	spec := Events[method]
+	if spec == nil {
+		return nil, fmt.Errorf("Unknown event '%s'", method)
+	}
+	evt := spec.newEvent()
+	if err := json.Unmarshal(params, evt); err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+func init() {
+	hc.SetSchemaChecker(checkSchema)
+	hc.SetTargetKindsFunc(targetKinds)
+}
+
+// Returns the kinds of targets supporting command or event method, or 0 if it's unknown.
+func targetKinds(method string) hc.TargetKinds {
+	if spec := Commands[method]; spec != nil {
+		return spec.Targets
+	}
+	if spec := Events[method]; spec != nil {
+		return spec.Targets
+	}
+	return 0
+}
+
+// Checks a command result or event for unknown fields at any level, and for missing required
+// fields at the top level.
+func checkSchema(method string, event bool, data []byte) *hc.SchemaError {
+	var fields []FieldSpec
+	var v interface{}
+	if event {
+		spec := Events[method]
+		if spec == nil {
+			return nil
+		}
+		fields, v = spec.Params, spec.newEvent()
+	} else {
+		spec := Commands[method]
+		if spec == nil || spec.newResult == nil {
+			return nil
+		}
+		fields, v = spec.Results, spec.newResult()
+	}
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var field string
+		if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+			field = strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), "\"")
+		}
+		return &hc.SchemaError{Method: method, Event: event, Field: field, Err: err}
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return &hc.SchemaError{Method: method, Event: event, Err: err}
+	}
+	for _, field := range fields {
+		if _, ok := present[field.Name]; !ok && !field.Optional {
+			return &hc.SchemaError{Method: method, Event: event, Field: field.Name,
+				Err: fmt.Errorf("Missing required field")}
+		}
+	}
+	return nil
+}
+
+var Commands = map[string]*CommandSpec{
+	"DOM.requestNode":           {Method: "DOM.requestNode", Params: []FieldSpec{{"objectId", "RemoteObjectId", false}}, Results: []FieldSpec{{"nodeId", "NodeId", false}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &RequestNodeParams{} }, newResult: func() interface{} { return &RequestNodeResult{} }},
+	"DOM.resolveNode":           {Method: "DOM.resolveNode", Params: []FieldSpec{{"nodeId", "NodeId", false}, {"objectGroup", "string", true}}, Results: []FieldSpec{{"object", "*RemoteObject", false}}, Experimental: false, Targets: hc.TargetPage, newParams: func() interface{} { return &ResolveNodeParams{} }, newResult: func() interface{} { return &ResolveNodeResult{} }},
+	"Network.clearBrowserCache": {Method: "Network.clearBrowserCache", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: nil, newResult: nil},
+	"Network.getCookies":        {Method: "Network.getCookies", Params: []FieldSpec{{"urls", "[]string", true}}, Results: []FieldSpec{{"cookies", "[]*Cookie", false}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newParams: func() interface{} { return &GetCookiesParams{} }, newResult: func() interface{} { return &GetCookiesResult{} }},
+	"Page.enable":               {Method: "Page.enable", Params: nil, Results: nil, Experimental: false, Targets: hc.TargetPage, newParams: nil, newResult: nil},
+	"Page.setCookie":            {Method: "Page.setCookie", Params: []FieldSpec{{"cookie", "*Cookie", false}}, Results: []FieldSpec{{"success", "bool", false}}, Experimental: true, Targets: hc.TargetPage, newParams: func() interface{} { return &SetCookieParams{} }, newResult: func() interface{} { return &SetCookieResult{} }},
+	"Tethering.bind":            {Method: "Tethering.bind", Params: []FieldSpec{{"port", "int", false}}, Results: nil, Experimental: false, Targets: hc.TargetBrowser, newParams: func() interface{} { return &BindParams{} }, newResult: nil},
+}
+
+var Events = map[string]*EventSpec{
+	"Network.responseReceived": {Method: "Network.responseReceived", Params: []FieldSpec{{"requestId", "RequestId", false}, {"headers", "Headers", false}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &ResponseReceivedEvent{} }},
+	"Page.loadEventFired":      {Method: "Page.loadEventFired", Params: []FieldSpec{{"timestamp", "float64", false}}, Experimental: false, Targets: hc.TargetPage | hc.TargetWorker, newEvent: func() interface{} { return &LoadEventFiredEvent{} }},
+	"Tethering.accepted":       {Method: "Tethering.accepted", Params: []FieldSpec{{"port", "int", false}, {"connectionId", "string", false}}, Experimental: false, Targets: hc.TargetBrowser, newEvent: func() interface{} { return &AcceptedEvent{} }},
+}
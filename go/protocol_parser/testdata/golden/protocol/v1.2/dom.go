@@ -0,0 +1,260 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"sync"
+)
+
+// Unique DOM node identifier.
+type NodeId int
+
+// CSS Shape Outside details.
+type ShapeOutsideInfo struct {
+	Shape       []*ShapeOutsideInfoShapeItem `json:"shape"`       // Shape bounds
+	MarginShape []json.RawMessage            `json:"marginShape"` // Margin shape bounds
+}
+
+type ResolveNodeParams struct {
+	NodeId      NodeId `json:"nodeId"`                // Id of the node to resolve.
+	ObjectGroup string `json:"objectGroup,omitempty"` // Symbolic group name that can be used to release multiple objects.
+}
+
+type ResolveNodeResult struct {
+	Object *RemoteObject `json:"object"` // JavaScript object wrapper for given node.
+}
+
+// Resolves JavaScript node object for given node id.
+type ResolveNodeCommand struct {
+	params *ResolveNodeParams
+	result ResolveNodeResult
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewResolveNodeCommand(params *ResolveNodeParams) *ResolveNodeCommand {
+	return &ResolveNodeCommand{
+		params: params,
+	}
+}
+
+func (cmd *ResolveNodeCommand) Name() string {
+	return "DOM.resolveNode"
+}
+
+func (cmd *ResolveNodeCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *ResolveNodeCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func ResolveNode(params *ResolveNodeParams, conn hc.CommandRunner) (result *ResolveNodeResult, err error) {
+	cmd := NewResolveNodeCommand(params)
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
+}
+
+type ResolveNodeCB func(result *ResolveNodeResult, err error)
+
+// Resolves JavaScript node object for given node id.
+type AsyncResolveNodeCommand struct {
+	params *ResolveNodeParams
+	cb     ResolveNodeCB
+	result *ResolveNodeResult
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncResolveNodeCommand(params *ResolveNodeParams, cb ResolveNodeCB) *AsyncResolveNodeCommand {
+	return &AsyncResolveNodeCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncResolveNodeCommand) Name() string {
+	return "DOM.resolveNode"
+}
+
+func (cmd *AsyncResolveNodeCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *ResolveNodeCommand) Result() *ResolveNodeResult {
+	return &cmd.result
+}
+
+func (cmd *ResolveNodeCommand) Done(data []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(data, &cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncResolveNodeCommand) Done(data []byte, err error) {
+	var result ResolveNodeResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncResolveNodeCommand) Wait(ctx context.Context) (*ResolveNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ResolveNodeCommand) Send(conn hc.CommandRunner) *AsyncResolveNodeCommand {
+	async := NewAsyncResolveNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+type RequestNodeParams struct {
+	ObjectId RemoteObjectId `json:"objectId"` // JavaScript object id to convert into node.
+}
+
+type RequestNodeResult struct {
+	NodeId NodeId `json:"nodeId"` // Node id for given object.
+}
+
+type RequestNodeCommand struct {
+	params *RequestNodeParams
+	result RequestNodeResult
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewRequestNodeCommand(params *RequestNodeParams) *RequestNodeCommand {
+	return &RequestNodeCommand{
+		params: params,
+	}
+}
+
+func (cmd *RequestNodeCommand) Name() string {
+	return "DOM.requestNode"
+}
+
+func (cmd *RequestNodeCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *RequestNodeCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func RequestNode(params *RequestNodeParams, conn hc.CommandRunner) (result *RequestNodeResult, err error) {
+	cmd := NewRequestNodeCommand(params)
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
+}
+
+type RequestNodeCB func(result *RequestNodeResult, err error)
+
+type AsyncRequestNodeCommand struct {
+	params *RequestNodeParams
+	cb     RequestNodeCB
+	result *RequestNodeResult
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncRequestNodeCommand(params *RequestNodeParams, cb RequestNodeCB) *AsyncRequestNodeCommand {
+	return &AsyncRequestNodeCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncRequestNodeCommand) Name() string {
+	return "DOM.requestNode"
+}
+
+func (cmd *AsyncRequestNodeCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *RequestNodeCommand) Result() *RequestNodeResult {
+	return &cmd.result
+}
+
+func (cmd *RequestNodeCommand) Done(data []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(data, &cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncRequestNodeCommand) Done(data []byte, err error) {
+	var result RequestNodeResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncRequestNodeCommand) Wait(ctx context.Context) (*RequestNodeResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *RequestNodeCommand) Send(conn hc.CommandRunner) *AsyncRequestNodeCommand {
+	async := NewAsyncRequestNodeCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// A point of the shape.
+type ShapeOutsideInfoShapeItem struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
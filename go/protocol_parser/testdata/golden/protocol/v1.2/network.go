@@ -0,0 +1,268 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"sync"
+)
+
+// Unique request identifier.
+type RequestId string
+
+// Request / response headers as keys / values of JSON object.
+type Headers map[string]string
+
+// Represents the cookie's 'SameSite' status.
+// @experimental
+type CookieSameSite string
+
+const CookieSameSiteStrict CookieSameSite = "Strict"
+const CookieSameSiteLax CookieSameSite = "Lax"
+
+// Cookie object
+type Cookie struct {
+	Name     string         `json:"name"`               // Cookie name.
+	Expires  json.Number    `json:"expires"`            // Cookie expiration date as the number of seconds since the UNIX epoch.
+	SameSite CookieSameSite `json:"sameSite,omitempty"` // Cookie SameSite type.
+}
+
+type GetCookiesParams struct {
+	Urls []string `json:"urls,omitempty"` // The list of URLs for which applicable cookies will be fetched
+}
+
+type GetCookiesResult struct {
+	Cookies []*Cookie `json:"cookies"` // Array of cookie objects.
+}
+
+// Returns all browser cookies for the current URL.
+type GetCookiesCommand struct {
+	params *GetCookiesParams
+	result GetCookiesResult
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewGetCookiesCommand(params *GetCookiesParams) *GetCookiesCommand {
+	return &GetCookiesCommand{
+		params: params,
+	}
+}
+
+func (cmd *GetCookiesCommand) Name() string {
+	return "Network.getCookies"
+}
+
+func (cmd *GetCookiesCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *GetCookiesCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func GetCookies(params *GetCookiesParams, conn hc.CommandRunner) (result *GetCookiesResult, err error) {
+	cmd := NewGetCookiesCommand(params)
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
+}
+
+type GetCookiesCB func(result *GetCookiesResult, err error)
+
+// Returns all browser cookies for the current URL.
+type AsyncGetCookiesCommand struct {
+	params *GetCookiesParams
+	cb     GetCookiesCB
+	result *GetCookiesResult
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncGetCookiesCommand(params *GetCookiesParams, cb GetCookiesCB) *AsyncGetCookiesCommand {
+	return &AsyncGetCookiesCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncGetCookiesCommand) Name() string {
+	return "Network.getCookies"
+}
+
+func (cmd *AsyncGetCookiesCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *GetCookiesCommand) Result() *GetCookiesResult {
+	return &cmd.result
+}
+
+func (cmd *GetCookiesCommand) Done(data []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(data, &cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncGetCookiesCommand) Done(data []byte, err error) {
+	var result GetCookiesResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncGetCookiesCommand) Wait(ctx context.Context) (*GetCookiesResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *GetCookiesCommand) Send(conn hc.CommandRunner) *AsyncGetCookiesCommand {
+	async := NewAsyncGetCookiesCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Clears browser cache.
+type ClearBrowserCacheCommand struct {
+	wg  sync.WaitGroup
+	err error
+}
+
+func NewClearBrowserCacheCommand() *ClearBrowserCacheCommand {
+	return &ClearBrowserCacheCommand{}
+}
+
+func (cmd *ClearBrowserCacheCommand) Name() string {
+	return "Network.clearBrowserCache"
+}
+
+func (cmd *ClearBrowserCacheCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *ClearBrowserCacheCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func ClearBrowserCache(conn hc.CommandRunner) (err error) {
+	cmd := NewClearBrowserCacheCommand()
+	return cmd.Run(conn)
+}
+
+type ClearBrowserCacheCB func(err error)
+
+// Clears browser cache.
+type AsyncClearBrowserCacheCommand struct {
+	cb   ClearBrowserCacheCB
+	done chan struct{}
+	err  error
+}
+
+// cb is optional. See Wait.
+func NewAsyncClearBrowserCacheCommand(cb ClearBrowserCacheCB) *AsyncClearBrowserCacheCommand {
+	return &AsyncClearBrowserCacheCommand{
+		cb:   cb,
+		done: make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncClearBrowserCacheCommand) Name() string {
+	return "Network.clearBrowserCache"
+}
+
+func (cmd *AsyncClearBrowserCacheCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *ClearBrowserCacheCommand) Done(data []byte, err error) {
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncClearBrowserCacheCommand) Done(data []byte, err error) {
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncClearBrowserCacheCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *ClearBrowserCacheCommand) Send(conn hc.CommandRunner) *AsyncClearBrowserCacheCommand {
+	async := NewAsyncClearBrowserCacheCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Fired when HTTP response is available.
+type ResponseReceivedEvent struct {
+	RequestId RequestId `json:"requestId"` // Request identifier.
+	Headers   Headers   `json:"headers"`   // HTTP response headers.
+}
+
+// Calls cb for each Network.responseReceived event, till the returned func is called.
+func OnResponseReceived(conn hc.EventSource, cb func(evt *ResponseReceivedEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &ResponseReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddEventSink("Network.responseReceived", sink)
+	return func() { conn.RemoveEventSink("Network.responseReceived", sink) }
+}
+
+// Like OnResponseReceived, but cb also gets hc.EventMeta.
+func OnResponseReceivedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *ResponseReceivedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &ResponseReceivedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Network.responseReceived", sink)
+	return func() { conn.RemoveEventSink("Network.responseReceived", sink) }
+}
@@ -0,0 +1,265 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"sync"
+)
+
+// Unique frame identifier.
+type FrameId string
+
+// Enables page domain notifications.
+type EnableCommand struct {
+	wg  sync.WaitGroup
+	err error
+}
+
+func NewEnableCommand() *EnableCommand {
+	return &EnableCommand{}
+}
+
+func (cmd *EnableCommand) Name() string {
+	return "Page.enable"
+}
+
+func (cmd *EnableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *EnableCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func Enable(conn hc.CommandRunner) (err error) {
+	cmd := NewEnableCommand()
+	return cmd.Run(conn)
+}
+
+type EnableCB func(err error)
+
+// Enables page domain notifications.
+type AsyncEnableCommand struct {
+	cb   EnableCB
+	done chan struct{}
+	err  error
+}
+
+// cb is optional. See Wait.
+func NewAsyncEnableCommand(cb EnableCB) *AsyncEnableCommand {
+	return &AsyncEnableCommand{
+		cb:   cb,
+		done: make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncEnableCommand) Name() string {
+	return "Page.enable"
+}
+
+func (cmd *AsyncEnableCommand) Params() interface{} {
+	return nil
+}
+
+func (cmd *EnableCommand) Done(data []byte, err error) {
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncEnableCommand) Done(data []byte, err error) {
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncEnableCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *EnableCommand) Send(conn hc.CommandRunner) *AsyncEnableCommand {
+	async := NewAsyncEnableCommand(nil)
+	conn.SendCommand(async)
+	return async
+}
+
+type SetCookieParams struct {
+	Cookie *Cookie `json:"cookie"`
+}
+
+type SetCookieResult struct {
+	Success bool `json:"success"` // True if successfully set cookie.
+}
+
+// Refers to a type of another domain.
+// @experimental
+type SetCookieCommand struct {
+	params *SetCookieParams
+	result SetCookieResult
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewSetCookieCommand(params *SetCookieParams) *SetCookieCommand {
+	return &SetCookieCommand{
+		params: params,
+	}
+}
+
+func (cmd *SetCookieCommand) Name() string {
+	return "Page.setCookie"
+}
+
+func (cmd *SetCookieCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *SetCookieCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func SetCookie(params *SetCookieParams, conn hc.CommandRunner) (result *SetCookieResult, err error) {
+	cmd := NewSetCookieCommand(params)
+	if err := cmd.Run(conn); err != nil {
+		return nil, err
+	}
+	return &cmd.result, nil
+}
+
+type SetCookieCB func(result *SetCookieResult, err error)
+
+// Refers to a type of another domain.
+// @experimental
+type AsyncSetCookieCommand struct {
+	params *SetCookieParams
+	cb     SetCookieCB
+	result *SetCookieResult
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncSetCookieCommand(params *SetCookieParams, cb SetCookieCB) *AsyncSetCookieCommand {
+	return &AsyncSetCookieCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncSetCookieCommand) Name() string {
+	return "Page.setCookie"
+}
+
+func (cmd *AsyncSetCookieCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *SetCookieCommand) Result() *SetCookieResult {
+	return &cmd.result
+}
+
+func (cmd *SetCookieCommand) Done(data []byte, err error) {
+	if err == nil {
+		err = json.Unmarshal(data, &cmd.result)
+	}
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncSetCookieCommand) Done(data []byte, err error) {
+	var result SetCookieResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err == nil {
+		cmd.result = &result
+	}
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(cmd.result, err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncSetCookieCommand) Wait(ctx context.Context) (*SetCookieResult, error) {
+	select {
+	case <-cmd.done:
+		return cmd.result, cmd.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *SetCookieCommand) Send(conn hc.CommandRunner) *AsyncSetCookieCommand {
+	async := NewAsyncSetCookieCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+type LoadEventFiredEvent struct {
+	Timestamp float64 `json:"timestamp"`
+}
+
+// Calls cb for each Page.loadEventFired event, till the returned func is called.
+func OnLoadEventFired(conn hc.EventSource, cb func(evt *LoadEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &LoadEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddEventSink("Page.loadEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.loadEventFired", sink) }
+}
+
+// Like OnLoadEventFired, but cb also gets hc.EventMeta.
+func OnLoadEventFiredMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *LoadEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &LoadEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Page.loadEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.loadEventFired", sink) }
+}
+
+// Like OnLoadEventFired, but cb is called right away if the event already fired for the current document.
+func OnLoadEventFiredSticky(conn *hc.Conn, cb func(evt *LoadEventFiredEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &LoadEventFiredEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddStickyEventSink("Page.loadEventFired", sink)
+	return func() { conn.RemoveEventSink("Page.loadEventFired", sink) }
+}
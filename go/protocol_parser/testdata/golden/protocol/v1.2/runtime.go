@@ -0,0 +1,15 @@
+package protocol
+
+import (
+	"encoding/json"
+)
+
+// Unique object identifier.
+type RemoteObjectId string
+
+// Mirror object referencing original JavaScript object.
+type RemoteObject struct {
+	Type     string          `json:"type"`               // Object type.
+	Value    json.RawMessage `json:"value,omitempty"`    // Remote object value in case of primitive values or JSON values (if it was requested).
+	ObjectId RemoteObjectId  `json:"objectId,omitempty"` // Unique object identifier (for non-primitive values).
+}
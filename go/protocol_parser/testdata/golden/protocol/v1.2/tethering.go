@@ -0,0 +1,138 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	hc "github.com/yijinliu/headless-chromium/go"
+	"sync"
+)
+
+type BindParams struct {
+	Port int `json:"port"` // Port number to bind.
+}
+
+// Request browser port binding.
+type BindCommand struct {
+	params *BindParams
+	wg     sync.WaitGroup
+	err    error
+}
+
+func NewBindCommand(params *BindParams) *BindCommand {
+	return &BindCommand{
+		params: params,
+	}
+}
+
+func (cmd *BindCommand) Name() string {
+	return "Tethering.bind"
+}
+
+func (cmd *BindCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *BindCommand) Run(conn hc.CommandRunner) error {
+	cmd.wg.Add(1)
+	if err := conn.SendCommand(cmd); err != nil {
+		return err
+	}
+	cmd.wg.Wait()
+	return cmd.err
+}
+
+func Bind(params *BindParams, conn hc.CommandRunner) (err error) {
+	cmd := NewBindCommand(params)
+	return cmd.Run(conn)
+}
+
+type BindCB func(err error)
+
+// Request browser port binding.
+type AsyncBindCommand struct {
+	params *BindParams
+	cb     BindCB
+	done   chan struct{}
+	err    error
+}
+
+// cb is optional. See Wait.
+func NewAsyncBindCommand(params *BindParams, cb BindCB) *AsyncBindCommand {
+	return &AsyncBindCommand{
+		params: params,
+		cb:     cb,
+		done:   make(chan struct{}),
+	}
+}
+
+func (cmd *AsyncBindCommand) Name() string {
+	return "Tethering.bind"
+}
+
+func (cmd *AsyncBindCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *BindCommand) Done(data []byte, err error) {
+	cmd.err = err
+	cmd.wg.Done()
+}
+
+func (cmd *AsyncBindCommand) Done(data []byte, err error) {
+	cmd.err = err
+	close(cmd.done)
+	if cmd.cb != nil {
+		cmd.cb(err)
+	}
+}
+
+// Waits till the command finishes, or ctx is done.
+func (cmd *AsyncBindCommand) Wait(ctx context.Context) error {
+	select {
+	case <-cmd.done:
+		return cmd.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the command without waiting for it. Wait on the returned command for the result.
+func (cmd *BindCommand) Send(conn hc.CommandRunner) *AsyncBindCommand {
+	async := NewAsyncBindCommand(cmd.params, nil)
+	conn.SendCommand(async)
+	return async
+}
+
+// Informs that port was successfully bound and got a specified connection id.
+type AcceptedEvent struct {
+	Port         int    `json:"port"`         // Port number that was successfully bound.
+	ConnectionId string `json:"connectionId"` // Connection id to be used.
+}
+
+// Calls cb for each Tethering.accepted event, till the returned func is called.
+func OnAccepted(conn hc.EventSource, cb func(evt *AcceptedEvent)) (remove func()) {
+	sink := hc.FuncToEventSink(func(name string, params []byte) {
+		evt := &AcceptedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(evt)
+		}
+	})
+	conn.AddEventSink("Tethering.accepted", sink)
+	return func() { conn.RemoveEventSink("Tethering.accepted", sink) }
+}
+
+// Like OnAccepted, but cb also gets hc.EventMeta.
+func OnAcceptedMeta(conn hc.EventSource, cb func(meta hc.EventMeta, evt *AcceptedEvent)) (remove func()) {
+	sink := hc.FuncToMetaEventSink(func(meta hc.EventMeta, name string, params []byte) {
+		evt := &AcceptedEvent{}
+		if err := json.Unmarshal(params, evt); err != nil {
+			conn.ReportEventError(name, params, err)
+		} else {
+			cb(meta, evt)
+		}
+	})
+	conn.AddEventSink("Tethering.accepted", sink)
+	return func() { conn.RemoveEventSink("Tethering.accepted", sink) }
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/cluster"
+)
+
+type BatchOptions struct {
+	// Number of requests rendered at the same time. Defaults to the number of browsers of the
+	// pool.
+	Concurrency int
+	// Timeout of each attempt of requests without their own Timeout. Defaults to 30 seconds.
+	RequestTimeout time.Duration
+	// Deadline of the whole batch, besides the one of ctx. 0 means none.
+	Timeout time.Duration
+	// Max retries of a request failing with a transient error: a timeout of the attempt, a
+	// browser connection closed e.g. by a crash, or an error with a Temporary method returning
+	// true. 0 means none.
+	Retries int
+	// Wait before the first retry, doubled for each further one. Defaults to 1 second.
+	RetryBackoff time.Duration
+	// Render the requests in random order, so that URLs of the same origin, which are often
+	// adjacent, aren't rendered all at once.
+	Shuffle bool
+	// Check renders with ValidateRender, inspecting pages. Rejected ones fail with
+	// *ValidationError, and aren't retried.
+	Validate *ValidateOptions
+	// Called once a request is done, with its index in reqs and the number of requests done.
+	// Calls are serialized, and block other requests from completing, so it must be quick.
+	Progress func(index int, outcome *RenderOutcome, done, total int)
+}
+
+// What became of a request of Batch.
+type RenderOutcome struct {
+	// Valid if Err is nil or a *ValidationError.
+	Result RenderResult
+	// nil on success. The context error for requests not started before the batch was canceled
+	// or timed out.
+	Err error
+	// Number of renders tried, 0 if the request wasn't started.
+	Attempts int
+	// Time spent on the request, including retries and their backoff.
+	Duration time.Duration
+	// Size of Result.Image.
+	Bytes int
+}
+
+const defaultRetryBackoff = time.Second
+
+// Renders reqs with browsers of pool, at most opts.Concurrency at a time. Outcomes are in the
+// order of reqs. Once ctx is done or opts.Timeout passed, no more requests are started, those
+// being rendered are aborted, and the partial outcomes are returned along with the context
+// error.
+func Batch(ctx context.Context, pool *cluster.Cluster, reqs []RenderRequest, opts BatchOptions) (
+	[]RenderOutcome, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = len(pool.Stats())
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Concurrency > len(reqs) {
+		opts.Concurrency = len(reqs)
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultRetryBackoff
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	order := make([]int, len(reqs))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.Shuffle {
+		rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+
+	outcomes := make([]RenderOutcome, len(reqs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Each worker writes only the outcomes of its own requests.
+				outcomes[i] = renderWithRetries(ctx, pool, reqs[i], &opts)
+				if opts.Progress != nil {
+					mu.Lock()
+					done++
+					opts.Progress(i, &outcomes[i], done, len(reqs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	started := make([]bool, len(reqs))
+schedule:
+	for _, i := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case next <- i:
+			started[i] = true
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(next)
+	wg.Wait()
+	for i := range outcomes {
+		if !started[i] {
+			outcomes[i].Err = ctx.Err()
+		}
+	}
+	return outcomes, ctx.Err()
+}
+
+func renderWithRetries(ctx context.Context, pool *cluster.Cluster, req RenderRequest,
+	opts *BatchOptions) RenderOutcome {
+	if req.Timeout <= 0 {
+		req.Timeout = opts.RequestTimeout
+	}
+	if opts.Validate != nil {
+		req.Inspect = true
+	}
+	start := time.Now()
+	var outcome RenderOutcome
+	backoff := opts.RetryBackoff
+	for {
+		outcome.Attempts++
+		outcome.Result, outcome.Err = renderWithPool(ctx, pool, req)
+		if outcome.Err == nil || outcome.Attempts > opts.Retries ||
+			!transientError(ctx, outcome.Err) {
+			break
+		}
+		logging.Vlogf(1, "Retrying %s in %v: %v", req.URL, backoff, outcome.Err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			outcome.Err = ctx.Err()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff *= 2
+	}
+	if outcome.Err == nil && opts.Validate != nil {
+		outcome.Err = ValidateRender(outcome.Result, *opts.Validate)
+	}
+	outcome.Duration = time.Since(start)
+	outcome.Bytes = len(outcome.Result.Image)
+	return outcome
+}
+
+func renderWithPool(ctx context.Context, pool *cluster.Cluster, req RenderRequest) (
+	RenderResult, error) {
+	browser, release, err := pool.Acquire(ctx)
+	if err != nil {
+		return RenderResult{}, err
+	}
+	defer release()
+	return Render(ctx, browser, req)
+}
+
+// Whether another attempt may succeed. Errors after ctx is done never are transient.
+func transientError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err == context.DeadlineExceeded || errors.Is(err, hc.ErrConnClosed) {
+		return true
+	}
+	temp, ok := err.(interface{ Temporary() bool })
+	return ok && temp.Temporary()
+}
@@ -0,0 +1,303 @@
+package render
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/metrics"
+)
+
+type CacheOptions struct {
+	// How long results are served from the cache. Defaults to 5 minutes.
+	TTL time.Duration
+	// How long failed renders are remembered, so that e.g. a broken page isn't rendered again
+	// for every request. 0 means errors aren't cached. Context errors, and errors with a
+	// Temporary method returning true, are never cached.
+	ErrorTTL time.Duration
+	// Least recently used results are evicted beyond this size of images. Defaults to 256MB.
+	MaxBytes int64
+	// Receives hc_render_cache_total and hc_render_cache_bytes, see package metrics. nil means
+	// none.
+	Metrics metrics.Metrics
+}
+
+const (
+	defaultCacheTTL      = 5 * time.Minute
+	defaultCacheMaxBytes = 256 << 20
+	// Rough size of an entry besides its image.
+	cacheEntryOverhead = 512
+)
+
+// Renders req, e.g. with Render.
+type RenderFunc func(ctx context.Context, req RenderRequest) (RenderResult, error)
+
+// Caches results of renders. Identical requests at the same time share one render. Requests
+// are identical if they render the same normalized URL the same way, regardless of Timeout.
+// Requests with cookies or extra headers in Session are never cached, as the pages may be
+// private.
+type Cache struct {
+	opts   CacheOptions
+	render RenderFunc
+
+	mu       sync.Mutex
+	entryMap map[string]*list.Element // Values are *cacheEntry.
+	lru      *list.List               // Most recently used first.
+	bytes    int64
+	callMap  map[string]*cacheCall
+}
+
+type cacheEntry struct {
+	key     string
+	url     string
+	result  RenderResult
+	err     error
+	expires time.Time
+	size    int64
+}
+
+type cacheCall struct {
+	done    chan struct{}
+	result  RenderResult
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Creates a cache rendering with render, e.g. a closure calling Render with a browser.
+func NewCache(opts CacheOptions, render RenderFunc) *Cache {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultCacheTTL
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultCacheMaxBytes
+	}
+	opts.Metrics = metrics.OrDiscard(opts.Metrics)
+	return &Cache{
+		opts:     opts,
+		render:   render,
+		entryMap: make(map[string]*list.Element),
+		lru:      list.New(),
+		callMap:  make(map[string]*cacheCall),
+	}
+}
+
+// Returns the cached result of an identical request, or renders it. RenderResult.Cached tells
+// which one happened.
+func (c *Cache) Render(ctx context.Context, req RenderRequest) (RenderResult, error) {
+	return c.do(ctx, req, false)
+}
+
+// Renders even if there is a cached result, and caches the new one. An identical render in
+// progress is shared still, as its result is just as fresh.
+func (c *Cache) Refresh(ctx context.Context, req RenderRequest) (RenderResult, error) {
+	return c.do(ctx, req, true)
+}
+
+// Removes all cached results of rawURL, whatever the other parameters were.
+func (c *Cache) Purge(rawURL string) {
+	normalized, err := normalizeURL(rawURL)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, elem := range c.entryMap {
+		if elem.Value.(*cacheEntry).url == normalized {
+			c.remove(elem)
+		}
+	}
+}
+
+func (c *Cache) PurgeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entryMap = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+	c.opts.Metrics.Gauge(metrics.RenderCacheBytes, nil, 0)
+}
+
+// Total size of the cached results.
+func (c *Cache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+func (c *Cache) do(ctx context.Context, req RenderRequest, refresh bool) (RenderResult, error) {
+	if session := req.Session; session != nil &&
+		(len(session.Cookies) > 0 || len(session.ExtraHeaders) > 0) {
+		c.opts.Metrics.Counter(metrics.RenderCacheTotal, metrics.Labels{"result": "bypass"}, 1)
+		return c.render(ctx, req)
+	}
+	normalized, err := normalizeURL(req.URL)
+	if err != nil {
+		return RenderResult{}, err
+	}
+	key := cacheKey(normalized, &req)
+
+	c.mu.Lock()
+	if elem := c.entryMap[key]; elem != nil && !refresh {
+		entry := elem.Value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			c.opts.Metrics.Counter(metrics.RenderCacheTotal, metrics.Labels{"result": "hit"}, 1)
+			result := entry.result
+			result.Cached = true
+			return result, entry.err
+		}
+		c.remove(elem)
+	}
+	call := c.callMap[key]
+	if call == nil {
+		// Not bound to ctx, as other requests may join. It's canceled when all leave.
+		renderCtx, cancel := context.WithCancel(context.Background())
+		call = &cacheCall{done: make(chan struct{}), cancel: cancel}
+		c.callMap[key] = call
+		go c.run(renderCtx, call, key, normalized, req)
+	}
+	call.waiters++
+	c.mu.Unlock()
+	c.opts.Metrics.Counter(metrics.RenderCacheTotal, metrics.Labels{"result": "miss"}, 1)
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		if call.waiters--; call.waiters == 0 {
+			call.cancel()
+		}
+		c.mu.Unlock()
+		return RenderResult{}, ctx.Err()
+	}
+}
+
+func (c *Cache) run(ctx context.Context, call *cacheCall, key, normalized string,
+	req RenderRequest) {
+	defer call.cancel()
+	call.result, call.err = c.render(ctx, req)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.callMap, key)
+	close(call.done)
+	ttl := c.opts.TTL
+	if call.err != nil {
+		if !cacheableError(call.err) {
+			return
+		}
+		ttl = c.opts.ErrorTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	entry := &cacheEntry{
+		key:     key,
+		url:     normalized,
+		result:  call.result,
+		err:     call.err,
+		expires: time.Now().Add(ttl),
+		size:    int64(len(call.result.Image)) + cacheEntryOverhead,
+	}
+	if entry.size > c.opts.MaxBytes {
+		return
+	}
+	if elem := c.entryMap[key]; elem != nil {
+		c.remove(elem)
+	}
+	c.entryMap[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+	c.opts.Metrics.Gauge(metrics.RenderCacheBytes, nil, float64(c.bytes))
+	for c.bytes > c.opts.MaxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Must be called with mu held.
+func (c *Cache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entryMap, entry.key)
+	c.bytes -= entry.size
+	c.opts.Metrics.Gauge(metrics.RenderCacheBytes, nil, float64(c.bytes))
+}
+
+func cacheableError(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	if temp, ok := err.(interface{ Temporary() bool }); ok && temp.Temporary() {
+		return false
+	}
+	return true
+}
+
+// Lower cases scheme and host, and drops default ports, fragments and the order of query
+// parameters.
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if port != "" {
+		u.Host += ":" + port
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawQuery = u.Query().Encode()
+	return u.String(), nil
+}
+
+// Everything of req which affects the result.
+type cacheKeyParams struct {
+	URL         string
+	Width       int
+	Height      int
+	Wait        WaitStrategy
+	Format      Format
+	Quality     int
+	DisableJS   bool
+	ColorScheme string
+	BlockTypes  []string
+	UserAgent   string
+	Languages   []string
+}
+
+func cacheKey(normalized string, req *RenderRequest) string {
+	params := cacheKeyParams{
+		URL:         normalized,
+		Width:       req.Width,
+		Height:      req.Height,
+		Wait:        req.Wait,
+		Format:      req.Format,
+		Quality:     req.Quality,
+		DisableJS:   req.DisableJS,
+		ColorScheme: req.ColorScheme,
+	}
+	if params.Format == "" {
+		params.Format = FormatJpeg
+	}
+	for _, t := range req.BlockTypes {
+		params.BlockTypes = append(params.BlockTypes, string(t))
+	}
+	if req.Session != nil {
+		params.UserAgent = req.Session.UserAgent
+		params.Languages = req.Session.Languages
+	}
+	// Marshaling these never fails.
+	data, _ := json.Marshal(&params)
+	return string(data)
+}
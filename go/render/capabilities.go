@@ -0,0 +1,138 @@
+package render
+
+import (
+	"fmt"
+	"sync"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// Something a browser may lack, see ErrCapabilityMissing.
+type Capability string
+
+const (
+	// Page.captureScreenshot encoding JPEG itself.
+	CapabilityJpegCapture Capability = "Page.captureScreenshot format"
+	// Page.captureScreenshot clipping the image itself.
+	CapabilityClip Capability = "Page.captureScreenshot clip"
+	// Page.printToPDF, needed for FormatPdf.
+	CapabilityPrintToPDF Capability = "Page.printToPDF"
+)
+
+// The first Chrome versions providing the capabilities.
+var minVersions = map[Capability]string{
+	CapabilityJpegCapture: "Chrome 60",
+	CapabilityClip:        "Chrome 61",
+	CapabilityPrintToPDF:  "Chrome 60",
+}
+
+// Returned when rendering needs a capability the browser lacks, and which has no fallback.
+type ErrCapabilityMissing struct {
+	Capability Capability
+	// E.g. "Chrome 60".
+	MinVersion string
+	// Version of the browser, see hc.Version.Browser.
+	Browser string
+}
+
+func (e *ErrCapabilityMissing) Error() string {
+	return fmt.Sprintf("%s lacks %s, which needs %s or later", e.Browser, e.Capability,
+		e.MinVersion)
+}
+
+// What Render can do with a browser.
+type BrowserCapabilities struct {
+	// See hc.Version.Browser.
+	Browser string
+	// Formats Render can produce.
+	Formats []Format
+	// Formats the browser encodes screenshots in. Others are re-encoded from PNG.
+	NativeFormats []Format
+	PrintToPDF    bool
+	// Whether the browser clips screenshots itself. hcutil.CaptureScreenshot emulates clips by
+	// resizing the view otherwise.
+	Clip bool
+}
+
+// Returns whether Render can produce format.
+func (c *BrowserCapabilities) Supports(format Format) bool {
+	for _, f := range c.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns ErrCapabilityMissing if Render can't produce format, nil otherwise.
+func (c *BrowserCapabilities) Check(format Format) error {
+	if format == FormatPdf && !c.PrintToPDF {
+		return c.missing(CapabilityPrintToPDF)
+	}
+	return nil
+}
+
+func (c *BrowserCapabilities) missing(capability Capability) error {
+	return &ErrCapabilityMissing{
+		Capability: capability, MinVersion: minVersions[capability], Browser: c.Browser}
+}
+
+type capabilitiesKey struct{}
+
+type capabilitiesProbe struct {
+	mu   sync.Mutex
+	caps *BrowserCapabilities
+}
+
+// Returns what Render can do with browser. The browser is probed with a blank page the first
+// time, and the report kept with the browser afterwards. Failed probes are tried again next
+// time.
+func Capabilities(browser *hc.Browser) (*BrowserCapabilities, error) {
+	probe := browser.Value(capabilitiesKey{}, func() interface{} {
+		return &capabilitiesProbe{}
+	}).(*capabilitiesProbe)
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+	if probe.caps != nil {
+		return probe.caps, nil
+	}
+	caps, err := probeCapabilities(browser)
+	if err != nil {
+		return nil, err
+	}
+	probe.caps = caps
+	return caps, nil
+}
+
+func probeCapabilities(browser *hc.Browser) (*BrowserCapabilities, error) {
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	pageConn, cleanup, err := openPage(browser, conn, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	capture, err := hcutil.ProbeCaptureCapabilities(pageConn)
+	if err != nil {
+		return nil, err
+	}
+	caps := &BrowserCapabilities{
+		Browser: browser.Version().Browser,
+		// All are re-encoded from PNG if need be.
+		Formats:       []Format{FormatJpeg, FormatPng, FormatGif},
+		NativeFormats: []Format{FormatPng},
+		PrintToPDF:    capture.PrintToPDF,
+		Clip:          capture.Clip,
+	}
+	if capture.JpegCapture {
+		caps.NativeFormats = append(caps.NativeFormats, FormatJpeg)
+	}
+	if capture.PrintToPDF {
+		caps.Formats = append(caps.Formats, FormatPdf)
+	}
+	return caps, nil
+}
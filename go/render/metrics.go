@@ -0,0 +1,40 @@
+package render
+
+import (
+	"context"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/metrics"
+)
+
+// Wraps render to record the hc_render_* metrics of each render in m, see package metrics.
+func Instrument(m metrics.Metrics, render RenderFunc) RenderFunc {
+	m = metrics.OrDiscard(m)
+	return func(ctx context.Context, req RenderRequest) (RenderResult, error) {
+		start := time.Now()
+		result, err := render(ctx, req)
+		labels := metrics.Labels{"status": renderStatus(err)}
+		m.Counter(metrics.RenderTotal, labels, 1)
+		m.Histogram(metrics.RenderDurationSeconds, labels, time.Since(start).Seconds())
+		if err == nil {
+			m.Histogram(metrics.RenderLoadSeconds, nil, result.LoadTime.Seconds())
+			m.Histogram(metrics.RenderCaptureSeconds, nil, result.CaptureTime.Seconds())
+		}
+		return result, err
+	}
+}
+
+func renderStatus(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case err == context.DeadlineExceeded:
+		return "timeout"
+	case err == context.Canceled:
+		return "canceled"
+	}
+	if temp, ok := err.(interface{ Temporary() bool }); ok && temp.Temporary() {
+		return "busy"
+	}
+	return "error"
+}
@@ -0,0 +1,326 @@
+// Package render renders web pages into images with a headless Chromium.
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+type Format string
+
+const (
+	FormatJpeg Format = "jpeg"
+	FormatPng  Format = "png"
+	FormatGif  Format = "gif"
+	// The page printed into PDF, which needs a browser with Page.printToPDF, see Capabilities.
+	FormatPdf Format = "pdf"
+)
+
+// Returns the MIME type of format.
+func (f Format) ContentType() string {
+	if f == FormatPdf {
+		return "application/pdf"
+	}
+	return "image/" + string(f)
+}
+
+// What to wait for after the load event, before capturing.
+type WaitStrategy struct {
+	// JavaScript expression to wait for, e.g. "document.querySelector('#chart')".
+	Condition string
+	// Time to wait at last, e.g. for animations.
+	Delay time.Duration
+	// How far the page must be loaded before Condition and Delay. Defaults to the load event.
+	// With hcutil.ReadyCustom, Condition is polled from DOMContentLoaded on instead.
+	Readiness hcutil.ReadinessLevel
+	// Fail if Readiness isn't reached, instead of falling back to the load event. See
+	// hcutil.NavigateOptions.Strict.
+	Strict bool
+	// Time to wait for Readiness. Defaults to half of the timeout of the request for
+	// hcutil.ReadyNetworkIdle and hcutil.ReadyCustom without Strict, so that there's time left
+	// to capture after falling back, and to all of it otherwise.
+	ReadinessTimeout time.Duration
+}
+
+type RenderRequest struct {
+	URL string
+	// Size of the viewport. The whole page is always rendered.
+	Width, Height int
+	Wait          WaitStrategy
+	// Defaults to FormatJpeg.
+	Format Format
+	// JPEG quality in [1, 100]. 0 means the default.
+	Quality int
+	// Defaults to 30 seconds.
+	Timeout time.Duration
+	// Render without running the page's JavaScript. Wait.Condition can't be used then.
+	DisableJS bool
+	// "light" or "dark" for prefers-color-scheme. Empty means the browser's default.
+	ColorScheme string
+	// Resource types not to load, e.g. fonts or media. See hcutil.BlockResourceTypes.
+	BlockTypes []protocol.ResourceType
+	// User agent, headers, languages and cookies to render with. nil means the browser's.
+	Session *hcutil.SessionConfig
+	// Report RenderResult.Page, e.g. for ValidateRender.
+	Inspect bool
+}
+
+type RenderResult struct {
+	// The image, or the PDF document for FormatPdf.
+	Image    []byte
+	Format   Format
+	FinalURL string
+	// HTTP status of the main document.
+	Status int
+	// Time spent on loading the page, and on capturing and encoding the image.
+	LoadTime, CaptureTime time.Duration
+	// Whether it's served by Cache instead of rendered for this request.
+	Cached bool
+	// How far the page was loaded when captured, see WaitStrategy.Readiness.
+	Navigation *hcutil.NavigationResult
+	// Set with RenderRequest.Inspect.
+	Page *PageSummary
+}
+
+const defaultTimeout = 30 * time.Second
+
+// Renders a page in its own browser context, which is disposed afterwards.
+func Render(ctx context.Context, browser *hc.Browser, req RenderRequest) (RenderResult, error) {
+	var result RenderResult
+	if req.URL == "" {
+		return result, fmt.Errorf("URL is required")
+	}
+	if req.Format == "" {
+		req.Format = FormatJpeg
+	}
+	if req.DisableJS && req.Wait.Condition != "" {
+		return result, hcutil.ErrJSDisabled
+	}
+	if req.Format == FormatPdf {
+		caps, err := Capabilities(browser)
+		if err != nil {
+			return result, err
+		}
+		if err := caps.Check(req.Format); err != nil {
+			return result, err
+		}
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+	pageConn, cleanup, err := openPage(browser, conn, req.Width, req.Height)
+	if err != nil {
+		return result, err
+	}
+	defer cleanup()
+	// Abort everything as soon as ctx is done.
+	go func() {
+		select {
+		case <-ctx.Done():
+			pageConn.Close()
+		case <-pageConn.Closed():
+		}
+	}()
+
+	start := time.Now()
+	if result.Navigation, err = load(ctx, pageConn, &req); err != nil {
+		return result, ctxErr(ctx, err)
+	}
+	result.LoadTime = time.Since(start)
+	if resp, err := hcutil.MainDocumentResponse(pageConn); err != nil {
+		logging.Vlog(1, err)
+	} else {
+		result.FinalURL = resp.Url
+		result.Status = int(resp.Status)
+	}
+	if req.Inspect {
+		if result.Page, err = summarizePage(pageConn); err != nil {
+			return result, ctxErr(ctx, err)
+		}
+	}
+
+	start = time.Now()
+	if req.Format == FormatPdf {
+		if result.Image, err = hcutil.PrintToPDF(pageConn, nil); err != nil {
+			return result, ctxErr(ctx, err)
+		}
+	} else {
+		data, err := hcutil.CaptureScreenshot(pageConn, &hcutil.ScreenshotOptions{FullPage: true})
+		if err != nil {
+			return result, ctxErr(ctx, err)
+		}
+		if result.Image, err = encode(data, req.Format, req.Quality); err != nil {
+			return result, err
+		}
+	}
+	result.Format = req.Format
+	result.CaptureTime = time.Since(start)
+	return result, nil
+}
+
+// Creates a page in a new browser context and connects to it.
+func openPage(browser *hc.Browser, conn *hc.Conn, width, height int) (
+	pageConn *hc.Conn, cleanup func(), err error) {
+	result, err := protocol.CreateBrowserContext(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	contextId := result.BrowserContextId
+	var targetId protocol.TargetID
+	cleanup = func() {
+		if pageConn != nil {
+			pageConn.Close()
+		}
+		if targetId != "" {
+			if _, err := protocol.CloseTarget(
+				&protocol.CloseTargetParams{TargetId: targetId}, conn); err != nil {
+				logging.Vlog(-1, err)
+			}
+		}
+		if _, err := protocol.DisposeBrowserContext(
+			&protocol.DisposeBrowserContextParams{BrowserContextId: contextId}, conn); err != nil {
+			logging.Vlog(-1, err)
+		}
+	}
+
+	if target, err := protocol.CreateTarget(&protocol.CreateTargetParams{
+		Url:              "about:blank",
+		Width:            width,
+		Height:           height,
+		BrowserContextId: contextId,
+	}, conn); err != nil {
+		cleanup()
+		return nil, nil, err
+	} else {
+		targetId = target.TargetId
+	}
+	if pageConn, err = browser.NewPageConn(string(targetId)); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return pageConn, cleanup, nil
+}
+
+func load(ctx context.Context, conn *hc.Conn, req *RenderRequest) (
+	*hcutil.NavigationResult, error) {
+	deadline, _ := ctx.Deadline()
+	if req.DisableJS {
+		if err := hcutil.DisableJavaScript(conn); err != nil {
+			return nil, err
+		}
+	}
+	if req.Session != nil {
+		if err := req.Session.Apply(conn); err != nil {
+			return nil, err
+		}
+	}
+	if req.ColorScheme != "" {
+		if err := hcutil.EmulateMediaFeature(
+			conn, "prefers-color-scheme", req.ColorScheme); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.BlockTypes) > 0 {
+		if err := hcutil.BlockResourceTypes(conn, req.BlockTypes...); err != nil {
+			return nil, err
+		}
+	}
+	wait := &req.Wait
+	opts := &hcutil.NavigateOptions{
+		Timeout:   wait.ReadinessTimeout,
+		Readiness: wait.Readiness,
+		Strict:    wait.Strict,
+	}
+	mayFallBack := !wait.Strict &&
+		(wait.Readiness == hcutil.ReadyNetworkIdle || wait.Readiness == hcutil.ReadyCustom)
+	if remaining := time.Until(deadline); opts.Timeout <= 0 && mayFallBack {
+		opts.Timeout = remaining / 2
+	} else if opts.Timeout <= 0 || opts.Timeout > remaining {
+		opts.Timeout = remaining
+	}
+	if wait.Readiness == hcutil.ReadyCustom {
+		if wait.Condition == "" {
+			return nil, errors.New("hcutil.ReadyCustom needs a Condition")
+		}
+		opts.Ready = func(conn *hc.Conn) (bool, error) {
+			var ok bool
+			err := hcutil.Evaluate(conn, "!!("+wait.Condition+")", &ok)
+			return ok, err
+		}
+	}
+	nav, err := hcutil.NavigateAndWaitForReadiness(conn, req.URL, opts)
+	if err != nil {
+		return nil, err
+	}
+	if wait.Condition != "" && wait.Readiness != hcutil.ReadyCustom {
+		if err := hcutil.WaitForCondition(
+			conn, wait.Condition, time.Until(deadline), nil); err != nil {
+			return nil, err
+		}
+	}
+	if wait.Delay > 0 {
+		select {
+		case <-time.After(wait.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nav, nil
+}
+
+// Prefers the context error, as the one from the connection is just a consequence.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Converts the PNG screenshot into format.
+func encode(data []byte, format Format, quality int) ([]byte, error) {
+	if format == FormatPng {
+		return data, nil
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	switch format {
+	case FormatGif:
+		err = gif.Encode(&buf, img, nil)
+	case FormatJpeg:
+		var opts *jpeg.Options
+		if quality > 0 {
+			opts = &jpeg.Options{Quality: quality}
+		}
+		err = jpeg.Encode(&buf, img, opts)
+	default:
+		return nil, fmt.Errorf("Unknown format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
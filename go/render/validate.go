@@ -0,0 +1,193 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"regexp"
+	"strings"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hcutil"
+)
+
+// What the page looked like when captured, reported with RenderRequest.Inspect.
+type PageSummary struct {
+	// location.href, e.g. "chrome-error://chromewebdata/" for error pages of the browser.
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// The beginning of the text of the body, up to maxSummaryText characters.
+	Text string `json:"text"`
+	// Number of elements of the document.
+	Nodes int `json:"nodes"`
+}
+
+const maxSummaryText = 16 << 10
+
+func summarizePage(conn *hc.Conn) (*PageSummary, error) {
+	var summary PageSummary
+	err := hcutil.Evaluate(conn, fmt.Sprintf(`({
+		url: location.href,
+		title: document.title,
+		text: document.body ? document.body.innerText.slice(0, %d) : "",
+		nodes: document.getElementsByTagName("*").length
+	})`, maxSummaryText), &summary)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Why ValidateRender rejected a render.
+type ValidationClass string
+
+const (
+	// The image is mostly of a single color, e.g. all white.
+	InvalidBlank ValidationClass = "blank"
+	// The browser rendered an error page, e.g. for ERR_NAME_NOT_RESOLVED.
+	InvalidErrorPage ValidationClass = "errorPage"
+	// The main document has a status ValidateOptions.AllowStatus rejects.
+	InvalidStatus ValidationClass = "status"
+	// The document has fewer elements than ValidateOptions.MinNodes.
+	InvalidTooFewNodes ValidationClass = "tooFewNodes"
+)
+
+type ValidationError struct {
+	Class  ValidationClass
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid render (%s): %s", e.Class, e.Detail)
+}
+
+type ValidateOptions struct {
+	// Share of the sampled pixels of the dominant color from which an image is blank. Defaults
+	// to 0.99. Negative disables the check.
+	BlankRatio float64
+	// Texts of error pages to look for besides DefaultErrorMarkers, matched case-sensitively.
+	ErrorMarkers []string
+	// Statuses of the main document which are fine. nil means those below 400. Unknown ones,
+	// e.g. of file: URLs, are 0, and always fine.
+	AllowStatus func(status int) bool
+	// Minimum number of elements of the document. 0 means no minimum.
+	MinNodes int
+}
+
+const defaultBlankRatio = 0.99
+
+// Texts of the error pages of Chromium.
+var DefaultErrorMarkers = []string{
+	"This site can’t be reached",
+	"This site can't be reached",
+	"This page isn’t working",
+	"This page isn't working",
+	"Your connection is not private",
+	"Aw, Snap!",
+}
+
+// Error codes of the network stack shown by error pages, e.g. "ERR_NAME_NOT_RESOLVED".
+var netErrorRe = regexp.MustCompile(`\b(net::)?ERR_[A-Z_]{3,}\b`)
+
+// URL of the error pages of Chromium.
+const errorPageURLPrefix = "chrome-error://"
+
+// Number of pixels sampled along each side of the image for the blank check.
+const blankSamples = 64
+
+// Returns a *ValidationError if result looks like a failed render, despite having succeeded:
+// a blank image, an error page, a bad status, or too few elements. The page checks need
+// result.Page, see RenderRequest.Inspect, and are skipped without it. PDFs aren't checked for
+// blankness.
+func ValidateRender(result RenderResult, opts ValidateOptions) error {
+	if result.Page != nil {
+		if err := checkErrorPage(result.Page, opts.ErrorMarkers); err != nil {
+			return err
+		}
+	}
+	allow := opts.AllowStatus
+	if allow == nil {
+		allow = func(status int) bool { return status < 400 }
+	}
+	if result.Status != 0 && !allow(result.Status) {
+		return &ValidationError{InvalidStatus, fmt.Sprintf("Main document has status %d",
+			result.Status)}
+	}
+	if result.Page != nil && opts.MinNodes > 0 && result.Page.Nodes < opts.MinNodes {
+		return &ValidationError{InvalidTooFewNodes, fmt.Sprintf("%d elements, less than %d",
+			result.Page.Nodes, opts.MinNodes)}
+	}
+	ratio := opts.BlankRatio
+	if ratio == 0 {
+		ratio = defaultBlankRatio
+	}
+	if ratio > 0 && result.Format != FormatPdf && len(result.Image) > 0 {
+		img, _, err := image.Decode(bytes.NewReader(result.Image))
+		if err != nil {
+			return err
+		}
+		if dominant := dominantColorRatio(img); dominant >= ratio {
+			return &ValidationError{InvalidBlank, fmt.Sprintf(
+				"%.1f%% of the image is of one color", dominant*100)}
+		}
+	}
+	return nil
+}
+
+func checkErrorPage(page *PageSummary, markers []string) error {
+	if strings.HasPrefix(page.URL, errorPageURLPrefix) {
+		detail := page.URL
+		if code := netErrorRe.FindString(page.Text); code != "" {
+			detail = code
+		}
+		return &ValidationError{InvalidErrorPage, detail}
+	}
+	for _, list := range [][]string{DefaultErrorMarkers, markers} {
+		for _, marker := range list {
+			if strings.Contains(page.Text, marker) || strings.Contains(page.Title, marker) {
+				return &ValidationError{InvalidErrorPage, fmt.Sprintf("Page shows %q", marker)}
+			}
+		}
+	}
+	// Codes alone may well be in pages about them, so only short pages count.
+	if code := netErrorRe.FindString(page.Text); code != "" && page.Nodes < 50 {
+		return &ValidationError{InvalidErrorPage, code}
+	}
+	return nil
+}
+
+// Samples a grid of pixels, and returns the share of the most common color. Colors are compared
+// with 4 bits per channel, so that JPEG noise doesn't hide blank images.
+func dominantColorRatio(img image.Image) float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 1
+	}
+	rows, cols := blankSamples, blankSamples
+	if h < rows {
+		rows = h
+	}
+	if w < cols {
+		cols = w
+	}
+	counts := make(map[uint32]int)
+	total, max := 0, 0
+	for i := 0; i < rows; i++ {
+		y := bounds.Min.Y + i*h/rows
+		for j := 0; j < cols; j++ {
+			x := bounds.Min.X + j*w/cols
+			r, g, b, _ := img.At(x, y).RGBA()
+			key := r>>12<<8 | g>>12<<4 | b>>12
+			counts[key]++
+			if counts[key] > max {
+				max = counts[key]
+			}
+			total++
+		}
+	}
+	return float64(max) / float64(total)
+}
@@ -0,0 +1,128 @@
+package render_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/yijinliu/headless-chromium/go/render"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Summary of the page Chromium shows for a host which doesn't resolve.
+var dnsErrorPage = &render.PageSummary{
+	URL:   "chrome-error://chromewebdata/",
+	Title: "example.invalid",
+	Text: "This site can’t be reached\nexample.invalid’s server IP address could not be " +
+		"found.\nERR_NAME_NOT_RESOLVED",
+	Nodes: 40,
+}
+
+var contentPage = &render.PageSummary{
+	URL:   "http://example.com/",
+	Title: "Example Domain",
+	Text:  "Example Domain\nThis domain is for use in illustrative examples in documents.",
+	Nodes: 120,
+}
+
+func TestValidateRender(t *testing.T) {
+	content := readFixture(t, "content.png")
+	for _, c := range []struct {
+		name   string
+		result render.RenderResult
+		opts   render.ValidateOptions
+		want   render.ValidationClass
+	}{
+		{name: "content", result: render.RenderResult{Image: content, Status: 200,
+			Page: contentPage}},
+		{name: "content jpeg", result: render.RenderResult{Image: readFixture(t, "content.jpg"),
+			Format: render.FormatJpeg}},
+
+		{name: "blank", result: render.RenderResult{Image: readFixture(t, "blank.png")},
+			want: render.InvalidBlank},
+		{name: "blank with JPEG noise",
+			result: render.RenderResult{Image: readFixture(t, "blank_noisy.jpg"),
+				Format: render.FormatJpeg},
+			want: render.InvalidBlank},
+		{name: "blank with a scrollbar",
+			result: render.RenderResult{Image: readFixture(t, "blank_scrollbar.png")},
+			want:   render.InvalidBlank},
+		{name: "blank check disabled", result: render.RenderResult{
+			Image: readFixture(t, "blank.png")}, opts: render.ValidateOptions{BlankRatio: -1}},
+		{name: "blank PDF", result: render.RenderResult{Image: []byte("%PDF-1.4"),
+			Format: render.FormatPdf}},
+
+		{name: "error page URL", result: render.RenderResult{Image: content,
+			Page: dnsErrorPage}, want: render.InvalidErrorPage},
+		{name: "error page marker", result: render.RenderResult{Image: content,
+			Page: &render.PageSummary{URL: "http://example.com/", Nodes: 500,
+				Text: "Aw, Snap!\nSomething went wrong while displaying this webpage."}},
+			want: render.InvalidErrorPage},
+		{name: "custom marker", result: render.RenderResult{Image: content,
+			Page: &render.PageSummary{URL: "http://example.com/", Title: "Maintenance",
+				Nodes: 500}},
+			opts: render.ValidateOptions{ErrorMarkers: []string{"Maintenance"}},
+			want: render.InvalidErrorPage},
+		{name: "net error code on a short page", result: render.RenderResult{Image: content,
+			Page: &render.PageSummary{URL: "http://example.com/", Nodes: 10,
+				Text: "net::ERR_CONNECTION_RESET"}},
+			want: render.InvalidErrorPage},
+		{name: "page about net errors", result: render.RenderResult{Image: content,
+			Page: &render.PageSummary{URL: "http://example.com/", Nodes: 800,
+				Text: "How to fix ERR_CONNECTION_RESET"}}},
+
+		{name: "not found", result: render.RenderResult{Image: content, Status: 404},
+			want: render.InvalidStatus},
+		{name: "server error", result: render.RenderResult{Image: content, Status: 503},
+			want: render.InvalidStatus},
+		{name: "unknown status", result: render.RenderResult{Image: content}},
+		{name: "allowed status", result: render.RenderResult{Image: content, Status: 404},
+			opts: render.ValidateOptions{AllowStatus: func(status int) bool { return true }}},
+		{name: "redirect status denied", result: render.RenderResult{Image: content,
+			Status: 302},
+			opts: render.ValidateOptions{AllowStatus: func(status int) bool {
+				return status == 200
+			}},
+			want: render.InvalidStatus},
+
+		{name: "too few nodes", result: render.RenderResult{Image: content,
+			Page: &render.PageSummary{URL: "http://example.com/", Nodes: 3}},
+			opts: render.ValidateOptions{MinNodes: 10}, want: render.InvalidTooFewNodes},
+		{name: "enough nodes", result: render.RenderResult{Image: content, Page: contentPage},
+			opts: render.ValidateOptions{MinNodes: 10}},
+		{name: "nodes unknown", result: render.RenderResult{Image: content},
+			opts: render.ValidateOptions{MinNodes: 10}},
+
+		// Error pages are reported rather than their blankness or status.
+		{name: "blank error page", result: render.RenderResult{
+			Image: readFixture(t, "blank.png"), Status: 502, Page: dnsErrorPage},
+			want: render.InvalidErrorPage},
+	} {
+		err := render.ValidateRender(c.result, c.opts)
+		if c.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if verr, ok := err.(*render.ValidationError); !ok || verr.Class != c.want {
+			t.Errorf("%s: got %v, want %s", c.name, err, c.want)
+		}
+	}
+}
+
+func TestValidateRenderBadImage(t *testing.T) {
+	err := render.ValidateRender(render.RenderResult{Image: []byte("not an image")},
+		render.ValidateOptions{})
+	if _, ok := err.(*render.ValidationError); ok || err == nil {
+		t.Errorf("Got %v, want a decoding error", err)
+	}
+}
@@ -0,0 +1,95 @@
+package headless_chromium
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+var ErrNoProcess = errors.New("browser isn't launched by this process")
+
+// Resources used by the browser and all its child processes.
+type ResourceUsage struct {
+	// Resident set size in bytes.
+	RSS uint64
+	// User and system CPU time.
+	CPUTime   time.Duration
+	OpenFDs   int
+	Processes int
+}
+
+func (u *ResourceUsage) String() string {
+	return fmt.Sprintf("rss: %dMB, cpu: %v, fds: %d, processes: %d",
+		u.RSS>>20, u.CPUTime, u.OpenFDs, u.Processes)
+}
+
+// Returns the resources used by the browser launched by NewBrowser, which runs in its own process
+// group. Only supported on Linux.
+func (b *Browser) ResourceUsage() (*ResourceUsage, error) {
+	b.closeMu.Lock()
+	process := b.process
+	b.closeMu.Unlock()
+	if process == nil {
+		return nil, ErrNoProcess
+	}
+	return processGroupUsage(process.Pid)
+}
+
+type ResourceLimits struct {
+	// Zero means no limit.
+	MaxRSS       uint64
+	MaxOpenFDs   int
+	MaxProcesses int
+	// How often to check. Defaults to 5 seconds.
+	Interval time.Duration
+	// Called with the usage every time a limit is exceeded, e.g. to restart the browser once it's
+	// idle. The browser isn't stopped.
+	OnExceeded func(usage *ResourceUsage)
+}
+
+// Whether usage exceeds any limit.
+func (l *ResourceLimits) Exceeded(usage *ResourceUsage) bool {
+	return (l.MaxRSS > 0 && usage.RSS > l.MaxRSS) ||
+		(l.MaxOpenFDs > 0 && usage.OpenFDs > l.MaxOpenFDs) ||
+		(l.MaxProcesses > 0 && usage.Processes > l.MaxProcesses)
+}
+
+// Polls ResourceUsage till stop is called or the browser is closed, and calls
+// limits.OnExceeded when any limit is exceeded.
+func (b *Browser) MonitorResources(limits ResourceLimits) (stop func(), err error) {
+	if _, err := b.ResourceUsage(); err != nil {
+		return nil, err
+	}
+	if limits.Interval <= 0 {
+		limits.Interval = 5 * time.Second
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(limits.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			usage, err := b.ResourceUsage()
+			if err != nil {
+				// Most likely the browser is gone.
+				logging.Vlog(1, err)
+				return
+			}
+			if limits.Exceeded(usage) {
+				logging.Vlogf(1, "Browser exceeds resource limits: %s", usage)
+				if limits.OnExceeded != nil {
+					limits.OnExceeded(usage)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
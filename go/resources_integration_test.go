@@ -0,0 +1,53 @@
+//go:build integration
+
+package headless_chromium_test
+
+import (
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+)
+
+// Loading a page holding a lot of memory makes the browser exceed an RSS limit set above its
+// idle usage.
+func TestIntegrationMonitorResources(t *testing.T) {
+	fixtures := hctest.NewFixtureServer(t)
+	browser := hctest.NewBrowser(t)
+	hctest.NewPage(t, browser, "about:blank")
+	idle, err := browser.ResourceUsage()
+	if err != nil {
+		t.Skip(err)
+	}
+	exceeded := make(chan *hc.ResourceUsage, 1)
+	stop, err := browser.MonitorResources(hc.ResourceLimits{
+		MaxRSS:   idle.RSS + hctest.FixtureMemoryHogSize/2,
+		Interval: 200 * time.Millisecond,
+		OnExceeded: func(usage *hc.ResourceUsage) {
+			select {
+			case exceeded <- usage:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	select {
+	case usage := <-exceeded:
+		t.Fatalf("Exceeded before loading the fixture: %s", usage)
+	case <-time.After(time.Second):
+	}
+
+	hctest.NewPage(t, browser, fixtures.URL+hctest.FixtureMemoryHog)
+	select {
+	case usage := <-exceeded:
+		if usage.RSS <= idle.RSS {
+			t.Errorf("Got %s, idle %s", usage, idle)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("Limit not exceeded, idle %s", idle)
+	}
+}
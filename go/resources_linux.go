@@ -0,0 +1,85 @@
+package headless_chromium
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Ticks per second of CPU times in /proc/<pid>/stat, which is 100 on all common platforms.
+const clockTicks = 100
+
+func newSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// Locks dir exclusively against other processes and goroutines till unlock is called.
+func lockDir(dir string) (unlock func(), err error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Cannot lock %s: %v", dir, err)
+	}
+	// Closing the file releases the lock.
+	return func() { f.Close() }, nil
+}
+
+// Kills processes left in the group, e.g. renderers of a crashed browser.
+func killProcessGroup(pgid int) {
+	syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+func processGroupUsage(pgid int) (*ResourceUsage, error) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return nil, err
+	}
+	usage := &ResourceUsage{}
+	pageSize := uint64(os.Getpagesize())
+	for _, stat := range stats {
+		data, err := ioutil.ReadFile(stat)
+		if err != nil {
+			// The process has exited.
+			continue
+		}
+		// The command in parentheses may contain spaces.
+		i := strings.LastIndexByte(string(data), ')')
+		if i < 0 {
+			continue
+		}
+		// Starts with the 3rd field, state.
+		fields := strings.Fields(string(data[i+1:]))
+		if len(fields) < 22 {
+			continue
+		}
+		if pgrp, _ := strconv.Atoi(fields[2]); pgrp != pgid {
+			continue
+		}
+		utime, _ := strconv.ParseUint(fields[11], 10, 64)
+		stime, _ := strconv.ParseUint(fields[12], 10, 64)
+		rss, _ := strconv.ParseUint(fields[21], 10, 64)
+		usage.Processes++
+		usage.CPUTime += time.Duration(utime+stime) * time.Second / clockTicks
+		usage.RSS += rss * pageSize
+		if fds, err := ioutil.ReadDir(filepath.Join(filepath.Dir(stat), "fd")); err == nil {
+			usage.OpenFDs += len(fds)
+		}
+	}
+	if usage.Processes == 0 {
+		return nil, fmt.Errorf("No process in group %d", pgid)
+	}
+	return usage, nil
+}
@@ -0,0 +1,30 @@
+//go:build !linux
+
+package headless_chromium
+
+import (
+	"errors"
+	"syscall"
+)
+
+var errResourceUsageUnsupported = errors.New("resource usage is only supported on Linux")
+
+func newSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// Can't tell, so assume it is.
+func processAlive(pid int) bool {
+	return true
+}
+
+// Stale locks are never taken over, since processAlive can't tell, so there's nothing to guard.
+func lockDir(dir string) (unlock func(), err error) {
+	return func() {}, nil
+}
+
+func killProcessGroup(pgid int) {}
+
+func processGroupUsage(pgid int) (*ResourceUsage, error) {
+	return nil, errResourceUsageUnsupported
+}
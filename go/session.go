@@ -0,0 +1,303 @@
+package headless_chromium
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// A protocol session with a target, multiplexed over a browser connection with
+// Target.sendMessageToTarget, e.g. for targets which can't be connected to directly. Commands
+// are sent like on a page connection, see CommandRunner. Events of the target go to sinks added
+// with AddEventSink, so that the On functions of the protocol package work on sessions too, see
+// EventSource.
+type Session struct {
+	conn     *Conn
+	targetId string
+	kind     TargetKinds
+	cancelFn func()
+
+	mu            sync.Mutex
+	nextCmdId     int
+	pendingCmdMap map[int]Command
+	evtSinkMap    map[string][]EventSink
+	closed        bool
+}
+
+// Attaches to targetId over conn, which must be a browser connection, see
+// Browser.NewBrowserConn. Call Close once done.
+func NewSession(conn *Conn, targetId string) (*Session, error) {
+	s := &Session{
+		conn:          conn,
+		targetId:      targetId,
+		pendingCmdMap: make(map[int]Command),
+		evtSinkMap:    make(map[string][]EventSink),
+	}
+	received := FuncToMetaEventSink(s.onReceivedMessage)
+	detached := FuncToEventSink(func(_ string, params []byte) {
+		var evt struct {
+			TargetId string `json:"targetId"`
+		}
+		if err := json.Unmarshal(params, &evt); err != nil {
+			conn.ReportEventError("Target.detachedFromTarget", params, err)
+		} else if evt.TargetId == targetId {
+			s.shutdown()
+		}
+	})
+	conn.AddEventSink("Target.receivedMessageFromTarget", received)
+	conn.AddEventSink("Target.detachedFromTarget", detached)
+	s.cancelFn = func() {
+		conn.RemoveEventSink("Target.receivedMessageFromTarget", received)
+		conn.RemoveEventSink("Target.detachedFromTarget", detached)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := runRaw(conn, "Target.attachToTarget",
+		map[string]string{"targetId": targetId}, &result); err != nil {
+		s.shutdown()
+		return nil, err
+	} else if !result.Success {
+		s.shutdown()
+		return nil, fmt.Errorf("Failed to attach to target %s", targetId)
+	}
+	var info struct {
+		TargetInfo struct {
+			Type string `json:"type"`
+		} `json:"targetInfo"`
+	}
+	// Commands aren't checked against the kind if it's unknown.
+	if err := runRaw(conn, "Target.getTargetInfo",
+		map[string]string{"targetId": targetId}, &info); err == nil {
+		s.kind = sessionKinds[info.TargetInfo.Type]
+	}
+	return s, nil
+}
+
+// Target kinds of target types listed by Target.getTargetInfo.
+var sessionKinds = map[string]TargetKinds{
+	"page":           TargetPage,
+	"iframe":         TargetPage,
+	"worker":         TargetWorker,
+	"shared_worker":  TargetWorker,
+	"service_worker": TargetWorker,
+	"browser":        TargetBrowser,
+}
+
+func (s *Session) TargetId() string {
+	return s.targetId
+}
+
+// The kind of the target, or 0 if unknown.
+func (s *Session) Kind() TargetKinds {
+	return s.kind
+}
+
+// Sends cmd to the target. Done of cmd is called like with Conn.SendCommand.
+func (s *Session) SendCommand(cmd Command) error {
+	if err := CheckTargetKind(s.kind, cmd.Name()); err != nil {
+		cmd.Done(nil, err)
+		return err
+	}
+	cj := &CommandJson{Method: cmd.Name(), Params: cmd.Params()}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		cmd.Done(nil, ErrConnClosed)
+		return ErrConnClosed
+	}
+	s.nextCmdId++
+	cj.Id = s.nextCmdId
+	s.pendingCmdMap[cj.Id] = cmd
+	s.mu.Unlock()
+
+	message, err := json.Marshal(cj)
+	if err == nil {
+		// Conn.SendCommand calls done before returning if it fails, and that error is returned
+		// below. Later errors arrive asynchronously, like responses.
+		var mu sync.Mutex
+		returned := false
+		var earlyErr error
+		err = s.conn.SendCommand(&rawCommand{
+			name:   "Target.sendMessageToTarget",
+			params: map[string]string{"targetId": s.targetId, "message": string(message)},
+			done: func(_ []byte, err error) {
+				if err == nil {
+					return
+				}
+				mu.Lock()
+				if !returned {
+					earlyErr = err
+					mu.Unlock()
+					return
+				}
+				mu.Unlock()
+				s.finishCommand(cj.Id, nil, err)
+			},
+		})
+		mu.Lock()
+		returned = true
+		mu.Unlock()
+		if err == nil && earlyErr != nil {
+			// Failed after being sent, but before SendCommand returned.
+			s.finishCommand(cj.Id, nil, earlyErr)
+		}
+	}
+	if err != nil {
+		if cmd := s.takeCommand(cj.Id); cmd != nil {
+			cmd.Done(nil, err)
+		}
+	}
+	return err
+}
+
+// Adds sink for events of the target named name, unless it was added already. Like on Conn, a
+// MetaEventSink gets EventMeta, though of the Target.receivedMessageFromTarget event carrying the
+// target's event, so Seq counts the events of the browser connection.
+func (s *Session) AddEventSink(name string, sink EventSink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sinks := s.evtSinkMap[name]
+	for _, item := range sinks {
+		if item == sink {
+			return
+		}
+	}
+	s.evtSinkMap[name] = append(sinks[:len(sinks):len(sinks)], sink)
+}
+
+func (s *Session) RemoveEventSink(name string, sink EventSink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sinks := s.evtSinkMap[name]
+	for i, item := range sinks {
+		if item == sink {
+			if len(sinks) == 1 {
+				delete(s.evtSinkMap, name)
+			} else {
+				s.evtSinkMap[name] = append(sinks[:i:i], sinks[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// Reports events of the target which sinks failed to decode, on the browser connection.
+func (s *Session) ReportEventError(name string, params []byte, err error) {
+	s.conn.ReportEventError(name, params, err)
+}
+
+// Detaches from the target. Pending commands fail with ErrConnClosed.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil
+	}
+	err := runRaw(s.conn, "Target.detachFromTarget",
+		map[string]string{"targetId": s.targetId}, nil)
+	s.shutdown()
+	return err
+}
+
+func (s *Session) shutdown() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	pending := s.pendingCmdMap
+	s.pendingCmdMap = make(map[int]Command)
+	s.mu.Unlock()
+	s.cancelFn()
+	for _, cmd := range pending {
+		go cmd.Done(nil, ErrConnClosed)
+	}
+}
+
+// Removes the pending command id, and returns it, or nil if it's done already.
+func (s *Session) takeCommand(id int) Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd := s.pendingCmdMap[id]
+	delete(s.pendingCmdMap, id)
+	return cmd
+}
+
+func (s *Session) finishCommand(id int, result []byte, err error) {
+	if cmd := s.takeCommand(id); cmd != nil {
+		go cmd.Done(result, err)
+	}
+}
+
+func (s *Session) onReceivedMessage(meta EventMeta, name string, params []byte) {
+	var evt struct {
+		TargetId string `json:"targetId"`
+		Message  string `json:"message"`
+	}
+	if err := json.Unmarshal(params, &evt); err != nil {
+		s.conn.ReportEventError(name, params, err)
+		return
+	}
+	if evt.TargetId != s.targetId {
+		return
+	}
+	mj := &MessageJson{}
+	if err := json.Unmarshal([]byte(evt.Message), mj); err != nil {
+		s.conn.logError("", err)
+		return
+	}
+	if mj.Id > 0 {
+		var err error
+		if mj.Error.Code != 0 || mj.Error.Message != "" {
+			err = &CommandError{ErrorJson: mj.Error}
+		}
+		s.finishCommand(mj.Id, []byte(mj.Result), err)
+		return
+	}
+	s.mu.Lock()
+	sinks := s.evtSinkMap[mj.Method]
+	s.mu.Unlock()
+	for _, sink := range sinks {
+		go deliverEvent(sink, meta, mj.Method, []byte(mj.Params))
+	}
+}
+
+// A command with raw params, for the few commands hc sends itself.
+type rawCommand struct {
+	name   string
+	params interface{}
+	done   func(result []byte, err error)
+}
+
+func (cmd *rawCommand) Name() string {
+	return cmd.name
+}
+
+func (cmd *rawCommand) Params() interface{} {
+	return cmd.params
+}
+
+func (cmd *rawCommand) Done(result []byte, err error) {
+	cmd.done(result, err)
+}
+
+// Runs a command on conn, and unmarshals its result into result, which may be nil.
+func runRaw(conn *Conn, name string, params interface{}, result interface{}) error {
+	ch := make(chan error, 1)
+	conn.SendCommand(&rawCommand{name: name, params: params, done: func(data []byte, err error) {
+		if err == nil && result != nil {
+			err = json.Unmarshal(data, result)
+		}
+		ch <- err
+	}})
+	select {
+	case err := <-ch:
+		return err
+	case <-conn.Closed():
+		return conn.closedErr(ErrConnClosed)
+	}
+}
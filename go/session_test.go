@@ -0,0 +1,196 @@
+package headless_chromium_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Attaches a session to target page1 over a browser connection of server.
+func newTestSession(t *testing.T, server *hctest.FakeServer) (*hc.Session, *hc.Conn,
+	*hctest.FakeConn) {
+	t.Helper()
+	server.Handle("Target.attachToTarget", hctest.FakeResult(map[string]bool{"success": true}))
+	server.Handle("Target.getTargetInfo", hctest.FakeResult(map[string]interface{}{
+		"targetInfo": map[string]string{"targetId": "page1", "type": "page"}}))
+	server.Handle("Target.detachFromTarget", hctest.FakeResult(struct{}{}))
+	conn, fake := server.NewBrowserConn()
+	session, err := hc.NewSession(conn, "page1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session, conn, fake
+}
+
+// Sends an event of the target, like the browser does for attached sessions.
+func emitToSession(t *testing.T, fake *hctest.FakeConn, targetId, method string,
+	params interface{}) {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, _ := json.Marshal(map[string]interface{}{"method": method,
+		"params": json.RawMessage(data)})
+	if err := fake.Emit("Target.receivedMessageFromTarget", map[string]string{
+		"targetId": targetId, "message": string(message)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The On functions of the protocol package work on sessions, sinks added twice get each event
+// once, and Meta sinks get the EventMeta of the browser connection.
+func TestSessionEventSinks(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	session, _, fake := newTestSession(t, server)
+
+	fired := make(chan float64, 4)
+	remove := protocol.OnLoadEventFired(session, func(evt *protocol.LoadEventFiredEvent) {
+		fired <- evt.Timestamp
+	})
+	metas := make(chan hc.EventMeta, 4)
+	defer protocol.OnLoadEventFiredMeta(session, func(meta hc.EventMeta,
+		evt *protocol.LoadEventFiredEvent) {
+		metas <- meta
+	})()
+	others := make(chan struct{}, 4)
+	sink := hc.FuncToEventSink(func(string, []byte) { others <- struct{}{} })
+	session.AddEventSink("Page.domContentEventFired", sink)
+	session.AddEventSink("Page.domContentEventFired", sink)
+
+	// Events of other targets are ignored.
+	emitToSession(t, fake, "page2", "Page.loadEventFired", map[string]float64{"timestamp": 1})
+	emitToSession(t, fake, "page1", "Page.loadEventFired", map[string]float64{"timestamp": 2})
+	emitToSession(t, fake, "page1", "Page.domContentEventFired",
+		map[string]float64{"timestamp": 3})
+	select {
+	case timestamp := <-fired:
+		if timestamp != 2 {
+			t.Errorf("Got timestamp %v", timestamp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event")
+	}
+	select {
+	case meta := <-metas:
+		// The event of page2 came first.
+		if meta.Seq != 2 || meta.Received.IsZero() || meta.Size == 0 {
+			t.Errorf("Got %+v", meta)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event with meta")
+	}
+	select {
+	case <-others:
+	case <-time.After(5 * time.Second):
+		t.Fatal("No Page.domContentEventFired")
+	}
+
+	remove()
+	session.RemoveEventSink("Page.domContentEventFired", sink)
+	emitToSession(t, fake, "page1", "Page.loadEventFired", map[string]float64{"timestamp": 4})
+	emitToSession(t, fake, "page1", "Page.domContentEventFired",
+		map[string]float64{"timestamp": 5})
+	select {
+	case <-metas:
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event with meta")
+	}
+	// Deliveries race each other, so give the removed sinks a moment to show up.
+	time.Sleep(50 * time.Millisecond)
+	if len(fired) != 0 || len(others) != 0 {
+		t.Errorf("Removed sinks got %d and %d events", len(fired), len(others))
+	}
+}
+
+// Events of the target which don't decode are reported on the browser connection.
+func TestSessionEventErrors(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	session, conn, fake := newTestSession(t, server)
+	errs := make(chan error, 1)
+	conn.SetErrorHandler(func(err error) { errs <- err })
+	defer protocol.OnLoadEventFired(session, func(*protocol.LoadEventFiredEvent) {
+		t.Error("Bad event delivered")
+	})()
+	emitToSession(t, fake, "page1", "Page.loadEventFired", map[string]string{"timestamp": "now"})
+	select {
+	case err := <-errs:
+		if evtErr, ok := err.(*hc.EventError); !ok || evtErr.Name != "Page.loadEventFired" {
+			t.Errorf("Got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No error reported")
+	}
+}
+
+// Errors of commands sent over a session are CommandErrors, like on a connection.
+func TestSessionCommandErrors(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	session, _, fake := newTestSession(t, server)
+	server.Handle("Target.sendMessageToTarget", func(cmd *hctest.FakeCommand) (interface{},
+		error) {
+		var params struct{ TargetId, Message string }
+		json.Unmarshal(cmd.Params, &params)
+		var inner hc.CommandJson
+		json.Unmarshal([]byte(params.Message), &inner)
+		message, _ := json.Marshal(map[string]interface{}{"id": inner.Id,
+			"error": hctest.MethodNotFound(inner.Method)})
+		go fake.Emit("Target.receivedMessageFromTarget", map[string]string{
+			"targetId": params.TargetId, "message": string(message)})
+		return struct{}{}, nil
+	})
+	_, err := protocol.GetNavigationHistory(session)
+	if !hc.IsMethodNotFound(err) {
+		t.Errorf("Got %v", err)
+	}
+}
+
+// Records whether Done was called, and with which error.
+type doneRecorder struct {
+	params interface{}
+	called bool
+	err    error
+}
+
+func (c *doneRecorder) Name() string {
+	return "Page.navigate"
+}
+
+func (c *doneRecorder) Params() interface{} {
+	return c.params
+}
+
+func (c *doneRecorder) Done(_ []byte, err error) {
+	c.called, c.err = true, err
+}
+
+// Like with Conn.SendCommand, Done is called before SendCommand returns if the command can't be
+// sent.
+func TestSessionSendFailures(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	session, conn, _ := newTestSession(t, server)
+	for _, c := range []struct {
+		name   string
+		params interface{}
+		limit  int
+	}{
+		{name: "unmarshalable", params: map[string]interface{}{"f": func() {}}},
+		{name: "oversized", params: map[string]string{"url": strings.Repeat("a", 1000)},
+			limit: 100},
+	} {
+		conn.SetMaxMessageSizes(c.limit, 0)
+		cmd := &doneRecorder{params: c.params}
+		err := session.SendCommand(cmd)
+		if err == nil {
+			t.Errorf("%s: sent", c.name)
+		} else if !cmd.called || cmd.err != err {
+			t.Errorf("%s: got %v, Done called %t with %v", c.name, err, cmd.called, cmd.err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package headless_chromium
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// Sinks of an event, with what SinkReport tells about them.
+type SinkReportEntry struct {
+	Event string
+	Count int
+	// Where the sinks were added, most frequent first, up to maxReportedSites. Empty unless
+	// SetTrackSinkSites is on.
+	Sites []SinkSite
+}
+
+type SinkSite struct {
+	// The first function outside of this package and the protocol package, e.g.
+	// "github.com/.../hcutil.listen (listen.go:25)".
+	Site  string
+	Count int
+}
+
+const maxReportedSites = 5
+
+var (
+	hcPkgPath       = reflect.TypeOf(Conn{}).PkgPath()
+	protocolPkgPath = hcPkgPath + "/protocol/"
+)
+
+// Logs a warning with a stack trace once an event has more than limit sinks, and again each time
+// their number doubles. Sinks are never dropped. It catches helpers adding sinks per request
+// without removing them, which slows every event down. 0, the default, disables it.
+func (c *Conn) SetSinkLimit(limit int) {
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	c.sinkLimit = limit
+	c.sinkWarnedMap = make(map[string]int)
+}
+
+// Records where sinks are added, for SinkReport. It costs a stack walk per AddEventSink, so
+// it's off by default. Only sinks added while it's on are attributed.
+func (c *Conn) SetTrackSinkSites(track bool) {
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	c.trackSinkSites = track
+}
+
+// Lists events with sinks, those with most sinks first.
+func (c *Conn) SinkReport() []SinkReportEntry {
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	report := make([]SinkReportEntry, 0, len(c.evtSinkMap))
+	for name, sinks := range c.evtSinkMap {
+		entry := SinkReportEntry{Event: name, Count: len(sinks)}
+		siteCounts := make(map[string]int)
+		for _, site := range c.evtSinkSiteMap[name] {
+			if site != "" {
+				siteCounts[site]++
+			}
+		}
+		for site, count := range siteCounts {
+			entry.Sites = append(entry.Sites, SinkSite{site, count})
+		}
+		sort.Slice(entry.Sites, func(i, j int) bool {
+			a, b := entry.Sites[i], entry.Sites[j]
+			return a.Count > b.Count || (a.Count == b.Count && a.Site < b.Site)
+		})
+		if len(entry.Sites) > maxReportedSites {
+			entry.Sites = entry.Sites[:maxReportedSites]
+		}
+		report = append(report, entry)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Event < b.Event)
+	})
+	return report
+}
+
+// Returns the number of sinks by event name.
+func (c *Conn) sinkCounts() map[string]int {
+	c.evtMu.Lock()
+	defer c.evtMu.Unlock()
+	counts := make(map[string]int, len(c.evtSinkMap))
+	for name, sinks := range c.evtSinkMap {
+		counts[name] = len(sinks)
+	}
+	return counts
+}
+
+// Returns the site of a sink being added, and warns if there are too many sinks of name, which
+// already has n, including the new one.
+func (c *Conn) checkSinkLocked(name string, n int) (site string) {
+	over := c.sinkLimit > 0 && n > c.sinkLimit && n >= 2*c.sinkWarnedMap[name]
+	if !over && !c.trackSinkSites {
+		return ""
+	}
+	frames := callerFrames()
+	if over {
+		c.sinkWarnedMap[name] = n
+		lines := make([]string, len(frames))
+		for i, frame := range frames {
+			lines[i] = fmt.Sprintf("\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+		}
+		logging.Vlogf(-1, "%d sinks of %s, over the limit %d. Leaking sinks? Added by:\n%s", n,
+			name, c.sinkLimit, strings.Join(lines, "\n"))
+	}
+	if !c.trackSinkSites {
+		return ""
+	}
+	for _, frame := range frames {
+		if !strings.HasPrefix(frame.Function, hcPkgPath+".") &&
+			!strings.HasPrefix(frame.Function, protocolPkgPath) {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, shortFile(frame.File), frame.Line)
+		}
+	}
+	return ""
+}
+
+func callerFrames() []runtime.Frame {
+	pcs := make([]uintptr, 32)
+	// Skips runtime.Callers, callerFrames and checkSinkLocked.
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var list []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		list = append(list, frame)
+		if !more {
+			return list
+		}
+	}
+}
+
+func shortFile(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
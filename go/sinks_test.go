@@ -0,0 +1,137 @@
+package headless_chromium_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+func addLoadSink(conn *hc.Conn) func() {
+	return protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {})
+}
+
+func TestSinkReport(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	conn.SetTrackSinkSites(true)
+	var removes []func()
+	for i := 0; i < 3; i++ {
+		removes = append(removes, addLoadSink(conn))
+	}
+	removes = append(removes, protocol.OnFrameNavigated(conn,
+		func(*protocol.FrameNavigatedEvent) {}))
+
+	report := conn.SinkReport()
+	if len(report) != 2 || report[0].Event != "Page.loadEventFired" || report[0].Count != 3 ||
+		report[1].Event != "Page.frameNavigated" || report[1].Count != 1 {
+		t.Fatalf("Got %+v", report)
+	}
+	sites := report[0].Sites
+	if len(sites) != 1 || sites[0].Count != 3 ||
+		!strings.Contains(sites[0].Site, "_test.addLoadSink (sinks_test.go") {
+		t.Errorf("Got sites %+v", sites)
+	}
+	if n := conn.Stats().EventSinks["Page.loadEventFired"]; n != 3 {
+		t.Errorf("Stats count %d sinks", n)
+	}
+
+	// Removed sinks get no more events, and are forgotten.
+	removes[0]()
+	removes[0]()
+	fired := make(chan struct{}, 10)
+	remove := protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {
+		fired <- struct{}{}
+	})
+	fake.Emit("Page.loadEventFired", map[string]float64{"timestamp": 1})
+	hctest.Flush(t, conn)
+	remove()
+	fake.Emit("Page.loadEventFired", map[string]float64{"timestamp": 2})
+	hctest.Flush(t, conn)
+	if len(fired) != 1 {
+		t.Errorf("Removed sink called %d times", len(fired))
+	}
+	if n := conn.Stats().EventSinks["Page.loadEventFired"]; n != 2 {
+		t.Errorf("%d sinks left", n)
+	}
+	for _, remove := range removes[1:] {
+		remove()
+	}
+	if report := conn.SinkReport(); len(report) != 0 {
+		t.Errorf("Got %+v after removing every sink", report)
+	}
+}
+
+// Going over the limit only warns.
+func TestSinkLimit(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, fake := server.NewPageConn()
+	conn.SetSinkLimit(2)
+	fired := make(chan struct{}, 10)
+	for i := 0; i < 5; i++ {
+		protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {
+			fired <- struct{}{}
+		})
+	}
+	fake.Emit("Page.loadEventFired", map[string]float64{"timestamp": 1})
+	hctest.Flush(t, conn)
+	if len(fired) != 5 {
+		t.Errorf("%d of 5 sinks called", len(fired))
+	}
+	if sites := conn.SinkReport()[0].Sites; len(sites) != 0 {
+		t.Errorf("Sites tracked without SetTrackSinkSites: %+v", sites)
+	}
+}
+
+// Records failures instead of failing the test, and runs cleanups on demand.
+type recordingTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestExpectNoSinkLeaks(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	conn, _ := server.NewPageConn()
+	// Sinks there before don't count.
+	addLoadSink(conn)
+
+	clean := &recordingTB{TB: t}
+	hctest.ExpectNoSinkLeaks(clean, conn)
+	addLoadSink(conn)()
+	clean.finish()
+	if len(clean.errors) != 0 {
+		t.Errorf("Removed sinks reported: %v", clean.errors)
+	}
+
+	leaky := &recordingTB{TB: t}
+	hctest.ExpectNoSinkLeaks(leaky, conn)
+	addLoadSink(conn)
+	addLoadSink(conn)
+	leaky.finish()
+	report := strings.Join(leaky.errors, "\n")
+	if !strings.Contains(report, "3 sinks of Page.loadEventFired, 1 at first") ||
+		!strings.Contains(report, "2 by ") ||
+		!strings.Contains(report, "_test.addLoadSink (sinks_test.go") {
+		t.Errorf("Got %s", report)
+	}
+}
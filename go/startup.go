@@ -0,0 +1,120 @@
+package headless_chromium
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+const (
+	// How long Launch waits for the browser to answer /json/version by default.
+	DefaultStartupTimeout = 30 * time.Second
+	// How long NewBrowserConn and NewPageConn retry transient dial failures by default.
+	DefaultDialRetryWindow = 10 * time.Second
+)
+
+// Backoff between attempts while the browser warms up.
+const (
+	minStartupBackoff = 50 * time.Millisecond
+	maxStartupBackoff = time.Second
+)
+
+// A non-200 answer of the devtools endpoint, e.g. 502 from a fronting proxy while the browser
+// starts.
+type httpStatusError struct {
+	url    string
+	status int
+	err    error
+}
+
+func (e *httpStatusError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %v (HTTP %d)", e.url, e.err, e.status)
+	}
+	return fmt.Sprintf("%s: HTTP %d", e.url, e.status)
+}
+
+func (e *httpStatusError) Unwrap() error {
+	return e.err
+}
+
+// Whether err may go away by trying again shortly, as the browser isn't accepting connections
+// yet, or a proxy in front of it can't reach it yet.
+func isTransientDialError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// Calls attempt till it succeeds, fails with an error which isn't transient, or window passes,
+// backing off exponentially in between. The last error is wrapped on timeout.
+func retryTransient(what string, window time.Duration, attempt func() error) error {
+	deadline := time.Now().Add(window)
+	backoff := minStartupBackoff
+	for {
+		err := attempt()
+		if err == nil || !isTransientDialError(err) {
+			return err
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("%s failed for %v: %w", what, window, err)
+		}
+		logging.Vlogf(2, "%s failed, retrying in %v: %v", what, backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxStartupBackoff {
+			backoff = maxStartupBackoff
+		}
+	}
+}
+
+// Options of NewRemoteBrowserWithOptions.
+type RemoteOptions struct {
+	// How long to wait for the browser to answer, e.g. when it was just started by another
+	// process. 0 means not to wait.
+	StartupTimeout time.Duration
+	// Like LaunchOptions.DialRetryWindow.
+	DialRetryWindow time.Duration
+}
+
+// Like NewRemoteBrowser, but waits for the browser to start, and retries dials like Launch.
+func NewRemoteBrowserWithOptions(addrPort string, opts RemoteOptions) (*Browser, error) {
+	browser := &Browser{addrPort: addrPort, dialRetryWindow: opts.DialRetryWindow}
+	if err := browser.waitReady(opts.StartupTimeout); err != nil {
+		return nil, err
+	}
+	return browser, nil
+}
+
+// Waits till the browser answers /json/version, and keeps the version.
+func (b *Browser) waitReady(timeout time.Duration) error {
+	return retryTransient("Fetching /json/version of "+b.addrPort, timeout, b.checkVersion)
+}
+
+func (b *Browser) retryWindow() time.Duration {
+	if b.dialRetryWindow <= 0 {
+		return DefaultDialRetryWindow
+	}
+	return b.dialRetryWindow
+}
+
+// Connects to url, retrying transient failures for Browser.dialRetryWindow.
+func (b *Browser) dial(url string, kind TargetKinds) (*Conn, error) {
+	var conn *Conn
+	err := retryTransient("Connecting to "+url, b.retryWindow(), func() (err error) {
+		conn, err = newConn(url, kind)
+		return err
+	})
+	return conn, err
+}
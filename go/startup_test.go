@@ -0,0 +1,93 @@
+package headless_chromium_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+)
+
+// A browser which starts listening late can be used as soon as binding returns, without sleeps.
+func TestWaitForStartingBrowser(t *testing.T) {
+	const delay = 2 * time.Second
+	server := hctest.NewDelayedFakeServer(t, delay)
+	if _, err := hc.NewRemoteBrowser(server.Addr()); err == nil {
+		t.Fatal("Bound to the browser before it listens")
+	}
+	start := time.Now()
+	browser, err := hc.NewRemoteBrowserWithOptions(server.Addr(), hc.RemoteOptions{
+		StartupTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < delay/2 {
+		t.Errorf("Bound after %v, before the browser listens", elapsed)
+	}
+	if browser.Version().ProtocolVersion != hctest.FakeVersion.ProtocolVersion {
+		t.Errorf("Got version %+v", browser.Version())
+	}
+	conn, err := browser.NewBrowserConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	conn, err = browser.NewPageConn(hctest.FakePageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestStartupTimeout(t *testing.T) {
+	server := hctest.NewDelayedFakeServer(t, 10*time.Second)
+	start := time.Now()
+	_, err := hc.NewRemoteBrowserWithOptions(server.Addr(), hc.RemoteOptions{
+		StartupTimeout: 300 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Fatalf("Got %v, want the last error wrapped", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Gave up after %v", elapsed)
+	}
+}
+
+// 502 from a proxy in front of the browser is retried, within the window.
+func TestDialRetry(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	browser, err := hc.NewRemoteBrowserWithOptions(server.Addr(), hc.RemoteOptions{
+		DialRetryWindow: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.RejectDials(3)
+	conn, err := browser.NewPageConn(hctest.FakePageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	browser, err = hc.NewRemoteBrowserWithOptions(server.Addr(), hc.RemoteOptions{
+		DialRetryWindow: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.RejectDials(1000)
+	if _, err := browser.NewBrowserConn(); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("Got %v, want the last error wrapped", err)
+	}
+}
+
+// Errors which trying again can't fix are returned right away.
+func TestDialPermanentError(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	browser := server.Browser()
+	start := time.Now()
+	if _, err := browser.NewPageConn("no-such-page"); err == nil {
+		t.Fatal("Connected to a missing page")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Failed after %v", elapsed)
+	}
+}
@@ -0,0 +1,119 @@
+package headless_chromium_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yijinliu/headless-chromium/go/hctest"
+	protocol "github.com/yijinliu/headless-chromium/go/protocol/v1.2"
+)
+
+// Answers Page.navigate after the lifecycle events of loading a document in the main frame, and
+// in a subframe which finishes loading last.
+func handleStickyNavigate(server *hctest.FakeServer) {
+	server.Handle("Page.navigate", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		for _, evt := range []struct {
+			method string
+			params interface{}
+		}{
+			{"Page.frameStartedLoading", map[string]string{"frameId": "main"}},
+			{"Page.frameNavigated", map[string]interface{}{"frame": map[string]string{
+				"id": "main", "loaderId": "loader", "url": "http://fixture/",
+				"securityOrigin": "http://fixture", "mimeType": "text/html"}}},
+			{"Page.domContentEventFired", map[string]float64{"timestamp": 1}},
+			{"Page.loadEventFired", map[string]float64{"timestamp": 2}},
+			{"Page.frameStoppedLoading", map[string]string{"frameId": "main"}},
+			{"Page.frameStoppedLoading", map[string]string{"frameId": "sub"}},
+		} {
+			if err := cmd.Conn.Emit(evt.method, evt.params); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]string{"frameId": "main"}, nil
+	})
+}
+
+// Sticky sinks added after the page loaded get the events of its document, of the main frame.
+func TestStickyEventsAfterLoad(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	handleStickyNavigate(server)
+	conn, fake := server.NewPageConn()
+	if _, err := protocol.Navigate(&protocol.NavigateParams{Url: "http://fixture/"},
+		conn); err != nil {
+		t.Fatal(err)
+	}
+	hctest.Flush(t, conn)
+
+	loads := make(chan float64, 2)
+	defer protocol.OnLoadEventFiredSticky(conn, func(evt *protocol.LoadEventFiredEvent) {
+		loads <- evt.Timestamp
+	})()
+	stops := make(chan protocol.FrameId, 2)
+	defer protocol.OnFrameStoppedLoadingSticky(conn, func(evt *protocol.FrameStoppedLoadingEvent) {
+		stops <- evt.FrameId
+	})()
+	defer protocol.OnLoadEventFired(conn, func(*protocol.LoadEventFiredEvent) {
+		t.Error("Plain sink got a past event")
+	})()
+	select {
+	case timestamp := <-loads:
+		if timestamp != 2 {
+			t.Errorf("Got timestamp %v", timestamp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No sticky load event")
+	}
+	select {
+	case frameId := <-stops:
+		if frameId != "main" {
+			t.Errorf("Got frame %s", frameId)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No sticky frameStoppedLoading")
+	}
+
+	// Once the main frame loads another document, the events are forgotten.
+	if err := fake.Emit("Page.frameStartedLoading", map[string]string{"frameId": "main"}); err != nil {
+		t.Fatal(err)
+	}
+	hctest.Flush(t, conn)
+	defer protocol.OnDomContentEventFiredSticky(conn, func(*protocol.DomContentEventFiredEvent) {
+		t.Error("Got an event of the previous document")
+	})()
+	time.Sleep(50 * time.Millisecond)
+}
+
+// Sinks added while the page loads get each event exactly once, whether it fires before or
+// after they're added.
+func TestStickyEventsRace(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	handleStickyNavigate(server)
+	for i := 0; i < 50; i++ {
+		conn, _ := server.NewPageConn()
+		navigated := make(chan error, 1)
+		go func() {
+			_, err := protocol.Navigate(&protocol.NavigateParams{Url: "http://fixture/"}, conn)
+			navigated <- err
+		}()
+		loads := make(chan struct{}, 2)
+		remove := protocol.OnLoadEventFiredSticky(conn, func(*protocol.LoadEventFiredEvent) {
+			loads <- struct{}{}
+		})
+		if err := <-navigated; err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-loads:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Round %d: no load event", i)
+		}
+		hctest.Flush(t, conn)
+		// Replays aren't waited for by Flush, so give a second delivery a moment to show up.
+		time.Sleep(10 * time.Millisecond)
+		if len(loads) != 0 {
+			t.Errorf("Round %d: got the load event twice", i)
+		}
+		remove()
+		conn.Close()
+	}
+}
@@ -0,0 +1,110 @@
+package headless_chromium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of targets a connection may talk to, as a bit set. 0 means unknown.
+type TargetKinds int
+
+const (
+	// The browser itself, see Browser.NewBrowserConn.
+	TargetBrowser TargetKinds = 1 << iota
+	// A page, see Browser.NewPageConn.
+	TargetPage
+	// A dedicated, shared or service worker.
+	TargetWorker
+
+	AnyTarget = TargetBrowser | TargetPage | TargetWorker
+)
+
+var targetKindNames = []struct {
+	kind TargetKinds
+	name string
+}{{TargetBrowser, "browser"}, {TargetPage, "page"}, {TargetWorker, "worker"}}
+
+// E.g. "page or worker".
+func (k TargetKinds) String() string {
+	var names []string
+	for _, n := range targetKindNames {
+		if k&n.kind != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "unknown"
+	}
+	return strings.Join(names, " or ")
+}
+
+// Parses a name of String, e.g. "page".
+func ParseTargetKind(name string) (TargetKinds, error) {
+	for _, n := range targetKindNames {
+		if n.name == name {
+			return n.kind, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown target kind '%s'", name)
+}
+
+var ErrWrongTargetKind = errors.New("wrong target kind")
+
+// A command or event was used on a connection to a target which doesn't support it, e.g.
+// Page.enable on a browser connection. errors.Is(err, ErrWrongTargetKind) is true for it.
+type WrongTargetKindError struct {
+	Method string
+	// The kinds supporting Method.
+	Required TargetKinds
+	// The kind of the connection.
+	Kind TargetKinds
+}
+
+func (e *WrongTargetKindError) Error() string {
+	return fmt.Sprintf("%s requires a %s connection, not a %s one", e.Method, e.Required, e.Kind)
+}
+
+func (e *WrongTargetKindError) Is(target error) bool {
+	return target == ErrWrongTargetKind
+}
+
+// Returns the kinds of targets supporting a command or event, or 0 if unknown. Registered by the
+// protocol package.
+type TargetKindsFunc func(method string) TargetKinds
+
+var targetKindsFunc TargetKindsFunc
+
+// Don't call this. The protocol package registers its function.
+func SetTargetKindsFunc(f TargetKindsFunc) {
+	targetKindsFunc = f
+}
+
+// Returns a WrongTargetKindError if a connection to a target of kind can't use method, a command
+// or event name. Nothing is checked if kind or the kinds supporting method are unknown.
+func CheckTargetKind(kind TargetKinds, method string) error {
+	if kind == 0 || targetKindsFunc == nil {
+		return nil
+	}
+	required := targetKindsFunc(method)
+	if required == 0 || required&kind != 0 {
+		return nil
+	}
+	return &WrongTargetKindError{Method: method, Required: required, Kind: kind}
+}
+
+// The kind of target the connection talks to, or 0 if unknown.
+func (c *Conn) Kind() TargetKinds {
+	return c.kind
+}
+
+// Returns a WrongTargetKindError if the connection can't use all of methods, e.g. for helpers
+// to fail up front instead of deep inside.
+func (c *Conn) CheckKind(methods ...string) error {
+	for _, method := range methods {
+		if err := CheckTargetKind(c.kind, method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
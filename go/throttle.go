@@ -0,0 +1,107 @@
+package headless_chromium
+
+import (
+	"math"
+	"time"
+)
+
+// Limits the rate of evaluations on a connection, so that polling helpers don't starve the
+// page's main thread, or skew what's measured. See SetEvaluateThrottle.
+type EvaluateThrottle struct {
+	// Evaluations per second. 0 means unlimited.
+	Rate float64
+	// Evaluations allowed at once after a quiet period. Defaults to 1.
+	Burst int
+	// Throttle every Runtime.evaluate and Runtime.callFunctionOn, e.g. of protocol.Evaluate
+	// called directly, by blocking SendCommand till its turn. By default only helpers calling
+	// ThrottleEvaluation are throttled.
+	All bool
+}
+
+// Commands throttled by EvaluateThrottle.
+var evaluationMethods = map[string]bool{
+	"Runtime.evaluate":       true,
+	"Runtime.callFunctionOn": true,
+}
+
+// A token bucket. Guarded by Conn.throttleMu.
+type evalThrottle struct {
+	config EvaluateThrottle
+	tokens float64
+	last   time.Time
+	// Number of evaluations which waited, and for how long in total.
+	throttled int
+	queueTime time.Duration
+}
+
+// Sets the throttle of evaluations. The zero EvaluateThrottle, the default, disables it.
+func (c *Conn) SetEvaluateThrottle(throttle EvaluateThrottle) {
+	if throttle.Burst < 1 {
+		throttle.Burst = 1
+	}
+	c.throttleMu.Lock()
+	defer c.throttleMu.Unlock()
+	t := &c.evalThrottle
+	t.config = throttle
+	t.tokens = float64(throttle.Burst)
+	t.last = time.Now()
+}
+
+// Called by helpers before sending Runtime.evaluate or Runtime.callFunctionOn. Blocks till the
+// throttle allows another evaluation. Fails if the connection is closed meanwhile.
+func (c *Conn) ThrottleEvaluation() error {
+	c.throttleMu.Lock()
+	all := c.evalThrottle.config.All
+	c.throttleMu.Unlock()
+	if all {
+		// SendCommand throttles it.
+		return nil
+	}
+	return c.throttleEvaluation()
+}
+
+func (c *Conn) throttleAllEvaluations() error {
+	c.throttleMu.Lock()
+	all := c.evalThrottle.config.All
+	c.throttleMu.Unlock()
+	if !all {
+		return nil
+	}
+	return c.throttleEvaluation()
+}
+
+func (c *Conn) throttleEvaluation() error {
+	c.throttleMu.Lock()
+	wait := c.evalThrottle.reserve(time.Now())
+	c.throttleMu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.closed:
+		return c.closedErr(ErrConnClosed)
+	}
+}
+
+// Takes a token, and returns how long to wait till it's available. Tokens are taken in advance,
+// so that waiting evaluations go in order.
+func (t *evalThrottle) reserve(now time.Time) time.Duration {
+	if t.config.Rate <= 0 {
+		return 0
+	}
+	elapsed := now.Sub(t.last).Seconds()
+	t.tokens = math.Min(float64(t.config.Burst), t.tokens+elapsed*t.config.Rate)
+	t.last = now
+	t.tokens--
+	if t.tokens >= 0 {
+		return 0
+	}
+	wait := time.Duration(-t.tokens / t.config.Rate * float64(time.Second))
+	t.throttled++
+	t.queueTime += wait
+	return wait
+}
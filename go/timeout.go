@@ -0,0 +1,66 @@
+package headless_chromium
+
+import (
+	"time"
+
+	"github.com/yijinliu/algo-lib/go/src/logging"
+)
+
+// How many late responses of timed out commands are remembered, to discard them quietly.
+const maxTimedOutIds = 1024
+
+// Sets the timeout of commands sent afterwards, e.g. so that a hung browser doesn't block
+// synchronous commands forever. Commands not answered in time fail with ErrCommandTimeout, and
+// their responses are discarded if they come later. The command is still sent if it's queued
+// when timing out. 0, the default, means no timeout. See also WithTimeout.
+func (c *Conn) SetDefaultTimeout(timeout time.Duration) {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	c.defaultTimeout = timeout
+}
+
+type timeoutRunner struct {
+	conn    *Conn
+	timeout time.Duration
+}
+
+func (r *timeoutRunner) SendCommand(cmd Command) error {
+	return r.conn.sendCommand(cmd, PriorityNormal, r.timeout)
+}
+
+// Returns a CommandRunner sending commands on c with timeout instead of the default one, e.g.
+// protocol.CaptureScreenshot(conn.WithTimeout(time.Minute)). See SetDefaultTimeout.
+func (c *Conn) WithTimeout(timeout time.Duration) CommandRunner {
+	return &timeoutRunner{c, timeout}
+}
+
+// Fails pending command id with ErrCommandTimeout. Called by the timer of the command.
+func (c *Conn) timeOutCommand(id int, timeout time.Duration) {
+	var method string
+	if c.finishCommand(id, nil, func(cmd Command) error {
+		method = cmd.Name()
+		// With cmdMu held, so that a response read right after this is known to be late.
+		c.commandTimeouts++
+		if len(c.timedOutIds) >= maxTimedOutIds {
+			// The browser isn't going to answer them anyway.
+			c.timedOutIds = make(map[int]bool)
+		}
+		c.timedOutIds[id] = true
+		return ErrCommandTimeout
+	}) {
+		logging.Vlogf(1, "%s (%d) timed out after %v", method, id, timeout)
+	}
+}
+
+// Returns whether id is of a command which timed out, and forgets it, counting its response as
+// late.
+func (c *Conn) timedOut(id int) bool {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	if !c.timedOutIds[id] {
+		return false
+	}
+	delete(c.timedOutIds, id)
+	c.lateResponses++
+	return true
+}
@@ -0,0 +1,66 @@
+package headless_chromium_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	hc "github.com/yijinliu/headless-chromium/go"
+	"github.com/yijinliu/headless-chromium/go/hctest"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	replyNow := make(chan struct{})
+	server.Handle("Test.late", func(cmd *hctest.FakeCommand) (interface{}, error) {
+		go func() {
+			<-replyNow
+			cmd.Conn.Reply(cmd.Id, echoParams{1})
+		}()
+		return nil, hctest.ErrNoReply
+	})
+	conn, _ := server.NewPageConn()
+
+	cmd := newTestCommand("Test.late", nil)
+	start := time.Now()
+	err := cmd.run(t, conn.WithTimeout(20*time.Millisecond), 10*time.Second)
+	if err != hc.ErrCommandTimeout {
+		t.Fatalf("Got %v, not ErrCommandTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Timed out after %v", elapsed)
+	}
+	// The response comes right after the timeout.
+	close(replyNow)
+	if err := roundTrip(conn); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt32(&cmd.calls); calls != 1 {
+		t.Errorf("Done called %d times", calls)
+	}
+	stats := conn.Stats()
+	if stats.CommandTimeouts != 1 || stats.LateResponses != 1 {
+		t.Errorf("Got %d timeouts and %d late responses, not 1 and 1", stats.CommandTimeouts,
+			stats.LateResponses)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	server := hctest.NewFakeServer(t)
+	server.Handle("Test.hang", func(*hctest.FakeCommand) (interface{}, error) {
+		return nil, hctest.ErrNoReply
+	})
+	conn, _ := server.NewPageConn()
+	conn.SetDefaultTimeout(20 * time.Millisecond)
+	err := newTestCommand("Test.hang", nil).run(t, conn, 10*time.Second)
+	if err != hc.ErrCommandTimeout {
+		t.Errorf("Got %v, not ErrCommandTimeout", err)
+	}
+	// Answered commands aren't affected.
+	if err := newTestCommand("Test.echo", nil).run(t, conn, 10*time.Second); err != nil {
+		t.Error(err)
+	}
+	if n := conn.Stats().CommandTimeouts; n != 1 {
+		t.Errorf("%d timeouts, not 1", n)
+	}
+}